@@ -20,10 +20,13 @@ import (
 	"github.com/exchange/admin/internal/service"
 	commonauth "github.com/exchange/common/pkg/auth"
 	commonerrors "github.com/exchange/common/pkg/errors"
+	commonredis "github.com/exchange/common/pkg/redis"
 	commonresp "github.com/exchange/common/pkg/response"
+	"github.com/exchange/common/pkg/shard"
 	"github.com/exchange/common/pkg/snowflake"
 	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -61,10 +64,37 @@ func main() {
 	}
 	log.Printf("Connected to PostgreSQL")
 
+	// 连接 Redis（交易对状态变更写入订单流）
+	redisTLSConfig, err := commonredis.TLSConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid Redis TLS config: %v", err)
+	}
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:         cfg.RedisAddr,
+		Password:     cfg.RedisPassword,
+		DB:           cfg.RedisDB,
+		TLSConfig:    redisTLSConfig,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+	})
+	defer redisClient.Close()
+	redisPingCtx, redisPingCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer redisPingCancel()
+	if err := redisClient.Ping(redisPingCtx).Err(); err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	log.Printf("Connected to Redis")
+
 	// 创建服务
 	idGen := snowflakeIDGen{}
 	repo := repository.NewAdminRepository(db)
 	svc := service.NewAdminService(repo, idGen)
+	shards, err := shard.NewRouter(cfg.MatchingShards, redisClient)
+	if err != nil {
+		log.Fatalf("Invalid matching shard config: %v", err)
+	}
+	svc.SetStatusPublisher(service.NewRedisStatusPublisher(redisClient, cfg.OrderStream, shards))
 
 	// HTTP 服务
 	mux := http.NewServeMux()
@@ -77,6 +107,7 @@ func main() {
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		deps := []dependencyStatus{
 			checkPostgres(r.Context(), db),
+			checkRedis(r.Context(), redisClient),
 		}
 		writeHealth(w, deps)
 	})
@@ -94,6 +125,7 @@ func main() {
 	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
 		deps := []dependencyStatus{
 			checkPostgres(r.Context(), db),
+			checkRedis(r.Context(), redisClient),
 		}
 		writeHealth(w, deps)
 	})
@@ -186,7 +218,7 @@ func main() {
 				return
 			}
 			if err := svc.CreateSymbol(r.Context(), actorID, r.RemoteAddr, &cfg); err != nil {
-				writeInternalError(w, err)
+				writeSymbolError(w, r, err)
 				return
 			}
 			w.Header().Set("Content-Type", "application/json")
@@ -226,7 +258,7 @@ func main() {
 			}
 			cfg.Symbol = symbol
 			if err := svc.UpdateSymbol(r.Context(), actorID, r.RemoteAddr, &cfg); err != nil {
-				writeInternalError(w, err)
+				writeSymbolError(w, r, err)
 				return
 			}
 			w.Header().Set("Content-Type", "application/json")
@@ -246,7 +278,7 @@ func main() {
 
 		actorID := getActorID(r)
 		var req struct {
-			Action string `json:"action"` // halt, cancelOnly, resume
+			Action string `json:"action"` // halt, cancelOnly, resume, auction（仅单个交易对）
 			Symbol string `json:"symbol"` // 可选，不传则全局
 		}
 		if !decodeJSON(w, r, &req) {
@@ -264,6 +296,8 @@ func main() {
 				status = service.StatusCancelOnly
 			case "resume":
 				status = service.StatusTrading
+			case "auction":
+				status = service.StatusAuction
 			default:
 				commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidParam, "invalid action")
 				return
@@ -386,6 +420,53 @@ func main() {
 		}
 	})
 
+	// 用户风控：账户等级与禁止交易开关（订单服务下单前风控读取）
+	mux.HandleFunc("/admin/userRisk", func(w http.ResponseWriter, r *http.Request) {
+		actorID := getActorID(r)
+
+		switch r.Method {
+		case http.MethodGet:
+			userID, _ := strconv.ParseInt(r.URL.Query().Get("userId"), 10, 64)
+			if userID == 0 {
+				commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidParam, "userId required")
+				return
+			}
+			settings, err := svc.GetUserRisk(r.Context(), userID)
+			if err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(settings)
+
+		case http.MethodPut:
+			var req struct {
+				UserID          int64  `json:"userId"`
+				Tier            string `json:"tier"`
+				TradingDisabled bool   `json:"tradingDisabled"`
+				Reason          string `json:"reason"`
+			}
+			if !decodeJSON(w, r, &req) {
+				return
+			}
+			settings := &repository.UserRiskSettings{
+				UserID:          req.UserID,
+				Tier:            req.Tier,
+				TradingDisabled: req.TradingDisabled,
+				Reason:          req.Reason,
+			}
+			if err := svc.SetUserRisk(r.Context(), actorID, r.RemoteAddr, settings); err != nil {
+				writeSymbolError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(settings)
+
+		default:
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+		}
+	})
+
 	// 中间件链
 	var handler http.Handler = mux
 	handler = adminPermissionMiddleware(repo, handler)
@@ -462,6 +543,22 @@ func checkPostgres(ctx context.Context, db *sql.DB) dependencyStatus {
 	}
 }
 
+func checkRedis(ctx context.Context, client *redis.Client) dependencyStatus {
+	start := time.Now()
+	timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	err := client.Ping(timeoutCtx).Err()
+	status := "ok"
+	if err != nil {
+		status = "down"
+	}
+	return dependencyStatus{
+		Name:    "redis",
+		Status:  status,
+		Latency: time.Since(start).Milliseconds(),
+	}
+}
+
 func writeHealth(w http.ResponseWriter, deps []dependencyStatus) {
 	status := "ok"
 	for _, dep := range deps {
@@ -552,6 +649,8 @@ var adminRoutePermissions = []adminRoutePermission{
 	{Method: http.MethodGet, Path: "/admin/userRoles", AnyOf: []string{"rbac:read", "user:read", "risk:read"}},
 	{Method: http.MethodPost, Path: "/admin/userRoles", AnyOf: []string{"rbac:write", "user:write", "risk:write"}},
 	{Method: http.MethodDelete, Path: "/admin/userRoles", AnyOf: []string{"rbac:write", "user:write", "risk:write"}},
+	{Method: http.MethodGet, Path: "/admin/userRisk", AnyOf: []string{"risk:read", "user:read"}},
+	{Method: http.MethodPut, Path: "/admin/userRisk", AnyOf: []string{"risk:write", "user:write"}},
 }
 
 func adminPermissionMiddleware(reader adminPermissionReader, next http.Handler) http.Handler {
@@ -679,3 +778,13 @@ func writeInternalError(w http.ResponseWriter, err error) {
 	log.Printf("internal error: %v", err)
 	commonresp.WriteErrorCode(w, nil, commonerrors.CodeInternal, "internal error")
 }
+
+// writeSymbolError 交易对配置、用户风控设置校验失败返回对应错误码，其余按内部错误处理
+func writeSymbolError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *commonerrors.Error
+	if errors.As(err, &apiErr) {
+		commonresp.WriteError(w, r, apiErr)
+		return
+	}
+	writeInternalError(w, err)
+}
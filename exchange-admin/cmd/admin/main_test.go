@@ -29,6 +29,7 @@ func TestMatchAdminRoutePermission(t *testing.T) {
 	}{
 		{name: "exact path", method: http.MethodPost, path: "/admin/symbols", matched: true},
 		{name: "prefix path", method: http.MethodPatch, path: "/admin/symbols/BTCUSDT", matched: true},
+		{name: "user risk", method: http.MethodPut, path: "/admin/userRisk", matched: true},
 		{name: "unknown path", method: http.MethodGet, path: "/admin/unknown", matched: false},
 	}
 
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	envconfig "github.com/exchange/common/pkg/config"
+	"github.com/exchange/common/pkg/shard"
 )
 
 // Config 服务配置
@@ -28,11 +29,19 @@ type Config struct {
 	DBConnMaxLifetime time.Duration
 	DBConnMaxIdleTime time.Duration
 
+	// Redis（交易对状态变更写入订单流）
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+
 	// Streams
 	OrderStream             string
 	EventStream             string
 	PrivateUserEventChannel string
 
+	// 撮合分片：状态变更写入交易对所属分片的订单流
+	MatchingShards shard.Config
+
 	// Auth
 	AuthTokenSecret string
 	AuthTokenTTL    time.Duration
@@ -64,10 +73,16 @@ func Load() *Config {
 		DBConnMaxLifetime: envconfig.GetEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
 		DBConnMaxIdleTime: envconfig.GetEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
 
+		RedisAddr:     envconfig.GetEnv("REDIS_ADDR", "localhost:6380"), // 默认使用6380避免与本地Redis冲突
+		RedisPassword: envconfig.GetEnv("REDIS_PASSWORD", ""),
+		RedisDB:       envconfig.GetEnvInt("REDIS_DB", 0),
+
 		OrderStream:             envconfig.GetEnv("ORDER_STREAM", "exchange:orders"),
 		EventStream:             envconfig.GetEnv("EVENT_STREAM", "exchange:events"),
 		PrivateUserEventChannel: envconfig.GetEnv("PRIVATE_USER_EVENT_CHANNEL", "private:user:{userId}:events"),
 
+		MatchingShards: shard.ConfigFromEnv(),
+
 		AuthTokenSecret: envconfig.GetEnv("AUTH_TOKEN_SECRET", ""),
 		AuthTokenTTL:    envconfig.GetEnvDuration("AUTH_TOKEN_TTL", 24*time.Hour),
 		AdminToken:      envconfig.GetEnv("ADMIN_TOKEN", ""),
@@ -83,6 +98,9 @@ func (c *Config) Validate() error {
 	if c.AuthTokenSecret == "" {
 		return fmt.Errorf("AUTH_TOKEN_SECRET is required")
 	}
+	if err := c.MatchingShards.Validate(); err != nil {
+		return fmt.Errorf("invalid MATCHING_SHARD_COUNT/MATCHING_SHARDS: %w", err)
+	}
 	if len(c.AuthTokenSecret) < envconfig.MinSecretLength {
 		return fmt.Errorf("AUTH_TOKEN_SECRET must be at least %d characters", envconfig.MinSecretLength)
 	}
@@ -102,6 +120,9 @@ func (c *Config) Validate() error {
 		if envconfig.IsInsecureDevSecret(c.AdminToken) {
 			return fmt.Errorf("ADMIN_TOKEN must not be a dev placeholder (APP_ENV=%s)", c.AppEnv)
 		}
+		if c.RedisPassword == "" {
+			return fmt.Errorf("REDIS_PASSWORD is required (APP_ENV=%s)", c.AppEnv)
+		}
 		if c.DBPassword == "" || c.DBPassword == "exchange123" {
 			return fmt.Errorf("DB_PASSWORD must be explicitly set (APP_ENV=%s)", c.AppEnv)
 		}
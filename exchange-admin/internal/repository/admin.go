@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/exchange/common/pkg/validate"
 	"github.com/lib/pq"
 )
 
@@ -23,22 +24,23 @@ func NewAdminRepository(db *sql.DB) *AdminRepository {
 
 // SymbolConfig 交易对配置
 type SymbolConfig struct {
-	Symbol         string  `json:"symbol"`
-	BaseAsset      string  `json:"baseAsset"`
-	QuoteAsset     string  `json:"quoteAsset"`
-	PriceTick      int64   `json:"priceTick"`
-	QtyStep        int64   `json:"qtyStep"`
-	PricePrecision int     `json:"pricePrecision"`
-	QtyPrecision   int     `json:"qtyPrecision"`
-	MinQty         int64   `json:"minQty"`
-	MaxQty         int64   `json:"maxQty"`
-	MinNotional    int64   `json:"minNotional"`
-	PriceLimitRate float64 `json:"priceLimitRate"`
-	MakerFeeRate   float64 `json:"makerFeeRate"`
-	TakerFeeRate   float64 `json:"takerFeeRate"`
-	Status         int     `json:"status"` // 1=TRADING, 2=HALT, 3=CANCEL_ONLY
-	CreatedAtMs    int64   `json:"createdAtMs"`
-	UpdatedAtMs    int64   `json:"updatedAtMs"`
+	Symbol         string              `json:"symbol"`
+	BaseAsset      string              `json:"baseAsset"`
+	QuoteAsset     string              `json:"quoteAsset"`
+	PriceTick      int64               `json:"priceTick"`
+	QtyStep        int64               `json:"qtyStep"`
+	PricePrecision int                 `json:"pricePrecision"`
+	QtyPrecision   int                 `json:"qtyPrecision"`
+	MinQty         int64               `json:"minQty"`
+	MaxQty         int64               `json:"maxQty"`
+	MinNotional    int64               `json:"minNotional"`
+	PriceLimitRate float64             `json:"priceLimitRate"`
+	MakerFeeRate   float64             `json:"makerFeeRate"`
+	TakerFeeRate   float64             `json:"takerFeeRate"`
+	Status         int                 `json:"status"`    // 1=TRADING, 2=HALT, 3=CANCEL_ONLY
+	TickBands      []validate.TickBand `json:"tickBands"` // 价格分档（按 minPrice 升序），为空时不分档
+	CreatedAtMs    int64               `json:"createdAtMs"`
+	UpdatedAtMs    int64               `json:"updatedAtMs"`
 }
 
 // ListSymbolConfigs 列出所有交易对
@@ -46,7 +48,7 @@ func (r *AdminRepository) ListSymbolConfigs(ctx context.Context) ([]*SymbolConfi
 	query := `
 		SELECT symbol, base_asset, quote_asset, price_tick, qty_step,
 		       price_precision, qty_precision, min_qty, max_qty, min_notional,
-		       price_limit_rate, maker_fee_rate, taker_fee_rate, status,
+		       price_limit_rate, maker_fee_rate, taker_fee_rate, status, tick_bands,
 		       created_at_ms, updated_at_ms
 		FROM exchange_order.symbol_configs
 		ORDER BY symbol
@@ -60,14 +62,18 @@ func (r *AdminRepository) ListSymbolConfigs(ctx context.Context) ([]*SymbolConfi
 	var configs []*SymbolConfig
 	for rows.Next() {
 		var c SymbolConfig
+		var tickBands []byte
 		if err := rows.Scan(
 			&c.Symbol, &c.BaseAsset, &c.QuoteAsset, &c.PriceTick, &c.QtyStep,
 			&c.PricePrecision, &c.QtyPrecision, &c.MinQty, &c.MaxQty, &c.MinNotional,
-			&c.PriceLimitRate, &c.MakerFeeRate, &c.TakerFeeRate, &c.Status,
+			&c.PriceLimitRate, &c.MakerFeeRate, &c.TakerFeeRate, &c.Status, &tickBands,
 			&c.CreatedAtMs, &c.UpdatedAtMs,
 		); err != nil {
 			return nil, fmt.Errorf("scan symbol: %w", err)
 		}
+		if err := json.Unmarshal(tickBands, &c.TickBands); err != nil {
+			return nil, fmt.Errorf("parse tick bands %s: %w", c.Symbol, err)
+		}
 		configs = append(configs, &c)
 	}
 	return configs, nil
@@ -78,16 +84,17 @@ func (r *AdminRepository) GetSymbolConfig(ctx context.Context, symbol string) (*
 	query := `
 		SELECT symbol, base_asset, quote_asset, price_tick, qty_step,
 		       price_precision, qty_precision, min_qty, max_qty, min_notional,
-		       price_limit_rate, maker_fee_rate, taker_fee_rate, status,
+		       price_limit_rate, maker_fee_rate, taker_fee_rate, status, tick_bands,
 		       created_at_ms, updated_at_ms
 		FROM exchange_order.symbol_configs
 		WHERE symbol = $1
 	`
 	var c SymbolConfig
+	var tickBands []byte
 	err := r.db.QueryRowContext(ctx, query, symbol).Scan(
 		&c.Symbol, &c.BaseAsset, &c.QuoteAsset, &c.PriceTick, &c.QtyStep,
 		&c.PricePrecision, &c.QtyPrecision, &c.MinQty, &c.MaxQty, &c.MinNotional,
-		&c.PriceLimitRate, &c.MakerFeeRate, &c.TakerFeeRate, &c.Status,
+		&c.PriceLimitRate, &c.MakerFeeRate, &c.TakerFeeRate, &c.Status, &tickBands,
 		&c.CreatedAtMs, &c.UpdatedAtMs,
 	)
 	if err == sql.ErrNoRows {
@@ -96,6 +103,9 @@ func (r *AdminRepository) GetSymbolConfig(ctx context.Context, symbol string) (*
 	if err != nil {
 		return nil, fmt.Errorf("get symbol: %w", err)
 	}
+	if err := json.Unmarshal(tickBands, &c.TickBands); err != nil {
+		return nil, fmt.Errorf("parse tick bands %s: %w", c.Symbol, err)
+	}
 	return &c, nil
 }
 
@@ -104,19 +114,23 @@ func (r *AdminRepository) CreateSymbolConfig(ctx context.Context, c *SymbolConfi
 	now := time.Now().UnixMilli()
 	c.CreatedAtMs = now
 	c.UpdatedAtMs = now
+	tickBands, err := marshalTickBands(c.TickBands)
+	if err != nil {
+		return err
+	}
 
 	query := `
 		INSERT INTO exchange_order.symbol_configs
 		(symbol, base_asset, quote_asset, price_tick, qty_step,
 		 price_precision, qty_precision, min_qty, max_qty, min_notional,
-		 price_limit_rate, maker_fee_rate, taker_fee_rate, status,
+		 price_limit_rate, maker_fee_rate, taker_fee_rate, status, tick_bands,
 		 created_at_ms, updated_at_ms)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = r.db.ExecContext(ctx, query,
 		c.Symbol, c.BaseAsset, c.QuoteAsset, c.PriceTick, c.QtyStep,
 		c.PricePrecision, c.QtyPrecision, c.MinQty, c.MaxQty, c.MinNotional,
-		c.PriceLimitRate, c.MakerFeeRate, c.TakerFeeRate, c.Status,
+		c.PriceLimitRate, c.MakerFeeRate, c.TakerFeeRate, c.Status, tickBands,
 		c.CreatedAtMs, c.UpdatedAtMs,
 	)
 	return err
@@ -125,19 +139,24 @@ func (r *AdminRepository) CreateSymbolConfig(ctx context.Context, c *SymbolConfi
 // UpdateSymbolConfig 更新交易对
 func (r *AdminRepository) UpdateSymbolConfig(ctx context.Context, c *SymbolConfig) error {
 	c.UpdatedAtMs = time.Now().UnixMilli()
+	tickBands, err := marshalTickBands(c.TickBands)
+	if err != nil {
+		return err
+	}
 
 	query := `
 		UPDATE exchange_order.symbol_configs
 		SET price_tick = $1, qty_step = $2, price_precision = $3, qty_precision = $4,
 		    min_qty = $5, max_qty = $6, min_notional = $7, price_limit_rate = $8,
-		    maker_fee_rate = $9, taker_fee_rate = $10, status = $11, updated_at_ms = $12
-		WHERE symbol = $13
+		    maker_fee_rate = $9, taker_fee_rate = $10, status = $11, tick_bands = $12,
+		    updated_at_ms = $13
+		WHERE symbol = $14
 	`
 	result, err := r.db.ExecContext(ctx, query,
 		c.PriceTick, c.QtyStep, c.PricePrecision, c.QtyPrecision,
 		c.MinQty, c.MaxQty, c.MinNotional, c.PriceLimitRate,
-		c.MakerFeeRate, c.TakerFeeRate, c.Status, c.UpdatedAtMs,
-		c.Symbol,
+		c.MakerFeeRate, c.TakerFeeRate, c.Status, tickBands,
+		c.UpdatedAtMs, c.Symbol,
 	)
 	if err != nil {
 		return err
@@ -149,6 +168,18 @@ func (r *AdminRepository) UpdateSymbolConfig(ctx context.Context, c *SymbolConfi
 	return nil
 }
 
+// marshalTickBands 价格分档写入 JSONB（空值写为空数组）
+func marshalTickBands(bands []validate.TickBand) (string, error) {
+	if len(bands) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(bands)
+	if err != nil {
+		return "", fmt.Errorf("marshal tick bands: %w", err)
+	}
+	return string(data), nil
+}
+
 // UpdateSymbolStatus 更新交易对状态（Kill Switch）
 func (r *AdminRepository) UpdateSymbolStatus(ctx context.Context, symbol string, status int) error {
 	query := `
@@ -337,3 +368,56 @@ func (r *AdminRepository) RemoveUserRole(ctx context.Context, userID, roleID int
 	_, err := r.db.ExecContext(ctx, query, userID, roleID)
 	return err
 }
+
+// UserRiskSettings 用户风控设置（订单服务下单前风控读取）
+type UserRiskSettings struct {
+	UserID          int64  `json:"userId"`
+	Tier            string `json:"tier"`            // 账户等级，空表示使用全局限额
+	TradingDisabled bool   `json:"tradingDisabled"` // 禁止新下单（撤单不受影响）
+	Reason          string `json:"reason,omitempty"`
+	UpdatedBy       int64  `json:"updatedBy"`
+	UpdatedAtMs     int64  `json:"updatedAtMs"`
+}
+
+// GetUserRiskSettings 获取用户风控设置，用户没有设置时返回零值设置
+func (r *AdminRepository) GetUserRiskSettings(ctx context.Context, userID int64) (*UserRiskSettings, error) {
+	query := `
+		SELECT user_id, tier, trading_disabled, reason, updated_by, updated_at_ms
+		FROM exchange_order.user_risk_settings
+		WHERE user_id = $1
+	`
+	var s UserRiskSettings
+	var reason sql.NullString
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&s.UserID, &s.Tier, &s.TradingDisabled, &reason, &s.UpdatedBy, &s.UpdatedAtMs,
+	)
+	if err == sql.ErrNoRows {
+		return &UserRiskSettings{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Reason = reason.String
+	return &s, nil
+}
+
+// UpsertUserRiskSettings 写入用户风控设置
+func (r *AdminRepository) UpsertUserRiskSettings(ctx context.Context, s *UserRiskSettings) error {
+	s.UpdatedAtMs = time.Now().UnixMilli()
+
+	query := `
+		INSERT INTO exchange_order.user_risk_settings
+		(user_id, tier, trading_disabled, reason, updated_by, updated_at_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			tier = EXCLUDED.tier,
+			trading_disabled = EXCLUDED.trading_disabled,
+			reason = EXCLUDED.reason,
+			updated_by = EXCLUDED.updated_by,
+			updated_at_ms = EXCLUDED.updated_at_ms
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		s.UserID, s.Tier, s.TradingDisabled, s.Reason, s.UpdatedBy, s.UpdatedAtMs,
+	)
+	return err
+}
@@ -157,6 +157,28 @@ func TestRoleJSON(t *testing.T) {
 	}
 }
 
+func TestUserRiskSettingsJSON(t *testing.T) {
+	s := &UserRiskSettings{
+		UserID:          200,
+		Tier:            "vip",
+		TradingDisabled: true,
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	var decoded UserRiskSettings
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	if !decoded.TradingDisabled || decoded.Tier != "vip" {
+		t.Fatalf("unexpected decoded settings: %+v", decoded)
+	}
+}
+
 func TestNewAdminRepository(t *testing.T) {
 	repo := NewAdminRepository(nil)
 	if repo == nil {
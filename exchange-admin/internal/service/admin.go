@@ -4,16 +4,20 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/exchange/admin/internal/repository"
+	commonerrors "github.com/exchange/common/pkg/errors"
+	"github.com/exchange/common/pkg/validate"
 )
 
 // AdminService 后台服务
 type AdminService struct {
-	repo  AdminRepository
-	idGen IDGenerator
+	repo      AdminRepository
+	idGen     IDGenerator
+	publisher StatusPublisher
 }
 
 // IDGenerator ID 生成器接口
@@ -29,6 +33,11 @@ func NewAdminService(repo AdminRepository, idGen IDGenerator) *AdminService {
 	}
 }
 
+// SetStatusPublisher 设置交易对状态变更通知（未设置时只更新数据库）
+func (s *AdminService) SetStatusPublisher(publisher StatusPublisher) {
+	s.publisher = publisher
+}
+
 // ========== 交易对管理 ==========
 
 // ListSymbols 列出交易对
@@ -46,6 +55,9 @@ func (s *AdminService) CreateSymbol(ctx context.Context, actorID int64, ip strin
 	if cfg.Status == 0 {
 		cfg.Status = 2 // 默认 HALT
 	}
+	if err := validate.TickBands(cfg.TickBands); err != nil {
+		return err
+	}
 
 	if err := s.repo.CreateSymbolConfig(ctx, cfg); err != nil {
 		return err
@@ -68,6 +80,10 @@ func (s *AdminService) CreateSymbol(ctx context.Context, actorID int64, ip strin
 
 // UpdateSymbol 更新交易对
 func (s *AdminService) UpdateSymbol(ctx context.Context, actorID int64, ip string, cfg *repository.SymbolConfig) error {
+	if err := validate.TickBands(cfg.TickBands); err != nil {
+		return err
+	}
+
 	// 获取旧配置
 	before, _ := s.repo.GetSymbolConfig(ctx, cfg.Symbol)
 	beforeJSON, _ := json.Marshal(before)
@@ -75,6 +91,11 @@ func (s *AdminService) UpdateSymbol(ctx context.Context, actorID int64, ip strin
 	if err := s.repo.UpdateSymbolConfig(ctx, cfg); err != nil {
 		return err
 	}
+	if before != nil && before.Status != cfg.Status {
+		if err := s.publishStatus(ctx, cfg.Symbol, cfg.Status); err != nil {
+			return err
+		}
+	}
 
 	// 审计日志
 	afterJSON, _ := json.Marshal(cfg)
@@ -99,6 +120,7 @@ const (
 	StatusTrading    = 1
 	StatusHalt       = 2
 	StatusCancelOnly = 3
+	StatusAuction    = 4 // 集合竞价：只挂单不撮合，切换到 TRADING 时统一撮合
 )
 
 // SetSymbolStatus 设置交易对状态
@@ -108,6 +130,9 @@ func (s *AdminService) SetSymbolStatus(ctx context.Context, actorID int64, ip, s
 	if err := s.repo.UpdateSymbolStatus(ctx, symbol, status); err != nil {
 		return err
 	}
+	if err := s.publishStatus(ctx, symbol, status); err != nil {
+		return err
+	}
 
 	// 审计日志
 	beforeStatus := 0
@@ -177,6 +202,18 @@ func (s *AdminService) GlobalResume(ctx context.Context, actorID int64, ip strin
 	if err := s.repo.UpdateAllSymbolStatus(ctx, StatusTrading); err != nil {
 		return err
 	}
+	// 处于集合竞价（或竞价中被暂停）的交易对需要通知撮合引擎统一撮合
+	if s.publisher != nil {
+		symbols, err := s.repo.ListSymbolConfigs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, sym := range symbols {
+			if err := s.publishStatus(ctx, sym.Symbol, StatusTrading); err != nil {
+				return err
+			}
+		}
+	}
 
 	// 审计日志
 	afterJSON, _ := json.Marshal(map[string]interface{}{"status": StatusTrading, "scope": "ALL"})
@@ -193,6 +230,17 @@ func (s *AdminService) GlobalResume(ctx context.Context, actorID int64, ip strin
 	return nil
 }
 
+// publishStatus 通知撮合引擎状态变更，数据库已更新，失败时由调用方重试（重复通知无副作用）
+func (s *AdminService) publishStatus(ctx context.Context, symbol string, status int) error {
+	if s.publisher == nil {
+		return nil
+	}
+	if err := s.publisher.PublishSymbolStatus(ctx, symbol, status); err != nil {
+		return fmt.Errorf("publish symbol status: %w", err)
+	}
+	return nil
+}
+
 // ========== 审计日志 ==========
 
 // ListAuditLogs 查询审计日志
@@ -257,6 +305,50 @@ func (s *AdminService) RemoveRole(ctx context.Context, actorID int64, ip string,
 	return nil
 }
 
+// ========== 用户风控 ==========
+
+// GetUserRisk 获取用户风控设置
+func (s *AdminService) GetUserRisk(ctx context.Context, userID int64) (*repository.UserRiskSettings, error) {
+	return s.repo.GetUserRiskSettings(ctx, userID)
+}
+
+// SetUserRisk 设置用户账户等级与禁止交易开关，订单服务下一笔下单即生效
+func (s *AdminService) SetUserRisk(ctx context.Context, actorID int64, ip string, settings *repository.UserRiskSettings) error {
+	if settings.UserID <= 0 {
+		return commonerrors.New(commonerrors.CodeInvalidParam, "userId required")
+	}
+	if len(settings.Tier) > 32 {
+		return commonerrors.Newf(commonerrors.CodeInvalidParam, "tier too long: %q", settings.Tier)
+	}
+	if len(settings.Reason) > 255 {
+		return commonerrors.New(commonerrors.CodeInvalidParam, "reason too long (max 255)")
+	}
+
+	// 获取旧设置
+	before, _ := s.repo.GetUserRiskSettings(ctx, settings.UserID)
+	beforeJSON, _ := json.Marshal(before)
+
+	settings.UpdatedBy = actorID
+	if err := s.repo.UpsertUserRiskSettings(ctx, settings); err != nil {
+		return err
+	}
+
+	// 审计日志
+	afterJSON, _ := json.Marshal(settings)
+	s.repo.CreateAuditLog(ctx, &repository.AuditLog{
+		AuditID:     s.idGen.NextID(),
+		ActorUserID: actorID,
+		Action:      "SET_USER_RISK",
+		TargetType:  "USER_RISK",
+		TargetID:    strconv.FormatInt(settings.UserID, 10),
+		BeforeJSON:  beforeJSON,
+		AfterJSON:   afterJSON,
+		IP:          ip,
+	})
+
+	return nil
+}
+
 // ========== 系统状态 ==========
 
 // SystemStatus 系统状态
@@ -264,6 +356,7 @@ type SystemStatus struct {
 	TradingSymbols    int   `json:"tradingSymbols"`
 	HaltedSymbols     int   `json:"haltedSymbols"`
 	CancelOnlySymbols int   `json:"cancelOnlySymbols"`
+	AuctionSymbols    int   `json:"auctionSymbols"`
 	ServerTimeMs      int64 `json:"serverTimeMs"`
 }
 
@@ -286,6 +379,8 @@ func (s *AdminService) GetSystemStatus(ctx context.Context) (*SystemStatus, erro
 			status.HaltedSymbols++
 		case StatusCancelOnly:
 			status.CancelOnlySymbols++
+		case StatusAuction:
+			status.AuctionSymbols++
 		}
 	}
 
@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/exchange/admin/internal/repository"
+	commonerrors "github.com/exchange/common/pkg/errors"
+	"github.com/exchange/common/pkg/validate"
 )
 
 // ========== 测试辅助工具 ==========
@@ -20,6 +23,24 @@ func (m *mockIDGenerator) NextID() int64 {
 	return m.nextID
 }
 
+type publishedStatus struct {
+	symbol string
+	status int
+}
+
+type mockStatusPublisher struct {
+	published []publishedStatus
+	err       error
+}
+
+func (m *mockStatusPublisher) PublishSymbolStatus(ctx context.Context, symbol string, status int) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.published = append(m.published, publishedStatus{symbol: symbol, status: status})
+	return nil
+}
+
 // ========== 常量测试 ==========
 
 func TestStatusConstants(t *testing.T) {
@@ -32,6 +53,9 @@ func TestStatusConstants(t *testing.T) {
 	if StatusCancelOnly != 3 {
 		t.Fatalf("expected StatusCancelOnly=3, got %d", StatusCancelOnly)
 	}
+	if StatusAuction != 4 {
+		t.Fatalf("expected StatusAuction=4, got %d", StatusAuction)
+	}
 }
 
 // ========== 交易对管理测试 ==========
@@ -179,6 +203,47 @@ func TestCreateSymbol_WithStatus(t *testing.T) {
 	}
 }
 
+func TestCreateSymbol_InvalidTickBands(t *testing.T) {
+	created := false
+	mockRepo := &mockRepository{
+		createSymbolConfigFunc: func(ctx context.Context, cfg *repository.SymbolConfig) error {
+			created = true
+			return nil
+		},
+		updateSymbolConfigFunc: func(ctx context.Context, cfg *repository.SymbolConfig) error {
+			created = true
+			return nil
+		},
+	}
+	svc := NewAdminService(mockRepo, &mockIDGenerator{})
+
+	invalid := [][]validate.TickBand{
+		{{MinPrice: 100, PriceTick: 10}, {MinPrice: 100, PriceTick: 20}}, // minPrice 未递增
+		{{MinPrice: 100, PriceTick: 0}},                                  // tick 为 0
+		{{MinPrice: 105, PriceTick: 10}},                                 // 分档起点不在本档网格上
+		{{MinPrice: 100, PriceTick: 10, QtyStep: -1}},
+	}
+	for i, bands := range invalid {
+		err := svc.CreateSymbol(context.Background(), 100, "192.168.1.1", &repository.SymbolConfig{Symbol: "DOGEUSDT", TickBands: bands})
+		var apiErr *commonerrors.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != commonerrors.CodeInvalidParam {
+			t.Fatalf("case %d: expected INVALID_PARAM, got %v", i, err)
+		}
+		err = svc.UpdateSymbol(context.Background(), 100, "192.168.1.1", &repository.SymbolConfig{Symbol: "DOGEUSDT", TickBands: bands})
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("case %d: expected update to fail, got %v", i, err)
+		}
+	}
+	if created {
+		t.Fatal("invalid tick bands must not be written")
+	}
+
+	bands := []validate.TickBand{{MinPrice: 100, PriceTick: 10}, {MinPrice: 1000, PriceTick: 100, QtyStep: 5}}
+	if err := svc.CreateSymbol(context.Background(), 100, "192.168.1.1", &repository.SymbolConfig{Symbol: "DOGEUSDT", TickBands: bands}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestCreateSymbol_RepositoryError(t *testing.T) {
 	mockRepo := &mockRepository{
 		createSymbolConfigFunc: func(ctx context.Context, cfg *repository.SymbolConfig) error {
@@ -284,6 +349,35 @@ func TestSetSymbolStatus_Success(t *testing.T) {
 	}
 }
 
+func TestSetSymbolStatus_PublishesToMatching(t *testing.T) {
+	mockRepo := &mockRepository{
+		getSymbolConfigFunc: func(ctx context.Context, symbol string) (*repository.SymbolConfig, error) {
+			return &repository.SymbolConfig{Symbol: symbol, Status: StatusTrading}, nil
+		},
+		updateSymbolStatusFunc: func(ctx context.Context, symbol string, status int) error {
+			return nil
+		},
+		createAuditLogFunc: func(ctx context.Context, log *repository.AuditLog) error {
+			return nil
+		},
+	}
+	publisher := &mockStatusPublisher{}
+	svc := NewAdminService(mockRepo, &mockIDGenerator{})
+	svc.SetStatusPublisher(publisher)
+
+	if err := svc.SetSymbolStatus(context.Background(), 100, "192.168.1.1", "BTCUSDT", StatusAuction); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(publisher.published) != 1 || publisher.published[0] != (publishedStatus{symbol: "BTCUSDT", status: StatusAuction}) {
+		t.Fatalf("unexpected published status: %+v", publisher.published)
+	}
+
+	publisher.err = fmt.Errorf("redis down")
+	if err := svc.SetSymbolStatus(context.Background(), 100, "192.168.1.1", "BTCUSDT", StatusTrading); err == nil {
+		t.Fatal("expected publish error, got nil")
+	}
+}
+
 func TestSetSymbolStatus_Error(t *testing.T) {
 	mockRepo := &mockRepository{
 		getSymbolConfigFunc: func(ctx context.Context, symbol string) (*repository.SymbolConfig, error) {
@@ -410,6 +504,35 @@ func TestGlobalResume_Success(t *testing.T) {
 	}
 }
 
+func TestGlobalResume_PublishesAllSymbols(t *testing.T) {
+	mockRepo := &mockRepository{
+		updateAllSymbolStatusFunc: func(ctx context.Context, status int) error {
+			return nil
+		},
+		listSymbolConfigsFunc: func(ctx context.Context) ([]*repository.SymbolConfig, error) {
+			return []*repository.SymbolConfig{{Symbol: "BTCUSDT"}, {Symbol: "ETHUSDT"}}, nil
+		},
+		createAuditLogFunc: func(ctx context.Context, log *repository.AuditLog) error {
+			return nil
+		},
+	}
+	publisher := &mockStatusPublisher{}
+	svc := NewAdminService(mockRepo, &mockIDGenerator{})
+	svc.SetStatusPublisher(publisher)
+
+	if err := svc.GlobalResume(context.Background(), 100, "192.168.1.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(publisher.published) != 2 {
+		t.Fatalf("expected 2 published statuses, got %+v", publisher.published)
+	}
+	for _, p := range publisher.published {
+		if p.status != StatusTrading {
+			t.Fatalf("expected TRADING, got %+v", p)
+		}
+	}
+}
+
 func TestGlobalResume_Error(t *testing.T) {
 	mockRepo := &mockRepository{
 		updateAllSymbolStatusFunc: func(ctx context.Context, status int) error {
@@ -641,6 +764,71 @@ func TestRemoveRole_Error(t *testing.T) {
 	}
 }
 
+// ========== 用户风控测试 ==========
+
+func TestSetUserRisk_Success(t *testing.T) {
+	var captured *repository.UserRiskSettings
+	var auditLog *repository.AuditLog
+
+	mockRepo := &mockRepository{
+		getUserRiskSettingsFunc: func(ctx context.Context, userID int64) (*repository.UserRiskSettings, error) {
+			return &repository.UserRiskSettings{UserID: userID}, nil
+		},
+		upsertUserRiskSettingsFunc: func(ctx context.Context, s *repository.UserRiskSettings) error {
+			captured = s
+			return nil
+		},
+		createAuditLogFunc: func(ctx context.Context, log *repository.AuditLog) error {
+			auditLog = log
+			return nil
+		},
+	}
+	svc := NewAdminService(mockRepo, &mockIDGenerator{})
+
+	err := svc.SetUserRisk(context.Background(), 100, "192.168.1.1", &repository.UserRiskSettings{
+		UserID:          200,
+		Tier:            "vip",
+		TradingDisabled: true,
+		Reason:          "abusive order rate",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if captured == nil || captured.UserID != 200 || !captured.TradingDisabled || captured.Tier != "vip" {
+		t.Fatalf("unexpected settings: %+v", captured)
+	}
+	if captured.UpdatedBy != 100 {
+		t.Fatalf("expected UpdatedBy=100, got %d", captured.UpdatedBy)
+	}
+	if auditLog == nil || auditLog.Action != "SET_USER_RISK" || auditLog.TargetID != "200" {
+		t.Fatalf("unexpected audit log: %+v", auditLog)
+	}
+}
+
+func TestSetUserRisk_InvalidUser(t *testing.T) {
+	svc := NewAdminService(&mockRepository{}, &mockIDGenerator{})
+
+	err := svc.SetUserRisk(context.Background(), 100, "192.168.1.1", &repository.UserRiskSettings{TradingDisabled: true})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestSetUserRisk_Error(t *testing.T) {
+	mockRepo := &mockRepository{
+		upsertUserRiskSettingsFunc: func(ctx context.Context, s *repository.UserRiskSettings) error {
+			return fmt.Errorf("database error")
+		},
+	}
+	svc := NewAdminService(mockRepo, &mockIDGenerator{})
+
+	err := svc.SetUserRisk(context.Background(), 100, "192.168.1.1", &repository.UserRiskSettings{UserID: 200})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
 // ========== 系统状态测试 ==========
 
 func TestGetSystemStatus_Success(t *testing.T) {
@@ -26,6 +26,10 @@ type mockRepository struct {
 	getUserRolesFunc   func(ctx context.Context, userID int64) ([]int64, error)
 	assignUserRoleFunc func(ctx context.Context, userID, roleID int64) error
 	removeUserRoleFunc func(ctx context.Context, userID, roleID int64) error
+
+	// 用户风控
+	getUserRiskSettingsFunc    func(ctx context.Context, userID int64) (*repository.UserRiskSettings, error)
+	upsertUserRiskSettingsFunc func(ctx context.Context, s *repository.UserRiskSettings) error
 }
 
 func (m *mockRepository) ListSymbolConfigs(ctx context.Context) ([]*repository.SymbolConfig, error) {
@@ -111,3 +115,17 @@ func (m *mockRepository) RemoveUserRole(ctx context.Context, userID, roleID int6
 	}
 	return fmt.Errorf("not implemented")
 }
+
+func (m *mockRepository) GetUserRiskSettings(ctx context.Context, userID int64) (*repository.UserRiskSettings, error) {
+	if m.getUserRiskSettingsFunc != nil {
+		return m.getUserRiskSettingsFunc(ctx, userID)
+	}
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockRepository) UpsertUserRiskSettings(ctx context.Context, s *repository.UserRiskSettings) error {
+	if m.upsertUserRiskSettingsFunc != nil {
+		return m.upsertUserRiskSettingsFunc(ctx, s)
+	}
+	return fmt.Errorf("not implemented")
+}
@@ -7,6 +7,13 @@ import (
 	"github.com/exchange/admin/internal/repository"
 )
 
+// StatusPublisher 交易对状态变更通知
+//
+// 状态写入订单流，撮合引擎与订单按同一顺序处理（进入集合竞价、恢复交易时撮合）。
+type StatusPublisher interface {
+	PublishSymbolStatus(ctx context.Context, symbol string, status int) error
+}
+
 // AdminRepository 后台仓储接口
 type AdminRepository interface {
 	// 交易对管理
@@ -26,4 +33,8 @@ type AdminRepository interface {
 	GetUserRoles(ctx context.Context, userID int64) ([]int64, error)
 	AssignUserRole(ctx context.Context, userID, roleID int64) error
 	RemoveUserRole(ctx context.Context, userID, roleID int64) error
+
+	// 用户风控
+	GetUserRiskSettings(ctx context.Context, userID int64) (*repository.UserRiskSettings, error)
+	UpsertUserRiskSettings(ctx context.Context, s *repository.UserRiskSettings) error
 }
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/exchange/common/pkg/shard"
+	"github.com/redis/go-redis/v9"
+)
+
+// statusMessage 订单流中的交易对状态变更消息（与撮合 OrderMessage 字段一致）
+type statusMessage struct {
+	Type   string `json:"type"` // SET_STATUS
+	Symbol string `json:"symbol"`
+	Status string `json:"status"` // TRADING / HALT / CANCEL_ONLY / AUCTION
+}
+
+// RedisStatusPublisher 将交易对状态变更写入订单流
+type RedisStatusPublisher struct {
+	client      redis.Cmdable
+	orderStream string
+	shards      *shard.Router // 撮合分片路由，为空时写入 orderStream
+}
+
+// NewRedisStatusPublisher 创建状态变更发布器，消息写入交易对所属撮合分片的订单流
+func NewRedisStatusPublisher(client redis.Cmdable, orderStream string, shards *shard.Router) *RedisStatusPublisher {
+	return &RedisStatusPublisher{client: client, orderStream: orderStream, shards: shards}
+}
+
+// PublishSymbolStatus 发布交易对状态
+func (p *RedisStatusPublisher) PublishSymbolStatus(ctx context.Context, symbol string, status int) error {
+	name := statusName(status)
+	if name == "" {
+		return fmt.Errorf("invalid symbol status: %d", status)
+	}
+	data, err := json.Marshal(&statusMessage{Type: "SET_STATUS", Symbol: symbol, Status: name})
+	if err != nil {
+		return err
+	}
+	stream := p.orderStream
+	if p.shards != nil {
+		if stream, err = p.shards.Stream(ctx, p.orderStream, symbol); err != nil {
+			return err
+		}
+	}
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		Values: map[string]interface{}{
+			"data": string(data),
+		},
+	}).Err()
+}
+
+func statusName(status int) string {
+	switch status {
+	case StatusTrading:
+		return "TRADING"
+	case StatusHalt:
+		return "HALT"
+	case StatusCancelOnly:
+		return "CANCEL_ONLY"
+	case StatusAuction:
+		return "AUCTION"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/exchange/clearing/internal/config"
+	"github.com/exchange/common/pkg/eventseq"
+	"github.com/redis/go-redis/v9"
+)
+
+// checkEventSeq 检查新投递事件的序列号连续性（pending 重试的事件不参与检查）
+//
+// 同组的清算实例分摊事件，序列号记录按消费者组保存在 Redis。
+// 结算按成交 ID 幂等，重复事件仍然处理；缺口意味着可能漏结算，上报并发起重同步请求。
+func checkEventSeq(ctx context.Context, redisClient *redis.Client, cfg *config.Config, tracker *eventseq.GroupTracker, msg redis.XMessage) eventseq.Result {
+	data, ok := msg.Values["data"].(string)
+	if !ok {
+		return eventseq.Result{Status: eventseq.StatusOK}
+	}
+	header, err := eventseq.ParseHeader(data)
+	if err != nil {
+		return eventseq.Result{Status: eventseq.StatusOK}
+	}
+	res, err := tracker.Observe(ctx, header.Symbol, header.Seq, msg.ID)
+	if err != nil {
+		log.Printf("Check event seq error: %v", err)
+		return res
+	}
+	switch res.Status {
+	case eventseq.StatusDuplicate:
+		eventSeqDuplicates.WithLabelValues(cfg.EventStream, cfg.ConsumerGroup, header.Symbol).Inc()
+		log.Printf("Event seq duplicate: symbol=%s last=%d got=%d msgId=%s", header.Symbol, res.Last, header.Seq, msg.ID)
+	case eventseq.StatusGap:
+		eventSeqGaps.WithLabelValues(cfg.EventStream, cfg.ConsumerGroup, header.Symbol).Inc()
+		log.Printf("Event seq gap: symbol=%s last=%d got=%d missing=%d msgId=%s", header.Symbol, res.Last, header.Seq, res.Missing, msg.ID)
+		req := eventseq.ResyncRequest{
+			Stream: cfg.EventStream, Group: cfg.ConsumerGroup, Consumer: cfg.ConsumerName,
+			Symbol: header.Symbol, LastSeq: res.Last, GotSeq: header.Seq, MsgID: msg.ID,
+		}
+		if err := redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: eventseq.ResyncStream(cfg.EventStream),
+			Values: req.Values(),
+		}).Err(); err != nil {
+			log.Printf("Request resync error: %v", err)
+		}
+	case eventseq.StatusReset:
+		log.Printf("Event seq reset: symbol=%s last=%d", header.Symbol, res.Last)
+	}
+	return res
+}
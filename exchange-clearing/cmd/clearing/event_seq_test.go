@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/exchange/clearing/internal/config"
+	"github.com/exchange/common/pkg/eventseq"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCheckEventSeqRequestsResyncOnGap(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	ctx := context.Background()
+	cfg := &config.Config{EventStream: "exchange:events", ConsumerGroup: "clearing-group", ConsumerName: "clearing-1"}
+	// grace 为 0：超前的序列号立即按缺口上报
+	tracker := eventseq.NewGroupTracker(redisClient, cfg.EventStream, cfg.ConsumerGroup, 0)
+	msg := func(id string, seq int64) redis.XMessage {
+		raw, _ := json.Marshal(TradeEvent{Type: "TRADE_CREATED", Symbol: "BTCUSDT", Seq: seq})
+		return redis.XMessage{ID: id, Values: map[string]interface{}{"data": string(raw)}}
+	}
+
+	want := []eventseq.Status{eventseq.StatusFirst, eventseq.StatusGap, eventseq.StatusDuplicate, eventseq.StatusOK}
+	for i, seq := range []int64{3, 6, 6, 7} {
+		if res := checkEventSeq(ctx, redisClient, cfg, tracker, msg("1-"+strconv.Itoa(i), seq)); res.Status != want[i] {
+			t.Fatalf("seq %d: status=%v, want %v", seq, res.Status, want[i])
+		}
+	}
+
+	resync, err := redisClient.XRange(ctx, "exchange:events:resync", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("xrange resync: %v", err)
+	}
+	if len(resync) != 1 || resync[0].Values["lastSeq"] != "3" || resync[0].Values["gotSeq"] != "6" || resync[0].Values["msgId"] != "1-1" {
+		t.Fatalf("unexpected resync requests: %+v", resync)
+	}
+}
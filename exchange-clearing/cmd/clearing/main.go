@@ -22,6 +22,7 @@ import (
 	"github.com/exchange/clearing/internal/service"
 	clearingws "github.com/exchange/clearing/internal/ws"
 	commonerrors "github.com/exchange/common/pkg/errors"
+	"github.com/exchange/common/pkg/eventseq"
 	"github.com/exchange/common/pkg/health"
 	commonredis "github.com/exchange/common/pkg/redis"
 	commonresp "github.com/exchange/common/pkg/response"
@@ -44,10 +45,18 @@ var (
 		Name: "redis_stream_dlq_total",
 		Help: "Total number of messages moved to Redis Stream DLQ.",
 	}, []string{"stream", "group"})
+	eventSeqGaps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "event_seq_gaps_total",
+		Help: "Total number of matching event sequence gaps detected.",
+	}, []string{"stream", "group", "symbol"})
+	eventSeqDuplicates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "event_seq_duplicates_total",
+		Help: "Total number of duplicate or out-of-order matching events received.",
+	}, []string{"stream", "group", "symbol"})
 )
 
 func init() {
-	prometheus.MustRegister(streamPending, streamErrors, streamDLQ)
+	prometheus.MustRegister(streamPending, streamErrors, streamDLQ, eventSeqGaps, eventSeqDuplicates)
 }
 
 func main() {
@@ -265,6 +274,28 @@ func main() {
 		json.NewEncoder(w).Encode(resp)
 	}))
 
+	// 批量冻结（做市报价按资产净额冻结，整批生效或整批失败）
+	mux.HandleFunc("/internal/freeze/batch", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+			return
+		}
+
+		var req service.BatchFreezeRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		resp, err := svc.BatchFreeze(r.Context(), &req)
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
 	// 解冻资金
 	mux.HandleFunc("/internal/unfreeze", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -287,6 +318,28 @@ func main() {
 		json.NewEncoder(w).Encode(resp)
 	}))
 
+	// 批量解冻（批量撤单）
+	mux.HandleFunc("/internal/unfreeze/batch", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+			return
+		}
+
+		var req service.BatchUnfreezeRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		resp, err := svc.BatchUnfreeze(r.Context(), &req)
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
 	// 扣除冻结资金（提现完成）
 	mux.HandleFunc("/internal/deduct", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -505,6 +558,7 @@ func checkConsumeLoop(loop *health.LoopMonitor) dependencyStatus {
 
 func consumeEvents(ctx context.Context, redisClient *redis.Client, svc *service.ClearingService, cfg *config.Config, resolver symbolMetaResolver, loop *health.LoopMonitor) {
 	log.Printf("Consuming events from %s", cfg.EventStream)
+	seqs := eventseq.NewGroupTracker(redisClient, cfg.EventStream, cfg.ConsumerGroup, eventseq.DefaultGroupGrace)
 
 	pendingTicker := time.NewTicker(30 * time.Second)
 	defer pendingTicker.Stop()
@@ -554,6 +608,10 @@ func consumeEvents(ctx context.Context, redisClient *redis.Client, svc *service.
 		}
 
 		for _, result := range results {
+			// 先检查整批序列号再处理，缩短与同组其他实例之间的乱序窗口
+			for _, msg := range result.Messages {
+				checkEventSeq(ctx, redisClient, cfg, seqs, msg)
+			}
 			for _, msg := range result.Messages {
 				processEvent(ctx, redisClient, svc, cfg, resolver, msg)
 			}
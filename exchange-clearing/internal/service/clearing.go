@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -101,6 +102,82 @@ func (s *ClearingService) Freeze(ctx context.Context, req *FreezeRequest) (*Free
 	return &FreezeResponse{Success: true, Balance: balance}, nil
 }
 
+// MaxBatchFreezeItems 单次批量冻结的最大条目数
+const MaxBatchFreezeItems = 16
+
+type BatchFreezeRequest struct {
+	Items []FreezeRequest
+}
+
+type BatchFreezeResponse struct {
+	Success   bool
+	ErrorCode string
+	Applied   int // 本次实际冻结的条目数（已处理过的幂等键不计入）
+}
+
+// BatchFreeze 在同一事务内批量冻结（做市报价按资产净额冻结），任一条目余额不足则整批不生效
+func (s *ClearingService) BatchFreeze(ctx context.Context, req *BatchFreezeRequest) (*BatchFreezeResponse, error) {
+	if req == nil || len(req.Items) == 0 || len(req.Items) > MaxBatchFreezeItems {
+		return &BatchFreezeResponse{Success: false, ErrorCode: "INVALID_PARAM"}, nil
+	}
+	entries := make([]*repository.LedgerEntry, 0, len(req.Items))
+	for i := range req.Items {
+		item := &req.Items[i]
+		if err := validateBalanceMutation(item.IdempotencyKey, item.UserID, item.Asset, item.Amount); err != nil {
+			return &BatchFreezeResponse{Success: false, ErrorCode: "INVALID_PARAM"}, nil
+		}
+		entries = append(entries, &repository.LedgerEntry{
+			LedgerID:       s.idGen.NextID(),
+			IdempotencyKey: item.IdempotencyKey,
+			UserID:         item.UserID,
+			Asset:          item.Asset,
+			AvailableDelta: -item.Amount,
+			FrozenDelta:    item.Amount,
+			Reason:         repository.ReasonOrderFreeze,
+			RefType:        item.RefType,
+			RefID:          item.RefID,
+			CreatedAt:      time.Now().UnixMilli(),
+		})
+	}
+	// 按账户顺序加锁，避免并发批次互相死锁
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].UserID != entries[j].UserID {
+			return entries[i].UserID < entries[j].UserID
+		}
+		return entries[i].Asset < entries[j].Asset
+	})
+
+	var applied []*repository.LedgerEntry
+	err := s.withOptimisticRetry(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		applied = applied[:0]
+		for _, entry := range entries {
+			if err := s.balRepo.Freeze(ctx, tx, entry); err != nil {
+				if err == repository.ErrIdempotencyConflict {
+					continue
+				}
+				return err
+			}
+			applied = append(applied, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == repository.ErrInsufficientBalance {
+			return &BatchFreezeResponse{Success: false, ErrorCode: "INSUFFICIENT_BALANCE"}, nil
+		}
+		return nil, fmt.Errorf("batch freeze: %w", err)
+	}
+
+	if s.publisher != nil {
+		for _, entry := range applied {
+			if pubErr := s.publisher.PublishFrozenEvent(ctx, entry.UserID, entry.Asset, entry.FrozenDelta); pubErr != nil {
+				log.Printf("publish frozen event error: %v", pubErr)
+			}
+		}
+	}
+	return &BatchFreezeResponse{Success: true, Applied: len(applied)}, nil
+}
+
 type UnfreezeRequest struct {
 	IdempotencyKey string
 	UserID         int64
@@ -159,6 +236,79 @@ func (s *ClearingService) Unfreeze(ctx context.Context, req *UnfreezeRequest) (*
 	return &UnfreezeResponse{Success: true, Balance: balance}, nil
 }
 
+// MaxBatchUnfreezeItems 单次批量解冻的最大条目数
+const MaxBatchUnfreezeItems = 500
+
+type BatchUnfreezeRequest struct {
+	Items []UnfreezeRequest
+}
+
+type BatchUnfreezeResponse struct {
+	Success   bool
+	ErrorCode string
+	Applied   int // 本次实际解冻的条目数（已处理过的幂等键不计入）
+}
+
+// BatchUnfreeze 在同一事务内批量解冻（批量撤单），已处理过的条目按幂等跳过
+func (s *ClearingService) BatchUnfreeze(ctx context.Context, req *BatchUnfreezeRequest) (*BatchUnfreezeResponse, error) {
+	if req == nil || len(req.Items) == 0 || len(req.Items) > MaxBatchUnfreezeItems {
+		return &BatchUnfreezeResponse{Success: false, ErrorCode: "INVALID_PARAM"}, nil
+	}
+	entries := make([]*repository.LedgerEntry, 0, len(req.Items))
+	for i := range req.Items {
+		item := &req.Items[i]
+		if err := validateBalanceMutation(item.IdempotencyKey, item.UserID, item.Asset, item.Amount); err != nil {
+			return &BatchUnfreezeResponse{Success: false, ErrorCode: "INVALID_PARAM"}, nil
+		}
+		entries = append(entries, &repository.LedgerEntry{
+			LedgerID:       s.idGen.NextID(),
+			IdempotencyKey: item.IdempotencyKey,
+			UserID:         item.UserID,
+			Asset:          item.Asset,
+			AvailableDelta: item.Amount,
+			FrozenDelta:    -item.Amount,
+			Reason:         repository.ReasonOrderUnfreeze,
+			RefType:        item.RefType,
+			RefID:          item.RefID,
+			CreatedAt:      time.Now().UnixMilli(),
+		})
+	}
+	// 按账户顺序加锁，避免并发批次互相死锁
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].UserID != entries[j].UserID {
+			return entries[i].UserID < entries[j].UserID
+		}
+		return entries[i].Asset < entries[j].Asset
+	})
+
+	var applied []*repository.LedgerEntry
+	err := s.withOptimisticRetry(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		applied = applied[:0]
+		for _, entry := range entries {
+			if err := s.balRepo.Unfreeze(ctx, tx, entry); err != nil {
+				if err == repository.ErrIdempotencyConflict {
+					continue
+				}
+				return err
+			}
+			applied = append(applied, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("batch unfreeze: %w", err)
+	}
+
+	if s.publisher != nil {
+		for _, entry := range applied {
+			if pubErr := s.publisher.PublishUnfrozenEvent(ctx, entry.UserID, entry.Asset, entry.AvailableDelta); pubErr != nil {
+				log.Printf("publish unfrozen event error: %v", pubErr)
+			}
+		}
+	}
+	return &BatchUnfreezeResponse{Success: true, Applied: len(applied)}, nil
+}
+
 type DeductRequest struct {
 	IdempotencyKey string
 	UserID         int64
@@ -475,6 +475,142 @@ func TestClearingServiceUnfreeze_Success(t *testing.T) {
 	}
 }
 
+func TestClearingServiceBatchUnfreeze_SkipsProcessedItems(t *testing.T) {
+	svc, mock, closeFn := newMockService(t, &mockIDGen{})
+	defer closeFn()
+
+	req := &BatchUnfreezeRequest{Items: []UnfreezeRequest{
+		{IdempotencyKey: "unfreeze:order:2", UserID: 9, Asset: "USDT", Amount: 30, RefType: "ORDER", RefID: "unfreeze:order:2"},
+		{IdempotencyKey: "unfreeze:order:1", UserID: 8, Asset: "BTC", Amount: 5, RefType: "ORDER", RefID: "unfreeze:order:1"},
+	}}
+
+	// 按账户顺序处理，已解冻的条目跳过，整批一个事务
+	mock.ExpectBegin()
+	expectCheckIdempotency(mock, "unfreeze:order:1")
+	expectCheckIdempotencyMiss(mock, "unfreeze:order:2")
+	expectBalanceForUpdate(mock, 9, "USDT", 0, 100, 1)
+	expectUpdateBalance(mock, 30, 70, 9, "USDT", 1, 1)
+	expectInsertLedger(mock, &repository.LedgerEntry{
+		IdempotencyKey: "unfreeze:order:2",
+		UserID:         9,
+		Asset:          "USDT",
+		AvailableDelta: 30,
+		FrozenDelta:    -30,
+		AvailableAfter: 30,
+		FrozenAfter:    70,
+		Reason:         repository.ReasonOrderUnfreeze,
+		RefType:        "ORDER",
+		RefID:          "unfreeze:order:2",
+	})
+	mock.ExpectCommit()
+
+	resp, err := svc.BatchUnfreeze(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !resp.Success || resp.Applied != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestClearingServiceBatchFreeze_AllOrNothing(t *testing.T) {
+	svc, mock, closeFn := newMockService(t, &mockIDGen{})
+	defer closeFn()
+
+	req := &BatchFreezeRequest{Items: []FreezeRequest{
+		{IdempotencyKey: "freeze:quote:7:USDT", UserID: 8, Asset: "USDT", Amount: 30, RefType: "QUOTE", RefID: "7"},
+		{IdempotencyKey: "freeze:quote:7:BTC", UserID: 8, Asset: "BTC", Amount: 5, RefType: "QUOTE", RefID: "7"},
+	}}
+
+	// 第二个资产余额不足：整批回滚
+	mock.ExpectBegin()
+	expectCheckIdempotencyMiss(mock, "freeze:quote:7:BTC")
+	expectBalanceForUpdate(mock, 8, "BTC", 10, 0, 1)
+	expectUpdateBalance(mock, 5, 5, 8, "BTC", 1, 1)
+	expectInsertLedger(mock, &repository.LedgerEntry{
+		IdempotencyKey: "freeze:quote:7:BTC",
+		UserID:         8,
+		Asset:          "BTC",
+		AvailableDelta: -5,
+		FrozenDelta:    5,
+		AvailableAfter: 5,
+		FrozenAfter:    5,
+		Reason:         repository.ReasonOrderFreeze,
+		RefType:        "QUOTE",
+		RefID:          "7",
+	})
+	expectCheckIdempotencyMiss(mock, "freeze:quote:7:USDT")
+	expectBalanceForUpdate(mock, 8, "USDT", 20, 0, 1)
+	mock.ExpectRollback()
+
+	resp, err := svc.BatchFreeze(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if resp.Success || resp.ErrorCode != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("expected INSUFFICIENT_BALANCE, got %+v", resp)
+	}
+
+	// 重试时已冻结的条目按幂等跳过
+	mock.ExpectBegin()
+	expectCheckIdempotency(mock, "freeze:quote:7:BTC")
+	expectCheckIdempotencyMiss(mock, "freeze:quote:7:USDT")
+	expectBalanceForUpdate(mock, 8, "USDT", 50, 0, 1)
+	expectUpdateBalance(mock, 20, 30, 8, "USDT", 1, 1)
+	expectInsertLedger(mock, &repository.LedgerEntry{
+		IdempotencyKey: "freeze:quote:7:USDT",
+		UserID:         8,
+		Asset:          "USDT",
+		AvailableDelta: -30,
+		FrozenDelta:    30,
+		AvailableAfter: 20,
+		FrozenAfter:    30,
+		Reason:         repository.ReasonOrderFreeze,
+		RefType:        "QUOTE",
+		RefID:          "7",
+	})
+	mock.ExpectCommit()
+
+	resp, err = svc.BatchFreeze(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !resp.Success || resp.Applied != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp, _ := svc.BatchFreeze(context.Background(), &BatchFreezeRequest{}); resp.Success {
+		t.Fatal("expected empty batch rejected")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestClearingServiceBatchUnfreeze_InvalidItem(t *testing.T) {
+	svc, mock, closeFn := newMockService(t, &mockIDGen{})
+	defer closeFn()
+
+	resp, err := svc.BatchUnfreeze(context.Background(), &BatchUnfreezeRequest{Items: []UnfreezeRequest{
+		{IdempotencyKey: "unfreeze:order:1", UserID: 8, Asset: "BTC", Amount: 5},
+		{IdempotencyKey: "unfreeze:order:2", UserID: 9, Asset: "USDT", Amount: 0},
+	}})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if resp.Success || resp.ErrorCode != "INVALID_PARAM" {
+		t.Fatalf("expected INVALID_PARAM, got %+v", resp)
+	}
+	if resp, _ := svc.BatchUnfreeze(context.Background(), &BatchUnfreezeRequest{}); resp.Success {
+		t.Fatal("expected empty batch rejected")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
 func TestClearingServiceDeduct_Success(t *testing.T) {
 	svc, mock, closeFn := newMockService(t, &mockIDGen{})
 	defer closeFn()
@@ -50,6 +50,12 @@ const (
 	CodeInvalidOrderType       Code = "INVALID_ORDER_TYPE"
 	CodeInvalidTimeInForce     Code = "INVALID_TIME_IN_FORCE"
 	CodeInvalidPrice           Code = "INVALID_PRICE"
+	CodeInvalidStopPrice       Code = "INVALID_STOP_PRICE"
+	CodeInvalidTrailingStop    Code = "INVALID_TRAILING_STOP"
+	CodeInvalidSTPMode         Code = "INVALID_STP_MODE"
+	CodeInvalidDisplayQty      Code = "INVALID_DISPLAY_QTY"
+	CodeInvalidExpireTime      Code = "INVALID_EXPIRE_TIME"
+	CodeInvalidQuoteOrderQty   Code = "INVALID_QUOTE_ORDER_QTY"
 	CodeInvalidQuantity        Code = "INVALID_QUANTITY"
 	CodePriceOutOfRange        Code = "PRICE_OUT_OF_RANGE"
 	CodeQtyTooSmall            Code = "QTY_TOO_SMALL"
@@ -62,6 +68,13 @@ const (
 	CodeSelfTradeBlocked       Code = "SELF_TRADE_BLOCKED"
 	CodeMarketOrderNotAllowed  Code = "MARKET_ORDER_NOT_ALLOWED"
 	CodePostOnlyRejected       Code = "POST_ONLY_REJECTED"
+	CodeAmendNotAllowed        Code = "AMEND_NOT_ALLOWED"
+	CodeInvalidAmendQty        Code = "INVALID_AMEND_QTY"
+	CodeAmendNoChange          Code = "AMEND_NO_CHANGE"
+	CodeAmendInProgress        Code = "AMEND_IN_PROGRESS"
+	CodeOrderListNotFound      Code = "ORDER_LIST_NOT_FOUND"
+	CodeAlgoOrderNotFound      Code = "ALGO_ORDER_NOT_FOUND"
+	CodeInvalidAlgoParam       Code = "INVALID_ALGO_PARAM"
 
 	// 资金 (5xxx)
 	CodeInsufficientBalance Code = "INSUFFICIENT_BALANCE"
@@ -93,6 +106,13 @@ const (
 	CodeInvalidCredentials Code = "INVALID_CREDENTIALS"
 	CodeKycRequired        Code = "KYC_REQUIRED"
 
+	// 下单前风控 (8xxx)
+	CodeTradingDisabled       Code = "TRADING_DISABLED"
+	CodeTooManyOpenOrders     Code = "TOO_MANY_OPEN_ORDERS"
+	CodeOrderNotionalTooLarge Code = "ORDER_NOTIONAL_TOO_LARGE"
+	CodeNotionalRateExceeded  Code = "NOTIONAL_RATE_EXCEEDED"
+	CodeOrderRateExceeded     Code = "ORDER_RATE_EXCEEDED"
+
 	// 配置/数据
 	CodeInvalidSymbolConfig Code = "INVALID_SYMBOL_CONFIG"
 
@@ -144,7 +164,8 @@ func isRetryable(code Code) bool {
 	switch code {
 	case CodeRateLimited, CodeTooManyRequests, CodeSystemBusy,
 		CodeTimeout, CodeUnavailable, CodeInvalidTimestamp,
-		CodeOrderRateLimited, CodeCancelRateLimited, CodeServiceDegraded:
+		CodeOrderRateLimited, CodeCancelRateLimited, CodeServiceDegraded,
+		CodeOrderRateExceeded, CodeNotionalRateExceeded:
 		return true
 	default:
 		return false
@@ -156,11 +177,13 @@ func httpStatus(code Code) int {
 	switch code {
 	case CodeOK:
 		return http.StatusOK
-	case CodeInvalidParam, CodeInvalidRequest, CodeInvalidPrice,
+	case CodeInvalidParam, CodeInvalidRequest, CodeInvalidPrice, CodeInvalidStopPrice, CodeInvalidTrailingStop,
 		CodeInvalidQuantity, CodeInvalidSide, CodeInvalidOrderType,
-		CodeInvalidTimeInForce, CodeInvalidAddress, CodePriceOutOfRange,
+		CodeInvalidTimeInForce, CodeInvalidSTPMode, CodeInvalidDisplayQty, CodeInvalidExpireTime, CodeInvalidQuoteOrderQty, CodeInvalidAlgoParam, CodeInvalidAddress, CodePriceOutOfRange,
 		CodeQtyTooSmall, CodeQtyTooLarge, CodeNotionalTooSmall,
+		CodeTooManyOpenOrders, CodeOrderNotionalTooLarge,
 		CodeMarketOrderNotAllowed, CodePostOnlyRejected, CodeSymbolNotTrading,
+		CodeAmendNotAllowed, CodeInvalidAmendQty, CodeAmendNoChange,
 		CodeWithdrawAmountTooSmall, CodeWithdrawAmountTooLarge, CodeAmountTooSmall:
 		return http.StatusBadRequest
 	case CodeUnauthenticated, CodeInvalidSignature, CodeInvalidApiKey,
@@ -170,17 +193,18 @@ func httpStatus(code Code) int {
 	case CodePermissionDenied, CodeApiKeyNoPermission, CodeIpNotWhitelisted,
 		Code2FARequired, CodeUserFrozen, CodeKycRequired, CodeApiKeyDisabled,
 		CodeUserDisabled, CodeDepositDisabled, CodeWithdrawDisabled,
-		CodeAddressNotWhitelisted:
+		CodeAddressNotWhitelisted, CodeTradingDisabled:
 		return http.StatusForbidden
-	case CodeNotFound, CodeOrderNotFound, CodeUserNotFound,
+	case CodeNotFound, CodeOrderNotFound, CodeOrderListNotFound, CodeAlgoOrderNotFound, CodeUserNotFound,
 		CodeSymbolNotFound, CodeAssetNotFound, CodeNetworkNotFound:
 		return http.StatusNotFound
 	case CodeAlreadyExists, CodeDuplicateClientOrderId, CodeIdempotencyConflict,
 		CodeOrderAlreadyCanceled, CodeOrderAlreadyFilled, CodeEmailExists,
-		CodeWithdrawPending, CodeWithdrawRejected, CodeSelfTradeBlocked:
+		CodeWithdrawPending, CodeWithdrawRejected, CodeSelfTradeBlocked,
+		CodeAmendInProgress:
 		return http.StatusConflict
 	case CodeRateLimited, CodeTooManyRequests, CodeOrderRateLimited,
-		CodeCancelRateLimited:
+		CodeCancelRateLimited, CodeOrderRateExceeded, CodeNotionalRateExceeded:
 		return http.StatusTooManyRequests
 	case CodeRequestTooLarge:
 		return http.StatusRequestEntityTooLarge
@@ -0,0 +1,177 @@
+// Package eventseq 撮合事件序列号连续性检查
+//
+// 撮合引擎为每个 symbol 分配单调递增、跨重启持久化的事件序列号（从 1 开始）。
+// 下游消费者用 Tracker 记录每个 symbol 最后处理的序列号，发现缺口或重复时
+// 上报指标并发起重同步请求。
+package eventseq
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status 序列号检查结果
+type Status int
+
+const (
+	StatusOK        Status = iota // 连续
+	StatusFirst                   // 该 symbol 首个事件，建立基线
+	StatusGap                     // 跳号，中间事件丢失
+	StatusDuplicate               // 不大于已处理的序列号
+	StatusReset                   // 新写入的消息序列号重新从 1 开始（上游持久化序列号丢失）
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusFirst:
+		return "first"
+	case StatusGap:
+		return "gap"
+	case StatusDuplicate:
+		return "duplicate"
+	case StatusReset:
+		return "reset"
+	default:
+		return fmt.Sprintf("status(%d)", int(s))
+	}
+}
+
+// Result 单个事件的检查结果
+type Result struct {
+	Status  Status
+	Last    int64 // 检查前已处理的最大序列号
+	Missing int64 // StatusGap 时缺失的事件数
+}
+
+// Tracker 按 symbol 记录最后处理的序列号及其消息 ID（并发安全）
+type Tracker struct {
+	mu   sync.Mutex
+	last map[string]position
+}
+
+type position struct {
+	seq   int64
+	msgID string
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{last: make(map[string]position)}
+}
+
+// Observe 检查并记录事件序列号，msgID 为事件的 Stream 消息 ID
+//
+// 序列号 1 只有出现在比已处理消息更新的消息中才视为重置，重新投递的旧消息按重复处理。
+// 重复事件不更新记录；未携带序列号（seq <= 0）的事件视为 StatusOK 且不参与检查。
+func (t *Tracker) Observe(symbol string, seq int64, msgID string) Result {
+	if seq <= 0 {
+		return Result{Status: StatusOK}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.last[symbol]
+	res := Result{Last: last.seq}
+	switch {
+	case !ok:
+		res.Status = StatusFirst
+	case seq == last.seq+1:
+		res.Status = StatusOK
+	case seq == 1 && CompareIDs(msgID, last.msgID) > 0:
+		res.Status = StatusReset
+	case seq <= last.seq:
+		res.Status = StatusDuplicate
+		return res
+	default:
+		res.Status = StatusGap
+		res.Missing = seq - last.seq - 1
+	}
+	t.last[symbol] = position{seq: seq, msgID: msgID}
+	return res
+}
+
+// Last 返回 symbol 最后处理的序列号，未处理过返回 0
+func (t *Tracker) Last(symbol string) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last[symbol].seq
+}
+
+// CompareIDs 比较 Redis Stream 消息 ID（<ms>-<seq>），返回 -1/0/1；空 ID 最小
+func CompareIDs(a, b string) int {
+	aMs, aSeq := parseID(a)
+	bMs, bSeq := parseID(b)
+	switch {
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1
+		}
+		return 1
+	case aSeq != bSeq:
+		if aSeq < bSeq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func parseID(id string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
+
+// Header 撮合事件公共字段
+type Header struct {
+	Symbol string `json:"symbol"`
+	Seq    int64  `json:"seq"`
+}
+
+// ParseHeader 从事件消息体中解析 symbol 与序列号
+func ParseHeader(data string) (Header, error) {
+	var h Header
+	if err := json.Unmarshal([]byte(data), &h); err != nil {
+		return Header{}, fmt.Errorf("unmarshal event header: %w", err)
+	}
+	return h, nil
+}
+
+// ResyncStream 重同步请求流名称（与 DLQ 一样以事件流名称为前缀）
+func ResyncStream(eventStream string) string {
+	return eventStream + ":resync"
+}
+
+// ResyncRequest 重同步请求：消费者发现缺口后写入 ResyncStream
+//
+// 行情服务消费该流并按撮合快照重建盘口（Group 为空时所有行情实例都会重建）；
+// 订单、清算按幂等处理，其请求只用于审计与运维排查。
+type ResyncRequest struct {
+	Stream   string
+	Group    string
+	Consumer string
+	Symbol   string
+	LastSeq  int64 // 缺口前最后处理的序列号
+	GotSeq   int64 // 实际收到的序列号
+	MsgID    string
+}
+
+// Values 转换为 XADD 字段
+func (r ResyncRequest) Values() map[string]interface{} {
+	return map[string]interface{}{
+		"stream":   r.Stream,
+		"group":    r.Group,
+		"consumer": r.Consumer,
+		"symbol":   r.Symbol,
+		"lastSeq":  r.LastSeq,
+		"gotSeq":   r.GotSeq,
+		"msgId":    r.MsgID,
+		"tsMs":     time.Now().UnixMilli(),
+	}
+}
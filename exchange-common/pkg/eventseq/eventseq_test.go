@@ -0,0 +1,77 @@
+package eventseq
+
+import "testing"
+
+func TestTrackerObserve(t *testing.T) {
+	tr := NewTracker()
+	steps := []struct {
+		symbol  string
+		seq     int64
+		msgID   string
+		status  Status
+		missing int64
+		last    int64
+	}{
+		{"BTCUSDT", 5, "10-0", StatusFirst, 0, 5},
+		{"BTCUSDT", 6, "11-0", StatusOK, 0, 6},
+		{"BTCUSDT", 6, "11-0", StatusDuplicate, 0, 6},
+		{"BTCUSDT", 4, "9-0", StatusDuplicate, 0, 6},
+		{"BTCUSDT", 9, "12-0", StatusGap, 2, 9},
+		{"ETHUSDT", 1, "12-1", StatusFirst, 0, 1},
+		{"ETHUSDT", 2, "13-0", StatusOK, 0, 2},
+		// 重新投递的序列号 1 不是重置
+		{"ETHUSDT", 1, "12-1", StatusDuplicate, 0, 2},
+		{"ETHUSDT", 2, "13-0", StatusDuplicate, 0, 2},
+		{"BTCUSDT", 1, "14-0", StatusReset, 0, 1},
+		{"BTCUSDT", 0, "15-0", StatusOK, 0, 1},
+	}
+	for i, step := range steps {
+		res := tr.Observe(step.symbol, step.seq, step.msgID)
+		if res.Status != step.status || res.Missing != step.missing {
+			t.Fatalf("step %d: got %+v, want status=%v missing=%d", i, res, step.status, step.missing)
+		}
+		if got := tr.Last(step.symbol); got != step.last {
+			t.Fatalf("step %d: last=%d, want %d", i, got, step.last)
+		}
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"2-0", "10-0", -1},
+		{"10-2", "10-10", -1},
+		{"10-1", "10-1", 0},
+		{"10-0", "", 1},
+	}
+	for _, c := range cases {
+		if got := CompareIDs(c.a, c.b); got != c.want {
+			t.Fatalf("CompareIDs(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestResyncRequestValues(t *testing.T) {
+	if got := ResyncStream("exchange:events"); got != "exchange:events:resync" {
+		t.Fatalf("unexpected resync stream: %s", got)
+	}
+	values := ResyncRequest{Symbol: "BTCUSDT", LastSeq: 6, GotSeq: 9, MsgID: "1-0"}.Values()
+	if values["symbol"] != "BTCUSDT" || values["lastSeq"] != int64(6) || values["gotSeq"] != int64(9) || values["msgId"] != "1-0" {
+		t.Fatalf("unexpected values: %+v", values)
+	}
+	if _, ok := values["tsMs"]; !ok {
+		t.Fatal("expected tsMs")
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	h, err := ParseHeader(`{"type":"TRADE_CREATED","symbol":"BTCUSDT","seq":12,"data":{}}`)
+	if err != nil || h.Symbol != "BTCUSDT" || h.Seq != 12 {
+		t.Fatalf("unexpected header: %+v err=%v", h, err)
+	}
+	if _, err := ParseHeader("not-json"); err == nil {
+		t.Fatal("expected error for invalid payload")
+	}
+}
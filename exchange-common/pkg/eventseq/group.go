@@ -0,0 +1,122 @@
+package eventseq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultGroupGrace 同组消费者乱序到达的序列号等待补齐的时间，超过后按缺口上报
+const DefaultGroupGrace = 10 * time.Second
+
+// observeGroupScript 在组共享状态上检查序列号
+//
+// KEYS[1] 组状态 Hash：<symbol> 连续处理到的序列号，<symbol>:id 最新消息 ID，<symbol>:since 出现超前序列号的时间；
+// KEYS[2] 该 symbol 超前于连续位置的序列号（ZSet）。
+// ARGV: symbol, seq, msgID, nowMs, graceMs。返回 {status, last, missing}，status 与 Status 取值一致。
+var observeGroupScript = redis.NewScript(`
+local function parse(id)
+	local ms, seq = string.match(id or '', '^(%d+)-(%d+)$')
+	return tonumber(ms) or 0, tonumber(seq) or 0
+end
+local function newer(a, b)
+	local ams, aseq = parse(a)
+	local bms, bseq = parse(b)
+	return ams > bms or (ams == bms and aseq > bseq)
+end
+-- advance 从 from 起吸收连续的超前序列号，更新连续位置与等待起点
+local function advance(from, now)
+	local w = from
+	while redis.call('ZSCORE', KEYS[2], w + 1) do
+		w = w + 1
+	end
+	redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', w)
+	redis.call('HSET', KEYS[1], ARGV[1], w)
+	if redis.call('ZCARD', KEYS[2]) > 0 then
+		redis.call('HSET', KEYS[1], ARGV[1] .. ':since', now)
+	else
+		redis.call('HDEL', KEYS[1], ARGV[1] .. ':since')
+	end
+end
+
+local seq = tonumber(ARGV[2])
+local now = tonumber(ARGV[4])
+local last = tonumber(redis.call('HGET', KEYS[1], ARGV[1]))
+local isNew = newer(ARGV[3], redis.call('HGET', KEYS[1], ARGV[1] .. ':id'))
+if isNew then
+	redis.call('HSET', KEYS[1], ARGV[1] .. ':id', ARGV[3])
+end
+
+if not last then
+	redis.call('HSET', KEYS[1], ARGV[1], seq)
+	return {1, 0, 0}
+end
+if seq == 1 and last >= 1 and isNew then
+	redis.call('DEL', KEYS[2])
+	redis.call('HSET', KEYS[1], ARGV[1], 1)
+	redis.call('HDEL', KEYS[1], ARGV[1] .. ':since')
+	return {4, last, 0}
+end
+if seq <= last or redis.call('ZSCORE', KEYS[2], seq) then
+	return {3, last, 0}
+end
+if seq == last + 1 then
+	advance(seq, now)
+	return {0, last, 0}
+end
+
+redis.call('ZADD', KEYS[2], seq, seq)
+local since = tonumber(redis.call('HGET', KEYS[1], ARGV[1] .. ':since'))
+if not since then
+	redis.call('HSET', KEYS[1], ARGV[1] .. ':since', now)
+	since = now
+end
+if now - since < tonumber(ARGV[5]) then
+	return {0, last, 0}
+end
+local lowest = tonumber(redis.call('ZRANGE', KEYS[2], 0, 0)[1])
+advance(lowest, now)
+return {2, last, lowest - last - 1}
+`)
+
+// GroupTracker 消费者组共享的序列号记录（保存在 Redis）
+//
+// 同组多个消费者各自收到部分事件，按组而不是按进程检查连续性。不同消费者处理的事件到达顺序
+// 与序列号不一定一致：超前的序列号先记下，缺口在 grace 内补齐不上报，超过 grace 仍未补齐时
+// 由之后的事件按 StatusGap 返回。
+type GroupTracker struct {
+	client redis.Scripter
+	key    string
+	grace  time.Duration
+	now    func() time.Time
+}
+
+// NewGroupTracker 创建事件流 eventStream 上消费者组 group 的序列号记录（grace 通常为 DefaultGroupGrace）
+func NewGroupTracker(client redis.Scripter, eventStream, group string, grace time.Duration) *GroupTracker {
+	return &GroupTracker{
+		client: client,
+		key:    eventStream + ":consumed:" + group,
+		grace:  grace,
+		now:    time.Now,
+	}
+}
+
+// Observe 检查并记录事件序列号（规则同 Tracker.Observe）
+func (t *GroupTracker) Observe(ctx context.Context, symbol string, seq int64, msgID string) (Result, error) {
+	if seq <= 0 {
+		return Result{Status: StatusOK}, nil
+	}
+	values, err := observeGroupScript.Run(ctx, t.client,
+		[]string{t.key, t.key + ":" + symbol},
+		symbol, seq, msgID, t.now().UnixMilli(), t.grace.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return Result{Status: StatusOK}, fmt.Errorf("observe event seq: %w", err)
+	}
+	if len(values) != 3 {
+		return Result{Status: StatusOK}, fmt.Errorf("observe event seq: unexpected reply %v", values)
+	}
+	return Result{Status: Status(values[0]), Last: values[1], Missing: values[2]}, nil
+}
@@ -0,0 +1,69 @@
+package eventseq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestGroupTrackerObserve(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	now := time.UnixMilli(1_000_000)
+	// 同组两个消费者共享记录
+	a := NewGroupTracker(client, "exchange:events", "clearing-group", DefaultGroupGrace)
+	b := NewGroupTracker(client, "exchange:events", "clearing-group", DefaultGroupGrace)
+	a.now = func() time.Time { return now }
+	b.now = a.now
+
+	steps := []struct {
+		tracker *GroupTracker
+		seq     int64
+		msgID   string
+		advance time.Duration
+		status  Status
+		last    int64
+		missing int64
+	}{
+		{a, 5, "10-0", 0, StatusFirst, 0, 0},
+		// 消费者 b 先处理 7，a 随后处理 6：乱序但没有缺口
+		{b, 7, "12-0", 0, StatusOK, 5, 0},
+		{a, 6, "11-0", 0, StatusOK, 5, 0},
+		{b, 7, "12-0", 0, StatusDuplicate, 7, 0},
+		{a, 8, "13-0", 0, StatusOK, 7, 0},
+		// 9 缺失：grace 内不上报，超过后由之后的事件上报
+		{b, 10, "15-0", 0, StatusOK, 8, 0},
+		{a, 11, "16-0", DefaultGroupGrace, StatusGap, 8, 1},
+		{b, 12, "17-0", 0, StatusOK, 11, 0},
+		{a, 9, "14-0", 0, StatusDuplicate, 12, 0},
+		// 重新投递的旧消息不是重置
+		{b, 1, "9-0", 0, StatusDuplicate, 12, 0},
+		{a, 1, "18-0", 0, StatusReset, 12, 0},
+		{b, 2, "19-0", 0, StatusOK, 1, 0},
+	}
+	ctx := context.Background()
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		res, err := step.tracker.Observe(ctx, "BTCUSDT", step.seq, step.msgID)
+		if err != nil {
+			t.Fatalf("step %d: observe: %v", i, err)
+		}
+		if res.Status != step.status || res.Last != step.last || res.Missing != step.missing {
+			t.Fatalf("step %d: got %+v, want status=%v last=%d missing=%d", i, res, step.status, step.last, step.missing)
+		}
+	}
+
+	other := NewGroupTracker(client, "exchange:events", "order-updater-group", DefaultGroupGrace)
+	if res, err := other.Observe(ctx, "BTCUSDT", 12, "17-0"); err != nil || res.Status != StatusFirst {
+		t.Fatalf("expected groups tracked separately, got %+v err=%v", res, err)
+	}
+}
@@ -0,0 +1,233 @@
+// Package shard 交易对到撮合分片的分配
+//
+// 每个撮合实例（分片）只消费自己的订单流。订单服务、管理端按交易对所属分片写入
+// 对应订单流，并向对应实例查询深度。分片号优先取 Redis 映射（Hash：symbol -> 分片号），
+// 其次取静态配置，都未分配时按交易对哈希取模。
+package shard
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	envconfig "github.com/exchange/common/pkg/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// cacheTTL Redis 映射的本地缓存时间（调整映射后最长在该时间内生效）
+const cacheTTL = 5 * time.Second
+
+// maxCacheEntries 本地缓存上限：交易对可能来自外部请求，缓存不能随之无限增长
+const maxCacheEntries = 10000
+
+// Config 分片配置
+type Config struct {
+	Count       int      // 分片数，1 表示不分片
+	Assignments string   // 静态分配：BTCUSDT=0,ETHUSDT=1
+	MapKey      string   // Redis 映射 Hash 键，为空不启用
+	URLs        []string // 各分片撮合实例地址（按分片号排列），只有访问撮合实例的服务需要配置
+}
+
+// ConfigFromEnv 从环境变量读取分片配置
+// （MATCHING_SHARD_COUNT、MATCHING_SHARDS、MATCHING_SHARD_MAP_KEY、MATCHING_SHARD_URLS），所有服务需使用相同取值
+func ConfigFromEnv() Config {
+	return Config{
+		Count:       envconfig.GetEnvInt("MATCHING_SHARD_COUNT", 1),
+		Assignments: envconfig.GetEnv("MATCHING_SHARDS", ""),
+		MapKey:      envconfig.GetEnv("MATCHING_SHARD_MAP_KEY", ""),
+		URLs:        envconfig.GetEnvSlice("MATCHING_SHARD_URLS", nil),
+	}
+}
+
+// Validate 校验分片配置
+func (c Config) Validate() error {
+	if c.Count < 1 {
+		return fmt.Errorf("shard count must be positive")
+	}
+	_, err := parseAssignments(c.Assignments, c.Count)
+	return err
+}
+
+// ValidateURLs 校验分片地址：分片时每个分片需配置一个地址
+func (c Config) ValidateURLs() error {
+	if c.Count > 1 && len(c.URLs) != c.Count {
+		return fmt.Errorf("expected %d shard URLs, got %d", c.Count, len(c.URLs))
+	}
+	return nil
+}
+
+// ServiceURLs 各分片撮合实例地址（按分片号排列），未配置时只有 fallback 一个实例
+func (c Config) ServiceURLs(fallback string) []string {
+	if len(c.URLs) > 0 {
+		return c.URLs
+	}
+	return []string{fallback}
+}
+
+// StreamName 分片的订单流名称（不分片时即为 base）
+func StreamName(base string, shard, count int) string {
+	if count <= 1 {
+		return base
+	}
+	return base + ":" + strconv.Itoa(shard)
+}
+
+// Hash 未分配交易对的默认分片
+func Hash(symbol string, count int) int {
+	if count <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(symbol))
+	return int(h.Sum32() % uint32(count))
+}
+
+// source 动态分片映射
+type source interface {
+	// lookup 返回交易对所在分片，ok 为 false 表示未分配
+	lookup(ctx context.Context, symbol string) (shard int, ok bool, err error)
+}
+
+type cacheEntry struct {
+	shard     int
+	expiresAt time.Time
+}
+
+// Router 交易对分片路由（并发安全）
+type Router struct {
+	count  int
+	static map[string]int
+	source source
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewRouter 创建分片路由，cfg.MapKey 非空时从 client 读取 Redis 映射
+func NewRouter(cfg Config, client redis.Cmdable) (*Router, error) {
+	if cfg.Count < 1 {
+		return nil, fmt.Errorf("shard count must be positive")
+	}
+	static, err := parseAssignments(cfg.Assignments, cfg.Count)
+	if err != nil {
+		return nil, err
+	}
+	r := &Router{
+		count:  cfg.Count,
+		static: static,
+		cache:  make(map[string]cacheEntry),
+	}
+	if cfg.MapKey != "" && client != nil {
+		r.source = &redisSource{client: client, key: cfg.MapKey}
+	}
+	return r, nil
+}
+
+// Count 分片数
+func (r *Router) Count() int {
+	return r.count
+}
+
+// Shard 交易对所在分片
+func (r *Router) Shard(ctx context.Context, symbol string) (int, error) {
+	if r.count <= 1 {
+		return 0, nil
+	}
+	if r.source == nil {
+		return r.fallback(symbol), nil
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	entry, ok := r.cache[symbol]
+	r.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.shard, nil
+	}
+
+	shard, found, err := r.source.lookup(ctx, symbol)
+	if err != nil {
+		return 0, fmt.Errorf("lookup shard of %s: %w", symbol, err)
+	}
+	if !found {
+		shard = r.fallback(symbol)
+	} else if shard < 0 || shard >= r.count {
+		return 0, fmt.Errorf("shard of %s out of range: %d", symbol, shard)
+	}
+
+	r.mu.Lock()
+	if len(r.cache) >= maxCacheEntries {
+		for key, entry := range r.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(r.cache, key)
+			}
+		}
+	}
+	if len(r.cache) < maxCacheEntries {
+		r.cache[symbol] = cacheEntry{shard: shard, expiresAt: now.Add(cacheTTL)}
+	}
+	r.mu.Unlock()
+	return shard, nil
+}
+
+// Stream 交易对所在分片的订单流名称
+func (r *Router) Stream(ctx context.Context, base, symbol string) (string, error) {
+	shard, err := r.Shard(ctx, symbol)
+	if err != nil {
+		return "", err
+	}
+	return StreamName(base, shard, r.count), nil
+}
+
+func (r *Router) fallback(symbol string) int {
+	if shard, ok := r.static[symbol]; ok {
+		return shard
+	}
+	return Hash(symbol, r.count)
+}
+
+// parseAssignments 解析静态分配 "SYMBOL=shard,..."
+func parseAssignments(spec string, count int) (map[string]int, error) {
+	result := make(map[string]int)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		symbol, value, ok := strings.Cut(part, "=")
+		symbol = strings.TrimSpace(symbol)
+		if !ok || symbol == "" {
+			return nil, fmt.Errorf("invalid shard assignment %q", part)
+		}
+		shard, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || shard < 0 || shard >= count {
+			return nil, fmt.Errorf("invalid shard assignment %q: shard must be in [0, %d)", part, count)
+		}
+		if _, dup := result[symbol]; dup {
+			return nil, fmt.Errorf("duplicate shard assignment for %s", symbol)
+		}
+		result[symbol] = shard
+	}
+	return result, nil
+}
+
+// redisSource Redis Hash 映射（field 为交易对，value 为分片号）
+type redisSource struct {
+	client redis.Cmdable
+	key    string
+}
+
+func (s *redisSource) lookup(ctx context.Context, symbol string) (int, bool, error) {
+	shard, err := s.client.HGet(ctx, s.key, symbol).Int()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return shard, true, nil
+}
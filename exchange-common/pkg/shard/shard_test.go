@@ -0,0 +1,189 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeSource struct {
+	shards map[string]int
+	err    error
+	calls  int
+}
+
+func (f *fakeSource) lookup(_ context.Context, symbol string) (int, bool, error) {
+	f.calls++
+	if f.err != nil {
+		return 0, false, f.err
+	}
+	shard, ok := f.shards[symbol]
+	return shard, ok, nil
+}
+
+func TestStreamName(t *testing.T) {
+	if got := StreamName("exchange:orders", 0, 1); got != "exchange:orders" {
+		t.Fatalf("unsharded stream = %q", got)
+	}
+	if got := StreamName("exchange:orders", 2, 4); got != "exchange:orders:2" {
+		t.Fatalf("sharded stream = %q", got)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "unsharded", cfg: Config{Count: 1}},
+		{name: "static", cfg: Config{Count: 2, Assignments: " BTCUSDT=0, ETHUSDT=1 "}},
+		{name: "zero count", cfg: Config{Count: 0}, wantErr: true},
+		{name: "out of range", cfg: Config{Count: 2, Assignments: "BTCUSDT=2"}, wantErr: true},
+		{name: "malformed", cfg: Config{Count: 2, Assignments: "BTCUSDT"}, wantErr: true},
+		{name: "duplicate", cfg: Config{Count: 2, Assignments: "BTCUSDT=0,BTCUSDT=1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("MATCHING_SHARD_COUNT", "")
+	t.Setenv("MATCHING_SHARDS", "")
+	t.Setenv("MATCHING_SHARD_MAP_KEY", "")
+	t.Setenv("MATCHING_SHARD_URLS", "")
+	cfg := ConfigFromEnv()
+	if cfg.Count != 1 || cfg.ValidateURLs() != nil {
+		t.Fatalf("expected unsharded config, got %+v", cfg)
+	}
+	if urls := cfg.ServiceURLs("http://matching:8082"); len(urls) != 1 || urls[0] != "http://matching:8082" {
+		t.Fatalf("expected fallback URL, got %v", urls)
+	}
+
+	t.Setenv("MATCHING_SHARD_COUNT", "2")
+	t.Setenv("MATCHING_SHARDS", "BTCUSDT=1")
+	t.Setenv("MATCHING_SHARD_MAP_KEY", "matching:shards")
+	t.Setenv("MATCHING_SHARD_URLS", "http://matching-0:8082, http://matching-1:8082")
+	cfg = ConfigFromEnv()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if err := cfg.ValidateURLs(); err != nil {
+		t.Fatalf("validate URLs: %v", err)
+	}
+	if cfg.Assignments != "BTCUSDT=1" || cfg.MapKey != "matching:shards" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if urls := cfg.ServiceURLs("http://matching:8082"); len(urls) != 2 || urls[1] != "http://matching-1:8082" {
+		t.Fatalf("unexpected shard URLs: %v", urls)
+	}
+
+	cfg.URLs = cfg.URLs[:1]
+	if err := cfg.ValidateURLs(); err == nil {
+		t.Fatal("expected missing shard URL rejected")
+	}
+}
+
+func TestRouterStaticAndHash(t *testing.T) {
+	r, err := NewRouter(Config{Count: 4, Assignments: "BTCUSDT=3"}, nil)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	ctx := context.Background()
+	if shard, err := r.Shard(ctx, "BTCUSDT"); err != nil || shard != 3 {
+		t.Fatalf("static shard = %d, %v", shard, err)
+	}
+	want := Hash("ETHUSDT", 4)
+	for i := 0; i < 3; i++ {
+		if shard, err := r.Shard(ctx, "ETHUSDT"); err != nil || shard != want {
+			t.Fatalf("hash shard = %d, %v, want %d", shard, err, want)
+		}
+	}
+	if stream, err := r.Stream(ctx, "exchange:orders", "BTCUSDT"); err != nil || stream != "exchange:orders:3" {
+		t.Fatalf("stream = %q, %v", stream, err)
+	}
+}
+
+func TestRouterDynamicMap(t *testing.T) {
+	src := &fakeSource{shards: map[string]int{"BTCUSDT": 1, "BADUSDT": 9}}
+	r, err := NewRouter(Config{Count: 2, Assignments: "BTCUSDT=0,ETHUSDT=0"}, nil)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	r.source = src
+	ctx := context.Background()
+
+	// Redis 映射优先于静态配置，且结果被缓存
+	for i := 0; i < 2; i++ {
+		if shard, err := r.Shard(ctx, "BTCUSDT"); err != nil || shard != 1 {
+			t.Fatalf("mapped shard = %d, %v", shard, err)
+		}
+	}
+	if src.calls != 1 {
+		t.Fatalf("expected cached lookup, got %d calls", src.calls)
+	}
+	// 未映射时回退到静态配置
+	if shard, err := r.Shard(ctx, "ETHUSDT"); err != nil || shard != 0 {
+		t.Fatalf("fallback shard = %d, %v", shard, err)
+	}
+	if _, err := r.Shard(ctx, "BADUSDT"); err == nil {
+		t.Fatal("expected out-of-range mapping rejected")
+	}
+
+	src.err = errors.New("redis down")
+	if _, err := r.Shard(ctx, "SOLUSDT"); err == nil {
+		t.Fatal("expected lookup error")
+	}
+}
+
+func TestRouterUnsharded(t *testing.T) {
+	src := &fakeSource{err: errors.New("unused")}
+	r, err := NewRouter(Config{Count: 1}, nil)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	r.source = src
+	if shard, err := r.Shard(context.Background(), "BTCUSDT"); err != nil || shard != 0 {
+		t.Fatalf("unsharded shard = %d, %v", shard, err)
+	}
+	if src.calls != 0 {
+		t.Fatal("expected no lookup when unsharded")
+	}
+}
+
+func TestRouterCacheBounded(t *testing.T) {
+	r, err := NewRouter(Config{Count: 2}, nil)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	r.source = &fakeSource{shards: map[string]int{}}
+	ctx := context.Background()
+
+	for i := 0; i < maxCacheEntries+100; i++ {
+		if _, err := r.Shard(ctx, fmt.Sprintf("SYM%d", i)); err != nil {
+			t.Fatalf("Shard: %v", err)
+		}
+	}
+	if len(r.cache) > maxCacheEntries {
+		t.Fatalf("expected cache bounded by %d, got %d", maxCacheEntries, len(r.cache))
+	}
+
+	// 过期条目在缓存满时被清理
+	for key, entry := range r.cache {
+		entry.expiresAt = time.Now().Add(-time.Second)
+		r.cache[key] = entry
+	}
+	if _, err := r.Shard(ctx, "BTCUSDT"); err != nil {
+		t.Fatalf("Shard: %v", err)
+	}
+	if _, ok := r.cache["BTCUSDT"]; !ok || len(r.cache) != 1 {
+		t.Fatalf("expected expired entries evicted, got %d entries", len(r.cache))
+	}
+}
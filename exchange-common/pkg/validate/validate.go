@@ -65,10 +65,10 @@ func OrderType(s string) error {
 // TimeInForce 校验有效期类型
 func TimeInForce(s string) error {
 	switch strings.ToUpper(strings.TrimSpace(s)) {
-	case "GTC", "IOC", "FOK", "POST_ONLY":
+	case "GTC", "IOC", "FOK", "POST_ONLY", "GTD", "DAY":
 		return nil
 	default:
-		return commonerrors.Newf(commonerrors.CodeInvalidTimeInForce, "invalid timeInForce: %q (expected GTC/IOC/FOK/POST_ONLY)", s)
+		return commonerrors.Newf(commonerrors.CodeInvalidTimeInForce, "invalid timeInForce: %q (expected GTC/IOC/FOK/POST_ONLY/GTD/DAY)", s)
 	}
 }
 
@@ -97,6 +97,47 @@ func Price(price int64, precision int) error {
 	return nil
 }
 
+// TickBand 价格分档：价格不低于 MinPrice 时使用的最小价格变动单位与数量步长（均为最小单位整数）
+//
+// 每一档适用到下一档的 MinPrice 为止；低于第一档的价格使用交易对本身的 price_tick / qty_step。
+// QtyStep 为 0 表示沿用交易对的 qty_step。
+type TickBand struct {
+	MinPrice  int64 `json:"minPrice"`
+	PriceTick int64 `json:"priceTick"`
+	QtyStep   int64 `json:"qtyStep,omitempty"`
+}
+
+// TickBands 校验价格分档配置：MinPrice 严格递增且在本档价格网格上，PriceTick 为正，QtyStep 非负
+func TickBands(bands []TickBand) error {
+	var prev int64
+	for i, band := range bands {
+		if band.MinPrice <= prev {
+			return commonerrors.Newf(commonerrors.CodeInvalidParam, "invalid tick band %d: minPrice %d must be > %d", i, band.MinPrice, prev)
+		}
+		if band.PriceTick <= 0 || band.MinPrice%band.PriceTick != 0 {
+			return commonerrors.Newf(commonerrors.CodeInvalidParam, "invalid tick band %d: priceTick %d must be > 0 and divide minPrice", i, band.PriceTick)
+		}
+		if band.QtyStep < 0 {
+			return commonerrors.Newf(commonerrors.CodeInvalidParam, "invalid tick band %d: qtyStep %d must be >= 0", i, band.QtyStep)
+		}
+		prev = band.MinPrice
+	}
+	return nil
+}
+
+// BandSteps 返回价格适用的最小价格变动单位与数量步长（bands 按 MinPrice 升序）
+func BandSteps(bands []TickBand, price, priceTick, qtyStep int64) (int64, int64) {
+	for i := len(bands) - 1; i >= 0; i-- {
+		if price >= bands[i].MinPrice {
+			if bands[i].QtyStep > 0 {
+				qtyStep = bands[i].QtyStep
+			}
+			return bands[i].PriceTick, qtyStep
+		}
+	}
+	return priceTick, qtyStep
+}
+
 func pow10i(n int) int64 {
 	if n < 0 {
 		return 0
@@ -0,0 +1,4 @@
+-- 条件单（STOP_LOSS / STOP_LOSS_LIMIT / TAKE_PROFIT）触发时间
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS trigger_time_ms BIGINT;
+COMMENT ON COLUMN exchange_order.orders.type IS '1=LIMIT, 2=MARKET, 3=STOP_LOSS, 4=STOP_LOSS_LIMIT, 5=TAKE_PROFIT';
+COMMENT ON COLUMN exchange_order.orders.trigger_time_ms IS 'stop order trigger time, NULL until triggered';
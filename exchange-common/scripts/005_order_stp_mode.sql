@@ -0,0 +1,3 @@
+-- 自成交防护模式（以 taker 的模式为准），原 1=NONE 的存量订单按 EXPIRE_TAKER 处理
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS stp_mode SMALLINT NOT NULL DEFAULT 1;
+COMMENT ON COLUMN exchange_order.orders.stp_mode IS '1=EXPIRE_TAKER, 2=EXPIRE_MAKER, 3=EXPIRE_BOTH, 4=DECREMENT';
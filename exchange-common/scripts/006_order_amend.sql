@@ -0,0 +1,6 @@
+-- 原子改单：买单改价后的累计冻结额与进行中的改单（同一订单同时只允许一个改单）
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS frozen_quote_qty BIGINT;
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS pending_amend_id BIGINT;
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS pending_amend_freeze BIGINT NOT NULL DEFAULT 0;
+COMMENT ON COLUMN exchange_order.orders.frozen_quote_qty IS 'buy orders: total frozen quote after amend, NULL means price*orig_qty';
+COMMENT ON COLUMN exchange_order.orders.pending_amend_freeze IS 'pre-frozen amount of the pending amend (quote for buy, base for sell)';
@@ -0,0 +1,3 @@
+-- 冰山单：每次展示数量（0 表示非冰山单）
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS display_qty BIGINT NOT NULL DEFAULT 0;
+COMMENT ON COLUMN exchange_order.orders.display_qty IS 'iceberg display qty scaled by 10^qty_precision, 0 means not iceberg';
@@ -0,0 +1,4 @@
+-- GTD / DAY 订单：到期时间（撮合到期撤单，订单状态记为 EXPIRED）
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS expire_time_ms BIGINT NOT NULL DEFAULT 0;
+COMMENT ON COLUMN exchange_order.orders.time_in_force IS '1=GTC, 2=IOC, 3=FOK, 4=POST_ONLY, 5=GTD, 6=DAY';
+COMMENT ON COLUMN exchange_order.orders.expire_time_ms IS 'GTD/DAY expiry time in ms, 0 means no expiry';
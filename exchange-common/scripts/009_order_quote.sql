@@ -0,0 +1,4 @@
+-- 做市批量报价：报价请求 ID（同一组报价单相同，由下一次报价整组替换）
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS quote_id BIGINT;
+COMMENT ON COLUMN exchange_order.orders.quote_id IS 'mass-quote request id, NULL means not a quote order';
+CREATE INDEX IF NOT EXISTS idx_orders_quote ON exchange_order.orders(quote_id) WHERE quote_id IS NOT NULL;
@@ -0,0 +1,3 @@
+-- 按金额市价买单：下单金额（即冻结额），orig_qty 为 0 时由撮合按金额成交
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS quote_order_qty BIGINT NOT NULL DEFAULT 0;
+COMMENT ON COLUMN exchange_order.orders.quote_order_qty IS 'scaled by 10^price_precision, 0 means quantity-based order';
@@ -0,0 +1,3 @@
+-- 价格分档的最小变动单位与数量步长：[{"minPrice":..,"priceTick":..,"qtyStep":..}]，按 minPrice 升序，空数组表示不分档
+ALTER TABLE exchange_order.symbol_configs ADD COLUMN IF NOT EXISTS tick_bands JSONB NOT NULL DEFAULT '[]';
+COMMENT ON COLUMN exchange_order.symbol_configs.tick_bands IS 'price bands, minPrice/priceTick scaled by 10^price_precision, qtyStep scaled by 10^qty_precision';
@@ -0,0 +1,21 @@
+-- 订单列表（OCO）：一组订单共用一次冻结，任一订单成交或触发后撤销其余订单
+CREATE TABLE IF NOT EXISTS exchange_order.order_lists (
+    list_id BIGINT PRIMARY KEY,
+    client_list_id VARCHAR(64),
+    user_id BIGINT NOT NULL,
+    symbol VARCHAR(32) NOT NULL,
+    contingency_type VARCHAR(16) NOT NULL,  -- OCO
+    side SMALLINT NOT NULL,  -- 1=BUY, 2=SELL
+    freeze_asset VARCHAR(16) NOT NULL,
+    frozen_amount BIGINT NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 1,  -- 1=EXECUTING, 2=ALL_DONE, 3=REJECTED
+    triggered_order_id BIGINT,  -- 第一条成交、触发或终止的订单，NULL 表示均未推进
+    create_time_ms BIGINT NOT NULL,
+    update_time_ms BIGINT NOT NULL,
+    UNIQUE(user_id, client_list_id)
+);
+COMMENT ON COLUMN exchange_order.order_lists.frozen_amount IS 'shared freeze of all orders in the list, scaled by asset precision';
+
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS list_id BIGINT;
+COMMENT ON COLUMN exchange_order.orders.list_id IS 'order list id, NULL means not in an order list';
+CREATE INDEX IF NOT EXISTS idx_orders_list ON exchange_order.orders(list_id) WHERE list_id IS NOT NULL;
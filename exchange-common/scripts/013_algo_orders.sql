@@ -0,0 +1,34 @@
+-- 算法单（TWAP / VWAP）：母单按计划拆分为 LIMIT IOC 子单，由订单服务调度
+CREATE TABLE IF NOT EXISTS exchange_order.algo_orders (
+    algo_id BIGINT PRIMARY KEY,
+    client_algo_id VARCHAR(64),
+    user_id BIGINT NOT NULL,
+    symbol VARCHAR(32) NOT NULL,
+    side SMALLINT NOT NULL,  -- 1=BUY, 2=SELL
+    algo_type VARCHAR(8) NOT NULL,  -- TWAP / VWAP
+    total_qty BIGINT NOT NULL,
+    executed_qty BIGINT NOT NULL DEFAULT 0,
+    executed_quote_qty BIGINT NOT NULL DEFAULT 0,
+    limit_price BIGINT NOT NULL,
+    participation_bps INT NOT NULL DEFAULT 0,  -- 子单不超过区间市场成交量的比例，0 表示不限
+    slice_interval_ms BIGINT NOT NULL,
+    start_time_ms BIGINT NOT NULL,
+    end_time_ms BIGINT NOT NULL,
+    next_slice_time_ms BIGINT NOT NULL,
+    last_slice_time_ms BIGINT NOT NULL,
+    expected_volume BIGINT NOT NULL DEFAULT 0,  -- VWAP 预计区间市场成交量，0 表示按 TWAP 节奏
+    market_volume BIGINT NOT NULL DEFAULT 0,  -- 开始后累计观察到的市场成交量
+    child_count INT NOT NULL DEFAULT 0,
+    active_child_id BIGINT,  -- 尚未结算的子单，NULL 表示无
+    status SMALLINT NOT NULL DEFAULT 1,  -- 1=RUNNING, 2=FINISHED, 3=CANCELED, 4=EXPIRED, 5=FAILED
+    reason VARCHAR(64),
+    create_time_ms BIGINT NOT NULL,
+    update_time_ms BIGINT NOT NULL,
+    UNIQUE(user_id, client_algo_id)
+);
+COMMENT ON COLUMN exchange_order.algo_orders.total_qty IS 'scaled by 10^qty_precision';
+COMMENT ON COLUMN exchange_order.algo_orders.limit_price IS 'scaled by 10^price_precision';
+
+CREATE INDEX IF NOT EXISTS idx_algo_orders_due ON exchange_order.algo_orders(next_slice_time_ms)
+    WHERE status = 1 OR active_child_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_algo_orders_user ON exchange_order.algo_orders(user_id, create_time_ms DESC);
@@ -0,0 +1,9 @@
+-- 跟踪止损（TRAILING_STOP）：回调幅度（价差或基点二选一）、可选激活价与激活后的最优价，stop_price 随成交更新
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS trailing_delta BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS trailing_bps INT NOT NULL DEFAULT 0;
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS activation_price BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE exchange_order.orders ADD COLUMN IF NOT EXISTS trailing_extreme_price BIGINT NOT NULL DEFAULT 0;
+COMMENT ON COLUMN exchange_order.orders.type IS '1=LIMIT, 2=MARKET, 3=STOP_LOSS, 4=STOP_LOSS_LIMIT, 5=TAKE_PROFIT, 6=TRAILING_STOP';
+COMMENT ON COLUMN exchange_order.orders.trailing_delta IS 'scaled by 10^price_precision, 0 means callback by trailing_bps';
+COMMENT ON COLUMN exchange_order.orders.activation_price IS 'scaled by 10^price_precision, 0 means active on acceptance';
+COMMENT ON COLUMN exchange_order.orders.trailing_extreme_price IS 'scaled by 10^price_precision, best price since activation, 0 until activated';
@@ -0,0 +1,10 @@
+-- 用户风控设置：账户等级决定下单前风控限额，trading_disabled 禁止新下单（不影响撤单）
+CREATE TABLE IF NOT EXISTS exchange_order.user_risk_settings (
+    user_id BIGINT PRIMARY KEY,
+    tier VARCHAR(32) NOT NULL DEFAULT '',  -- 账户等级，空表示使用全局限额
+    trading_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    reason VARCHAR(255),
+    updated_by BIGINT NOT NULL DEFAULT 0,  -- 最后修改的后台用户
+    updated_at_ms BIGINT NOT NULL
+);
+COMMENT ON TABLE exchange_order.user_risk_settings IS 'per-user pre-trade risk settings, users without a row use the global limits';
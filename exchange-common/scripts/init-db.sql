@@ -88,12 +88,14 @@ CREATE TABLE exchange_order.symbol_configs (
     price_limit_rate NUMERIC(8, 4) NOT NULL DEFAULT 0.05,
     maker_fee_rate NUMERIC(8, 6) NOT NULL DEFAULT 0.001,
     taker_fee_rate NUMERIC(8, 6) NOT NULL DEFAULT 0.001,
-    status SMALLINT NOT NULL DEFAULT 1,  -- 1=TRADING, 2=HALT, 3=CANCEL_ONLY
+    status SMALLINT NOT NULL DEFAULT 1,  -- 1=TRADING, 2=HALT, 3=CANCEL_ONLY, 4=AUCTION
+    tick_bands JSONB NOT NULL DEFAULT '[]',  -- 价格分档的最小变动单位与数量步长，按 minPrice 升序
     created_at_ms BIGINT NOT NULL,
     updated_at_ms BIGINT NOT NULL
 );
 
 COMMENT ON COLUMN exchange_order.symbol_configs.price_tick IS 'scaled by 10^price_precision';
+COMMENT ON COLUMN exchange_order.symbol_configs.tick_bands IS 'price bands, minPrice/priceTick scaled by 10^price_precision, qtyStep scaled by 10^qty_precision';
 COMMENT ON COLUMN exchange_order.symbol_configs.qty_step IS 'scaled by 10^qty_precision';
 COMMENT ON COLUMN exchange_order.symbol_configs.min_qty IS 'scaled by 10^qty_precision';
 COMMENT ON COLUMN exchange_order.symbol_configs.max_qty IS 'scaled by 10^qty_precision';
@@ -106,8 +108,8 @@ CREATE TABLE exchange_order.orders (
     user_id BIGINT NOT NULL,
     symbol VARCHAR(32) NOT NULL,
     side SMALLINT NOT NULL,  -- 1=BUY, 2=SELL
-    type SMALLINT NOT NULL,  -- 1=LIMIT, 2=MARKET
-    time_in_force SMALLINT NOT NULL DEFAULT 1,  -- 1=GTC, 2=IOC, 3=FOK, 4=POST_ONLY
+    type SMALLINT NOT NULL,  -- 1=LIMIT, 2=MARKET, 3=STOP_LOSS, 4=STOP_LOSS_LIMIT, 5=TAKE_PROFIT, 6=TRAILING_STOP
+    time_in_force SMALLINT NOT NULL DEFAULT 1,  -- 1=GTC, 2=IOC, 3=FOK, 4=POST_ONLY, 5=GTD, 6=DAY
     price BIGINT,
     stop_price BIGINT,
     orig_qty BIGINT NOT NULL,
@@ -116,10 +118,23 @@ CREATE TABLE exchange_order.orders (
     status SMALLINT NOT NULL DEFAULT 1,  -- 0=INIT, 1=NEW, 2=PARTIAL, 3=FILLED, 4=CANCELED, 5=REJECTED, 6=EXPIRED
     reject_reason VARCHAR(255),
     cancel_reason VARCHAR(255),
-    stp_mode SMALLINT NOT NULL DEFAULT 1,  -- 1=NONE, 2=CANCEL_TAKER, 3=CANCEL_MAKER, 4=CANCEL_BOTH
+    stp_mode SMALLINT NOT NULL DEFAULT 1,  -- 1=EXPIRE_TAKER, 2=EXPIRE_MAKER, 3=EXPIRE_BOTH, 4=DECREMENT
     create_time_ms BIGINT NOT NULL,
     update_time_ms BIGINT NOT NULL,
     transact_time_ms BIGINT,
+    trigger_time_ms BIGINT,  -- 条件单触发时间，未触发为 NULL
+    frozen_quote_qty BIGINT,  -- 买单改单后的累计冻结额，NULL 表示 price*orig_qty
+    pending_amend_id BIGINT,  -- 进行中的改单 ID
+    pending_amend_freeze BIGINT NOT NULL DEFAULT 0,  -- 改单预冻结金额
+    display_qty BIGINT NOT NULL DEFAULT 0,  -- 冰山单每次展示数量，0 表示非冰山单
+    expire_time_ms BIGINT NOT NULL DEFAULT 0,  -- GTD/DAY 到期时间，0 表示不过期
+    quote_id BIGINT,  -- 做市报价请求 ID，NULL 表示非报价单
+    quote_order_qty BIGINT NOT NULL DEFAULT 0,  -- 按金额市价买单的金额，0 表示按数量下单
+    list_id BIGINT,  -- 订单列表 ID，NULL 表示非列表订单
+    trailing_delta BIGINT NOT NULL DEFAULT 0,  -- 跟踪止损回调价差，0 表示按 trailing_bps
+    trailing_bps INT NOT NULL DEFAULT 0,  -- 跟踪止损回调比例（基点）
+    activation_price BIGINT NOT NULL DEFAULT 0,  -- 跟踪止损激活价，0 表示接受即激活
+    trailing_extreme_price BIGINT NOT NULL DEFAULT 0,  -- 跟踪止损激活后的最优价，未激活为 0
     UNIQUE(user_id, client_order_id)
 );
 
@@ -128,10 +143,85 @@ COMMENT ON COLUMN exchange_order.orders.stop_price IS 'scaled by 10^price_precis
 COMMENT ON COLUMN exchange_order.orders.orig_qty IS 'scaled by 10^qty_precision';
 COMMENT ON COLUMN exchange_order.orders.executed_qty IS 'scaled by 10^qty_precision';
 COMMENT ON COLUMN exchange_order.orders.cumulative_quote_qty IS 'scaled by 10^price_precision';
+COMMENT ON COLUMN exchange_order.orders.frozen_quote_qty IS 'scaled by 10^price_precision';
+COMMENT ON COLUMN exchange_order.orders.display_qty IS 'scaled by 10^qty_precision';
+COMMENT ON COLUMN exchange_order.orders.quote_order_qty IS 'scaled by 10^price_precision';
+COMMENT ON COLUMN exchange_order.orders.trailing_delta IS 'scaled by 10^price_precision';
+COMMENT ON COLUMN exchange_order.orders.activation_price IS 'scaled by 10^price_precision';
+COMMENT ON COLUMN exchange_order.orders.trailing_extreme_price IS 'scaled by 10^price_precision';
 
 CREATE INDEX idx_orders_user_status ON exchange_order.orders(user_id, status, update_time_ms DESC);
 CREATE INDEX idx_orders_user_symbol ON exchange_order.orders(user_id, symbol, update_time_ms DESC);
 CREATE INDEX idx_orders_symbol ON exchange_order.orders(symbol, update_time_ms DESC);
+CREATE INDEX idx_orders_quote ON exchange_order.orders(quote_id) WHERE quote_id IS NOT NULL;
+CREATE INDEX idx_orders_list ON exchange_order.orders(list_id) WHERE list_id IS NOT NULL;
+
+-- 订单列表（OCO）：一组订单共用一次冻结，任一订单成交或触发后撤销其余订单
+CREATE TABLE exchange_order.order_lists (
+    list_id BIGINT PRIMARY KEY,
+    client_list_id VARCHAR(64),
+    user_id BIGINT NOT NULL,
+    symbol VARCHAR(32) NOT NULL,
+    contingency_type VARCHAR(16) NOT NULL,  -- OCO
+    side SMALLINT NOT NULL,  -- 1=BUY, 2=SELL
+    freeze_asset VARCHAR(16) NOT NULL,
+    frozen_amount BIGINT NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 1,  -- 1=EXECUTING, 2=ALL_DONE, 3=REJECTED
+    triggered_order_id BIGINT,  -- 第一条成交、触发或终止的订单，NULL 表示均未推进
+    create_time_ms BIGINT NOT NULL,
+    update_time_ms BIGINT NOT NULL,
+    UNIQUE(user_id, client_list_id)
+);
+
+COMMENT ON COLUMN exchange_order.order_lists.frozen_amount IS 'shared freeze of all orders in the list, scaled by asset precision';
+
+-- 算法单（TWAP / VWAP）：母单按计划拆分为 LIMIT IOC 子单，由订单服务调度
+CREATE TABLE exchange_order.algo_orders (
+    algo_id BIGINT PRIMARY KEY,
+    client_algo_id VARCHAR(64),
+    user_id BIGINT NOT NULL,
+    symbol VARCHAR(32) NOT NULL,
+    side SMALLINT NOT NULL,  -- 1=BUY, 2=SELL
+    algo_type VARCHAR(8) NOT NULL,  -- TWAP / VWAP
+    total_qty BIGINT NOT NULL,
+    executed_qty BIGINT NOT NULL DEFAULT 0,
+    executed_quote_qty BIGINT NOT NULL DEFAULT 0,
+    limit_price BIGINT NOT NULL,
+    participation_bps INT NOT NULL DEFAULT 0,  -- 子单不超过区间市场成交量的比例，0 表示不限
+    slice_interval_ms BIGINT NOT NULL,
+    start_time_ms BIGINT NOT NULL,
+    end_time_ms BIGINT NOT NULL,
+    next_slice_time_ms BIGINT NOT NULL,
+    last_slice_time_ms BIGINT NOT NULL,
+    expected_volume BIGINT NOT NULL DEFAULT 0,  -- VWAP 预计区间市场成交量，0 表示按 TWAP 节奏
+    market_volume BIGINT NOT NULL DEFAULT 0,  -- 开始后累计观察到的市场成交量
+    child_count INT NOT NULL DEFAULT 0,
+    active_child_id BIGINT,  -- 尚未结算的子单，NULL 表示无
+    status SMALLINT NOT NULL DEFAULT 1,  -- 1=RUNNING, 2=FINISHED, 3=CANCELED, 4=EXPIRED, 5=FAILED
+    reason VARCHAR(64),
+    create_time_ms BIGINT NOT NULL,
+    update_time_ms BIGINT NOT NULL,
+    UNIQUE(user_id, client_algo_id)
+);
+
+COMMENT ON COLUMN exchange_order.algo_orders.total_qty IS 'scaled by 10^qty_precision';
+COMMENT ON COLUMN exchange_order.algo_orders.limit_price IS 'scaled by 10^price_precision';
+
+CREATE INDEX idx_algo_orders_due ON exchange_order.algo_orders(next_slice_time_ms)
+    WHERE status = 1 OR active_child_id IS NOT NULL;
+CREATE INDEX idx_algo_orders_user ON exchange_order.algo_orders(user_id, create_time_ms DESC);
+
+-- 用户风控设置：账户等级决定下单前风控限额，trading_disabled 禁止新下单（不影响撤单）
+CREATE TABLE exchange_order.user_risk_settings (
+    user_id BIGINT PRIMARY KEY,
+    tier VARCHAR(32) NOT NULL DEFAULT '',  -- 账户等级，空表示使用全局限额
+    trading_disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    reason VARCHAR(255),
+    updated_by BIGINT NOT NULL DEFAULT 0,  -- 最后修改的后台用户
+    updated_at_ms BIGINT NOT NULL
+);
+
+COMMENT ON TABLE exchange_order.user_risk_settings IS 'per-user pre-trade risk settings, users without a row use the global limits';
 
 -- 成交表
 CREATE TABLE exchange_order.trades (
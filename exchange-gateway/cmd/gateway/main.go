@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
 	"syscall"
 	"time"
@@ -23,6 +24,7 @@ import (
 	"github.com/exchange/common/pkg/logger"
 	commonredis "github.com/exchange/common/pkg/redis"
 	commonresp "github.com/exchange/common/pkg/response"
+	"github.com/exchange/common/pkg/shard"
 	"github.com/exchange/common/pkg/tracing"
 	"github.com/exchange/gateway/internal/config"
 	"github.com/exchange/gateway/internal/middleware"
@@ -108,8 +110,18 @@ func main() {
 	}
 	l.Info("Connected to Redis")
 
+	matchingShards, err := shard.NewRouter(cfg.MatchingShards, redisClient)
+	if err != nil {
+		l.Error(fmt.Sprintf("Invalid matching shard config: %v", err))
+		os.Exit(1)
+	}
+
 	// Private events (pub/sub) consumer (powers private websocket push).
 	hub := ws.NewHub()
+	// 断线撤单 / 倒计时撤单（dead man's switch）
+	deadMan := ws.NewDeadManSwitch(redisClient, ws.DefaultCountdownKey, cancelAllOpenOrders(cfg.OrderServiceURL, cfg.InternalToken))
+	hub.SetDeadManSwitch(deadMan)
+	go deadMan.Run(ctx, 500*time.Millisecond)
 	consumer := ws.NewConsumer(redisClient, hub, cfg.PrivateUserEventChannel)
 	var privateEventLoop health.LoopMonitor
 	go runPrivateConsumer(ctx, consumer, &privateEventLoop, l)
@@ -155,6 +167,12 @@ func main() {
 	mux.HandleFunc("/v1/depth", proxyHandler(cfg.MarketDataServiceURL, cfg.InternalToken, l))
 	mux.HandleFunc("/v1/trades", proxyHandler(cfg.MarketDataServiceURL, cfg.InternalToken, l))
 	mux.HandleFunc("/v1/ticker", proxyHandler(cfg.MarketDataServiceURL, cfg.InternalToken, l))
+	mux.HandleFunc("/v1/auction", proxyHandler(cfg.MarketDataServiceURL, cfg.InternalToken, l))
+	// 逐笔订单簿快照由交易对所属撮合分片直接生成；快照需遍历整本订单簿，单独限流
+	l3Limiter := middleware.NewRateLimiter(cfg.L3RateLimit, time.Second)
+	mux.Handle("/v1/depth/l3", middleware.RateLimit(l3Limiter, middleware.IPKeyFunc)(
+		l3Handler(cfg.MatchingShards.ServiceURLs(cfg.MatchingServiceURL), matchingShards, cfg.InternalToken, l),
+	))
 
 	// 代理到 user 服务 (Auth)
 	mux.HandleFunc("/v1/auth/register", proxyHandler(cfg.UserServiceURL, cfg.InternalToken, l))
@@ -336,12 +354,47 @@ func main() {
 		middleware.RequirePermissionByMethod(map[string]int{
 			http.MethodGet:    middleware.PermRead,
 			http.MethodPost:   middleware.PermTrade,
+			http.MethodPut:    middleware.PermTrade,
 			http.MethodDelete: middleware.PermTrade,
 		}, 0)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
 	)
 	privateMux.Handle("/v1/openOrders",
+		middleware.RequirePermissionByMethod(map[string]int{
+			http.MethodGet:    middleware.PermRead,
+			http.MethodDelete: middleware.PermTrade,
+		}, 0)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
+	)
+	privateMux.Handle("/v1/batchOrders",
+		middleware.RequirePermissionByMethod(map[string]int{
+			http.MethodPost:   middleware.PermTrade,
+			http.MethodDelete: middleware.PermTrade,
+		}, 0)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
+	)
+	privateMux.Handle("/v1/massQuote",
+		middleware.RequirePermission(middleware.PermTrade)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
+	)
+	privateMux.Handle("/v1/orderList/oco",
+		middleware.RequirePermission(middleware.PermTrade)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
+	)
+	privateMux.Handle("/v1/orderList",
+		middleware.RequirePermissionByMethod(map[string]int{
+			http.MethodGet:    middleware.PermRead,
+			http.MethodDelete: middleware.PermTrade,
+		}, 0)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
+	)
+	privateMux.Handle("/v1/algoOrder",
+		middleware.RequirePermissionByMethod(map[string]int{
+			http.MethodGet:    middleware.PermRead,
+			http.MethodPost:   middleware.PermTrade,
+			http.MethodDelete: middleware.PermTrade,
+		}, 0)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
+	)
+	privateMux.Handle("/v1/openAlgoOrders",
 		middleware.RequirePermission(middleware.PermRead)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
 	)
+	privateMux.Handle("/v1/countdownCancelAll",
+		middleware.RequirePermission(middleware.PermTrade)(countdownCancelAllHandler(deadMan)),
+	)
 	privateMux.Handle("/v1/allOrders",
 		middleware.RequirePermission(middleware.PermRead)(http.HandlerFunc(proxyHandler(cfg.OrderServiceURL, cfg.InternalToken, l))),
 	)
@@ -363,6 +416,13 @@ func main() {
 	// 注册私有路由
 	mux.Handle("/v1/order", authHandler)
 	mux.Handle("/v1/openOrders", authHandler)
+	mux.Handle("/v1/batchOrders", authHandler)
+	mux.Handle("/v1/massQuote", authHandler)
+	mux.Handle("/v1/orderList/oco", authHandler)
+	mux.Handle("/v1/orderList", authHandler)
+	mux.Handle("/v1/algoOrder", authHandler)
+	mux.Handle("/v1/openAlgoOrders", authHandler)
+	mux.Handle("/v1/countdownCancelAll", authHandler)
 	mux.Handle("/v1/allOrders", authHandler)
 	mux.Handle("/v1/myTrades", authHandler)
 	mux.Handle("/v1/account", authHandler)
@@ -421,7 +481,9 @@ func main() {
 
 	l.Info("Shutting down...")
 	cancel()
+	deadMan.Shutdown()
 	hub.CloseAll()
+	deadMan.Wait()
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 	wsServer.Shutdown(shutdownCtx)
@@ -521,6 +583,37 @@ func writeHealth(w http.ResponseWriter, deps []dependencyStatus) {
 }
 
 // proxyHandler 创建代理处理器
+// l3SymbolPattern 交易对格式（大写字母、数字与下划线）
+var l3SymbolPattern = regexp.MustCompile(`^[A-Z0-9_]{2,20}$`)
+
+// l3Handler 校验交易对后转发到其所属撮合分片
+func l3Handler(shardURLs []string, shards *shard.Router, internalToken string, l *logger.Logger) http.HandlerFunc {
+	proxies := make([]http.HandlerFunc, len(shardURLs))
+	for i, url := range shardURLs {
+		proxies[i] = proxyHandler(url, internalToken, l)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol")))
+		if !l3SymbolPattern.MatchString(symbol) {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidParam, "invalid symbol")
+			return
+		}
+		idx, err := shards.Shard(r.Context(), symbol)
+		if err != nil || idx >= len(proxies) {
+			if l != nil {
+				l.Error(fmt.Sprintf("route l3 request for %s: shard %d, %v", symbol, idx, err))
+			}
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeUnavailable, "order book snapshot unavailable")
+			return
+		}
+		r = r.Clone(r.Context())
+		q := r.URL.Query()
+		q.Set("symbol", symbol)
+		r.URL.RawQuery = q.Encode()
+		proxies[idx](w, r)
+	}
+}
+
 func proxyHandler(targetURL string, internalToken string, l *logger.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 构建目标 URL（禁止信任客户端 userId；由网关注入）
@@ -602,6 +695,69 @@ func proxyHandler(targetURL string, internalToken string, l *logger.Logger) http
 	}
 }
 
+// cancelAllOpenOrders 通过订单服务撤销用户全部挂单（断线撤单 / 倒计时撤单）
+func cancelAllOpenOrders(orderServiceURL, internalToken string) ws.CancelAllFunc {
+	return func(ctx context.Context, userID int64) error {
+		target := strings.TrimRight(orderServiceURL, "/") + "/v1/openOrders"
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, target, nil)
+		if err != nil {
+			return fmt.Errorf("create cancel request: %w", err)
+		}
+		req.Header.Set("X-Internal-Token", internalToken)
+		req.Header.Set("X-User-Id", fmt.Sprintf("%d", userID))
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("call order service: %w", err)
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("order service returned %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// countdownCancelAllHandler 倒计时撤单心跳：到期未续期则撤销全部挂单，countdownTime=0 取消倒计时
+func countdownCancelAllHandler(deadMan *ws.DeadManSwitch) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+			return
+		}
+		var req struct {
+			CountdownTime int64 `json:"countdownTime"` // 毫秒
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, "invalid request body")
+			return
+		}
+		if req.CountdownTime < 0 {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, "countdownTime must not be negative")
+			return
+		}
+
+		userID := middleware.GetUserID(r.Context())
+		triggerTime, err := deadMan.SetCountdown(r.Context(), userID, time.Duration(req.CountdownTime)*time.Millisecond)
+		if errors.Is(err, ws.ErrInvalidCountdown) {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest,
+				fmt.Sprintf("countdownTime must be 0 or between %d and %d", ws.MinCountdown.Milliseconds(), ws.MaxCountdown.Milliseconds()))
+			return
+		}
+		if err != nil {
+			commonresp.WriteStatusError(w, r, http.StatusServiceUnavailable, commonerrors.CodeUnavailable, "countdown unavailable")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int64{
+			"countdownTime": req.CountdownTime,
+			"triggerTime":   triggerTime,
+		})
+	}
+}
+
 // corsMiddleware CORS 中间件
 func corsMiddleware(allowedOrigins []string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	envconfig "github.com/exchange/common/pkg/config"
+	"github.com/exchange/common/pkg/shard"
 )
 
 // Config 服务配置
@@ -21,6 +22,8 @@ type Config struct {
 	UserServiceURL       string
 	MatchingServiceURL   string
 	MarketDataServiceURL string
+	// 撮合分片：逐笔订单簿快照转发到交易对所属分片
+	MatchingShards shard.Config // URLs 未配置时使用 MatchingServiceURL
 
 	// Redis
 	RedisAddr     string
@@ -43,6 +46,7 @@ type Config struct {
 	// 限流
 	IPRateLimit   int // 每秒请求数
 	UserRateLimit int
+	L3RateLimit   int // 逐笔订单簿快照每 IP 每秒请求数（快照需撮合遍历整本订单簿）
 }
 
 // Load 加载配置
@@ -63,6 +67,7 @@ func Load() *Config {
 		UserServiceURL:       envconfig.GetEnv("USER_SERVICE_URL", "http://localhost:8085"),
 		MatchingServiceURL:   envconfig.GetEnv("MATCHING_SERVICE_URL", "http://localhost:8082"),
 		MarketDataServiceURL: envconfig.GetEnv("MARKETDATA_SERVICE_URL", "http://localhost:8084"),
+		MatchingShards:       shard.ConfigFromEnv(),
 
 		RedisAddr:     envconfig.GetEnv("REDIS_ADDR", "localhost:6380"), // 默认使用6380避免与本地Redis冲突
 		RedisPassword: envconfig.GetEnv("REDIS_PASSWORD", ""),
@@ -78,6 +83,7 @@ func Load() *Config {
 
 		IPRateLimit:   envconfig.GetEnvInt("IP_RATE_LIMIT", 100),
 		UserRateLimit: envconfig.GetEnvInt("USER_RATE_LIMIT", 50),
+		L3RateLimit:   envconfig.GetEnvInt("L3_RATE_LIMIT", 1),
 	}
 }
 
@@ -85,6 +91,15 @@ func (c *Config) Validate() error {
 	if c.InternalToken == "" {
 		return fmt.Errorf("INTERNAL_TOKEN is required")
 	}
+	if err := c.MatchingShards.Validate(); err != nil {
+		return fmt.Errorf("invalid MATCHING_SHARD_COUNT/MATCHING_SHARDS: %w", err)
+	}
+	if err := c.MatchingShards.ValidateURLs(); err != nil {
+		return fmt.Errorf("MATCHING_SHARD_URLS must list one URL per shard: %w", err)
+	}
+	if c.L3RateLimit < 1 {
+		return fmt.Errorf("L3_RATE_LIMIT must be positive")
+	}
 	if c.AppEnv != "dev" {
 		if envconfig.IsInsecureDevSecret(c.InternalToken) {
 			return fmt.Errorf("INTERNAL_TOKEN must not be a dev placeholder (APP_ENV=%s)", c.AppEnv)
@@ -0,0 +1,202 @@
+// Package ws cancel-on-disconnect (dead man's switch) for API sessions.
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultCountdownKey is the sorted set holding countdown deadlines (member userID, score ms).
+	DefaultCountdownKey = "gateway:countdown-cancel"
+
+	// MinCountdown / MaxCountdown bound the countdown accepted by SetCountdown.
+	MinCountdown = time.Second
+	MaxCountdown = 10 * time.Minute
+
+	queryCancelOnDisconnect = "cancelOnDisconnect"
+
+	cancelAllAttempts = 3
+	cancelAllTimeout  = 5 * time.Second
+	sweepBatchSize    = 100
+)
+
+// ErrInvalidCountdown is returned for a countdown outside [MinCountdown, MaxCountdown].
+var ErrInvalidCountdown = errors.New("countdown out of range")
+
+// claimExpiredScript removes a countdown only if it is still expired, so a
+// heartbeat that lands between the scan and the claim keeps the orders alive.
+var claimExpiredScript = redis.NewScript(`
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if score and tonumber(score) <= tonumber(ARGV[2]) then
+	return redis.call('ZREM', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// CancelAllFunc cancels all open orders of a user.
+type CancelAllFunc func(ctx context.Context, userID int64) error
+
+// DeadManSwitch cancels a user's open orders when the user's last opted-in
+// private connection drops, or when a countdown set via the REST heartbeat
+// expires.
+//
+// Countdowns live in Redis so any gateway instance can refresh them; every
+// instance sweeps expired entries and the atomic claim makes exactly one of
+// them trigger the cancel.
+type DeadManSwitch struct {
+	client    redis.Cmdable
+	key       string
+	cancelAll CancelAllFunc
+	wg        sync.WaitGroup
+
+	// Opted-in connections per user on this instance; closing is set on
+	// shutdown so connections closed by the gateway itself don't trigger.
+	mu       sync.Mutex
+	sessions map[int64]int
+	closing  bool
+}
+
+// NewDeadManSwitch creates a dead man's switch.
+func NewDeadManSwitch(client redis.Cmdable, key string, cancelAll CancelAllFunc) *DeadManSwitch {
+	if key == "" {
+		key = DefaultCountdownKey
+	}
+	return &DeadManSwitch{
+		client:    client,
+		key:       key,
+		cancelAll: cancelAll,
+		sessions:  make(map[int64]int),
+	}
+}
+
+// SetCountdown arms (or re-arms) the countdown for a user and returns the
+// trigger time in ms. A zero countdown disarms it and returns 0.
+func (d *DeadManSwitch) SetCountdown(ctx context.Context, userID int64, countdown time.Duration) (int64, error) {
+	member := strconv.FormatInt(userID, 10)
+	if countdown == 0 {
+		return 0, d.client.ZRem(ctx, d.key, member).Err()
+	}
+	if countdown < MinCountdown || countdown > MaxCountdown {
+		return 0, ErrInvalidCountdown
+	}
+	triggerAt := time.Now().Add(countdown).UnixMilli()
+	if err := d.client.ZAdd(ctx, d.key, redis.Z{Score: float64(triggerAt), Member: member}).Err(); err != nil {
+		return 0, err
+	}
+	return triggerAt, nil
+}
+
+// Connected registers an opted-in connection of the user.
+func (d *DeadManSwitch) Connected(userID int64) {
+	d.mu.Lock()
+	d.sessions[userID]++
+	d.mu.Unlock()
+}
+
+// Disconnected is called when an opted-in connection of the user drops. The
+// cancel fires only when it was the user's last opted-in connection, and
+// never after Shutdown.
+func (d *DeadManSwitch) Disconnected(userID int64) {
+	d.mu.Lock()
+	if n := d.sessions[userID] - 1; n > 0 {
+		d.sessions[userID] = n
+		d.mu.Unlock()
+		return
+	}
+	delete(d.sessions, userID)
+	closing := d.closing
+	d.mu.Unlock()
+	if closing {
+		return
+	}
+	d.trigger(userID, "disconnect")
+}
+
+// Shutdown deregisters connections without triggering: call it before the
+// gateway closes its connections so a restart doesn't cancel every opted-in
+// user's orders. Countdowns stay in Redis for the other instances.
+func (d *DeadManSwitch) Shutdown() {
+	d.mu.Lock()
+	d.closing = true
+	d.mu.Unlock()
+}
+
+// Run sweeps expired countdowns until ctx is done.
+func (d *DeadManSwitch) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.sweep(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				log.Printf("countdown cancel sweep error: %v", err)
+			}
+		}
+	}
+}
+
+func (d *DeadManSwitch) sweep(ctx context.Context, now time.Time) error {
+	nowMs := strconv.FormatInt(now.UnixMilli(), 10)
+	members, err := d.client.ZRangeByScore(ctx, d.key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   nowMs,
+		Count: sweepBatchSize,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("scan countdowns: %w", err)
+	}
+	for _, member := range members {
+		claimed, err := claimExpiredScript.Run(ctx, d.client, []string{d.key}, member, nowMs).Int()
+		if err != nil {
+			return fmt.Errorf("claim countdown: %w", err)
+		}
+		if claimed == 0 {
+			continue
+		}
+		userID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		d.trigger(userID, "countdown")
+	}
+	return nil
+}
+
+func (d *DeadManSwitch) trigger(userID int64, reason string) {
+	if d.cancelAll == nil {
+		return
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		var err error
+		for attempt := 0; attempt < cancelAllAttempts; attempt++ {
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), cancelAllTimeout)
+			err = d.cancelAll(ctx, userID)
+			cancel()
+			if err == nil {
+				log.Printf("cancel-on-disconnect triggered: user=%d reason=%s", userID, reason)
+				return
+			}
+		}
+		log.Printf("cancel-on-disconnect failed: user=%d reason=%s err=%v", userID, reason, err)
+	}()
+}
+
+// Wait blocks until all triggered cancels have finished.
+func (d *DeadManSwitch) Wait() {
+	d.wg.Wait()
+}
@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/exchange/gateway/internal/middleware"
+	"github.com/redis/go-redis/v9"
+)
+
+type cancelRecorder struct {
+	mu    sync.Mutex
+	users []int64
+}
+
+func (c *cancelRecorder) cancelAll(_ context.Context, userID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.users = append(c.users, userID)
+	return nil
+}
+
+func (c *cancelRecorder) calls() []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int64(nil), c.users...)
+}
+
+func newTestDeadMan(t *testing.T) (*DeadManSwitch, *cancelRecorder, *miniredis.Miniredis) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	recorder := &cancelRecorder{}
+	return NewDeadManSwitch(client, "", recorder.cancelAll), recorder, mr
+}
+
+func TestDeadManCountdownExpires(t *testing.T) {
+	deadMan, recorder, _ := newTestDeadMan(t)
+	ctx := context.Background()
+
+	if _, err := deadMan.SetCountdown(ctx, 7, 500*time.Millisecond); err != ErrInvalidCountdown {
+		t.Fatalf("expected ErrInvalidCountdown, got %v", err)
+	}
+	triggerAt, err := deadMan.SetCountdown(ctx, 7, 2*time.Second)
+	if err != nil {
+		t.Fatalf("set countdown: %v", err)
+	}
+	if _, err := deadMan.SetCountdown(ctx, 8, 5*time.Second); err != nil {
+		t.Fatalf("set countdown: %v", err)
+	}
+
+	if err := deadMan.sweep(ctx, time.UnixMilli(triggerAt-1)); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	deadMan.Wait()
+	if len(recorder.calls()) != 0 {
+		t.Fatalf("expected no cancel before deadline, got %v", recorder.calls())
+	}
+
+	if err := deadMan.sweep(ctx, time.UnixMilli(triggerAt)); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	deadMan.Wait()
+	if calls := recorder.calls(); len(calls) != 1 || calls[0] != 7 {
+		t.Fatalf("expected user 7 canceled once, got %v", calls)
+	}
+
+	// 已触发的倒计时被移除，不会重复撤单
+	if err := deadMan.sweep(ctx, time.UnixMilli(triggerAt+1000)); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	deadMan.Wait()
+	if len(recorder.calls()) != 1 {
+		t.Fatalf("expected single cancel, got %v", recorder.calls())
+	}
+}
+
+func TestDeadManCountdownDisarm(t *testing.T) {
+	deadMan, recorder, mr := newTestDeadMan(t)
+	ctx := context.Background()
+
+	if _, err := deadMan.SetCountdown(ctx, 7, time.Second); err != nil {
+		t.Fatalf("set countdown: %v", err)
+	}
+	if triggerAt, err := deadMan.SetCountdown(ctx, 7, 0); err != nil || triggerAt != 0 {
+		t.Fatalf("disarm: triggerAt=%d err=%v", triggerAt, err)
+	}
+	if mr.Exists(DefaultCountdownKey) {
+		t.Fatal("expected countdown removed")
+	}
+	if err := deadMan.sweep(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	deadMan.Wait()
+	if len(recorder.calls()) != 0 {
+		t.Fatalf("expected no cancel after disarm, got %v", recorder.calls())
+	}
+}
+
+func TestDeadManTriggersOnLastConnection(t *testing.T) {
+	deadMan, recorder, _ := newTestDeadMan(t)
+	deadMan.Connected(42)
+	deadMan.Connected(42)
+
+	deadMan.Disconnected(42)
+	deadMan.Wait()
+	if len(recorder.calls()) != 0 {
+		t.Fatalf("expected no cancel while another connection is open, got %v", recorder.calls())
+	}
+
+	deadMan.Disconnected(42)
+	deadMan.Wait()
+	if calls := recorder.calls(); len(calls) != 1 || calls[0] != 42 {
+		t.Fatalf("expected user 42 canceled once, got %v", calls)
+	}
+}
+
+func TestDeadManShutdownDoesNotTrigger(t *testing.T) {
+	deadMan, recorder, _ := newTestDeadMan(t)
+	deadMan.Connected(42)
+	deadMan.Connected(43)
+
+	deadMan.Shutdown()
+	deadMan.Disconnected(42)
+	deadMan.Disconnected(43)
+	deadMan.Wait()
+	if len(recorder.calls()) != 0 {
+		t.Fatalf("expected no cancel on shutdown, got %v", recorder.calls())
+	}
+}
+
+func TestPrivateHandlerCancelOnDisconnect(t *testing.T) {
+	deadMan, recorder, _ := newTestDeadMan(t)
+	hub := NewHub()
+	hub.SetDeadManSwitch(deadMan)
+	authCfg := &middleware.AuthConfig{
+		TimeWindow: 30 * time.Second,
+		VerifySignature: func(ctx context.Context, req *middleware.VerifySignatureRequest) (int64, int, error) {
+			switch req.APIKey {
+			case "trade-key":
+				return 42, middleware.PermRead | middleware.PermTrade, nil
+			case "read-key":
+				return 43, middleware.PermRead, nil
+			}
+			return 0, 0, errInvalidAPIKey
+		},
+	}
+	server := httptest.NewServer(PrivateHandler(hub, authCfg, []string{"*"}))
+	defer server.Close()
+
+	// 只读 Key 不能开启断线撤单
+	readConn := dialPrivateWithQuery(t, server.URL, "read-key", "sig", queryCancelOnDisconnect, "true")
+	defer readConn.Close()
+	if _, _, err := readConn.ReadMessage(); err == nil {
+		t.Fatal("expected read-only key rejected")
+	}
+
+	plain := dialPrivate(t, server.URL, "trade-key", "sig")
+	conn := dialPrivateWithQuery(t, server.URL, "trade-key", "sig", queryCancelOnDisconnect, "true")
+	waitFor(t, func() bool { return hub.ConnectionCount() == 2 })
+
+	plain.Close()
+	waitFor(t, func() bool { return hub.ConnectionCount() == 1 })
+	deadMan.Wait()
+	if len(recorder.calls()) != 0 {
+		t.Fatalf("expected no cancel for plain connection, got %v", recorder.calls())
+	}
+
+	conn.Close()
+	waitFor(t, func() bool { return len(recorder.calls()) == 1 })
+	if calls := recorder.calls(); calls[0] != 42 {
+		t.Fatalf("expected user 42 canceled, got %v", calls)
+	}
+}
@@ -20,6 +20,9 @@ type Client struct {
 	conn         *websocket.Conn
 	send         chan []byte
 	lastActivity int64
+
+	// cancelOnDisconnect cancels the user's open orders when this connection drops.
+	cancelOnDisconnect bool
 }
 
 func (c *Client) touch() {
@@ -37,6 +40,7 @@ type Hub struct {
 	maxPerUser        int
 	total             int64
 	activeConnections int64
+	deadMan           *DeadManSwitch
 }
 
 // NewHub creates a new hub.
@@ -58,6 +62,19 @@ func NewHubWithMaxConnections(limit int) *Hub {
 	}
 }
 
+// SetDeadManSwitch enables cancel-on-disconnect for opted-in connections.
+func (h *Hub) SetDeadManSwitch(d *DeadManSwitch) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deadMan = d
+}
+
+func (h *Hub) deadManSwitch() *DeadManSwitch {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.deadMan
+}
+
 // Subscribe registers a connection for a user and returns the client wrapper.
 func (h *Hub) Subscribe(userID int64, conn *websocket.Conn) (*Client, error) {
 	client := &Client{
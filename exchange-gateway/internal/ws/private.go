@@ -64,6 +64,11 @@ func PrivateHandler(hub *Hub, authCfg *middleware.AuthConfig, allowedOrigins []s
 			closeWithCode(conn, 4001, "unauthorized")
 			return
 		}
+		cancelOnDisconnect := wantsCancelOnDisconnect(r)
+		if cancelOnDisconnect && hub.deadManSwitch() == nil {
+			closeWithCode(conn, 4002, "cancel on disconnect unavailable")
+			return
+		}
 
 		client, err := hub.Subscribe(userID, conn)
 		if err != nil {
@@ -72,6 +77,10 @@ func PrivateHandler(hub *Hub, authCfg *middleware.AuthConfig, allowedOrigins []s
 			conn.Close()
 			return
 		}
+		client.cancelOnDisconnect = cancelOnDisconnect
+		if cancelOnDisconnect {
+			hub.deadManSwitch().Connected(userID)
+		}
 
 		go writePump(client, userID, hub)
 		go readPump(client, userID, hub)
@@ -101,6 +110,11 @@ func readPump(client *Client, userID int64, hub *Hub) {
 	defer func() {
 		hub.Unsubscribe(userID, client)
 		conn.Close()
+		if client.cancelOnDisconnect {
+			if deadMan := hub.deadManSwitch(); deadMan != nil {
+				deadMan.Disconnected(userID)
+			}
+		}
 	}()
 
 	conn.SetReadLimit(4096)
@@ -217,12 +231,22 @@ func authenticateRequest(r *http.Request, cfg *middleware.AuthConfig) (int64, er
 		if (res.permissions & middleware.PermRead) == 0 {
 			return 0, fmt.Errorf("permission denied")
 		}
+		// Cancel-on-disconnect cancels orders, so it needs a trading key.
+		if wantsCancelOnDisconnect(r) && (res.permissions&middleware.PermTrade) == 0 {
+			return 0, fmt.Errorf("permission denied")
+		}
 		return res.userID, nil
 	case <-ctx.Done():
 		return 0, fmt.Errorf("auth timeout")
 	}
 }
 
+// wantsCancelOnDisconnect reports whether the connection opted in (part of the signed query).
+func wantsCancelOnDisconnect(r *http.Request) bool {
+	v, _ := strconv.ParseBool(r.URL.Query().Get(queryCancelOnDisconnect))
+	return v
+}
+
 func cloneQueryWithoutSignature(query map[string][]string) map[string][]string {
 	if len(query) == 0 {
 		return map[string][]string{}
@@ -199,8 +199,16 @@ func TestAuthHubCloseAll(t *testing.T) {
 var errInvalidAPIKey = fmt.Errorf("invalid api key")
 
 func dialPrivate(t *testing.T, serverURL, apiKey, secret string) *websocket.Conn {
+	return dialPrivateWithQuery(t, serverURL, apiKey, secret)
+}
+
+// dialPrivateWithQuery dials with extra key/value query pairs.
+func dialPrivateWithQuery(t *testing.T, serverURL, apiKey, secret string, extra ...string) *websocket.Conn {
 	timestamp := time.Now().UnixMilli()
 	values := url.Values{}
+	for i := 0; i+1 < len(extra); i += 2 {
+		values.Set(extra[i], extra[i+1])
+	}
 	values.Set(queryAPIKey, apiKey)
 	values.Set(queryTimestamp, strconv.FormatInt(timestamp, 10))
 	values.Set(queryNonce, "nonce")
@@ -16,6 +16,8 @@ import (
 	commonerrors "github.com/exchange/common/pkg/errors"
 	commonredis "github.com/exchange/common/pkg/redis"
 	commonresp "github.com/exchange/common/pkg/response"
+	"github.com/exchange/common/pkg/shard"
+	"github.com/exchange/marketdata/internal/client"
 	"github.com/exchange/marketdata/internal/config"
 	"github.com/exchange/marketdata/internal/service"
 	"github.com/exchange/marketdata/internal/ws"
@@ -64,6 +66,15 @@ func main() {
 		Consumer:    cfg.ConsumerName,
 		ReplayCount: cfg.ReplayCount,
 	})
+	shards, err := shard.NewRouter(cfg.MatchingShards, redisClient)
+	if err != nil {
+		log.Fatalf("Invalid matching shard config: %v", err)
+	}
+	books, err := client.NewMatchingClient(cfg.MatchingShards.ServiceURLs(cfg.MatchingServiceURL), shards, cfg.InternalToken)
+	if err != nil {
+		log.Fatalf("Invalid matching shard config: %v", err)
+	}
+	svc.SetBookSource(books)
 
 	// 启动事件消费
 	if err := svc.Start(ctx); err != nil {
@@ -175,6 +186,18 @@ func main() {
 		}
 	}))
 
+	// 集合竞价参考价
+	mux.HandleFunc("/v1/auction", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		symbol := r.URL.Query().Get("symbol")
+		if symbol == "" {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidParam, "symbol required")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(svc.GetAuction(symbol))
+	}))
+
 	// WebSocket 连接数
 	mux.HandleFunc("/stats", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
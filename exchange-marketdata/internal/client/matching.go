@@ -0,0 +1,72 @@
+// Package client 撮合服务客户端
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/exchange/common/pkg/shard"
+	"github.com/exchange/marketdata/internal/service"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// MatchingClient 按交易对所属分片读取撮合逐笔订单簿快照
+type MatchingClient struct {
+	shardURLs     []string // 按分片号排列的撮合实例地址
+	shards        *shard.Router
+	internalToken string
+	httpClient    *http.Client
+}
+
+// NewMatchingClient 创建撮合客户端
+func NewMatchingClient(shardURLs []string, shards *shard.Router, internalToken string) (*MatchingClient, error) {
+	if len(shardURLs) == 0 || shards == nil || len(shardURLs) != shards.Count() {
+		return nil, fmt.Errorf("need one matching URL per shard")
+	}
+	c := &MatchingClient{
+		shardURLs:     make([]string, len(shardURLs)),
+		shards:        shards,
+		internalToken: internalToken,
+		httpClient:    &http.Client{Timeout: defaultTimeout},
+	}
+	for i, u := range shardURLs {
+		c.shardURLs[i] = strings.TrimRight(u, "/")
+	}
+	return c, nil
+}
+
+// BookL3 获取逐笔订单簿快照
+func (c *MatchingClient) BookL3(ctx context.Context, symbol string) (*service.L3Book, error) {
+	id, err := c.shards.Shard(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("route symbol: %w", err)
+	}
+	target := fmt.Sprintf("%s/v1/depth/l3?symbol=%s", c.shardURLs[id], url.QueryEscape(symbol))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if c.internalToken != "" {
+		req.Header.Set("X-Internal-Token", c.internalToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get l3 book: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("l3 book status: %d", resp.StatusCode)
+	}
+	var book service.L3Book
+	if err := json.NewDecoder(resp.Body).Decode(&book); err != nil {
+		return nil, fmt.Errorf("decode l3 book: %w", err)
+	}
+	return &book, nil
+}
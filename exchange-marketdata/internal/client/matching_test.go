@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/exchange/common/pkg/shard"
+	"github.com/exchange/marketdata/internal/service"
+)
+
+func TestMatchingClient_BookL3Sharded(t *testing.T) {
+	newShard := func(seq int64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/depth/l3" || r.Header.Get("X-Internal-Token") != "token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			book := service.L3Book{Symbol: r.URL.Query().Get("symbol"), Seq: seq, Bids: []service.L3Order{{OrderID: 1, Price: 100, Qty: 5}}}
+			if err := json.NewEncoder(w).Encode(book); err != nil {
+				t.Fatalf("encode response: %v", err)
+			}
+		}))
+	}
+	shard0 := newShard(10)
+	defer shard0.Close()
+	shard1 := newShard(20)
+	defer shard1.Close()
+
+	router, err := shard.NewRouter(shard.Config{Count: 2, Assignments: "BTCUSDT=1,ETHUSDT=0"}, nil)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+	client, err := NewMatchingClient([]string{shard0.URL, shard1.URL + "/"}, router, "token")
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	ctx := context.Background()
+	if book, err := client.BookL3(ctx, "BTCUSDT"); err != nil || book.Seq != 20 || book.Symbol != "BTCUSDT" || len(book.Bids) != 1 {
+		t.Fatalf("expected BTCUSDT from shard 1, got %+v, %v", book, err)
+	}
+	if book, err := client.BookL3(ctx, "ETHUSDT"); err != nil || book.Seq != 10 {
+		t.Fatalf("expected ETHUSDT from shard 0, got %+v, %v", book, err)
+	}
+
+	if _, err := NewMatchingClient([]string{shard0.URL}, router, "token"); err == nil {
+		t.Fatal("expected URL count mismatch rejected")
+	}
+}
+
+func TestMatchingClient_BookL3StatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	router, _ := shard.NewRouter(shard.Config{Count: 1}, nil)
+	client, err := NewMatchingClient([]string{server.URL}, router, "")
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := client.BookL3(context.Background(), "BTCUSDT"); err == nil {
+		t.Fatal("expected error on non-200 status")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	envconfig "github.com/exchange/common/pkg/config"
+	"github.com/exchange/common/pkg/shard"
 )
 
 // Config 服务配置
@@ -26,6 +27,10 @@ type Config struct {
 	ConsumerName  string
 	ReplayCount   int
 
+	// 撮合分片：发现序列号缺口后从交易对所属分片读取逐笔订单簿快照重建盘口
+	MatchingServiceURL string
+	MatchingShards     shard.Config // URLs 未配置时使用 MatchingServiceURL
+
 	// Private events (pub/sub)
 	PrivateUserEventChannel string
 
@@ -59,6 +64,9 @@ func Load() *Config {
 		ConsumerName:  envconfig.GetEnv("CONSUMER_NAME", "marketdata-1"),
 		ReplayCount:   envconfig.GetEnvInt("EVENT_REPLAY_COUNT", 1000),
 
+		MatchingServiceURL: envconfig.GetEnv("MATCHING_SERVICE_URL", "http://localhost:8082"),
+		MatchingShards:     shard.ConfigFromEnv(),
+
 		PrivateUserEventChannel: envconfig.GetEnv("PRIVATE_USER_EVENT_CHANNEL", "private:user:{userId}:events"),
 
 		InternalToken: envconfig.GetEnv("INTERNAL_TOKEN", ""),
@@ -72,6 +80,12 @@ func (c *Config) Validate() error {
 	if c.InternalToken == "" {
 		return fmt.Errorf("INTERNAL_TOKEN is required")
 	}
+	if err := c.MatchingShards.Validate(); err != nil {
+		return fmt.Errorf("invalid MATCHING_SHARD_COUNT/MATCHING_SHARDS: %w", err)
+	}
+	if err := c.MatchingShards.ValidateURLs(); err != nil {
+		return fmt.Errorf("MATCHING_SHARD_URLS must list one URL per shard: %w", err)
+	}
 	if c.AppEnv != "dev" {
 		if envconfig.IsInsecureDevSecret(c.InternalToken) {
 			return fmt.Errorf("INTERNAL_TOKEN must not be a dev placeholder (APP_ENV=%s)", c.AppEnv)
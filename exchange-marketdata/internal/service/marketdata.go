@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/exchange/common/pkg/eventseq"
 	"github.com/exchange/common/pkg/health"
 	"github.com/redis/go-redis/v9"
 )
@@ -23,6 +24,12 @@ type MarketDataService struct {
 	replayCount int
 
 	loop health.LoopMonitor
+	seq  *eventseq.Tracker
+
+	// applyMu 串行化事件应用与盘口重建；stale 为发现缺口、等待按快照重建的交易对
+	applyMu sync.Mutex
+	stale   map[string]*resyncState
+	books   BookSource
 
 	// 内存盘口
 	depths map[string]*Depth
@@ -36,6 +43,9 @@ type MarketDataService struct {
 	// 24h ticker
 	tickers map[string]*Ticker
 
+	// 集合竞价参考价
+	auctions map[string]*Auction
+
 	// 订阅者
 	subscribers map[string][]chan *Event
 	subMu       sync.RWMutex
@@ -45,7 +55,9 @@ type orderLevel struct {
 	OrderID   int64
 	Side      int
 	Price     int64
-	LeavesQty int64
+	LeavesQty int64 // 计入盘口的数量（冰山单为当前展示数量）
+	TotalQty  int64 // 剩余数量（含冰山单隐藏部分）
+	Iceberg   bool
 }
 
 // Depth 盘口
@@ -63,6 +75,28 @@ type PriceLevel struct {
 	Qty   int64 `json:"qty"`
 }
 
+// L3 增量动作
+const (
+	L3Add    = "add"
+	L3Modify = "modify" // 数量变化，保留时间优先级
+	L3Delete = "delete"
+)
+
+// L3Update 逐笔订单簿增量（不含用户信息，冰山单仅展示可见数量）
+//
+// 失去时间优先级的变化（改价、加量、冰山单补充展示数量）以 delete + add 推送，
+// 客户端按推送顺序维护每个价位内的排队顺序。
+type L3Update struct {
+	Symbol      string `json:"symbol"`
+	Seq         int64  `json:"seq"`
+	Action      string `json:"action"`
+	OrderID     int64  `json:"orderId"`
+	Side        int    `json:"side"`
+	Price       int64  `json:"price"`
+	Qty         int64  `json:"qty"`
+	TimestampMs int64  `json:"timestampMs"`
+}
+
 // Trade 成交
 type Trade struct {
 	TradeID     int64  `json:"tradeId"`
@@ -89,6 +123,17 @@ type Ticker struct {
 	CloseTimeMs        int64  `json:"closeTimeMs"`
 }
 
+// Auction 集合竞价参考价
+type Auction struct {
+	Symbol      string `json:"symbol"`
+	InAuction   bool   `json:"inAuction"`
+	Price       int64  `json:"price"` // 竞价中为参考均衡价，结束后为统一成交价（0 表示无成交）
+	Volume      int64  `json:"volume"`
+	BuyQty      int64  `json:"buyQty"`
+	SellQty     int64  `json:"sellQty"`
+	TimestampMs int64  `json:"timestampMs"`
+}
+
 // Event 推送事件
 type Event struct {
 	Channel     string      `json:"channel"`
@@ -106,16 +151,26 @@ type Config struct {
 }
 
 // NewMarketDataService 创建行情服务
+//
+// 每个实例在内存中维护完整盘口，必须收到交易对的全部事件才能按序列号检查连续性，
+// 因此实际使用的消费者组为 Group:Consumer，实例之间互不分摊消息。
 func NewMarketDataService(redisClient RedisClient, cfg *Config) *MarketDataService {
+	group := cfg.Group
+	if cfg.Consumer != "" {
+		group = cfg.Group + ":" + cfg.Consumer
+	}
 	return &MarketDataService{
 		redis:       redisClient,
 		eventStream: cfg.EventStream,
-		group:       cfg.Group,
+		group:       group,
 		consumer:    cfg.Consumer,
 		replayCount: cfg.ReplayCount,
+		seq:         eventseq.NewTracker(),
+		stale:       make(map[string]*resyncState),
 		depths:      make(map[string]*Depth),
 		trades:      make(map[string][]*Trade),
 		tickers:     make(map[string]*Ticker),
+		auctions:    make(map[string]*Auction),
 		openOrders:  make(map[string]map[int64]*orderLevel),
 		subscribers: make(map[string][]chan *Event),
 	}
@@ -137,6 +192,7 @@ func (s *MarketDataService) Start(ctx context.Context) error {
 
 	s.loop.Tick()
 	go s.consumeEvents(ctx)
+	go s.resyncLoop(ctx)
 	return nil
 }
 
@@ -191,6 +247,19 @@ func (s *MarketDataService) GetTicker(symbol string) *Ticker {
 	return &snapshot
 }
 
+// GetAuction 获取集合竞价参考价
+func (s *MarketDataService) GetAuction(symbol string) *Auction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	auction, ok := s.auctions[symbol]
+	if !ok {
+		return &Auction{Symbol: symbol}
+	}
+	snapshot := *auction
+	return &snapshot
+}
+
 // GetAllTickers 获取所有 ticker
 func (s *MarketDataService) GetAllTickers() []*Ticker {
 	s.mu.RLock()
@@ -349,11 +418,12 @@ type TradeData struct {
 
 // OrderAcceptedData 订单接受数据
 type OrderAcceptedData struct {
-	OrderID int64 `json:"OrderID"`
-	UserID  int64 `json:"UserID"`
-	Side    int   `json:"Side"`
-	Price   int64 `json:"Price"`
-	Qty     int64 `json:"Qty"`
+	OrderID    int64 `json:"OrderID"`
+	UserID     int64 `json:"UserID"`
+	Side       int   `json:"Side"`
+	Price      int64 `json:"Price"`
+	Qty        int64 `json:"Qty"`
+	VisibleQty int64 `json:"VisibleQty"` // 冰山单展示数量，0 表示非冰山单
 }
 
 // OrderCanceledData 订单取消数据
@@ -363,10 +433,35 @@ type OrderCanceledData struct {
 	LeavesQty int64 `json:"LeavesQty"`
 }
 
+// MassCanceledData 批量撤单数据
+type MassCanceledData struct {
+	Orders []RemovedOrder `json:"Orders"`
+}
+
+// MassQuotedData 批量报价生效数据（Canceled 为被替换的旧报价单）
+type MassQuotedData struct {
+	Canceled []RemovedOrder `json:"Canceled"`
+}
+
+// RemovedOrder 批量事件中被撤销的订单
+type RemovedOrder struct {
+	OrderID int64 `json:"OrderID"`
+}
+
 type OrderPartiallyFilledData struct {
-	OrderID   int64 `json:"OrderID"`
-	UserID    int64 `json:"UserID"`
-	LeavesQty int64 `json:"LeavesQty"`
+	OrderID    int64 `json:"OrderID"`
+	UserID     int64 `json:"UserID"`
+	LeavesQty  int64 `json:"LeavesQty"`
+	VisibleQty int64 `json:"VisibleQty"` // 冰山单展示数量，0 表示非冰山单
+}
+
+// OrderAmendedData 改单事件数据（LeavesQty 为改单后、撮合前的剩余数量）
+type OrderAmendedData struct {
+	OrderID      int64 `json:"OrderID"`
+	Price        int64 `json:"Price"`
+	LeavesQty    int64 `json:"LeavesQty"`
+	VisibleQty   int64 `json:"VisibleQty"`   // 冰山单展示数量，0 表示非冰山单
+	PriorityKept bool  `json:"PriorityKept"` // 同价减量，保留时间优先级
 }
 
 type OrderFilledData struct {
@@ -374,6 +469,14 @@ type OrderFilledData struct {
 	UserID  int64 `json:"UserID"`
 }
 
+// AuctionData 集合竞价事件数据（AUCTION_UNCROSSED 仅携带 Price/Volume）
+type AuctionData struct {
+	Price   int64 `json:"Price"`
+	Volume  int64 `json:"Volume"`
+	BuyQty  int64 `json:"BuyQty"`
+	SellQty int64 `json:"SellQty"`
+}
+
 func (s *MarketDataService) processEvent(ctx context.Context, msg redis.XMessage) {
 	data, ok := msg.Values["data"].(string)
 	if !ok {
@@ -381,30 +484,81 @@ func (s *MarketDataService) processEvent(ctx context.Context, msg redis.XMessage
 		return
 	}
 
-	if err := s.processEventData(data); err != nil {
+	if err := s.processEventData(msg.ID, data); err != nil {
 		log.Printf("process event error: %v", err)
 	}
 	s.redis.XAck(ctx, s.eventStream, s.group, msg.ID)
 }
 
-func (s *MarketDataService) processEventData(data string) error {
+func (s *MarketDataService) processEventData(msgID, data string) error {
 	var event MatchingEvent
 	if err := json.Unmarshal([]byte(data), &event); err != nil {
 		return fmt.Errorf("unmarshal event: %w", err)
 	}
 
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	// 盘口按事件增量维护，必须严格按序列号应用：重复事件直接丢弃；
+	// 出现缺口后该交易对停止应用盘口增量，等待按撮合快照重建
+	res := s.seq.Observe(event.Symbol, event.Seq, msgID)
+	switch res.Status {
+	case eventseq.StatusDuplicate:
+		eventSeqDuplicates.WithLabelValues(s.eventStream, s.group, event.Symbol).Inc()
+		return nil
+	case eventseq.StatusGap:
+		eventSeqGaps.WithLabelValues(s.eventStream, s.group, event.Symbol).Inc()
+		log.Printf("event seq gap: symbol=%s last=%d got=%d missing=%d", event.Symbol, res.Last, event.Seq, res.Missing)
+		s.markStale(event.Symbol, event.Seq-1)
+		s.requestResync(event.Symbol, res.Last, event.Seq)
+	case eventseq.StatusReset:
+		log.Printf("event seq reset: symbol=%s last=%d", event.Symbol, res.Last)
+	}
+
 	switch event.Type {
 	case "TRADE_CREATED":
 		s.handleTradeCreated(event)
+	case "AUCTION_INDICATIVE", "AUCTION_UNCROSSED":
+		s.handleAuction(event)
+	default:
+		if !s.bufferStale(event) {
+			s.applyBookEvent(event)
+		}
+	}
+	return nil
+}
+
+// applyBookEvent 应用盘口增量事件
+func (s *MarketDataService) applyBookEvent(event MatchingEvent) {
+	switch event.Type {
 	case "ORDER_ACCEPTED":
 		s.handleOrderAccepted(event)
-	case "ORDER_PARTIALLY_FILLED":
+	case "ORDER_PARTIALLY_FILLED", "ORDER_REDUCED":
+		// ORDER_REDUCED（自成交防护扣减）同样携带 OrderID/LeavesQty
 		s.handleOrderPartiallyFilled(event)
-	case "ORDER_CANCELED", "ORDER_FILLED":
+	case "ORDER_AMENDED":
+		s.handleOrderAmended(event)
+	case "ORDER_CANCELED", "ORDER_FILLED", "MASS_CANCELED", "MASS_QUOTED":
 		s.handleOrderRemoved(event)
 	}
+}
 
-	return nil
+func (s *MarketDataService) requestResync(symbol string, lastSeq, gotSeq int64) {
+	if s.redis == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req := eventseq.ResyncRequest{
+		Stream: s.eventStream, Group: s.group, Consumer: s.consumer,
+		Symbol: symbol, LastSeq: lastSeq, GotSeq: gotSeq,
+	}
+	if err := s.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: eventseq.ResyncStream(s.eventStream),
+		Values: req.Values(),
+	}).Err(); err != nil {
+		log.Printf("request resync error: symbol=%s err=%v", symbol, err)
+	}
 }
 
 func (s *MarketDataService) replayRecent(ctx context.Context, count int) error {
@@ -425,7 +579,7 @@ func (s *MarketDataService) replayRecent(ctx context.Context, count int) error {
 		if !ok {
 			continue
 		}
-		if err := s.processEventData(data); err != nil {
+		if err := s.processEventData(results[i].ID, data); err != nil {
 			log.Printf("replay event error: %v", err)
 		}
 	}
@@ -503,6 +657,30 @@ func (s *MarketDataService) handleTradeCreated(event MatchingEvent) {
 	s.publish(event.Symbol, "trades", t)
 }
 
+func (s *MarketDataService) handleAuction(event MatchingEvent) {
+	var data AuctionData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return
+	}
+
+	auction := &Auction{
+		Symbol:      event.Symbol,
+		InAuction:   event.Type == "AUCTION_INDICATIVE",
+		Price:       data.Price,
+		Volume:      data.Volume,
+		BuyQty:      data.BuyQty,
+		SellQty:     data.SellQty,
+		TimestampMs: time.Now().UnixMilli(),
+	}
+
+	s.mu.Lock()
+	s.auctions[event.Symbol] = auction
+	s.mu.Unlock()
+
+	snapshot := *auction
+	s.publish(event.Symbol, "auction", &snapshot)
+}
+
 func (s *MarketDataService) handleOrderAccepted(event MatchingEvent) {
 	var order OrderAcceptedData
 	if err := json.Unmarshal(event.Data, &order); err != nil {
@@ -522,8 +700,8 @@ func (s *MarketDataService) handleOrderAccepted(event MatchingEvent) {
 		s.depths[event.Symbol] = depth
 	}
 
-	// 添加到盘口
-	level := PriceLevel{Price: order.Price, Qty: order.Qty}
+	// 添加到盘口（冰山单仅展示可见部分）
+	level := PriceLevel{Price: order.Price, Qty: shownQty(order.Qty, order.VisibleQty)}
 	if order.Side == 1 { // BUY
 		depth.Bids = applyLevelDelta(depth.Bids, level.Price, level.Qty, true)
 	} else { // SELL
@@ -532,18 +710,22 @@ func (s *MarketDataService) handleOrderAccepted(event MatchingEvent) {
 	if _, ok := s.openOrders[event.Symbol]; !ok {
 		s.openOrders[event.Symbol] = make(map[int64]*orderLevel)
 	}
-	s.openOrders[event.Symbol][order.OrderID] = &orderLevel{
+	entry := &orderLevel{
 		OrderID:   order.OrderID,
 		Side:      order.Side,
 		Price:     order.Price,
-		LeavesQty: order.Qty,
+		LeavesQty: level.Qty,
+		TotalQty:  order.Qty,
+		Iceberg:   order.VisibleQty > 0,
 	}
+	s.openOrders[event.Symbol][order.OrderID] = entry
 
 	depth.LastUpdateID = event.Seq
 	depth.TimestampMs = time.Now().UnixMilli()
 
 	// 推送盘口更新
 	s.publishDepth(event.Symbol, depth)
+	s.publishL3(event, L3Add, entry)
 }
 
 func (s *MarketDataService) handleOrderPartiallyFilled(event MatchingEvent) {
@@ -569,10 +751,9 @@ func (s *MarketDataService) handleOrderPartiallyFilled(event MatchingEvent) {
 		return
 	}
 
-	leavesQty := data.LeavesQty
-	if leavesQty < 0 {
-		leavesQty = 0
-	}
+	leavesQty := shownQty(data.LeavesQty, data.VisibleQty)
+	// 冰山单展示部分被吃完后补充并排到档位末尾
+	requeued := event.Type == "ORDER_PARTIALLY_FILLED" && entry.Iceberg && entry.TotalQty-data.LeavesQty >= entry.LeavesQty
 
 	deltaQty := leavesQty - entry.LeavesQty
 	if entry.Side == 1 {
@@ -588,28 +769,101 @@ func (s *MarketDataService) handleOrderPartiallyFilled(event MatchingEvent) {
 		}
 	} else {
 		entry.LeavesQty = leavesQty
+		entry.TotalQty = data.LeavesQty
+		// 按快照重建的冰山单在首次成交时才能识别
+		entry.Iceberg = entry.Iceberg || data.VisibleQty > 0
+	}
+
+	depth.LastUpdateID = event.Seq
+	depth.TimestampMs = time.Now().UnixMilli()
+	s.publishDepth(event.Symbol, depth)
+	switch {
+	case leavesQty == 0:
+		s.publishL3(event, L3Delete, entry)
+	case requeued:
+		s.publishL3(event, L3Delete, entry)
+		s.publishL3(event, L3Add, entry)
+	default:
+		s.publishL3(event, L3Modify, entry)
+	}
+}
+
+// handleOrderAmended 改单：将挂单从原价位移到新价位（改单后的成交由后续事件更新）
+func (s *MarketDataService) handleOrderAmended(event MatchingEvent) {
+	var data OrderAmendedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	depth := s.depths[event.Symbol]
+	if depth == nil {
+		return
 	}
+	entry, ok := s.openOrders[event.Symbol][data.OrderID]
+	if !ok || entry == nil {
+		return
+	}
+
+	leavesQty := shownQty(data.LeavesQty, data.VisibleQty)
+	if entry.Side == 1 {
+		depth.Bids = applyLevelDelta(depth.Bids, entry.Price, -entry.LeavesQty, true)
+		depth.Bids = applyLevelDelta(depth.Bids, data.Price, leavesQty, true)
+	} else {
+		depth.Asks = applyLevelDelta(depth.Asks, entry.Price, -entry.LeavesQty, false)
+		depth.Asks = applyLevelDelta(depth.Asks, data.Price, leavesQty, false)
+	}
+	if !data.PriorityKept {
+		s.publishL3(event, L3Delete, entry)
+	}
+	entry.Price = data.Price
+	entry.LeavesQty = leavesQty
+	entry.TotalQty = data.LeavesQty
 
 	depth.LastUpdateID = event.Seq
 	depth.TimestampMs = time.Now().UnixMilli()
 	s.publishDepth(event.Symbol, depth)
+	if data.PriorityKept {
+		s.publishL3(event, L3Modify, entry)
+	} else {
+		s.publishL3(event, L3Add, entry)
+	}
 }
 
 func (s *MarketDataService) handleOrderRemoved(event MatchingEvent) {
-	var orderID int64
+	var orderIDs []int64
 	switch event.Type {
 	case "ORDER_CANCELED":
 		var data OrderCanceledData
 		if err := json.Unmarshal(event.Data, &data); err != nil {
 			return
 		}
-		orderID = data.OrderID
+		orderIDs = []int64{data.OrderID}
 	case "ORDER_FILLED":
 		var data OrderFilledData
 		if err := json.Unmarshal(event.Data, &data); err != nil {
 			return
 		}
-		orderID = data.OrderID
+		orderIDs = []int64{data.OrderID}
+	case "MASS_CANCELED":
+		var data MassCanceledData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return
+		}
+		for _, order := range data.Orders {
+			orderIDs = append(orderIDs, order.OrderID)
+		}
+	case "MASS_QUOTED":
+		// 旧报价在同一事件中撤销，新报价随后以 ORDER_ACCEPTED 入簿
+		var data MassQuotedData
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return
+		}
+		for _, order := range data.Canceled {
+			orderIDs = append(orderIDs, order.OrderID)
+		}
 	default:
 		return
 	}
@@ -622,7 +876,11 @@ func (s *MarketDataService) handleOrderRemoved(event MatchingEvent) {
 		return
 	}
 
-	if ordersBySymbol, ok := s.openOrders[event.Symbol]; ok {
+	for _, orderID := range orderIDs {
+		ordersBySymbol, ok := s.openOrders[event.Symbol]
+		if !ok {
+			break
+		}
 		if entry, exists := ordersBySymbol[orderID]; exists && entry != nil {
 			deltaQty := -entry.LeavesQty
 			if entry.Side == 1 {
@@ -634,6 +892,7 @@ func (s *MarketDataService) handleOrderRemoved(event MatchingEvent) {
 			if len(ordersBySymbol) == 0 {
 				delete(s.openOrders, event.Symbol)
 			}
+			s.publishL3(event, L3Delete, entry)
 		}
 	}
 
@@ -650,18 +909,7 @@ func (s *MarketDataService) publish(symbol, dataType string, data interface{}) {
 		TimestampMs: time.Now().UnixMilli(),
 		Data:        data,
 	}
-
-	s.subMu.RLock()
-	subs := s.subscribers[channel]
-	s.subMu.RUnlock()
-
-	for _, ch := range subs {
-		select {
-		case ch <- event:
-		default:
-			// 队列满，丢弃
-		}
-	}
+	s.dispatch(channel, event)
 }
 
 func (s *MarketDataService) publishDepth(symbol string, depth *Depth) {
@@ -679,7 +927,36 @@ func (s *MarketDataService) publishDepth(symbol string, depth *Depth) {
 		TimestampMs: snapshot.TimestampMs,
 		Data:        snapshot,
 	}
+	s.dispatch(channel, event)
+}
 
+// publishL3 推送逐笔增量（Seq 为撮合事件序列号，同一事件的多条增量序列号相同）
+func (s *MarketDataService) publishL3(event MatchingEvent, action string, entry *orderLevel) {
+	qty := entry.LeavesQty
+	if action == L3Delete {
+		qty = 0
+	}
+	update := &L3Update{
+		Symbol:      event.Symbol,
+		Seq:         event.Seq,
+		Action:      action,
+		OrderID:     entry.OrderID,
+		Side:        entry.Side,
+		Price:       entry.Price,
+		Qty:         qty,
+		TimestampMs: time.Now().UnixMilli(),
+	}
+	channel := "market." + event.Symbol + ".l3"
+	s.dispatch(channel, &Event{
+		Channel:     channel,
+		Seq:         event.Seq,
+		TimestampMs: update.TimestampMs,
+		Data:        update,
+	})
+}
+
+// dispatch 非阻塞投递给频道的订阅者，队列满时丢弃
+func (s *MarketDataService) dispatch(channel string, event *Event) {
 	s.subMu.RLock()
 	subs := s.subscribers[channel]
 	s.subMu.RUnlock()
@@ -795,6 +1072,17 @@ func applyLevelDelta(levels []PriceLevel, price int64, delta int64, descending b
 	return insertLevel(levels, PriceLevel{Price: price, Qty: delta}, descending)
 }
 
+// shownQty 计入盘口的数量：冰山单取展示数量，其余取剩余数量
+func shownQty(leavesQty, visibleQty int64) int64 {
+	if leavesQty <= 0 {
+		return 0
+	}
+	if visibleQty > 0 && visibleQty < leavesQty {
+		return visibleQty
+	}
+	return leavesQty
+}
+
 func formatPercent(p float64) string {
 	return fmt.Sprintf("%+.2f%%", p)
 }
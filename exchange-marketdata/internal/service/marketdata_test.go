@@ -1,8 +1,15 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func TestDepthStruct(t *testing.T) {
@@ -456,9 +463,25 @@ func TestDepthUpdatesFromOrderLifecycle(t *testing.T) {
 	}
 
 	mustProcessEvent(t, svc, MatchingEvent{
-		Type:   "ORDER_FILLED",
+		Type:   "ORDER_REDUCED",
 		Symbol: symbol,
 		Seq:    3,
+		Data: mustJSON(t, OrderPartiallyFilledData{
+			OrderID:   1001,
+			UserID:    10,
+			LeavesQty: 30,
+		}),
+	})
+
+	depth = svc.GetDepth(symbol, 20)
+	if len(depth.Bids) != 1 || depth.Bids[0].Qty != 30 {
+		t.Fatalf("expected bid qty=30 after stp decrement, got %+v", depth.Bids)
+	}
+
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_FILLED",
+		Symbol: symbol,
+		Seq:    4,
 		Data: mustJSON(t, OrderFilledData{
 			OrderID: 1001,
 			UserID:  10,
@@ -471,6 +494,52 @@ func TestDepthUpdatesFromOrderLifecycle(t *testing.T) {
 	}
 }
 
+func TestDepthMovesAmendedOrder(t *testing.T) {
+	svc := NewMarketDataService(nil, &Config{})
+	symbol := "BTCUSDT"
+
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_ACCEPTED",
+		Symbol: symbol,
+		Seq:    1,
+		Data: mustJSON(t, OrderAcceptedData{
+			OrderID: 3001,
+			UserID:  10,
+			Side:    2,
+			Price:   101,
+			Qty:     50,
+		}),
+	})
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_AMENDED",
+		Symbol: symbol,
+		Seq:    2,
+		Data: mustJSON(t, OrderAmendedData{
+			OrderID:   3001,
+			Price:     102,
+			LeavesQty: 20,
+		}),
+	})
+
+	depth := svc.GetDepth(symbol, 20)
+	if len(depth.Asks) != 1 || depth.Asks[0].Price != 102 || depth.Asks[0].Qty != 20 {
+		t.Fatalf("expected ask 20@102 after amend, got %+v", depth.Asks)
+	}
+
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_CANCELED",
+		Symbol: symbol,
+		Seq:    3,
+		Data: mustJSON(t, OrderCanceledData{
+			OrderID: 3001,
+			UserID:  10,
+		}),
+	})
+	if depth = svc.GetDepth(symbol, 20); len(depth.Asks) != 0 {
+		t.Fatalf("expected empty asks after cancel, got %+v", depth.Asks)
+	}
+}
+
 func TestDepthRemovesCanceledOrder(t *testing.T) {
 	svc := NewMarketDataService(nil, &Config{})
 	symbol := "ETHUSDT"
@@ -510,6 +579,43 @@ func TestDepthRemovesCanceledOrder(t *testing.T) {
 	}
 }
 
+func TestDepthRemovesMassQuotedOrders(t *testing.T) {
+	svc := NewMarketDataService(nil, &Config{})
+	symbol := "ETHUSDT"
+
+	for i, side := range []int{1, 2} {
+		mustProcessEvent(t, svc, MatchingEvent{
+			Type:   "ORDER_ACCEPTED",
+			Symbol: symbol,
+			Seq:    int64(i + 1),
+			Data: mustJSON(t, OrderAcceptedData{
+				OrderID: int64(3001 + i),
+				UserID:  30,
+				Side:    side,
+				Price:   int64(3100000 + i*200000),
+				Qty:     10000000,
+			}),
+		})
+	}
+
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "MASS_QUOTED",
+		Symbol: symbol,
+		Seq:    3,
+		Data: mustJSON(t, MassQuotedData{
+			Canceled: []RemovedOrder{{OrderID: 3001}, {OrderID: 3002}},
+		}),
+	})
+
+	depth := svc.GetDepth(symbol, 20)
+	if len(depth.Bids) != 0 || len(depth.Asks) != 0 {
+		t.Fatalf("expected empty depth after quote replace, got bids=%+v asks=%+v", depth.Bids, depth.Asks)
+	}
+	if depth.LastUpdateID != 3 {
+		t.Fatalf("expected last update id 3, got %d", depth.LastUpdateID)
+	}
+}
+
 func TestDepthAggregatesOrdersAtSamePrice(t *testing.T) {
 	svc := NewMarketDataService(nil, &Config{})
 	symbol := "BTCUSDT"
@@ -577,10 +683,13 @@ func TestDepthAggregatesOrdersAtSamePrice(t *testing.T) {
 	}
 }
 
+// testMsgIDs 测试事件的 Stream 消息 ID，按处理顺序递增
+var testMsgIDs atomic.Int64
+
 func mustProcessEvent(t *testing.T, svc *MarketDataService, event MatchingEvent) {
 	t.Helper()
 	raw := mustJSON(t, event)
-	if err := svc.processEventData(string(raw)); err != nil {
+	if err := svc.processEventData(fmt.Sprintf("1-%d", testMsgIDs.Add(1)), string(raw)); err != nil {
 		t.Fatalf("process event error: %v", err)
 	}
 }
@@ -593,3 +702,297 @@ func mustJSON(t *testing.T, v interface{}) json.RawMessage {
 	}
 	return data
 }
+
+func TestDepthShowsIcebergVisibleQty(t *testing.T) {
+	svc := NewMarketDataService(nil, &Config{})
+	symbol := "BTCUSDT"
+
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_ACCEPTED",
+		Symbol: symbol,
+		Seq:    1,
+		Data: mustJSON(t, OrderAcceptedData{
+			OrderID:    4001,
+			UserID:     10,
+			Side:       1,
+			Price:      100,
+			Qty:        300,
+			VisibleQty: 10,
+		}),
+	})
+	depth := svc.GetDepth(symbol, 20)
+	if len(depth.Bids) != 1 || depth.Bids[0].Qty != 10 {
+		t.Fatalf("expected only visible qty 10, got %+v", depth.Bids)
+	}
+
+	// 展示部分成交 4 后
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_PARTIALLY_FILLED",
+		Symbol: symbol,
+		Seq:    2,
+		Data:   mustJSON(t, OrderPartiallyFilledData{OrderID: 4001, LeavesQty: 296, VisibleQty: 6}),
+	})
+	if depth = svc.GetDepth(symbol, 20); depth.Bids[0].Qty != 6 {
+		t.Fatalf("expected visible qty 6, got %+v", depth.Bids)
+	}
+
+	// 展示部分成交完毕并补充
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_PARTIALLY_FILLED",
+		Symbol: symbol,
+		Seq:    3,
+		Data:   mustJSON(t, OrderPartiallyFilledData{OrderID: 4001, LeavesQty: 290, VisibleQty: 10}),
+	})
+	if depth = svc.GetDepth(symbol, 20); depth.Bids[0].Qty != 10 {
+		t.Fatalf("expected replenished visible qty 10, got %+v", depth.Bids)
+	}
+
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_CANCELED",
+		Symbol: symbol,
+		Seq:    4,
+		Data:   mustJSON(t, OrderCanceledData{OrderID: 4001, UserID: 10}),
+	})
+	if depth = svc.GetDepth(symbol, 20); len(depth.Bids) != 0 {
+		t.Fatalf("expected empty bids after cancel, got %+v", depth.Bids)
+	}
+}
+
+func TestProcessEventSkipsDuplicateSeq(t *testing.T) {
+	svc := NewMarketDataService(nil, &Config{})
+	symbol := "BTCUSDT"
+	accepted := MatchingEvent{
+		Type:   "ORDER_ACCEPTED",
+		Symbol: symbol,
+		Seq:    7,
+		Data: mustJSON(t, OrderAcceptedData{
+			OrderID: 4001,
+			UserID:  10,
+			Side:    1,
+			Price:   100,
+			Qty:     10,
+		}),
+	}
+	mustProcessEvent(t, svc, accepted)
+
+	// 重复投递的事件不能重复计入盘口
+	dup := accepted
+	dup.Data = mustJSON(t, OrderAcceptedData{OrderID: 4002, UserID: 10, Side: 1, Price: 100, Qty: 5})
+	mustProcessEvent(t, svc, dup)
+	if depth := svc.GetDepth(symbol, 20); len(depth.Bids) != 1 || depth.Bids[0].Qty != 10 {
+		t.Fatalf("duplicate seq should be skipped, got %+v", depth.Bids)
+	}
+
+	// 出现缺口后停止应用盘口增量（等待重建），但记录最新序列号
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "ORDER_CANCELED",
+		Symbol: symbol,
+		Seq:    10,
+		Data:   mustJSON(t, OrderCanceledData{OrderID: 4001, UserID: 10}),
+	})
+	if depth := svc.GetDepth(symbol, 20); len(depth.Bids) != 1 || depth.Bids[0].Qty != 10 {
+		t.Fatalf("expected increments held back after gap, got %+v", depth.Bids)
+	}
+	if last := svc.seq.Last(symbol); last != 10 {
+		t.Fatalf("expected last seq=10, got %d", last)
+	}
+}
+
+type fakeBookSource struct {
+	book  *L3Book
+	err   error
+	calls int
+}
+
+func (f *fakeBookSource) BookL3(_ context.Context, symbol string) (*L3Book, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.book, nil
+}
+
+func TestGapRebuildsBookFromSnapshot(t *testing.T) {
+	svc := NewMarketDataService(nil, &Config{})
+	books := &fakeBookSource{err: errors.New("matching unavailable")}
+	svc.SetBookSource(books)
+	symbol := "BTCUSDT"
+
+	mustProcessEvent(t, svc, MatchingEvent{Type: "ORDER_ACCEPTED", Symbol: symbol, Seq: 1,
+		Data: mustJSON(t, OrderAcceptedData{OrderID: 1, Side: 1, Price: 100, Qty: 10})})
+	// 缺失 seq 2-3（订单 2 入簿、订单 1 撤销），之后的事件先缓存
+	events := []MatchingEvent{
+		{Type: "ORDER_ACCEPTED", Seq: 4, Data: mustJSON(t, OrderAcceptedData{OrderID: 3, Side: 2, Price: 110, Qty: 7})},
+		{Type: "ORDER_PARTIALLY_FILLED", Seq: 5, Data: mustJSON(t, OrderPartiallyFilledData{OrderID: 2, LeavesQty: 6})},
+		{Type: "TRADE_CREATED", Seq: 6, Data: mustJSON(t, TradeData{TradeID: 1, Price: 110, Qty: 2})},
+		{Type: "ORDER_PARTIALLY_FILLED", Seq: 7, Data: mustJSON(t, OrderPartiallyFilledData{OrderID: 3, LeavesQty: 5})},
+	}
+	for _, event := range events {
+		event.Symbol = symbol
+		mustProcessEvent(t, svc, event)
+	}
+	if depth := svc.GetDepth(symbol, 20); len(depth.Bids) != 1 || depth.Bids[0].Price != 100 || len(depth.Asks) != 0 {
+		t.Fatalf("expected book frozen at seq 1, got %+v", depth)
+	}
+	if trades := svc.GetTrades(symbol, 10); len(trades) != 1 {
+		t.Fatalf("expected trades applied while stale, got %d", len(trades))
+	}
+
+	// 撮合不可用：保持失效并按间隔重试
+	ctx := context.Background()
+	now := time.Now()
+	svc.rebuildStale(ctx, now)
+	svc.rebuildStale(ctx, now.Add(time.Second))
+	if books.calls != 1 {
+		t.Fatalf("expected retry throttled, got %d calls", books.calls)
+	}
+
+	// 快照落后于缺口：不能用于重建
+	books.err = nil
+	books.book = &L3Book{Symbol: symbol, Seq: 2}
+	svc.rebuildStale(ctx, now.Add(resyncRetryInterval))
+	if _, stale := svc.stale[symbol]; !stale {
+		t.Fatal("expected snapshot older than the gap rejected")
+	}
+
+	// 快照包含到 seq 5：之后只应用 seq 6、7
+	books.book = &L3Book{Symbol: symbol, Seq: 5,
+		Bids: []L3Order{{OrderID: 2, Price: 99, Qty: 6}},
+		Asks: []L3Order{{OrderID: 3, Price: 110, Qty: 7}},
+	}
+	svc.rebuildStale(ctx, now.Add(2*resyncRetryInterval))
+	if _, stale := svc.stale[symbol]; stale {
+		t.Fatal("expected book rebuilt")
+	}
+	depth := svc.GetDepth(symbol, 20)
+	if len(depth.Bids) != 1 || depth.Bids[0] != (PriceLevel{Price: 99, Qty: 6}) ||
+		len(depth.Asks) != 1 || depth.Asks[0] != (PriceLevel{Price: 110, Qty: 5}) {
+		t.Fatalf("unexpected rebuilt depth %+v", depth)
+	}
+
+	// 重建后恢复增量应用
+	mustProcessEvent(t, svc, MatchingEvent{Type: "ORDER_FILLED", Symbol: symbol, Seq: 8,
+		Data: mustJSON(t, OrderFilledData{OrderID: 3})})
+	if depth := svc.GetDepth(symbol, 20); len(depth.Asks) != 0 || depth.LastUpdateID != 8 {
+		t.Fatalf("expected increments applied after rebuild, got %+v", depth)
+	}
+}
+
+func TestResyncRequestMarksOwnGroupOnly(t *testing.T) {
+	svc := NewMarketDataService(nil, &Config{Group: "marketdata-group", Consumer: "marketdata-1"})
+	if svc.group != "marketdata-group:marketdata-1" {
+		t.Fatalf("expected per-instance group, got %s", svc.group)
+	}
+	svc.handleResyncRequest(redis.XMessage{Values: map[string]interface{}{"group": "order-group", "symbol": "BTCUSDT"}})
+	svc.handleResyncRequest(redis.XMessage{Values: map[string]interface{}{"group": svc.group, "symbol": "ETHUSDT"}})
+	svc.handleResyncRequest(redis.XMessage{Values: map[string]interface{}{"symbol": "SOLUSDT"}})
+	if _, ok := svc.stale["BTCUSDT"]; ok {
+		t.Fatal("request for another group must be ignored")
+	}
+	if _, ok := svc.stale["ETHUSDT"]; !ok {
+		t.Fatal("expected own group request handled")
+	}
+	if _, ok := svc.stale["SOLUSDT"]; !ok {
+		t.Fatal("expected request without group handled")
+	}
+}
+
+func TestAuctionEventsUpdateIndicative(t *testing.T) {
+	svc := NewMarketDataService(nil, &Config{})
+	symbol := "BTCUSDT"
+	ch := svc.Subscribe("market." + symbol + ".auction")
+
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "AUCTION_INDICATIVE",
+		Symbol: symbol,
+		Seq:    1,
+		Data:   mustJSON(t, AuctionData{Price: 100, Volume: 14, BuyQty: 20, SellQty: 14}),
+	})
+	auction := svc.GetAuction(symbol)
+	if !auction.InAuction || auction.Price != 100 || auction.Volume != 14 || auction.BuyQty != 20 {
+		t.Fatalf("unexpected indicative: %+v", auction)
+	}
+	select {
+	case ev := <-ch:
+		if ev.Data.(*Auction).Price != 100 {
+			t.Fatalf("unexpected pushed auction: %+v", ev.Data)
+		}
+	default:
+		t.Fatal("expected auction push")
+	}
+
+	mustProcessEvent(t, svc, MatchingEvent{
+		Type:   "AUCTION_UNCROSSED",
+		Symbol: symbol,
+		Seq:    2,
+		Data:   mustJSON(t, AuctionData{Price: 100, Volume: 14}),
+	})
+	if auction = svc.GetAuction(symbol); auction.InAuction || auction.Volume != 14 {
+		t.Fatalf("expected auction ended, got %+v", auction)
+	}
+}
+
+func TestL3UpdatesFromOrderLifecycle(t *testing.T) {
+	svc := NewMarketDataService(nil, &Config{})
+	symbol := "BTCUSDT"
+	ch := svc.Subscribe("market." + symbol + ".l3")
+
+	events := []MatchingEvent{
+		{Type: "ORDER_ACCEPTED", Seq: 1, Data: mustJSON(t, OrderAcceptedData{OrderID: 1, UserID: 10, Side: 1, Price: 100, Qty: 50})},
+		{Type: "ORDER_ACCEPTED", Seq: 2, Data: mustJSON(t, OrderAcceptedData{OrderID: 2, UserID: 11, Side: 1, Price: 100, Qty: 300, VisibleQty: 10})},
+		// 普通成交：原地减量
+		{Type: "ORDER_PARTIALLY_FILLED", Seq: 3, Data: mustJSON(t, OrderPartiallyFilledData{OrderID: 1, LeavesQty: 40})},
+		// 冰山单部分展示成交：原地减量
+		{Type: "ORDER_PARTIALLY_FILLED", Seq: 4, Data: mustJSON(t, OrderPartiallyFilledData{OrderID: 2, LeavesQty: 296, VisibleQty: 6})},
+		// 冰山单展示部分吃完并补充：排到末尾
+		{Type: "ORDER_PARTIALLY_FILLED", Seq: 5, Data: mustJSON(t, OrderPartiallyFilledData{OrderID: 2, LeavesQty: 290, VisibleQty: 10})},
+		// 同价减量保留优先级
+		{Type: "ORDER_AMENDED", Seq: 6, Data: mustJSON(t, OrderAmendedData{OrderID: 1, Price: 100, LeavesQty: 30, PriorityKept: true})},
+		// 改价重新排队
+		{Type: "ORDER_AMENDED", Seq: 7, Data: mustJSON(t, OrderAmendedData{OrderID: 1, Price: 99, LeavesQty: 30})},
+		{Type: "ORDER_CANCELED", Seq: 8, Data: mustJSON(t, OrderCanceledData{OrderID: 2, UserID: 11})},
+		{Type: "ORDER_FILLED", Seq: 9, Data: mustJSON(t, OrderFilledData{OrderID: 1, UserID: 10})},
+	}
+	for _, event := range events {
+		event.Symbol = symbol
+		mustProcessEvent(t, svc, event)
+	}
+
+	want := []L3Update{
+		{Seq: 1, Action: L3Add, OrderID: 1, Side: 1, Price: 100, Qty: 50},
+		{Seq: 2, Action: L3Add, OrderID: 2, Side: 1, Price: 100, Qty: 10},
+		{Seq: 3, Action: L3Modify, OrderID: 1, Side: 1, Price: 100, Qty: 40},
+		{Seq: 4, Action: L3Modify, OrderID: 2, Side: 1, Price: 100, Qty: 6},
+		{Seq: 5, Action: L3Delete, OrderID: 2, Side: 1, Price: 100},
+		{Seq: 5, Action: L3Add, OrderID: 2, Side: 1, Price: 100, Qty: 10},
+		{Seq: 6, Action: L3Modify, OrderID: 1, Side: 1, Price: 100, Qty: 30},
+		{Seq: 7, Action: L3Delete, OrderID: 1, Side: 1, Price: 100},
+		{Seq: 7, Action: L3Add, OrderID: 1, Side: 1, Price: 99, Qty: 30},
+		{Seq: 8, Action: L3Delete, OrderID: 2, Side: 1, Price: 100},
+		{Seq: 9, Action: L3Delete, OrderID: 1, Side: 1, Price: 99},
+	}
+	for i, w := range want {
+		select {
+		case event := <-ch:
+			update, ok := event.Data.(*L3Update)
+			if !ok {
+				t.Fatalf("update %d: unexpected data %T", i, event.Data)
+			}
+			if event.Seq != w.Seq || event.Channel != "market.BTCUSDT.l3" {
+				t.Fatalf("update %d: unexpected event %+v", i, event)
+			}
+			w.Symbol = symbol
+			w.TimestampMs = update.TimestampMs
+			if *update != w {
+				t.Fatalf("update %d = %+v, want %+v", i, *update, w)
+			}
+		default:
+			t.Fatalf("missing update %d: %+v", i, w)
+		}
+	}
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected extra update: %+v", event.Data)
+	default:
+	}
+}
@@ -0,0 +1,22 @@
+package service
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	eventSeqGaps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "event_seq_gaps_total",
+		Help: "Total number of matching event sequence gaps detected.",
+	}, []string{"stream", "group", "symbol"})
+	eventSeqDuplicates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "event_seq_duplicates_total",
+		Help: "Total number of duplicate or out-of-order matching events skipped.",
+	}, []string{"stream", "group", "symbol"})
+	bookResyncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "book_resyncs_total",
+		Help: "Total number of order books rebuilt from a matching snapshot after a sequence gap.",
+	}, []string{"stream", "group", "symbol"})
+)
+
+func init() {
+	prometheus.MustRegister(eventSeqGaps, eventSeqDuplicates, bookResyncs)
+}
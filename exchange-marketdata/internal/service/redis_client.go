@@ -15,6 +15,8 @@ type RedisClient interface {
 	XClaim(ctx context.Context, args *redis.XClaimArgs) *redis.XMessageSliceCmd
 	XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd
 	XRevRangeN(ctx context.Context, stream, start, stop string, count int64) *redis.XMessageSliceCmd
+	XAdd(ctx context.Context, args *redis.XAddArgs) *redis.StringCmd
+	XRead(ctx context.Context, args *redis.XReadArgs) *redis.XStreamSliceCmd
 }
 
 // RedisClientAdapter 适配器，将 *redis.Client 适配为 RedisClient 接口
@@ -50,3 +52,11 @@ func (a *RedisClientAdapter) XAck(ctx context.Context, stream, group string, ids
 func (a *RedisClientAdapter) XRevRangeN(ctx context.Context, stream, start, stop string, count int64) *redis.XMessageSliceCmd {
 	return a.client.XRevRangeN(ctx, stream, start, stop, count)
 }
+
+func (a *RedisClientAdapter) XAdd(ctx context.Context, args *redis.XAddArgs) *redis.StringCmd {
+	return a.client.XAdd(ctx, args)
+}
+
+func (a *RedisClientAdapter) XRead(ctx context.Context, args *redis.XReadArgs) *redis.XStreamSliceCmd {
+	return a.client.XRead(ctx, args)
+}
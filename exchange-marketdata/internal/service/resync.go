@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/exchange/common/pkg/eventseq"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// resyncRetryInterval 重建失败（撮合不可用、快照落后于缺口）后的重试间隔
+	resyncRetryInterval = 2 * time.Second
+	// maxResyncBuffer 等待重建期间每个交易对最多缓存的盘口事件数，超出后丢弃缓存并要求更新的快照
+	maxResyncBuffer = 10000
+)
+
+var errNoBookSource = errors.New("book source not configured")
+
+// L3Order 逐笔订单簿中的挂单
+type L3Order struct {
+	OrderID int64 `json:"orderId"`
+	Price   int64 `json:"price"`
+	Qty     int64 `json:"qty"`
+}
+
+// L3Book 撮合生成的逐笔订单簿快照（Seq 为快照包含的最后一个事件序列号）
+type L3Book struct {
+	Symbol string    `json:"symbol"`
+	Seq    int64     `json:"seq"`
+	Bids   []L3Order `json:"bids"`
+	Asks   []L3Order `json:"asks"`
+}
+
+// BookSource 盘口重建数据源（撮合逐笔订单簿快照）
+type BookSource interface {
+	BookL3(ctx context.Context, symbol string) (*L3Book, error)
+}
+
+// resyncState 等待重建的交易对
+type resyncState struct {
+	need      int64           // 快照至少要包含到的序列号（缺口末尾）
+	buffered  []MatchingEvent // 发现缺口后收到的盘口事件，重建后应用序列号大于快照的部分
+	nextRetry time.Time
+}
+
+// SetBookSource 设置盘口重建数据源
+func (s *MarketDataService) SetBookSource(books BookSource) {
+	s.books = books
+}
+
+// markStale 标记交易对盘口失效：停止应用增量，等待按快照重建
+//
+// 调用方需持有 s.applyMu。
+func (s *MarketDataService) markStale(symbol string, need int64) {
+	st, ok := s.stale[symbol]
+	if !ok {
+		st = &resyncState{}
+		s.stale[symbol] = st
+	}
+	if need > st.need {
+		st.need = need
+	}
+	st.nextRetry = time.Time{}
+}
+
+// bufferStale 交易对等待重建时缓存盘口事件，返回 false 表示交易对未失效
+//
+// 调用方需持有 s.applyMu。
+func (s *MarketDataService) bufferStale(event MatchingEvent) bool {
+	st, ok := s.stale[event.Symbol]
+	if !ok {
+		return false
+	}
+	if len(st.buffered) >= maxResyncBuffer {
+		// 缓存溢出：丢弃缓存，改为要求快照覆盖到当前事件
+		log.Printf("resync buffer overflow: symbol=%s seq=%d", event.Symbol, event.Seq)
+		st.buffered = nil
+		if event.Seq > st.need {
+			st.need = event.Seq
+		}
+		return true
+	}
+	st.buffered = append(st.buffered, event)
+	return true
+}
+
+// resyncLoop 重建失效的交易对盘口
+//
+// 同时消费重同步请求流：本实例（同一消费者组）或未指定消费者组的请求会触发对应交易对重建，
+// 运维可写入请求手动重建盘口。
+func (s *MarketDataService) resyncLoop(ctx context.Context) {
+	stream := eventseq.ResyncStream(s.eventStream)
+	lastID := "$"
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		results, err := s.redis.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{stream, lastID},
+			Count:   100,
+			Block:   time.Second,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("read resync requests error: %v", err)
+			time.Sleep(time.Second)
+		}
+		for _, result := range results {
+			for _, msg := range result.Messages {
+				lastID = msg.ID
+				s.handleResyncRequest(msg)
+			}
+		}
+		s.rebuildStale(ctx, time.Now())
+	}
+}
+
+func (s *MarketDataService) handleResyncRequest(msg redis.XMessage) {
+	group, _ := msg.Values["group"].(string)
+	symbol, _ := msg.Values["symbol"].(string)
+	if symbol == "" || (group != "" && group != s.group) {
+		return
+	}
+	s.applyMu.Lock()
+	s.markStale(symbol, 0)
+	s.applyMu.Unlock()
+}
+
+// rebuildStale 按撮合快照重建到期的失效交易对
+func (s *MarketDataService) rebuildStale(ctx context.Context, now time.Time) {
+	s.applyMu.Lock()
+	var due []string
+	for symbol, st := range s.stale {
+		if !now.Before(st.nextRetry) {
+			due = append(due, symbol)
+		}
+	}
+	s.applyMu.Unlock()
+
+	for _, symbol := range due {
+		if err := s.rebuild(ctx, symbol); err != nil {
+			log.Printf("rebuild book error: symbol=%s err=%v", symbol, err)
+			s.applyMu.Lock()
+			if st, ok := s.stale[symbol]; ok {
+				st.nextRetry = now.Add(resyncRetryInterval)
+			}
+			s.applyMu.Unlock()
+		}
+	}
+}
+
+// rebuild 以撮合逐笔快照替换交易对盘口，并应用缓存中序列号大于快照的事件
+func (s *MarketDataService) rebuild(ctx context.Context, symbol string) error {
+	if s.books == nil {
+		return errNoBookSource
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	book, err := s.books.BookL3(fetchCtx, symbol)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	st, ok := s.stale[symbol]
+	if !ok {
+		return nil
+	}
+	if book.Seq < st.need {
+		return fmt.Errorf("snapshot seq %d behind gap end %d", book.Seq, st.need)
+	}
+	delete(s.stale, symbol)
+
+	s.mu.Lock()
+	depth := &Depth{Symbol: symbol, Bids: []PriceLevel{}, Asks: []PriceLevel{}, LastUpdateID: book.Seq, TimestampMs: time.Now().UnixMilli()}
+	orders := make(map[int64]*orderLevel, len(book.Bids)+len(book.Asks))
+	// 快照只有展示数量：冰山单的隐藏部分在后续成交事件中恢复
+	for _, o := range book.Bids {
+		depth.Bids = applyLevelDelta(depth.Bids, o.Price, o.Qty, true)
+		orders[o.OrderID] = &orderLevel{OrderID: o.OrderID, Side: 1, Price: o.Price, LeavesQty: o.Qty, TotalQty: o.Qty}
+	}
+	for _, o := range book.Asks {
+		depth.Asks = applyLevelDelta(depth.Asks, o.Price, o.Qty, false)
+		orders[o.OrderID] = &orderLevel{OrderID: o.OrderID, Side: 2, Price: o.Price, LeavesQty: o.Qty, TotalQty: o.Qty}
+	}
+	s.depths[symbol] = depth
+	if len(orders) > 0 {
+		s.openOrders[symbol] = orders
+	} else {
+		delete(s.openOrders, symbol)
+	}
+	s.publishDepth(symbol, depth)
+	s.mu.Unlock()
+
+	applied := 0
+	for _, event := range st.buffered {
+		if event.Seq > book.Seq {
+			s.applyBookEvent(event)
+			applied++
+		}
+	}
+	bookResyncs.WithLabelValues(s.eventStream, s.group, symbol).Inc()
+	log.Printf("book rebuilt: symbol=%s seq=%d orders=%d replayed=%d", symbol, book.Seq, len(orders), applied)
+	return nil
+}
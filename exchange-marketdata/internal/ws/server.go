@@ -376,7 +376,7 @@ func allowOrigin(r *http.Request, allowed []string) bool {
 }
 
 func validateChannel(channel string) (string, error) {
-	// Expected: market.<SYMBOL>.(book|trades|ticker)
+	// Expected: market.<SYMBOL>.(book|l3|trades|ticker|auction)
 	parts := strings.Split(channel, ".")
 	if len(parts) != 3 {
 		return "", fmt.Errorf("invalid channel")
@@ -395,7 +395,7 @@ func validateChannel(channel string) (string, error) {
 		}
 	}
 	switch parts[2] {
-	case "book", "trades", "ticker":
+	case "book", "l3", "trades", "ticker", "auction":
 		return channel, nil
 	default:
 		return "", fmt.Errorf("invalid channel")
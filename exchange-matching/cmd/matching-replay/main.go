@@ -0,0 +1,290 @@
+// matching-replay 撮合引擎确定性重放与审计
+//
+// 读取订单流（Redis 或导出的 JSONL 文件），驱动全新的撮合引擎，并与记录的事件流逐条比对。
+// 事件一致时退出码为 0，存在差异时为 1，参数或读取错误为 2。
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	commonredis "github.com/exchange/common/pkg/redis"
+	"github.com/exchange/common/pkg/shard"
+	"github.com/exchange/matching/internal/config"
+	"github.com/exchange/matching/internal/engine"
+	"github.com/exchange/matching/internal/handler"
+	"github.com/exchange/matching/internal/recovery"
+	"github.com/exchange/matching/internal/replay"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	batchSize    = 1000
+	maxLineBytes = 16 << 20
+)
+
+type replayConfig struct {
+	OrdersPath  string
+	EventsPath  string
+	From        string
+	To          string
+	EventsFrom  string
+	ShardID     int
+	Symbol      string
+	SnapshotDir string
+	Dedupe      bool
+	OutPath     string
+	MaxDiffs    int
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(runCLI(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func parseFlags(args []string, cfg *config.Config) (replayConfig, error) {
+	fs := flag.NewFlagSet("matching-replay", flag.ContinueOnError)
+
+	var rc replayConfig
+	fs.StringVar(&rc.OrdersPath, "orders", "", "JSONL file of order stream messages ({\"id\":...,\"data\":{...}}); empty reads the Redis order stream")
+	fs.StringVar(&rc.EventsPath, "events", "", "JSONL file of recorded event messages; empty reads the Redis event stream")
+	fs.StringVar(&rc.From, "from", "-", "first order stream ID to replay (Redis only)")
+	fs.StringVar(&rc.To, "to", "+", "last order stream ID to replay (Redis only)")
+	fs.StringVar(&rc.EventsFrom, "events-from", "-", "first event stream ID to load (Redis only)")
+	fs.IntVar(&rc.ShardID, "shard", cfg.ShardID, "order stream shard (Redis only)")
+	fs.StringVar(&rc.Symbol, "symbol", "", "replay only this symbol")
+	fs.StringVar(&rc.SnapshotDir, "snapshot-dir", "", "directory of engine snapshots to start from")
+	fs.BoolVar(&rc.Dedupe, "dedupe", cfg.OrderDedupeTTL > 0, "apply only the first message per dedupe key")
+	fs.StringVar(&rc.OutPath, "out", "", "write replayed events to this JSONL file")
+	fs.IntVar(&rc.MaxDiffs, "max-diffs", 20, "number of mismatches to print")
+
+	if err := fs.Parse(args); err != nil {
+		return rc, err
+	}
+	rc.Symbol = strings.ToUpper(strings.TrimSpace(rc.Symbol))
+	if rc.ShardID < 0 || rc.ShardID >= max(cfg.Shards.Count, 1) {
+		return rc, fmt.Errorf("invalid shard %d", rc.ShardID)
+	}
+	return rc, nil
+}
+
+func runCLI(ctx context.Context, args []string, out, errOut io.Writer) int {
+	cfg := config.Load()
+	rc, err := parseFlags(args, cfg)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(errOut, err.Error())
+		return 2
+	}
+	code, err := run(ctx, cfg, rc, out)
+	if err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return 2
+	}
+	return code
+}
+
+func run(ctx context.Context, cfg *config.Config, rc replayConfig, out io.Writer) (int, error) {
+	var redisClient *redis.Client
+	if rc.OrdersPath == "" || rc.EventsPath == "" {
+		tlsConfig, err := commonredis.TLSConfigFromEnv()
+		if err != nil {
+			return 0, fmt.Errorf("invalid Redis TLS config: %w", err)
+		}
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:        cfg.RedisAddr,
+			Password:    cfg.RedisPassword,
+			DB:          cfg.RedisDB,
+			TLSConfig:   tlsConfig,
+			DialTimeout: 5 * time.Second,
+			ReadTimeout: 30 * time.Second,
+		})
+		defer redisClient.Close()
+	}
+
+	snaps, err := loadSnapshots(ctx, rc.SnapshotDir, rc.Symbol)
+	if err != nil {
+		return 0, err
+	}
+	r := replay.NewReplayer(replay.Config{
+		Symbol:    rc.Symbol,
+		Breaker:   breakerConfig(cfg),
+		Dedupe:    rc.Dedupe,
+		Snapshots: snaps,
+	})
+
+	if rc.EventsPath != "" {
+		err = readLines(rc.EventsPath, r.Record)
+	} else {
+		err = readStream(ctx, redisClient, cfg.EventStream, rc.EventsFrom, "+", func(msg redis.XMessage) error {
+			data, ok := msg.Values["data"].(string)
+			if !ok {
+				return nil
+			}
+			return r.Record([]byte(data))
+		})
+	}
+	if err != nil {
+		return 0, fmt.Errorf("load recorded events: %w", err)
+	}
+
+	if rc.OutPath != "" {
+		f, err := os.Create(rc.OutPath)
+		if err != nil {
+			return 0, fmt.Errorf("create output: %w", err)
+		}
+		defer f.Close()
+		w := bufio.NewWriter(f)
+		defer w.Flush()
+		r.OnEvent(func(data []byte) {
+			w.Write(data)
+			w.WriteByte('\n')
+		})
+	}
+
+	if rc.OrdersPath != "" {
+		err = readLines(rc.OrdersPath, func(line []byte) error {
+			var msg replay.Message
+			if err := json.Unmarshal(line, &msg); err != nil {
+				return fmt.Errorf("decode order message: %w", err)
+			}
+			return r.Apply(ctx, msg)
+		})
+	} else {
+		orderStream := shard.StreamName(cfg.OrderStream, rc.ShardID, cfg.Shards.Count)
+		err = readStream(ctx, redisClient, orderStream, rc.From, rc.To, func(msg redis.XMessage) error {
+			orderMsg, ok := handler.DecodeOrderMessage(msg)
+			if !ok {
+				return nil
+			}
+			return r.Apply(ctx, replay.Message{ID: msg.ID, Data: orderMsg})
+		})
+	}
+	if err != nil {
+		return 0, fmt.Errorf("replay order stream: %w", err)
+	}
+
+	result, err := r.Finish(ctx)
+	if err != nil {
+		return 0, err
+	}
+	report(out, result, rc.MaxDiffs)
+	if !result.OK() {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// loadSnapshots 读取起始快照（dir 为空时不使用快照）
+func loadSnapshots(ctx context.Context, dir, symbol string) (map[string]*engine.Snapshot, error) {
+	snaps := make(map[string]*engine.Snapshot)
+	if dir == "" {
+		return snaps, nil
+	}
+	store, err := recovery.NewFileSnapshotStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	symbols, err := store.ListSymbols(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range symbols {
+		if symbol != "" && s != symbol {
+			continue
+		}
+		snap, err := store.Load(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if snap != nil {
+			snaps[s] = snap
+		}
+	}
+	return snaps, nil
+}
+
+// readStream 按 ID 顺序分批读取 Redis Stream 的 [from, to] 区间
+func readStream(ctx context.Context, client *redis.Client, stream, from, to string, fn func(redis.XMessage) error) error {
+	start := from
+	for {
+		msgs, err := client.XRangeN(ctx, stream, start, to, batchSize).Result()
+		if err != nil {
+			return fmt.Errorf("xrange %s: %w", stream, err)
+		}
+		for _, msg := range msgs {
+			if err := fn(msg); err != nil {
+				return err
+			}
+		}
+		if len(msgs) < batchSize {
+			return nil
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+// readLines 逐行读取 JSONL 文件（跳过空行）
+func readLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func report(out io.Writer, result *replay.Result, maxDiffs int) {
+	fmt.Fprintf(out, "messages=%d duplicates=%d events=%d matched=%d mismatched=%d unrecorded=%d not_replayed=%d\n",
+		result.Messages, result.Duplicates, result.Events, result.Matched, len(result.Mismatches), result.Unrecorded, result.NotReplayed)
+	for i, mismatch := range result.Mismatches {
+		if i >= maxDiffs {
+			fmt.Fprintf(out, "... %d more mismatches\n", len(result.Mismatches)-maxDiffs)
+			break
+		}
+		recorded := string(mismatch.Recorded)
+		if recorded == "" {
+			recorded = "<missing>"
+		}
+		fmt.Fprintf(out, "MISMATCH %s seq=%d\n  recorded: %s\n  replayed: %s\n", mismatch.Symbol, mismatch.Seq, recorded, mismatch.Replayed)
+	}
+}
+
+func breakerConfig(cfg *config.Config) engine.BreakerConfig {
+	action := engine.BreakerActionAuction
+	if cfg.BreakerAction == "reject" {
+		action = engine.BreakerActionReject
+	}
+	return engine.BreakerConfig{
+		BandBps:         cfg.PriceBandBps,
+		Window:          cfg.PriceBandWindow,
+		Action:          action,
+		AuctionDuration: cfg.BreakerAuctionDuration,
+	}
+}
@@ -16,8 +16,10 @@ import (
 	commonerrors "github.com/exchange/common/pkg/errors"
 	commonredis "github.com/exchange/common/pkg/redis"
 	commonresp "github.com/exchange/common/pkg/response"
+	"github.com/exchange/common/pkg/shard"
 	"github.com/exchange/common/pkg/snowflake"
 	"github.com/exchange/matching/internal/config"
+	"github.com/exchange/matching/internal/engine"
 	"github.com/exchange/matching/internal/handler"
 	"github.com/exchange/matching/internal/metrics"
 	"github.com/exchange/matching/internal/orderbook"
@@ -88,21 +90,50 @@ func main() {
 		orderLoader = recovery.NewDBOrderLoader(db)
 	}
 
+	var snapshotStore handler.SnapshotStore
+	if cfg.SnapshotDir != "" {
+		store, err := recovery.NewFileSnapshotStore(cfg.SnapshotDir)
+		if err != nil {
+			log.Fatalf("Failed to init snapshot store: %v", err)
+		}
+		snapshotStore = store
+		log.Printf("Engine snapshots enabled at %s (interval %s)", cfg.SnapshotDir, cfg.SnapshotInterval)
+	}
+
+	shards, err := shard.NewRouter(cfg.Shards, redisClient)
+	if err != nil {
+		log.Fatalf("Invalid shard config: %v", err)
+	}
+	orderStream := shard.StreamName(cfg.OrderStream, cfg.ShardID, cfg.Shards.Count)
+	if cfg.Shards.Count > 1 {
+		log.Printf("Matching shard %d of %d", cfg.ShardID, cfg.Shards.Count)
+	}
+
 	// 创建处理器
 	h := handler.NewHandler(redisClient, &handler.Config{
-		OrderStream: cfg.OrderStream,
-		EventStream: cfg.EventStream,
-		Group:       cfg.ConsumerGroup,
-		Consumer:    cfg.ConsumerName,
-		DedupeTTL:   cfg.OrderDedupeTTL,
-		OrderLoader: orderLoader,
+		OrderStream:      orderStream,
+		EventStream:      cfg.EventStream,
+		Group:            cfg.ConsumerGroup,
+		Consumer:         cfg.ConsumerName,
+		DedupeTTL:        cfg.OrderDedupeTTL,
+		OrderLoader:      orderLoader,
+		SnapshotStore:    snapshotStore,
+		SnapshotInterval: cfg.SnapshotInterval,
+		Breaker:          breakerConfig(cfg),
+		Shards:           shards,
+		ShardID:          cfg.ShardID,
+		LeaseTTL:         leaseTTL(cfg),
 	})
 
 	// 启动处理器
 	if err := h.Start(ctx); err != nil {
 		log.Fatalf("Failed to start handler: %v", err)
 	}
-	log.Printf("Handler started, consuming from %s", cfg.OrderStream)
+	if h.IsLeader() {
+		log.Printf("Handler started, consuming from %s", orderStream)
+	} else {
+		log.Printf("Handler started as standby, following %s", orderStream)
+	}
 
 	// HTTP 服务（健康检查 + 深度查询）
 	mux := http.NewServeMux()
@@ -164,6 +195,26 @@ func main() {
 	})
 	mux.HandleFunc("/depth", depthHandler)
 	mux.HandleFunc("/v1/depth", depthHandler)
+	mux.HandleFunc("/v1/depth/l3", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		symbol, valid := handler.NormalizeSymbol(r.URL.Query().Get("symbol"))
+		if !valid {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidParam, "invalid symbol")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		book, ok, err := h.GetBookL3(ctx, symbol)
+		if !ok {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeSymbolNotFound, "symbol not found")
+			return
+		}
+		if err != nil {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeTimeout, "order book snapshot unavailable")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(book)
+	}))
 
 	if cfg.AppEnv == "dev" || os.Getenv("ALLOW_INTERNAL_RESET") == "1" {
 		resetHandler := requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
@@ -204,7 +255,14 @@ func main() {
 	// 等待退出信号
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	exitCode := 0
+	select {
+	case <-sigCh:
+	case <-h.LeaseLost():
+		// 失去租约后订单簿可能超前于订单流，退出后以备机身份重新恢复
+		log.Println("Leader lease lost")
+		exitCode = 1
+	}
 
 	log.Println("Shutting down...")
 	cancel()
@@ -216,6 +274,17 @@ func main() {
 	}
 	redisClient.Close()
 	log.Println("Shutdown complete")
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// leaseTTL 未启用主备时返回 0（单实例运行）
+func leaseTTL(cfg *config.Config) time.Duration {
+	if !cfg.HAEnabled {
+		return 0
+	}
+	return cfg.LeaderLeaseTTL
 }
 
 type dependencyStatus struct {
@@ -279,6 +348,19 @@ func writeHealth(w http.ResponseWriter, deps []dependencyStatus) {
 	})
 }
 
+func breakerConfig(cfg *config.Config) engine.BreakerConfig {
+	action := engine.BreakerActionAuction
+	if cfg.BreakerAction == "reject" {
+		action = engine.BreakerActionReject
+	}
+	return engine.BreakerConfig{
+		BandBps:         cfg.PriceBandBps,
+		Window:          cfg.PriceBandWindow,
+		Action:          action,
+		AuctionDuration: cfg.BreakerAuctionDuration,
+	}
+}
+
 func metricsAuthorized(r *http.Request, token string) bool {
 	if token == "" {
 		return true
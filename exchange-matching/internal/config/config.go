@@ -8,6 +8,7 @@ import (
 	"time"
 
 	envconfig "github.com/exchange/common/pkg/config"
+	"github.com/exchange/common/pkg/shard"
 )
 
 // Config 服务配置
@@ -43,6 +44,24 @@ type Config struct {
 	OrderDedupeTTL  time.Duration
 	RecoveryEnabled bool
 
+	// Snapshot（SnapshotDir 为空时不启用）
+	SnapshotDir      string
+	SnapshotInterval time.Duration
+
+	// 波动熔断（PriceBandBps 为 0 时不启用）
+	PriceBandBps           int64
+	PriceBandWindow        time.Duration
+	BreakerAction          string // auction / reject
+	BreakerAuctionDuration time.Duration
+
+	// 分片：本实例只消费分片 ShardID 的订单流（Shards.Count 为 1 时不分片）
+	ShardID int
+	Shards  shard.Config
+
+	// 主备：同一分片的多个实例竞争租约，持有者消费订单流，其余实例热备
+	HAEnabled      bool
+	LeaderLeaseTTL time.Duration
+
 	// Private events (pub/sub)
 	PrivateUserEventChannel string
 
@@ -83,6 +102,20 @@ func Load() *Config {
 		OrderDedupeTTL:  envconfig.GetEnvDuration("MATCHING_ORDER_DEDUP_TTL", 24*time.Hour),
 		RecoveryEnabled: envconfig.GetEnvBool("MATCHING_RECOVERY_ENABLED", strings.ToLower(envconfig.GetEnv("APP_ENV", "dev")) != "dev"),
 
+		SnapshotDir:      envconfig.GetEnv("MATCHING_SNAPSHOT_DIR", ""),
+		SnapshotInterval: envconfig.GetEnvDuration("MATCHING_SNAPSHOT_INTERVAL", 30*time.Second),
+
+		PriceBandBps:           envconfig.GetEnvInt64("MATCHING_PRICE_BAND_BPS", 1000),
+		PriceBandWindow:        envconfig.GetEnvDuration("MATCHING_PRICE_BAND_WINDOW", 5*time.Minute),
+		BreakerAction:          strings.ToLower(envconfig.GetEnv("MATCHING_BREAKER_ACTION", "auction")),
+		BreakerAuctionDuration: envconfig.GetEnvDuration("MATCHING_BREAKER_AUCTION_DURATION", 30*time.Second),
+
+		ShardID: envconfig.GetEnvInt("MATCHING_SHARD_ID", 0),
+		Shards:  shard.ConfigFromEnv(),
+
+		HAEnabled:      envconfig.GetEnvBool("MATCHING_HA_ENABLED", false),
+		LeaderLeaseTTL: envconfig.GetEnvDuration("MATCHING_LEADER_LEASE_TTL", 5*time.Second),
+
 		PrivateUserEventChannel: envconfig.GetEnv("PRIVATE_USER_EVENT_CHANNEL", "private:user:{userId}:events"),
 
 		InternalToken: envconfig.GetEnv("INTERNAL_TOKEN", ""),
@@ -120,6 +153,34 @@ func (c *Config) Validate() error {
 	if c.OrderDedupeTTL <= 0 {
 		return fmt.Errorf("MATCHING_ORDER_DEDUP_TTL must be positive")
 	}
+	if c.SnapshotDir != "" && c.SnapshotInterval <= 0 {
+		return fmt.Errorf("MATCHING_SNAPSHOT_INTERVAL must be positive")
+	}
+	if c.PriceBandBps < 0 || c.PriceBandWindow < 0 {
+		return fmt.Errorf("MATCHING_PRICE_BAND_BPS and MATCHING_PRICE_BAND_WINDOW must not be negative")
+	}
+	if err := c.Shards.Validate(); err != nil {
+		return fmt.Errorf("invalid MATCHING_SHARD_COUNT/MATCHING_SHARDS: %w", err)
+	}
+	if c.ShardID < 0 || c.ShardID >= c.Shards.Count {
+		return fmt.Errorf("MATCHING_SHARD_ID must be in [0, MATCHING_SHARD_COUNT)")
+	}
+	if c.HAEnabled && c.LeaderLeaseTTL < time.Second {
+		return fmt.Errorf("MATCHING_LEADER_LEASE_TTL must be at least 1s")
+	}
+	if c.HAEnabled && c.SnapshotDir == "" {
+		// 备机只能从快照对齐订单簿与事件序列号
+		return fmt.Errorf("MATCHING_SNAPSHOT_DIR is required when MATCHING_HA_ENABLED=true")
+	}
+	switch c.BreakerAction {
+	case "auction":
+		if c.PriceBandBps > 0 && c.BreakerAuctionDuration <= 0 {
+			return fmt.Errorf("MATCHING_BREAKER_AUCTION_DURATION must be positive")
+		}
+	case "reject":
+	default:
+		return fmt.Errorf("MATCHING_BREAKER_ACTION must be auction or reject")
+	}
 	return nil
 }
 
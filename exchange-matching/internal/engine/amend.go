@@ -0,0 +1,89 @@
+package engine
+
+import "github.com/exchange/matching/internal/orderbook"
+
+// processAmendOrder 原子改单（撤单+下单合并为一步）
+//
+// cmd.Qty 为改单后的订单总数量（含已成交），cmd.Price/cmd.Qty 为 0 表示不修改。
+// 同价减量保留时间优先级；改价或加量视为重新入簿，失去时间优先级并可能立即成交。
+// 改单失败发送 AMEND_REJECTED，原订单保持不变。
+func (e *Engine) processAmendOrder(cmd *Command) {
+	order := e.book.GetOrder(cmd.OrderID)
+	if order == nil || (cmd.UserID != 0 && order.UserID != cmd.UserID) {
+		e.rejectAmend(cmd, "ORDER_NOT_FOUND")
+		return
+	}
+
+	newPrice := cmd.Price
+	if newPrice <= 0 {
+		newPrice = order.Price
+	}
+	newQty := cmd.Qty
+	if newQty <= 0 {
+		newQty = order.OrigQty
+	}
+	executedQty := order.OrigQty - order.LeavesQty
+
+	switch {
+	case newQty <= executedQty:
+		e.rejectAmend(cmd, "INVALID_AMEND_QTY")
+		return
+	case newPrice == order.Price && newQty == order.OrigQty:
+		e.rejectAmend(cmd, "AMEND_NO_CHANGE")
+		return
+	}
+
+	amended := &OrderAmendedData{
+		OrderID:       order.OrderID,
+		ClientOrderID: order.ClientOrderID,
+		UserID:        order.UserID,
+		AmendID:       cmd.AmendID,
+		Side:          order.Side,
+		OldPrice:      order.Price,
+		Price:         newPrice,
+		OldQty:        order.OrigQty,
+		OrigQty:       newQty,
+		LeavesQty:     newQty - executedQty,
+	}
+
+	// 同价减量：原地扣减，保留时间优先级
+	if newPrice == order.Price && newQty < order.OrigQty {
+		e.book.DecreaseOrderQty(order.OrderID, order.OrigQty-newQty)
+		amended.PriorityKept = true
+		amended.VisibleQty = visibleQty(order)
+		e.emit(EventOrderAmended, amended)
+		return
+	}
+
+	// POST_ONLY 改价后会立即成交则拒绝改单
+	if order.TimeInForce == 4 && e.wouldMatch(&orderbook.Order{Side: order.Side, Price: newPrice}) {
+		e.rejectAmend(cmd, "POST_ONLY_REJECTED")
+		return
+	}
+
+	e.book.RemoveOrder(order.OrderID)
+	order.Price = newPrice
+	order.OrigQty = newQty
+	order.LeavesQty = newQty - executedQty
+	order.VisibleQty = 0
+	order.Timestamp = e.now().UnixNano()
+	amended.VisibleQty = restingVisibleQty(order)
+	e.emit(EventOrderAmended, amended)
+
+	// 集合竞价期间只重新入簿，等待统一撮合
+	if e.auction {
+		e.book.AddOrder(order)
+		return
+	}
+	e.matchOrder(order, 1, true)
+}
+
+func (e *Engine) rejectAmend(cmd *Command, reason string) {
+	e.emit(EventAmendRejected, &AmendRejectedData{
+		OrderID:       cmd.OrderID,
+		ClientOrderID: cmd.ClientOrderID,
+		UserID:        cmd.UserID,
+		AmendID:       cmd.AmendID,
+		Reason:        reason,
+	})
+}
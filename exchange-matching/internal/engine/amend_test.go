@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+func TestAmendQtyDownKeepsPriority(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdAmendOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT", Qty: 4, AmendID: 7,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 12, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 2,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderFilled) != nil
+	})
+
+	amended := findEvent(events, EventOrderAmended)
+	if amended == nil {
+		t.Fatal("expected amended event")
+	}
+	data := amended.Data.(*OrderAmendedData)
+	if data.AmendID != 7 || !data.PriorityKept || data.OldQty != 10 || data.OrigQty != 4 || data.LeavesQty != 4 || data.Price != 100 {
+		t.Fatalf("unexpected amended data: %+v", data)
+	}
+	trade := findEvent(events, EventTradeCreated).Data.(*TradeCreatedData)
+	if trade.MakerOrderID != 1 {
+		t.Fatalf("expected amended order to keep priority, maker=%d", trade.MakerOrderID)
+	}
+	_, asks := engine.Depth(1)
+	if len(asks) != 1 || asks[0].Qty != 7 {
+		t.Fatalf("unexpected asks: %+v", asks)
+	}
+}
+
+func TestAmendPriceLosesPriorityAndMatches(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 12, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 99, Qty: 4,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdAmendOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT", Price: 99, AmendID: 8,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderPartiallyFilled) != nil
+	})
+
+	amended := findEvent(events, EventOrderAmended)
+	if amended == nil {
+		t.Fatal("expected amended event")
+	}
+	data := amended.Data.(*OrderAmendedData)
+	if data.PriorityKept || data.OldPrice != 100 || data.Price != 99 || data.LeavesQty != 10 {
+		t.Fatalf("unexpected amended data: %+v", data)
+	}
+	trade := findEvent(events, EventTradeCreated).Data.(*TradeCreatedData)
+	if trade.TakerOrderID != 1 || trade.MakerOrderID != 3 || trade.Price != 99 || trade.Qty != 4 {
+		t.Fatalf("unexpected trade: %+v", trade)
+	}
+	partial := findEvent(events, EventOrderPartiallyFilled).Data.(*OrderPartiallyFilledData)
+	if partial.OrderID != 1 || partial.ExecutedQty != 4 || partial.LeavesQty != 6 {
+		t.Fatalf("unexpected partial fill: %+v", partial)
+	}
+
+	// 剩余数量重新入簿（不重复发送 ORDER_ACCEPTED），排在订单 2 之前的价位
+	bids, asks := engine.Depth(2)
+	if len(bids) != 0 || len(asks) != 2 || asks[0].Price != 99 || asks[0].Qty != 6 || asks[1].Qty != 5 {
+		t.Fatalf("unexpected depth bids=%+v asks=%+v", bids, asks)
+	}
+	for _, ev := range events {
+		if ev.Type == EventOrderAccepted && ev.Data.(*OrderAcceptedData).OrderID == 1 && ev.Seq > amended.Seq {
+			t.Fatal("amended order should not emit ORDER_ACCEPTED")
+		}
+	}
+}
+
+func TestAmendRejected(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	cases := []struct {
+		cmd    *Command
+		reason string
+	}{
+		{&Command{Type: CmdAmendOrder, OrderID: 99, UserID: 10, Qty: 4, AmendID: 1}, "ORDER_NOT_FOUND"},
+		{&Command{Type: CmdAmendOrder, OrderID: 1, UserID: 11, Qty: 4, AmendID: 2}, "ORDER_NOT_FOUND"},
+		{&Command{Type: CmdAmendOrder, OrderID: 1, UserID: 10, Price: 100, Qty: 10, AmendID: 3}, "AMEND_NO_CHANGE"},
+	}
+	for _, tc := range cases {
+		submitOrFail(t, engine, tc.cmd)
+	}
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return len(ev) >= 2+len(cases)
+	})
+	for i, tc := range cases {
+		ev := events[2+i]
+		if ev.Type != EventAmendRejected {
+			t.Fatalf("case %d: expected amend rejected, got %v", i, ev.Type)
+		}
+		data := ev.Data.(*AmendRejectedData)
+		if data.AmendID != tc.cmd.AmendID || data.Reason != tc.reason {
+			t.Fatalf("case %d: unexpected data %+v", i, data)
+		}
+	}
+	_, asks := engine.Depth(1)
+	if len(asks) != 1 || asks[0].Qty != 15 {
+		t.Fatalf("book should be unchanged: %+v", asks)
+	}
+}
+
+func TestAmendQtyBelowExecutedRejected(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 12, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 6,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdAmendOrder, OrderID: 1, UserID: 10, Qty: 6, AmendID: 5,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventAmendRejected) != nil
+	})
+	data := findEvent(events, EventAmendRejected).Data.(*AmendRejectedData)
+	if data.Reason != "INVALID_AMEND_QTY" {
+		t.Fatalf("unexpected reason: %s", data.Reason)
+	}
+}
@@ -0,0 +1,123 @@
+package engine
+
+import "github.com/exchange/matching/internal/orderbook"
+
+// 交易对状态（Command.Status，与 symbol_configs.status 一致）
+const (
+	StatusTrading    = 1
+	StatusHalt       = 2
+	StatusCancelOnly = 3
+	StatusAuction    = 4
+)
+
+// AuctionIndicativeData 集合竞价参考价事件数据（进入竞价及盘口变化导致参考价变化时发送）
+type AuctionIndicativeData struct {
+	Price   int64 // 参考均衡价，0 表示买卖盘未交叉
+	Volume  int64 // 参考价下的可成交数量
+	BuyQty  int64 // 价格不低于参考价的买单总量
+	SellQty int64 // 价格不高于参考价的卖单总量
+}
+
+// AuctionUncrossedData 集合竞价结束事件数据（在撮合产生的成交与订单事件之后发送）
+type AuctionUncrossedData struct {
+	Price  int64 // 统一成交价，0 表示没有成交
+	Volume int64 // 成交总量
+}
+
+// processSetStatus 处理交易对状态变更
+//
+// 进入 AUCTION 后只挂单不撮合；切换到 TRADING 时以单一价格撮合交叉部分。
+// HALT / CANCEL_ONLY 由订单服务拦截新订单，竞价状态保留到恢复交易时再撮合。
+func (e *Engine) processSetStatus(cmd *Command) {
+	switch cmd.Status {
+	case StatusAuction:
+		if e.auction {
+			// 熔断竞价转为人工控制，到期后不再自动恢复
+			e.clearBreaker()
+			return
+		}
+		e.auction = true
+		e.publishIndicative(true)
+	case StatusTrading:
+		if e.auction {
+			e.uncross()
+		}
+	case StatusHalt, StatusCancelOnly:
+		e.clearBreaker()
+	}
+}
+
+// publishIndicative 发送集合竞价参考价，force 为 false 时仅在参考价变化时发送
+func (e *Engine) publishIndicative(force bool) {
+	eq := e.book.Equilibrium(e.lastPrice)
+	if !force && eq == e.indicative {
+		return
+	}
+	e.indicative = eq
+	e.emit(EventAuctionIndicative, &AuctionIndicativeData{
+		Price:   eq.Price,
+		Volume:  eq.Volume,
+		BuyQty:  eq.BuyQty,
+		SellQty: eq.SellQty,
+	})
+}
+
+// uncross 结束集合竞价：以成交量最大的均衡价撮合，参考价为最新成交价
+func (e *Engine) uncross() {
+	e.auction = false
+	e.indicative = orderbook.Equilibrium{}
+	e.clearBreaker()
+
+	eq := e.book.Equilibrium(e.lastPrice)
+	var volume int64
+	if eq.Volume > 0 {
+		for _, result := range e.book.Uncross(eq.Price) {
+			volume += e.emitUncrossResult(result)
+		}
+	}
+
+	price := eq.Price
+	if volume == 0 {
+		price = 0
+	} else if e.breaker.BandBps > 0 {
+		// 统一成交价作为新的参考价
+		e.bandSamples = []PriceSample{{TimeMs: e.clockMs(), Price: price}}
+	}
+	e.emit(EventAuctionUncrossed, &AuctionUncrossedData{Price: price, Volume: volume})
+}
+
+// emitUncrossResult 发送单个买单的集合竞价撮合事件，返回成交数量
+//
+// 买单在撮合前已在簿，未完全成交的剩余数量保留原有优先级，因此不再发送 ORDER_ACCEPTED。
+func (e *Engine) emitUncrossResult(result *orderbook.MatchResult) int64 {
+	order := result.TakerOrder
+	stpReason := e.emitFills(order, result)
+
+	var traded int64
+	for _, trade := range result.Trades {
+		traded += trade.Qty
+	}
+
+	executedQty := order.OrigQty - order.LeavesQty - result.TakerExpiredQty
+	switch {
+	case result.TakerExpired:
+		e.expireTaker(order, result, executedQty, stpReason)
+	case result.TakerFilled:
+		e.emit(EventOrderFilled, &OrderFilledData{
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			UserID:        order.UserID,
+			ExecutedQty:   executedQty,
+		})
+	case traded > 0:
+		e.emit(EventOrderPartiallyFilled, &OrderPartiallyFilledData{
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			UserID:        order.UserID,
+			ExecutedQty:   executedQty,
+			LeavesQty:     order.LeavesQty,
+			VisibleQty:    visibleQty(order),
+		})
+	}
+	return traded
+}
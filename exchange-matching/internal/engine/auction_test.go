@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+func submitAuctionBook(t *testing.T, engine *Engine) {
+	t.Helper()
+	submitOrFail(t, engine, &Command{Type: CmdSetStatus, Symbol: "BTCUSDT", Status: StatusAuction})
+	orders := []*Command{
+		{OrderID: 1, UserID: 1, Side: orderbook.SideBuy, Price: 101, Qty: 10},
+		{OrderID: 2, UserID: 2, Side: orderbook.SideBuy, Price: 100, Qty: 10},
+		{OrderID: 3, UserID: 3, Side: orderbook.SideSell, Price: 99, Qty: 8},
+		{OrderID: 4, UserID: 4, Side: orderbook.SideSell, Price: 100, Qty: 6},
+	}
+	for _, cmd := range orders {
+		cmd.Type = CmdNewOrder
+		cmd.Symbol = "BTCUSDT"
+		cmd.OrderType = 1
+		cmd.TimeInForce = 1
+		submitOrFail(t, engine, cmd)
+	}
+}
+
+func TestAuctionRestsOrdersAndPublishesIndicative(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitAuctionBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 5, UserID: 5, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 2, TimeInForce: 2, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderRejected) != nil
+	})
+	if findEvent(events, EventTradeCreated) != nil {
+		t.Fatal("expected no trades during auction")
+	}
+	rejected := findEvent(events, EventOrderRejected).Data.(*OrderRejectedData)
+	if rejected.OrderID != 5 || rejected.Reason != "AUCTION_ORDER_NOT_ALLOWED" {
+		t.Fatalf("unexpected reject: %+v", rejected)
+	}
+
+	// 进入竞价时发送一次，此后仅在参考价变化时发送
+	var last *AuctionIndicativeData
+	count := 0
+	for _, ev := range events {
+		if ev.Type == EventAuctionIndicative {
+			last = ev.Data.(*AuctionIndicativeData)
+			count++
+		}
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 indicative events, got %d", count)
+	}
+	if last.Price != 100 || last.Volume != 14 || last.BuyQty != 20 || last.SellQty != 14 {
+		t.Fatalf("unexpected indicative: %+v", last)
+	}
+}
+
+func TestAuctionUncrossOnTrading(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitAuctionBook(t, engine)
+	submitOrFail(t, engine, &Command{Type: CmdSetStatus, Symbol: "BTCUSDT", Status: StatusTrading})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventAuctionUncrossed) != nil
+	})
+	var volume int64
+	for _, ev := range events {
+		if ev.Type == EventTradeCreated {
+			trade := ev.Data.(*TradeCreatedData)
+			if trade.Price != 100 {
+				t.Fatalf("expected uncross at 100, got %+v", trade)
+			}
+			volume += trade.Qty
+		}
+	}
+	uncrossed := findEvent(events, EventAuctionUncrossed).Data.(*AuctionUncrossedData)
+	if volume != 14 || uncrossed.Price != 100 || uncrossed.Volume != 14 {
+		t.Fatalf("unexpected uncross: volume=%d data=%+v", volume, uncrossed)
+	}
+	if findEvent(events, EventOrderAccepted).Data.(*OrderAcceptedData).OrderID != 1 {
+		t.Fatal("expected orders accepted before uncross")
+	}
+
+	bids, asks := engine.Depth(5)
+	if len(bids) != 1 || bids[0] != (orderbook.PriceQty{Price: 100, Qty: 6}) || len(asks) != 0 {
+		t.Fatalf("unexpected depth after uncross: %+v %+v", bids, asks)
+	}
+
+	// 恢复连续撮合
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 6, UserID: 6, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1,
+	})
+	events = collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventTradeCreated) != nil
+	})
+	if findEvent(events, EventAuctionIndicative) != nil {
+		t.Fatal("expected no indicative after auction ended")
+	}
+}
+
+func TestAuctionSnapshotKeepsState(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitAuctionBook(t, engine)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	snap, err := engine.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if !snap.Auction {
+		t.Fatal("expected auction flag in snapshot")
+	}
+
+	restored := NewEngine("BTCUSDT", 10000, 10000)
+	defer restored.Stop()
+	if err := restored.Restore(snap); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restored.Start()
+	submitOrFail(t, restored, &Command{
+		Type: CmdNewOrder, OrderID: 7, UserID: 7, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 98, Qty: 1,
+	})
+	events := collectUntil(t, restored, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventAuctionIndicative) != nil
+	})
+	if findEvent(events, EventTradeCreated) != nil {
+		t.Fatal("expected restored engine to stay in auction")
+	}
+	if events[0].Type != EventOrderAccepted || events[0].Seq != snap.Seq+1 {
+		t.Fatalf("unexpected first event: %+v", events[0])
+	}
+}
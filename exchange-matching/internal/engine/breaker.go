@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+// 价格带被突破时的处理方式
+const (
+	BreakerActionAuction = 1 // 暂停连续撮合，进入短时集合竞价（默认）
+	BreakerActionReject  = 2 // 撤销 taker 会突破价格带的剩余数量，继续连续撮合
+)
+
+// BreakerConfig 波动熔断配置
+//
+// 参考价为滚动窗口起点时的最新成交价，价格带为参考价上下 BandBps 基点；
+// taker 只能在价格带内成交，剩余数量会以带外价格成交时触发熔断。
+type BreakerConfig struct {
+	BandBps         int64         // 价格带宽度（基点），0 表示不启用
+	Window          time.Duration // 参考价滚动窗口，0 表示以最新成交价为参考价
+	Action          int           // BreakerActionAuction / BreakerActionReject
+	AuctionDuration time.Duration // 熔断竞价时长（BreakerActionAuction）
+}
+
+// CircuitBreakerData 熔断事件数据（在触发订单的事件之后发送）
+type CircuitBreakerData struct {
+	Action      string // AUCTION / REJECT
+	OrderID     int64  // 触发熔断的订单
+	RefPrice    int64  // 参考价
+	LowPrice    int64  // 价格带下限
+	HighPrice   int64  // 价格带上限
+	BreachPrice int64  // 价格带外的对手盘最优价
+	BreakerID   int64  // 熔断竞价标识（AUCTION），即预计恢复连续撮合的时间（毫秒）
+}
+
+// PriceSample 参考价窗口内的成交价（仅记录价格变化）
+type PriceSample struct {
+	TimeMs int64
+	Price  int64
+}
+
+// priceBand 当前价格带
+type priceBand struct {
+	ref, low, high int64
+}
+
+// SetBreaker 设置波动熔断配置（需在 Start 之前调用）
+//
+// resume 在熔断竞价到期时调用，由调用方通过订单流提交 CmdResumeBreaker，
+// 使恢复撮合的时机与订单流顺序一致；为空时引擎直接提交给自身。
+func (e *Engine) SetBreaker(cfg BreakerConfig, resume func(symbol string, breakerID int64)) {
+	e.breaker = cfg
+	e.resumeBreaker = resume
+}
+
+// clockMs 引擎时间：优先取订单流消息 ID 中的时间，重放时得到相同的价格带与恢复时间
+func (e *Engine) clockMs() int64 {
+	if ms, _ := parseStreamID(e.streamID); ms > 0 {
+		return int64(ms)
+	}
+	return e.now().UnixMilli()
+}
+
+// recordTradePrice 记录成交价，用于计算参考价
+func (e *Engine) recordTradePrice(price int64) {
+	if e.breaker.BandBps <= 0 {
+		return
+	}
+	if n := len(e.bandSamples); n > 0 && e.bandSamples[n-1].Price == price {
+		return
+	}
+	e.bandSamples = append(e.bandSamples, PriceSample{TimeMs: e.clockMs(), Price: price})
+}
+
+// currentBand 计算当前价格带，未启用或尚无成交时返回 false
+func (e *Engine) currentBand() (priceBand, bool) {
+	if e.breaker.BandBps <= 0 || len(e.bandSamples) == 0 {
+		return priceBand{}, false
+	}
+	cutoff := e.clockMs() - e.breaker.Window.Milliseconds()
+	for len(e.bandSamples) > 1 && e.bandSamples[1].TimeMs <= cutoff {
+		e.bandSamples = e.bandSamples[1:]
+	}
+	ref := e.bandSamples[0].Price
+	width := ref * e.breaker.BandBps / 10000
+	return priceBand{ref: ref, low: ref - width, high: ref + width}, true
+}
+
+// bandLimit taker 在价格带内可接受的最差价格（0 表示不限）
+func bandLimit(order *orderbook.Order, band priceBand) int64 {
+	if order.Side == orderbook.SideBuy {
+		if order.Price == 0 || order.Price > band.high {
+			return band.high
+		}
+		return order.Price
+	}
+	if band.low <= 0 {
+		return order.Price
+	}
+	if order.Price == 0 || order.Price < band.low {
+		return band.low
+	}
+	return order.Price
+}
+
+// bandBreach 撮合后 taker 剩余数量能否以价格带外的价格成交，返回该对手盘价格
+func (e *Engine) bandBreach(order *orderbook.Order, band priceBand) (int64, bool) {
+	if order.LeavesQty <= 0 {
+		return 0, false
+	}
+	if order.Side == orderbook.SideBuy {
+		ask, _, ok := e.book.BestAsk()
+		return ask, ok && ask > band.high && (order.Price == 0 || order.Price >= ask)
+	}
+	bid, _, ok := e.book.BestBid()
+	return bid, ok && bid < band.low && (order.Price == 0 || order.Price <= bid)
+}
+
+// tripBreaker 价格带被突破：处理 taker 剩余数量并发送熔断事件
+//
+// AUCTION 模式下 GTC 限价单剩余数量挂单参与熔断竞价，其余订单的剩余数量撤销；
+// REJECT 模式下剩余数量一律撤销，未成交的新订单直接拒绝。
+func (e *Engine) tripBreaker(order *orderbook.Order, orderType int, result *orderbook.MatchResult, executedQty int64, amended bool, band priceBand, breachPrice int64) {
+	auction := e.breaker.Action != BreakerActionReject
+	traded := len(result.Trades) > 0
+
+	if auction && orderType == 1 && order.TimeInForce == 1 {
+		if traded {
+			e.emit(EventOrderPartiallyFilled, &OrderPartiallyFilledData{
+				OrderID:       order.OrderID,
+				ClientOrderID: order.ClientOrderID,
+				UserID:        order.UserID,
+				ExecutedQty:   executedQty,
+				LeavesQty:     order.LeavesQty,
+				VisibleQty:    restingVisibleQty(order),
+			})
+		}
+		e.restOrder(order, amended)
+	} else if traded || amended {
+		if traded {
+			e.emit(EventOrderPartiallyFilled, &OrderPartiallyFilledData{
+				OrderID:       order.OrderID,
+				ClientOrderID: order.ClientOrderID,
+				UserID:        order.UserID,
+				ExecutedQty:   executedQty,
+				LeavesQty:     order.LeavesQty,
+			})
+		}
+		e.emit(EventOrderCanceled, &OrderCanceledData{
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			UserID:        order.UserID,
+			LeavesQty:     order.LeavesQty,
+			Reason:        "PRICE_BAND_BREACHED",
+		})
+	} else {
+		e.emit(EventOrderRejected, &OrderRejectedData{
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			UserID:        order.UserID,
+			Reason:        "PRICE_BAND_BREACHED",
+		})
+	}
+
+	data := &CircuitBreakerData{
+		Action:      "REJECT",
+		OrderID:     order.OrderID,
+		RefPrice:    band.ref,
+		LowPrice:    band.low,
+		HighPrice:   band.high,
+		BreachPrice: breachPrice,
+	}
+	if !auction {
+		e.emit(EventCircuitBreaker, data)
+		return
+	}
+
+	data.Action = "AUCTION"
+	data.BreakerID = e.clockMs() + e.breaker.AuctionDuration.Milliseconds()
+	e.emit(EventCircuitBreaker, data)
+
+	e.auction = true
+	e.breakerID = data.BreakerID
+	e.scheduleBreakerResume()
+	e.publishIndicative(true)
+}
+
+// scheduleBreakerResume 在熔断竞价到期时请求恢复连续撮合
+func (e *Engine) scheduleBreakerResume() {
+	e.stopBreakerTimer()
+	breakerID := e.breakerID
+	delay := time.Until(time.UnixMilli(breakerID))
+	e.breakerTimer = time.AfterFunc(max(delay, 0), func() {
+		if e.ctx.Err() != nil {
+			return
+		}
+		if e.resumeBreaker != nil {
+			e.resumeBreaker(e.symbol, breakerID)
+			return
+		}
+		e.Submit(&Command{Type: CmdResumeBreaker, Symbol: e.symbol, BreakerID: breakerID})
+	})
+}
+
+func (e *Engine) stopBreakerTimer() {
+	if e.breakerTimer != nil {
+		e.breakerTimer.Stop()
+		e.breakerTimer = nil
+	}
+}
+
+// clearBreaker 熔断竞价结束或转为人工控制
+func (e *Engine) clearBreaker() {
+	e.breakerID = 0
+	e.stopBreakerTimer()
+}
+
+// processResumeBreaker 熔断竞价到期：仅结束对应的熔断竞价，重复或过期的请求忽略
+func (e *Engine) processResumeBreaker(cmd *Command) {
+	if !e.auction || e.breakerID == 0 || cmd.BreakerID != e.breakerID {
+		return
+	}
+	e.uncross()
+}
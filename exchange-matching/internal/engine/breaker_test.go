@@ -0,0 +1,246 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+func newBreakerEngine(action int, duration time.Duration) *Engine {
+	engine := NewEngine("BTCUSDT", 10000, 10000)
+	engine.SetBreaker(BreakerConfig{
+		BandBps:         1000,
+		Window:          time.Minute,
+		Action:          action,
+		AuctionDuration: duration,
+	}, nil)
+	engine.Start()
+	return engine
+}
+
+// submitBreakerBook 以 100 成交一笔建立参考价，价格带为 [90, 110]，卖盘剩余 105 x 1、130 x 5
+func submitBreakerBook(t *testing.T, engine *Engine) {
+	t.Helper()
+	orders := []*Command{
+		{OrderID: 1, UserID: 1, Side: orderbook.SideSell, Price: 100, Qty: 1},
+		{OrderID: 2, UserID: 1, Side: orderbook.SideSell, Price: 105, Qty: 1},
+		{OrderID: 3, UserID: 1, Side: orderbook.SideSell, Price: 130, Qty: 5},
+		{OrderID: 4, UserID: 2, Side: orderbook.SideBuy, Price: 100, Qty: 1},
+	}
+	for _, cmd := range orders {
+		cmd.Type = CmdNewOrder
+		cmd.Symbol = "BTCUSDT"
+		cmd.OrderType = 1
+		cmd.TimeInForce = 1
+		submitOrFail(t, engine, cmd)
+	}
+}
+
+func TestBreakerAuctionStopsMarketSweep(t *testing.T) {
+	engine := newBreakerEngine(BreakerActionAuction, time.Hour)
+	defer engine.Stop()
+
+	submitBreakerBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 5, UserID: 3, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 2, TimeInForce: 2, Qty: 5,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventAuctionIndicative) != nil
+	})
+	for _, ev := range events {
+		if ev.Type == EventTradeCreated && ev.Data.(*TradeCreatedData).Price > 110 {
+			t.Fatalf("expected no trade outside band, got %+v", ev.Data)
+		}
+	}
+	canceled := findEvent(events, EventOrderCanceled)
+	if canceled == nil {
+		t.Fatal("expected market order remainder canceled")
+	}
+	if data := canceled.Data.(*OrderCanceledData); data.OrderID != 5 || data.LeavesQty != 4 || data.Reason != "PRICE_BAND_BREACHED" {
+		t.Fatalf("unexpected cancel: %+v", data)
+	}
+	tripped := findEvent(events, EventCircuitBreaker)
+	if tripped == nil {
+		t.Fatal("expected circuit breaker event")
+	}
+	breaker := tripped.Data.(*CircuitBreakerData)
+	if breaker.Action != "AUCTION" || breaker.RefPrice != 100 || breaker.HighPrice != 110 || breaker.BreachPrice != 130 {
+		t.Fatalf("unexpected breaker data: %+v", breaker)
+	}
+
+	// 熔断竞价期间只接受 GTC 限价单
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 6, UserID: 4, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 130, Qty: 2,
+	})
+	// 过期的恢复请求不结束竞价
+	submitOrFail(t, engine, &Command{Type: CmdResumeBreaker, Symbol: "BTCUSDT", BreakerID: breaker.BreakerID - 1})
+	submitOrFail(t, engine, &Command{Type: CmdResumeBreaker, Symbol: "BTCUSDT", BreakerID: breaker.BreakerID})
+
+	events = collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventAuctionUncrossed) != nil
+	})
+	uncrossed := findEvent(events, EventAuctionUncrossed).Data.(*AuctionUncrossedData)
+	if uncrossed.Price != 130 || uncrossed.Volume != 2 {
+		t.Fatalf("unexpected uncross: %+v", uncrossed)
+	}
+	if findEvent(events, EventOrderRejected) != nil {
+		t.Fatal("expected GTC limit order accepted during breaker auction")
+	}
+}
+
+func TestBreakerRejectCancelsRemainder(t *testing.T) {
+	engine := newBreakerEngine(BreakerActionReject, 0)
+	defer engine.Stop()
+
+	submitBreakerBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 5, UserID: 3, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 130, Qty: 3,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 6, UserID: 3, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 2, TimeInForce: 2, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderRejected) != nil
+	})
+	canceled := findEvent(events, EventOrderCanceled).Data.(*OrderCanceledData)
+	if canceled.OrderID != 5 || canceled.LeavesQty != 2 || canceled.Reason != "PRICE_BAND_BREACHED" {
+		t.Fatalf("unexpected cancel: %+v", canceled)
+	}
+	rejected := findEvent(events, EventOrderRejected).Data.(*OrderRejectedData)
+	if rejected.OrderID != 6 || rejected.Reason != "PRICE_BAND_BREACHED" {
+		t.Fatalf("unexpected reject: %+v", rejected)
+	}
+	if breaker := findEvent(events, EventCircuitBreaker).Data.(*CircuitBreakerData); breaker.Action != "REJECT" {
+		t.Fatalf("unexpected breaker action: %+v", breaker)
+	}
+	if findEvent(events, EventAuctionIndicative) != nil {
+		t.Fatal("expected continuous trading to continue in reject mode")
+	}
+	if price, qty, ok := engine.book.BestAsk(); !ok || price != 130 || qty != 5 {
+		t.Fatalf("expected ask 130 x 5 untouched, got %d x %d", price, qty)
+	}
+}
+
+func TestBreakerAuctionResumesAfterDuration(t *testing.T) {
+	engine := newBreakerEngine(BreakerActionAuction, 20*time.Millisecond)
+	defer engine.Stop()
+
+	submitBreakerBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 5, UserID: 3, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 130, Qty: 3,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventAuctionUncrossed) != nil
+	})
+	if findEvent(events, EventCircuitBreaker) == nil {
+		t.Fatal("expected circuit breaker event")
+	}
+	uncrossed := findEvent(events, EventAuctionUncrossed).Data.(*AuctionUncrossedData)
+	if uncrossed.Price != 130 || uncrossed.Volume != 2 {
+		t.Fatalf("unexpected uncross: %+v", uncrossed)
+	}
+}
+
+func TestBreakerSnapshotKeepsReference(t *testing.T) {
+	engine := newBreakerEngine(BreakerActionAuction, time.Hour)
+	defer engine.Stop()
+
+	submitBreakerBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 5, UserID: 3, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 130, Qty: 3,
+	})
+	collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventCircuitBreaker) != nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	snap, err := engine.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if !snap.Auction || snap.BreakerID == 0 || len(snap.BandSamples) == 0 {
+		t.Fatalf("expected breaker state in snapshot: %+v", snap)
+	}
+
+	restored := NewEngine("BTCUSDT", 10000, 10000)
+	restored.SetBreaker(engine.breaker, nil)
+	if err := restored.Restore(snap); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	restored.Start()
+	defer restored.Stop()
+
+	submitOrFail(t, restored, &Command{Type: CmdResumeBreaker, Symbol: "BTCUSDT", BreakerID: snap.BreakerID})
+	events := collectUntil(t, restored, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventAuctionUncrossed) != nil
+	})
+	if uncrossed := findEvent(events, EventAuctionUncrossed).Data.(*AuctionUncrossedData); uncrossed.Volume != 2 {
+		t.Fatalf("unexpected uncross after restore: %+v", uncrossed)
+	}
+}
+
+func TestBreakerAuctionRearmsTriggeredStops(t *testing.T) {
+	engine := newBreakerEngine(BreakerActionAuction, time.Hour)
+	defer engine.Stop()
+
+	submitBreakerBook(t, engine)
+	for _, cmd := range []*Command{
+		{OrderID: 6, Qty: 2},
+		{OrderID: 7, Qty: 1},
+	} {
+		cmd.Type, cmd.UserID, cmd.Symbol = CmdNewOrder, 3, "BTCUSDT"
+		cmd.Side, cmd.OrderType, cmd.TimeInForce, cmd.StopPrice = orderbook.SideBuy, orderTypeStopLoss, 1, 105
+		submitOrFail(t, engine, cmd)
+	}
+	// 105 成交触发两笔止损市价单：第一笔扫到 130 引发熔断竞价，第二笔放回触发簿
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 8, UserID: 4, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 105, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventAuctionIndicative) != nil
+	})
+	tripped := findEvent(events, EventCircuitBreaker)
+	if tripped == nil || tripped.Data.(*CircuitBreakerData).OrderID != 6 {
+		t.Fatalf("expected breaker tripped by stop 6, got %+v", tripped)
+	}
+	for _, ev := range events {
+		if ev.Type == EventStopOrderTriggered && ev.Data.(*StopOrderTriggeredData).OrderID == 7 {
+			t.Fatal("stop 7 must not trigger during the auction")
+		}
+		if ev.Type == EventOrderRejected && ev.Data.(*OrderRejectedData).OrderID == 7 {
+			t.Fatalf("stop 7 must not be rejected: %+v", ev.Data)
+		}
+	}
+
+	// 竞价期间补充卖盘，无交叉结束竞价后按最新价 105 重新触发
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 9, UserID: 5, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 106, Qty: 3,
+	})
+	submitOrFail(t, engine, &Command{Type: CmdResumeBreaker, Symbol: "BTCUSDT", BreakerID: tripped.Data.(*CircuitBreakerData).BreakerID})
+
+	events = collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return orderFilled(ev, 7) })
+	uncrossed := findEvent(events, EventAuctionUncrossed)
+	triggered := findEvent(events, EventStopOrderTriggered)
+	if uncrossed == nil || triggered == nil || triggered.Seq < uncrossed.Seq || triggered.Data.(*StopOrderTriggeredData).OrderID != 7 {
+		t.Fatalf("expected stop 7 triggered after the uncross, got %+v", events)
+	}
+	trade := findEvent(events, EventTradeCreated).Data.(*TradeCreatedData)
+	if trade.TakerOrderID != 7 || trade.Price != 106 || trade.Qty != 1 {
+		t.Fatalf("unexpected trade: %+v", trade)
+	}
+}
@@ -18,20 +18,49 @@ type CommandType int
 const (
 	CmdNewOrder CommandType = iota + 1
 	CmdCancelOrder
+	CmdAmendOrder
+	CmdSetStatus
+	CmdResumeBreaker
+	CmdMassCancel
+	CmdExpireOrders
+	CmdMassQuote
 )
 
 // Command 撮合命令
 type Command struct {
-	Type          CommandType
-	OrderID       int64
-	ClientOrderID string
-	UserID        int64
-	Symbol        string
-	Side          orderbook.Side
-	OrderType     int // 1=LIMIT, 2=MARKET
-	TimeInForce   int // 1=GTC, 2=IOC, 3=FOK, 4=POST_ONLY
-	Price         int64
-	Qty           int64
+	Type            CommandType
+	OrderID         int64
+	ClientOrderID   string
+	UserID          int64
+	Symbol          string
+	Side            orderbook.Side
+	OrderType       int // 1=LIMIT, 2=MARKET, 3=STOP_LOSS, 4=STOP_LOSS_LIMIT, 5=TAKE_PROFIT, 6=TRAILING_STOP
+	TimeInForce     int // 1=GTC, 2=IOC, 3=FOK, 4=POST_ONLY
+	Price           int64
+	Qty             int64
+	QuoteOrderQty   int64             // 按金额市价买单的金额（Qty 为 0，需同时指定 QtyScale）
+	StopPrice       int64             // 条件单触发价（跟踪止损由引擎按成交价维护，未激活为 0）
+	TrailingDelta   int64             // 跟踪止损回调价差，与 TrailingBps 二选一
+	TrailingBps     int64             // 跟踪止损回调比例（基点）
+	ActivationPrice int64             // 跟踪止损激活价，0 表示接受后按最新价激活
+	TrailingExtreme int64             // 跟踪止损激活后的最优价（卖出为最高价，买入为最低价），0 表示未激活
+	ExpireTimeMs    int64             // GTD/DAY 到期时间（毫秒），0 表示不过期（按 GTC 挂单）
+	STPMode         orderbook.STPMode // 自成交防护模式，0 按 EXPIRE_TAKER 处理
+	AmendID         int64             // 改单请求 ID（CmdAmendOrder），Price/Qty 为 0 表示不修改
+	DisplayQty      int64             // 冰山单每次展示数量，0 表示非冰山单（仅 GTC/POST_ONLY 限价单生效）
+	StreamID        string            // 来源订单流消息 ID（快照据此记录重放起点）
+	Status          int               // 交易对状态（CmdSetStatus）：1=TRADING, 2=HALT, 3=CANCEL_ONLY, 4=AUCTION
+	BreakerID       int64             // 要结束的熔断竞价（CmdResumeBreaker）
+	RequestID       int64             // 批量撤单请求 ID（CmdMassCancel，按 UserID 与 Side 撤单，Side 为 0 表示双边）；做市报价请求 ID（CmdMassQuote）
+	QuoteID         int64             // 做市报价请求 ID（由 CmdMassQuote 生成的报价单），0 表示普通订单
+	ListID          int64             // 订单列表 ID（OCO），0 表示非列表订单
+	SiblingOrderID  int64             // 同列表的另一条订单：本订单成交或触发时由引擎在同一命令内撤销
+	Quotes          []QuoteEntry      // 新的报价单（CmdMassQuote，TimeInForce 与 STPMode 对整组生效）
+	BuyFrozen       int64             // 本次报价新冻结的 quote 资产（CmdMassQuote）
+	SellFrozen      int64             // 本次报价新冻结的 base 资产（CmdMassQuote）
+	QtyScale        int64             // 数量精度对应的倍数（成交额 price * qty / QtyScale：CmdMassQuote 核对冻结额，按金额市价买单扣减金额）
+
+	reply chan *Snapshot // cmdSnapshot 的结果通道
 }
 
 // Event 撮合事件
@@ -53,6 +82,18 @@ const (
 	EventTradeCreated
 	EventOrderFilled
 	EventOrderPartiallyFilled
+	EventStopOrderAccepted
+	EventStopOrderTriggered
+	EventOrderReduced
+	EventOrderAmended
+	EventAmendRejected
+	EventAuctionIndicative
+	EventAuctionUncrossed
+	EventCircuitBreaker
+	EventMassCanceled
+	EventMassQuoted
+	EventMassQuoteRejected
+	EventTrailingStopUpdated
 )
 
 // OrderAcceptedData 订单接受事件数据
@@ -63,6 +104,7 @@ type OrderAcceptedData struct {
 	Side          orderbook.Side
 	Price         int64
 	Qty           int64
+	VisibleQty    int64 // 冰山单当前展示数量（非冰山单为 0）
 }
 
 // OrderRejectedData 订单拒绝事件数据
@@ -109,6 +151,89 @@ type OrderPartiallyFilledData struct {
 	UserID        int64
 	ExecutedQty   int64
 	LeavesQty     int64
+	VisibleQty    int64 // 冰山单当前展示数量（非冰山单为 0）
+}
+
+// OrderReducedData 订单数量被扣减事件数据（STP DECREMENT）
+type OrderReducedData struct {
+	OrderID       int64
+	ClientOrderID string
+	UserID        int64
+	ReducedQty    int64 // 本次扣减数量
+	OrigQty       int64 // 扣减后的订单数量
+	LeavesQty     int64
+	VisibleQty    int64 // 冰山单当前展示数量（非冰山单为 0）
+	Reason        string
+}
+
+// OrderAmendedData 改单成功事件数据（在改单后的撮合事件之前发送）
+type OrderAmendedData struct {
+	OrderID       int64
+	ClientOrderID string
+	UserID        int64
+	AmendID       int64
+	Side          orderbook.Side
+	OldPrice      int64
+	Price         int64
+	OldQty        int64
+	OrigQty       int64 // 改单后的订单数量（含已成交）
+	LeavesQty     int64 // 改单后、撮合前的剩余数量
+	PriorityKept  bool  // 是否保留时间优先级（同价减量）
+	VisibleQty    int64 // 冰山单改单后的展示数量（非冰山单为 0）
+}
+
+// AmendRejectedData 改单拒绝事件数据（原订单保持不变）
+type AmendRejectedData struct {
+	OrderID       int64
+	ClientOrderID string
+	UserID        int64
+	AmendID       int64
+	Reason        string
+}
+
+// MassCanceledData 批量撤单事件数据（一次请求对应一个事件，Orders 可为空）
+type MassCanceledData struct {
+	RequestID int64
+	UserID    int64
+	Side      orderbook.Side // 0 表示双边
+	Orders    []CanceledOrder
+	Reason    string
+}
+
+// CanceledOrder 批量撤单中被撤销的订单
+type CanceledOrder struct {
+	OrderID       int64
+	ClientOrderID string
+	LeavesQty     int64
+}
+
+// StopOrderAcceptedData 条件单进入触发簿事件数据
+type StopOrderAcceptedData struct {
+	OrderID       int64
+	ClientOrderID string
+	UserID        int64
+	Side          orderbook.Side
+	Price         int64
+	Qty           int64
+	StopPrice     int64
+}
+
+// StopOrderTriggeredData 条件单触发事件数据
+type StopOrderTriggeredData struct {
+	OrderID       int64
+	ClientOrderID string
+	UserID        int64
+	StopPrice     int64
+	LastPrice     int64
+}
+
+// TrailingStopUpdatedData 跟踪止损触发价更新事件数据
+type TrailingStopUpdatedData struct {
+	OrderID       int64
+	ClientOrderID string
+	UserID        int64
+	StopPrice     int64
+	ExtremePrice  int64
 }
 
 // Engine 撮合引擎
@@ -116,11 +241,40 @@ type Engine struct {
 	symbol string
 	book   *orderbook.OrderBook
 
+	// 条件单触发簿与最新成交价（仅引擎 goroutine 访问）
+	triggers  *triggerBook
+	lastPrice int64
+
+	// 已处理的最后一条订单流消息 ID（仅引擎 goroutine 访问）
+	streamID string
+
+	// 集合竞价状态与最近一次发布的参考价（仅引擎 goroutine 访问）
+	auction    bool
+	indicative orderbook.Equilibrium
+
+	// 波动熔断：配置、参考价样本与当前熔断竞价（仅引擎 goroutine 访问）
+	breaker       BreakerConfig
+	resumeBreaker func(symbol string, breakerID int64)
+	bandSamples   []PriceSample
+	breakerID     int64 // 非 0 表示当前竞价由熔断触发
+	breakerTimer  *time.Timer
+
+	// GTD/DAY 到期堆（已撤销或成交订单的条目延迟删除）与到期请求定时器（仅引擎 goroutine 访问）
+	expiries        expiryHeap
+	expiryCompactAt int
+	requestExpiry   func(symbol string)
+	expiryAt        int64
+	expiryTimer     *time.Timer
+
 	cmdCh   chan *Command
 	eventCh chan *Event
 
-	seq int64
-	mu  sync.Mutex
+	// 时钟（事件与订单时间戳，仅引擎 goroutine 调用）
+	now func() time.Time
+
+	seq          int64
+	mutedThrough int64 // 序列号不大于该值的事件不发送（重放期间）
+	mu           sync.Mutex
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -130,17 +284,41 @@ type Engine struct {
 func NewEngine(symbol string, cmdBufferSize, eventBufferSize int) *Engine {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Engine{
-		symbol:  symbol,
-		book:    orderbook.NewOrderBook(symbol),
-		cmdCh:   make(chan *Command, cmdBufferSize),
-		eventCh: make(chan *Event, eventBufferSize),
-		ctx:     ctx,
-		cancel:  cancel,
+		symbol:   symbol,
+		book:     orderbook.NewOrderBook(symbol),
+		triggers: newTriggerBook(),
+		cmdCh:    make(chan *Command, cmdBufferSize),
+		eventCh:  make(chan *Event, eventBufferSize),
+		now:      time.Now,
+		ctx:      ctx,
+		cancel:   cancel,
+
+		expiryCompactAt: expiryCompactMin,
 	}
 }
 
-// Start 启动引擎
+// SetClock 设置引擎时钟（需在 Start 之前调用）
+//
+// 时钟只用于事件与订单的时间戳，以及订单流消息 ID 缺失时的引擎时间；
+// 撮合结果不依赖时钟，重放时注入确定性的时钟，同样的命令序列得到完全一致的事件。
+func (e *Engine) SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	e.now = now
+	e.book.SetClock(now)
+}
+
+// SetIDGenerator 设置成交 ID 生成器（需在 Start 之前调用，为空时使用 snowflake）
+func (e *Engine) SetIDGenerator(next func() int64) {
+	e.book.SetIDGenerator(next)
+}
+
+// Start 启动引擎（Restore、AddOrderDirect 恢复订单簿之后调用）
 func (e *Engine) Start() {
+	// 恢复的订单可能已到期
+	e.scheduleExpiry()
+	e.book.Publish()
 	go e.run()
 }
 
@@ -178,12 +356,34 @@ func (e *Engine) Done() <-chan struct{} {
 	return e.ctx.Done()
 }
 
-// Depth 获取深度
+// Depth 获取深度（读取引擎最近一次发布的快照，不阻塞撮合；最多 orderbook.PublishedLevels 档）
 func (e *Engine) Depth(limit int) (bids, asks []orderbook.PriceQty) {
-	return e.book.Depth(limit)
+	return e.book.PublishedDepth(limit)
+}
+
+// SetSeq 设置已分配的最后一个事件序列号（用于重启后延续序列号，需在提交任何命令之前调用）
+func (e *Engine) SetSeq(seq int64) {
+	e.mu.Lock()
+	e.seq = seq
+	e.mu.Unlock()
+}
+
+// SetLastPrice 设置最新成交价（用于从订单库恢复，需在 Start 之前调用）
+func (e *Engine) SetLastPrice(price int64) {
+	e.lastPrice = price
+}
+
+// Seq 返回已分配的最后一个事件序列号
+func (e *Engine) Seq() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.seq
 }
 
-// AddOrderDirect 直接添加订单到订单簿（用于恢复，不触发撮合）
+// cmdExpireRecovered 内部命令：撤销恢复时不能挂单的已触发订单剩余数量
+const cmdExpireRecovered CommandType = 101
+
+// AddOrderDirect 直接添加订单到订单簿（用于恢复，不触发撮合，需在 Start 之前调用）
 func (e *Engine) AddOrderDirect(order *types.OpenOrder) error {
 	if order == nil {
 		return nil
@@ -209,14 +409,21 @@ func (e *Engine) AddOrderDirect(order *types.OpenOrder) error {
 	}
 
 	orderType := strings.ToUpper(order.OrderType)
-	if orderType != "" && orderType != "LIMIT" {
-		// 恢复只支持挂单（LIMIT）；OPEN 状态的 MARKET/其他类型不应存在
+	switch orderType {
+	case "", "LIMIT":
+	case "STOP_LOSS", "STOP_LOSS_LIMIT", "TAKE_PROFIT", "TRAILING_STOP":
+		if order.Triggered && orderType != "STOP_LOSS_LIMIT" {
+			// 已触发的市价条件单不会挂单
+			return fmt.Errorf("unsupported triggered orderType for recovery: %s", order.OrderType)
+		}
+	default:
+		// 恢复只支持挂单（LIMIT）与未触发的条件单；OPEN 状态的 MARKET/其他类型不应存在
 		return fmt.Errorf("unsupported orderType for recovery: %s", order.OrderType)
 	}
 
 	var tif int
 	switch strings.ToUpper(order.TimeInForce) {
-	case "GTC", "":
+	case "GTC", "", "GTD", "DAY":
 		tif = 1
 	case "IOC":
 		tif = 2
@@ -228,22 +435,112 @@ func (e *Engine) AddOrderDirect(order *types.OpenOrder) error {
 		return fmt.Errorf("invalid timeInForce: %s", order.TimeInForce)
 	}
 
+	var stpMode orderbook.STPMode
+	switch strings.ToUpper(order.STPMode) {
+	case "EXPIRE_TAKER", "":
+		stpMode = orderbook.STPExpireTaker
+	case "EXPIRE_MAKER":
+		stpMode = orderbook.STPExpireMaker
+	case "EXPIRE_BOTH":
+		stpMode = orderbook.STPExpireBoth
+	case "DECREMENT":
+		stpMode = orderbook.STPDecrement
+	default:
+		return fmt.Errorf("invalid stpMode: %s", order.STPMode)
+	}
+
+	if isStopOrderType(stopOrderType(orderType)) && !order.Triggered {
+		stop := &Command{
+			Type:            CmdNewOrder,
+			OrderID:         order.OrderID,
+			ClientOrderID:   order.ClientOrderID,
+			UserID:          order.UserID,
+			Symbol:          order.Symbol,
+			Side:            side,
+			OrderType:       stopOrderType(orderType),
+			TimeInForce:     tif,
+			Price:           order.Price,
+			Qty:             order.LeavesQty,
+			StopPrice:       order.StopPrice,
+			ExpireTimeMs:    order.ExpireTimeMs,
+			STPMode:         stpMode,
+			DisplayQty:      order.DisplayQty,
+			TrailingDelta:   order.TrailingDelta,
+			TrailingBps:     order.TrailingBps,
+			ActivationPrice: order.ActivationPrice,
+			TrailingExtreme: order.TrailingExtreme,
+			ListID:          order.ListID,
+			SiblingOrderID:  order.SiblingOrderID,
+		}
+		if stop.OrderType == orderTypeTrailingStop {
+			if !validTrailing(stop) {
+				return fmt.Errorf("invalid trailing stop")
+			}
+		} else if order.StopPrice <= 0 {
+			return fmt.Errorf("invalid stopPrice")
+		}
+		e.triggers.Add(stop)
+		e.trackExpiry(order.OrderID, order.ExpireTimeMs)
+		return nil
+	}
+
+	if order.Triggered && (tif == 2 || tif == 3) {
+		// 已触发的 IOC/FOK 止损限价单不挂单：引擎启动后撤销剩余数量（重启前未处理完的撮合结果无法还原）
+		return e.Submit(&Command{
+			Type:          cmdExpireRecovered,
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			UserID:        order.UserID,
+			Symbol:        order.Symbol,
+			Qty:           order.LeavesQty,
+		})
+	}
+
+	// 快照未携带订单数量时按剩余数量处理
+	origQty := order.OrigQty
+	if origQty < order.LeavesQty {
+		origQty = order.LeavesQty
+	}
 	obOrder := &orderbook.Order{
-		OrderID:       order.OrderID,
-		UserID:        order.UserID,
-		ClientOrderID: order.ClientOrderID,
-		Symbol:        order.Symbol,
-		Side:          side,
-		Price:         order.Price,
-		OrigQty:       order.LeavesQty,
-		LeavesQty:     order.LeavesQty,
-		TimeInForce:   tif,
-		Timestamp:     order.CreatedAt,
+		OrderID:        order.OrderID,
+		UserID:         order.UserID,
+		ClientOrderID:  order.ClientOrderID,
+		Symbol:         order.Symbol,
+		Side:           side,
+		Price:          order.Price,
+		OrigQty:        origQty,
+		LeavesQty:      order.LeavesQty,
+		TimeInForce:    tif,
+		STPMode:        stpMode,
+		ExpireTimeMs:   order.ExpireTimeMs,
+		QuoteID:        order.QuoteID,
+		ListID:         order.ListID,
+		SiblingOrderID: order.SiblingOrderID,
+		Timestamp:      order.CreatedAt,
+	}
+	if order.DisplayQty > 0 && order.DisplayQty < order.LeavesQty {
+		obOrder.DisplayQty = order.DisplayQty
 	}
 	e.book.AddOrder(obOrder)
+	e.trackExpiry(obOrder.OrderID, obOrder.ExpireTimeMs)
 	return nil
 }
 
+func stopOrderType(orderType string) int {
+	switch orderType {
+	case "STOP_LOSS":
+		return orderTypeStopLoss
+	case "STOP_LOSS_LIMIT":
+		return orderTypeStopLossLimit
+	case "TAKE_PROFIT":
+		return orderTypeTakeProfit
+	case "TRAILING_STOP":
+		return orderTypeTrailingStop
+	default:
+		return 0
+	}
+}
+
 func (e *Engine) run() {
 	for {
 		select {
@@ -256,28 +553,196 @@ func (e *Engine) run() {
 }
 
 func (e *Engine) processCommand(cmd *Command) {
+	// pending 重试的旧消息可能晚于新消息处理，只前移不回退
+	if cmd.StreamID != "" && CompareStreamIDs(cmd.StreamID, e.streamID) > 0 {
+		e.streamID = cmd.StreamID
+	}
+	if cmd.Type == cmdSnapshot {
+		cmd.reply <- e.buildSnapshot()
+		return
+	}
+	// 先撤销到期订单：到期时间按订单流时钟判断，重放时得到相同的事件顺序
+	e.expireOrders(e.clockMs())
 	switch cmd.Type {
 	case CmdNewOrder:
-		e.processNewOrder(cmd)
+		if isStopOrderType(cmd.OrderType) {
+			e.processStopOrder(cmd)
+		} else {
+			e.processNewOrder(cmd)
+		}
 	case CmdCancelOrder:
 		e.processCancelOrder(cmd)
+	case CmdAmendOrder:
+		e.processAmendOrder(cmd)
+	case CmdSetStatus:
+		e.processSetStatus(cmd)
+	case CmdResumeBreaker:
+		e.processResumeBreaker(cmd)
+	case CmdMassCancel:
+		e.processMassCancel(cmd)
+	case CmdExpireOrders:
+		e.processExpireOrders()
+	case CmdMassQuote:
+		e.processMassQuote(cmd)
+	case cmdExpireRecovered:
+		e.emit(EventOrderCanceled, &OrderCanceledData{
+			OrderID:       cmd.OrderID,
+			ClientOrderID: cmd.ClientOrderID,
+			UserID:        cmd.UserID,
+			LeavesQty:     cmd.Qty,
+			Reason:        "IOC_EXPIRED",
+		})
+	}
+	if e.auction {
+		e.publishIndicative(false)
 	}
+	e.drainTriggers()
+	e.scheduleExpiry()
+	e.book.Publish()
 }
 
-func (e *Engine) processNewOrder(cmd *Command) {
-	now := time.Now().UnixNano()
+// processStopOrder 条件单进入触发簿
+func (e *Engine) processStopOrder(cmd *Command) {
+	trailing := cmd.OrderType == orderTypeTrailingStop
+	reason := ""
+	switch {
+	case e.auction:
+		reason = "AUCTION_ORDER_NOT_ALLOWED"
+	case trailing && !validTrailing(cmd):
+		reason = "INVALID_TRAILING_STOP"
+	case !trailing && cmd.StopPrice <= 0:
+		reason = "INVALID_STOP_PRICE"
+	case cmd.ExpireTimeMs > 0 && cmd.ExpireTimeMs <= e.clockMs():
+		reason = "EXPIRED"
+	case !trailing && stopTriggered(cmd, e.lastPrice):
+		reason = "STOP_WOULD_TRIGGER_IMMEDIATELY"
+	case cmd.ListID != 0 && !e.listSiblingIntact(cmd):
+		reason = "ORDER_LIST_DONE"
+	case e.triggers.Get(cmd.OrderID) != nil || e.book.GetOrder(cmd.OrderID) != nil:
+		reason = "DUPLICATE_ORDER"
+	}
+	if reason != "" {
+		e.emit(EventOrderRejected, &OrderRejectedData{
+			OrderID:       cmd.OrderID,
+			ClientOrderID: cmd.ClientOrderID,
+			UserID:        cmd.UserID,
+			Reason:        reason,
+		})
+		return
+	}
 
-	order := &orderbook.Order{
+	if trailing {
+		// 触发价由引擎维护，忽略请求中的值
+		cmd.StopPrice = 0
+		cmd.TrailingExtreme = 0
+	}
+	e.triggers.Add(cmd)
+	e.trackExpiry(cmd.OrderID, cmd.ExpireTimeMs)
+	e.emit(EventStopOrderAccepted, &StopOrderAcceptedData{
 		OrderID:       cmd.OrderID,
-		UserID:        cmd.UserID,
 		ClientOrderID: cmd.ClientOrderID,
-		Symbol:        cmd.Symbol,
+		UserID:        cmd.UserID,
 		Side:          cmd.Side,
 		Price:         cmd.Price,
-		OrigQty:       cmd.Qty,
-		LeavesQty:     cmd.Qty,
-		TimeInForce:   cmd.TimeInForce,
-		Timestamp:     now,
+		Qty:           cmd.Qty,
+		StopPrice:     cmd.StopPrice,
+	})
+	// 未指定激活价（或最新价已越过激活价）的跟踪止损按最新价立即激活
+	if trailing && trail(cmd, e.lastPrice) {
+		e.emitTrailingStopUpdated(cmd)
+	}
+}
+
+// trailStops 成交后更新跟踪止损的最优价与触发价
+func (e *Engine) trailStops(price int64) {
+	for _, stop := range e.triggers.Trail(price) {
+		e.emitTrailingStopUpdated(stop)
+	}
+}
+
+func (e *Engine) emitTrailingStopUpdated(stop *Command) {
+	e.emit(EventTrailingStopUpdated, &TrailingStopUpdatedData{
+		OrderID:       stop.OrderID,
+		ClientOrderID: stop.ClientOrderID,
+		UserID:        stop.UserID,
+		StopPrice:     stop.StopPrice,
+		ExtremePrice:  stop.TrailingExtreme,
+	})
+}
+
+// drainTriggers 按最新成交价触发条件单，触发后的成交可能继续触发其他条件单
+//
+// 集合竞价期间不触发：触发后的成交引发熔断竞价时，同批尚未执行的条件单按原顺序放回触发簿，
+// 竞价结束后按最新成交价重新判断（竞价期间市价单会被拒绝）。
+func (e *Engine) drainTriggers() {
+	for !e.auction {
+		triggered := e.triggers.PopTriggered(e.lastPrice)
+		if len(triggered) == 0 {
+			return
+		}
+		for i, stop := range triggered {
+			if e.auction {
+				for _, rest := range triggered[i:] {
+					e.triggers.Add(rest)
+				}
+				return
+			}
+			e.emit(EventStopOrderTriggered, &StopOrderTriggeredData{
+				OrderID:       stop.OrderID,
+				ClientOrderID: stop.ClientOrderID,
+				UserID:        stop.UserID,
+				StopPrice:     stop.StopPrice,
+				LastPrice:     e.lastPrice,
+			})
+			// 先撤销同列表订单，再执行触发后的订单
+			e.cancelListSibling(stop.ListID, stop.SiblingOrderID)
+			e.processNewOrder(triggeredCommand(stop))
+		}
+	}
+}
+
+func (e *Engine) processNewOrder(cmd *Command) {
+	// 集合竞价期间只接受 GTC 限价单
+	if e.auction && (cmd.OrderType != 1 || cmd.TimeInForce != 1) {
+		e.emit(EventOrderRejected, &OrderRejectedData{
+			OrderID:       cmd.OrderID,
+			ClientOrderID: cmd.ClientOrderID,
+			UserID:        cmd.UserID,
+			Reason:        "AUCTION_ORDER_NOT_ALLOWED",
+		})
+		return
+	}
+	if cmd.ExpireTimeMs > 0 && cmd.ExpireTimeMs <= e.clockMs() {
+		e.emit(EventOrderRejected, &OrderRejectedData{
+			OrderID:       cmd.OrderID,
+			ClientOrderID: cmd.ClientOrderID,
+			UserID:        cmd.UserID,
+			Reason:        "EXPIRED",
+		})
+		return
+	}
+
+	now := e.now().UnixNano()
+
+	order := &orderbook.Order{
+		OrderID:        cmd.OrderID,
+		UserID:         cmd.UserID,
+		ClientOrderID:  cmd.ClientOrderID,
+		Symbol:         cmd.Symbol,
+		Side:           cmd.Side,
+		Price:          cmd.Price,
+		OrigQty:        cmd.Qty,
+		LeavesQty:      cmd.Qty,
+		TimeInForce:    cmd.TimeInForce,
+		STPMode:        cmd.STPMode,
+		QuoteID:        cmd.QuoteID,
+		ListID:         cmd.ListID,
+		SiblingOrderID: cmd.SiblingOrderID,
+		Timestamp:      now,
+	}
+	// 到期时间仅对挂单生效
+	if cmd.OrderType == 1 && cmd.TimeInForce == 1 {
+		order.ExpireTimeMs = cmd.ExpireTimeMs
 	}
 
 	// 市价单价格设为 0
@@ -285,60 +750,90 @@ func (e *Engine) processNewOrder(cmd *Command) {
 		order.Price = 0
 	}
 
-	// POST_ONLY 检查
-	if cmd.TimeInForce == 4 {
-		if e.wouldMatch(order) {
+	// 按金额市价买单：数量上限为按最优卖价可买的数量，撮合时逐笔扣减金额
+	if cmd.OrderType == 2 && cmd.Side == orderbook.SideBuy && cmd.QuoteOrderQty > 0 {
+		if reason := e.applyQuoteBudget(order, cmd); reason != "" {
 			e.emit(EventOrderRejected, &OrderRejectedData{
 				OrderID:       cmd.OrderID,
 				ClientOrderID: cmd.ClientOrderID,
 				UserID:        cmd.UserID,
-				Reason:        "POST_ONLY_REJECTED",
+				Reason:        reason,
 			})
 			return
 		}
 	}
 
-	// 撮合
-	result := e.book.Match(order)
+	// 冰山单：仅对可挂单的限价单生效，展示数量不小于订单数量时按普通订单处理
+	if cmd.OrderType == 1 && (cmd.TimeInForce == 1 || cmd.TimeInForce == 4) &&
+		cmd.DisplayQty > 0 && cmd.DisplayQty < cmd.Qty {
+		order.DisplayQty = cmd.DisplayQty
+	}
 
-	// 发送成交事件
-	for _, trade := range result.Trades {
-		e.emit(EventTradeCreated, &TradeCreatedData{
-			TradeID:      trade.TradeID,
-			MakerOrderID: trade.MakerOrderID,
-			TakerOrderID: trade.TakerOrderID,
-			MakerUserID:  trade.MakerUserID,
-			TakerUserID:  trade.TakerUserID,
-			Price:        trade.Price,
-			Qty:          trade.Qty,
-			TakerSide:    trade.TakerSide,
-		})
+	// 集合竞价期间只挂单不撮合
+	if e.auction {
+		e.restOrder(order, false)
+		return
 	}
 
-	// 发送 maker 更新事件
-	for _, maker := range result.MakerUpdates {
-		if maker.LeavesQty <= 0 {
-			e.emit(EventOrderFilled, &OrderFilledData{
-				OrderID:       maker.OrderID,
-				ClientOrderID: maker.ClientOrderID,
-				UserID:        maker.UserID,
-				ExecutedQty:   maker.OrigQty,
+	// POST_ONLY 检查
+	if cmd.TimeInForce == 4 {
+		if e.wouldMatch(order) {
+			e.emit(EventOrderRejected, &OrderRejectedData{
+				OrderID:       cmd.OrderID,
+				ClientOrderID: cmd.ClientOrderID,
+				UserID:        cmd.UserID,
+				Reason:        "POST_ONLY_REJECTED",
 			})
-		} else {
-			e.emit(EventOrderPartiallyFilled, &OrderPartiallyFilledData{
-				OrderID:       maker.OrderID,
-				ClientOrderID: maker.ClientOrderID,
-				UserID:        maker.UserID,
-				ExecutedQty:   maker.OrigQty - maker.LeavesQty,
-				LeavesQty:     maker.LeavesQty,
+			return
+		}
+	}
+
+	// FOK 预检：无法立即完全成交则整单拒绝，不产生任何成交
+	if cmd.TimeInForce == 3 {
+		if reason := e.fokRejectReason(order); reason != "" {
+			e.emit(EventOrderRejected, &OrderRejectedData{
+				OrderID:       cmd.OrderID,
+				ClientOrderID: cmd.ClientOrderID,
+				UserID:        cmd.UserID,
+				Reason:        reason,
 			})
+			return
 		}
 	}
 
+	e.matchOrder(order, cmd.OrderType, false)
+}
+
+// matchOrder 撮合订单并发送成交、maker 与 taker 事件
+//
+// amended 表示已在簿订单改单后重新入簿：剩余数量直接挂单，不再发送 ORDER_ACCEPTED。
+func (e *Engine) matchOrder(order *orderbook.Order, orderType int, amended bool) {
+	tif := order.TimeInForce
+
+	// 撮合（启用价格带时只在带内成交）
+	band, banded := e.currentBand()
+	var result *orderbook.MatchResult
+	if banded {
+		result = e.book.MatchLimit(order, bandLimit(order, band))
+	} else {
+		result = e.book.Match(order)
+	}
+	stpReason := e.emitFills(order, result)
+
 	// 处理 taker
-	executedQty := order.OrigQty - order.LeavesQty
+	executedQty := order.OrigQty - order.LeavesQty - result.TakerExpiredQty
+	tradedNow := len(result.Trades) > 0
 
-	if result.TakerFilled {
+	if banded && !result.TakerExpired {
+		if breachPrice, breached := e.bandBreach(order, band); breached {
+			e.tripBreaker(order, orderType, result, executedQty, amended, band, breachPrice)
+			return
+		}
+	}
+
+	if result.TakerExpired {
+		e.expireTaker(order, result, executedQty, stpReason)
+	} else if result.TakerFilled {
 		// 完全成交
 		e.emit(EventOrderFilled, &OrderFilledData{
 			OrderID:       order.OrderID,
@@ -346,7 +841,7 @@ func (e *Engine) processNewOrder(cmd *Command) {
 			UserID:        order.UserID,
 			ExecutedQty:   executedQty,
 		})
-	} else if executedQty > 0 {
+	} else if tradedNow {
 		// 部分成交
 		e.emit(EventOrderPartiallyFilled, &OrderPartiallyFilledData{
 			OrderID:       order.OrderID,
@@ -354,10 +849,11 @@ func (e *Engine) processNewOrder(cmd *Command) {
 			UserID:        order.UserID,
 			ExecutedQty:   executedQty,
 			LeavesQty:     order.LeavesQty,
+			VisibleQty:    restingVisibleQty(order),
 		})
 
 		// IOC: 取消剩余
-		if cmd.TimeInForce == 2 {
+		if tif == 2 {
 			e.emit(EventOrderCanceled, &OrderCanceledData{
 				OrderID:       order.OrderID,
 				ClientOrderID: order.ClientOrderID,
@@ -365,50 +861,177 @@ func (e *Engine) processNewOrder(cmd *Command) {
 				LeavesQty:     order.LeavesQty,
 				Reason:        "IOC_EXPIRED",
 			})
-		} else if cmd.OrderType == 1 { // 限价单挂单
-			e.book.AddOrder(order)
-			e.emit(EventOrderAccepted, &OrderAcceptedData{
-				OrderID:       order.OrderID,
-				ClientOrderID: order.ClientOrderID,
-				UserID:        order.UserID,
-				Side:          order.Side,
-				Price:         order.Price,
-				Qty:           order.LeavesQty,
-			})
+		} else if orderType == 1 { // 限价单挂单
+			e.restOrder(order, amended)
 		}
 	} else {
 		// 无成交
-		if cmd.TimeInForce == 2 || cmd.TimeInForce == 3 { // IOC/FOK
+		if tif == 2 || tif == 3 { // IOC/FOK
 			e.emit(EventOrderRejected, &OrderRejectedData{
-				OrderID:       cmd.OrderID,
-				ClientOrderID: cmd.ClientOrderID,
-				UserID:        cmd.UserID,
-				Reason:        "NO_LIQUIDITY",
-			})
-		} else if cmd.OrderType == 1 { // 限价单挂单
-			e.book.AddOrder(order)
-			e.emit(EventOrderAccepted, &OrderAcceptedData{
 				OrderID:       order.OrderID,
 				ClientOrderID: order.ClientOrderID,
 				UserID:        order.UserID,
-				Side:          order.Side,
-				Price:         order.Price,
-				Qty:           order.LeavesQty,
+				Reason:        "NO_LIQUIDITY",
 			})
+		} else if orderType == 1 { // 限价单挂单
+			e.restOrder(order, amended)
 		} else { // 市价单无流动性
 			e.emit(EventOrderRejected, &OrderRejectedData{
-				OrderID:       cmd.OrderID,
-				ClientOrderID: cmd.ClientOrderID,
-				UserID:        cmd.UserID,
+				OrderID:       order.OrderID,
+				ClientOrderID: order.ClientOrderID,
+				UserID:        order.UserID,
 				Reason:        "NO_LIQUIDITY",
 			})
 		}
 	}
 }
 
+// applyQuoteBudget 设置按金额市价买单的金额与数量上限，返回拒绝原因
+func (e *Engine) applyQuoteBudget(order *orderbook.Order, cmd *Command) string {
+	if cmd.QtyScale <= 0 || order.STPMode == orderbook.STPDecrement {
+		return "INVALID_PARAM"
+	}
+	bestAsk, _, ok := e.book.BestAsk()
+	if !ok {
+		return "NO_LIQUIDITY"
+	}
+	// 卖盘价格只会越来越高，按最优卖价可买数量即为可成交数量上限
+	qty := orderbook.MulDiv(cmd.QuoteOrderQty, cmd.QtyScale, bestAsk)
+	if qty <= 0 {
+		return "NOTIONAL_TOO_SMALL"
+	}
+	order.OrigQty = qty
+	order.LeavesQty = qty
+	order.QuoteBudget = cmd.QuoteOrderQty
+	order.QtyScale = cmd.QtyScale
+	return ""
+}
+
+// emitFills 发送成交、maker 更新与自成交防护事件，返回 taker 的 STP 撤单原因
+//
+// 成交的列表订单在同一命令内撤销同列表的另一条订单。
+func (e *Engine) emitFills(order *orderbook.Order, result *orderbook.MatchResult) string {
+	// 发送成交事件
+	for _, trade := range result.Trades {
+		e.emit(EventTradeCreated, &TradeCreatedData{
+			TradeID:      trade.TradeID,
+			MakerOrderID: trade.MakerOrderID,
+			TakerOrderID: trade.TakerOrderID,
+			MakerUserID:  trade.MakerUserID,
+			TakerUserID:  trade.TakerUserID,
+			Price:        trade.Price,
+			Qty:          trade.Qty,
+			TakerSide:    trade.TakerSide,
+		})
+		e.lastPrice = trade.Price
+		e.recordTradePrice(trade.Price)
+		e.trailStops(trade.Price)
+	}
+
+	// 发送 maker 更新事件
+	for _, maker := range result.MakerUpdates {
+		if maker.LeavesQty <= 0 {
+			e.emit(EventOrderFilled, &OrderFilledData{
+				OrderID:       maker.OrderID,
+				ClientOrderID: maker.ClientOrderID,
+				UserID:        maker.UserID,
+				ExecutedQty:   maker.OrigQty,
+			})
+		} else {
+			e.emit(EventOrderPartiallyFilled, &OrderPartiallyFilledData{
+				OrderID:       maker.OrderID,
+				ClientOrderID: maker.ClientOrderID,
+				UserID:        maker.UserID,
+				ExecutedQty:   maker.OrigQty - maker.LeavesQty,
+				LeavesQty:     maker.LeavesQty,
+				VisibleQty:    visibleQty(maker),
+			})
+		}
+		e.cancelListSibling(maker.ListID, maker.SiblingOrderID)
+	}
+	if len(result.Trades) > 0 {
+		e.cancelListSibling(order.ListID, order.SiblingOrderID)
+	}
+
+	// 发送自成交防护事件
+	stpReason := stpCancelReason(order.STPMode)
+	e.emitSTPMakers(result.STPMakers, stpReason)
+	if result.TakerReducedQty > 0 {
+		e.emitReduced(order, result.TakerReducedQty, stpReason)
+	}
+	return stpReason
+}
+
+// expireTaker 自成交防护撤销 taker 剩余数量，不挂单，避免盘口交叉
+func (e *Engine) expireTaker(order *orderbook.Order, result *orderbook.MatchResult, executedQty int64, reason string) {
+	if len(result.Trades) > 0 {
+		e.emit(EventOrderPartiallyFilled, &OrderPartiallyFilledData{
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			UserID:        order.UserID,
+			ExecutedQty:   executedQty,
+			LeavesQty:     result.TakerExpiredQty,
+		})
+	}
+	e.emit(EventOrderCanceled, &OrderCanceledData{
+		OrderID:       order.OrderID,
+		ClientOrderID: order.ClientOrderID,
+		UserID:        order.UserID,
+		LeavesQty:     result.TakerExpiredQty,
+		Reason:        reason,
+	})
+}
+
+func (e *Engine) restOrder(order *orderbook.Order, amended bool) {
+	e.book.AddOrder(order)
+	if amended {
+		// 改单保留原到期时间，到期堆中的条目仍然有效
+		return
+	}
+	e.trackExpiry(order.OrderID, order.ExpireTimeMs)
+	e.emit(EventOrderAccepted, &OrderAcceptedData{
+		OrderID:       order.OrderID,
+		ClientOrderID: order.ClientOrderID,
+		UserID:        order.UserID,
+		Side:          order.Side,
+		Price:         order.Price,
+		Qty:           order.LeavesQty,
+		VisibleQty:    visibleQty(order),
+	})
+}
+
+// visibleQty 冰山单当前展示数量，非冰山单返回 0
+func visibleQty(order *orderbook.Order) int64 {
+	if !order.IsIceberg() {
+		return 0
+	}
+	return order.VisibleQty
+}
+
+// restingVisibleQty 冰山单（重新）入簿后的展示数量，非冰山单返回 0
+func restingVisibleQty(order *orderbook.Order) int64 {
+	if !order.IsIceberg() {
+		return 0
+	}
+	if order.DisplayQty < order.LeavesQty {
+		return order.DisplayQty
+	}
+	return order.LeavesQty
+}
+
 func (e *Engine) processCancelOrder(cmd *Command) {
 	order := e.book.RemoveOrder(cmd.OrderID)
 	if order == nil {
+		if stop := e.triggers.Remove(cmd.OrderID); stop != nil {
+			e.emit(EventOrderCanceled, &OrderCanceledData{
+				OrderID:       stop.OrderID,
+				ClientOrderID: stop.ClientOrderID,
+				UserID:        stop.UserID,
+				LeavesQty:     stop.Qty,
+				Reason:        "USER_CANCELED",
+			})
+			return
+		}
 		e.emit(EventOrderRejected, &OrderRejectedData{
 			OrderID:       cmd.OrderID,
 			ClientOrderID: cmd.ClientOrderID,
@@ -427,6 +1050,47 @@ func (e *Engine) processCancelOrder(cmd *Command) {
 	})
 }
 
+// fokRejectReason FOK 订单无法在价格带内完全成交时的拒绝原因
+func (e *Engine) fokRejectReason(order *orderbook.Order) string {
+	if !e.book.CanFill(order) {
+		return "NO_LIQUIDITY"
+	}
+	if band, ok := e.currentBand(); ok {
+		check := *order
+		check.Price = bandLimit(order, band)
+		if !e.book.CanFill(&check) {
+			return "PRICE_BAND_BREACHED"
+		}
+	}
+	return ""
+}
+
+// processMassCancel 撤销用户在本交易对的全部挂单与条件单，只发送一个批量事件
+func (e *Engine) processMassCancel(cmd *Command) {
+	data := &MassCanceledData{
+		RequestID: cmd.RequestID,
+		UserID:    cmd.UserID,
+		Side:      cmd.Side,
+		Orders:    make([]CanceledOrder, 0),
+		Reason:    "MASS_CANCELED",
+	}
+	for _, order := range e.book.RemoveUserOrders(cmd.UserID, cmd.Side) {
+		data.Orders = append(data.Orders, CanceledOrder{
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			LeavesQty:     order.LeavesQty,
+		})
+	}
+	for _, stop := range e.triggers.RemoveUser(cmd.UserID, cmd.Side) {
+		data.Orders = append(data.Orders, CanceledOrder{
+			OrderID:       stop.OrderID,
+			ClientOrderID: stop.ClientOrderID,
+			LeavesQty:     stop.Qty,
+		})
+	}
+	e.emit(EventMassCanceled, data)
+}
+
 func (e *Engine) wouldMatch(order *orderbook.Order) bool {
 	if order.Side == orderbook.SideBuy {
 		bestAsk, _, ok := e.book.BestAsk()
@@ -440,13 +1104,19 @@ func (e *Engine) emit(eventType EventType, data interface{}) {
 	e.mu.Lock()
 	e.seq++
 	seq := e.seq
+	muted := seq <= e.mutedThrough
 	e.mu.Unlock()
+	if muted {
+		return
+	}
+	// 先发布深度：收到事件的一方读到的深度不早于该事件
+	e.book.Publish()
 
 	event := &Event{
 		Type:      eventType,
 		Symbol:    e.symbol,
 		Seq:       seq,
-		Timestamp: time.Now().UnixNano(),
+		Timestamp: e.now().UnixNano(),
 		Data:      data,
 	}
 
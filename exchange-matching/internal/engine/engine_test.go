@@ -14,6 +14,24 @@ func TestCommandTypeConstants(t *testing.T) {
 	if CmdCancelOrder != 2 {
 		t.Fatalf("expected CmdCancelOrder=2, got %d", CmdCancelOrder)
 	}
+	if CmdAmendOrder != 3 {
+		t.Fatalf("expected CmdAmendOrder=3, got %d", CmdAmendOrder)
+	}
+	if CmdSetStatus != 4 {
+		t.Fatalf("expected CmdSetStatus=4, got %d", CmdSetStatus)
+	}
+	if CmdResumeBreaker != 5 {
+		t.Fatalf("expected CmdResumeBreaker=5, got %d", CmdResumeBreaker)
+	}
+	if CmdMassCancel != 6 {
+		t.Fatalf("expected CmdMassCancel=6, got %d", CmdMassCancel)
+	}
+	if CmdExpireOrders != 7 {
+		t.Fatalf("expected CmdExpireOrders=7, got %d", CmdExpireOrders)
+	}
+	if CmdMassQuote != 8 {
+		t.Fatalf("expected CmdMassQuote=8, got %d", CmdMassQuote)
+	}
 }
 
 func TestEventTypeConstants(t *testing.T) {
@@ -35,6 +53,39 @@ func TestEventTypeConstants(t *testing.T) {
 	if EventOrderPartiallyFilled != 6 {
 		t.Fatalf("expected EventOrderPartiallyFilled=6, got %d", EventOrderPartiallyFilled)
 	}
+	if EventStopOrderAccepted != 7 {
+		t.Fatalf("expected EventStopOrderAccepted=7, got %d", EventStopOrderAccepted)
+	}
+	if EventStopOrderTriggered != 8 {
+		t.Fatalf("expected EventStopOrderTriggered=8, got %d", EventStopOrderTriggered)
+	}
+	if EventOrderReduced != 9 {
+		t.Fatalf("expected EventOrderReduced=9, got %d", EventOrderReduced)
+	}
+	if EventOrderAmended != 10 {
+		t.Fatalf("expected EventOrderAmended=10, got %d", EventOrderAmended)
+	}
+	if EventAmendRejected != 11 {
+		t.Fatalf("expected EventAmendRejected=11, got %d", EventAmendRejected)
+	}
+	if EventAuctionIndicative != 12 {
+		t.Fatalf("expected EventAuctionIndicative=12, got %d", EventAuctionIndicative)
+	}
+	if EventAuctionUncrossed != 13 {
+		t.Fatalf("expected EventAuctionUncrossed=13, got %d", EventAuctionUncrossed)
+	}
+	if EventCircuitBreaker != 14 {
+		t.Fatalf("expected EventCircuitBreaker=14, got %d", EventCircuitBreaker)
+	}
+	if EventMassCanceled != 15 {
+		t.Fatalf("expected EventMassCanceled=15, got %d", EventMassCanceled)
+	}
+	if EventMassQuoted != 16 {
+		t.Fatalf("expected EventMassQuoted=16, got %d", EventMassQuoted)
+	}
+	if EventMassQuoteRejected != 17 {
+		t.Fatalf("expected EventMassQuoteRejected=17, got %d", EventMassQuoteRejected)
+	}
 }
 
 func TestCommandStruct(t *testing.T) {
@@ -493,6 +544,106 @@ func TestMarketOrderMatch(t *testing.T) {
 	}
 }
 
+func TestMarketBuyByQuoteOrderQty(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	for i, level := range []struct{ price, qty int64 }{{100, 30}, {101, 50}} {
+		submitOrFail(t, engine, &Command{
+			Type:        CmdNewOrder,
+			OrderID:     int64(i + 1),
+			UserID:      10,
+			Symbol:      "BTCUSDT",
+			Side:        orderbook.SideSell,
+			OrderType:   1,
+			TimeInForce: 1,
+			Price:       level.price,
+			Qty:         level.qty,
+		})
+	}
+
+	// 金额 500（数量精度 10）：100 价位买 30 花费 300，101 价位买 19 花费 191，剩余 9 不足 1 个单位
+	submitOrFail(t, engine, &Command{
+		Type:          CmdNewOrder,
+		OrderID:       3,
+		UserID:        20,
+		Symbol:        "BTCUSDT",
+		Side:          orderbook.SideBuy,
+		OrderType:     2,
+		TimeInForce:   2,
+		QuoteOrderQty: 500,
+		QtyScale:      10,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return len(ev) >= 7
+	})
+	trade1 := events[2].Data.(*TradeCreatedData)
+	trade2 := events[3].Data.(*TradeCreatedData)
+	if trade1.Price != 100 || trade1.Qty != 30 || trade2.Price != 101 || trade2.Qty != 19 {
+		t.Fatalf("unexpected trades %+v / %+v", trade1, trade2)
+	}
+	if events[6].Type != EventOrderFilled {
+		t.Fatalf("expected taker filled, got %v", events[6].Type)
+	}
+	if filled := events[6].Data.(*OrderFilledData); filled.OrderID != 3 || filled.ExecutedQty != 49 {
+		t.Fatalf("unexpected taker fill %+v", filled)
+	}
+	if _, qty, _ := engine.book.BestAsk(); qty != 31 {
+		t.Fatalf("expected 31 left at best ask, got %d", qty)
+	}
+}
+
+func TestMarketBuyByQuoteOrderQtyRejected(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type:          CmdNewOrder,
+		OrderID:       1,
+		UserID:        20,
+		Symbol:        "BTCUSDT",
+		Side:          orderbook.SideBuy,
+		OrderType:     2,
+		TimeInForce:   2,
+		QuoteOrderQty: 500,
+		QtyScale:      10,
+	})
+	submitOrFail(t, engine, &Command{
+		Type:        CmdNewOrder,
+		OrderID:     2,
+		UserID:      10,
+		Symbol:      "BTCUSDT",
+		Side:        orderbook.SideSell,
+		OrderType:   1,
+		TimeInForce: 1,
+		Price:       1000,
+		Qty:         30,
+	})
+	// 金额不足以按最优卖价买入 1 个单位
+	submitOrFail(t, engine, &Command{
+		Type:          CmdNewOrder,
+		OrderID:       3,
+		UserID:        20,
+		Symbol:        "BTCUSDT",
+		Side:          orderbook.SideBuy,
+		OrderType:     2,
+		TimeInForce:   2,
+		QuoteOrderQty: 50,
+		QtyScale:      10,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return len(ev) >= 3
+	})
+	if events[0].Type != EventOrderRejected || events[0].Data.(*OrderRejectedData).Reason != "NO_LIQUIDITY" {
+		t.Fatalf("expected NO_LIQUIDITY reject, got %+v", events[0])
+	}
+	if events[2].Type != EventOrderRejected || events[2].Data.(*OrderRejectedData).Reason != "NOTIONAL_TOO_SMALL" {
+		t.Fatalf("expected NOTIONAL_TOO_SMALL reject, got %+v", events[2])
+	}
+}
+
 func TestSelfTradeProtection(t *testing.T) {
 	engine := newTestEngine()
 	defer engine.Stop()
@@ -531,22 +682,23 @@ func TestSelfTradeProtection(t *testing.T) {
 		Qty:         5,
 	})
 
+	// 默认 EXPIRE_TAKER：taker 遇到自己的挂单即撤销剩余，不越过自成交档位成交
 	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
-		return len(ev) >= 5
+		return len(ev) >= 3
 	})
-	if events[2].Type != EventTradeCreated {
-		t.Fatalf("expected trade event, got %v", events[2].Type)
+	if events[2].Type != EventOrderCanceled {
+		t.Fatalf("expected cancel event, got %v", events[2].Type)
 	}
-	trade := events[2].Data.(*TradeCreatedData)
-	if trade.MakerUserID != 11 || trade.TakerUserID != 10 {
-		t.Fatalf("unexpected trade users maker=%d taker=%d", trade.MakerUserID, trade.TakerUserID)
+	canceled := events[2].Data.(*OrderCanceledData)
+	if canceled.OrderID != 3 || canceled.LeavesQty != 5 || canceled.Reason != "STP_EXPIRE_TAKER" {
+		t.Fatalf("unexpected cancel: %+v", canceled)
 	}
 
 	bids, asks := engine.Depth(1)
 	if len(bids) != 0 || len(asks) != 1 {
 		t.Fatalf("expected only asks to have depth, bids=%d asks=%d", len(bids), len(asks))
 	}
-	if asks[0].Qty != 10 {
+	if asks[0].Qty != 15 {
 		t.Fatalf("unexpected ask quantity=%d", asks[0].Qty)
 	}
 }
@@ -821,6 +973,66 @@ func TestFOKNoLiquidity(t *testing.T) {
 	}
 }
 
+func TestFOKPartialLiquidityRejected(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 12, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 3, Price: 100, Qty: 16,
+	})
+	// 同一用户的 maker 在前，默认 EXPIRE_TAKER 下无法完全成交
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 4, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 2, TimeInForce: 3, Qty: 5,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return len(ev) >= 4
+	})
+	for i, ev := range events[2:] {
+		if ev.Type != EventOrderRejected {
+			t.Fatalf("event %d: expected single rejection, got %v", i, ev.Type)
+		}
+		if data := ev.Data.(*OrderRejectedData); data.Reason != "NO_LIQUIDITY" {
+			t.Fatalf("unexpected reject reason=%s", data.Reason)
+		}
+	}
+	_, asks := engine.Depth(1)
+	if len(asks) != 1 || asks[0].Qty != 15 {
+		t.Fatalf("book should be untouched: %+v", asks)
+	}
+}
+
+func TestFOKFillsCompletely(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 3, Price: 100, Qty: 5,
+		STPMode: orderbook.STPExpireMaker,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		filled := findEvent(ev, EventOrderFilled)
+		return filled != nil && len(ev) >= 6
+	})
+	if findEvent(events, EventOrderRejected) != nil {
+		t.Fatal("unexpected rejection")
+	}
+	trade := findEvent(events, EventTradeCreated).Data.(*TradeCreatedData)
+	if trade.MakerOrderID != 2 || trade.Qty != 5 {
+		t.Fatalf("unexpected trade: %+v", trade)
+	}
+	last := events[len(events)-1]
+	if last.Type != EventOrderFilled || last.Data.(*OrderFilledData).OrderID != 3 {
+		t.Fatalf("expected taker filled, got %v %+v", last.Type, last.Data)
+	}
+}
+
 func TestMarketOrderNoLiquidity(t *testing.T) {
 	engine := newTestEngine()
 	defer engine.Stop()
@@ -849,3 +1061,59 @@ func TestMarketOrderNoLiquidity(t *testing.T) {
 		t.Fatalf("unexpected reject reason=%s", data.Reason)
 	}
 }
+
+func TestSetSeqContinuesSequence(t *testing.T) {
+	engine := NewEngine("BTCUSDT", 100, 100)
+	engine.SetSeq(41)
+	engine.Start()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1,
+	})
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return len(ev) >= 1 })
+	if events[0].Seq != 42 || engine.Seq() != 42 {
+		t.Fatalf("expected seq to continue from 41, got event=%d engine=%d", events[0].Seq, engine.Seq())
+	}
+}
+
+func TestInjectedClockAndTradeIDs(t *testing.T) {
+	engine := NewEngine("BTCUSDT", 100, 100)
+	clock := time.UnixMilli(1700000000000)
+	engine.SetClock(func() time.Time { return clock })
+	nextID := int64(500)
+	engine.SetIDGenerator(func() int64 {
+		nextID++
+		return nextID
+	})
+	engine.Start()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 2,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 30, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1,
+	})
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return len(ev) >= 7 })
+
+	var tradeIDs []int64
+	for _, event := range events {
+		if event.Timestamp != clock.UnixNano() {
+			t.Fatalf("expected injected clock timestamp, got %d", event.Timestamp)
+		}
+		if trade, ok := event.Data.(*TradeCreatedData); ok {
+			tradeIDs = append(tradeIDs, trade.TradeID)
+		}
+	}
+	if len(tradeIDs) != 2 || tradeIDs[0] != 501 || tradeIDs[1] != 502 {
+		t.Fatalf("expected injected trade ids [501 502], got %v", tradeIDs)
+	}
+}
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"container/heap"
+	"time"
+)
+
+const (
+	// expiryRetryDelay 到期请求未能过期任何订单时（订单流时钟落后于本机）的重试间隔
+	expiryRetryDelay = 50 * time.Millisecond
+	// expiryCompactMin 到期堆压缩阈值下限（已撤销/成交订单的条目延迟删除）
+	expiryCompactMin = 1024
+)
+
+// expiryEntry 到期堆条目
+type expiryEntry struct {
+	atMs    int64
+	orderID int64
+}
+
+// expiryHeap 按到期时间排序的小顶堆，同一时间按订单 ID 排序
+type expiryHeap []expiryEntry
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool {
+	if h[i].atMs != h[j].atMs {
+		return h[i].atMs < h[j].atMs
+	}
+	return h[i].orderID < h[j].orderID
+}
+
+func (h expiryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x interface{}) { *h = append(*h, x.(expiryEntry)) }
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	*h = old[:n-1]
+	return entry
+}
+
+// SetExpiryRequester 设置到期请求回调（需在 Start 之前调用）
+//
+// request 在最早的 GTD/DAY 订单到期时调用，由调用方通过订单流提交 CmdExpireOrders，
+// 使到期撤单的时机与订单流顺序一致；为空时引擎直接提交给自身。
+func (e *Engine) SetExpiryRequester(request func(symbol string)) {
+	e.requestExpiry = request
+}
+
+// trackExpiry 记录挂单或条件单的到期时间
+func (e *Engine) trackExpiry(orderID, expireTimeMs int64) {
+	if expireTimeMs <= 0 {
+		return
+	}
+	heap.Push(&e.expiries, expiryEntry{atMs: expireTimeMs, orderID: orderID})
+	if len(e.expiries) > e.expiryCompactAt {
+		e.compactExpiries()
+	}
+}
+
+// expiryLive 条目对应的订单仍在簿且到期时间未变
+func (e *Engine) expiryLive(entry expiryEntry) bool {
+	if order := e.book.GetOrder(entry.orderID); order != nil {
+		return order.ExpireTimeMs == entry.atMs
+	}
+	if stop := e.triggers.Get(entry.orderID); stop != nil {
+		return stop.ExpireTimeMs == entry.atMs
+	}
+	return false
+}
+
+// compactExpiries 删除已失效的条目
+func (e *Engine) compactExpiries() {
+	live := e.expiries[:0]
+	for _, entry := range e.expiries {
+		if e.expiryLive(entry) {
+			live = append(live, entry)
+		}
+	}
+	e.expiries = live
+	heap.Init(&e.expiries)
+	e.expiryCompactAt = max(2*len(e.expiries), expiryCompactMin)
+}
+
+// expireOrders 撤销到期时间不晚于 nowMs 的挂单与条件单（按到期时间、订单 ID 顺序）
+func (e *Engine) expireOrders(nowMs int64) {
+	for len(e.expiries) > 0 && e.expiries[0].atMs <= nowMs {
+		entry := heap.Pop(&e.expiries).(expiryEntry)
+		if !e.expiryLive(entry) {
+			continue
+		}
+		if order := e.book.RemoveOrder(entry.orderID); order != nil {
+			e.emit(EventOrderCanceled, &OrderCanceledData{
+				OrderID:       order.OrderID,
+				ClientOrderID: order.ClientOrderID,
+				UserID:        order.UserID,
+				LeavesQty:     order.LeavesQty,
+				Reason:        "EXPIRED",
+			})
+			continue
+		}
+		if stop := e.triggers.Remove(entry.orderID); stop != nil {
+			e.emit(EventOrderCanceled, &OrderCanceledData{
+				OrderID:       stop.OrderID,
+				ClientOrderID: stop.ClientOrderID,
+				UserID:        stop.UserID,
+				LeavesQty:     stop.Qty,
+				Reason:        "EXPIRED",
+			})
+		}
+	}
+}
+
+// nextExpiry 最早的有效到期时间
+func (e *Engine) nextExpiry() (int64, bool) {
+	for len(e.expiries) > 0 {
+		if e.expiryLive(e.expiries[0]) {
+			return e.expiries[0].atMs, true
+		}
+		heap.Pop(&e.expiries)
+	}
+	return 0, false
+}
+
+// scheduleExpiry 在最早的订单到期时请求撤单，目标不变时保留已有定时器
+func (e *Engine) scheduleExpiry() {
+	atMs, ok := e.nextExpiry()
+	if !ok {
+		e.stopExpiryTimer()
+		return
+	}
+	if atMs == e.expiryAt && e.expiryTimer != nil {
+		return
+	}
+	e.armExpiryTimer(atMs, max(time.Until(time.UnixMilli(atMs)), 0))
+}
+
+func (e *Engine) armExpiryTimer(atMs int64, delay time.Duration) {
+	e.stopExpiryTimer()
+	e.expiryAt = atMs
+	e.expiryTimer = time.AfterFunc(delay, func() {
+		if e.ctx.Err() != nil {
+			return
+		}
+		if e.requestExpiry != nil {
+			e.requestExpiry(e.symbol)
+			return
+		}
+		e.Submit(&Command{Type: CmdExpireOrders, Symbol: e.symbol})
+	})
+}
+
+func (e *Engine) stopExpiryTimer() {
+	if e.expiryTimer != nil {
+		e.expiryTimer.Stop()
+		e.expiryTimer = nil
+	}
+	e.expiryAt = 0
+}
+
+// processExpireOrders 到期请求：到期撤单已在处理命令前完成，这里只重新安排定时器
+//
+// 订单流时钟落后于本机时可能没有订单到期，延迟 expiryRetryDelay 后再次请求。
+func (e *Engine) processExpireOrders() {
+	atMs, ok := e.nextExpiry()
+	if !ok || atMs != e.expiryAt {
+		return
+	}
+	e.armExpiryTimer(atMs, max(time.Until(time.UnixMilli(atMs)), expiryRetryDelay))
+}
@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+	"github.com/exchange/matching/internal/types"
+)
+
+// newExpiryEngine 到期请求不做处理，到期时间完全由订单流消息 ID 决定
+func newExpiryEngine() *Engine {
+	engine := NewEngine("BTCUSDT", 10000, 10000)
+	engine.SetExpiryRequester(func(string) {})
+	engine.Start()
+	return engine
+}
+
+func TestGTDOrdersExpireOnStreamClock(t *testing.T) {
+	engine := newExpiryEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Side: orderbook.SideSell,
+		OrderType: 1, TimeInForce: 1, Price: 100, Qty: 5, ExpireTimeMs: 2000, StreamID: "1000-0",
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 1, Symbol: "BTCUSDT", Side: orderbook.SideBuy,
+		OrderType: orderTypeStopLossLimit, TimeInForce: 1, Price: 210, Qty: 2, StopPrice: 200,
+		ExpireTimeMs: 3000, StreamID: "1000-1",
+	})
+	// 部分成交后剩余数量到期
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 2, Symbol: "BTCUSDT", Side: orderbook.SideBuy,
+		OrderType: 1, TimeInForce: 1, Price: 100, Qty: 2, StreamID: "1500-0",
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 4, UserID: 2, Symbol: "BTCUSDT", Side: orderbook.SideBuy,
+		OrderType: 1, TimeInForce: 1, Price: 90, Qty: 1, StreamID: "2000-0",
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		accepted := 0
+		for _, e := range ev {
+			if e.Type == EventOrderAccepted && e.Data.(*OrderAcceptedData).OrderID == 4 {
+				accepted++
+			}
+		}
+		return accepted == 1
+	})
+	canceled := findEvent(events, EventOrderCanceled)
+	if canceled == nil {
+		t.Fatal("expected GTD order expired")
+	}
+	if data := canceled.Data.(*OrderCanceledData); data.OrderID != 1 || data.LeavesQty != 3 || data.Reason != "EXPIRED" {
+		t.Fatalf("unexpected expiry: %+v", data)
+	}
+	if canceled != events[len(events)-2] {
+		t.Fatal("expected expiry emitted before the order that advanced the clock")
+	}
+	if _, _, ok := engine.book.BestAsk(); ok {
+		t.Fatal("expected expired order removed from book")
+	}
+
+	// 条件单在触发前到期
+	submitOrFail(t, engine, &Command{Type: CmdExpireOrders, Symbol: "BTCUSDT", StreamID: "3000-0"})
+	events = collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderCanceled) != nil
+	})
+	if data := findEvent(events, EventOrderCanceled).Data.(*OrderCanceledData); data.OrderID != 2 || data.LeavesQty != 2 || data.Reason != "EXPIRED" {
+		t.Fatalf("unexpected stop expiry: %+v", data)
+	}
+	if engine.triggers.Len() != 0 {
+		t.Fatal("expected expired stop removed from trigger book")
+	}
+
+	// 到达时已过期的订单直接拒绝
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 5, UserID: 1, Symbol: "BTCUSDT", Side: orderbook.SideSell,
+		OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1, ExpireTimeMs: 2500, StreamID: "3000-1",
+	})
+	events = collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderRejected) != nil
+	})
+	if data := findEvent(events, EventOrderRejected).Data.(*OrderRejectedData); data.OrderID != 5 || data.Reason != "EXPIRED" {
+		t.Fatalf("unexpected reject: %+v", data)
+	}
+}
+
+func TestExpiryTimerRequestsExpire(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Side: orderbook.SideBuy,
+		OrderType: 1, TimeInForce: 1, Price: 100, Qty: 5,
+		ExpireTimeMs: time.Now().Add(30 * time.Millisecond).UnixMilli(),
+	})
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderCanceled) != nil
+	})
+	if data := findEvent(events, EventOrderCanceled).Data.(*OrderCanceledData); data.OrderID != 1 || data.Reason != "EXPIRED" {
+		t.Fatalf("unexpected expiry: %+v", data)
+	}
+}
+
+func TestExpirySurvivesSnapshotAndAmend(t *testing.T) {
+	engine := newExpiryEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Side: orderbook.SideBuy,
+		OrderType: 1, TimeInForce: 1, Price: 100, Qty: 5, ExpireTimeMs: 5000, StreamID: "1000-0",
+	})
+	// 改价重新入簿保留到期时间
+	submitOrFail(t, engine, &Command{Type: CmdAmendOrder, OrderID: 1, UserID: 1, AmendID: 1, Price: 101, StreamID: "1000-1"})
+	collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderAmended) != nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	snap, err := engine.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if len(snap.Orders) != 1 || snap.Orders[0].ExpireTimeMs != 5000 {
+		t.Fatalf("expected expiry in snapshot: %+v", snap.Orders)
+	}
+
+	restored := NewEngine("BTCUSDT", 10000, 10000)
+	restored.SetExpiryRequester(func(string) {})
+	if err := restored.Restore(snap); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	restored.Start()
+	defer restored.Stop()
+
+	submitOrFail(t, restored, &Command{Type: CmdExpireOrders, Symbol: "BTCUSDT", StreamID: "5000-0"})
+	events := collectUntil(t, restored, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderCanceled) != nil
+	})
+	if data := findEvent(events, EventOrderCanceled).Data.(*OrderCanceledData); data.OrderID != 1 || data.Reason != "EXPIRED" {
+		t.Fatalf("unexpected expiry after restore: %+v", data)
+	}
+}
+
+func TestRecoveredExpiredOrdersExpireWithoutCommands(t *testing.T) {
+	now := time.Now()
+	expired := now.Add(-time.Minute).UnixMilli()
+	recover := map[string]func(*Engine) error{
+		"database": func(engine *Engine) error {
+			return engine.AddOrderDirect(&types.OpenOrder{
+				OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Side: "BUY", OrderType: "LIMIT",
+				TimeInForce: "GTD", Price: 100, OrigQty: 5, LeavesQty: 5, ExpireTimeMs: expired,
+			})
+		},
+		"snapshot": func(engine *Engine) error {
+			return engine.Restore(&Snapshot{
+				Version: SnapshotVersion, Symbol: "BTCUSDT", StreamID: fmt.Sprintf("%d-0", now.UnixMilli()),
+				Orders: []orderbook.Order{{
+					OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Side: orderbook.SideBuy, Price: 100,
+					OrigQty: 5, LeavesQty: 5, TimeInForce: 1, ExpireTimeMs: expired,
+				}},
+			})
+		},
+	}
+	for name, restore := range recover {
+		// 空闲交易对：恢复后没有任何命令，到期定时器仍需撤销订单
+		engine := NewEngine("BTCUSDT", 10000, 10000)
+		if err := restore(engine); err != nil {
+			t.Fatalf("%s: recover: %v", name, err)
+		}
+		engine.Start()
+
+		events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+			return findEvent(ev, EventOrderCanceled) != nil
+		})
+		if data := findEvent(events, EventOrderCanceled).Data.(*OrderCanceledData); data.OrderID != 1 || data.Reason != "EXPIRED" {
+			t.Fatalf("%s: unexpected expiry: %+v", name, data)
+		}
+		engine.Stop()
+	}
+}
+
+func TestExpiryHeapCompaction(t *testing.T) {
+	engine := NewEngine("BTCUSDT", 10000, 10000)
+	for i := int64(1); i <= expiryCompactMin+1; i++ {
+		engine.trackExpiry(i, 1000+i)
+	}
+	if len(engine.expiries) != 0 {
+		t.Fatalf("expected entries of orders not in book compacted, got %d", len(engine.expiries))
+	}
+	if _, ok := engine.nextExpiry(); ok {
+		t.Fatal("expected no pending expiry")
+	}
+}
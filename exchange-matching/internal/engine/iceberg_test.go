@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+func TestIcebergOrderShowsDisplayQty(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 30, DisplayQty: 10,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 11, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 12,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderFilled) != nil
+	})
+
+	accepted := findEvent(events, EventOrderAccepted).Data.(*OrderAcceptedData)
+	if accepted.Qty != 30 || accepted.VisibleQty != 10 {
+		t.Fatalf("unexpected accepted data: %+v", accepted)
+	}
+	trades := 0
+	for _, ev := range events {
+		if ev.Type == EventTradeCreated {
+			trades++
+		}
+	}
+	if trades != 2 {
+		t.Fatalf("expected 2 trades (slice + replenished slice), got %d", trades)
+	}
+	partial := findEvent(events, EventOrderPartiallyFilled).Data.(*OrderPartiallyFilledData)
+	if partial.OrderID != 1 || partial.LeavesQty != 18 || partial.VisibleQty != 8 {
+		t.Fatalf("unexpected maker update: %+v", partial)
+	}
+	_, asks := engine.Depth(1)
+	if len(asks) != 1 || asks[0].Qty != 8 {
+		t.Fatalf("expected only visible qty in depth, got %+v", asks)
+	}
+}
+
+func TestIcebergIgnoredForIOC(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 5,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 11, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 2, Price: 100, Qty: 8, DisplayQty: 2,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderCanceled) != nil
+	})
+	trade := findEvent(events, EventTradeCreated).Data.(*TradeCreatedData)
+	if trade.Qty != 5 {
+		t.Fatalf("expected IOC taker to match full qty, got %+v", trade)
+	}
+	if partial := findEvent(events, EventOrderPartiallyFilled).Data.(*OrderPartiallyFilledData); partial.VisibleQty != 0 {
+		t.Fatalf("expected non-iceberg partial fill, got %+v", partial)
+	}
+}
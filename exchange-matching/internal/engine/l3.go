@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+// L3Order 逐笔挂单（不含用户信息，冰山单仅展示可见数量）
+type L3Order struct {
+	OrderID int64 `json:"orderId"`
+	Price   int64 `json:"price"`
+	Qty     int64 `json:"qty"`
+}
+
+// L3Book 逐笔订单簿快照，同一价位按时间优先级排列
+//
+// Seq 为生成快照时已分配的最后一个事件序列号：应用 seq 大于 Seq 的 L3 增量即可与推送保持一致。
+type L3Book struct {
+	Symbol string    `json:"symbol"`
+	Seq    int64     `json:"seq"`
+	Bids   []L3Order `json:"bids"`
+	Asks   []L3Order `json:"asks"`
+}
+
+// BookL3 生成逐笔订单簿快照（排在已提交的命令之后执行）
+func (e *Engine) BookL3(ctx context.Context) (*L3Book, error) {
+	snap, err := e.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	book := &L3Book{
+		Symbol: e.symbol,
+		Seq:    snap.Seq,
+		Bids:   make([]L3Order, 0),
+		Asks:   make([]L3Order, 0),
+	}
+	for i := range snap.Orders {
+		order := &snap.Orders[i]
+		l3 := L3Order{OrderID: order.OrderID, Price: order.Price, Qty: order.LeavesQty}
+		if order.IsIceberg() {
+			l3.Qty = order.VisibleQty
+		}
+		if order.Side == orderbook.SideBuy {
+			book.Bids = append(book.Bids, l3)
+		} else {
+			book.Asks = append(book.Asks, l3)
+		}
+	}
+	return book, nil
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+func TestMassCancelBySide(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	orders := []*Command{
+		{OrderID: 1, UserID: 10, Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 99, Qty: 5},
+		{OrderID: 2, UserID: 10, Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 101, Qty: 5},
+		{OrderID: 3, UserID: 20, Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 99, Qty: 5},
+		{OrderID: 4, UserID: 10, Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 98, Qty: 3},
+		{OrderID: 5, UserID: 10, Side: orderbook.SideBuy, OrderType: orderTypeStopLossLimit, TimeInForce: 1, Price: 120, Qty: 2, StopPrice: 110},
+	}
+	for _, cmd := range orders {
+		cmd.Type = CmdNewOrder
+		cmd.Symbol = "BTCUSDT"
+		submitOrFail(t, engine, cmd)
+	}
+	submitOrFail(t, engine, &Command{Type: CmdMassCancel, Symbol: "BTCUSDT", UserID: 10, Side: orderbook.SideBuy, RequestID: 7})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventMassCanceled) != nil
+	})
+	if findEvent(events, EventOrderCanceled) != nil {
+		t.Fatal("expected a single batch event instead of per-order cancels")
+	}
+	data := findEvent(events, EventMassCanceled).Data.(*MassCanceledData)
+	if data.RequestID != 7 || data.UserID != 10 || data.Reason != "MASS_CANCELED" {
+		t.Fatalf("unexpected mass cancel: %+v", data)
+	}
+	if len(data.Orders) != 3 || data.Orders[0].OrderID != 1 || data.Orders[1].OrderID != 4 || data.Orders[2].OrderID != 5 {
+		t.Fatalf("expected orders 1,4,5 canceled, got %+v", data.Orders)
+	}
+	if data.Orders[1].LeavesQty != 3 || data.Orders[2].LeavesQty != 2 {
+		t.Fatalf("unexpected leaves qty: %+v", data.Orders)
+	}
+	if price, qty, ok := engine.book.BestBid(); !ok || price != 99 || qty != 5 {
+		t.Fatalf("expected other user's bid kept, got %d x %d", price, qty)
+	}
+	if price, _, ok := engine.book.BestAsk(); !ok || price != 101 {
+		t.Fatalf("expected sell side kept, got %d", price)
+	}
+	if engine.triggers.Len() != 0 {
+		t.Fatalf("expected stop order removed, len=%d", engine.triggers.Len())
+	}
+}
+
+func TestMassCancelEmptyStillReplies(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{Type: CmdMassCancel, Symbol: "BTCUSDT", UserID: 10, RequestID: 8})
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventMassCanceled) != nil
+	})
+	if data := findEvent(events, EventMassCanceled).Data.(*MassCanceledData); data.RequestID != 8 || len(data.Orders) != 0 {
+		t.Fatalf("unexpected empty mass cancel: %+v", data)
+	}
+}
@@ -0,0 +1,40 @@
+package engine
+
+// listSiblingIntact 列表的止损单入簿前检查：同列表的限价单仍在簿且尚未成交
+//
+// 两条订单在同一个 MULTI 中写入订单流，限价单先于止损单处理；限价单已成交、撤销或被拒绝时列表已结束。
+func (e *Engine) listSiblingIntact(cmd *Command) bool {
+	sibling := e.book.GetOrder(cmd.SiblingOrderID)
+	return sibling != nil && sibling.ListID == cmd.ListID && sibling.LeavesQty == sibling.OrigQty
+}
+
+// cancelListSibling 撤销同列表的另一条订单（挂单或未触发的条件单，原因 ORDER_LIST_CANCELED），不存在时忽略
+func (e *Engine) cancelListSibling(listID, siblingID int64) {
+	if listID == 0 || siblingID == 0 {
+		return
+	}
+	if order := e.book.GetOrder(siblingID); order != nil {
+		if order.ListID != listID {
+			return
+		}
+		e.book.RemoveOrder(siblingID)
+		e.emit(EventOrderCanceled, &OrderCanceledData{
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			UserID:        order.UserID,
+			LeavesQty:     order.LeavesQty,
+			Reason:        "ORDER_LIST_CANCELED",
+		})
+		return
+	}
+	if stop := e.triggers.Get(siblingID); stop != nil && stop.ListID == listID {
+		e.triggers.Remove(siblingID)
+		e.emit(EventOrderCanceled, &OrderCanceledData{
+			OrderID:       stop.OrderID,
+			ClientOrderID: stop.ClientOrderID,
+			UserID:        stop.UserID,
+			LeavesQty:     stop.Qty,
+			Reason:        "ORDER_LIST_CANCELED",
+		})
+	}
+}
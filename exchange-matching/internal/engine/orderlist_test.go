@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+// submitSellOCO 卖出 OCO：订单 1 为 105 限价单，订单 2 为 95 触发的止损市价单
+func submitSellOCO(t *testing.T, engine *Engine) {
+	t.Helper()
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 105, Qty: 10,
+		ListID: 7, SiblingOrderID: 2,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: orderTypeStopLoss, TimeInForce: 1, Qty: 10, StopPrice: 95,
+		ListID: 7, SiblingOrderID: 1,
+	})
+}
+
+func orderFilled(events []*Event, orderID int64) bool {
+	for _, ev := range events {
+		if ev.Type == EventOrderFilled && ev.Data.(*OrderFilledData).OrderID == orderID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOrderListStopTriggerCancelsLimitLeg(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSellOCO(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 94, Qty: 20,
+	})
+	// 在 94 成交触发止损单：限价单须在止损单执行前撤销
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 4, UserID: 30, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 94, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return orderFilled(ev, 2) })
+	for i, ev := range events {
+		if ev.Type != EventStopOrderTriggered {
+			continue
+		}
+		if i+1 >= len(events) || events[i+1].Type != EventOrderCanceled {
+			t.Fatalf("expected limit leg canceled right after trigger, got %+v", events[i+1:])
+		}
+		canceled := events[i+1].Data.(*OrderCanceledData)
+		if canceled.OrderID != 1 || canceled.LeavesQty != 10 || canceled.Reason != "ORDER_LIST_CANCELED" {
+			t.Fatalf("unexpected sibling cancel: %+v", canceled)
+		}
+		if _, asks := engine.Depth(10); len(asks) != 0 {
+			t.Fatalf("expected limit leg removed from book, got asks %+v", asks)
+		}
+		return
+	}
+	t.Fatal("expected stop triggered event")
+}
+
+func TestOrderListLimitFillCancelsStopLeg(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSellOCO(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 105, Qty: 4,
+	})
+	// 价格随后跌破触发价：止损单已在限价单成交的同一命令内撤销，不再触发
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 4, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 90, Qty: 1,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 5, UserID: 30, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 90, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return orderFilled(ev, 5) })
+	var sawCancel bool
+	for i, ev := range events {
+		switch ev.Type {
+		case EventStopOrderTriggered:
+			t.Fatalf("stop leg must not trigger after the limit leg traded")
+		case EventOrderCanceled:
+			canceled := ev.Data.(*OrderCanceledData)
+			if canceled.OrderID != 2 || canceled.LeavesQty != 10 || canceled.Reason != "ORDER_LIST_CANCELED" {
+				t.Fatalf("unexpected cancel: %+v", canceled)
+			}
+			// 同一命令内：紧跟在 maker 部分成交之后、taker 完全成交之前
+			if events[i-1].Type != EventOrderPartiallyFilled || !orderFilled(events[i+1:i+2], 3) {
+				t.Fatalf("expected sibling cancel inside the fill, got %v before and %v after", events[i-1].Type, events[i+1].Type)
+			}
+			sawCancel = true
+		}
+	}
+	if !sawCancel {
+		t.Fatal("expected stop leg canceled")
+	}
+}
+
+func TestOrderListStopLegRejectedAfterLimitTraded(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	// 限价单入簿即成交：随后到达的止损单不再入簿
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 106, Qty: 10,
+	})
+	submitSellOCO(t, engine)
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return findEvent(ev, EventOrderRejected) != nil })
+	if findEvent(events, EventStopOrderAccepted) != nil {
+		t.Fatal("stop leg must not be accepted after the limit leg traded")
+	}
+	rejected := findEvent(events, EventOrderRejected).Data.(*OrderRejectedData)
+	if rejected.OrderID != 2 || rejected.Reason != "ORDER_LIST_DONE" {
+		t.Fatalf("unexpected reject: %+v", rejected)
+	}
+}
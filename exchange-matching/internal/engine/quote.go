@@ -0,0 +1,121 @@
+package engine
+
+import "github.com/exchange/matching/internal/orderbook"
+
+// QuoteEntry 报价单（限价单，数量为订单数量）
+type QuoteEntry struct {
+	OrderID int64
+	Side    orderbook.Side
+	Price   int64
+	Qty     int64
+}
+
+// MassQuotedData 批量报价生效事件数据（在新报价单的撮合事件之前发送）
+//
+// BuyCarried/SellCarried 为旧报价剩余冻结中转给新报价的部分，其余部分由订单服务解冻。
+type MassQuotedData struct {
+	RequestID   int64
+	UserID      int64
+	Canceled    []CanceledOrder
+	Orders      []int64 // 新报价单 ID（按请求顺序）
+	BuyCarried  int64
+	SellCarried int64
+}
+
+// MassQuoteRejectedData 批量报价拒绝事件数据（旧报价保持不变，新冻结由订单服务解冻）
+type MassQuoteRejectedData struct {
+	RequestID  int64
+	UserID     int64
+	Orders     []int64
+	BuyFrozen  int64
+	SellFrozen int64
+	Reason     string
+}
+
+// processMassQuote 撤销用户的全部报价单并挂出新的报价单，旧报价剩余冻结加新冻结不足时整组拒绝
+func (e *Engine) processMassQuote(cmd *Command) {
+	orders := make([]int64, 0, len(cmd.Quotes))
+	for _, quote := range cmd.Quotes {
+		orders = append(orders, quote.OrderID)
+	}
+	reject := func(reason string) {
+		e.emit(EventMassQuoteRejected, &MassQuoteRejectedData{
+			RequestID:  cmd.RequestID,
+			UserID:     cmd.UserID,
+			Orders:     orders,
+			BuyFrozen:  cmd.BuyFrozen,
+			SellFrozen: cmd.SellFrozen,
+			Reason:     reason,
+		})
+	}
+	if cmd.QtyScale <= 0 {
+		reject("INVALID_PARAM")
+		return
+	}
+
+	var needBuy, needSell int64
+	for _, quote := range cmd.Quotes {
+		if quote.Qty <= 0 || quote.Price <= 0 || (quote.Side != orderbook.SideBuy && quote.Side != orderbook.SideSell) {
+			reject("INVALID_PARAM")
+			return
+		}
+		if e.book.GetOrder(quote.OrderID) != nil || e.triggers.Get(quote.OrderID) != nil {
+			reject("DUPLICATE_ORDER")
+			return
+		}
+		if quote.Side == orderbook.SideBuy {
+			needBuy += quote.Price * quote.Qty / cmd.QtyScale
+		} else {
+			needSell += quote.Qty
+		}
+	}
+
+	// 旧报价的剩余冻结按剩余数量计算（买单取下界：成交价不高于报价）
+	old := e.book.UserQuotes(cmd.UserID)
+	var heldBuy, heldSell int64
+	for _, order := range old {
+		if order.Side == orderbook.SideBuy {
+			heldBuy += order.Price * order.LeavesQty / cmd.QtyScale
+		} else {
+			heldSell += order.LeavesQty
+		}
+	}
+	if needBuy > cmd.BuyFrozen+heldBuy || needSell > cmd.SellFrozen+heldSell {
+		reject("INSUFFICIENT_BALANCE")
+		return
+	}
+
+	data := &MassQuotedData{
+		RequestID:   cmd.RequestID,
+		UserID:      cmd.UserID,
+		Canceled:    make([]CanceledOrder, 0, len(old)),
+		Orders:      orders,
+		BuyCarried:  max(needBuy-cmd.BuyFrozen, 0),
+		SellCarried: max(needSell-cmd.SellFrozen, 0),
+	}
+	for _, order := range old {
+		e.book.RemoveOrder(order.OrderID)
+		data.Canceled = append(data.Canceled, CanceledOrder{
+			OrderID:       order.OrderID,
+			ClientOrderID: order.ClientOrderID,
+			LeavesQty:     order.LeavesQty,
+		})
+	}
+	e.emit(EventMassQuoted, data)
+
+	for _, quote := range cmd.Quotes {
+		e.processNewOrder(&Command{
+			Type:        CmdNewOrder,
+			OrderID:     quote.OrderID,
+			UserID:      cmd.UserID,
+			Symbol:      cmd.Symbol,
+			Side:        quote.Side,
+			OrderType:   1,
+			TimeInForce: cmd.TimeInForce,
+			Price:       quote.Price,
+			Qty:         quote.Qty,
+			STPMode:     cmd.STPMode,
+			QuoteID:     cmd.RequestID,
+		})
+	}
+}
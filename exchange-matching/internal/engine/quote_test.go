@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+// countEvents 统计某类事件的数量
+func countEvents(events []*Event, eventType EventType) int {
+	n := 0
+	for _, ev := range events {
+		if ev.Type == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMassQuoteReplacesQuoteSet(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	// 普通订单不属于报价组
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT", Side: orderbook.SideBuy,
+		OrderType: 1, TimeInForce: 1, Price: 95, Qty: 5,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdMassQuote, RequestID: 100, UserID: 10, Symbol: "BTCUSDT", TimeInForce: 1, QtyScale: 1,
+		BuyFrozen: 495, SellFrozen: 5,
+		Quotes: []QuoteEntry{
+			{OrderID: 11, Side: orderbook.SideBuy, Price: 99, Qty: 5},
+			{OrderID: 12, Side: orderbook.SideSell, Price: 101, Qty: 5},
+		},
+	})
+	// 旧报价卖单部分成交
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 20, Symbol: "BTCUSDT", Side: orderbook.SideBuy,
+		OrderType: 1, TimeInForce: 2, Price: 101, Qty: 2,
+	})
+	collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderFilled) != nil
+	})
+
+	// 新报价只冻结差额：买单由旧买单剩余冻结覆盖，卖单追加 1
+	submitOrFail(t, engine, &Command{
+		Type: CmdMassQuote, RequestID: 200, UserID: 10, Symbol: "BTCUSDT", TimeInForce: 1, QtyScale: 1,
+		SellFrozen: 1,
+		Quotes: []QuoteEntry{
+			{OrderID: 21, Side: orderbook.SideBuy, Price: 98, Qty: 5},
+			{OrderID: 22, Side: orderbook.SideSell, Price: 102, Qty: 4},
+		},
+	})
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return countEvents(ev, EventOrderAccepted) == 2
+	})
+	if events[0].Type != EventMassQuoted {
+		t.Fatalf("expected MASS_QUOTED before the new quotes, got %v", events[0].Type)
+	}
+	data := events[0].Data.(*MassQuotedData)
+	if data.RequestID != 200 || data.UserID != 10 || len(data.Orders) != 2 || data.Orders[0] != 21 {
+		t.Fatalf("unexpected mass quote: %+v", data)
+	}
+	if len(data.Canceled) != 2 || data.Canceled[0].OrderID != 11 || data.Canceled[1].OrderID != 12 || data.Canceled[1].LeavesQty != 3 {
+		t.Fatalf("expected old quotes 11,12 canceled, got %+v", data.Canceled)
+	}
+	if data.BuyCarried != 490 || data.SellCarried != 3 {
+		t.Fatalf("unexpected carried funds: buy=%d sell=%d", data.BuyCarried, data.SellCarried)
+	}
+	if findEvent(events, EventOrderCanceled) != nil {
+		t.Fatal("expected old quotes canceled inside the batch event")
+	}
+
+	if engine.book.GetOrder(1) == nil {
+		t.Fatal("expected non-quote order kept")
+	}
+	if order := engine.book.GetOrder(21); order == nil || order.QuoteID != 200 {
+		t.Fatalf("expected new quote resting with quote id, got %+v", order)
+	}
+	if price, qty, ok := engine.book.BestAsk(); !ok || price != 102 || qty != 4 {
+		t.Fatalf("unexpected best ask %d x %d", price, qty)
+	}
+}
+
+func TestMassQuoteRejectedWhenOldQuotesTraded(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdMassQuote, RequestID: 100, UserID: 10, Symbol: "BTCUSDT", TimeInForce: 1, QtyScale: 1,
+		SellFrozen: 5,
+		Quotes:     []QuoteEntry{{OrderID: 11, Side: orderbook.SideSell, Price: 101, Qty: 5}},
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 20, Symbol: "BTCUSDT", Side: orderbook.SideBuy,
+		OrderType: 1, TimeInForce: 2, Price: 101, Qty: 2,
+	})
+	// 订单服务按成交前的剩余数量计算，未追加冻结
+	submitOrFail(t, engine, &Command{
+		Type: CmdMassQuote, RequestID: 200, UserID: 10, Symbol: "BTCUSDT", TimeInForce: 1, QtyScale: 1,
+		Quotes: []QuoteEntry{{OrderID: 21, Side: orderbook.SideSell, Price: 100, Qty: 5}},
+	})
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventMassQuoteRejected) != nil
+	})
+	data := findEvent(events, EventMassQuoteRejected).Data.(*MassQuoteRejectedData)
+	if data.RequestID != 200 || data.Reason != "INSUFFICIENT_BALANCE" || len(data.Orders) != 1 || data.Orders[0] != 21 {
+		t.Fatalf("unexpected reject: %+v", data)
+	}
+	if order := engine.book.GetOrder(11); order == nil || order.LeavesQty != 3 {
+		t.Fatalf("expected old quote kept, got %+v", order)
+	}
+	if engine.book.GetOrder(21) != nil {
+		t.Fatal("expected rejected quote not added")
+	}
+}
@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+// SnapshotVersion 快照格式版本，格式不兼容变更时递增
+//
+// 2：订单与条件单携带到期时间、报价、跟踪止损与订单列表字段，快照携带竞价与熔断状态。
+// 版本 1 的快照缺少这些字段（gob 解码为零值，无法与"未设置"区分），不再恢复，交易对改从订单库恢复。
+const SnapshotVersion = 2
+
+// ErrSnapshotVersion 快照版本与当前格式不一致
+var ErrSnapshotVersion = errors.New("unsupported snapshot version")
+
+// snapshotMagic 快照文件头
+const snapshotMagic = "MESNAP"
+
+// cmdSnapshot 内部命令：在引擎 goroutine 内生成快照，保证与已提交命令的顺序一致
+const cmdSnapshot CommandType = 100
+
+// Snapshot 引擎快照：订单簿（按价格时间优先级）、条件单触发簿与事件序列号
+//
+// 快照在引擎 goroutine 内生成，StreamID 为生成时已处理的最后一条订单流消息 ID；
+// 恢复快照后从该 ID 之后重放订单流，即可得到与重启前一致的订单簿与序列号。
+type Snapshot struct {
+	Version     int
+	Symbol      string
+	Seq         int64  // 已分配的最后一个事件序列号
+	LastPrice   int64  // 最新成交价（条件单触发依据）
+	StreamID    string // 已处理的最后一条订单流消息 ID，空表示尚未处理任何消息
+	Auction     bool   // 是否处于集合竞价
+	BreakerID   int64  // 非 0 表示竞价由熔断触发（到期后自动恢复）
+	BandSamples []PriceSample
+	CreatedAtMs int64
+	Orders      []orderbook.Order // 按 OrderBook.Orders 顺序
+	Stops       []Command         // 未触发的条件单（按触发顺序）
+}
+
+// Snapshot 生成快照（排在已提交的命令之后执行）
+func (e *Engine) Snapshot(ctx context.Context) (*Snapshot, error) {
+	reply := make(chan *Snapshot, 1)
+	if err := e.Submit(&Command{Type: cmdSnapshot, reply: reply}); err != nil {
+		return nil, err
+	}
+	select {
+	case snap := <-reply:
+		return snap, nil
+	case <-e.ctx.Done():
+		return nil, fmt.Errorf("engine stopped")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (e *Engine) buildSnapshot() *Snapshot {
+	stops := e.triggers.All()
+	snap := &Snapshot{
+		Version:     SnapshotVersion,
+		Symbol:      e.symbol,
+		Seq:         e.Seq(),
+		LastPrice:   e.lastPrice,
+		StreamID:    e.streamID,
+		Auction:     e.auction,
+		BreakerID:   e.breakerID,
+		BandSamples: append([]PriceSample(nil), e.bandSamples...),
+		CreatedAtMs: e.now().UnixMilli(),
+		Orders:      e.book.Orders(),
+		Stops:       make([]Command, 0, len(stops)),
+	}
+	for _, stop := range stops {
+		snap.Stops = append(snap.Stops, *stop)
+	}
+	return snap
+}
+
+// Restore 从快照恢复订单簿、触发簿与序列号（需在 Start 之前调用）
+func (e *Engine) Restore(snap *Snapshot) error {
+	if snap == nil {
+		return fmt.Errorf("nil snapshot")
+	}
+	if snap.Version != SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, snap.Version)
+	}
+	if snap.Symbol != e.symbol {
+		return fmt.Errorf("snapshot symbol mismatch: %s != %s", snap.Symbol, e.symbol)
+	}
+	if len(e.book.Orders()) > 0 || e.triggers.Len() > 0 {
+		return fmt.Errorf("engine not empty")
+	}
+
+	for i := range snap.Orders {
+		order := snap.Orders[i]
+		e.book.AddOrder(&order)
+		e.trackExpiry(order.OrderID, order.ExpireTimeMs)
+	}
+	for i := range snap.Stops {
+		stop := snap.Stops[i]
+		e.triggers.Add(&stop)
+		e.trackExpiry(stop.OrderID, stop.ExpireTimeMs)
+	}
+	e.lastPrice = snap.LastPrice
+	e.streamID = snap.StreamID
+	e.auction = snap.Auction
+	if e.auction {
+		// 与生成快照时最近一次发布的参考价一致，避免重放时多发事件
+		e.indicative = e.book.Equilibrium(e.lastPrice)
+		if snap.BreakerID > 0 {
+			e.breakerID = snap.BreakerID
+			e.scheduleBreakerResume()
+		}
+	}
+	e.bandSamples = append([]PriceSample(nil), snap.BandSamples...)
+	e.SetSeq(snap.Seq)
+	e.book.Publish()
+	return nil
+}
+
+// MuteEventsThrough 丢弃序列号不大于 seq 的事件（序列号照常分配）
+//
+// 重放订单流时，崩溃前已发布的事件会被重新生成，据此避免重复发布。
+func (e *Engine) MuteEventsThrough(seq int64) {
+	e.mu.Lock()
+	e.mutedThrough = seq
+	e.mu.Unlock()
+}
+
+// CompareStreamIDs 比较 Redis Stream 消息 ID（<ms>-<seq>），返回 -1/0/1；空 ID 最小
+func CompareStreamIDs(a, b string) int {
+	aMs, aSeq := parseStreamID(a)
+	bMs, bSeq := parseStreamID(b)
+	switch {
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1
+		}
+		return 1
+	case aSeq != bSeq:
+		if aSeq < bSeq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func parseStreamID(id string) (uint64, uint64) {
+	if id == "" {
+		return 0, 0
+	}
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
+
+// EncodeSnapshot 将快照编码为二进制格式（文件头 + gob）
+func EncodeSnapshot(w io.Writer, snap *Snapshot) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(bw).Encode(snap); err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	return bw.Flush()
+}
+
+// DecodeSnapshot 解码 EncodeSnapshot 写入的快照
+func DecodeSnapshot(r io.Reader) (*Snapshot, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("read snapshot header: %w", err)
+	}
+	if string(magic) != snapshotMagic {
+		return nil, fmt.Errorf("invalid snapshot header")
+	}
+	var snap Snapshot
+	if err := gob.NewDecoder(br).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snap.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, snap.Version)
+	}
+	return &snap, nil
+}
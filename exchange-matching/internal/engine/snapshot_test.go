@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+func TestSnapshotRestoreKeepsPriorityAndSeq(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 12, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 3, StreamID: "5-0",
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 4, UserID: 12, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 90, Qty: 20, DisplayQty: 5,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 5, UserID: 12, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 3, TimeInForce: 2, Qty: 1, StopPrice: 95,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	snap, err := engine.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if snap.StreamID != "5-0" || snap.LastPrice != 100 || len(snap.Orders) != 3 || len(snap.Stops) != 1 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeSnapshot(&buf, snap); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	decoded, err := DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	restored := NewEngine("BTCUSDT", 10000, 10000)
+	defer restored.Stop()
+	if err := restored.Restore(decoded); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if err := restored.Restore(decoded); err == nil {
+		t.Fatal("expected restore into non-empty engine to fail")
+	}
+	restored.Start()
+
+	wantBids, wantAsks := engine.Depth(5)
+	bids, asks := restored.Depth(5)
+	if len(bids) != len(wantBids) || len(asks) != len(wantAsks) || bids[0] != wantBids[0] || asks[0] != wantAsks[0] {
+		t.Fatalf("depth mismatch: got %+v %+v, want %+v %+v", bids, asks, wantBids, wantAsks)
+	}
+	if bids[0].Qty != 5 {
+		t.Fatalf("expected iceberg visible qty restored, got %+v", bids)
+	}
+
+	// 订单 1 剩余 7 仍排在订单 2 之前，序列号从快照继续
+	submitOrFail(t, restored, &Command{
+		Type: CmdNewOrder, OrderID: 6, UserID: 13, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1,
+	})
+	events := collectUntil(t, restored, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderFilled) != nil
+	})
+	if events[0].Seq != snap.Seq+1 {
+		t.Fatalf("expected seq %d, got %d", snap.Seq+1, events[0].Seq)
+	}
+	trade := findEvent(events, EventTradeCreated).Data.(*TradeCreatedData)
+	if trade.MakerOrderID != 1 {
+		t.Fatalf("expected maker 1 to keep priority, got %d", trade.MakerOrderID)
+	}
+}
+
+func TestSnapshotRestoresStopOrders(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 3, TimeInForce: 2, Qty: 1, StopPrice: 95,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	snap, err := engine.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	restored := NewEngine("BTCUSDT", 10000, 10000)
+	defer restored.Stop()
+	if err := restored.Restore(snap); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	restored.Start()
+	submitOrFail(t, restored, &Command{Type: CmdCancelOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT"})
+	events := collectUntil(t, restored, 2*time.Second, func(ev []*Event) bool {
+		return len(ev) >= 1
+	})
+	if events[0].Type != EventOrderCanceled || events[0].Data.(*OrderCanceledData).LeavesQty != 1 {
+		t.Fatalf("expected restored stop order canceled, got %v %+v", events[0].Type, events[0].Data)
+	}
+}
+
+func TestMuteEventsThrough(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	engine.MuteEventsThrough(1)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 101, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return len(ev) >= 1
+	})
+	if events[0].Seq != 2 || events[0].Data.(*OrderAcceptedData).OrderID != 2 {
+		t.Fatalf("expected first event muted, got seq=%d %+v", events[0].Seq, events[0].Data)
+	}
+}
+
+func TestDecodeSnapshotRejectsInvalidHeader(t *testing.T) {
+	if _, err := DecodeSnapshot(bytes.NewReader([]byte("garbage"))); err == nil {
+		t.Fatal("expected invalid header error")
+	}
+}
+
+func TestSnapshotRejectsOldVersion(t *testing.T) {
+	engine := NewEngine("BTCUSDT", 10, 10)
+	old := &Snapshot{Version: 1, Symbol: "BTCUSDT", StreamID: "1-0"}
+	if err := engine.Restore(old); !errors.Is(err, ErrSnapshotVersion) {
+		t.Fatalf("expected ErrSnapshotVersion from restore, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeSnapshot(&buf, old); err != nil {
+		t.Fatalf("encode snapshot: %v", err)
+	}
+	if _, err := DecodeSnapshot(&buf); !errors.Is(err, ErrSnapshotVersion) {
+		t.Fatalf("expected ErrSnapshotVersion from decode, got %v", err)
+	}
+}
+
+func TestCompareStreamIDs(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1-0", "1-0", 0},
+		{"1-1", "1-0", 1},
+		{"9-5", "10-0", -1},
+		{"", "0-1", -1},
+		{"", "", 0},
+	}
+	for _, tc := range cases {
+		if got := CompareStreamIDs(tc.a, tc.b); got != tc.want {
+			t.Fatalf("CompareStreamIDs(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestBookL3OrderByOrder(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 11, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 3,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 12, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 20, DisplayQty: 5,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 13, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 101, Qty: 1,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 4, UserID: 14, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 105, Qty: 7,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	book, err := engine.BookL3(ctx)
+	if err != nil {
+		t.Fatalf("book l3: %v", err)
+	}
+	if book.Symbol != "BTCUSDT" || book.Seq != 4 {
+		t.Fatalf("unexpected book header: %+v", book)
+	}
+	wantBids := []L3Order{{OrderID: 3, Price: 101, Qty: 1}, {OrderID: 1, Price: 100, Qty: 3}, {OrderID: 2, Price: 100, Qty: 5}}
+	if len(book.Bids) != len(wantBids) {
+		t.Fatalf("unexpected bids: %+v", book.Bids)
+	}
+	for i, want := range wantBids {
+		if book.Bids[i] != want {
+			t.Fatalf("bids[%d] = %+v, want %+v", i, book.Bids[i], want)
+		}
+	}
+	if len(book.Asks) != 1 || book.Asks[0] != (L3Order{OrderID: 4, Price: 105, Qty: 7}) {
+		t.Fatalf("unexpected asks: %+v", book.Asks)
+	}
+}
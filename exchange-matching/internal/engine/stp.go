@@ -0,0 +1,51 @@
+package engine
+
+import "github.com/exchange/matching/internal/orderbook"
+
+// stpCancelReason 自成交防护撤单/扣减原因（取 taker 的 STP 模式）
+func stpCancelReason(mode orderbook.STPMode) string {
+	switch mode {
+	case orderbook.STPExpireMaker:
+		return "STP_EXPIRE_MAKER"
+	case orderbook.STPExpireBoth:
+		return "STP_EXPIRE_BOTH"
+	case orderbook.STPDecrement:
+		return "STP_DECREMENT"
+	default:
+		return "STP_EXPIRE_TAKER"
+	}
+}
+
+// emitSTPMakers 发送被自成交防护撤销或扣减的 maker 事件
+//
+// 撤销的 maker 以 ORDER_CANCELED 通知，LeavesQty 为本次失效数量，订单服务据此解冻；
+// 仅扣减的 maker 以 ORDER_REDUCED 通知。
+func (e *Engine) emitSTPMakers(expiries []*orderbook.STPExpiry, reason string) {
+	for _, expiry := range expiries {
+		maker := expiry.Order
+		if expiry.Canceled {
+			e.emit(EventOrderCanceled, &OrderCanceledData{
+				OrderID:       maker.OrderID,
+				ClientOrderID: maker.ClientOrderID,
+				UserID:        maker.UserID,
+				LeavesQty:     expiry.Qty,
+				Reason:        reason,
+			})
+			continue
+		}
+		e.emitReduced(maker, expiry.Qty, reason)
+	}
+}
+
+func (e *Engine) emitReduced(order *orderbook.Order, reducedQty int64, reason string) {
+	e.emit(EventOrderReduced, &OrderReducedData{
+		OrderID:       order.OrderID,
+		ClientOrderID: order.ClientOrderID,
+		UserID:        order.UserID,
+		ReducedQty:    reducedQty,
+		OrigQty:       order.OrigQty,
+		LeavesQty:     order.LeavesQty,
+		VisibleQty:    visibleQty(order),
+		Reason:        reason,
+	})
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+func submitSelfTradeBook(t *testing.T, engine *Engine) {
+	t.Helper()
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 10,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 11, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 5,
+	})
+}
+
+func TestSTPExpireMaker(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 8,
+		STPMode: orderbook.STPExpireMaker,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderAccepted) != nil && len(ev) >= 7
+	})
+
+	canceled := findEvent(events, EventOrderCanceled)
+	if canceled == nil {
+		t.Fatal("expected maker cancel event")
+	}
+	data := canceled.Data.(*OrderCanceledData)
+	if data.OrderID != 1 || data.LeavesQty != 10 || data.Reason != "STP_EXPIRE_MAKER" {
+		t.Fatalf("unexpected maker cancel: %+v", data)
+	}
+	trade := findEvent(events, EventTradeCreated).Data.(*TradeCreatedData)
+	if trade.MakerOrderID != 2 || trade.Qty != 5 {
+		t.Fatalf("unexpected trade: %+v", trade)
+	}
+
+	// taker 剩余 3 挂单，卖盘已清空，不存在交叉
+	last := events[len(events)-1]
+	if last.Type != EventOrderAccepted || last.Data.(*OrderAcceptedData).Qty != 3 {
+		t.Fatalf("expected taker rest 3, got %v %+v", last.Type, last.Data)
+	}
+	bids, asks := engine.Depth(1)
+	if len(asks) != 0 || len(bids) != 1 || bids[0].Qty != 3 {
+		t.Fatalf("unexpected depth bids=%+v asks=%+v", bids, asks)
+	}
+}
+
+func TestSTPDecrementEvents(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitSelfTradeBook(t, engine)
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 4,
+		STPMode: orderbook.STPDecrement,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return len(ev) >= 4
+	})
+
+	// maker 10 -> 6（ORDER_REDUCED），taker 4 全部扣减（ORDER_CANCELED）
+	reduced := events[2]
+	if reduced.Type != EventOrderReduced {
+		t.Fatalf("expected reduced event, got %v", reduced.Type)
+	}
+	rd := reduced.Data.(*OrderReducedData)
+	if rd.OrderID != 1 || rd.ReducedQty != 4 || rd.OrigQty != 6 || rd.LeavesQty != 6 || rd.Reason != "STP_DECREMENT" {
+		t.Fatalf("unexpected reduced data: %+v", rd)
+	}
+	canceled := events[3]
+	if canceled.Type != EventOrderCanceled {
+		t.Fatalf("expected taker cancel, got %v", canceled.Type)
+	}
+	cd := canceled.Data.(*OrderCanceledData)
+	if cd.OrderID != 3 || cd.LeavesQty != 4 || cd.Reason != "STP_DECREMENT" {
+		t.Fatalf("unexpected taker cancel: %+v", cd)
+	}
+
+	_, asks := engine.Depth(1)
+	if len(asks) != 1 || asks[0].Qty != 11 {
+		t.Fatalf("expected ask qty=11, got %+v", asks)
+	}
+}
@@ -0,0 +1,290 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+// 条件单类型（Command.OrderType）
+const (
+	orderTypeStopLoss      = 3 // 止损市价
+	orderTypeStopLossLimit = 4 // 止损限价
+	orderTypeTakeProfit    = 5 // 止盈市价
+	orderTypeTrailingStop  = 6 // 跟踪止损市价
+)
+
+// maxTrailingBps 跟踪止损回调比例上限（基点，不含）
+const maxTrailingBps = 10000
+
+func isStopOrderType(orderType int) bool {
+	switch orderType {
+	case orderTypeStopLoss, orderTypeStopLossLimit, orderTypeTakeProfit, orderTypeTrailingStop:
+		return true
+	default:
+		return false
+	}
+}
+
+// triggersOnRise 条件单是否在价格上涨至 StopPrice 时触发
+//
+// 买入止损 / 卖出止盈 / 买入跟踪止损：最新价 >= StopPrice 触发
+// 卖出止损 / 买入止盈 / 卖出跟踪止损：最新价 <= StopPrice 触发
+func triggersOnRise(cmd *Command) bool {
+	if cmd.OrderType == orderTypeTakeProfit {
+		return cmd.Side == orderbook.SideSell
+	}
+	return cmd.Side == orderbook.SideBuy
+}
+
+// stopTriggered 判断最新成交价是否已触及条件单触发价
+func stopTriggered(cmd *Command, lastPrice int64) bool {
+	if lastPrice <= 0 {
+		return false
+	}
+	if triggersOnRise(cmd) {
+		return lastPrice >= cmd.StopPrice
+	}
+	return lastPrice <= cmd.StopPrice
+}
+
+// validTrailing 跟踪止损回调参数：价差与比例二选一
+func validTrailing(cmd *Command) bool {
+	if cmd.TrailingDelta < 0 || cmd.TrailingBps < 0 || cmd.TrailingBps >= maxTrailingBps || cmd.ActivationPrice < 0 {
+		return false
+	}
+	return (cmd.TrailingDelta > 0) != (cmd.TrailingBps > 0)
+}
+
+// trail 按成交价更新跟踪止损，触发价变化时返回 true
+//
+// 卖出跟踪止损在价格涨至 ActivationPrice 后激活，记录激活后的最高价，触发价为最高价减回调幅度；
+// 买入方向相反（跌至激活价后激活，记录最低价，触发价为最低价加回调幅度）。
+// 未指定激活价时按第一笔成交价激活。触发价只朝有利方向移动。
+func trail(cmd *Command, price int64) bool {
+	if price <= 0 {
+		return false
+	}
+	rise := triggersOnRise(cmd)
+	switch {
+	case cmd.TrailingExtreme == 0:
+		if cmd.ActivationPrice > 0 && ((rise && price > cmd.ActivationPrice) || (!rise && price < cmd.ActivationPrice)) {
+			return false
+		}
+	case rise && price < cmd.TrailingExtreme, !rise && price > cmd.TrailingExtreme:
+	default:
+		return false
+	}
+	cmd.TrailingExtreme = price
+
+	offset := cmd.TrailingDelta
+	if offset <= 0 {
+		offset = max(price*cmd.TrailingBps/maxTrailingBps, 1)
+	}
+	if rise {
+		cmd.StopPrice = price + offset
+	} else {
+		cmd.StopPrice = max(price-offset, 0)
+	}
+	return true
+}
+
+// triggeredCommand 条件单触发后转换为普通订单命令
+func triggeredCommand(cmd *Command) *Command {
+	next := *cmd
+	next.Type = CmdNewOrder
+	next.StopPrice = 0
+	if cmd.OrderType == orderTypeStopLossLimit {
+		next.OrderType = 1
+	} else {
+		next.OrderType = 2
+		if next.TimeInForce != 3 {
+			next.TimeInForce = 2 // 市价单剩余不挂单
+		}
+	}
+	return &next
+}
+
+// triggerBook 条件单触发簿（仅由引擎 goroutine 访问，无需加锁）
+//
+// rising 按 StopPrice 升序，falling 按 StopPrice 降序；同触发价按入簿顺序触发。
+// 跟踪止损的触发价随成交移动，单独按入簿顺序保存在 trailing 中。
+type triggerBook struct {
+	rising   []*Command
+	falling  []*Command
+	trailing []*Command
+	orders   map[int64]*Command
+}
+
+func newTriggerBook() *triggerBook {
+	return &triggerBook{
+		orders: make(map[int64]*Command),
+	}
+}
+
+// Len 触发簿中的条件单数量
+func (tb *triggerBook) Len() int {
+	return len(tb.orders)
+}
+
+// Get 获取条件单
+func (tb *triggerBook) Get(orderID int64) *Command {
+	return tb.orders[orderID]
+}
+
+// Add 添加条件单
+func (tb *triggerBook) Add(cmd *Command) bool {
+	if _, exists := tb.orders[cmd.OrderID]; exists {
+		return false
+	}
+	if cmd.OrderType == orderTypeTrailingStop {
+		tb.trailing = append(tb.trailing, cmd)
+	} else if triggersOnRise(cmd) {
+		tb.rising = insertStop(tb.rising, cmd, false)
+	} else {
+		tb.falling = insertStop(tb.falling, cmd, true)
+	}
+	tb.orders[cmd.OrderID] = cmd
+	return true
+}
+
+// All 按触发顺序导出条件单（先上穿后下穿，最后为跟踪止损），依次 Add 可还原相同顺序
+func (tb *triggerBook) All() []*Command {
+	all := make([]*Command, 0, len(tb.orders))
+	all = append(all, tb.rising...)
+	all = append(all, tb.falling...)
+	return append(all, tb.trailing...)
+}
+
+// Trail 按成交价更新所有跟踪止损，返回触发价发生变化的条件单（按入簿顺序）
+func (tb *triggerBook) Trail(price int64) []*Command {
+	var moved []*Command
+	for _, cmd := range tb.trailing {
+		if trail(cmd, price) {
+			moved = append(moved, cmd)
+		}
+	}
+	return moved
+}
+
+// Remove 移除条件单
+func (tb *triggerBook) Remove(orderID int64) *Command {
+	cmd, exists := tb.orders[orderID]
+	if !exists {
+		return nil
+	}
+	if cmd.OrderType == orderTypeTrailingStop {
+		tb.trailing = removeStop(tb.trailing, orderID)
+	} else if triggersOnRise(cmd) {
+		tb.rising = removeStop(tb.rising, orderID)
+	} else {
+		tb.falling = removeStop(tb.falling, orderID)
+	}
+	delete(tb.orders, orderID)
+	return cmd
+}
+
+// RemoveUser 移除用户的条件单（side 为 0 表示双边），按订单 ID 升序返回
+func (tb *triggerBook) RemoveUser(userID int64, side orderbook.Side) []*Command {
+	match := func(cmd *Command) bool {
+		return cmd.UserID == userID && (side == 0 || cmd.Side == side)
+	}
+	var removed []*Command
+	keep := func(stops []*Command) []*Command {
+		n := 0
+		for _, cmd := range stops {
+			if match(cmd) {
+				removed = append(removed, cmd)
+				delete(tb.orders, cmd.OrderID)
+				continue
+			}
+			stops[n] = cmd
+			n++
+		}
+		clear(stops[n:])
+		return stops[:n]
+	}
+	tb.rising = keep(tb.rising)
+	tb.falling = keep(tb.falling)
+	tb.trailing = keep(tb.trailing)
+	sort.Slice(removed, func(i, j int) bool { return removed[i].OrderID < removed[j].OrderID })
+	return removed
+}
+
+// PopTriggered 取出所有已被最新价触发的条件单
+func (tb *triggerBook) PopTriggered(lastPrice int64) []*Command {
+	if lastPrice <= 0 || len(tb.orders) == 0 {
+		return nil
+	}
+	var triggered []*Command
+
+	n := 0
+	for n < len(tb.rising) && tb.rising[n].StopPrice <= lastPrice {
+		n++
+	}
+	if n > 0 {
+		triggered = append(triggered, tb.rising[:n]...)
+		tb.rising = append(tb.rising[:0], tb.rising[n:]...)
+	}
+
+	n = 0
+	for n < len(tb.falling) && tb.falling[n].StopPrice >= lastPrice {
+		n++
+	}
+	if n > 0 {
+		triggered = append(triggered, tb.falling[:n]...)
+		tb.falling = append(tb.falling[:0], tb.falling[n:]...)
+	}
+
+	// 跟踪止损：已激活且触发价被触及
+	n = 0
+	for _, cmd := range tb.trailing {
+		if cmd.TrailingExtreme > 0 && stopTriggered(cmd, lastPrice) {
+			triggered = append(triggered, cmd)
+			continue
+		}
+		tb.trailing[n] = cmd
+		n++
+	}
+	clear(tb.trailing[n:])
+	tb.trailing = tb.trailing[:n]
+
+	for _, cmd := range triggered {
+		delete(tb.orders, cmd.OrderID)
+	}
+	return triggered
+}
+
+// insertStop 按触发价插入（二分查找，同价插入末尾保持 FIFO）
+func insertStop(stops []*Command, cmd *Command, descending bool) []*Command {
+	lo, hi := 0, len(stops)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if descending {
+			if stops[mid].StopPrice >= cmd.StopPrice {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		} else {
+			if stops[mid].StopPrice <= cmd.StopPrice {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+	}
+	stops = append(stops, nil)
+	copy(stops[lo+1:], stops[lo:])
+	stops[lo] = cmd
+	return stops
+}
+
+func removeStop(stops []*Command, orderID int64) []*Command {
+	for i, cmd := range stops {
+		if cmd.OrderID == orderID {
+			return append(stops[:i], stops[i+1:]...)
+		}
+	}
+	return stops
+}
@@ -0,0 +1,410 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/orderbook"
+	"github.com/exchange/matching/internal/types"
+)
+
+func TestTriggerBookOrdering(t *testing.T) {
+	tb := newTriggerBook()
+	tb.Add(&Command{OrderID: 1, Side: orderbook.SideBuy, OrderType: orderTypeStopLoss, StopPrice: 110})
+	tb.Add(&Command{OrderID: 2, Side: orderbook.SideBuy, OrderType: orderTypeStopLoss, StopPrice: 105})
+	tb.Add(&Command{OrderID: 3, Side: orderbook.SideBuy, OrderType: orderTypeStopLoss, StopPrice: 105})
+	tb.Add(&Command{OrderID: 4, Side: orderbook.SideSell, OrderType: orderTypeStopLoss, StopPrice: 90})
+	tb.Add(&Command{OrderID: 5, Side: orderbook.SideBuy, OrderType: orderTypeTakeProfit, StopPrice: 95})
+
+	if tb.Add(&Command{OrderID: 1, Side: orderbook.SideBuy, OrderType: orderTypeStopLoss, StopPrice: 120}) {
+		t.Fatal("expected duplicate add to fail")
+	}
+	if got := tb.PopTriggered(100); len(got) != 0 {
+		t.Fatalf("expected no trigger at 100, got %d", len(got))
+	}
+
+	got := tb.PopTriggered(107)
+	if len(got) != 2 || got[0].OrderID != 2 || got[1].OrderID != 3 {
+		t.Fatalf("expected orders 2,3 triggered in FIFO order, got %+v", got)
+	}
+
+	got = tb.PopTriggered(90)
+	if len(got) != 2 || got[0].OrderID != 5 || got[1].OrderID != 4 {
+		t.Fatalf("expected orders 5,4 triggered, got %+v", got)
+	}
+
+	if tb.Remove(1) == nil || tb.Len() != 0 {
+		t.Fatalf("expected trigger book empty, len=%d", tb.Len())
+	}
+}
+
+func TestTriggeredCommand(t *testing.T) {
+	limit := triggeredCommand(&Command{OrderType: orderTypeStopLossLimit, TimeInForce: 1, Price: 100, StopPrice: 99})
+	if limit.OrderType != 1 || limit.TimeInForce != 1 || limit.Price != 100 || limit.StopPrice != 0 {
+		t.Fatalf("unexpected stop-limit conversion: %+v", limit)
+	}
+	market := triggeredCommand(&Command{OrderType: orderTypeTakeProfit, TimeInForce: 1, StopPrice: 99})
+	if market.OrderType != 2 || market.TimeInForce != 2 {
+		t.Fatalf("unexpected take-profit conversion: %+v", market)
+	}
+}
+
+func TestStopLimitTriggeredByTrade(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	// 卖出止损限价：价格跌到 95 触发，以 94 挂卖单
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: orderTypeStopLossLimit, TimeInForce: 1,
+		Price: 94, Qty: 10, StopPrice: 95,
+	})
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return len(ev) >= 1 })
+	if events[0].Type != EventStopOrderAccepted {
+		t.Fatalf("expected stop accepted, got %v", events[0].Type)
+	}
+	if bids, asks := engine.Depth(10); len(bids) != 0 || len(asks) != 0 {
+		t.Fatalf("stop order must not appear in depth")
+	}
+
+	// 在 95 成交，触发止损
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 95, Qty: 5,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 30, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 95, Qty: 5,
+	})
+
+	events = collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		return findEvent(ev, EventOrderAccepted) != nil && findEvent(ev, EventStopOrderTriggered) != nil &&
+			ev[len(ev)-1].Type == EventOrderAccepted && len(ev) >= 6
+	})
+	triggered := findEvent(events, EventStopOrderTriggered).Data.(*StopOrderTriggeredData)
+	if triggered.OrderID != 1 || triggered.LastPrice != 95 {
+		t.Fatalf("unexpected trigger data: %+v", triggered)
+	}
+	accepted := events[len(events)-1].Data.(*OrderAcceptedData)
+	if accepted.OrderID != 1 || accepted.Price != 94 || accepted.Qty != 10 {
+		t.Fatalf("unexpected triggered order accepted: %+v", accepted)
+	}
+	_, asks := engine.Depth(10)
+	if len(asks) != 1 || asks[0].Price != 94 || asks[0].Qty != 10 {
+		t.Fatalf("unexpected asks after trigger: %+v", asks)
+	}
+}
+
+func TestStopMarketTriggeredByTrade(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	// 卖盘深度：100 x 5, 101 x 10
+	for i, price := range []int64{100, 101} {
+		submitOrFail(t, engine, &Command{
+			Type: CmdNewOrder, OrderID: int64(i + 1), UserID: 10, Symbol: "BTCUSDT",
+			Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: price, Qty: 5 * int64(i+1),
+		})
+	}
+	// 买入止损市价：价格涨到 100 触发
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: orderTypeStopLoss, TimeInForce: 1, Qty: 8, StopPrice: 100,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 4, UserID: 30, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 2, TimeInForce: 2, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		for _, e := range ev {
+			if e.Type == EventOrderFilled && e.Data.(*OrderFilledData).OrderID == 3 {
+				return true
+			}
+		}
+		return false
+	})
+	if findEvent(events, EventStopOrderTriggered) == nil {
+		t.Fatal("expected stop triggered event")
+	}
+	_, asks := engine.Depth(10)
+	if len(asks) != 1 || asks[0].Price != 101 || asks[0].Qty != 6 {
+		t.Fatalf("unexpected asks after stop market: %+v", asks)
+	}
+}
+
+func TestStopOrderRejectWouldTrigger(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 2, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 100, Qty: 1,
+	})
+	// 最新价 100，买入止损 99 会立即触发
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: orderTypeStopLoss, TimeInForce: 1, Qty: 1, StopPrice: 99,
+	})
+	// 缺少触发价
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 4, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: orderTypeStopLoss, TimeInForce: 1, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		n := 0
+		for _, e := range ev {
+			if e.Type == EventOrderRejected {
+				n++
+			}
+		}
+		return n >= 2
+	})
+	reasons := map[int64]string{}
+	for _, e := range events {
+		if e.Type == EventOrderRejected {
+			data := e.Data.(*OrderRejectedData)
+			reasons[data.OrderID] = data.Reason
+		}
+	}
+	if reasons[3] != "STOP_WOULD_TRIGGER_IMMEDIATELY" || reasons[4] != "INVALID_STOP_PRICE" {
+		t.Fatalf("unexpected reject reasons: %+v", reasons)
+	}
+}
+
+func TestCancelStopOrder(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: orderTypeTakeProfit, TimeInForce: 1, Qty: 3, StopPrice: 90,
+	})
+	submitOrFail(t, engine, &Command{Type: CmdCancelOrder, OrderID: 1, UserID: 10, Symbol: "BTCUSDT"})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return len(ev) >= 2 })
+	canceled := findEvent(events, EventOrderCanceled)
+	if canceled == nil {
+		t.Fatalf("expected cancel event, got %v", events[1].Type)
+	}
+	data := canceled.Data.(*OrderCanceledData)
+	if data.OrderID != 1 || data.LeavesQty != 3 || data.Reason != "USER_CANCELED" {
+		t.Fatalf("unexpected cancel data: %+v", data)
+	}
+}
+
+func TestAddOrderDirectStopOrder(t *testing.T) {
+	engine := NewEngine("BTCUSDT", 10, 10)
+
+	if err := engine.AddOrderDirect(&types.OpenOrder{
+		OrderID: 1, UserID: 10, Symbol: "BTCUSDT", Side: "SELL", OrderType: "STOP_LOSS",
+		TimeInForce: "IOC", LeavesQty: 5, StopPrice: 90,
+	}); err != nil {
+		t.Fatalf("add stop order: %v", err)
+	}
+	if err := engine.AddOrderDirect(&types.OpenOrder{
+		OrderID: 2, UserID: 10, Symbol: "BTCUSDT", Side: "SELL", OrderType: "STOP_LOSS_LIMIT",
+		TimeInForce: "GTC", Price: 89, LeavesQty: 5, StopPrice: 90, Triggered: true,
+	}); err != nil {
+		t.Fatalf("add triggered stop-limit order: %v", err)
+	}
+	if err := engine.AddOrderDirect(&types.OpenOrder{
+		OrderID: 3, UserID: 10, Symbol: "BTCUSDT", Side: "SELL", OrderType: "TAKE_PROFIT",
+		TimeInForce: "IOC", LeavesQty: 5, StopPrice: 90, Triggered: true,
+	}); err == nil {
+		t.Fatal("expected triggered market stop to be rejected")
+	}
+	if err := engine.AddOrderDirect(&types.OpenOrder{
+		OrderID: 4, UserID: 10, Symbol: "BTCUSDT", Side: "SELL", OrderType: "TRAILING_STOP",
+		TimeInForce: "IOC", LeavesQty: 5, StopPrice: 95, TrailingDelta: 5, TrailingExtreme: 100,
+	}); err != nil {
+		t.Fatalf("add trailing stop: %v", err)
+	}
+	if err := engine.AddOrderDirect(&types.OpenOrder{
+		OrderID: 5, UserID: 10, Symbol: "BTCUSDT", Side: "SELL", OrderType: "TRAILING_STOP",
+		TimeInForce: "IOC", LeavesQty: 5,
+	}); err == nil {
+		t.Fatal("expected trailing stop without callback to be rejected")
+	}
+
+	if engine.triggers.Get(1) == nil {
+		t.Fatal("expected untriggered stop in trigger book")
+	}
+	if stop := engine.triggers.Get(4); stop == nil || stop.StopPrice != 95 || stop.TrailingExtreme != 100 {
+		t.Fatalf("expected trailing stop restored with its extreme, got %+v", stop)
+	}
+	if engine.book.GetOrder(2) == nil {
+		t.Fatal("expected triggered stop-limit in order book")
+	}
+}
+
+func TestAddOrderDirectRecoversTriggerState(t *testing.T) {
+	engine := NewEngine("BTCUSDT", 10000, 10000)
+	defer engine.Stop()
+
+	// 最新成交价已跌破触发价：恢复后的第一条命令触发止损单
+	engine.SetLastPrice(90)
+	if err := engine.AddOrderDirect(&types.OpenOrder{
+		OrderID: 3, UserID: 20, Symbol: "BTCUSDT", Side: "BUY", OrderType: "LIMIT",
+		TimeInForce: "GTC", Price: 80, OrigQty: 5, LeavesQty: 5,
+	}); err != nil {
+		t.Fatalf("add resting order: %v", err)
+	}
+	if err := engine.AddOrderDirect(&types.OpenOrder{
+		OrderID: 1, UserID: 10, Symbol: "BTCUSDT", Side: "SELL", OrderType: "STOP_LOSS",
+		TimeInForce: "GTC", LeavesQty: 2, StopPrice: 95,
+	}); err != nil {
+		t.Fatalf("add stop order: %v", err)
+	}
+	// 已触发的 IOC 止损限价单不挂单，撤销剩余数量
+	if err := engine.AddOrderDirect(&types.OpenOrder{
+		OrderID: 2, UserID: 10, Symbol: "BTCUSDT", Side: "BUY", OrderType: "STOP_LOSS_LIMIT",
+		TimeInForce: "IOC", Price: 120, OrigQty: 5, LeavesQty: 3, StopPrice: 110, Triggered: true,
+	}); err != nil {
+		t.Fatalf("add triggered IOC stop-limit: %v", err)
+	}
+	engine.Start()
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool { return findEvent(ev, EventOrderFilled) != nil })
+	canceled := findEvent(events, EventOrderCanceled)
+	if canceled == nil || events[0] != canceled {
+		t.Fatalf("expected IOC remainder canceled first, got %+v", events)
+	}
+	if data := canceled.Data.(*OrderCanceledData); data.OrderID != 2 || data.LeavesQty != 3 || data.Reason != "IOC_EXPIRED" {
+		t.Fatalf("unexpected cancel: %+v", data)
+	}
+	if engine.book.GetOrder(2) != nil {
+		t.Fatal("triggered IOC stop-limit must not rest")
+	}
+	triggered := findEvent(events, EventStopOrderTriggered)
+	if triggered == nil || triggered.Data.(*StopOrderTriggeredData).LastPrice != 90 {
+		t.Fatalf("expected stop triggered at restored last price, got %+v", triggered)
+	}
+	if filled := findEvent(events, EventOrderFilled).Data.(*OrderFilledData); filled.OrderID != 1 {
+		t.Fatalf("expected stop filled, got %+v", filled)
+	}
+}
+
+func TestTrailStop(t *testing.T) {
+	// 卖出跟踪止损：涨到 110 激活，回调 5
+	sell := &Command{OrderID: 1, Side: orderbook.SideSell, OrderType: orderTypeTrailingStop, TrailingDelta: 5, ActivationPrice: 110}
+	if trail(sell, 100) || sell.TrailingExtreme != 0 {
+		t.Fatalf("expected sell trailing stop inactive below activation: %+v", sell)
+	}
+	if !trail(sell, 110) || sell.TrailingExtreme != 110 || sell.StopPrice != 105 {
+		t.Fatalf("unexpected activation: %+v", sell)
+	}
+	if trail(sell, 108) || sell.StopPrice != 105 {
+		t.Fatalf("stop price must not move down: %+v", sell)
+	}
+	if !trail(sell, 115) || sell.StopPrice != 110 {
+		t.Fatalf("unexpected trail up: %+v", sell)
+	}
+
+	// 买入跟踪止损：回调 1%，按第一笔成交价激活
+	buy := &Command{OrderID: 2, Side: orderbook.SideBuy, OrderType: orderTypeTrailingStop, TrailingBps: 100}
+	if !trail(buy, 1000) || buy.StopPrice != 1010 {
+		t.Fatalf("unexpected buy activation: %+v", buy)
+	}
+	if trail(buy, 1005) || !trail(buy, 900) || buy.StopPrice != 909 {
+		t.Fatalf("unexpected buy trail: %+v", buy)
+	}
+
+	for _, cmd := range []*Command{
+		{TrailingDelta: 5, TrailingBps: 10},
+		{},
+		{TrailingBps: maxTrailingBps},
+		{TrailingDelta: 5, ActivationPrice: -1},
+	} {
+		if validTrailing(cmd) {
+			t.Fatalf("expected invalid trailing params: %+v", cmd)
+		}
+	}
+}
+
+func TestTriggerBookTrailing(t *testing.T) {
+	tb := newTriggerBook()
+	tb.Add(&Command{OrderID: 1, UserID: 10, Side: orderbook.SideSell, OrderType: orderTypeTrailingStop, TrailingDelta: 5})
+	tb.Add(&Command{OrderID: 2, UserID: 20, Side: orderbook.SideSell, OrderType: orderTypeTrailingStop, TrailingDelta: 5, ActivationPrice: 120})
+	tb.Add(&Command{OrderID: 3, UserID: 10, Side: orderbook.SideSell, OrderType: orderTypeStopLoss, StopPrice: 90})
+
+	if moved := tb.Trail(100); len(moved) != 1 || moved[0].OrderID != 1 {
+		t.Fatalf("expected only order 1 activated, got %+v", moved)
+	}
+	if got := tb.PopTriggered(100); len(got) != 0 {
+		t.Fatalf("expected no trigger at 100, got %+v", got)
+	}
+	if all := tb.All(); len(all) != 3 || all[0].OrderID != 3 || all[2].OrderID != 2 {
+		t.Fatalf("unexpected export order: %+v", all)
+	}
+	got := tb.PopTriggered(95)
+	if len(got) != 1 || got[0].OrderID != 1 || tb.Get(1) != nil {
+		t.Fatalf("expected activated trailing stop triggered, got %+v", got)
+	}
+	if removed := tb.RemoveUser(20, 0); len(removed) != 1 || removed[0].OrderID != 2 || tb.Len() != 1 {
+		t.Fatalf("unexpected remove user: %+v", removed)
+	}
+}
+
+func TestTrailingStopTriggeredByTrade(t *testing.T) {
+	engine := newTestEngine()
+	defer engine.Stop()
+
+	trade := func(id, price int64) {
+		submitOrFail(t, engine, &Command{
+			Type: CmdNewOrder, OrderID: id, UserID: 10, Symbol: "BTCUSDT",
+			Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: price, Qty: 1,
+		})
+		submitOrFail(t, engine, &Command{
+			Type: CmdNewOrder, OrderID: id + 1, UserID: 20, Symbol: "BTCUSDT",
+			Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: price, Qty: 1,
+		})
+	}
+	trade(1, 100)
+
+	// 卖出跟踪止损：按最新价 100 激活，回调 5
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 3, UserID: 30, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: orderTypeTrailingStop, TimeInForce: 1, Qty: 2, TrailingDelta: 5,
+	})
+	trade(4, 110)
+	// 承接触发后市价卖单的买盘，随后在 104 成交触发
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 6, UserID: 20, Symbol: "BTCUSDT",
+		Side: orderbook.SideBuy, OrderType: 1, TimeInForce: 1, Price: 104, Qty: 5,
+	})
+	submitOrFail(t, engine, &Command{
+		Type: CmdNewOrder, OrderID: 7, UserID: 10, Symbol: "BTCUSDT",
+		Side: orderbook.SideSell, OrderType: 1, TimeInForce: 1, Price: 104, Qty: 1,
+	})
+
+	events := collectUntil(t, engine, 2*time.Second, func(ev []*Event) bool {
+		for _, e := range ev {
+			if e.Type == EventOrderFilled && e.Data.(*OrderFilledData).OrderID == 3 {
+				return true
+			}
+		}
+		return false
+	})
+	var stops []int64
+	for _, e := range events {
+		if e.Type == EventTrailingStopUpdated {
+			data := e.Data.(*TrailingStopUpdatedData)
+			if data.OrderID != 3 {
+				t.Fatalf("unexpected trailing update: %+v", data)
+			}
+			stops = append(stops, data.StopPrice)
+		}
+	}
+	if len(stops) != 2 || stops[0] != 95 || stops[1] != 105 {
+		t.Fatalf("unexpected trailing stop prices: %v", stops)
+	}
+	triggered := findEvent(events, EventStopOrderTriggered).Data.(*StopOrderTriggeredData)
+	if triggered.OrderID != 3 || triggered.StopPrice != 105 || triggered.LastPrice != 104 {
+		t.Fatalf("unexpected trigger data: %+v", triggered)
+	}
+}
@@ -5,13 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/exchange/common/pkg/health"
 	"github.com/exchange/common/pkg/logger"
+	commonredis "github.com/exchange/common/pkg/redis"
+	"github.com/exchange/common/pkg/shard"
 	"github.com/exchange/matching/internal/engine"
 	"github.com/exchange/matching/internal/metrics"
 	"github.com/exchange/matching/internal/orderbook"
@@ -19,6 +24,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// symbolPattern 交易对格式（大写字母、数字与下划线）
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9_]{2,20}$`)
+
 // OrderLoader 订单加载器接口（用于启动时恢复订单簿）
 type OrderLoader interface {
 	// LoadOpenOrders 加载指定 symbol 的所有 OPEN 状态订单
@@ -26,6 +34,10 @@ type OrderLoader interface {
 	LoadOpenOrders(ctx context.Context, symbol string) ([]*OpenOrder, error)
 	// ListActiveSymbols 列出所有有活跃订单的交易对
 	ListActiveSymbols(ctx context.Context) ([]string, error)
+	// ListAuctionSymbols 列出处于集合竞价状态的交易对
+	ListAuctionSymbols(ctx context.Context) ([]string, error)
+	// LoadLastPrice 加载指定 symbol 的最新成交价（没有成交时为 0）
+	LoadLastPrice(ctx context.Context, symbol string) (int64, error)
 }
 
 // OpenOrder 启动恢复用的挂单快照（来自数据库）
@@ -35,16 +47,44 @@ type OpenOrder = types.OpenOrder
 
 // OrderMessage 订单消息（从 Redis Stream 接收）
 type OrderMessage struct {
-	Type          string `json:"type"` // NEW / CANCEL
-	OrderID       int64  `json:"orderId"`
-	ClientOrderID string `json:"clientOrderId"`
-	UserID        int64  `json:"userId"`
-	Symbol        string `json:"symbol"`
-	Side          string `json:"side"`        // BUY / SELL
-	OrderType     string `json:"orderType"`   // LIMIT / MARKET
-	TimeInForce   string `json:"timeInForce"` // GTC / IOC / FOK / POST_ONLY
-	Price         int64  `json:"price"`       // 最小单位整数
-	Qty           int64  `json:"qty"`
+	Type            string `json:"type"` // NEW / CANCEL / AMEND / SET_STATUS / RESUME_BREAKER / MASS_CANCEL / EXPIRE_ORDERS / MASS_QUOTE
+	OrderID         int64  `json:"orderId"`
+	ClientOrderID   string `json:"clientOrderId"`
+	UserID          int64  `json:"userId"`
+	Symbol          string `json:"symbol"`
+	Side            string `json:"side"`        // BUY / SELL
+	OrderType       string `json:"orderType"`   // LIMIT / MARKET / STOP_LOSS / STOP_LOSS_LIMIT / TAKE_PROFIT / TRAILING_STOP
+	TimeInForce     string `json:"timeInForce"` // GTC / IOC / FOK / POST_ONLY / GTD / DAY
+	Price           int64  `json:"price"`       // 最小单位整数
+	Qty             int64  `json:"qty"`
+	QuoteOrderQty   int64  `json:"quoteOrderQty,omitempty"`   // 按金额市价买单的金额（qty 为 0，qtyScale 必填）
+	StopPrice       int64  `json:"stopPrice,omitempty"`       // 条件单触发价
+	TrailingDelta   int64  `json:"trailingDelta,omitempty"`   // 跟踪止损回调价差
+	TrailingBps     int64  `json:"trailingBps,omitempty"`     // 跟踪止损回调比例（基点）
+	ActivationPrice int64  `json:"activationPrice,omitempty"` // 跟踪止损激活价
+	STPMode         string `json:"stpMode,omitempty"`         // EXPIRE_TAKER / EXPIRE_MAKER / EXPIRE_BOTH / DECREMENT
+	AmendID         int64  `json:"amendId,omitempty"`         // 改单请求 ID（AMEND：price/qty 为新值，0 表示不修改）
+	DisplayQty      int64  `json:"displayQty,omitempty"`      // 冰山单每次展示数量
+	Status          string `json:"status,omitempty"`          // SET_STATUS：TRADING / HALT / CANCEL_ONLY / AUCTION
+	BreakerID       int64  `json:"breakerId,omitempty"`       // RESUME_BREAKER：要结束的熔断竞价
+	RequestID       int64  `json:"requestId,omitempty"`       // MASS_CANCEL：批量撤单请求 ID（side 为空表示双边）；MASS_QUOTE：报价请求 ID
+	ExpireTime      int64  `json:"expireTime,omitempty"`      // GTD/DAY 到期时间（毫秒）
+	ListID          int64  `json:"listId,omitempty"`          // 订单列表 ID（OCO）
+	SiblingOrderID  int64  `json:"siblingOrderId,omitempty"`  // 同列表的另一条订单，本订单成交或触发时撤销
+
+	// MASS_QUOTE：整组替换的报价单（timeInForce / stpMode 对整组生效）
+	Quotes     []QuoteMessage `json:"quotes,omitempty"`
+	BuyFrozen  int64          `json:"buyFrozen,omitempty"`  // 本次新冻结的 quote 资产
+	SellFrozen int64          `json:"sellFrozen,omitempty"` // 本次新冻结的 base 资产
+	QtyScale   int64          `json:"qtyScale,omitempty"`   // 数量精度倍数（10^qtyPrecision），按金额市价买单同样使用
+}
+
+// QuoteMessage 批量报价中的单个报价单
+type QuoteMessage struct {
+	OrderID int64  `json:"orderId"`
+	Side    string `json:"side"` // BUY / SELL
+	Price   int64  `json:"price"`
+	Qty     int64  `json:"qty"`
 }
 
 // EventMessage 事件消息（发送到 Redis Stream）
@@ -65,17 +105,40 @@ type Handler struct {
 
 	orderStream string // 输入流名称
 	eventStream string // 输出流名称
+	seqKey      string // 每个 symbol 已发布的最大事件序列号（Hash）
 	group       string // 消费者组
 	consumer    string // 消费者名称
 	dedupeTTL   time.Duration
 
 	orderLoader  OrderLoader
+	snapshots    SnapshotStore
+	snapshotIvl  time.Duration
+	breaker      engine.BreakerConfig
+	shards       *shard.Router
+	shardID      int
 	recoveryDone chan struct{}
 	ctxMu        sync.RWMutex
 	ctx          context.Context
 
 	forwardWg sync.WaitGroup // 跟踪 forwardEvents goroutine
 	loop      health.LoopMonitor
+
+	publishedMu sync.Mutex
+	published   map[string]int64 // symbol -> 已发布到事件流的最大序列号
+
+	// 主备（lease 为空时单实例运行）
+	lease      *commonredis.Lock
+	leaseKey   string
+	leaseOwner string
+	leaseTTL   time.Duration
+	leader     atomic.Bool
+	leaseLost  chan struct{}
+	lostOnce   sync.Once
+	followFrom string               // 备机下一次跟随的订单流起点（XRANGE 格式）
+	followed   map[string]string    // 备机：symbol -> 已应用的最后一条消息 ID（只有按快照对齐的交易对才有记录）
+	resyncAt   map[string]time.Time // 备机：未对齐的交易对下一次尝试按快照对齐的时间
+	deferredMu sync.Mutex
+	deferred   map[string]*OrderMessage
 }
 
 const (
@@ -102,6 +165,21 @@ type Config struct {
 	DedupeTTL   time.Duration
 	OrderLoader OrderLoader
 	Logger      *logger.Logger
+
+	// 快照（可选）：SnapshotStore 为空时仅从订单库恢复
+	SnapshotStore    SnapshotStore
+	SnapshotInterval time.Duration
+
+	// 波动熔断（BandBps 为 0 时不启用）
+	Breaker engine.BreakerConfig
+
+	// 分片（可选）：Shards 为空时负责所有交易对，OrderStream 应为本分片的订单流
+	Shards  *shard.Router
+	ShardID int
+
+	// 主备（可选）：LeaseTTL 为 0 时单实例运行；同一订单流的实例竞争 LeaseKey 租约，持有者为主机
+	LeaseKey string
+	LeaseTTL time.Duration
 }
 
 // NewHandler 创建处理器
@@ -114,18 +192,39 @@ func NewHandler(redisClient *redis.Client, cfg *Config) *Handler {
 	if log == nil {
 		log = logger.New("matching", nil)
 	}
-	return &Handler{
+	h := &Handler{
 		redis:        redisClient,
 		engines:      make(map[string]*engine.Engine),
 		log:          log,
 		orderStream:  cfg.OrderStream,
 		eventStream:  cfg.EventStream,
+		seqKey:       cfg.EventStream + ":seq",
 		group:        cfg.Group,
 		consumer:     cfg.Consumer,
 		dedupeTTL:    dedupeTTL,
 		orderLoader:  cfg.OrderLoader,
+		snapshots:    cfg.SnapshotStore,
+		snapshotIvl:  cfg.SnapshotInterval,
+		breaker:      cfg.Breaker,
+		shards:       cfg.Shards,
+		shardID:      cfg.ShardID,
 		recoveryDone: make(chan struct{}),
+		published:    make(map[string]int64),
+		followed:     make(map[string]string),
+		resyncAt:     make(map[string]time.Time),
+		deferred:     make(map[string]*OrderMessage),
 	}
+	if cfg.LeaseTTL > 0 {
+		h.leaseKey = cfg.LeaseKey
+		if h.leaseKey == "" {
+			h.leaseKey = cfg.OrderStream + ":leader"
+		}
+		h.leaseOwner = cfg.Consumer + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+		h.leaseTTL = cfg.LeaseTTL
+		h.lease = commonredis.NewLock(&commonredis.Client{Client: redisClient}, h.leaseKey, h.leaseOwner, cfg.LeaseTTL)
+		h.leaseLost = make(chan struct{})
+	}
+	return h
 }
 
 // Start 启动处理器
@@ -140,8 +239,22 @@ func (h *Handler) Start(ctx context.Context) error {
 	h.ctx = ctx
 	h.ctxMu.Unlock()
 
+	if h.lease != nil {
+		acquired, err := h.lease.Acquire(ctx)
+		if err != nil {
+			return fmt.Errorf("acquire leader lease: %w", err)
+		}
+		h.setLeader(acquired)
+		if !acquired {
+			if err := h.initFollow(ctx); err != nil {
+				return fmt.Errorf("init follower: %w", err)
+			}
+			h.log.Info("leader lease held by another instance, starting as standby")
+		}
+	}
+
 	// 恢复订单簿（在开始消费新消息之前）
-	h.log.Info("recovering order books from database")
+	h.log.Info("recovering order books")
 	if err := h.recoverOrderBooks(ctx); err != nil {
 		h.log.WithError(err).Warn("recover order books warning")
 		// 不返回错误，允许服务继续启动
@@ -151,13 +264,37 @@ func (h *Handler) Start(ctx context.Context) error {
 
 	h.loop.Tick()
 
-	// 启动消费循环
-	go h.consumeLoop(ctx)
+	// 启动消费循环（备机先跟随主机，获得租约后转为消费）
+	switch {
+	case h.lease == nil:
+		go h.consumeLoop(ctx)
+	case h.leader.Load():
+		go h.renewLoop(ctx)
+		go h.consumeLoop(ctx)
+	default:
+		go h.followLoop(ctx)
+	}
+	if h.snapshots != nil {
+		go h.snapshotLoop(ctx)
+	}
 
 	return nil
 }
 
+// recoverOrderBooks 优先从快照恢复并重放订单流，没有快照的 symbol 从订单库恢复
+//
+// 备机只保留快照恢复的结果：订单库中的订单簿与订单流位置、事件序列号都无法与主机对齐，
+// 其余交易对在跟随时按快照对齐，成为主机时再从订单库恢复。
 func (h *Handler) recoverOrderBooks(ctx context.Context) error {
+	restored := h.restoreSnapshots(ctx)
+	if !h.IsLeader() {
+		return nil
+	}
+	return h.recoverFromDatabase(ctx, restored)
+}
+
+// recoverFromDatabase 从订单库恢复 restored 以外的交易对
+func (h *Handler) recoverFromDatabase(ctx context.Context, restored map[string]bool) error {
 	if h.orderLoader == nil {
 		return nil
 	}
@@ -166,16 +303,100 @@ func (h *Handler) recoverOrderBooks(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("list active symbols: %w", err)
 	}
+	if symbols, err = h.ownedSymbols(ctx, symbols); err != nil {
+		return err
+	}
 
+	recovered := make([]string, 0, len(symbols))
 	for _, symbol := range symbols {
+		if restored[symbol] {
+			continue
+		}
 		if err := h.recoverSymbol(ctx, symbol); err != nil {
 			h.log.WithError(err).WithField("symbol", symbol).Warn("recover symbol error")
 			// 继续恢复其他 symbol，不中断
+			continue
+		}
+		recovered = append(recovered, symbol)
+	}
+
+	// 快照已记录竞价状态；订单库恢复的 symbol 按 symbol_configs 状态重新进入竞价
+	auctions, err := h.orderLoader.ListAuctionSymbols(ctx)
+	if err != nil {
+		return fmt.Errorf("list auction symbols: %w", err)
+	}
+	if auctions, err = h.ownedSymbols(ctx, auctions); err != nil {
+		return err
+	}
+	inAuction := make(map[string]bool, len(auctions))
+	for _, symbol := range auctions {
+		if restored[symbol] {
+			continue
+		}
+		inAuction[symbol] = true
+		if err := h.setStatus(ctx, symbol, engine.StatusAuction); err != nil {
+			return fmt.Errorf("restore auction %s: %w", symbol, err)
+		}
+	}
+
+	// 熔断竞价状态不在订单库中：恢复后盘口交叉的 symbol 立即以单一价格撮合
+	for _, symbol := range recovered {
+		if inAuction[symbol] || !h.crossed(symbol) {
+			continue
+		}
+		if err := h.setStatus(ctx, symbol, engine.StatusAuction); err != nil {
+			return fmt.Errorf("uncross %s: %w", symbol, err)
+		}
+		if err := h.setStatus(ctx, symbol, engine.StatusTrading); err != nil {
+			return fmt.Errorf("uncross %s: %w", symbol, err)
 		}
 	}
 	return nil
 }
 
+// ownedSymbols 过滤出分配给本分片的交易对
+func (h *Handler) ownedSymbols(ctx context.Context, symbols []string) ([]string, error) {
+	if h.shards == nil {
+		return symbols, nil
+	}
+	owned := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		id, err := h.shards.Shard(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+		if id == h.shardID {
+			owned = append(owned, symbol)
+		}
+	}
+	return owned, nil
+}
+
+// owns 交易对是否分配给本分片（查询失败按不属于处理）
+func (h *Handler) owns(ctx context.Context, symbol string) bool {
+	owned, err := h.ownedSymbols(ctx, []string{symbol})
+	if err != nil {
+		h.log.WithError(err).WithField("symbol", symbol).Warn("lookup shard error")
+		return false
+	}
+	return len(owned) == 1
+}
+
+func (h *Handler) setStatus(ctx context.Context, symbol string, status int) error {
+	cmd := &engine.Command{Type: engine.CmdSetStatus, Symbol: symbol, Status: status}
+	return h.submitBlocking(ctx, h.getOrCreateEngine(symbol), cmd)
+}
+
+// crossed 订单簿是否交叉（买一价不低于卖一价）
+func (h *Handler) crossed(symbol string) bool {
+	eng := h.engineFor(symbol)
+	if eng == nil {
+		return false
+	}
+	bids, asks := eng.Depth(1)
+	return len(bids) > 0 && len(asks) > 0 && bids[0].Price >= asks[0].Price
+}
+
 func (h *Handler) recoverSymbol(ctx context.Context, symbol string) error {
 	orders, err := h.orderLoader.LoadOpenOrders(ctx, symbol)
 	if err != nil {
@@ -184,8 +405,15 @@ func (h *Handler) recoverSymbol(ctx context.Context, symbol string) error {
 	if len(orders) == 0 {
 		return nil
 	}
+	// 条件单按最新成交价触发，恢复后与重启前一致
+	lastPrice, err := h.orderLoader.LoadLastPrice(ctx, symbol)
+	if err != nil {
+		return err
+	}
 
-	eng := h.getOrCreateEngine(symbol)
+	// 订单全部写入后再启动引擎
+	eng := h.newEngine(symbol)
+	eng.SetLastPrice(lastPrice)
 	for _, order := range orders {
 		if order == nil {
 			continue
@@ -197,6 +425,7 @@ func (h *Handler) recoverSymbol(ctx context.Context, symbol string) error {
 			})
 		}
 	}
+	h.startEngine(symbol, eng)
 
 	h.log.Infof("recovered orders", map[string]interface{}{
 		"symbol": symbol, "count": len(orders),
@@ -228,6 +457,9 @@ func (h *Handler) consumeLoop(ctx context.Context) {
 
 	for {
 		h.loop.Tick()
+		if !h.IsLeader() {
+			return
+		}
 
 		select {
 		case <-ctx.Done():
@@ -296,11 +528,17 @@ func (h *Handler) processMessage(ctx context.Context, msg redis.XMessage) {
 		return
 	}
 
+	if h.skipWithoutEngine(&orderMsg) {
+		h.ack(ctx, msg.ID)
+		return
+	}
+
 	// 获取或创建引擎
 	eng := h.getOrCreateEngine(orderMsg.Symbol)
 
 	// 转换为命令
-	cmd := h.toCommand(&orderMsg)
+	cmd := ToCommand(&orderMsg)
+	cmd.StreamID = msg.ID
 
 	// 提交命令
 	if err := eng.Submit(cmd); err != nil {
@@ -310,15 +548,25 @@ func (h *Handler) processMessage(ctx context.Context, msg redis.XMessage) {
 		return
 	}
 
-	h.markDedupeDone(ctx, dedupeKey)
+	h.markDedupeDone(ctx, dedupeKey, msg.ID)
 	h.ack(ctx, msg.ID)
 }
 
+// skipWithoutEngine 引擎不存在说明不在集合竞价中，除进入竞价外的状态变更无需处理
+func (h *Handler) skipWithoutEngine(msg *OrderMessage) bool {
+	return NeedsEngine(msg) && h.engineFor(msg.Symbol) == nil
+}
+
+// NeedsEngine 消息只作用于已存在的引擎（退出竞价的状态变更、熔断恢复、订单到期），不单独创建引擎
+func NeedsEngine(msg *OrderMessage) bool {
+	return msg.Type == "SET_STATUS" && msg.Status != "AUCTION" || msg.Type == "RESUME_BREAKER" || msg.Type == "EXPIRE_ORDERS"
+}
+
 func (h *Handler) acquireDedupe(ctx context.Context, msg *OrderMessage) (string, dedupeAction) {
-	if h.dedupeTTL <= 0 || msg == nil || msg.OrderID <= 0 {
+	key := h.dedupeKey(msg)
+	if key == "" {
 		return "", dedupeActionProcess
 	}
-	key := fmt.Sprintf("dedupe:%s:%d", strings.ToLower(msg.Type), msg.OrderID)
 	timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	ok, err := h.redis.SetNX(timeoutCtx, key, dedupeStateProcessing, h.processingDedupeTTL()).Result()
@@ -338,19 +586,47 @@ func (h *Handler) acquireDedupe(ctx context.Context, msg *OrderMessage) (string,
 		h.log.WithError(err).Warn("dedupe read status error")
 		return key, dedupeActionProcess
 	}
-	if status == dedupeStateDone {
+	if _, done := parseDedupeDone(status); done {
 		return key, dedupeActionAlreadyDone
 	}
 	return key, dedupeActionInFlight
 }
 
-func (h *Handler) markDedupeDone(ctx context.Context, dedupeKey string) {
+func (h *Handler) dedupeKey(msg *OrderMessage) string {
+	if h.dedupeTTL <= 0 {
+		return ""
+	}
+	return DedupeKey(msg)
+}
+
+// DedupeKey 消息的去重键，同一键的消息只生效一次；空表示不去重
+func DedupeKey(msg *OrderMessage) string {
+	if msg != nil && msg.Type == "MASS_CANCEL" && msg.RequestID > 0 {
+		// 同一请求按交易对拆成多条消息
+		return fmt.Sprintf("dedupe:mass_cancel:%d:%s", msg.RequestID, msg.Symbol)
+	}
+	if msg != nil && msg.Type == "MASS_QUOTE" && msg.RequestID > 0 {
+		return fmt.Sprintf("dedupe:mass_quote:%d", msg.RequestID)
+	}
+	if msg == nil || msg.OrderID <= 0 {
+		return ""
+	}
+	key := fmt.Sprintf("dedupe:%s:%d", strings.ToLower(msg.Type), msg.OrderID)
+	if msg.AmendID > 0 {
+		// 同一订单可多次改单，按改单请求去重
+		key = fmt.Sprintf("%s:%d", key, msg.AmendID)
+	}
+	return key
+}
+
+// markDedupeDone 标记已处理，值中记录实际生效的消息 ID（快照重放据此跳过重复消息）
+func (h *Handler) markDedupeDone(ctx context.Context, dedupeKey, msgID string) {
 	if dedupeKey == "" || h.dedupeTTL <= 0 {
 		return
 	}
 	timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
-	if err := h.redis.Set(timeoutCtx, dedupeKey, dedupeStateDone, h.dedupeTTL).Err(); err != nil {
+	if err := h.redis.Set(timeoutCtx, dedupeKey, dedupeStateDone+":"+msgID, h.dedupeTTL).Err(); err != nil {
 		h.log.WithError(err).WithField("dedupeKey", dedupeKey).Warn("mark dedupe done error")
 	}
 }
@@ -378,6 +654,11 @@ func (h *Handler) releaseDedupe(ctx context.Context, dedupeKey string) {
 }
 
 func (h *Handler) processPending(ctx context.Context) error {
+	return h.claimPending(ctx, defaultClaimMinIdle)
+}
+
+// claimPending 接管空闲超过 minIdle 的 pending 消息，重试次数过多的转入死信流
+func (h *Handler) claimPending(ctx context.Context, minIdle time.Duration) error {
 	if summary, err := h.redis.XPending(ctx, h.orderStream, h.group).Result(); err == nil {
 		metrics.SetStreamPending(h.orderStream, h.group, summary.Count)
 	}
@@ -396,7 +677,7 @@ func (h *Handler) processPending(ctx context.Context) error {
 	var ids []string
 	dlqIDs := make(map[string]int64)
 	for _, entry := range pending {
-		if entry.Idle >= defaultClaimMinIdle {
+		if entry.Idle >= minIdle {
 			ids = append(ids, entry.ID)
 			if entry.RetryCount > defaultMaxStreamRetries {
 				dlqIDs[entry.ID] = entry.RetryCount
@@ -411,7 +692,7 @@ func (h *Handler) processPending(ctx context.Context) error {
 		Stream:   h.orderStream,
 		Group:    h.group,
 		Consumer: h.consumer,
-		MinIdle:  defaultClaimMinIdle,
+		MinIdle:  minIdle,
 		Messages: ids,
 	}).Result()
 	if err != nil {
@@ -468,7 +749,39 @@ func (h *Handler) getOrCreateEngine(symbol string) *engine.Engine {
 		return eng
 	}
 
-	eng = engine.NewEngine(symbol, 10000, 10000)
+	eng = h.newEngine(symbol)
+	h.startEngineLocked(symbol, eng)
+	return eng
+}
+
+// newEngine 创建交易对引擎（未启动）
+//
+// 恢复在启动前写入订单簿，由 startEngine 启动；启动后只有引擎 goroutine 写订单簿。
+func (h *Handler) newEngine(symbol string) *engine.Engine {
+	eng := engine.NewEngine(symbol, 10000, 10000)
+	eng.SetBreaker(h.breaker, h.requestBreakerResume)
+	eng.SetExpiryRequester(h.requestExpiry)
+	// 延续重启前的事件序列号，保证下游看到的 seq 单调递增
+	if seq, err := h.loadSeq(context.Background(), symbol); err != nil {
+		h.log.WithError(err).WithField("symbol", symbol).Warn("load event seq error")
+	} else if seq > 0 {
+		eng.SetSeq(seq)
+		h.setPublished(symbol, seq)
+	}
+	return eng
+}
+
+// startEngine 启动 newEngine 创建的引擎并登记，替换交易对已有的引擎
+func (h *Handler) startEngine(symbol string, eng *engine.Engine) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if old, ok := h.engines[symbol]; ok {
+		old.Stop()
+	}
+	h.startEngineLocked(symbol, eng)
+}
+
+func (h *Handler) startEngineLocked(symbol string, eng *engine.Engine) {
 	eng.Start()
 
 	// 启动事件转发
@@ -482,7 +795,6 @@ func (h *Handler) getOrCreateEngine(symbol string) *engine.Engine {
 	go h.forwardEvents(evtCtx, eng)
 
 	h.engines[symbol] = eng
-	return eng
 }
 
 func (h *Handler) forwardEvents(ctx context.Context, eng *engine.Engine) {
@@ -497,40 +809,41 @@ func (h *Handler) forwardEvents(ctx context.Context, eng *engine.Engine) {
 			if event == nil {
 				continue
 			}
-			eventMsg := &EventMessage{
-				Type:      eventTypeToString(event.Type),
-				Symbol:    event.Symbol,
-				Seq:       event.Seq,
-				Timestamp: event.Timestamp,
-				Data:      event.Data,
-			}
-
-			data, err := json.Marshal(eventMsg)
+			data, err := json.Marshal(NewEventMessage(event))
 			if err != nil {
 				h.log.WithError(err).Warn("marshal event error")
 				continue
 			}
 
-			if err := h.publishEvent(ctx, data); err != nil && ctx.Err() == nil {
-				h.log.WithError(err).Warn("send event error")
+			if err := h.deliverEvent(ctx, eng, event.Symbol, event.Seq, data); err != nil {
+				if ctx.Err() == nil {
+					h.log.WithError(err).Warn("send event error")
+				}
+				continue
 			}
+			h.setPublished(event.Symbol, event.Seq)
 		}
 	}
 }
 
-func (h *Handler) publishEvent(ctx context.Context, payload []byte) error {
+// publishEvent 发布事件，并在同一事务中记录该 symbol 已发布的序列号
+func (h *Handler) publishEvent(ctx context.Context, symbol string, seq int64, payload []byte) error {
 	backoff := 200 * time.Millisecond
 	for {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 		sendCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
-		_, err := h.redis.XAdd(sendCtx, &redis.XAddArgs{
-			Stream: h.eventStream,
-			Values: map[string]interface{}{
-				"data": string(payload),
-			},
-		}).Result()
+		_, err := h.redis.TxPipelined(sendCtx, func(pipe redis.Pipeliner) error {
+			pipe.XAdd(sendCtx, &redis.XAddArgs{
+				Stream: h.eventStream,
+				Values: map[string]interface{}{
+					"data": string(payload),
+				},
+			})
+			pipe.HSet(sendCtx, h.seqKey, symbol, seq)
+			return nil
+		})
 		cancel()
 		if err == nil {
 			return nil
@@ -548,7 +861,57 @@ func (h *Handler) publishEvent(ctx context.Context, payload []byte) error {
 	}
 }
 
-func (h *Handler) toCommand(msg *OrderMessage) *engine.Command {
+// requestBreakerResume 熔断竞价到期：写入订单流，按消息顺序结束竞价（重复请求由引擎忽略）
+func (h *Handler) requestBreakerResume(symbol string, breakerID int64) {
+	h.requestInternal(&OrderMessage{Type: "RESUME_BREAKER", Symbol: symbol, BreakerID: breakerID}, "request breaker resume error")
+}
+
+// requestExpiry GTD/DAY 订单到期：写入订单流，按消息时间撤销到期订单（重复请求由引擎忽略）
+func (h *Handler) requestExpiry(symbol string) {
+	h.requestInternal(&OrderMessage{Type: "EXPIRE_ORDERS", Symbol: symbol}, "request order expiry error")
+}
+
+// requestInternal 引擎内部请求写入订单流，与用户订单共用同一顺序（备机暂存到成为主机）
+func (h *Handler) requestInternal(msg *OrderMessage, errMsg string) {
+	if !h.IsLeader() {
+		h.deferRequest(msg)
+		return
+	}
+	h.ctxMu.RLock()
+	ctx := h.ctx
+	h.ctxMu.RUnlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := h.redis.XAdd(timeoutCtx, &redis.XAddArgs{
+		Stream: h.orderStream,
+		Values: map[string]interface{}{
+			"data": string(data),
+		},
+	}).Err(); err != nil && ctx.Err() == nil {
+		h.log.WithError(err).WithField("symbol", msg.Symbol).Warn(errMsg)
+	}
+}
+
+// loadSeq 读取 symbol 已发布的最大事件序列号，不存在返回 0
+func (h *Handler) loadSeq(ctx context.Context, symbol string) (int64, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	seq, err := h.redis.HGet(timeoutCtx, h.seqKey, symbol).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return seq, err
+}
+
+// ToCommand 订单流消息转换为引擎命令（不含 StreamID）
+func ToCommand(msg *OrderMessage) *engine.Command {
 	cmd := &engine.Command{
 		OrderID:       msg.OrderID,
 		ClientOrderID: msg.ClientOrderID,
@@ -562,6 +925,50 @@ func (h *Handler) toCommand(msg *OrderMessage) *engine.Command {
 	case "CANCEL":
 		cmd.Type = engine.CmdCancelOrder
 		return cmd
+	case "AMEND":
+		cmd.Type = engine.CmdAmendOrder
+		cmd.AmendID = msg.AmendID
+		cmd.Price = msg.Price
+		cmd.Qty = msg.Qty
+		return cmd
+	case "SET_STATUS":
+		cmd.Type = engine.CmdSetStatus
+		cmd.Status = symbolStatus(msg.Status)
+		return cmd
+	case "RESUME_BREAKER":
+		cmd.Type = engine.CmdResumeBreaker
+		cmd.BreakerID = msg.BreakerID
+		return cmd
+	case "EXPIRE_ORDERS":
+		cmd.Type = engine.CmdExpireOrders
+		return cmd
+	case "MASS_CANCEL":
+		cmd.Type = engine.CmdMassCancel
+		cmd.RequestID = msg.RequestID
+		switch msg.Side {
+		case "BUY":
+			cmd.Side = orderbook.SideBuy
+		case "SELL":
+			cmd.Side = orderbook.SideSell
+		}
+		return cmd
+	case "MASS_QUOTE":
+		cmd.Type = engine.CmdMassQuote
+		cmd.RequestID = msg.RequestID
+		cmd.BuyFrozen = msg.BuyFrozen
+		cmd.SellFrozen = msg.SellFrozen
+		cmd.QtyScale = msg.QtyScale
+		cmd.Quotes = make([]engine.QuoteEntry, 0, len(msg.Quotes))
+		for _, quote := range msg.Quotes {
+			entry := engine.QuoteEntry{OrderID: quote.OrderID, Price: quote.Price, Qty: quote.Qty}
+			switch quote.Side {
+			case "BUY":
+				entry.Side = orderbook.SideBuy
+			case "SELL":
+				entry.Side = orderbook.SideSell
+			}
+			cmd.Quotes = append(cmd.Quotes, entry)
+		}
 	default:
 		cmd.Type = engine.CmdNewOrder
 	}
@@ -580,6 +987,14 @@ func (h *Handler) toCommand(msg *OrderMessage) *engine.Command {
 		cmd.OrderType = 1
 	case "MARKET":
 		cmd.OrderType = 2
+	case "STOP_LOSS":
+		cmd.OrderType = 3
+	case "STOP_LOSS_LIMIT":
+		cmd.OrderType = 4
+	case "TAKE_PROFIT":
+		cmd.OrderType = 5
+	case "TRAILING_STOP":
+		cmd.OrderType = 6
 	default:
 		cmd.OrderType = 1
 	}
@@ -594,22 +1009,73 @@ func (h *Handler) toCommand(msg *OrderMessage) *engine.Command {
 		cmd.TimeInForce = 3
 	case "POST_ONLY":
 		cmd.TimeInForce = 4
+	case "GTD", "DAY":
+		// 到期前按 GTC 挂单，DAY 的到期时间由订单服务按交易时段计算
+		cmd.TimeInForce = 1
+		cmd.ExpireTimeMs = msg.ExpireTime
 	default:
 		cmd.TimeInForce = 1
 	}
 
+	// STPMode（缺省按 EXPIRE_TAKER）
+	switch msg.STPMode {
+	case "EXPIRE_MAKER":
+		cmd.STPMode = orderbook.STPExpireMaker
+	case "EXPIRE_BOTH":
+		cmd.STPMode = orderbook.STPExpireBoth
+	case "DECREMENT":
+		cmd.STPMode = orderbook.STPDecrement
+	default:
+		cmd.STPMode = orderbook.STPExpireTaker
+	}
+
 	cmd.Price = msg.Price
 	cmd.Qty = msg.Qty
+	cmd.QuoteOrderQty = msg.QuoteOrderQty
+	cmd.QtyScale = msg.QtyScale
+	cmd.StopPrice = msg.StopPrice
+	cmd.TrailingDelta = msg.TrailingDelta
+	cmd.TrailingBps = msg.TrailingBps
+	cmd.ActivationPrice = msg.ActivationPrice
+	cmd.DisplayQty = msg.DisplayQty
+	cmd.ListID = msg.ListID
+	cmd.SiblingOrderID = msg.SiblingOrderID
 
 	return cmd
 }
 
+func symbolStatus(status string) int {
+	switch status {
+	case "TRADING":
+		return engine.StatusTrading
+	case "HALT":
+		return engine.StatusHalt
+	case "CANCEL_ONLY":
+		return engine.StatusCancelOnly
+	case "AUCTION":
+		return engine.StatusAuction
+	default:
+		return 0
+	}
+}
+
 func (h *Handler) ack(ctx context.Context, id string) {
 	if err := h.redis.XAck(ctx, h.orderStream, h.group, id).Err(); err != nil {
 		h.log.WithError(err).WithField("msgId", id).Warn("ack message error")
 	}
 }
 
+// NewEventMessage 引擎事件转换为事件流消息
+func NewEventMessage(event *engine.Event) *EventMessage {
+	return &EventMessage{
+		Type:      eventTypeToString(event.Type),
+		Symbol:    event.Symbol,
+		Seq:       event.Seq,
+		Timestamp: event.Timestamp,
+		Data:      event.Data,
+	}
+}
+
 func eventTypeToString(t engine.EventType) string {
 	switch t {
 	case engine.EventOrderAccepted:
@@ -624,6 +1090,30 @@ func eventTypeToString(t engine.EventType) string {
 		return "ORDER_FILLED"
 	case engine.EventOrderPartiallyFilled:
 		return "ORDER_PARTIALLY_FILLED"
+	case engine.EventStopOrderAccepted:
+		return "STOP_ORDER_ACCEPTED"
+	case engine.EventStopOrderTriggered:
+		return "STOP_ORDER_TRIGGERED"
+	case engine.EventOrderReduced:
+		return "ORDER_REDUCED"
+	case engine.EventOrderAmended:
+		return "ORDER_AMENDED"
+	case engine.EventAmendRejected:
+		return "AMEND_REJECTED"
+	case engine.EventAuctionIndicative:
+		return "AUCTION_INDICATIVE"
+	case engine.EventAuctionUncrossed:
+		return "AUCTION_UNCROSSED"
+	case engine.EventCircuitBreaker:
+		return "CIRCUIT_BREAKER"
+	case engine.EventMassCanceled:
+		return "MASS_CANCELED"
+	case engine.EventMassQuoted:
+		return "MASS_QUOTED"
+	case engine.EventMassQuoteRejected:
+		return "MASS_QUOTE_REJECTED"
+	case engine.EventTrailingStopUpdated:
+		return "TRAILING_STOP_UPDATED"
 	default:
 		return "UNKNOWN"
 	}
@@ -631,7 +1121,7 @@ func eventTypeToString(t engine.EventType) string {
 
 // GetDepth 获取深度
 func (h *Handler) GetDepth(symbol string, limit int) (bids, asks []orderbook.PriceQty, ok bool) {
-	if symbol == "" {
+	if symbol == "" || !h.owns(context.Background(), symbol) {
 		return nil, nil, false
 	}
 	eng := h.getOrCreateEngine(symbol)
@@ -639,12 +1129,49 @@ func (h *Handler) GetDepth(symbol string, limit int) (bids, asks []orderbook.Pri
 	return bids, asks, true
 }
 
+// NormalizeSymbol 规范化外部传入的交易对（去空白、转大写），格式不合法时 ok 为 false
+func NormalizeSymbol(symbol string) (string, bool) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	return symbol, symbolPattern.MatchString(symbol)
+}
+
+// GetBookL3 获取逐笔订单簿快照
+//
+// 只查询已有引擎：接口对外开放，不能因任意交易对创建引擎。
+func (h *Handler) GetBookL3(ctx context.Context, symbol string) (*engine.L3Book, bool, error) {
+	symbol, ok := NormalizeSymbol(symbol)
+	if !ok {
+		return nil, false, nil
+	}
+	eng := h.engineFor(symbol)
+	if eng == nil || !h.owns(ctx, symbol) {
+		return nil, false, nil
+	}
+	book, err := eng.BookL3(ctx)
+	if err != nil {
+		return nil, true, err
+	}
+	return book, true, nil
+}
+
 func (h *Handler) ResetEngines(symbol string) int {
+	return h.stopEngines(symbol, true)
+}
+
+// stopEngines 停止引擎；dropSnapshot 为 true 时同时删除快照（管理端重置）
+func (h *Handler) stopEngines(symbol string, dropSnapshot bool) int {
 	h.mu.Lock()
 
 	resetOne := func(key string, eng *engine.Engine) {
 		eng.Stop()
 		delete(h.engines, key)
+		h.clearPublished(key)
+		if dropSnapshot && h.snapshots != nil {
+			// 重置后的空订单簿不应在重启时被旧快照覆盖
+			if err := h.snapshots.Delete(context.Background(), key); err != nil {
+				h.log.WithError(err).WithField("symbol", key).Warn("delete snapshot error")
+			}
+		}
 	}
 
 	if symbol != "" {
@@ -674,6 +1201,6 @@ func (h *Handler) ResetEngines(symbol string) int {
 // Stop 优雅关闭处理器
 func (h *Handler) Stop() {
 	h.log.Info("stopping handler")
-	h.ResetEngines("")
+	h.stopEngines("", false)
 	h.log.Info("handler stopped")
 }
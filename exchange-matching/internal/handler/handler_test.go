@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exchange/matching/internal/engine"
+)
+
+func TestGetBookL3_DoesNotCreateEngines(t *testing.T) {
+	_, client := newHATestRedis(t)
+	h := NewHandler(client, &Config{OrderStream: haOrderStream, EventStream: haEventStream, Group: "matching", Consumer: "a"})
+	t.Cleanup(h.Stop)
+	ctx := context.Background()
+
+	for _, symbol := range []string{"ETHUSDT", "not a symbol", "", "ABCDEFGHIJKLMNOPQRSTUVWXYZ"} {
+		if _, ok, err := h.GetBookL3(ctx, symbol); ok || err != nil {
+			t.Fatalf("%q: expected not found, got ok=%v err=%v", symbol, ok, err)
+		}
+	}
+	h.mu.RLock()
+	count := len(h.engines)
+	h.mu.RUnlock()
+	if count != 0 {
+		t.Fatalf("expected no engines created, got %d", count)
+	}
+
+	h.getOrCreateEngine("BTCUSDT")
+	book, ok, err := h.GetBookL3(ctx, " btcusdt ")
+	if !ok || err != nil || book == nil {
+		t.Fatalf("expected book for existing engine, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	cases := map[string]bool{"BTCUSDT": true, "BTC_USDT": true, " ethusdt": true, "BTC/USDT": false, "": false, "A": false}
+	for in, want := range cases {
+		if _, ok := NormalizeSymbol(in); ok != want {
+			t.Fatalf("%q: expected %v, got %v", in, want, ok)
+		}
+	}
+}
+
+// fakeOrderLoader 订单库恢复数据
+type fakeOrderLoader struct {
+	orders    []*OpenOrder
+	lastPrice int64
+}
+
+func (f *fakeOrderLoader) LoadOpenOrders(_ context.Context, _ string) ([]*OpenOrder, error) {
+	return f.orders, nil
+}
+
+func (f *fakeOrderLoader) ListActiveSymbols(_ context.Context) ([]string, error) {
+	return []string{"BTCUSDT"}, nil
+}
+
+func (f *fakeOrderLoader) ListAuctionSymbols(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeOrderLoader) LoadLastPrice(_ context.Context, _ string) (int64, error) {
+	return f.lastPrice, nil
+}
+
+func TestRecoverSymbol_RestoresLastPrice(t *testing.T) {
+	_, client := newHATestRedis(t)
+	loader := &fakeOrderLoader{
+		orders: []*OpenOrder{
+			{OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Side: "SELL", OrderType: "LIMIT", TimeInForce: "GTC", Price: 110, OrigQty: 5, LeavesQty: 5},
+			{OrderID: 2, UserID: 1, Symbol: "BTCUSDT", Side: "SELL", OrderType: "STOP_LOSS", TimeInForce: "GTC", StopPrice: 90, OrigQty: 5, LeavesQty: 5},
+		},
+		lastPrice: 100,
+	}
+	h := NewHandler(client, &Config{OrderStream: haOrderStream, EventStream: haEventStream, Group: "matching", Consumer: "a", OrderLoader: loader})
+	t.Cleanup(h.Stop)
+	ctx := context.Background()
+
+	if err := h.recoverSymbol(ctx, "BTCUSDT"); err != nil {
+		t.Fatalf("recover symbol: %v", err)
+	}
+	snap, err := h.engineFor("BTCUSDT").Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if snap.LastPrice != 100 || len(snap.Orders) != 1 || len(snap.Stops) != 1 {
+		t.Fatalf("unexpected recovered state: lastPrice=%d orders=%d stops=%d", snap.LastPrice, len(snap.Orders), len(snap.Stops))
+	}
+}
+
+func TestRestoreSnapshots_OldVersionFallsBackToDatabase(t *testing.T) {
+	_, client := newHATestRedis(t)
+	store := newMemSnapshotStore()
+	store.snaps["BTCUSDT"] = &engine.Snapshot{Version: engine.SnapshotVersion - 1, Symbol: "BTCUSDT", StreamID: "1-0"}
+	h := NewHandler(client, &Config{OrderStream: haOrderStream, EventStream: haEventStream, Group: "matching", Consumer: "a", SnapshotStore: store})
+	t.Cleanup(h.Stop)
+
+	if restored := h.restoreSnapshots(context.Background()); restored["BTCUSDT"] {
+		t.Fatal("old snapshot version must not be restored")
+	}
+	if h.engineFor("BTCUSDT") != nil {
+		t.Fatal("expected no engine left from the old snapshot")
+	}
+}
+
+// 恢复期间引擎不能与恢复 goroutine 同时写订单簿（go test -race）
+func TestRecoverSymbol_BuildsBookBeforeStart(t *testing.T) {
+	_, client := newHATestRedis(t)
+	loader := &fakeOrderLoader{
+		orders: []*OpenOrder{
+			{OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Side: "BUY", OrderType: "STOP_LOSS_LIMIT", TimeInForce: "IOC", Price: 120, StopPrice: 110, OrigQty: 5, LeavesQty: 3, Triggered: true},
+		},
+		lastPrice: 100,
+	}
+	for i := int64(2); i <= 2001; i++ {
+		loader.orders = append(loader.orders, &OpenOrder{
+			OrderID: i, UserID: 2, Symbol: "BTCUSDT", Side: "SELL", OrderType: "LIMIT", TimeInForce: "GTC", Price: 1000 + i, OrigQty: 1, LeavesQty: 1,
+		})
+	}
+	h := NewHandler(client, &Config{OrderStream: haOrderStream, EventStream: haEventStream, Group: "matching", Consumer: "a", OrderLoader: loader})
+	t.Cleanup(h.Stop)
+	ctx := context.Background()
+
+	if err := h.recoverSymbol(ctx, "BTCUSDT"); err != nil {
+		t.Fatalf("recover symbol: %v", err)
+	}
+	waitFor(t, 2*time.Second, "IOC remainder canceled", func() bool {
+		events := readEvents(t, client)
+		return len(events) == 1 && events[0].Type == "ORDER_CANCELED"
+	})
+	snap, err := h.engineFor("BTCUSDT").Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if len(snap.Orders) != 2000 {
+		t.Fatalf("expected 2000 resting orders, got %d", len(snap.Orders))
+	}
+	if _, asks := h.engineFor("BTCUSDT").Depth(1); len(asks) != 1 || asks[0].Price != 1002 {
+		t.Fatalf("expected recovered depth published, got %+v", asks)
+	}
+}
@@ -0,0 +1,495 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/exchange/matching/internal/engine"
+	"github.com/exchange/matching/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	followPollInterval  = 100 * time.Millisecond
+	releaseTimeout      = 2 * time.Second
+	resyncRetryInterval = time.Second
+)
+
+var errNotLeader = errors.New("not leader")
+
+// publishFencedScript 仍持有租约且序列号未发布时写入事件流并记录序列号
+//
+// 返回 1 已发布，2 序列号已发布（跳过），0 未持有租约。
+var publishFencedScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+local published = tonumber(redis.call('HGET', KEYS[3], ARGV[2]) or '0')
+if tonumber(ARGV[3]) <= published then
+	return 2
+end
+redis.call('XADD', KEYS[2], '*', 'data', ARGV[4])
+redis.call('HSET', KEYS[3], ARGV[2], ARGV[3])
+return 1
+`)
+
+// IsLeader 是否为主机（未启用主备时始终为 true）
+func (h *Handler) IsLeader() bool {
+	return h.lease == nil || h.leader.Load()
+}
+
+// LeaseLost 主机失去租约时关闭；引擎状态已超前于订单流跟随位置，调用方应重启实例以备机身份恢复
+func (h *Handler) LeaseLost() <-chan struct{} {
+	return h.leaseLost
+}
+
+func (h *Handler) setLeader(leader bool) {
+	h.leader.Store(leader)
+	metrics.SetLeader(leader)
+}
+
+// loseLeadership 停止消费与发布
+func (h *Handler) loseLeadership(reason string) {
+	if !h.leader.Load() {
+		return
+	}
+	h.setLeader(false)
+	h.log.WithField("reason", reason).Error("lost leader lease")
+	h.lostOnce.Do(func() { close(h.leaseLost) })
+}
+
+// renewLoop 主机续租；续租失败超过租约时间或租约已被他人持有时失去主机身份
+func (h *Handler) renewLoop(ctx context.Context) {
+	ticker := time.NewTicker(h.leaseTTL / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			// 正常退出时释放租约，备机无需等待租约过期
+			releaseCtx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+			if err := h.lease.Release(releaseCtx); err != nil {
+				h.log.WithError(err).Warn("release leader lease error")
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		}
+		if !h.leader.Load() {
+			return
+		}
+		ok, err := h.lease.Extend(ctx, h.leaseTTL)
+		if err == nil && ok {
+			renewed = time.Now()
+			continue
+		}
+		if err != nil && time.Since(renewed) < h.leaseTTL {
+			h.log.WithError(err).Warn("renew leader lease error")
+			continue
+		}
+		if err != nil && ctx.Err() != nil {
+			continue
+		}
+		h.loseLeadership("lease renewal failed")
+		return
+	}
+}
+
+// initFollow 备机启动：记录跟随起点（第一条尚未 ACK 的消息，或最后投递的消息之后）
+//
+// 需在恢复订单簿之前调用，快照重放在同一位置停止。
+func (h *Handler) initFollow(ctx context.Context) error {
+	lastDelivered, err := h.lastDeliveredID(ctx)
+	if err != nil {
+		return err
+	}
+	if lastDelivered == "" {
+		h.followFrom = "-"
+		return nil
+	}
+	pending, err := h.pendingIDs(ctx)
+	if err != nil {
+		return err
+	}
+	h.followFrom = "(" + lastDelivered
+	lowest := ""
+	for id := range pending {
+		if lowest == "" || engine.CompareStreamIDs(id, lowest) < 0 {
+			lowest = id
+		}
+	}
+	if lowest != "" {
+		h.followFrom = lowest
+	}
+	return nil
+}
+
+// followLoop 备机：跟随主机已处理的消息，租约空出后接管
+func (h *Handler) followLoop(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.loop.SetError(fmt.Errorf("panic: %v", r))
+			h.log.Errorf("followLoop panic", map[string]interface{}{
+				"panic": r, "stack": string(debug.Stack()),
+			})
+		}
+	}()
+
+	acquireEvery := h.leaseTTL / 3
+	var lastAttempt time.Time
+	for {
+		h.loop.Tick()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(lastAttempt) >= acquireEvery {
+			lastAttempt = time.Now()
+			acquired, err := h.lease.Acquire(ctx)
+			if err != nil && ctx.Err() == nil {
+				h.log.WithError(err).Warn("acquire leader lease error")
+			}
+			if acquired {
+				h.promote(ctx)
+				return
+			}
+		}
+
+		applied, err := h.followOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			h.loop.SetError(err)
+			h.log.WithError(err).Warn("follow order stream error")
+		}
+		if applied > 0 && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// followOnce 按顺序应用主机已 ACK 的消息，遇到仍在 pending 中的消息时停下等待
+func (h *Handler) followOnce(ctx context.Context) (int, error) {
+	lastDelivered, err := h.lastDeliveredID(ctx)
+	if err != nil || lastDelivered == "" {
+		return 0, err
+	}
+	msgs, err := h.redis.XRangeN(ctx, h.orderStream, h.followFrom, lastDelivered, replayBatchSize).Result()
+	if err != nil {
+		return 0, fmt.Errorf("xrange %s: %w", h.orderStream, err)
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	pending, err := h.pendingIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, msg := range msgs {
+		if pending[msg.ID] {
+			// 主机可能尚未处理完，保持与主机相同的顺序
+			h.followFrom = msg.ID
+			return applied, nil
+		}
+		if err := h.applyFollowed(ctx, msg, pending); err != nil {
+			return applied, err
+		}
+		h.followFrom = "(" + msg.ID
+		applied++
+	}
+	return applied, nil
+}
+
+// applyFollowed 按主机的处理结果应用一条消息（去重规则与快照重放相同）
+//
+// 未对齐的交易对先尝试按快照对齐，仍未对齐时跳过该消息。
+func (h *Handler) applyFollowed(ctx context.Context, msg redis.XMessage, pending map[string]bool) error {
+	orderMsg, ok := DecodeOrderMessage(msg)
+	if !ok {
+		return nil
+	}
+	through, aligned := h.followed[orderMsg.Symbol]
+	if !aligned {
+		if err := h.resyncSymbol(ctx, orderMsg.Symbol, msg.ID, pending); err != nil && ctx.Err() == nil {
+			h.log.WithError(err).WithField("symbol", orderMsg.Symbol).Warn("align standby from snapshot error")
+		}
+		if through, aligned = h.followed[orderMsg.Symbol]; !aligned {
+			return nil
+		}
+	}
+	if engine.CompareStreamIDs(msg.ID, through) <= 0 {
+		// 快照或快照重放已包含该消息
+		return nil
+	}
+	if h.replayDecision(ctx, orderMsg, msg.ID, pending[msg.ID]) != replayApply || h.skipWithoutEngine(orderMsg) {
+		return nil
+	}
+	cmd := ToCommand(orderMsg)
+	cmd.StreamID = msg.ID
+	if err := h.submitBlocking(ctx, h.engineFor(orderMsg.Symbol), cmd); err != nil {
+		return err
+	}
+	h.followed[orderMsg.Symbol] = msg.ID
+	return nil
+}
+
+// resyncSymbol 按快照对齐交易对：恢复快照，重放快照之后、before 之前的消息（before 为空时重放到最后投递的消息）
+//
+// 没有快照或订单流已被裁剪时保持未对齐，之后的消息间隔 resyncRetryInterval 再尝试。
+func (h *Handler) resyncSymbol(ctx context.Context, symbol, before string, pending map[string]bool) error {
+	if h.snapshots == nil || time.Now().Before(h.resyncAt[symbol]) {
+		return nil
+	}
+	h.resyncAt[symbol] = time.Now().Add(resyncRetryInterval)
+
+	snap, err := h.snapshots.Load(ctx, symbol)
+	if err != nil || snap == nil || snap.StreamID == "" {
+		return err
+	}
+	// 在未启动的引擎上恢复，启动后替换旧引擎：旧引擎上进行中的逐笔快照等命令不受影响
+	eng := h.newEngine(symbol)
+	if err := eng.Restore(snap); err != nil {
+		eng.Stop()
+		h.dropEngine(symbol)
+		return fmt.Errorf("restore snapshot: %w", err)
+	}
+	published, err := h.loadSeq(ctx, symbol)
+	if err != nil {
+		eng.Stop()
+		h.dropEngine(symbol)
+		return fmt.Errorf("load event seq: %w", err)
+	}
+	published = max(published, snap.Seq)
+	eng.MuteEventsThrough(published)
+	h.startEngine(symbol, eng)
+	h.setPublished(symbol, published)
+
+	h.followed[symbol] = snap.StreamID
+	replayed, err := h.replaySymbol(ctx, symbol, before, pending)
+	if err != nil {
+		delete(h.followed, symbol)
+		h.dropEngine(symbol)
+		return err
+	}
+	delete(h.resyncAt, symbol)
+	h.log.Infof("aligned from snapshot", map[string]interface{}{
+		"symbol": symbol, "seq": snap.Seq, "streamID": snap.StreamID, "replayed": replayed,
+	})
+	return nil
+}
+
+// replaySymbol 重放交易对对齐位置之后、before 之前的消息
+func (h *Handler) replaySymbol(ctx context.Context, symbol, before string, pending map[string]bool) (int, error) {
+	start := h.followed[symbol]
+	end := "(" + before
+	if before == "" {
+		lastDelivered, err := h.lastDeliveredID(ctx)
+		if err != nil || lastDelivered == "" {
+			return 0, err
+		}
+		end = lastDelivered
+	} else if engine.CompareStreamIDs(before, start) <= 0 {
+		return 0, nil
+	}
+
+	// 快照之后的消息已被裁剪时无法重放
+	first, err := h.redis.XRangeN(ctx, h.orderStream, "-", "+", 1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("xrange %s: %w", h.orderStream, err)
+	}
+	if len(first) > 0 && engine.CompareStreamIDs(first[0].ID, start) > 0 {
+		return 0, fmt.Errorf("order stream trimmed past snapshot %s", start)
+	}
+
+	replayed := 0
+	for {
+		msgs, err := h.redis.XRangeN(ctx, h.orderStream, "("+start, end, replayBatchSize).Result()
+		if err != nil {
+			return replayed, fmt.Errorf("xrange %s: %w", h.orderStream, err)
+		}
+		for _, msg := range msgs {
+			orderMsg, ok := DecodeOrderMessage(msg)
+			if !ok || orderMsg.Symbol != symbol {
+				continue
+			}
+			if h.replayDecision(ctx, orderMsg, msg.ID, pending[msg.ID]) != replayApply {
+				continue
+			}
+			cmd := ToCommand(orderMsg)
+			cmd.StreamID = msg.ID
+			if err := h.submitBlocking(ctx, h.engineFor(symbol), cmd); err != nil {
+				return replayed, err
+			}
+			h.followed[symbol] = msg.ID
+			replayed++
+		}
+		if len(msgs) < replayBatchSize {
+			return replayed, nil
+		}
+		start = msgs[len(msgs)-1].ID
+	}
+}
+
+// promote 备机获得租约：补齐已投递的消息，接管上一任主机遗留的 pending 消息后开始消费
+func (h *Handler) promote(ctx context.Context) {
+	h.setLeader(true)
+	h.log.Info("acquired leader lease, promoting to leader")
+	go h.renewLoop(ctx)
+
+	if err := h.catchUp(ctx); err != nil && ctx.Err() == nil {
+		h.loop.SetError(err)
+		h.log.WithError(err).Error("catch up order stream error")
+	}
+	if err := h.recoverUnaligned(ctx); err != nil && ctx.Err() == nil {
+		h.log.WithError(err).Warn("recover unaligned symbols error")
+	}
+	h.flushDeferred()
+
+	// 上一任主机的租约已过期，其 pending 消息无需再等待默认的 claim 时间
+	if err := h.claimPending(ctx, h.leaseTTL); err != nil && ctx.Err() == nil {
+		h.log.WithError(err).Warn("claim pending after promotion error")
+	}
+	h.consumeLoop(ctx)
+}
+
+// catchUp 应用跟随位置到最后投递消息之间的全部消息；pending 消息按去重记录判断，未完成的留给消费循环
+func (h *Handler) catchUp(ctx context.Context) error {
+	lastDelivered, err := h.lastDeliveredID(ctx)
+	if err != nil || lastDelivered == "" {
+		return err
+	}
+	pending, err := h.pendingIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		msgs, err := h.redis.XRangeN(ctx, h.orderStream, h.followFrom, lastDelivered, replayBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("xrange %s: %w", h.orderStream, err)
+		}
+		for _, msg := range msgs {
+			if err := h.applyFollowed(ctx, msg, pending); err != nil {
+				return err
+			}
+			h.followFrom = "(" + msg.ID
+		}
+		if len(msgs) < replayBatchSize {
+			return nil
+		}
+	}
+}
+
+// recoverUnaligned 接管时恢复跟随期间未能对齐的交易对：有快照的按快照对齐并重放，其余与冷启动一样从订单库恢复
+//
+// 上一任主机已失去租约，此时读取的已发布序列号不会再变化，从订单库恢复的交易对从该序列号继续。
+func (h *Handler) recoverUnaligned(ctx context.Context) error {
+	if h.snapshots != nil {
+		pending, err := h.pendingIDs(ctx)
+		if err != nil {
+			return err
+		}
+		symbols, err := h.snapshots.ListSymbols(ctx)
+		if err != nil {
+			return fmt.Errorf("list snapshots: %w", err)
+		}
+		if symbols, err = h.ownedSymbols(ctx, symbols); err != nil {
+			return err
+		}
+		for _, symbol := range symbols {
+			if _, ok := h.followed[symbol]; ok {
+				continue
+			}
+			delete(h.resyncAt, symbol)
+			if err := h.resyncSymbol(ctx, symbol, "", pending); err != nil && ctx.Err() == nil {
+				h.log.WithError(err).WithField("symbol", symbol).Warn("align from snapshot error")
+			}
+		}
+	}
+
+	aligned := make(map[string]bool, len(h.followed))
+	for symbol := range h.followed {
+		aligned[symbol] = true
+	}
+	return h.recoverFromDatabase(ctx, aligned)
+}
+
+// deferRequest 备机的引擎定时请求（熔断恢复、订单到期）留到成为主机后写入订单流
+//
+// 主机已写入的同类请求会重复，引擎忽略过期或重复的请求。
+func (h *Handler) deferRequest(msg *OrderMessage) {
+	h.deferredMu.Lock()
+	h.deferred[msg.Type+":"+msg.Symbol] = msg
+	h.deferredMu.Unlock()
+}
+
+func (h *Handler) flushDeferred() {
+	h.deferredMu.Lock()
+	deferred := h.deferred
+	h.deferred = make(map[string]*OrderMessage)
+	h.deferredMu.Unlock()
+	for _, msg := range deferred {
+		h.requestInternal(msg, "flush deferred request error")
+	}
+}
+
+// deliverEvent 发布事件
+//
+// 主备模式下只有主机发布；备机等待主机发布同一序列号，成为主机后发布尚未发布的部分。
+func (h *Handler) deliverEvent(ctx context.Context, eng *engine.Engine, symbol string, seq int64, payload []byte) error {
+	if h.lease == nil {
+		return h.publishEvent(ctx, symbol, seq, payload)
+	}
+	for {
+		if h.publishedSeq(symbol) >= seq {
+			return nil
+		}
+		if h.leader.Load() {
+			err := h.publishFenced(ctx, symbol, seq, payload)
+			if err == nil {
+				return nil
+			}
+			if !errors.Is(err, errNotLeader) && ctx.Err() == nil {
+				h.log.WithError(err).Warn("send event error")
+			}
+		} else if published, err := h.loadSeq(ctx, symbol); err == nil {
+			h.setPublished(symbol, published)
+			if published >= seq {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-eng.Done():
+			return fmt.Errorf("engine stopped")
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// publishFenced 校验租约后发布事件
+func (h *Handler) publishFenced(ctx context.Context, symbol string, seq int64, payload []byte) error {
+	sendCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	result, err := publishFencedScript.Run(sendCtx, h.redis,
+		[]string{h.leaseKey, h.eventStream, h.seqKey},
+		h.leaseOwner, symbol, strconv.FormatInt(seq, 10), string(payload),
+	).Int()
+	if err != nil {
+		return err
+	}
+	if result == 0 {
+		h.loseLeadership("lease not held when publishing")
+		return errNotLeader
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/exchange/matching/internal/engine"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	haOrderStream = "orders"
+	haEventStream = "events"
+	haLeaseKey    = "orders:leader"
+)
+
+// memSnapshotStore 副本共享的快照目录
+type memSnapshotStore struct {
+	mu    sync.Mutex
+	snaps map[string]*engine.Snapshot
+}
+
+func newMemSnapshotStore() *memSnapshotStore {
+	return &memSnapshotStore{snaps: make(map[string]*engine.Snapshot)}
+}
+
+func (s *memSnapshotStore) Save(_ context.Context, snap *engine.Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snaps[snap.Symbol] = snap
+	return nil
+}
+
+func (s *memSnapshotStore) Load(_ context.Context, symbol string) (*engine.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snaps[symbol], nil
+}
+
+func (s *memSnapshotStore) Delete(_ context.Context, symbol string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.snaps, symbol)
+	return nil
+}
+
+func (s *memSnapshotStore) ListSymbols(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	symbols := make([]string, 0, len(s.snaps))
+	for symbol := range s.snaps {
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+func newHATestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+// startHAHandler 启动一个副本，返回停止函数
+func startHAHandler(t *testing.T, client *redis.Client, consumer string, store SnapshotStore, leaseTTL time.Duration) (*Handler, func()) {
+	t.Helper()
+	h := NewHandler(client, &Config{
+		OrderStream:      haOrderStream,
+		EventStream:      haEventStream,
+		Group:            "matching",
+		Consumer:         consumer,
+		SnapshotStore:    store,
+		SnapshotInterval: 50 * time.Millisecond,
+		LeaseKey:         haLeaseKey,
+		LeaseTTL:         leaseTTL,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := h.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("start %s: %v", consumer, err)
+	}
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			h.Stop()
+		})
+	}
+	t.Cleanup(stop)
+	return h, stop
+}
+
+func addOrder(t *testing.T, client *redis.Client, orderID int64, side string, price, qty int64) {
+	t.Helper()
+	data, _ := json.Marshal(&OrderMessage{
+		Type: "NEW", OrderID: orderID, UserID: orderID, Symbol: "BTCUSDT",
+		Side: side, OrderType: "LIMIT", TimeInForce: "GTC", Price: price, Qty: qty,
+	})
+	if err := client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: haOrderStream,
+		Values: map[string]interface{}{"data": string(data)},
+	}).Err(); err != nil {
+		t.Fatalf("add order %d: %v", orderID, err)
+	}
+}
+
+type publishedEvent struct {
+	Type   string          `json:"type"`
+	Symbol string          `json:"symbol"`
+	Seq    int64           `json:"seq"`
+	Data   json.RawMessage `json:"data"`
+}
+
+func readEvents(t *testing.T, client *redis.Client) []publishedEvent {
+	t.Helper()
+	msgs, err := client.XRange(context.Background(), haEventStream, "-", "+").Result()
+	if err != nil && err != redis.Nil {
+		t.Fatalf("read events: %v", err)
+	}
+	events := make([]publishedEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		var event publishedEvent
+		if err := json.Unmarshal([]byte(msg.Values["data"].(string)), &event); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// eventKeys 事件类型与订单（成交取主动方订单），不含每次撮合不同的成交 ID
+func eventKeys(t *testing.T, events []publishedEvent) []string {
+	t.Helper()
+	keys := make([]string, 0, len(events))
+	for _, event := range events {
+		var data struct {
+			OrderID      int64
+			TakerOrderID int64
+		}
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			t.Fatalf("decode event data: %v", err)
+		}
+		keys = append(keys, fmt.Sprintf("%s:%d", event.Type, data.OrderID+data.TakerOrderID))
+	}
+	return keys
+}
+
+// tradesByTaker 成交按主动方订单统计
+func tradesByTaker(t *testing.T, events []publishedEvent) map[int64]int {
+	t.Helper()
+	trades := make(map[int64]int)
+	for _, event := range events {
+		if event.Type != "TRADE_CREATED" {
+			continue
+		}
+		var trade engine.TradeCreatedData
+		if err := json.Unmarshal(event.Data, &trade); err != nil {
+			t.Fatalf("decode trade: %v", err)
+		}
+		trades[trade.TakerOrderID]++
+	}
+	return trades
+}
+
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func depthString(h *Handler) string {
+	eng := h.engineFor("BTCUSDT")
+	if eng == nil {
+		return ""
+	}
+	bids, asks := eng.Depth(10)
+	return fmt.Sprint(bids, asks)
+}
+
+func TestPublishFencedScript(t *testing.T) {
+	mr, client := newHATestRedis(t)
+	ctx := context.Background()
+	mr.Set(haLeaseKey, "owner-a")
+
+	run := func(owner string, seq int64) int {
+		t.Helper()
+		result, err := publishFencedScript.Run(ctx, client,
+			[]string{haLeaseKey, haEventStream, haEventStream + ":seq"},
+			owner, "BTCUSDT", seq, fmt.Sprintf(`{"seq":%d}`, seq),
+		).Int()
+		if err != nil {
+			t.Fatalf("run script: %v", err)
+		}
+		return result
+	}
+
+	if got := run("owner-b", 1); got != 0 {
+		t.Fatalf("stale owner: expected 0, got %d", got)
+	}
+	if n, _ := client.XLen(ctx, haEventStream).Result(); n != 0 {
+		t.Fatalf("stale owner must not publish, got %d events", n)
+	}
+	if got := run("owner-a", 1); got != 1 {
+		t.Fatalf("expected published, got %d", got)
+	}
+	if got := run("owner-a", 1); got != 2 {
+		t.Fatalf("expected seq 1 skipped, got %d", got)
+	}
+	if got := run("owner-a", 2); got != 1 {
+		t.Fatalf("expected seq 2 published, got %d", got)
+	}
+	if n, _ := client.XLen(ctx, haEventStream).Result(); n != 2 {
+		t.Fatalf("expected 2 events, got %d", n)
+	}
+	if seq, _ := client.HGet(ctx, haEventStream+":seq", "BTCUSDT").Int64(); seq != 2 {
+		t.Fatalf("expected published seq 2, got %d", seq)
+	}
+}
+
+func TestStandbyPromote_NoDuplicateTrades(t *testing.T) {
+	_, client := newHATestRedis(t)
+	store := newMemSnapshotStore()
+	leaseTTL := 600 * time.Millisecond
+
+	leader, stopLeader := startHAHandler(t, client, "a", store, leaseTTL)
+	standby, _ := startHAHandler(t, client, "b", store, leaseTTL)
+	if !leader.IsLeader() || standby.IsLeader() {
+		t.Fatalf("expected a leader and a standby")
+	}
+
+	// 备机启动时没有快照：不能自行建簿，等待主机快照对齐
+	addOrder(t, client, 1, "SELL", 100, 10)
+	addOrder(t, client, 2, "BUY", 100, 4)
+	waitFor(t, 2*time.Second, "first trade", func() bool { return tradesByTaker(t, readEvents(t, client))[2] == 1 })
+	waitFor(t, 2*time.Second, "leader snapshot", func() bool {
+		snap, _ := store.Load(context.Background(), "BTCUSDT")
+		return snap != nil && snap.StreamID != ""
+	})
+
+	// 超过重试间隔后的新消息触发备机按快照对齐
+	time.Sleep(resyncRetryInterval + 100*time.Millisecond)
+	addOrder(t, client, 3, "BUY", 90, 1)
+	waitFor(t, 3*time.Second, "standby aligned", func() bool {
+		return depthString(standby) != "" && depthString(standby) == depthString(leader)
+	})
+
+	stopLeader()
+	waitFor(t, 3*time.Second, "standby promoted", standby.IsLeader)
+	// 等待旧主机阻塞中的读取结束，新消息只由新主机消费
+	time.Sleep(1100 * time.Millisecond)
+
+	addOrder(t, client, 4, "BUY", 100, 3)
+	waitFor(t, 3*time.Second, "trade after promotion", func() bool { return tradesByTaker(t, readEvents(t, client))[4] == 1 })
+	time.Sleep(200 * time.Millisecond)
+
+	events := readEvents(t, client)
+	for i, event := range events {
+		if event.Seq != int64(i+1) {
+			t.Fatalf("event %d (%s): expected seq %d, got %d", i, event.Type, i+1, event.Seq)
+		}
+	}
+	want := []string{
+		"ORDER_ACCEPTED:1", "TRADE_CREATED:2", "ORDER_PARTIALLY_FILLED:1", "ORDER_FILLED:2",
+		"ORDER_ACCEPTED:3",
+		"TRADE_CREATED:4", "ORDER_PARTIALLY_FILLED:1", "ORDER_FILLED:4",
+	}
+	if got := eventKeys(t, events); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+}
+
+func TestLeaseLostWhilePublishing(t *testing.T) {
+	mr, client := newHATestRedis(t)
+	// 续租间隔远大于测试时长，由发布脚本发现租约丢失
+	leader, _ := startHAHandler(t, client, "a", newMemSnapshotStore(), 30*time.Second)
+	if !leader.IsLeader() {
+		t.Fatal("expected leader")
+	}
+
+	addOrder(t, client, 1, "SELL", 100, 10)
+	waitFor(t, 2*time.Second, "order accepted", func() bool { return len(readEvents(t, client)) == 1 })
+
+	mr.Set(haLeaseKey, "other-replica")
+	addOrder(t, client, 2, "BUY", 100, 4)
+
+	select {
+	case <-leader.LeaseLost():
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected lease lost")
+	}
+	if leader.IsLeader() {
+		t.Fatal("expected leadership dropped")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if events := readEvents(t, client); len(events) != 1 {
+		t.Fatalf("expected no events published after losing the lease, got %d", len(events))
+	}
+	if seq, _ := client.HGet(context.Background(), haEventStream+":seq", "BTCUSDT").Int64(); seq != 1 {
+		t.Fatalf("expected published seq 1, got %d", seq)
+	}
+}
+
+func TestResyncSymbol_SwapsEngineAfterRestore(t *testing.T) {
+	_, client := newHATestRedis(t)
+	store := newMemSnapshotStore()
+	h := NewHandler(client, &Config{OrderStream: haOrderStream, EventStream: haEventStream, Group: "matching", Consumer: "b", SnapshotStore: store})
+	t.Cleanup(h.Stop)
+	ctx := context.Background()
+
+	leader := engine.NewEngine("BTCUSDT", 10, 10)
+	if err := leader.AddOrderDirect(&OpenOrder{
+		OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Side: "SELL", OrderType: "LIMIT", TimeInForce: "GTC", Price: 100, OrigQty: 5, LeavesQty: 5,
+	}); err != nil {
+		t.Fatalf("add order: %v", err)
+	}
+	leader.Start()
+	defer leader.Stop()
+	snap, err := leader.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	snap.StreamID = "1-0"
+	store.Save(ctx, snap)
+
+	// 旧引擎上持续执行逐笔快照命令，对齐不能与其同时写订单簿（go test -race）
+	old := h.getOrCreateEngine("BTCUSDT")
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				h.GetBookL3(ctx, "BTCUSDT")
+			}
+		}
+	}()
+	err = h.resyncSymbol(ctx, "BTCUSDT", "2-0", nil)
+	close(stop)
+	<-done
+	if err != nil {
+		t.Fatalf("resync: %v", err)
+	}
+
+	if h.engineFor("BTCUSDT") == old {
+		t.Fatal("expected engine replaced")
+	}
+	book, ok, err := h.GetBookL3(ctx, "BTCUSDT")
+	if !ok || err != nil || len(book.Asks) != 1 || book.Asks[0].OrderID != 1 {
+		t.Fatalf("expected restored book, got %+v ok=%v err=%v", book, ok, err)
+	}
+}
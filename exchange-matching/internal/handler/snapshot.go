@@ -0,0 +1,429 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/exchange/matching/internal/engine"
+	"github.com/redis/go-redis/v9"
+)
+
+// SnapshotStore 引擎快照存储接口
+type SnapshotStore interface {
+	Save(ctx context.Context, snap *engine.Snapshot) error
+	// Load 加载指定 symbol 的快照，不存在时返回 nil, nil
+	Load(ctx context.Context, symbol string) (*engine.Snapshot, error)
+	Delete(ctx context.Context, symbol string) error
+	ListSymbols(ctx context.Context) ([]string, error)
+}
+
+const (
+	defaultSnapshotInterval = 30 * time.Second
+	snapshotTimeout         = 5 * time.Second
+	replayBatchSize         = 500
+)
+
+type replayDecision int
+
+const (
+	replayApply replayDecision = iota + 1
+	replaySkip
+)
+
+// restoreSnapshots 加载快照并重放订单流，返回成功恢复的 symbol
+//
+// 任一步骤失败时放弃全部快照恢复结果，由调用方回退到订单库恢复。
+func (h *Handler) restoreSnapshots(ctx context.Context) map[string]bool {
+	restored := make(map[string]bool)
+	if h.snapshots == nil {
+		return restored
+	}
+
+	symbols, err := h.snapshots.ListSymbols(ctx)
+	if err != nil {
+		h.log.WithError(err).Warn("list snapshots error")
+		return restored
+	}
+	// 已迁移到其他分片的交易对不再恢复（快照中的消息 ID 属于本分片的订单流）
+	if symbols, err = h.ownedSymbols(ctx, symbols); err != nil {
+		h.log.WithError(err).Warn("filter snapshots by shard error")
+		return restored
+	}
+
+	snaps := make(map[string]*engine.Snapshot, len(symbols))
+	engines := make(map[string]*engine.Engine, len(symbols))
+	for _, symbol := range symbols {
+		snap, err := h.snapshots.Load(ctx, symbol)
+		if errors.Is(err, engine.ErrSnapshotVersion) {
+			// 旧格式快照：从订单库恢复，下一次快照按当前格式覆盖
+			h.log.WithError(err).WithField("symbol", symbol).Info("snapshot format outdated, recovering from database")
+			continue
+		}
+		if err != nil || snap == nil || snap.StreamID == "" {
+			if err != nil {
+				h.log.WithError(err).WithField("symbol", symbol).Warn("load snapshot error")
+			}
+			continue
+		}
+		eng := h.newEngine(symbol)
+		if err := eng.Restore(snap); err != nil {
+			h.log.WithError(err).WithField("symbol", symbol).Warn("restore snapshot error")
+			h.discardEngine(symbol, eng)
+			continue
+		}
+		snaps[symbol] = snap
+		engines[symbol] = eng
+	}
+	if len(snaps) == 0 {
+		return restored
+	}
+
+	published, err := h.lastPublishedSeqs(ctx, snaps)
+	if err != nil {
+		h.log.WithError(err).Warn("load event seq error, falling back to database")
+		for symbol, eng := range engines {
+			h.discardEngine(symbol, eng)
+		}
+		return restored
+	}
+	for symbol, seq := range published {
+		engines[symbol].MuteEventsThrough(seq)
+		h.setPublished(symbol, seq)
+	}
+	// 订单簿恢复完成后再启动，重放经引擎 goroutine 写入
+	for symbol, eng := range engines {
+		h.startEngine(symbol, eng)
+	}
+
+	replayed, err := h.replayOrderStream(ctx, snaps)
+	if err != nil {
+		h.log.WithError(err).Warn("replay order stream error, falling back to database")
+		h.dropEngines(snaps)
+		for symbol := range snaps {
+			delete(h.followed, symbol)
+		}
+		return restored
+	}
+
+	for symbol, snap := range snaps {
+		restored[symbol] = true
+		if _, ok := h.followed[symbol]; !ok {
+			h.followed[symbol] = snap.StreamID
+		}
+		h.log.Infof("restored from snapshot", map[string]interface{}{
+			"symbol": symbol, "orders": len(snap.Orders), "stops": len(snap.Stops),
+			"seq": snap.Seq, "streamID": snap.StreamID, "replayed": replayed[symbol],
+		})
+	}
+	return restored
+}
+
+// lastPublishedSeqs 读取每个 symbol 已发布的最大序列号
+//
+// 快照之后、崩溃之前发布的事件在重放时会重新生成，需要静默以免重复发布。
+func (h *Handler) lastPublishedSeqs(ctx context.Context, snaps map[string]*engine.Snapshot) (map[string]int64, error) {
+	result := make(map[string]int64, len(snaps))
+	for symbol, snap := range snaps {
+		seq, err := h.loadSeq(ctx, symbol)
+		if err != nil {
+			return nil, fmt.Errorf("load event seq %s: %w", symbol, err)
+		}
+		result[symbol] = max(seq, snap.Seq)
+	}
+	return result, nil
+}
+
+// replayOrderStream 重放快照之后已投递给消费者组的订单消息
+//
+// 仍在 pending 中的消息交给消费循环处理；已完成的消息只有在去重记录指向本消息时才重放，
+// 避免重复投递的同一订单被执行两次。备机在第一条 pending 消息处停止，之后由跟随循环按顺序处理。
+func (h *Handler) replayOrderStream(ctx context.Context, snaps map[string]*engine.Snapshot) (map[string]int, error) {
+	replayed := make(map[string]int, len(snaps))
+
+	lastDelivered, err := h.lastDeliveredID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if lastDelivered == "" {
+		return replayed, nil
+	}
+	pending, err := h.pendingIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := ""
+	for _, snap := range snaps {
+		if start == "" || engine.CompareStreamIDs(snap.StreamID, start) < 0 {
+			start = snap.StreamID
+		}
+	}
+	// 快照之后的消息已被裁剪时无法重放
+	first, err := h.redis.XRangeN(ctx, h.orderStream, "-", "+", 1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("xrange %s: %w", h.orderStream, err)
+	}
+	if len(first) == 0 || engine.CompareStreamIDs(first[0].ID, start) > 0 {
+		return nil, fmt.Errorf("order stream trimmed past snapshot %s", start)
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		msgs, err := h.redis.XRangeN(ctx, h.orderStream, "("+start, lastDelivered, replayBatchSize).Result()
+		if err != nil {
+			return nil, fmt.Errorf("xrange %s: %w", h.orderStream, err)
+		}
+		for _, msg := range msgs {
+			if pending[msg.ID] && !h.IsLeader() {
+				return replayed, nil
+			}
+			orderMsg, ok := DecodeOrderMessage(msg)
+			if !ok {
+				continue
+			}
+			snap, ok := snaps[orderMsg.Symbol]
+			if !ok || engine.CompareStreamIDs(msg.ID, snap.StreamID) <= 0 {
+				continue
+			}
+			if h.replayDecision(ctx, orderMsg, msg.ID, pending[msg.ID]) != replayApply {
+				continue
+			}
+			cmd := ToCommand(orderMsg)
+			cmd.StreamID = msg.ID
+			if err := h.submitBlocking(ctx, h.engineFor(orderMsg.Symbol), cmd); err != nil {
+				return nil, err
+			}
+			replayed[orderMsg.Symbol]++
+			h.followed[orderMsg.Symbol] = msg.ID
+		}
+		if len(msgs) < replayBatchSize {
+			return replayed, nil
+		}
+		start = msgs[len(msgs)-1].ID
+	}
+}
+
+func (h *Handler) replayDecision(ctx context.Context, msg *OrderMessage, msgID string, isPending bool) replayDecision {
+	key := h.dedupeKey(msg)
+	if key == "" {
+		if isPending {
+			return replaySkip
+		}
+		return replayApply
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	status, err := h.redis.Get(timeoutCtx, key).Result()
+	if err != nil && err != redis.Nil {
+		// 无法判断时按投递状态处理：已 ACK 的消息必然已提交给引擎
+		if isPending {
+			return replaySkip
+		}
+		return replayApply
+	}
+	if doneID, done := parseDedupeDone(status); done {
+		if doneID == "" || doneID == msgID {
+			return replayApply
+		}
+		return replaySkip
+	}
+	if err == redis.Nil && !isPending {
+		// 去重记录已过期，但消息已 ACK
+		return replayApply
+	}
+	return replaySkip
+}
+
+func (h *Handler) lastDeliveredID(ctx context.Context) (string, error) {
+	groups, err := h.redis.XInfoGroups(ctx, h.orderStream).Result()
+	if err != nil {
+		return "", fmt.Errorf("xinfo groups: %w", err)
+	}
+	for _, g := range groups {
+		if g.Name == h.group {
+			if g.LastDeliveredID == "0-0" {
+				return "", nil
+			}
+			return g.LastDeliveredID, nil
+		}
+	}
+	return "", nil
+}
+
+func (h *Handler) pendingIDs(ctx context.Context) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	start := "-"
+	for {
+		entries, err := h.redis.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: h.orderStream,
+			Group:  h.group,
+			Start:  start,
+			End:    "+",
+			Count:  replayBatchSize,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("xpending: %w", err)
+		}
+		for _, entry := range entries {
+			ids[entry.ID] = true
+		}
+		if len(entries) < replayBatchSize {
+			return ids, nil
+		}
+		start = "(" + entries[len(entries)-1].ID
+	}
+}
+
+// submitBlocking 提交命令，队列满时等待引擎消费
+func (h *Handler) submitBlocking(ctx context.Context, eng *engine.Engine, cmd *engine.Command) error {
+	for {
+		err := eng.Submit(cmd)
+		if err == nil || !strings.Contains(err.Error(), "queue full") {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+// snapshotLoop 定期为每个引擎生成快照
+//
+// 主备模式下只有主机写快照：快照目录由同一分片的副本共享，备机据此对齐订单簿。
+func (h *Handler) snapshotLoop(ctx context.Context) {
+	interval := h.snapshotIvl
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !h.IsLeader() {
+				continue
+			}
+			h.mu.RLock()
+			engines := make(map[string]*engine.Engine, len(h.engines))
+			for symbol, eng := range h.engines {
+				engines[symbol] = eng
+			}
+			h.mu.RUnlock()
+
+			for symbol, eng := range engines {
+				if err := h.saveSnapshot(ctx, symbol, eng); err != nil && ctx.Err() == nil {
+					h.log.WithError(err).WithField("symbol", symbol).Warn("save snapshot error")
+				}
+			}
+		}
+	}
+}
+
+// saveSnapshot 生成并保存快照
+//
+// 只有快照内的事件全部发布后才落盘，否则重启时这些事件既不会被重放也不会被发布。
+func (h *Handler) saveSnapshot(ctx context.Context, symbol string, eng *engine.Engine) error {
+	snapCtx, cancel := context.WithTimeout(ctx, snapshotTimeout)
+	defer cancel()
+
+	snap, err := eng.Snapshot(snapCtx)
+	if err != nil {
+		return err
+	}
+	if snap.StreamID == "" {
+		// 尚未处理订单流消息，订单库恢复即可
+		return nil
+	}
+	for h.publishedSeq(symbol) < snap.Seq {
+		select {
+		case <-snapCtx.Done():
+			return fmt.Errorf("wait events published: %w", snapCtx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return h.snapshots.Save(snapCtx, snap)
+}
+
+func (h *Handler) setPublished(symbol string, seq int64) {
+	h.publishedMu.Lock()
+	if seq > h.published[symbol] {
+		h.published[symbol] = seq
+	}
+	h.publishedMu.Unlock()
+}
+
+func (h *Handler) publishedSeq(symbol string) int64 {
+	h.publishedMu.Lock()
+	defer h.publishedMu.Unlock()
+	return h.published[symbol]
+}
+
+func (h *Handler) clearPublished(symbol string) {
+	h.publishedMu.Lock()
+	delete(h.published, symbol)
+	h.publishedMu.Unlock()
+}
+
+func (h *Handler) engineFor(symbol string) *engine.Engine {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.engines[symbol]
+}
+
+// dropEngines 停止快照恢复失败的引擎（保留快照文件以便排查）
+func (h *Handler) dropEngines(snaps map[string]*engine.Snapshot) {
+	for symbol := range snaps {
+		h.dropEngine(symbol)
+	}
+}
+
+// discardEngine 丢弃未启动的引擎
+func (h *Handler) discardEngine(symbol string, eng *engine.Engine) {
+	eng.Stop()
+	h.clearPublished(symbol)
+}
+
+func (h *Handler) dropEngine(symbol string) {
+	h.mu.Lock()
+	if eng, ok := h.engines[symbol]; ok {
+		eng.Stop()
+		delete(h.engines, symbol)
+	}
+	h.mu.Unlock()
+	h.clearPublished(symbol)
+}
+
+// DecodeOrderMessage 解析订单流消息的 data 字段
+func DecodeOrderMessage(msg redis.XMessage) (*OrderMessage, bool) {
+	data, ok := msg.Values["data"].(string)
+	if !ok {
+		return nil, false
+	}
+	var orderMsg OrderMessage
+	if err := json.Unmarshal([]byte(data), &orderMsg); err != nil {
+		return nil, false
+	}
+	return &orderMsg, true
+}
+
+// parseDedupeDone 解析去重状态，返回记录的消息 ID（旧格式 "done" 无 ID）
+func parseDedupeDone(status string) (string, bool) {
+	if status == dedupeStateDone {
+		return "", true
+	}
+	if id, ok := strings.CutPrefix(status, dedupeStateDone+":"); ok {
+		return id, true
+	}
+	return "", false
+}
@@ -63,6 +63,11 @@ var (
 		Name: "matching_engine_count",
 		Help: "Number of active matching engines.",
 	})
+
+	leader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "matching_leader",
+		Help: "1 when this instance holds the leader lease, 0 on a standby.",
+	})
 )
 
 // Init registers metrics with the registry once.
@@ -79,6 +84,7 @@ func Init() {
 			streamDLQ,
 			streamErrors,
 			engineCount,
+			leader,
 		)
 	})
 }
@@ -135,3 +141,13 @@ func SetEngineCount(count int) {
 	Init()
 	engineCount.Set(float64(count))
 }
+
+// SetLeader records whether this instance is the leader.
+func SetLeader(isLeader bool) {
+	Init()
+	if isLeader {
+		leader.Set(1)
+		return
+	}
+	leader.Set(0)
+}
@@ -67,6 +67,17 @@ func TestAddMatchingThroughputNoop(t *testing.T) {
 	}
 }
 
+func TestSetLeader(t *testing.T) {
+	SetLeader(true)
+	if got := testutil.ToFloat64(leader); got != 1 {
+		t.Fatalf("matching_leader mismatch: got %v want 1", got)
+	}
+	SetLeader(false)
+	if got := testutil.ToFloat64(leader); got != 0 {
+		t.Fatalf("matching_leader mismatch: got %v want 0", got)
+	}
+}
+
 func getHistogramSampleCount(t *testing.T) uint64 {
 	t.Helper()
 	mfs, err := registry.Gather()
@@ -0,0 +1,136 @@
+package orderbook
+
+import "sort"
+
+// Equilibrium 集合竞价均衡结果
+type Equilibrium struct {
+	Price   int64 // 均衡价，买卖盘不交叉时为 0
+	Volume  int64 // 均衡价下的可成交数量
+	BuyQty  int64 // 价格不低于均衡价的买单总量
+	SellQty int64 // 价格不高于均衡价的卖单总量
+}
+
+// Imbalance 未成交数量（正数为买方剩余，负数为卖方剩余）
+func (eq Equilibrium) Imbalance() int64 {
+	return eq.BuyQty - eq.SellQty
+}
+
+// Equilibrium 计算集合竞价均衡价（冰山单隐藏数量同样参与）
+//
+// 候选价为交叉区间内的所有挂单价位，依次比较：可成交量最大、未成交量最小、
+// 与参考价 refPrice 最接近（refPrice<=0 时忽略），仍相同时取较低价。
+func (ob *OrderBook) Equilibrium(refPrice int64) Equilibrium {
+	bestBid, bestAsk := ob.bids.front(), ob.asks.front()
+	if bestBid == nil || bestAsk == nil || bestBid.Price < bestAsk.Price {
+		return Equilibrium{}
+	}
+	lo, hi := bestAsk.Price, bestBid.Price
+
+	// 交叉区间内的买卖档位（买盘降序、卖盘升序）、候选价（升序去重）与区间内买单总量
+	var bids, asks []*PriceLevel
+	candidates := make([]int64, 0)
+	var buyQty int64
+	ob.bids.each(func(level *PriceLevel) bool {
+		if level.Price < lo {
+			return false
+		}
+		bids = append(bids, level)
+		candidates = append(candidates, level.Price)
+		buyQty += level.Total
+		return true
+	})
+	ob.asks.each(func(level *PriceLevel) bool {
+		if level.Price > hi {
+			return false
+		}
+		asks = append(asks, level)
+		candidates = append(candidates, level.Price)
+		return true
+	})
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	var best Equilibrium
+	var sellQty int64
+	askIdx := 0
+	bidIdx := len(bids) - 1 // 买盘价格降序，从末尾开始即为升序
+	last := int64(-1)
+	for _, price := range candidates {
+		if price == last {
+			continue
+		}
+		last = price
+		for askIdx < len(asks) && asks[askIdx].Price <= price {
+			sellQty += asks[askIdx].Total
+			askIdx++
+		}
+		for bidIdx >= 0 && bids[bidIdx].Price < price {
+			buyQty -= bids[bidIdx].Total
+			bidIdx--
+		}
+
+		cand := Equilibrium{Price: price, Volume: min(buyQty, sellQty), BuyQty: buyQty, SellQty: sellQty}
+		if cand.Volume > 0 && betterEquilibrium(cand, best, refPrice) {
+			best = cand
+		}
+	}
+	return best
+}
+
+// betterEquilibrium 候选价按升序遍历，同等条件下保留先出现的较低价
+func betterEquilibrium(cand, best Equilibrium, refPrice int64) bool {
+	if best.Volume == 0 || cand.Volume != best.Volume {
+		return cand.Volume > best.Volume
+	}
+	if ci, bi := abs(cand.Imbalance()), abs(best.Imbalance()); ci != bi {
+		return ci < bi
+	}
+	if refPrice > 0 {
+		return abs(cand.Price-refPrice) < abs(best.Price-refPrice)
+	}
+	return false
+}
+
+// Uncross 集合竞价结束时以单一价格撮合交叉部分
+//
+// 买单按价格时间优先依次作为 taker，与价格不高于 price 的卖单撮合，成交价统一为 price，
+// 每个参与撮合的买单对应一个 MatchResult。未完全成交的买单保留原有优先级继续挂单。
+func (ob *OrderBook) Uncross(price int64) []*MatchResult {
+	results := make([]*MatchResult, 0)
+	for {
+		level, ask := ob.bids.front(), ob.asks.front()
+		if level == nil || level.Price < price || ask == nil || ask.Price > price {
+			break
+		}
+		bid := level.head
+		leaves, shown := bid.LeavesQty, bid.shownQty()
+
+		result := ob.match(bid, price, price)
+		results = append(results, result)
+
+		// 同步买单所在档位的数量（成交、STP 扣减或撤销）
+		consumed := leaves - bid.LeavesQty
+		if consumed <= 0 {
+			break
+		}
+		if bid.IsIceberg() {
+			bid.VisibleQty -= min(consumed, bid.VisibleQty)
+			if bid.VisibleQty == 0 {
+				bid.VisibleQty = min(bid.DisplayQty, bid.LeavesQty)
+			}
+		}
+		level.Total -= consumed
+		level.Visible -= shown - bid.shownQty()
+		ob.dirty = true
+		if bid.LeavesQty <= 0 {
+			ob.RemoveOrder(bid.OrderID)
+		}
+	}
+	return results
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,97 @@
+package orderbook
+
+import "testing"
+
+func newAuctionBook() *OrderBook {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 1, Side: SideBuy, Price: 101, OrigQty: 10, LeavesQty: 10})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 2, Side: SideBuy, Price: 100, OrigQty: 10, LeavesQty: 10})
+	ob.AddOrder(&Order{OrderID: 3, UserID: 3, Side: SideBuy, Price: 98, OrigQty: 5, LeavesQty: 5})
+	ob.AddOrder(&Order{OrderID: 4, UserID: 4, Side: SideSell, Price: 99, OrigQty: 8, LeavesQty: 8})
+	ob.AddOrder(&Order{OrderID: 5, UserID: 5, Side: SideSell, Price: 100, OrigQty: 6, LeavesQty: 6})
+	ob.AddOrder(&Order{OrderID: 6, UserID: 6, Side: SideSell, Price: 102, OrigQty: 5, LeavesQty: 5})
+	return ob
+}
+
+func TestEquilibriumMaximizesVolume(t *testing.T) {
+	ob := newAuctionBook()
+
+	eq := ob.Equilibrium(0)
+	if eq.Price != 100 || eq.Volume != 14 {
+		t.Fatalf("expected equilibrium 100 x 14, got %+v", eq)
+	}
+	if eq.BuyQty != 20 || eq.SellQty != 14 || eq.Imbalance() != 6 {
+		t.Fatalf("unexpected imbalance: %+v", eq)
+	}
+}
+
+func TestEquilibriumNoCross(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 1, Side: SideBuy, Price: 99, OrigQty: 10, LeavesQty: 10})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 2, Side: SideSell, Price: 100, OrigQty: 10, LeavesQty: 10})
+
+	if eq := ob.Equilibrium(0); eq.Price != 0 || eq.Volume != 0 {
+		t.Fatalf("expected no equilibrium, got %+v", eq)
+	}
+}
+
+func TestEquilibriumTieBreaksByReferencePrice(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 1, Side: SideBuy, Price: 101, OrigQty: 10, LeavesQty: 10})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 2, Side: SideSell, Price: 99, OrigQty: 10, LeavesQty: 10})
+
+	if eq := ob.Equilibrium(0); eq.Price != 99 {
+		t.Fatalf("expected lower price without reference, got %+v", eq)
+	}
+	if eq := ob.Equilibrium(105); eq.Price != 101 {
+		t.Fatalf("expected price closest to reference, got %+v", eq)
+	}
+}
+
+func TestUncrossSinglePrice(t *testing.T) {
+	ob := newAuctionBook()
+
+	results := ob.Uncross(100)
+	var volume int64
+	for _, result := range results {
+		for _, trade := range result.Trades {
+			if trade.Price != 100 {
+				t.Fatalf("expected all trades at 100, got %d", trade.Price)
+			}
+			volume += trade.Qty
+		}
+	}
+	if volume != 14 {
+		t.Fatalf("expected uncross volume 14, got %d", volume)
+	}
+	if len(results) != 2 || !results[0].TakerFilled || results[1].TakerFilled {
+		t.Fatalf("unexpected taker results: %d", len(results))
+	}
+
+	if price, qty, _ := ob.BestBid(); price != 100 || qty != 6 {
+		t.Fatalf("expected remaining bid 100 x 6, got %d x %d", price, qty)
+	}
+	if price, _, _ := ob.BestAsk(); price != 102 {
+		t.Fatalf("expected best ask 102, got %d", price)
+	}
+	if ob.GetOrder(1) != nil || ob.GetOrder(4) != nil || ob.GetOrder(5) != nil {
+		t.Fatal("expected filled orders removed from book")
+	}
+}
+
+func TestUncrossIcebergBidReplenishes(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 1, Side: SideBuy, Price: 100, OrigQty: 30, LeavesQty: 30, DisplayQty: 5})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 2, Side: SideSell, Price: 100, OrigQty: 12, LeavesQty: 12})
+
+	ob.Uncross(100)
+
+	bid := ob.GetOrder(1)
+	if bid == nil || bid.LeavesQty != 18 || bid.VisibleQty != 5 {
+		t.Fatalf("unexpected iceberg bid after uncross: %+v", bid)
+	}
+	bids, asks := ob.Depth(5)
+	if len(bids) != 1 || bids[0].Qty != 5 || len(asks) != 0 {
+		t.Fatalf("unexpected depth after uncross: bids=%+v asks=%+v", bids, asks)
+	}
+}
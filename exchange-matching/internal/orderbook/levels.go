@@ -0,0 +1,233 @@
+package orderbook
+
+// 价格档位索引
+//
+// 每一侧的档位按价格优先级组成跳表（买盘降序、卖盘升序），PriceLevel 本身即跳表节点；
+// 档位内的订单通过 Order 上的 prev/next 组成侵入式双向链表，入簿、撤单不再额外分配链表节点。
+// 插入、删除档位为 O(log n)，最优档位为 O(1)，撮合吃掉最优档位时直接从表头摘除。
+// 空档位按侧缓存复用；Order 不做池化：订单由引擎创建，移出订单簿后仍通过 RemoveOrder、
+// MatchResult.MakerUpdates 和自成交防护结果交还引擎生成事件，归还时机分散在各出口，误归还会让新订单覆盖仍在使用的订单。
+
+const (
+	// maxLevelHeight 跳表最大层数（晋升概率 1/4，足够支撑百万级档位）
+	maxLevelHeight = 16
+	// levelPoolSize 每一侧缓存的空闲档位数量上限
+	levelPoolSize = 1024
+)
+
+// PriceLevel 价格档位
+type PriceLevel struct {
+	Price   int64
+	Total   int64 // 该档位总数量（含冰山单隐藏部分）
+	Visible int64 // 该档位展示数量（冰山单仅计当前展示部分）
+
+	// 档位内订单（时间优先）
+	head, tail *Order
+	count      int
+
+	// 跳表前向指针，长度即节点层数
+	forward []*PriceLevel
+}
+
+// Len 档位内订单数量
+func (l *PriceLevel) Len() int {
+	return l.count
+}
+
+// Front 档位内时间优先级最高的订单
+func (l *PriceLevel) Front() *Order {
+	return l.head
+}
+
+// pushBack 订单排到档位末尾
+func (l *PriceLevel) pushBack(order *Order) {
+	order.level = l
+	order.prev = l.tail
+	order.next = nil
+	if l.tail != nil {
+		l.tail.next = order
+	} else {
+		l.head = order
+	}
+	l.tail = order
+	l.count++
+}
+
+// unlink 从档位中摘除订单
+func (l *PriceLevel) unlink(order *Order) {
+	if order.prev != nil {
+		order.prev.next = order.next
+	} else {
+		l.head = order.next
+	}
+	if order.next != nil {
+		order.next.prev = order.prev
+	} else {
+		l.tail = order.prev
+	}
+	order.level, order.prev, order.next = nil, nil, nil
+	l.count--
+}
+
+// moveToBack 订单重新排到档位末尾（冰山单补充展示数量）
+func (l *PriceLevel) moveToBack(order *Order) {
+	if l.tail == order {
+		return
+	}
+	l.unlink(order)
+	l.pushBack(order)
+}
+
+// levelList 一侧的档位跳表与价格索引
+type levelList struct {
+	desc    bool // true: 价格降序（买盘）
+	head    PriceLevel
+	height  int
+	byPrice map[int64]*PriceLevel
+	free    []*PriceLevel
+	rand    uint64
+}
+
+func newLevelList(desc bool) *levelList {
+	return &levelList{
+		desc:    desc,
+		head:    PriceLevel{forward: make([]*PriceLevel, maxLevelHeight)},
+		height:  1,
+		byPrice: make(map[int64]*PriceLevel),
+		// 固定种子：同样的操作序列得到同样的跳表结构，重放时性能特征一致
+		rand: 0x9E3779B97F4A7C15,
+	}
+}
+
+// Len 档位数量
+func (s *levelList) Len() int {
+	return len(s.byPrice)
+}
+
+// get 按价格查找档位
+func (s *levelList) get(price int64) *PriceLevel {
+	return s.byPrice[price]
+}
+
+// front 最优档位
+func (s *levelList) front() *PriceLevel {
+	return s.head.forward[0]
+}
+
+// before a 的优先级是否高于 b
+func (s *levelList) before(a, b int64) bool {
+	if s.desc {
+		return a > b
+	}
+	return a < b
+}
+
+// randomHeight xorshift64，每层晋升概率 1/4
+func (s *levelList) randomHeight() int {
+	s.rand ^= s.rand << 13
+	s.rand ^= s.rand >> 7
+	s.rand ^= s.rand << 17
+	height := 1
+	for r := s.rand; height < maxLevelHeight && r&3 == 0; r >>= 2 {
+		height++
+	}
+	return height
+}
+
+// getOrInsert 返回价格对应的档位，不存在时创建
+func (s *levelList) getOrInsert(price int64) *PriceLevel {
+	if level := s.byPrice[price]; level != nil {
+		return level
+	}
+
+	var update [maxLevelHeight]*PriceLevel
+	node := &s.head
+	for h := s.height - 1; h >= 0; h-- {
+		for next := node.forward[h]; next != nil && s.before(next.Price, price); next = node.forward[h] {
+			node = next
+		}
+		update[h] = node
+	}
+
+	height := s.randomHeight()
+	if height > s.height {
+		for h := s.height; h < height; h++ {
+			update[h] = &s.head
+		}
+		s.height = height
+	}
+
+	level := s.alloc(price, height)
+	for h := 0; h < height; h++ {
+		level.forward[h] = update[h].forward[h]
+		update[h].forward[h] = level
+	}
+	s.byPrice[price] = level
+	return level
+}
+
+// remove 删除空档位
+func (s *levelList) remove(level *PriceLevel) {
+	if s.byPrice[level.Price] != level {
+		return
+	}
+	delete(s.byPrice, level.Price)
+
+	if s.head.forward[0] == level {
+		// 撮合总是从最优档位开始吃，最优档位在其所有层上都是第一个节点
+		for h := range level.forward {
+			s.head.forward[h] = level.forward[h]
+		}
+	} else {
+		node := &s.head
+		for h := s.height - 1; h >= 0; h-- {
+			for next := node.forward[h]; next != nil && s.before(next.Price, level.Price); next = node.forward[h] {
+				node = next
+			}
+			if h < len(level.forward) && node.forward[h] == level {
+				node.forward[h] = level.forward[h]
+			}
+		}
+	}
+	for s.height > 1 && s.head.forward[s.height-1] == nil {
+		s.height--
+	}
+	s.release(level)
+}
+
+// alloc 从空闲档位中分配
+func (s *levelList) alloc(price int64, height int) *PriceLevel {
+	var level *PriceLevel
+	if n := len(s.free); n > 0 {
+		level = s.free[n-1]
+		s.free = s.free[:n-1]
+	} else {
+		level = &PriceLevel{}
+	}
+	level.Price = price
+	if cap(level.forward) >= height {
+		level.forward = level.forward[:height]
+	} else {
+		level.forward = make([]*PriceLevel, height)
+	}
+	return level
+}
+
+// release 回收档位（订单已全部移出）
+func (s *levelList) release(level *PriceLevel) {
+	forward := level.forward[:cap(level.forward)]
+	clear(forward)
+	*level = PriceLevel{forward: forward[:0]}
+	if len(s.free) < levelPoolSize {
+		s.free = append(s.free, level)
+	}
+}
+
+// each 按价格优先级遍历档位，fn 返回 false 时停止
+func (s *levelList) each(fn func(level *PriceLevel) bool) {
+	for level := s.head.forward[0]; level != nil; level = level.forward[0] {
+		if !fn(level) {
+			return
+		}
+	}
+}
@@ -2,8 +2,10 @@
 package orderbook
 
 import (
-	"container/list"
-	"sync"
+	"math"
+	"math/bits"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/exchange/common/pkg/snowflake"
@@ -17,218 +19,315 @@ const (
 	SideSell Side = 2
 )
 
+// STPMode 自成交防护模式（以 taker 的模式为准）
+type STPMode int
+
+const (
+	STPExpireTaker STPMode = 1 // 撤销 taker 剩余数量（默认）
+	STPExpireMaker STPMode = 2 // 撤销自成交的 maker，taker 继续撮合
+	STPExpireBoth  STPMode = 3 // 同时撤销 maker 与 taker 剩余数量
+	STPDecrement   STPMode = 4 // 双方同时扣减较小数量，扣减至 0 的一方撤销
+)
+
 // Order 订单
 type Order struct {
-	OrderID       int64
-	UserID        int64
-	ClientOrderID string
-	Symbol        string
-	Side          Side
-	Price         int64 // 最小单位整数
-	OrigQty       int64 // 原始数量
-	LeavesQty     int64 // 剩余数量
-	TimeInForce   int   // 1=GTC, 2=IOC, 3=FOK, 4=POST_ONLY
-	Timestamp     int64 // 纳秒时间戳
-	element       *list.Element
-}
-
-// PriceLevel 价格档位
-type PriceLevel struct {
-	Price  int64
-	Orders *list.List // *Order
-	Total  int64      // 该档位总数量
+	OrderID        int64
+	UserID         int64
+	ClientOrderID  string
+	Symbol         string
+	Side           Side
+	Price          int64 // 最小单位整数
+	OrigQty        int64 // 原始数量
+	LeavesQty      int64 // 剩余数量
+	TimeInForce    int   // 1=GTC, 2=IOC, 3=FOK, 4=POST_ONLY
+	STPMode        STPMode
+	DisplayQty     int64 // 冰山单每次展示数量，0 表示非冰山单
+	VisibleQty     int64 // 冰山单当前展示的剩余数量（由订单簿维护）
+	ExpireTimeMs   int64 // GTD/DAY 到期时间（毫秒），0 表示不过期
+	QuoteID        int64 // 做市报价请求 ID，非 0 表示报价单（整组由下一次报价替换）
+	ListID         int64 // 订单列表 ID（OCO），0 表示非列表订单
+	SiblingOrderID int64 // 同列表的另一条订单
+	QuoteBudget    int64 // 按金额市价买单的剩余可用金额（撮合时逐笔扣减）
+	QtyScale       int64 // 数量精度对应的倍数，非 0 表示按金额市价买单（成交额 price * qty / QtyScale）
+	Timestamp      int64 // 纳秒时间戳
+
+	// 所在档位与档位内的前后订单（由订单簿维护）
+	level      *PriceLevel
+	prev, next *Order
+}
+
+// IsIceberg 是否冰山单
+func (o *Order) IsIceberg() bool {
+	return o.DisplayQty > 0
+}
+
+// HasQuoteBudget 是否按金额成交的市价买单
+func (o *Order) HasQuoteBudget() bool {
+	return o.QtyScale > 0
+}
+
+// shownQty 计入深度的数量：冰山单为当前展示部分，其余为剩余数量
+func (o *Order) shownQty() int64 {
+	if o.IsIceberg() {
+		return o.VisibleQty
+	}
+	return o.LeavesQty
+}
+
+// clampVisible 剩余数量减少后，冰山单展示数量不超过剩余数量
+func (o *Order) clampVisible() {
+	if o.IsIceberg() && o.VisibleQty > o.LeavesQty {
+		o.VisibleQty = o.LeavesQty
+	}
 }
 
 // OrderBook 订单簿
+//
+// 单写者：除 PublishedDepth 外的方法只能由撮合引擎 goroutine 调用，订单簿本身不加锁；
+// 其他 goroutine 通过 Publish 发布的深度快照无锁读取。
 type OrderBook struct {
 	Symbol string
 
 	// 买盘：价格降序（高价优先）
-	bids map[int64]*PriceLevel
+	bids *levelList
 	// 卖盘：价格升序（低价优先）
-	asks map[int64]*PriceLevel
+	asks *levelList
 
 	// 订单索引
 	orders map[int64]*Order
+	// 用户订单索引（批量撤单）
+	users map[int64]map[int64]*Order
 
-	// 价格排序缓存
-	bidPrices []int64
-	askPrices []int64
+	// 已发布的深度快照；dirty 表示订单簿在上次发布后有变动
+	published atomic.Pointer[depthSnapshot]
+	dirty     bool
 
-	mu sync.RWMutex
+	// 时钟与成交 ID 生成器（重放时注入确定性实现）
+	now    func() time.Time
+	nextID func() int64
 }
 
 // NewOrderBook 创建订单簿
 func NewOrderBook(symbol string) *OrderBook {
 	return &OrderBook{
-		Symbol:    symbol,
-		bids:      make(map[int64]*PriceLevel),
-		asks:      make(map[int64]*PriceLevel),
-		orders:    make(map[int64]*Order),
-		bidPrices: make([]int64, 0),
-		askPrices: make([]int64, 0),
+		Symbol: symbol,
+		bids:   newLevelList(true),
+		asks:   newLevelList(false),
+		orders: make(map[int64]*Order),
+		users:  make(map[int64]map[int64]*Order),
+		dirty:  true,
+		now:    time.Now,
+		nextID: snowflake.MustNextID,
 	}
 }
 
+// SetClock 设置订单与成交时间戳使用的时钟，为空时使用系统时钟
+func (ob *OrderBook) SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	ob.now = now
+}
+
+// SetIDGenerator 设置成交 ID 生成器，为空时使用 snowflake
+func (ob *OrderBook) SetIDGenerator(next func() int64) {
+	if next == nil {
+		next = snowflake.MustNextID
+	}
+	ob.nextID = next
+}
+
+// side 返回订单所在一侧的档位
+func (ob *OrderBook) side(side Side) *levelList {
+	if side == SideBuy {
+		return ob.bids
+	}
+	return ob.asks
+}
+
 // AddOrder 添加订单到订单簿
 func (ob *OrderBook) AddOrder(order *Order) {
-	ob.mu.Lock()
-	defer ob.mu.Unlock()
-
 	if _, exists := ob.orders[order.OrderID]; exists {
 		return
 	}
 
 	if order.Timestamp == 0 {
-		order.Timestamp = time.Now().UnixNano()
+		order.Timestamp = ob.now().UnixNano()
 	}
 
-	var levels map[int64]*PriceLevel
-	var prices *[]int64
-	if order.Side == SideBuy {
-		levels = ob.bids
-		prices = &ob.bidPrices
-	} else {
-		levels = ob.asks
-		prices = &ob.askPrices
-	}
+	level := ob.side(order.Side).getOrInsert(order.Price)
 
-	level, exists := levels[order.Price]
-	if !exists {
-		level = &PriceLevel{
-			Price:  order.Price,
-			Orders: list.New(),
-		}
-		levels[order.Price] = level
-		*prices = insertPrice(*prices, order.Price, order.Side == SideBuy)
+	// 冰山单未携带有效展示数量时（新订单或重新入簿）按完整展示部分入簿，快照恢复时保留原展示数量
+	if order.IsIceberg() && (order.VisibleQty <= 0 || order.VisibleQty > order.LeavesQty) {
+		order.VisibleQty = min(order.DisplayQty, order.LeavesQty)
 	}
-
-	order.element = level.Orders.PushBack(order)
+	level.pushBack(order)
 	level.Total += order.LeavesQty
+	level.Visible += order.shownQty()
 	ob.orders[order.OrderID] = order
+	userOrders, ok := ob.users[order.UserID]
+	if !ok {
+		userOrders = make(map[int64]*Order)
+		ob.users[order.UserID] = userOrders
+	}
+	userOrders[order.OrderID] = order
+	ob.dirty = true
 }
 
-// RemoveOrder 从订单簿移除订单
-func (ob *OrderBook) RemoveOrder(orderID int64) *Order {
-	ob.mu.Lock()
-	defer ob.mu.Unlock()
+// forgetOrder 从订单索引与用户索引中删除订单
+func (ob *OrderBook) forgetOrder(order *Order) {
+	delete(ob.orders, order.OrderID)
+	if userOrders, ok := ob.users[order.UserID]; ok {
+		delete(userOrders, order.OrderID)
+		if len(userOrders) == 0 {
+			delete(ob.users, order.UserID)
+		}
+	}
+}
 
-	return ob.removeOrderLocked(orderID)
+// unlinkOrder 将订单移出档位，档位为空时删除档位
+func (ob *OrderBook) unlinkOrder(order *Order) {
+	level := order.level
+	if level == nil {
+		return
+	}
+	level.unlink(order)
+	level.Total -= order.LeavesQty
+	level.Visible -= order.shownQty()
+	if level.count == 0 {
+		ob.side(order.Side).remove(level)
+	}
 }
 
-func (ob *OrderBook) removeOrderLocked(orderID int64) *Order {
+// RemoveOrder 从订单簿移除订单
+func (ob *OrderBook) RemoveOrder(orderID int64) *Order {
 	order, exists := ob.orders[orderID]
 	if !exists {
 		return nil
 	}
 
-	var levels map[int64]*PriceLevel
-	var prices *[]int64
-	if order.Side == SideBuy {
-		levels = ob.bids
-		prices = &ob.bidPrices
-	} else {
-		levels = ob.asks
-		prices = &ob.askPrices
-	}
-
-	level := levels[order.Price]
-	if level != nil {
-		level.Orders.Remove(order.element)
-		level.Total -= order.LeavesQty
-
-		if level.Orders.Len() == 0 {
-			delete(levels, order.Price)
-			*prices = removePrice(*prices, order.Price, order.Side == SideBuy)
-		}
-	}
-
-	delete(ob.orders, orderID)
+	ob.unlinkOrder(order)
+	ob.forgetOrder(order)
+	ob.dirty = true
 	return order
 }
 
 // ReduceOrderQty 减少订单数量（部分成交）
 func (ob *OrderBook) ReduceOrderQty(orderID int64, qty int64) {
-	ob.mu.Lock()
-	defer ob.mu.Unlock()
-
 	order, exists := ob.orders[orderID]
 	if !exists {
 		return
 	}
 
-	var levels map[int64]*PriceLevel
-	if order.Side == SideBuy {
-		levels = ob.bids
-	} else {
-		levels = ob.asks
+	shown := order.shownQty()
+	order.LeavesQty -= qty
+	order.clampVisible()
+	if level := order.level; level != nil {
+		level.Total -= qty
+		level.Visible -= shown - order.shownQty()
 	}
+	ob.dirty = true
 
-	level := levels[order.Price]
-	if level != nil {
-		level.Total -= qty
+	if order.LeavesQty <= 0 {
+		ob.RemoveOrder(orderID)
+	}
+}
+
+// DecreaseOrderQty 改单减少订单数量，保留其在价格档位中的时间优先级
+//
+// 与 ReduceOrderQty（成交）不同，OrigQty 同步减少；qty 必须小于剩余数量。
+func (ob *OrderBook) DecreaseOrderQty(orderID int64, qty int64) bool {
+	order, exists := ob.orders[orderID]
+	if !exists || qty <= 0 || qty >= order.LeavesQty {
+		return false
 	}
+
+	shown := order.shownQty()
 	order.LeavesQty -= qty
+	order.OrigQty -= qty
+	order.clampVisible()
+	if level := order.level; level != nil {
+		level.Total -= qty
+		level.Visible -= shown - order.shownQty()
+	}
+	ob.dirty = true
+	return true
+}
 
-	if order.LeavesQty <= 0 {
-		ob.removeOrderLocked(orderID)
+// Orders 按撮合优先级导出订单副本（买盘在前、卖盘在后，同价位按时间优先）
+//
+// 按返回顺序依次 AddOrder 即可还原相同的价格时间优先级。
+func (ob *OrderBook) Orders() []Order {
+	orders := make([]Order, 0, len(ob.orders))
+	collect := func(level *PriceLevel) bool {
+		for o := level.head; o != nil; o = o.next {
+			order := *o
+			order.level, order.prev, order.next = nil, nil, nil
+			orders = append(orders, order)
+		}
+		return true
 	}
+	ob.bids.each(collect)
+	ob.asks.each(collect)
+	return orders
+}
+
+// RemoveUserOrders 移除用户在簿的全部订单（side 为 0 表示双边），按订单 ID 升序返回
+func (ob *OrderBook) RemoveUserOrders(userID int64, side Side) []*Order {
+	removed := ob.userOrders(userID, func(order *Order) bool {
+		return side == 0 || order.Side == side
+	})
+	for _, order := range removed {
+		ob.RemoveOrder(order.OrderID)
+	}
+	return removed
+}
+
+// UserQuotes 用户在簿的报价单，按订单 ID 升序返回
+func (ob *OrderBook) UserQuotes(userID int64) []*Order {
+	return ob.userOrders(userID, func(order *Order) bool {
+		return order.QuoteID != 0
+	})
+}
+
+// userOrders 按订单 ID 升序返回用户满足条件的在簿订单
+func (ob *OrderBook) userOrders(userID int64, match func(order *Order) bool) []*Order {
+	orders := make([]*Order, 0, len(ob.users[userID]))
+	for _, order := range ob.users[userID] {
+		if match(order) {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderID < orders[j].OrderID })
+	return orders
 }
 
 // GetOrder 获取订单
 func (ob *OrderBook) GetOrder(orderID int64) *Order {
-	ob.mu.RLock()
-	defer ob.mu.RUnlock()
 	return ob.orders[orderID]
 }
 
 // BestBid 最优买价
 func (ob *OrderBook) BestBid() (int64, int64, bool) {
-	ob.mu.RLock()
-	defer ob.mu.RUnlock()
-
-	if len(ob.bidPrices) == 0 {
+	level := ob.bids.front()
+	if level == nil {
 		return 0, 0, false
 	}
-	price := ob.bidPrices[0]
-	level := ob.bids[price]
-	return price, level.Total, true
+	return level.Price, level.Total, true
 }
 
 // BestAsk 最优卖价
 func (ob *OrderBook) BestAsk() (int64, int64, bool) {
-	ob.mu.RLock()
-	defer ob.mu.RUnlock()
-
-	if len(ob.askPrices) == 0 {
+	level := ob.asks.front()
+	if level == nil {
 		return 0, 0, false
 	}
-	price := ob.askPrices[0]
-	level := ob.asks[price]
-	return price, level.Total, true
+	return level.Price, level.Total, true
 }
 
-// Depth 获取深度
+// Depth 获取深度（冰山单仅计展示数量）
 func (ob *OrderBook) Depth(limit int) (bids, asks []PriceQty) {
-	ob.mu.RLock()
-	defer ob.mu.RUnlock()
-
-	bids = make([]PriceQty, 0, limit)
-	asks = make([]PriceQty, 0, limit)
-
-	for i := 0; i < len(ob.bidPrices) && i < limit; i++ {
-		price := ob.bidPrices[i]
-		level := ob.bids[price]
-		bids = append(bids, PriceQty{Price: price, Qty: level.Total})
-	}
-
-	for i := 0; i < len(ob.askPrices) && i < limit; i++ {
-		price := ob.askPrices[i]
-		level := ob.asks[price]
-		asks = append(asks, PriceQty{Price: price, Qty: level.Total})
-	}
-
-	return
+	return ob.bids.depth(limit), ob.asks.depth(limit)
 }
 
 // PriceQty 价格数量对
@@ -243,6 +342,19 @@ type MatchResult struct {
 	MakerUpdates []*Order // 被动方订单更新
 	TakerOrder   *Order   // 主动方订单
 	TakerFilled  bool     // 主动方是否完全成交
+
+	// 自成交防护结果
+	STPMakers       []*STPExpiry // 被撤销或扣减的 maker（按处理顺序）
+	TakerReducedQty int64        // DECREMENT 模式下 taker 被扣减但未终结的数量
+	TakerExpired    bool         // taker 剩余数量是否因自成交防护被撤销
+	TakerExpiredQty int64        // taker 被撤销的剩余数量
+}
+
+// STPExpiry 自成交防护对 maker 的处理
+type STPExpiry struct {
+	Order    *Order
+	Qty      int64 // 本次失效数量
+	Canceled bool  // true: 订单被撤销并移出订单簿；false: 仅扣减数量（DECREMENT）
 }
 
 // Trade 成交
@@ -261,68 +373,83 @@ type Trade struct {
 
 // Match 撮合订单
 func (ob *OrderBook) Match(taker *Order) *MatchResult {
-	ob.mu.Lock()
-	defer ob.mu.Unlock()
+	return ob.match(taker, taker.Price, 0)
+}
+
+// MatchLimit 撮合订单，对手盘价格不超过 limitPrice（0 表示不限）
+func (ob *OrderBook) MatchLimit(taker *Order, limitPrice int64) *MatchResult {
+	return ob.match(taker, limitPrice, 0)
+}
 
+// match 撮合 taker
+//
+// limitPrice 为可接受的对手盘最差价格（0 表示市价），tradePrice 为 0 时按 maker 价格成交。
+func (ob *OrderBook) match(taker *Order, limitPrice, tradePrice int64) *MatchResult {
 	result := &MatchResult{
 		Trades:       make([]*Trade, 0),
 		MakerUpdates: make([]*Order, 0),
 		TakerOrder:   taker,
 	}
 
-	var levels map[int64]*PriceLevel
-	var prices *[]int64
+	var levels *levelList
 	var canMatch func(makerPrice, takerPrice int64) bool
 
 	if taker.Side == SideBuy {
 		levels = ob.asks
-		prices = &ob.askPrices
 		canMatch = func(makerPrice, takerPrice int64) bool {
 			return takerPrice == 0 || makerPrice <= takerPrice // 市价单 takerPrice=0
 		}
 	} else {
 		levels = ob.bids
-		prices = &ob.bidPrices
 		canMatch = func(makerPrice, takerPrice int64) bool {
 			return takerPrice == 0 || makerPrice >= takerPrice
 		}
 	}
 
-	now := time.Now().UnixNano()
+	now := ob.now().UnixNano()
 
-	for taker.LeavesQty > 0 && len(*prices) > 0 {
-		bestPrice := (*prices)[0]
-
-		if !canMatch(bestPrice, taker.Price) {
+	for taker.LeavesQty > 0 {
+		level := levels.front()
+		if level == nil || !canMatch(level.Price, limitPrice) {
 			break
 		}
 
-		level := levels[bestPrice]
-		matchedInLevel := false // 追踪本档位是否有匹配
-		for e := level.Orders.Front(); e != nil && taker.LeavesQty > 0; {
-			maker := e.Value.(*Order)
-			next := e.Next()
+		for maker := level.head; maker != nil && taker.LeavesQty > 0; {
+			next := maker.next
 
-			// 自成交检查
+			// 自成交防护
 			if maker.UserID == taker.UserID {
-				e = next
+				ob.preventSelfTrade(result, level, taker, maker)
+				maker = next
 				continue
 			}
 
-			matchedInLevel = true
+			price := maker.Price // 成交价为 maker 价格
+			if tradePrice > 0 {
+				price = tradePrice
+			}
 
-			// 计算成交数量
-			matchQty := min(taker.LeavesQty, maker.LeavesQty)
+			// 计算成交数量（冰山单每次仅以展示部分成交）
+			matchQty := min(taker.LeavesQty, maker.shownQty())
+			if taker.HasQuoteBudget() {
+				// 剩余金额不足以买入 1 个最小单位时结束，未成交的数量不再计入订单
+				matchQty = min(matchQty, MulDiv(taker.QuoteBudget, taker.QtyScale, price))
+				if matchQty <= 0 {
+					taker.OrigQty -= taker.LeavesQty
+					taker.LeavesQty = 0
+					break
+				}
+			}
 
 			// 创建成交
 			trade := &Trade{
-				TradeID:      snowflake.MustNextID(),
+				TradeID:      ob.nextID(),
 				Symbol:       ob.Symbol,
 				MakerOrderID: maker.OrderID,
 				TakerOrderID: taker.OrderID,
 				MakerUserID:  maker.UserID,
 				TakerUserID:  taker.UserID,
-				Price:        maker.Price, // 成交价为 maker 价格
+				Price:        price,
 				Qty:          matchQty,
 				TakerSide:    taker.Side,
 				Timestamp:    now,
@@ -331,97 +458,154 @@ func (ob *OrderBook) Match(taker *Order) *MatchResult {
 
 			// 更新数量
 			taker.LeavesQty -= matchQty
+			if taker.HasQuoteBudget() {
+				taker.QuoteBudget -= MulDiv(price, matchQty, taker.QtyScale)
+			}
 			maker.LeavesQty -= matchQty
 			level.Total -= matchQty
+			level.Visible -= matchQty
 
-			result.MakerUpdates = append(result.MakerUpdates, maker)
+			if !maker.IsIceberg() || !containsOrder(result.MakerUpdates, maker) {
+				result.MakerUpdates = append(result.MakerUpdates, maker)
+			}
 
 			// 移除完全成交的 maker
 			if maker.LeavesQty <= 0 {
-				level.Orders.Remove(e)
-				delete(ob.orders, maker.OrderID)
+				level.unlink(maker)
+				ob.forgetOrder(maker)
+			} else if maker.IsIceberg() {
+				maker.VisibleQty -= matchQty
+				if maker.VisibleQty == 0 {
+					// 展示部分成交完毕：从隐藏数量补充并重新排到档位末尾
+					maker.VisibleQty = min(maker.DisplayQty, maker.LeavesQty)
+					maker.Timestamp = now
+					level.Visible += maker.VisibleQty
+					level.moveToBack(maker)
+					if next == nil {
+						next = maker
+					}
+				}
 			}
 
-			e = next
-		}
-
-		// 如果本档位没有任何匹配（全是自成交），跳出循环
-		if !matchedInLevel {
-			break
+			maker = next
 		}
 
 		// 移除空档位
-		if level.Orders.Len() == 0 {
-			delete(levels, bestPrice)
-			*prices = (*prices)[1:]
+		if level.count == 0 {
+			levels.remove(level)
 		}
 	}
 
-	result.TakerFilled = taker.LeavesQty <= 0
+	if len(result.Trades) > 0 || len(result.STPMakers) > 0 {
+		ob.dirty = true
+	}
+	result.TakerFilled = !result.TakerExpired && taker.LeavesQty <= 0
 	return result
 }
 
-// insertPrice 插入价格并保持排序（二分查找 O(log n)）
-func insertPrice(prices []int64, price int64, descending bool) []int64 {
-	n := len(prices)
-	// 二分查找插入位置
-	lo, hi := 0, n
-	for lo < hi {
-		mid := lo + (hi-lo)/2
-		if descending {
-			if prices[mid] > price {
-				lo = mid + 1
-			} else {
-				hi = mid
+// MulDiv 计算 a * b / c（向下取整，中间结果 128 位，超出 int64 时取最大值）
+func MulDiv(a, b, c int64) int64 {
+	if a <= 0 || b <= 0 || c <= 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi >= uint64(c) {
+		return math.MaxInt64
+	}
+	q, _ := bits.Div64(hi, lo, uint64(c))
+	if q > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(q)
+}
+
+// CanFill 预检 taker 能否立即完全成交（只读，不修改订单簿）
+//
+// 按价格时间优先遍历对手盘并遵循 taker 的 STP 模式：EXPIRE_MAKER 跳过同一用户的 maker，
+// 其余模式遇到同一用户的 maker 时 taker 会被撤销或扣减，视为无法完全成交。
+func (ob *OrderBook) CanFill(taker *Order) bool {
+	levels := ob.asks
+	if taker.Side == SideSell {
+		levels = ob.bids
+	}
+
+	need := taker.LeavesQty
+	for level := levels.front(); level != nil && need > 0; level = level.forward[0] {
+		if taker.Price != 0 {
+			if taker.Side == SideBuy && level.Price > taker.Price {
+				break
 			}
-		} else {
-			if prices[mid] < price {
-				lo = mid + 1
-			} else {
-				hi = mid
+			if taker.Side == SideSell && level.Price < taker.Price {
+				break
+			}
+		}
+		for maker := level.head; maker != nil && need > 0; maker = maker.next {
+			if maker.UserID == taker.UserID {
+				if taker.STPMode == STPExpireMaker {
+					continue
+				}
+				return false
 			}
+			need -= maker.LeavesQty
 		}
 	}
-
-	prices = append(prices, 0)
-	copy(prices[lo+1:], prices[lo:])
-	prices[lo] = price
-	return prices
+	return need <= 0
 }
 
-// removePrice 移除价格（二分查找 O(log n)）
-func removePrice(prices []int64, price int64, descending bool) []int64 {
-	n := len(prices)
-	if n == 0 {
-		return prices
+// preventSelfTrade 按 taker 的 STP 模式处理同一用户的 maker
+//
+// taker 被撤销时将其 LeavesQty 置 0 以终止撮合，被撤销数量记录在 TakerExpiredQty。
+func (ob *OrderBook) preventSelfTrade(result *MatchResult, level *PriceLevel, taker, maker *Order) {
+	expireMaker := func(qty int64) {
+		level.unlink(maker)
+		level.Total -= maker.LeavesQty
+		level.Visible -= maker.shownQty()
+		ob.forgetOrder(maker)
+		maker.LeavesQty = 0
+		result.STPMakers = append(result.STPMakers, &STPExpiry{Order: maker, Qty: qty, Canceled: true})
 	}
-	// 二分查找
-	lo, hi := 0, n
-	for lo < hi {
-		mid := lo + (hi-lo)/2
-		if descending {
-			if prices[mid] > price {
-				lo = mid + 1
-			} else {
-				hi = mid
-			}
+	expireTaker := func(qty int64) {
+		result.TakerExpired = true
+		result.TakerExpiredQty = qty
+		taker.LeavesQty = 0
+	}
+
+	switch taker.STPMode {
+	case STPExpireMaker:
+		expireMaker(maker.LeavesQty)
+	case STPExpireBoth:
+		expireMaker(maker.LeavesQty)
+		expireTaker(taker.LeavesQty)
+	case STPDecrement:
+		qty := min(taker.LeavesQty, maker.LeavesQty)
+		if maker.LeavesQty == qty {
+			expireMaker(qty)
 		} else {
-			if prices[mid] < price {
-				lo = mid + 1
-			} else {
-				hi = mid
-			}
+			shown := maker.shownQty()
+			maker.LeavesQty -= qty
+			maker.OrigQty -= qty
+			maker.clampVisible()
+			level.Total -= qty
+			level.Visible -= shown - maker.shownQty()
+			result.STPMakers = append(result.STPMakers, &STPExpiry{Order: maker, Qty: qty})
 		}
+		if taker.LeavesQty == qty {
+			expireTaker(qty)
+		} else {
+			taker.LeavesQty -= qty
+			taker.OrigQty -= qty
+			result.TakerReducedQty += qty
+		}
+	default: // STPExpireTaker
+		expireTaker(taker.LeavesQty)
 	}
-	if lo < n && prices[lo] == price {
-		return append(prices[:lo], prices[lo+1:]...)
-	}
-	return prices
 }
 
-func min(a, b int64) int64 {
-	if a < b {
-		return a
+func containsOrder(orders []*Order, order *Order) bool {
+	for _, o := range orders {
+		if o == order {
+			return true
+		}
 	}
-	return b
+	return false
 }
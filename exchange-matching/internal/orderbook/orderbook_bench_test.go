@@ -0,0 +1,141 @@
+package orderbook
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+// 深簿基准：每侧 benchLevels 个价位、每个价位 benchOrdersPerLevel 笔订单，
+// 对应长尾交易对上铺满网格单的场景。
+const (
+	benchLevels         = 10000
+	benchOrdersPerLevel = 4
+	benchMid            = 1_000_000
+)
+
+// newDeepBook 买盘 [mid-2*levels, mid) 与卖盘 (mid, mid+2*levels] 的偶数价位挂单，奇数价位留空
+func newDeepBook(b *testing.B) (*OrderBook, int64) {
+	b.Helper()
+	ob := NewOrderBook("BTCUSDT")
+	id := int64(0)
+	for i := int64(1); i <= benchLevels; i++ {
+		for j := 0; j < benchOrdersPerLevel; j++ {
+			id++
+			ob.AddOrder(&Order{OrderID: id, UserID: id%97 + 1, Side: SideBuy, Price: benchMid - 2*i, OrigQty: 10, LeavesQty: 10})
+			id++
+			ob.AddOrder(&Order{OrderID: id, UserID: id%97 + 1, Side: SideSell, Price: benchMid + 2*i, OrigQty: 10, LeavesQty: 10})
+		}
+	}
+	return ob, id
+}
+
+// BenchmarkDeepBookAddCancelNewLevel 在深簿中间新建价位再撤单（档位插入与删除）
+func BenchmarkDeepBookAddCancelNewLevel(b *testing.B) {
+	ob, id := newDeepBook(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id++
+		offset := 2*int64(i%benchLevels) + 1
+		ob.AddOrder(&Order{OrderID: id, UserID: 1000, Side: SideSell, Price: benchMid + offset, OrigQty: 10, LeavesQty: 10})
+		ob.RemoveOrder(id)
+	}
+}
+
+// BenchmarkDeepBookAddCancelExistingLevel 在已有价位排队再撤单
+func BenchmarkDeepBookAddCancelExistingLevel(b *testing.B) {
+	ob, id := newDeepBook(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id++
+		offset := 2 * (int64(i%benchLevels) + 1)
+		ob.AddOrder(&Order{OrderID: id, UserID: 1000, Side: SideBuy, Price: benchMid - offset, OrigQty: 10, LeavesQty: 10})
+		ob.RemoveOrder(id)
+	}
+}
+
+// BenchmarkDeepBookMatchBestLevel 吃掉最优价位后在深簿末端补回同样的价位
+func BenchmarkDeepBookMatchBestLevel(b *testing.B) {
+	ob, id := newDeepBook(b)
+	worst := int64(benchMid + 2*benchLevels)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id++
+		taker := &Order{OrderID: id, UserID: 2000, Side: SideBuy, OrigQty: 10 * benchOrdersPerLevel, LeavesQty: 10 * benchOrdersPerLevel}
+		if result := ob.Match(taker); !result.TakerFilled {
+			b.Fatal("expected taker filled")
+		}
+		worst += 2
+		for j := 0; j < benchOrdersPerLevel; j++ {
+			id++
+			ob.AddOrder(&Order{OrderID: id, UserID: id%97 + 1, Side: SideSell, Price: worst, OrigQty: 10, LeavesQty: 10})
+		}
+	}
+}
+
+// BenchmarkDeepBookSweep 一笔大单扫过 100 个价位，再补回被吃掉的价位
+func BenchmarkDeepBookSweep(b *testing.B) {
+	const sweepLevels = 100
+	ob, id := newDeepBook(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id++
+		qty := int64(10 * benchOrdersPerLevel * sweepLevels)
+		taker := &Order{OrderID: id, UserID: 2000, Side: SideSell, OrigQty: qty, LeavesQty: qty}
+		ob.Match(taker)
+		b.StopTimer()
+		for l := int64(1); l <= sweepLevels; l++ {
+			for j := 0; j < benchOrdersPerLevel; j++ {
+				id++
+				ob.AddOrder(&Order{OrderID: id, UserID: id%97 + 1, Side: SideBuy, Price: benchMid - 2*l, OrigQty: 10, LeavesQty: 10})
+			}
+		}
+		b.StartTimer()
+	}
+}
+
+// BenchmarkDeepBookPublish 订单簿变动后发布深度快照
+func BenchmarkDeepBookPublish(b *testing.B) {
+	ob, _ := newDeepBook(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.dirty = true
+		ob.Publish()
+	}
+}
+
+// BenchmarkDeepBookPublishedDepthWhileMatching 撮合 goroutine 持续变动订单簿时并发读取深度
+func BenchmarkDeepBookPublishedDepthWhileMatching(b *testing.B) {
+	ob, id := newDeepBook(b)
+	ob.Publish()
+	var stop atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; !stop.Load(); i++ {
+			id++
+			offset := 2*int64(i%benchLevels) + 1
+			ob.AddOrder(&Order{OrderID: id, UserID: 1000, Side: SideSell, Price: benchMid + offset, OrigQty: 10, LeavesQty: 10})
+			ob.Publish()
+			ob.RemoveOrder(id)
+			ob.Publish()
+		}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if bids, _ := ob.PublishedDepth(20); len(bids) != 20 {
+				b.Fatal("expected 20 bid levels")
+			}
+		}
+	})
+	b.StopTimer()
+	stop.Store(true)
+	<-done
+}
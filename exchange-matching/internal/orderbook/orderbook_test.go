@@ -1,6 +1,7 @@
 package orderbook
 
 import (
+	"math"
 	"testing"
 )
 
@@ -48,13 +49,27 @@ func TestOrderStruct(t *testing.T) {
 	}
 }
 
-func TestInsertPrice_MiddleInsert(t *testing.T) {
-	// 升序插入
-	prices := []int64{}
-	prices = insertPrice(prices, 100, false)
-	prices = insertPrice(prices, 50, false)
-	prices = insertPrice(prices, 150, false)
+// levelPrices 按价格优先级列出档位价格
+func levelPrices(s *levelList) []int64 {
+	prices := make([]int64, 0, s.Len())
+	s.each(func(level *PriceLevel) bool {
+		prices = append(prices, level.Price)
+		return true
+	})
+	return prices
+}
+
+func newLevels(desc bool, prices ...int64) *levelList {
+	s := newLevelList(desc)
+	for _, price := range prices {
+		s.getOrInsert(price)
+	}
+	return s
+}
 
+func TestLevelList_MiddleInsert(t *testing.T) {
+	// 升序插入
+	prices := levelPrices(newLevels(false, 100, 50, 150))
 	expected := []int64{50, 100, 150}
 	for i, p := range expected {
 		if prices[i] != p {
@@ -63,11 +78,7 @@ func TestInsertPrice_MiddleInsert(t *testing.T) {
 	}
 
 	// 降序插入
-	prices = []int64{}
-	prices = insertPrice(prices, 100, true)
-	prices = insertPrice(prices, 50, true)
-	prices = insertPrice(prices, 150, true)
-
+	prices = levelPrices(newLevels(true, 100, 50, 150))
 	expected = []int64{150, 100, 50}
 	for i, p := range expected {
 		if prices[i] != p {
@@ -76,31 +87,33 @@ func TestInsertPrice_MiddleInsert(t *testing.T) {
 	}
 }
 
-func TestRemovePrice_RemoveMiddle(t *testing.T) {
-	prices := []int64{50, 100, 150, 200}
+func TestLevelList_RemoveMiddle(t *testing.T) {
+	s := newLevels(false, 50, 100, 150, 200)
 
 	// 移除中间
-	result := removePrice(prices, 100, false)
-	if len(result) != 3 {
-		t.Errorf("expected len 3, got %d", len(result))
+	s.remove(s.get(100))
+	if got := levelPrices(s); len(got) != 3 || s.get(100) != nil {
+		t.Errorf("expected len 3, got %v", got)
 	}
 
-	// 移除不存在
-	result = removePrice([]int64{50, 150}, 100, false)
-	if len(result) != 2 {
-		t.Error("should not change when price not found")
+	// 移除其他侧的同价档位不影响本侧
+	other := newLevels(false, 150)
+	s.remove(other.get(150))
+	if s.Len() != 3 {
+		t.Error("should not change when level not in list")
 	}
 
-	// 空切片
-	result = removePrice([]int64{}, 100, false)
-	if len(result) != 0 {
-		t.Error("empty slice should remain empty")
+	// 空表
+	empty := newLevelList(false)
+	if empty.front() != nil || len(levelPrices(empty)) != 0 {
+		t.Error("empty list should remain empty")
 	}
 }
 
-func TestRemovePrice_Descending(t *testing.T) {
-	prices := []int64{200, 150, 100, 50}
-	result := removePrice(prices, 150, true)
+func TestLevelList_RemoveDescending(t *testing.T) {
+	s := newLevels(true, 200, 150, 100, 50)
+	s.remove(s.get(150))
+	result := levelPrices(s)
 	expected := []int64{200, 100, 50}
 	if len(result) != len(expected) {
 		t.Fatalf("expected len %d, got %d", len(expected), len(result))
@@ -112,6 +125,33 @@ func TestRemovePrice_Descending(t *testing.T) {
 	}
 }
 
+func TestLevelList_ManyLevels(t *testing.T) {
+	s := newLevelList(false)
+	// 乱序插入后删除奇数价位，剩余价位保持升序
+	for i := int64(0); i < 5000; i++ {
+		s.getOrInsert((i * 7919) % 5000)
+	}
+	for price := int64(1); price < 5000; price += 2 {
+		s.remove(s.get(price))
+	}
+	prices := levelPrices(s)
+	if len(prices) != 2500 {
+		t.Fatalf("expected 2500 levels, got %d", len(prices))
+	}
+	for i, price := range prices {
+		if price != int64(2*i) {
+			t.Fatalf("prices[%d] expected %d, got %d", i, 2*i, price)
+		}
+	}
+	// 反复删除最优档位
+	for i := 0; i < 100; i++ {
+		s.remove(s.front())
+	}
+	if front := s.front(); front == nil || front.Price != 200 || s.Len() != 2400 {
+		t.Fatalf("unexpected front after removing best levels: %+v", front)
+	}
+}
+
 func TestPriceLevelStruct(t *testing.T) {
 	level := &PriceLevel{
 		Price: 50000,
@@ -230,6 +270,49 @@ func TestRemoveOrder(t *testing.T) {
 	}
 }
 
+func TestRemoveUserOrders(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 3, UserID: 100, Side: SideSell, Price: 50100, OrigQty: 10, LeavesQty: 10})
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideBuy, Price: 50000, OrigQty: 10, LeavesQty: 10})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 200, Side: SideBuy, Price: 50000, OrigQty: 10, LeavesQty: 10})
+	ob.AddOrder(&Order{OrderID: 4, UserID: 100, Side: SideBuy, Price: 49900, OrigQty: 10, LeavesQty: 10})
+
+	removed := ob.RemoveUserOrders(100, SideBuy)
+	if len(removed) != 2 || removed[0].OrderID != 1 || removed[1].OrderID != 4 {
+		t.Fatalf("expected buy orders 1,4 removed, got %+v", removed)
+	}
+	if price, qty, _ := ob.BestBid(); price != 50000 || qty != 10 {
+		t.Fatalf("expected other user's bid kept, got %d x %d", price, qty)
+	}
+
+	removed = ob.RemoveUserOrders(100, 0)
+	if len(removed) != 1 || removed[0].OrderID != 3 {
+		t.Fatalf("expected remaining order 3 removed, got %+v", removed)
+	}
+	if _, _, ok := ob.BestAsk(); ok {
+		t.Fatal("expected empty asks")
+	}
+	if len(ob.RemoveUserOrders(100, 0)) != 0 {
+		t.Fatal("expected no orders left for user")
+	}
+}
+
+func TestUserQuotes(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 5, UserID: 100, Side: SideSell, Price: 50100, OrigQty: 10, LeavesQty: 10, QuoteID: 9})
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideBuy, Price: 50000, OrigQty: 10, LeavesQty: 10})
+	ob.AddOrder(&Order{OrderID: 3, UserID: 100, Side: SideBuy, Price: 49900, OrigQty: 10, LeavesQty: 10, QuoteID: 9})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 200, Side: SideBuy, Price: 49900, OrigQty: 10, LeavesQty: 10, QuoteID: 7})
+
+	quotes := ob.UserQuotes(100)
+	if len(quotes) != 2 || quotes[0].OrderID != 3 || quotes[1].OrderID != 5 {
+		t.Fatalf("expected quotes 3,5, got %+v", quotes)
+	}
+	if len(ob.UserQuotes(300)) != 0 {
+		t.Fatal("expected no quotes for unknown user")
+	}
+}
+
 func TestRemoveNonExistentOrder(t *testing.T) {
 	ob := NewOrderBook("BTCUSDT")
 	removed := ob.RemoveOrder(999)
@@ -326,6 +409,54 @@ func TestDepthLimit(t *testing.T) {
 	}
 }
 
+func TestPublishedDepth(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	if bids, asks := ob.PublishedDepth(10); len(bids) != 0 || len(asks) != 0 {
+		t.Fatalf("expected empty depth before publish, got %v %v", bids, asks)
+	}
+
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideBuy, Price: 50000, LeavesQty: 100})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 100, Side: SideSell, Price: 51000, LeavesQty: 150})
+	if bids, _ := ob.PublishedDepth(10); len(bids) != 0 {
+		t.Fatal("expected unpublished changes hidden from readers")
+	}
+
+	ob.Publish()
+	bids, asks := ob.PublishedDepth(10)
+	if len(bids) != 1 || bids[0].Qty != 100 || len(asks) != 1 || asks[0].Price != 51000 {
+		t.Fatalf("unexpected published depth: %v %v", bids, asks)
+	}
+
+	// 读取方拿到的是副本
+	bids[0].Qty = 1
+	ob.RemoveOrder(1)
+	if again, _ := ob.PublishedDepth(10); len(again) != 1 || again[0].Qty != 100 {
+		t.Fatalf("expected published snapshot unchanged until next publish, got %v", again)
+	}
+	ob.Publish()
+	if again, _ := ob.PublishedDepth(10); len(again) != 0 {
+		t.Fatalf("expected bid removed after publish, got %v", again)
+	}
+}
+
+func TestLevelReusedAfterEmpty(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideSell, Price: 50000, LeavesQty: 10})
+	ob.RemoveOrder(1)
+	if ob.asks.Len() != 0 || len(ob.asks.free) != 1 {
+		t.Fatalf("expected empty level returned to pool, levels=%d free=%d", ob.asks.Len(), len(ob.asks.free))
+	}
+
+	ob.AddOrder(&Order{OrderID: 2, UserID: 100, Side: SideSell, Price: 49000, LeavesQty: 5})
+	price, qty, ok := ob.BestAsk()
+	if !ok || price != 49000 || qty != 5 || len(ob.asks.free) != 0 {
+		t.Fatalf("unexpected reused level price=%d qty=%d", price, qty)
+	}
+	if order := ob.GetOrder(2); order.level == nil || order.level.Len() != 1 || order.level.Front() != order {
+		t.Fatal("expected order linked into reused level")
+	}
+}
+
 func TestReduceOrderQty(t *testing.T) {
 	ob := NewOrderBook("BTCUSDT")
 
@@ -355,6 +486,34 @@ func TestReduceOrderQtyToZero(t *testing.T) {
 	}
 }
 
+func TestDecreaseOrderQtyKeepsPriority(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideSell, Price: 50000, OrigQty: 100, LeavesQty: 100})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 101, Side: SideSell, Price: 50000, OrigQty: 50, LeavesQty: 50})
+
+	if !ob.DecreaseOrderQty(1, 60) {
+		t.Fatal("expected decrease to succeed")
+	}
+	if ob.DecreaseOrderQty(1, 40) {
+		t.Fatal("expected decrease to full leaves qty to fail")
+	}
+
+	order := ob.GetOrder(1)
+	if order.LeavesQty != 40 || order.OrigQty != 40 {
+		t.Fatalf("unexpected order qty: leaves=%d orig=%d", order.LeavesQty, order.OrigQty)
+	}
+	_, asks := ob.Depth(1)
+	if len(asks) != 1 || asks[0].Qty != 90 {
+		t.Fatalf("unexpected asks: %#v", asks)
+	}
+
+	result := ob.Match(&Order{OrderID: 3, UserID: 200, Side: SideBuy, Price: 50000, OrigQty: 10, LeavesQty: 10})
+	if len(result.Trades) != 1 || result.Trades[0].MakerOrderID != 1 {
+		t.Fatalf("expected amended order to keep time priority: %#v", result.Trades)
+	}
+}
+
 func TestMatchBuyOrder(t *testing.T) {
 	ob := NewOrderBook("BTCUSDT")
 
@@ -487,25 +646,159 @@ func TestMatchSelfTradePrevention(t *testing.T) {
 	}
 }
 
-func TestInsertPrice_BidAskMiddle(t *testing.T) {
+func newSTPBook() *OrderBook {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideSell, Price: 50000, OrigQty: 30, LeavesQty: 30})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 200, Side: SideSell, Price: 50000, OrigQty: 20, LeavesQty: 20})
+	return ob
+}
+
+func TestMatchSTPExpireTaker(t *testing.T) {
+	ob := newSTPBook()
+	taker := &Order{OrderID: 3, UserID: 100, Side: SideBuy, Price: 50000, OrigQty: 40, LeavesQty: 40, STPMode: STPExpireTaker}
+
+	result := ob.Match(taker)
+
+	if len(result.Trades) != 0 || len(result.STPMakers) != 0 {
+		t.Fatalf("expected no trades or maker expiry, got trades=%d makers=%d", len(result.Trades), len(result.STPMakers))
+	}
+	if !result.TakerExpired || result.TakerExpiredQty != 40 || result.TakerFilled {
+		t.Fatalf("unexpected taker result: %+v", result)
+	}
+	if _, qty, _ := ob.BestAsk(); qty != 50 {
+		t.Fatalf("expected book untouched, ask qty=%d", qty)
+	}
+}
+
+func TestMatchSTPExpireMaker(t *testing.T) {
+	ob := newSTPBook()
+	taker := &Order{OrderID: 3, UserID: 100, Side: SideBuy, Price: 50000, OrigQty: 40, LeavesQty: 40, STPMode: STPExpireMaker}
+
+	result := ob.Match(taker)
+
+	if len(result.STPMakers) != 1 || !result.STPMakers[0].Canceled || result.STPMakers[0].Qty != 30 {
+		t.Fatalf("expected maker 1 expired, got %+v", result.STPMakers)
+	}
+	if ob.GetOrder(1) != nil {
+		t.Fatal("expected self maker removed from book")
+	}
+	if len(result.Trades) != 1 || result.Trades[0].MakerOrderID != 2 || result.Trades[0].Qty != 20 {
+		t.Fatalf("unexpected trades: %+v", result.Trades)
+	}
+	if result.TakerExpired || taker.LeavesQty != 20 {
+		t.Fatalf("expected taker to keep 20 leaves, got expired=%v leaves=%d", result.TakerExpired, taker.LeavesQty)
+	}
+	if _, _, ok := ob.BestAsk(); ok {
+		t.Fatal("expected ask level removed")
+	}
+}
+
+func TestMatchSTPExpireBoth(t *testing.T) {
+	ob := newSTPBook()
+	taker := &Order{OrderID: 3, UserID: 100, Side: SideBuy, Price: 50000, OrigQty: 40, LeavesQty: 40, STPMode: STPExpireBoth}
+
+	result := ob.Match(taker)
+
+	if len(result.STPMakers) != 1 || result.STPMakers[0].Order.OrderID != 1 || !result.STPMakers[0].Canceled {
+		t.Fatalf("expected maker 1 expired, got %+v", result.STPMakers)
+	}
+	if !result.TakerExpired || result.TakerExpiredQty != 40 || len(result.Trades) != 0 {
+		t.Fatalf("unexpected taker result: %+v", result)
+	}
+	if _, qty, _ := ob.BestAsk(); qty != 20 {
+		t.Fatalf("expected ask qty=20, got %d", qty)
+	}
+}
+
+func TestMatchSTPDecrement(t *testing.T) {
+	ob := newSTPBook()
+	taker := &Order{OrderID: 3, UserID: 100, Side: SideBuy, Price: 50000, OrigQty: 40, LeavesQty: 40, STPMode: STPDecrement}
+
+	result := ob.Match(taker)
+
+	// maker 1 (30) 被完全扣减撤销，taker 扣减 30 后与 maker 2 成交 10
+	if len(result.STPMakers) != 1 || !result.STPMakers[0].Canceled || result.STPMakers[0].Qty != 30 {
+		t.Fatalf("expected maker 1 decremented to zero, got %+v", result.STPMakers)
+	}
+	if result.TakerReducedQty != 30 || taker.OrigQty != 10 {
+		t.Fatalf("expected taker reduced by 30, reduced=%d orig=%d", result.TakerReducedQty, taker.OrigQty)
+	}
+	if len(result.Trades) != 1 || result.Trades[0].Qty != 10 || !result.TakerFilled {
+		t.Fatalf("unexpected trades=%+v filled=%v", result.Trades, result.TakerFilled)
+	}
+	if _, qty, _ := ob.BestAsk(); qty != 10 {
+		t.Fatalf("expected ask qty=10, got %d", qty)
+	}
+}
+
+func TestMatchSTPDecrementReducesMaker(t *testing.T) {
+	ob := newSTPBook()
+	taker := &Order{OrderID: 3, UserID: 100, Side: SideBuy, Price: 50000, OrigQty: 10, LeavesQty: 10, STPMode: STPDecrement}
+
+	result := ob.Match(taker)
+
+	if len(result.STPMakers) != 1 || result.STPMakers[0].Canceled || result.STPMakers[0].Qty != 10 {
+		t.Fatalf("expected maker 1 reduced by 10, got %+v", result.STPMakers)
+	}
+	maker := ob.GetOrder(1)
+	if maker == nil || maker.LeavesQty != 20 || maker.OrigQty != 20 {
+		t.Fatalf("unexpected maker after decrement: %+v", maker)
+	}
+	if !result.TakerExpired || result.TakerExpiredQty != 10 || result.TakerFilled || len(result.Trades) != 0 {
+		t.Fatalf("unexpected taker result: %+v", result)
+	}
+	if _, qty, _ := ob.BestAsk(); qty != 40 {
+		t.Fatalf("expected ask qty=40, got %d", qty)
+	}
+}
+
+func TestCanFill(t *testing.T) {
+	ob := newSTPBook()
+	ob.AddOrder(&Order{OrderID: 4, UserID: 300, Side: SideSell, Price: 50100, OrigQty: 10, LeavesQty: 10})
+
+	cases := []struct {
+		name  string
+		taker *Order
+		want  bool
+	}{
+		{"enough across levels", &Order{UserID: 900, Side: SideBuy, Price: 50100, LeavesQty: 60}, true},
+		{"limit price caps levels", &Order{UserID: 900, Side: SideBuy, Price: 50000, LeavesQty: 60}, false},
+		{"market order", &Order{UserID: 900, Side: SideBuy, Price: 0, LeavesQty: 60}, true},
+		{"not enough", &Order{UserID: 900, Side: SideBuy, Price: 0, LeavesQty: 61}, false},
+		{"self trade expires taker", &Order{UserID: 100, Side: SideBuy, Price: 50100, LeavesQty: 10, STPMode: STPExpireTaker}, false},
+		{"self trade decrements taker", &Order{UserID: 100, Side: SideBuy, Price: 50100, LeavesQty: 10, STPMode: STPDecrement}, false},
+		{"expire maker skips own order", &Order{UserID: 100, Side: SideBuy, Price: 50100, LeavesQty: 30, STPMode: STPExpireMaker}, true},
+		{"expire maker excludes own qty", &Order{UserID: 100, Side: SideBuy, Price: 50100, LeavesQty: 31, STPMode: STPExpireMaker}, false},
+		{"empty side", &Order{UserID: 900, Side: SideSell, Price: 0, LeavesQty: 1}, false},
+	}
+	for _, tc := range cases {
+		if got := ob.CanFill(tc.taker); got != tc.want {
+			t.Fatalf("%s: CanFill=%v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if _, qty, _ := ob.BestAsk(); qty != 50 {
+		t.Fatalf("expected book untouched, ask qty=%d", qty)
+	}
+}
+
+func TestLevelList_BidAskMiddle(t *testing.T) {
 	// Test descending (bids)
-	prices := []int64{50000, 49000, 48000}
-	prices = insertPrice(prices, 49500, true)
+	prices := levelPrices(newLevels(true, 50000, 49000, 48000, 49500))
 	if prices[1] != 49500 {
 		t.Fatalf("expected 49500 at index 1, got %d", prices[1])
 	}
 
 	// Test ascending (asks)
-	prices = []int64{50000, 51000, 52000}
-	prices = insertPrice(prices, 50500, false)
+	prices = levelPrices(newLevels(false, 50000, 51000, 52000, 50500))
 	if prices[1] != 50500 {
 		t.Fatalf("expected 50500 at index 1, got %d", prices[1])
 	}
 }
 
-func TestRemovePrice_AscendingInput(t *testing.T) {
-	prices := []int64{48000, 49000, 50000}
-	prices = removePrice(prices, 49000, false)
+func TestLevelList_RemoveAscending(t *testing.T) {
+	s := newLevels(false, 48000, 49000, 50000)
+	s.remove(s.get(49000))
+	prices := levelPrices(s)
 	if len(prices) != 2 {
 		t.Fatalf("expected 2 prices, got %d", len(prices))
 	}
@@ -525,3 +818,98 @@ func TestMin(t *testing.T) {
 		t.Fatal("expected min(10, 10) = 10")
 	}
 }
+
+func TestIcebergDepthShowsDisplayQty(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideSell, Price: 50000, OrigQty: 100, LeavesQty: 100, DisplayQty: 10})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 200, Side: SideSell, Price: 50000, OrigQty: 5, LeavesQty: 5})
+
+	_, asks := ob.Depth(5)
+	if len(asks) != 1 || asks[0].Qty != 15 {
+		t.Fatalf("expected only visible qty in depth, got %+v", asks)
+	}
+	if _, qty, _ := ob.BestAsk(); qty != 105 {
+		t.Fatalf("expected best ask total 105, got %d", qty)
+	}
+
+	ob.RemoveOrder(1)
+	_, asks = ob.Depth(5)
+	if len(asks) != 1 || asks[0].Qty != 5 {
+		t.Fatalf("unexpected depth after remove: %+v", asks)
+	}
+}
+
+func TestIcebergReplenishRequeues(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideSell, Price: 50000, OrigQty: 25, LeavesQty: 25, DisplayQty: 10})
+	ob.AddOrder(&Order{OrderID: 2, UserID: 200, Side: SideSell, Price: 50000, OrigQty: 5, LeavesQty: 5})
+
+	// 吃掉冰山单第一个展示部分后，冰山单补充并排到订单 2 之后
+	taker := &Order{OrderID: 3, UserID: 300, Side: SideBuy, Price: 50000, OrigQty: 12, LeavesQty: 12}
+	result := ob.Match(taker)
+
+	if len(result.Trades) != 2 {
+		t.Fatalf("expected 2 trades, got %d", len(result.Trades))
+	}
+	if result.Trades[0].MakerOrderID != 1 || result.Trades[0].Qty != 10 {
+		t.Fatalf("unexpected first trade: %+v", result.Trades[0])
+	}
+	if result.Trades[1].MakerOrderID != 2 || result.Trades[1].Qty != 2 {
+		t.Fatalf("expected requeued iceberg behind order 2, got %+v", result.Trades[1])
+	}
+	iceberg := ob.GetOrder(1)
+	if iceberg.LeavesQty != 15 || iceberg.VisibleQty != 10 {
+		t.Fatalf("unexpected iceberg state leaves=%d visible=%d", iceberg.LeavesQty, iceberg.VisibleQty)
+	}
+	_, asks := ob.Depth(5)
+	if len(asks) != 1 || asks[0].Qty != 13 {
+		t.Fatalf("expected visible 10+3, got %+v", asks)
+	}
+}
+
+func TestIcebergSweepsHiddenQty(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideSell, Price: 50000, OrigQty: 25, LeavesQty: 25, DisplayQty: 10})
+
+	taker := &Order{OrderID: 2, UserID: 200, Side: SideBuy, Price: 50000, OrigQty: 30, LeavesQty: 30}
+	result := ob.Match(taker)
+
+	if len(result.Trades) != 3 || taker.LeavesQty != 5 {
+		t.Fatalf("expected 3 slices filled, trades=%d leaves=%d", len(result.Trades), taker.LeavesQty)
+	}
+	if len(result.MakerUpdates) != 1 || result.MakerUpdates[0].LeavesQty != 0 {
+		t.Fatalf("expected single maker update, got %+v", result.MakerUpdates)
+	}
+	if _, _, ok := ob.BestAsk(); ok {
+		t.Fatal("expected ask side empty")
+	}
+}
+
+func TestIcebergDecreaseClampsVisible(t *testing.T) {
+	ob := NewOrderBook("BTCUSDT")
+	ob.AddOrder(&Order{OrderID: 1, UserID: 100, Side: SideBuy, Price: 50000, OrigQty: 25, LeavesQty: 25, DisplayQty: 10})
+
+	if !ob.DecreaseOrderQty(1, 20) {
+		t.Fatal("expected decrease to succeed")
+	}
+	bids, _ := ob.Depth(5)
+	if len(bids) != 1 || bids[0].Qty != 5 || ob.GetOrder(1).VisibleQty != 5 {
+		t.Fatalf("unexpected depth after decrease: %+v", bids)
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	if got := MulDiv(1999, 10, 101); got != 197 {
+		t.Fatalf("expected 197, got %d", got)
+	}
+	// 中间结果超出 int64
+	if got := MulDiv(5_000_000_000_000, 100_000_000, 50_000_000_000); got != 10_000_000_000 {
+		t.Fatalf("expected 10000000000, got %d", got)
+	}
+	if got := MulDiv(math.MaxInt64, 4, 2); got != math.MaxInt64 {
+		t.Fatalf("expected clamp to MaxInt64, got %d", got)
+	}
+	if got := MulDiv(100, 10, 0); got != 0 {
+		t.Fatalf("expected 0 for zero divisor, got %d", got)
+	}
+}
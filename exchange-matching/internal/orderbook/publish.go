@@ -0,0 +1,47 @@
+package orderbook
+
+// PublishedLevels 深度快照每一侧发布的档位数量
+const PublishedLevels = 100
+
+// depthSnapshot 已发布的深度快照，发布后不再修改
+type depthSnapshot struct {
+	bids []PriceQty
+	asks []PriceQty
+}
+
+// depth 按价格优先级取前 limit 档（冰山单仅计展示数量）
+func (s *levelList) depth(limit int) []PriceQty {
+	levels := make([]PriceQty, 0, max(min(limit, s.Len()), 0))
+	for level := s.front(); level != nil && len(levels) < limit; level = level.forward[0] {
+		levels = append(levels, PriceQty{Price: level.Price, Qty: level.Visible})
+	}
+	return levels
+}
+
+// Publish 订单簿有变动时发布前 PublishedLevels 档深度快照（由撮合引擎 goroutine 调用）
+func (ob *OrderBook) Publish() {
+	if !ob.dirty {
+		return
+	}
+	ob.published.Store(&depthSnapshot{
+		bids: ob.bids.depth(PublishedLevels),
+		asks: ob.asks.depth(PublishedLevels),
+	})
+	ob.dirty = false
+}
+
+// PublishedDepth 读取最近一次发布的深度（任意 goroutine 可调用，limit 不超过 PublishedLevels）
+func (ob *OrderBook) PublishedDepth(limit int) (bids, asks []PriceQty) {
+	snap := ob.published.Load()
+	if snap == nil {
+		return []PriceQty{}, []PriceQty{}
+	}
+	return clonePrefix(snap.bids, limit), clonePrefix(snap.asks, limit)
+}
+
+func clonePrefix(levels []PriceQty, limit int) []PriceQty {
+	n := max(min(limit, len(levels)), 0)
+	out := make([]PriceQty, n)
+	copy(out, levels[:n])
+	return out
+}
@@ -3,6 +3,7 @@ package recovery
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -27,12 +28,31 @@ func (l *DBOrderLoader) ListActiveSymbols(ctx context.Context) ([]string, error)
 	const query = `
 		SELECT DISTINCT symbol
 		FROM exchange_order.orders
-		WHERE status IN (1, 2) AND type = 1
+		WHERE status IN (1, 2)
+		  AND (type IN (1, 4) OR (type IN (3, 5, 6) AND trigger_time_ms IS NULL))
 		ORDER BY symbol ASC
 	`
+	return l.querySymbols(ctx, query, "list active symbols")
+}
+
+// ListAuctionSymbols 列出处于集合竞价状态的交易对（symbol_configs.status=4）
+func (l *DBOrderLoader) ListAuctionSymbols(ctx context.Context) ([]string, error) {
+	if l == nil || l.db == nil {
+		return nil, fmt.Errorf("db not configured")
+	}
+	const query = `
+		SELECT symbol
+		FROM exchange_order.symbol_configs
+		WHERE status = 4
+		ORDER BY symbol ASC
+	`
+	return l.querySymbols(ctx, query, "list auction symbols")
+}
+
+func (l *DBOrderLoader) querySymbols(ctx context.Context, query, op string) ([]string, error) {
 	rows, err := l.db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("list active symbols: %w", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
 
@@ -66,8 +86,24 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*t
 			o.type,
 			o.time_in_force,
 			o.price::text,
+			COALESCE(o.stop_price, 0)::text,
+			o.trigger_time_ms IS NOT NULL,
+			o.trailing_delta::text,
+			o.trailing_bps,
+			o.activation_price::text,
+			o.trailing_extreme_price::text,
+			o.stp_mode,
 			o.orig_qty::text,
 			o.executed_qty::text,
+			o.display_qty::text,
+			o.expire_time_ms,
+			COALESCE(o.quote_id, 0),
+			COALESCE(o.list_id, 0),
+			COALESCE((
+				SELECT s.order_id FROM exchange_order.orders s
+				WHERE s.list_id = o.list_id AND s.order_id <> o.order_id
+				LIMIT 1
+			), 0),
 			o.create_time_ms,
 			sc.price_precision,
 			sc.qty_precision
@@ -75,8 +111,8 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*t
 		JOIN exchange_order.symbol_configs sc ON sc.symbol = o.symbol
 		WHERE o.symbol = $1
 		  AND o.status IN (1, 2)
-		  AND o.type = 1
-		ORDER BY o.create_time_ms ASC, o.order_id ASC
+		  AND (o.type IN (1, 4) OR (o.type IN (3, 5, 6) AND o.trigger_time_ms IS NULL))
+		ORDER BY COALESCE(o.trigger_time_ms, o.create_time_ms) ASC, o.order_id ASC
 	`
 	rows, err := l.db.QueryContext(ctx, query, symbol)
 	if err != nil {
@@ -95,8 +131,20 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*t
 			orderType     int
 			timeInForce   int
 			priceRaw      string
+			stopPriceRaw  string
+			triggered     bool
+			trailingRaw   string
+			trailingBps   int64
+			activationRaw string
+			extremeRaw    string
+			stpMode       int
 			origQtyRaw    string
 			executedRaw   string
+			displayRaw    string
+			expireTimeMs  int64
+			quoteID       int64
+			listID        int64
+			siblingID     int64
 			createTimeMs  int64
 			pricePrec     int
 			qtyPrec       int
@@ -110,8 +158,20 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*t
 			&orderType,
 			&timeInForce,
 			&priceRaw,
+			&stopPriceRaw,
+			&triggered,
+			&trailingRaw,
+			&trailingBps,
+			&activationRaw,
+			&extremeRaw,
+			&stpMode,
 			&origQtyRaw,
 			&executedRaw,
+			&displayRaw,
+			&expireTimeMs,
+			&quoteID,
+			&listID,
+			&siblingID,
 			&createTimeMs,
 			&pricePrec,
 			&qtyPrec,
@@ -123,6 +183,22 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*t
 		if err != nil {
 			return nil, fmt.Errorf("parse price: orderID=%d: %w", orderID, err)
 		}
+		stopPrice, err := parseScaledInt(stopPriceRaw, pricePrec)
+		if err != nil {
+			return nil, fmt.Errorf("parse stop_price: orderID=%d: %w", orderID, err)
+		}
+		trailingDelta, err := parseScaledInt(trailingRaw, pricePrec)
+		if err != nil {
+			return nil, fmt.Errorf("parse trailing_delta: orderID=%d: %w", orderID, err)
+		}
+		activation, err := parseScaledInt(activationRaw, pricePrec)
+		if err != nil {
+			return nil, fmt.Errorf("parse activation_price: orderID=%d: %w", orderID, err)
+		}
+		extreme, err := parseScaledInt(extremeRaw, pricePrec)
+		if err != nil {
+			return nil, fmt.Errorf("parse trailing_extreme_price: orderID=%d: %w", orderID, err)
+		}
 		origQty, err := parseScaledInt(origQtyRaw, qtyPrec)
 		if err != nil {
 			return nil, fmt.Errorf("parse orig_qty: orderID=%d: %w", orderID, err)
@@ -131,22 +207,39 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*t
 		if err != nil {
 			return nil, fmt.Errorf("parse executed_qty: orderID=%d: %w", orderID, err)
 		}
+		displayQty, err := parseScaledInt(displayRaw, qtyPrec)
+		if err != nil {
+			return nil, fmt.Errorf("parse display_qty: orderID=%d: %w", orderID, err)
+		}
 		leavesQty := origQty - executedQty
 		if leavesQty < 0 {
 			leavesQty = 0
 		}
 
 		orders = append(orders, &types.OpenOrder{
-			OrderID:       orderID,
-			ClientOrderID: clientOrderID,
-			UserID:        userID,
-			Symbol:        dbSymbol,
-			Side:          sideToString(side),
-			OrderType:     orderTypeToString(orderType),
-			TimeInForce:   timeInForceToString(timeInForce),
-			Price:         price,
-			LeavesQty:     leavesQty,
-			CreatedAt:     createTimeMs * 1_000_000, // ms -> ns
+			OrderID:         orderID,
+			ClientOrderID:   clientOrderID,
+			UserID:          userID,
+			Symbol:          dbSymbol,
+			Side:            sideToString(side),
+			OrderType:       orderTypeToString(orderType),
+			TimeInForce:     timeInForceToString(timeInForce),
+			Price:           price,
+			StopPrice:       stopPrice,
+			Triggered:       triggered,
+			STPMode:         stpModeToString(stpMode),
+			TrailingDelta:   trailingDelta,
+			TrailingBps:     trailingBps,
+			ActivationPrice: activation,
+			TrailingExtreme: extreme,
+			OrigQty:         origQty,
+			LeavesQty:       leavesQty,
+			DisplayQty:      displayQty,
+			ExpireTimeMs:    expireTimeMs,
+			QuoteID:         quoteID,
+			ListID:          listID,
+			SiblingOrderID:  siblingID,
+			CreatedAt:       createTimeMs * 1_000_000, // ms -> ns
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -155,6 +248,28 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*t
 	return orders, nil
 }
 
+// LoadLastPrice 加载交易对的最新成交价（按计价精度缩放），没有成交时返回 0
+func (l *DBOrderLoader) LoadLastPrice(ctx context.Context, symbol string) (int64, error) {
+	if l == nil || l.db == nil {
+		return 0, fmt.Errorf("db not configured")
+	}
+	const query = `
+		SELECT price
+		FROM exchange_order.trades
+		WHERE symbol = $1
+		ORDER BY timestamp_ms DESC, trade_id DESC
+		LIMIT 1
+	`
+	var price int64
+	if err := l.db.QueryRowContext(ctx, query, symbol).Scan(&price); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("load last price: %w", err)
+	}
+	return price, nil
+}
+
 func parseScaledInt(value string, precision int) (int64, error) {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -187,6 +302,29 @@ func orderTypeToString(orderType int) string {
 		return "LIMIT"
 	case 2:
 		return "MARKET"
+	case 3:
+		return "STOP_LOSS"
+	case 4:
+		return "STOP_LOSS_LIMIT"
+	case 5:
+		return "TAKE_PROFIT"
+	case 6:
+		return "TRAILING_STOP"
+	default:
+		return ""
+	}
+}
+
+func stpModeToString(mode int) string {
+	switch mode {
+	case 1:
+		return "EXPIRE_TAKER"
+	case 2:
+		return "EXPIRE_MAKER"
+	case 3:
+		return "EXPIRE_BOTH"
+	case 4:
+		return "DECREMENT"
 	default:
 		return ""
 	}
@@ -202,6 +340,10 @@ func timeInForceToString(tif int) string {
 		return "FOK"
 	case 4:
 		return "POST_ONLY"
+	case 5:
+		return "GTD"
+	case 6:
+		return "DAY"
 	default:
 		return ""
 	}
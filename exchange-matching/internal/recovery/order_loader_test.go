@@ -40,7 +40,15 @@ func TestEnumMappers(t *testing.T) {
 	if orderTypeToString(1) != "LIMIT" || orderTypeToString(2) != "MARKET" {
 		t.Fatal("order type mapper failed")
 	}
-	if timeInForceToString(1) != "GTC" || timeInForceToString(4) != "POST_ONLY" {
+	if orderTypeToString(3) != "STOP_LOSS" || orderTypeToString(4) != "STOP_LOSS_LIMIT" || orderTypeToString(5) != "TAKE_PROFIT" ||
+		orderTypeToString(6) != "TRAILING_STOP" {
+		t.Fatal("stop order type mapper failed")
+	}
+	if timeInForceToString(1) != "GTC" || timeInForceToString(4) != "POST_ONLY" ||
+		timeInForceToString(5) != "GTD" || timeInForceToString(6) != "DAY" {
 		t.Fatal("tif mapper failed")
 	}
+	if stpModeToString(1) != "EXPIRE_TAKER" || stpModeToString(4) != "DECREMENT" || stpModeToString(0) != "" {
+		t.Fatal("stp mode mapper failed")
+	}
 }
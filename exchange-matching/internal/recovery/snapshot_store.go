@@ -0,0 +1,127 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/exchange/matching/internal/engine"
+)
+
+const snapshotExt = ".snap"
+
+var snapshotSymbolPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// FileSnapshotStore 本地文件快照存储：每个 symbol 一个文件，先写临时文件再原子替换。
+type FileSnapshotStore struct {
+	dir string
+}
+
+func NewFileSnapshotStore(dir string) (*FileSnapshotStore, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("snapshot dir required")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create snapshot dir: %w", err)
+	}
+	return &FileSnapshotStore{dir: dir}, nil
+}
+
+func (s *FileSnapshotStore) Save(ctx context.Context, snap *engine.Snapshot) error {
+	if snap == nil {
+		return fmt.Errorf("nil snapshot")
+	}
+	path, err := s.path(snap.Symbol)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, snap.Symbol+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp snapshot: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // rename 成功后为空操作
+
+	if err := engine.EncodeSnapshot(tmp, snap); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load 读取 symbol 的快照，不存在时返回 nil, nil
+func (s *FileSnapshotStore) Load(ctx context.Context, symbol string) (*engine.Snapshot, error) {
+	path, err := s.path(symbol)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	snap, err := engine.DecodeSnapshot(f)
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot %s: %w", symbol, err)
+	}
+	if snap.Symbol != symbol {
+		return nil, fmt.Errorf("snapshot symbol mismatch: %s != %s", snap.Symbol, symbol)
+	}
+	return snap, nil
+}
+
+// Delete 删除 symbol 的快照（不存在时不报错）
+func (s *FileSnapshotStore) Delete(ctx context.Context, symbol string) error {
+	path, err := s.path(symbol)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSymbols 列出已有快照的 symbol（升序）
+func (s *FileSnapshotStore) ListSymbols(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot dir: %w", err)
+	}
+	var symbols []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotExt) {
+			continue
+		}
+		symbols = append(symbols, strings.TrimSuffix(name, snapshotExt))
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+func (s *FileSnapshotStore) path(symbol string) (string, error) {
+	if !snapshotSymbolPattern.MatchString(symbol) {
+		return "", fmt.Errorf("invalid snapshot symbol: %q", symbol)
+	}
+	return filepath.Join(s.dir, symbol+snapshotExt), nil
+}
@@ -0,0 +1,67 @@
+package recovery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/exchange/matching/internal/engine"
+	"github.com/exchange/matching/internal/orderbook"
+)
+
+func TestFileSnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	if snap, err := store.Load(ctx, "BTCUSDT"); err != nil || snap != nil {
+		t.Fatalf("expected missing snapshot, got %v %v", snap, err)
+	}
+
+	snap := &engine.Snapshot{
+		Version:  engine.SnapshotVersion,
+		Symbol:   "BTCUSDT",
+		Seq:      42,
+		StreamID: "1700000000000-3",
+		Orders: []orderbook.Order{
+			{OrderID: 1, UserID: 10, Side: orderbook.SideSell, Price: 100, OrigQty: 5, LeavesQty: 5, Timestamp: 7},
+		},
+	}
+	if err := store.Save(ctx, snap); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	snap.Seq = 43
+	if err := store.Save(ctx, snap); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+
+	got, err := store.Load(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Seq != 43 || got.StreamID != "1700000000000-3" || len(got.Orders) != 1 || got.Orders[0].Timestamp != 7 {
+		t.Fatalf("unexpected snapshot: %+v", got)
+	}
+
+	symbols, err := store.ListSymbols(ctx)
+	if err != nil || len(symbols) != 1 || symbols[0] != "BTCUSDT" {
+		t.Fatalf("unexpected symbols: %v %v", symbols, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected temp files cleaned up, got %d entries", len(entries))
+	}
+
+	if err := store.Delete(ctx, "BTCUSDT"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "BTCUSDT.snap")); !os.IsNotExist(err) {
+		t.Fatalf("expected snapshot removed, got %v", err)
+	}
+	if _, err := store.Load(ctx, "../etc"); err == nil {
+		t.Fatal("expected invalid symbol error")
+	}
+}
@@ -0,0 +1,381 @@
+// Package replay 撮合引擎确定性重放与审计
+//
+// 按订单流顺序把消息交给全新的引擎（注入确定性时钟与成交 ID 生成器），
+// 将产生的事件与事件流中记录的事件按（交易对, 序列号）逐条比对，用于争议调查和引擎升级的回归验证。
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/exchange/matching/internal/engine"
+	"github.com/exchange/matching/internal/handler"
+)
+
+// clockStep 重放时钟每次读取前进的时间
+const clockStep = time.Microsecond
+
+// Message 订单流消息
+type Message struct {
+	ID   string                `json:"id"` // 订单流消息 ID（引擎时间取自其中的毫秒部分）
+	Data *handler.OrderMessage `json:"data"`
+}
+
+// Config 重放配置
+type Config struct {
+	Symbol    string                      // 只重放该交易对，空表示全部
+	Breaker   engine.BreakerConfig        // 与线上一致的波动熔断配置
+	Dedupe    bool                        // 按去重键只应用第一条消息（与线上启用去重时一致）
+	Snapshots map[string]*engine.Snapshot // 起始快照；没有快照的交易对从空订单簿、序列号 0 开始
+}
+
+// Mismatch 一条不一致的事件
+type Mismatch struct {
+	Symbol   string          `json:"symbol"`
+	Seq      int64           `json:"seq"`
+	Recorded json.RawMessage `json:"recorded,omitempty"` // 为空表示事件流中没有该序列号
+	Replayed json.RawMessage `json:"replayed"`
+}
+
+// Result 重放结果
+type Result struct {
+	Messages    int        // 应用的订单流消息数
+	Duplicates  int        // 按去重键跳过的消息数
+	Events      int        // 重放产生的事件数
+	Matched     int        // 与记录一致的事件数
+	Unrecorded  int        // 序列号超出记录范围的事件数（记录导出不完整时出现）
+	NotReplayed int        // 记录中序列号超出重放范围的事件数（重放区间结束得更早时出现）
+	Mismatches  []Mismatch // 按交易对、序列号排序
+}
+
+// OK 重放事件与记录是否一致
+func (r *Result) OK() bool {
+	return len(r.Mismatches) == 0
+}
+
+// recordedEvents 某交易对的记录事件
+type recordedEvents struct {
+	bySeq    map[int64]json.RawMessage
+	maxSeq   int64
+	tradeIDs map[int64]int64 // seq -> 成交 ID
+}
+
+// Replayer 重放器（Apply 只能由单个 goroutine 调用）
+type Replayer struct {
+	cfg      Config
+	recorded map[string]*recordedEvents
+	symbols  map[string]*symbolReplay
+	seen     map[string]bool
+
+	onEventMu sync.Mutex
+	onEvent   func(data []byte)
+
+	messages   int
+	duplicates int
+}
+
+// symbolReplay 单个交易对的引擎与比对状态（比对结果只由 compare goroutine 写入，stopped 关闭后读取）
+type symbolReplay struct {
+	eng      *engine.Engine
+	startSeq int64
+	through  string // 快照已包含的最后一条消息 ID
+	done     chan struct{}
+	stopped  chan struct{}
+
+	events     int
+	matched    int
+	unrecorded int
+	lastSeq    int64
+	mismatches []Mismatch
+	err        error
+}
+
+// NewReplayer 创建重放器
+func NewReplayer(cfg Config) *Replayer {
+	return &Replayer{
+		cfg:      cfg,
+		recorded: make(map[string]*recordedEvents),
+		symbols:  make(map[string]*symbolReplay),
+		seen:     make(map[string]bool),
+	}
+}
+
+// OnEvent 设置重放事件的回调（事件流消息 JSON，可写出后作为另一次重放的记录），需在 Apply 之前调用
+//
+// 各交易对的事件顺序与序列号一致，不同交易对之间的先后不确定；回调不会并发执行。
+func (r *Replayer) OnEvent(fn func(data []byte)) {
+	r.onEvent = fn
+}
+
+// Record 加载一条记录的事件（事件流消息 JSON），需在 Apply 之前加载完毕
+func (r *Replayer) Record(data []byte) error {
+	var event struct {
+		Type   string `json:"type"`
+		Symbol string `json:"symbol"`
+		Seq    int64  `json:"seq"`
+		Data   struct {
+			TradeID int64
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("decode recorded event: %w", err)
+	}
+	if event.Symbol == "" || event.Seq <= 0 {
+		return fmt.Errorf("recorded event without symbol or seq")
+	}
+	if r.cfg.Symbol != "" && event.Symbol != r.cfg.Symbol {
+		return nil
+	}
+	rec := r.recorded[event.Symbol]
+	if rec == nil {
+		rec = &recordedEvents{bySeq: make(map[int64]json.RawMessage), tradeIDs: make(map[int64]int64)}
+		r.recorded[event.Symbol] = rec
+	}
+	rec.bySeq[event.Seq] = append(json.RawMessage(nil), data...)
+	rec.maxSeq = max(rec.maxSeq, event.Seq)
+	if event.Type == "TRADE_CREATED" {
+		rec.tradeIDs[event.Seq] = event.Data.TradeID
+	}
+	return nil
+}
+
+// Apply 按订单流顺序应用一条消息
+func (r *Replayer) Apply(ctx context.Context, msg Message) error {
+	if msg.Data == nil {
+		return nil
+	}
+	if msg.ID == "" {
+		return fmt.Errorf("message without stream id")
+	}
+	symbol := msg.Data.Symbol
+	if r.cfg.Symbol != "" && symbol != r.cfg.Symbol {
+		return nil
+	}
+	sr := r.symbols[symbol]
+	if sr == nil && r.cfg.Snapshots[symbol] != nil {
+		var err error
+		if sr, err = r.start(symbol, msg.ID); err != nil {
+			return err
+		}
+	}
+	if sr != nil && engine.CompareStreamIDs(msg.ID, sr.through) <= 0 {
+		// 快照已包含该消息
+		return nil
+	}
+	if r.cfg.Dedupe {
+		if key := handler.DedupeKey(msg.Data); key != "" {
+			if r.seen[key] {
+				r.duplicates++
+				return nil
+			}
+			r.seen[key] = true
+		}
+	}
+	if sr == nil {
+		if handler.NeedsEngine(msg.Data) {
+			return nil
+		}
+		var err error
+		if sr, err = r.start(symbol, msg.ID); err != nil {
+			return err
+		}
+	}
+
+	cmd := handler.ToCommand(msg.Data)
+	cmd.StreamID = msg.ID
+	for {
+		err := sr.eng.Submit(cmd)
+		if err == nil {
+			break
+		}
+		if !strings.Contains(err.Error(), "queue full") {
+			return fmt.Errorf("submit %s: %w", msg.ID, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	r.messages++
+	return nil
+}
+
+// start 创建交易对的引擎（有快照时从快照恢复）
+func (r *Replayer) start(symbol, firstID string) (*symbolReplay, error) {
+	eng := engine.NewEngine(symbol, 10000, 10000)
+	// 熔断恢复与订单到期请求已记录在订单流中，重放时不再额外提交
+	eng.SetBreaker(r.cfg.Breaker, func(string, int64) {})
+	eng.SetExpiryRequester(func(string) {})
+
+	sr := &symbolReplay{eng: eng, done: make(chan struct{}), stopped: make(chan struct{})}
+	clockStart := streamTime(firstID)
+	if snap := r.cfg.Snapshots[symbol]; snap != nil {
+		if err := eng.Restore(snap); err != nil {
+			return nil, fmt.Errorf("restore snapshot %s: %w", symbol, err)
+		}
+		sr.startSeq = snap.Seq
+		sr.through = snap.StreamID
+		clockStart = time.UnixMilli(snap.CreatedAtMs)
+	}
+	eng.SetClock(newStepClock(clockStart))
+	eng.SetIDGenerator(r.recordedTradeIDs(symbol, sr.startSeq))
+	sr.lastSeq = sr.startSeq
+	eng.Start()
+	go r.compare(symbol, sr)
+
+	r.symbols[symbol] = sr
+	return sr, nil
+}
+
+// Finish 等待已提交的消息处理完毕，停止引擎并汇总结果
+func (r *Replayer) Finish(ctx context.Context) (*Result, error) {
+	result := &Result{Messages: r.messages, Duplicates: r.duplicates}
+	symbols := make([]string, 0, len(r.symbols))
+	for symbol := range r.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var firstErr error
+	for _, symbol := range symbols {
+		sr := r.symbols[symbol]
+		// 快照命令排在已提交的命令之后：返回时全部事件均已进入事件通道
+		_, err := sr.eng.Snapshot(ctx)
+		close(sr.done)
+		<-sr.stopped
+		sr.eng.Stop()
+		if err == nil {
+			err = sr.err
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("replay %s: %w", symbol, err)
+		}
+
+		result.Events += sr.events
+		result.Matched += sr.matched
+		result.Unrecorded += sr.unrecorded
+		result.Mismatches = append(result.Mismatches, sr.mismatches...)
+		if rec := r.recorded[symbol]; rec != nil && rec.maxSeq > sr.lastSeq {
+			result.NotReplayed += int(rec.maxSeq - sr.lastSeq)
+		}
+	}
+	return result, firstErr
+}
+
+// compare 逐条比对引擎事件，done 关闭后取完通道中剩余的事件退出
+func (r *Replayer) compare(symbol string, sr *symbolReplay) {
+	defer close(sr.stopped)
+	for {
+		select {
+		case event := <-sr.eng.Events():
+			r.check(symbol, sr, event)
+		case <-sr.done:
+			for {
+				select {
+				case event := <-sr.eng.Events():
+					r.check(symbol, sr, event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *Replayer) check(symbol string, sr *symbolReplay, event *engine.Event) {
+	if event == nil {
+		return
+	}
+	data, err := json.Marshal(handler.NewEventMessage(event))
+	if err != nil {
+		if sr.err == nil {
+			sr.err = fmt.Errorf("marshal event %d: %w", event.Seq, err)
+		}
+		return
+	}
+	if r.onEvent != nil {
+		r.onEventMu.Lock()
+		r.onEvent(data)
+		r.onEventMu.Unlock()
+	}
+	sr.events++
+	sr.lastSeq = event.Seq
+
+	rec := r.recorded[symbol]
+	if rec == nil || event.Seq > rec.maxSeq {
+		sr.unrecorded++
+		return
+	}
+	recorded, ok := rec.bySeq[event.Seq]
+	if ok && sameEvent(recorded, data) {
+		sr.matched++
+		return
+	}
+	sr.mismatches = append(sr.mismatches, Mismatch{Symbol: symbol, Seq: event.Seq, Recorded: recorded, Replayed: data})
+}
+
+// recordedTradeIDs 按记录中的顺序复用成交 ID，记录用完后从最大的记录 ID 之后递增
+//
+// 成交顺序一致时成交 ID 也一致，比对只反映真正的撮合差异。
+func (r *Replayer) recordedTradeIDs(symbol string, afterSeq int64) func() int64 {
+	var ids []int64
+	var last int64
+	if rec := r.recorded[symbol]; rec != nil {
+		seqs := make([]int64, 0, len(rec.tradeIDs))
+		for seq := range rec.tradeIDs {
+			if seq > afterSeq {
+				seqs = append(seqs, seq)
+			}
+		}
+		sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+		for _, seq := range seqs {
+			ids = append(ids, rec.tradeIDs[seq])
+			last = max(last, rec.tradeIDs[seq])
+		}
+	}
+	return func() int64 {
+		if len(ids) > 0 {
+			id := ids[0]
+			ids = ids[1:]
+			return id
+		}
+		last++
+		return last
+	}
+}
+
+// sameEvent 比较两条事件流消息（忽略时间戳）
+func sameEvent(a, b []byte) bool {
+	var x, y map[string]interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	delete(x, "timestamp")
+	delete(y, "timestamp")
+	return reflect.DeepEqual(x, y)
+}
+
+// newStepClock 确定性时钟：从 start 开始，每次读取前进 clockStep（只在引擎 goroutine 内调用）
+func newStepClock(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(clockStep)
+		return now
+	}
+}
+
+// streamTime 订单流消息 ID 中的时间
+func streamTime(id string) time.Time {
+	var ms, n uint64
+	if _, err := fmt.Sscanf(id, "%d-%d", &ms, &n); err != nil {
+		return time.UnixMilli(0)
+	}
+	return time.UnixMilli(int64(ms))
+}
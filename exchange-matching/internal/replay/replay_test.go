@@ -0,0 +1,130 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/exchange/matching/internal/engine"
+	"github.com/exchange/matching/internal/handler"
+)
+
+func testMessages() []Message {
+	return []Message{
+		{ID: "1700000000000-0", Data: &handler.OrderMessage{Type: "NEW", OrderID: 1, UserID: 10, Symbol: "BTCUSDT", Side: "SELL", OrderType: "LIMIT", TimeInForce: "GTC", Price: 100, Qty: 3}},
+		{ID: "1700000000001-0", Data: &handler.OrderMessage{Type: "NEW", OrderID: 2, UserID: 20, Symbol: "BTCUSDT", Side: "BUY", OrderType: "LIMIT", TimeInForce: "GTC", Price: 100, Qty: 1}},
+		// 重复投递的同一订单
+		{ID: "1700000000002-0", Data: &handler.OrderMessage{Type: "NEW", OrderID: 2, UserID: 20, Symbol: "BTCUSDT", Side: "BUY", OrderType: "LIMIT", TimeInForce: "GTC", Price: 100, Qty: 1}},
+		{ID: "1700000000003-0", Data: &handler.OrderMessage{Type: "NEW", OrderID: 3, UserID: 30, Symbol: "ETHUSDT", Side: "BUY", OrderType: "LIMIT", TimeInForce: "GTC", Price: 50, Qty: 1}},
+		{ID: "1700000000004-0", Data: &handler.OrderMessage{Type: "NEW", OrderID: 4, UserID: 40, Symbol: "BTCUSDT", Side: "BUY", OrderType: "MARKET", TimeInForce: "IOC", Qty: 1}},
+		{ID: "1700000000005-0", Data: &handler.OrderMessage{Type: "CANCEL", OrderID: 1, UserID: 10, Symbol: "BTCUSDT"}},
+		// 引擎不存在时的内部请求不创建引擎
+		{ID: "1700000000006-0", Data: &handler.OrderMessage{Type: "EXPIRE_ORDERS", Symbol: "SOLUSDT"}},
+	}
+}
+
+// record 重放一次，返回产生的事件作为记录
+func record(t *testing.T, cfg Config, msgs []Message) [][]byte {
+	t.Helper()
+	r := NewReplayer(cfg)
+	var events [][]byte
+	r.OnEvent(func(data []byte) { events = append(events, data) })
+	result := run(t, r, msgs)
+	if result.Events != len(events) || result.Unrecorded != len(events) {
+		t.Fatalf("expected all %d events unrecorded, got %+v", len(events), result)
+	}
+	return events
+}
+
+func run(t *testing.T, r *Replayer, msgs []Message) *Result {
+	t.Helper()
+	ctx := context.Background()
+	for _, msg := range msgs {
+		if err := r.Apply(ctx, msg); err != nil {
+			t.Fatalf("apply %s: %v", msg.ID, err)
+		}
+	}
+	result, err := r.Finish(ctx)
+	if err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	return result
+}
+
+func TestReplayMatchesRecordedEvents(t *testing.T) {
+	recorded := record(t, Config{Dedupe: true}, testMessages())
+
+	r := NewReplayer(Config{Dedupe: true})
+	for _, data := range recorded {
+		if err := r.Record(data); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	result := run(t, r, testMessages())
+	if !result.OK() || result.Matched != len(recorded) || result.Unrecorded != 0 || result.NotReplayed != 0 {
+		t.Fatalf("expected replay to match recording, got %+v", result)
+	}
+	if result.Messages != 5 || result.Duplicates != 1 {
+		t.Fatalf("expected 5 applied and 1 duplicate message, got %d/%d", result.Messages, result.Duplicates)
+	}
+}
+
+func TestReplayReportsMismatch(t *testing.T) {
+	recorded := record(t, Config{Dedupe: true}, testMessages())
+
+	r := NewReplayer(Config{Dedupe: true, Symbol: "BTCUSDT"})
+	var tampered int64
+	for _, data := range recorded {
+		var event map[string]interface{}
+		if err := json.Unmarshal(data, &event); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if event["type"] == "TRADE_CREATED" && tampered == 0 {
+			event["data"].(map[string]interface{})["Price"] = 101
+			tampered = int64(event["seq"].(float64))
+			data, _ = json.Marshal(event)
+		}
+		if err := r.Record(data); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	result := run(t, r, testMessages())
+	if result.OK() || len(result.Mismatches) != 1 {
+		t.Fatalf("expected one mismatch, got %+v", result)
+	}
+	mismatch := result.Mismatches[0]
+	if mismatch.Symbol != "BTCUSDT" || mismatch.Seq != tampered || !strings.Contains(string(mismatch.Replayed), `"Price":100`) {
+		t.Fatalf("unexpected mismatch %+v", mismatch)
+	}
+}
+
+func TestReplayFromSnapshotSkipsIncludedMessages(t *testing.T) {
+	msgs := testMessages()
+	recorded := record(t, Config{Dedupe: true, Symbol: "BTCUSDT"}, msgs)
+
+	// 快照包含第一条消息：卖单 1 已挂在订单簿上
+	eng := engine.NewEngine("BTCUSDT", 10, 10)
+	eng.Start()
+	defer eng.Stop()
+	cmd := handler.ToCommand(msgs[0].Data)
+	cmd.StreamID = msgs[0].ID
+	if err := eng.Submit(cmd); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	snap, err := eng.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	r := NewReplayer(Config{Dedupe: true, Snapshots: map[string]*engine.Snapshot{"BTCUSDT": snap}})
+	for _, data := range recorded {
+		if err := r.Record(data); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	result := run(t, r, msgs)
+	if !result.OK() || result.Matched != len(recorded)-1 {
+		t.Fatalf("expected replay after snapshot to match, got %+v", result)
+	}
+}
@@ -7,9 +7,23 @@ type OpenOrder struct {
 	UserID        int64
 	Symbol        string
 	Side          string // BUY/SELL
-	OrderType     string // LIMIT/MARKET
-	TimeInForce   string // GTC/IOC/FOK/POST_ONLY
+	OrderType     string // LIMIT/MARKET/STOP_LOSS/STOP_LOSS_LIMIT/TAKE_PROFIT/TRAILING_STOP
+	TimeInForce   string // GTC/IOC/FOK/POST_ONLY/GTD/DAY
 	Price         int64
-	LeavesQty     int64 // 剩余数量
-	CreatedAt     int64 // 纳秒时间戳
+	StopPrice     int64 // 条件单触发价
+	Triggered     bool  // 条件单是否已触发
+	// 跟踪止损：回调价差或比例（基点）、激活价与激活后的最优价（0 表示未激活）
+	TrailingDelta   int64
+	TrailingBps     int64
+	ActivationPrice int64
+	TrailingExtreme int64
+	STPMode         string // EXPIRE_TAKER/EXPIRE_MAKER/EXPIRE_BOTH/DECREMENT
+	OrigQty         int64  // 订单数量（含已成交）
+	LeavesQty       int64  // 剩余数量
+	DisplayQty      int64  // 冰山单每次展示数量，0 表示非冰山单
+	ExpireTimeMs    int64  // GTD/DAY 到期时间（毫秒），0 表示不过期
+	QuoteID         int64  // 做市报价请求 ID，非 0 表示报价单
+	ListID          int64  // 订单列表 ID（OCO），0 表示非列表订单
+	SiblingOrderID  int64  // 同列表的另一条订单
+	CreatedAt       int64  // 纳秒时间戳
 }
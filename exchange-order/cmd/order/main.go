@@ -15,9 +15,11 @@ import (
 	"syscall"
 	"time"
 
+	commondecimal "github.com/exchange/common/pkg/decimal"
 	commonerrors "github.com/exchange/common/pkg/errors"
 	commonredis "github.com/exchange/common/pkg/redis"
 	commonresp "github.com/exchange/common/pkg/response"
+	"github.com/exchange/common/pkg/shard"
 	"github.com/exchange/common/pkg/snowflake"
 	"github.com/exchange/order/internal/client"
 	"github.com/exchange/order/internal/config"
@@ -91,7 +93,15 @@ func main() {
 	// 创建服务
 	idGen := snowflakeIDGen{}
 	repo := repository.NewOrderRepository(db)
-	matchingClient := client.NewMatchingClient(cfg.MatchingServiceURL, cfg.InternalToken)
+	shards, err := shard.NewRouter(cfg.MatchingShards, redisClient)
+	if err != nil {
+		log.Fatalf("Invalid matching shard config: %v", err)
+	}
+	matchingURLs := cfg.MatchingShards.ServiceURLs(cfg.MatchingServiceURL)
+	matchingClient, err := client.NewShardedMatchingClient(matchingURLs, shards, cfg.InternalToken)
+	if err != nil {
+		log.Fatalf("Invalid matching shard config: %v", err)
+	}
 	clearingClient := client.NewClearingClient(cfg.ClearingBaseURL, cfg.InternalToken)
 	validator := service.NewPriceValidator(repo, matchingClient, service.PriceValidatorConfig{
 		Enabled:          cfg.PriceProtection.Enabled,
@@ -100,6 +110,15 @@ func main() {
 	svc := service.NewOrderService(repo, redisClient, idGen, cfg.OrderStream, validator, clearingClient, metricsClient)
 	wsPublisher := orderws.NewPublisher(redisClient, cfg.PrivateUserEventChannel)
 	svc.SetPublisher(wsPublisher)
+	svc.SetDaySessionEnd(cfg.DaySessionEnd)
+	svc.SetShardRouter(shards)
+	if cfg.Risk.Enabled {
+		riskCfg, err := toRiskConfig(cfg.Risk)
+		if err != nil {
+			log.Fatalf("Invalid risk config: %v", err)
+		}
+		svc.SetRiskChecker(service.NewRiskEngine(repo, redisClient, riskCfg, metricsClient))
+	}
 
 	tradeRepo := repository.NewTradeRepository(db)
 	updater := service.NewOrderUpdater(redisClient, repo, tradeRepo, clearingClient, metricsClient, &service.UpdaterConfig{
@@ -108,10 +127,20 @@ func main() {
 		Consumer:    cfg.MatchingConsumerName,
 	})
 	updater.SetPublisher(wsPublisher)
+	updater.SetOrderLists(repo, svc)
 	if err := updater.Start(ctx); err != nil {
 		log.Fatalf("Failed to start order updater: %v", err)
 	}
 
+	marketDataClient := client.NewMarketDataClient(cfg.MarketDataServiceURL, cfg.InternalToken)
+	algoSvc := service.NewAlgoService(repo, svc, marketDataClient, tradeRepo, service.AlgoConfig{
+		PollInterval:     cfg.Algo.PollInterval,
+		MinSliceInterval: cfg.Algo.MinSliceInterval,
+		MaxDuration:      cfg.Algo.MaxDuration,
+	})
+	algoSvc.SetPublisher(wsPublisher)
+	algoSvc.Start(ctx)
+
 	// HTTP 服务
 	mux := http.NewServeMux()
 	healthHTTPClient := &http.Client{Timeout: 2 * time.Second}
@@ -134,20 +163,20 @@ func main() {
 		deps := []dependencyStatus{
 			checkPostgres(r.Context(), db),
 			checkRedis(r.Context(), redisClient),
-			checkHTTP(r.Context(), "matching", cfg.MatchingServiceURL, healthHTTPClient),
 			checkHTTP(r.Context(), "clearing", cfg.ClearingBaseURL, healthHTTPClient),
 			checkConsumeLoop(updater),
 		}
+		deps = append(deps, checkMatchingShards(r.Context(), matchingURLs, healthHTTPClient)...)
 		writeHealth(w, deps)
 	})
 	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
 		deps := []dependencyStatus{
 			checkPostgres(r.Context(), db),
 			checkRedis(r.Context(), redisClient),
-			checkHTTP(r.Context(), "matching", cfg.MatchingServiceURL, healthHTTPClient),
 			checkHTTP(r.Context(), "clearing", cfg.ClearingBaseURL, healthHTTPClient),
 			checkConsumeLoop(updater),
 		}
+		deps = append(deps, checkMatchingShards(r.Context(), matchingURLs, healthHTTPClient)...)
 		writeHealth(w, deps)
 	})
 
@@ -190,6 +219,8 @@ func main() {
 		switch r.Method {
 		case http.MethodPost:
 			handleCreateOrder(w, r, svc)
+		case http.MethodPut:
+			handleAmendOrder(w, r, svc)
 		case http.MethodDelete:
 			handleCancelOrder(w, r, svc)
 		case http.MethodGet:
@@ -199,8 +230,95 @@ func main() {
 		}
 	}))
 
-	// 当前委托
+	// 做市批量报价
+	mux.HandleFunc("/v1/massQuote", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+			return
+		}
+		handleMassQuote(w, r, svc)
+	}))
+
+	// 批量下单 / 批量撤单
+	mux.HandleFunc("/v1/batchOrders", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleBatchCreateOrders(w, r, svc)
+		case http.MethodDelete:
+			handleBatchCancelOrders(w, r, svc)
+		default:
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+		}
+	}))
+
+	// OCO 订单列表
+	mux.HandleFunc("/v1/orderList/oco", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+			return
+		}
+		handleCreateOrderList(w, r, svc)
+	}))
+	mux.HandleFunc("/v1/orderList", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetOrderList(w, r, svc)
+		case http.MethodDelete:
+			handleCancelOrderList(w, r, svc)
+		default:
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+		}
+	}))
+
+	// 算法单（TWAP / VWAP）
+	mux.HandleFunc("/v1/algoOrder", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleCreateAlgoOrder(w, r, algoSvc)
+		case http.MethodGet:
+			handleGetAlgoOrder(w, r, algoSvc)
+		case http.MethodDelete:
+			handleCancelAlgoOrder(w, r, algoSvc)
+		default:
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+		}
+	}))
+	mux.HandleFunc("/v1/openAlgoOrders", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+			return
+		}
+		userID, err := getUserIDFromHeader(r)
+		if err != nil {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		algos, err := algoSvc.ListOpenAlgoOrders(r.Context(), userID, r.URL.Query().Get("symbol"), limit)
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		resp := make([]*algoOrderResponse, 0, len(algos))
+		for _, algo := range algos {
+			resp = append(resp, toAlgoOrderResponse(algo))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	// 当前委托（DELETE 为批量撤单）
 	mux.HandleFunc("/v1/openOrders", requireInternalAuth(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			handleMassCancel(w, r, svc)
+			return
+		default:
+			commonresp.WriteStatusError(w, r, http.StatusMethodNotAllowed, commonerrors.CodeInvalidRequest, "method not allowed")
+			return
+		}
 		userID, err := getUserIDFromHeader(r)
 		if err != nil {
 			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
@@ -309,6 +427,38 @@ func (g snowflakeIDGen) NextID() int64 {
 	return snowflake.MustNextID()
 }
 
+// toRiskConfig 将风控配置转换为服务层限额
+func toRiskConfig(cfg config.RiskConfig) (service.RiskConfig, error) {
+	tiers, err := cfg.Tiers()
+	if err != nil {
+		return service.RiskConfig{}, err
+	}
+	out := service.RiskConfig{Default: toRiskLimits(cfg.Default), Tiers: make(map[string]service.RiskLimits, len(tiers))}
+	for tier, limits := range tiers {
+		out.Tiers[tier] = toRiskLimits(limits)
+	}
+	return out, nil
+}
+
+func toRiskLimits(limits config.RiskLimits) service.RiskLimits {
+	notional := func(value string) *commondecimal.Decimal {
+		if value == "" {
+			return nil
+		}
+		v, err := commondecimal.New(value)
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+	return service.RiskLimits{
+		MaxOpenOrders:        limits.MaxOpenOrders,
+		MaxOrderNotional:     notional(limits.MaxOrderNotional),
+		MaxNotionalPerMinute: notional(limits.MaxNotionalPerMinute),
+		MaxOrdersPerSecond:   limits.MaxOrdersPerSecond,
+	}
+}
+
 type dependencyStatus struct {
 	Name    string `json:"name"`
 	Status  string `json:"status"`
@@ -352,6 +502,18 @@ func checkRedis(ctx context.Context, client *redis.Client) dependencyStatus {
 	}
 }
 
+// checkMatchingShards 检查每个撮合分片（单实例时名称仍为 matching）
+func checkMatchingShards(ctx context.Context, urls []string, client *http.Client) []dependencyStatus {
+	if len(urls) == 1 {
+		return []dependencyStatus{checkHTTP(ctx, "matching", urls[0], client)}
+	}
+	deps := make([]dependencyStatus, 0, len(urls))
+	for i, u := range urls {
+		deps = append(deps, checkHTTP(ctx, fmt.Sprintf("matching-%d", i), u, client))
+	}
+	return deps
+}
+
 func checkHTTP(ctx context.Context, name, baseURL string, client *http.Client) dependencyStatus {
 	start := time.Now()
 	status := "ok"
@@ -420,9 +582,71 @@ type CreateOrderRequest struct {
 	Type          string `json:"type"`
 	TimeInForce   string `json:"timeInForce"`
 	Price         int64  `json:"price"`
+	StopPrice     int64  `json:"stopPrice"`
 	Quantity      int64  `json:"quantity"`
 	QuoteOrderQty int64  `json:"quoteOrderQty"`
 	ClientOrderID string `json:"clientOrderId"`
+	STPMode       string `json:"stpMode"`
+	DisplayQty    int64  `json:"displayQty"`
+	ExpireTime    int64  `json:"expireTime"`
+	// 跟踪止损参数（trailingDelta 与 trailingBps 二选一）
+	TrailingDelta   int64 `json:"trailingDelta"`
+	TrailingBps     int64 `json:"trailingBps"`
+	ActivationPrice int64 `json:"activationPrice"`
+}
+
+// BatchOrdersRequest 批量下单请求
+type BatchOrdersRequest struct {
+	Orders []*CreateOrderRequest `json:"orders"`
+}
+
+// MassQuoteRequest 批量报价请求（quotes 为空表示撤销全部报价）
+type MassQuoteRequest struct {
+	Symbol      string              `json:"symbol"`
+	TimeInForce string              `json:"timeInForce"`
+	STPMode     string              `json:"stpMode"`
+	Quotes      []MassQuoteLevelReq `json:"quotes"`
+}
+
+// MassQuoteLevelReq 报价档位
+type MassQuoteLevelReq struct {
+	Side     string `json:"side"`
+	Price    int64  `json:"price"`
+	Quantity int64  `json:"quantity"`
+}
+
+// CreateOrderListRequest OCO 下单请求（stopLimitPrice 为 0 表示止损单触发后按市价成交）
+type CreateOrderListRequest struct {
+	Symbol         string `json:"symbol"`
+	Side           string `json:"side"`
+	Quantity       int64  `json:"quantity"`
+	Price          int64  `json:"price"`
+	StopPrice      int64  `json:"stopPrice"`
+	StopLimitPrice int64  `json:"stopLimitPrice"`
+	ClientListID   string `json:"listClientOrderId"`
+	STPMode        string `json:"stpMode"`
+}
+
+// CreateAlgoOrderRequest 算法单请求（duration/sliceInterval 为毫秒，sliceInterval 为 0 表示按持续时间均分 20 次）
+type CreateAlgoOrderRequest struct {
+	Symbol           string `json:"symbol"`
+	Side             string `json:"side"`
+	AlgoType         string `json:"algoType"`
+	Quantity         int64  `json:"quantity"`
+	Price            int64  `json:"price"`
+	Duration         int64  `json:"duration"`
+	SliceInterval    int64  `json:"sliceInterval"`
+	ParticipationBps int64  `json:"participationBps"`
+	ClientAlgoID     string `json:"clientAlgoId"`
+}
+
+// AmendOrderRequest 改单请求（price/quantity 为 0 表示不修改，quantity 为改单后的订单总数量）
+type AmendOrderRequest struct {
+	Symbol        string `json:"symbol"`
+	OrderID       int64  `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId"`
+	Price         int64  `json:"price"`
+	Quantity      int64  `json:"quantity"`
 }
 
 func getUserIDFromHeader(r *http.Request) (int64, error) {
@@ -456,9 +680,17 @@ func handleCreateOrder(w http.ResponseWriter, r *http.Request, svc *service.Orde
 		Type:          req.Type,
 		TimeInForce:   req.TimeInForce,
 		Price:         req.Price,
+		StopPrice:     req.StopPrice,
 		Quantity:      req.Quantity,
 		QuoteOrderQty: req.QuoteOrderQty,
 		ClientOrderID: req.ClientOrderID,
+		STPMode:       req.STPMode,
+		DisplayQty:    req.DisplayQty,
+		ExpireTime:    req.ExpireTime,
+
+		TrailingDelta:   req.TrailingDelta,
+		TrailingBps:     req.TrailingBps,
+		ActivationPrice: req.ActivationPrice,
 	})
 	if err != nil {
 		writeInternalError(w, err)
@@ -502,6 +734,487 @@ func handleCancelOrder(w http.ResponseWriter, r *http.Request, svc *service.Orde
 	json.NewEncoder(w).Encode(toOrderResponse(resp.Order))
 }
 
+func handleMassCancel(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	resp, err := svc.MassCancel(r.Context(), &service.MassCancelRequest{
+		UserID: userID,
+		Symbol: r.URL.Query().Get("symbol"),
+		Side:   r.URL.Query().Get("side"),
+	})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	symbols := resp.Symbols
+	if symbols == nil {
+		symbols = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&massCancelResponse{RequestID: resp.RequestID, Symbols: symbols})
+}
+
+type massCancelResponse struct {
+	RequestID int64    `json:"requestId"`
+	Symbols   []string `json:"symbols"`
+}
+
+func handleBatchCreateOrders(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var req BatchOrdersRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	orders := make([]*service.CreateOrderRequest, 0, len(req.Orders))
+	for _, o := range req.Orders {
+		if o == nil {
+			orders = append(orders, nil)
+			continue
+		}
+		orders = append(orders, &service.CreateOrderRequest{
+			UserID:        userID,
+			Symbol:        o.Symbol,
+			Side:          o.Side,
+			Type:          o.Type,
+			TimeInForce:   o.TimeInForce,
+			Price:         o.Price,
+			StopPrice:     o.StopPrice,
+			Quantity:      o.Quantity,
+			QuoteOrderQty: o.QuoteOrderQty,
+			ClientOrderID: o.ClientOrderID,
+			STPMode:       o.STPMode,
+			DisplayQty:    o.DisplayQty,
+			ExpireTime:    o.ExpireTime,
+
+			TrailingDelta:   o.TrailingDelta,
+			TrailingBps:     o.TrailingBps,
+			ActivationPrice: o.ActivationPrice,
+		})
+	}
+
+	resp, err := svc.BatchCreateOrders(r.Context(), &service.BatchCreateOrdersRequest{UserID: userID, Orders: orders})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toBatchOrdersResponse(resp.Results))
+}
+
+func handleBatchCancelOrders(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+	var orderIDs []int64
+	for _, raw := range splitList(r.URL.Query().Get("orderIds")) {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidParam, "invalid orderIds")
+			return
+		}
+		orderIDs = append(orderIDs, id)
+	}
+
+	resp, err := svc.BatchCancelOrders(r.Context(), &service.BatchCancelOrdersRequest{
+		UserID:         userID,
+		OrderIDs:       orderIDs,
+		ClientOrderIDs: splitList(r.URL.Query().Get("clientOrderIds")),
+	})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toBatchOrdersResponse(resp.Results))
+}
+
+// splitList 解析逗号分隔的查询参数（忽略空项）
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// batchOrderResult 批量请求的单笔结果：成功时为 order，失败时为 code
+type batchOrderResult struct {
+	Order *orderResponse `json:"order,omitempty"`
+	Code  string         `json:"code,omitempty"`
+}
+
+type batchOrdersResponse struct {
+	Results []*batchOrderResult `json:"results"`
+}
+
+func toBatchOrdersResponse(results []service.BatchOrderResult) *batchOrdersResponse {
+	resp := &batchOrdersResponse{Results: make([]*batchOrderResult, 0, len(results))}
+	for _, result := range results {
+		if result.ErrorCode != "" {
+			resp.Results = append(resp.Results, &batchOrderResult{Code: result.ErrorCode})
+			continue
+		}
+		resp.Results = append(resp.Results, &batchOrderResult{Order: toOrderResponse(result.Order)})
+	}
+	return resp
+}
+
+func handleMassQuote(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var req MassQuoteRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	quotes := make([]service.QuoteLevel, 0, len(req.Quotes))
+	for _, q := range req.Quotes {
+		quotes = append(quotes, service.QuoteLevel{Side: q.Side, Price: q.Price, Quantity: q.Quantity})
+	}
+
+	resp, err := svc.MassQuote(r.Context(), &service.MassQuoteRequest{
+		UserID:      userID,
+		Symbol:      req.Symbol,
+		TimeInForce: req.TimeInForce,
+		STPMode:     req.STPMode,
+		Quotes:      quotes,
+	})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&massQuoteResponse{QuoteID: resp.QuoteID, Orders: toOrderResponses(resp.Orders)})
+}
+
+type massQuoteResponse struct {
+	QuoteID int64            `json:"quoteId"`
+	Orders  []*orderResponse `json:"orders"`
+}
+
+func handleCreateOrderList(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var req CreateOrderListRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	resp, err := svc.CreateOrderList(r.Context(), &service.CreateOrderListRequest{
+		UserID:         userID,
+		Symbol:         req.Symbol,
+		Side:           req.Side,
+		Quantity:       req.Quantity,
+		Price:          req.Price,
+		StopPrice:      req.StopPrice,
+		StopLimitPrice: req.StopLimitPrice,
+		ClientListID:   req.ClientListID,
+		STPMode:        req.STPMode,
+	})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOrderListResponse(resp.List, resp.Orders))
+}
+
+func handleGetOrderList(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+	listID, _ := strconv.ParseInt(r.URL.Query().Get("orderListId"), 10, 64)
+
+	list, orders, err := svc.GetOrderList(r.Context(), userID, listID)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeOrderListNotFound, "order list not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOrderListResponse(list, orders))
+}
+
+func handleCancelOrderList(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+	listID, _ := strconv.ParseInt(r.URL.Query().Get("orderListId"), 10, 64)
+
+	resp, err := svc.CancelOrderList(r.Context(), &service.CancelOrderListRequest{
+		UserID:       userID,
+		ListID:       listID,
+		ClientListID: r.URL.Query().Get("listClientOrderId"),
+	})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOrderListResponse(resp.List, resp.Orders))
+}
+
+type orderListResponse struct {
+	OrderListID       int64            `json:"orderListId"`
+	ListClientOrderID string           `json:"listClientOrderId,omitempty"`
+	ContingencyType   string           `json:"contingencyType"`
+	Symbol            string           `json:"symbol"`
+	ListStatus        string           `json:"listStatus"`
+	Orders            []*orderResponse `json:"orders"`
+	CreatedAt         int64            `json:"createdAt"`
+	UpdatedAt         int64            `json:"updatedAt"`
+}
+
+func toOrderListResponse(list *repository.OrderList, orders []*repository.Order) *orderListResponse {
+	status := "EXECUTING"
+	switch list.Status {
+	case repository.OrderListStatusAllDone:
+		status = "ALL_DONE"
+	case repository.OrderListStatusRejected:
+		status = "REJECTED"
+	}
+	return &orderListResponse{
+		OrderListID:       list.ListID,
+		ListClientOrderID: list.ClientListID,
+		ContingencyType:   list.ContingencyType,
+		Symbol:            list.Symbol,
+		ListStatus:        status,
+		Orders:            toOrderResponses(orders),
+		CreatedAt:         list.CreateTimeMs,
+		UpdatedAt:         list.UpdateTimeMs,
+	}
+}
+
+func handleCreateAlgoOrder(w http.ResponseWriter, r *http.Request, svc *service.AlgoService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var req CreateAlgoOrderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	resp, err := svc.CreateAlgoOrder(r.Context(), &service.CreateAlgoOrderRequest{
+		UserID:           userID,
+		Symbol:           req.Symbol,
+		Side:             req.Side,
+		AlgoType:         req.AlgoType,
+		Quantity:         req.Quantity,
+		Price:            req.Price,
+		DurationMs:       req.Duration,
+		SliceIntervalMs:  req.SliceInterval,
+		ParticipationBps: req.ParticipationBps,
+		ClientAlgoID:     req.ClientAlgoID,
+	})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toAlgoOrderResponse(resp.Algo))
+}
+
+func handleGetAlgoOrder(w http.ResponseWriter, r *http.Request, svc *service.AlgoService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+	algoID, _ := strconv.ParseInt(r.URL.Query().Get("algoId"), 10, 64)
+
+	algo, err := svc.GetAlgoOrder(r.Context(), userID, algoID, r.URL.Query().Get("clientAlgoId"))
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeAlgoOrderNotFound, "algo order not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toAlgoOrderResponse(algo))
+}
+
+func handleCancelAlgoOrder(w http.ResponseWriter, r *http.Request, svc *service.AlgoService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+	algoID, _ := strconv.ParseInt(r.URL.Query().Get("algoId"), 10, 64)
+
+	resp, err := svc.CancelAlgoOrder(r.Context(), &service.CancelAlgoOrderRequest{
+		UserID:       userID,
+		AlgoID:       algoID,
+		ClientAlgoID: r.URL.Query().Get("clientAlgoId"),
+	})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toAlgoOrderResponse(resp.Algo))
+}
+
+type algoOrderResponse struct {
+	AlgoID           int64  `json:"algoId"`
+	ClientAlgoID     string `json:"clientAlgoId,omitempty"`
+	Symbol           string `json:"symbol"`
+	Side             string `json:"side"`
+	AlgoType         string `json:"algoType"`
+	Status           string `json:"status"`
+	Reason           string `json:"reason,omitempty"`
+	Quantity         int64  `json:"quantity"`
+	ExecutedQty      int64  `json:"executedQty"`
+	ExecutedQuoteQty int64  `json:"executedQuoteQty"`
+	Price            int64  `json:"price"`
+	ParticipationBps int64  `json:"participationBps,omitempty"`
+	SliceInterval    int64  `json:"sliceInterval"`
+	StartTime        int64  `json:"startTime"`
+	EndTime          int64  `json:"endTime"`
+	NextSliceTime    int64  `json:"nextSliceTime,omitempty"`
+	ChildOrderCount  int    `json:"childOrderCount"`
+	ActiveChildID    int64  `json:"activeChildOrderId,omitempty"`
+	CreatedAt        int64  `json:"createdAt"`
+	UpdatedAt        int64  `json:"updatedAt"`
+}
+
+func toAlgoOrderResponse(algo *repository.AlgoOrder) *algoOrderResponse {
+	status := "RUNNING"
+	switch algo.Status {
+	case repository.AlgoStatusFinished:
+		status = "FINISHED"
+	case repository.AlgoStatusCanceled:
+		status = "CANCELED"
+	case repository.AlgoStatusExpired:
+		status = "EXPIRED"
+	case repository.AlgoStatusFailed:
+		status = "FAILED"
+	}
+	resp := &algoOrderResponse{
+		AlgoID:           algo.AlgoID,
+		ClientAlgoID:     algo.ClientAlgoID,
+		Symbol:           algo.Symbol,
+		Side:             sideToString(algo.Side),
+		AlgoType:         algo.AlgoType,
+		Status:           status,
+		Reason:           algo.Reason,
+		Quantity:         algo.TotalQty,
+		ExecutedQty:      algo.ExecutedQty,
+		ExecutedQuoteQty: algo.ExecutedQuoteQty,
+		Price:            algo.LimitPrice,
+		ParticipationBps: algo.ParticipationBps,
+		SliceInterval:    algo.SliceIntervalMs,
+		StartTime:        algo.StartTimeMs,
+		EndTime:          algo.EndTimeMs,
+		ChildOrderCount:  algo.ChildCount,
+		ActiveChildID:    algo.ActiveChildID,
+		CreatedAt:        algo.CreateTimeMs,
+		UpdatedAt:        algo.UpdateTimeMs,
+	}
+	if !algo.IsFinal() {
+		resp.NextSliceTime = algo.NextSliceTimeMs
+	}
+	return resp
+}
+
+func handleAmendOrder(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
+	userID, err := getUserIDFromHeader(r)
+	if err != nil {
+		commonresp.WriteErrorCode(w, r, commonerrors.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var req AmendOrderRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	resp, err := svc.AmendOrder(r.Context(), &service.AmendOrderRequest{
+		UserID:        userID,
+		Symbol:        req.Symbol,
+		OrderID:       req.OrderID,
+		ClientOrderID: req.ClientOrderID,
+		Price:         req.Price,
+		Quantity:      req.Quantity,
+	})
+	if err != nil {
+		writeInternalError(w, err)
+		return
+	}
+
+	if resp.ErrorCode != "" {
+		commonresp.WriteErrorCode(w, r, commonerrors.Code(resp.ErrorCode), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOrderResponse(resp.Order))
+}
+
 func handleGetOrder(w http.ResponseWriter, r *http.Request, svc *service.OrderService) {
 	userID, err := getUserIDFromHeader(r)
 	if err != nil {
@@ -585,13 +1298,22 @@ type symbolInfoResponse struct {
 	PriceLimitRate string `json:"priceLimitRate,omitempty"`
 	MakerFeeRate   string `json:"makerFeeRate,omitempty"`
 	TakerFeeRate   string `json:"takerFeeRate,omitempty"`
+
+	TickBands []tickBandResponse `json:"tickBands,omitempty"`
+}
+
+// tickBandResponse 价格分档（价格不低于 minPrice 时适用，qtyStep 为空表示沿用交易对 qtyStep）
+type tickBandResponse struct {
+	MinPrice  string `json:"minPrice"`
+	PriceTick string `json:"priceTick"`
+	QtyStep   string `json:"qtyStep,omitempty"`
 }
 
 func toSymbolInfoResponse(cfg *repository.SymbolConfig) *symbolInfoResponse {
 	if cfg == nil {
 		return nil
 	}
-	return &symbolInfoResponse{
+	resp := &symbolInfoResponse{
 		Symbol:         cfg.Symbol,
 		BaseAsset:      cfg.BaseAsset,
 		QuoteAsset:     cfg.QuoteAsset,
@@ -609,41 +1331,82 @@ func toSymbolInfoResponse(cfg *repository.SymbolConfig) *symbolInfoResponse {
 		MakerFeeRate:   cfg.MakerFeeRate,
 		TakerFeeRate:   cfg.TakerFeeRate,
 	}
+	for _, band := range cfg.TickBands {
+		item := tickBandResponse{
+			MinPrice:  strconv.FormatInt(band.MinPrice, 10),
+			PriceTick: strconv.FormatInt(band.PriceTick, 10),
+		}
+		if band.QtyStep > 0 {
+			item.QtyStep = strconv.FormatInt(band.QtyStep, 10)
+		}
+		resp.TickBands = append(resp.TickBands, item)
+	}
+	return resp
 }
 
 type orderResponse struct {
-	OrderID       int64  `json:"orderId"`
-	ClientOrderID string `json:"clientOrderId,omitempty"`
-	Symbol        string `json:"symbol"`
-	Side          string `json:"side"`
-	Type          string `json:"type"`
-	TimeInForce   string `json:"timeInForce"`
-	Price         string `json:"price"`
-	OrigQty       string `json:"origQty"`
-	ExecutedQty   string `json:"executedQty"`
-	Status        string `json:"status"`
-	CreatedAt     int64  `json:"createdAt"`
-	UpdatedAt     int64  `json:"updatedAt"`
+	OrderID        int64  `json:"orderId"`
+	ClientOrderID  string `json:"clientOrderId,omitempty"`
+	Symbol         string `json:"symbol"`
+	Side           string `json:"side"`
+	Type           string `json:"type"`
+	TimeInForce    string `json:"timeInForce"`
+	Price          string `json:"price"`
+	StopPrice      string `json:"stopPrice,omitempty"`
+	OrigQty        string `json:"origQty"`
+	ExecutedQty    string `json:"executedQty"`
+	Status         string `json:"status"`
+	STPMode        string `json:"stpMode"`
+	TriggeredAt    int64  `json:"triggeredAt,omitempty"`
+	PendingAmendID int64  `json:"pendingAmendId,omitempty"`
+	DisplayQty     int64  `json:"displayQty,omitempty"`
+	ExpireTime     int64  `json:"expireTime,omitempty"`
+	QuoteOrderQty  int64  `json:"quoteOrderQty,omitempty"`
+	OrderListID    int64  `json:"orderListId,omitempty"`
+	CreatedAt      int64  `json:"createdAt"`
+	UpdatedAt      int64  `json:"updatedAt"`
+
+	TrailingDelta        int64 `json:"trailingDelta,omitempty"`
+	TrailingBps          int64 `json:"trailingBps,omitempty"`
+	ActivationPrice      int64 `json:"activationPrice,omitempty"`
+	TrailingExtremePrice int64 `json:"trailingExtremePrice,omitempty"`
 }
 
 func toOrderResponse(order *repository.Order) *orderResponse {
 	if order == nil {
 		return nil
 	}
-	return &orderResponse{
-		OrderID:       order.OrderID,
-		ClientOrderID: order.ClientOrderID,
-		Symbol:        order.Symbol,
-		Side:          sideToString(order.Side),
-		Type:          typeToString(order.Type),
-		TimeInForce:   tifToString(order.TimeInForce),
-		Price:         order.Price,
-		OrigQty:       order.OrigQty,
-		ExecutedQty:   order.ExecutedQty,
-		Status:        statusToString(order.Status),
-		CreatedAt:     order.CreateTimeMs,
-		UpdatedAt:     order.UpdateTimeMs,
+	resp := &orderResponse{
+		OrderID:        order.OrderID,
+		ClientOrderID:  order.ClientOrderID,
+		Symbol:         order.Symbol,
+		Side:           sideToString(order.Side),
+		Type:           typeToString(order.Type),
+		TimeInForce:    tifToString(order.TimeInForce),
+		Price:          order.Price,
+		OrigQty:        order.OrigQty,
+		ExecutedQty:    order.ExecutedQty,
+		Status:         statusToString(order.Status),
+		STPMode:        stpModeToString(order.STPMode),
+		PendingAmendID: order.PendingAmendID,
+		DisplayQty:     order.DisplayQty,
+		ExpireTime:     order.ExpireTimeMs,
+		QuoteOrderQty:  order.QuoteOrderQty,
+		OrderListID:    order.ListID,
+		CreatedAt:      order.CreateTimeMs,
+		UpdatedAt:      order.UpdateTimeMs,
+	}
+	if order.IsStopOrder() {
+		resp.StopPrice = order.StopPrice
+		resp.TriggeredAt = order.TriggerTimeMs
 	}
+	if order.Type == repository.TypeTrailingStop {
+		resp.TrailingDelta = order.TrailingDelta
+		resp.TrailingBps = order.TrailingBps
+		resp.ActivationPrice = order.ActivationPrice
+		resp.TrailingExtremePrice = order.TrailingExtreme
+	}
+	return resp
 }
 
 func toOrderResponses(orders []*repository.Order) []*orderResponse {
@@ -731,6 +1494,14 @@ func typeToString(orderType int) string {
 		return "LIMIT"
 	case repository.TypeMarket:
 		return "MARKET"
+	case repository.TypeStopLoss:
+		return "STOP_LOSS"
+	case repository.TypeStopLossLimit:
+		return "STOP_LOSS_LIMIT"
+	case repository.TypeTakeProfit:
+		return "TAKE_PROFIT"
+	case repository.TypeTrailingStop:
+		return "TRAILING_STOP"
 	default:
 		return "UNKNOWN"
 	}
@@ -742,11 +1513,30 @@ func tifToString(tif int) string {
 		return "IOC"
 	case 3:
 		return "FOK"
+	case 4:
+		return "POST_ONLY"
+	case 5:
+		return "GTD"
+	case 6:
+		return "DAY"
 	default:
 		return "GTC"
 	}
 }
 
+func stpModeToString(mode int) string {
+	switch mode {
+	case repository.STPExpireMaker:
+		return "EXPIRE_MAKER"
+	case repository.STPExpireBoth:
+		return "EXPIRE_BOTH"
+	case repository.STPDecrement:
+		return "DECREMENT"
+	default:
+		return "EXPIRE_TAKER"
+	}
+}
+
 func statusToString(status int) string {
 	switch status {
 	case repository.StatusInit:
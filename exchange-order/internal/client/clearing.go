@@ -81,6 +81,70 @@ func (c *ClearingClient) UnfreezeBalance(ctx context.Context, userID int64, asse
 	return c.postUnfreeze(ctx, "/internal/unfreeze", req)
 }
 
+type BatchFreezeRequest struct {
+	Items []FreezeRequest `json:"Items"`
+}
+
+type BatchFreezeResponse struct {
+	Success   bool   `json:"Success"`
+	ErrorCode string `json:"ErrorCode"`
+	Applied   int    `json:"Applied"`
+}
+
+// BatchFreezeBalance 在清算的同一事务内批量冻结（做市报价），任一条目余额不足则整批不生效
+func (c *ClearingClient) BatchFreezeBalance(ctx context.Context, items []FreezeRequest) (*BatchFreezeResponse, error) {
+	for i := range items {
+		if items[i].RefType == "" {
+			items[i].RefType = "ORDER"
+		}
+		if items[i].RefID == "" {
+			items[i].RefID = items[i].IdempotencyKey
+		}
+	}
+	respBody, err := c.post(ctx, "/internal/freeze/batch", &BatchFreezeRequest{Items: items})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp BatchFreezeResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &resp, nil
+}
+
+type BatchUnfreezeRequest struct {
+	Items []UnfreezeRequest `json:"Items"`
+}
+
+type BatchUnfreezeResponse struct {
+	Success   bool   `json:"Success"`
+	ErrorCode string `json:"ErrorCode"`
+	Applied   int    `json:"Applied"`
+}
+
+// BatchUnfreezeBalance 在清算的同一事务内批量解冻（批量撤单）
+func (c *ClearingClient) BatchUnfreezeBalance(ctx context.Context, items []UnfreezeRequest) (*BatchUnfreezeResponse, error) {
+	for i := range items {
+		if items[i].RefType == "" {
+			items[i].RefType = "ORDER"
+		}
+		if items[i].RefID == "" {
+			items[i].RefID = items[i].IdempotencyKey
+		}
+	}
+	respBody, err := c.post(ctx, "/internal/unfreeze/batch", &BatchUnfreezeRequest{Items: items})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp BatchUnfreezeResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &resp, nil
+}
+
 func (c *ClearingClient) postFreeze(ctx context.Context, path string, body interface{}) (*FreezeResponse, error) {
 	respBody, err := c.post(ctx, path, body)
 	if err != nil {
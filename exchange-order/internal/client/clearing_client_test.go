@@ -77,6 +77,72 @@ func TestClearingClient_UnfreezeBalance(t *testing.T) {
 	}
 }
 
+func TestClearingClient_BatchUnfreezeBalance(t *testing.T) {
+	var got BatchUnfreezeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/internal/unfreeze/batch" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Success": true,
+			"Applied": 2,
+		})
+	}))
+	defer server.Close()
+
+	c := NewClearingClient(server.URL, "internal-token")
+	resp, err := c.BatchUnfreezeBalance(context.Background(), []UnfreezeRequest{
+		{IdempotencyKey: "unfreeze:order:1", UserID: 22, Asset: "BTC", Amount: 5},
+		{IdempotencyKey: "unfreeze:order:2", UserID: 22, Asset: "USDT", Amount: 50},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success || resp.Applied != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(got.Items) != 2 || got.Items[1].Asset != "USDT" || got.Items[1].RefType != "ORDER" || got.Items[1].RefID != "unfreeze:order:2" {
+		t.Fatalf("unexpected request payload: %+v", got)
+	}
+}
+
+func TestClearingClient_BatchFreezeBalance(t *testing.T) {
+	var got BatchFreezeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/internal/freeze/batch" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Success":   false,
+			"ErrorCode": "INSUFFICIENT_BALANCE",
+		})
+	}))
+	defer server.Close()
+
+	c := NewClearingClient(server.URL, "internal-token")
+	resp, err := c.BatchFreezeBalance(context.Background(), []FreezeRequest{
+		{IdempotencyKey: "freeze:quote:7:USDT", UserID: 22, Asset: "USDT", Amount: 50},
+		{IdempotencyKey: "freeze:quote:7:BTC", UserID: 22, Asset: "BTC", Amount: 5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || resp.ErrorCode != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(got.Items) != 2 || got.Items[1].Asset != "BTC" || got.Items[1].RefType != "ORDER" || got.Items[1].RefID != "freeze:quote:7:BTC" {
+		t.Fatalf("unexpected request payload: %+v", got)
+	}
+}
+
 func TestClearingClient_FreezeBalance_StatusError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
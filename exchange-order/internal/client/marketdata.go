@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MarketDataClient 调用行情服务内部接口（算法单按市场成交量调度）
+type MarketDataClient struct {
+	baseURL       string
+	internalToken string
+	httpClient    *http.Client
+}
+
+// NewMarketDataClient 创建行情客户端
+func NewMarketDataClient(baseURL, internalToken string) *MarketDataClient {
+	return &MarketDataClient{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		internalToken: internalToken,
+		httpClient: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}
+}
+
+// MarketTrade 最近成交
+type MarketTrade struct {
+	TradeID     int64 `json:"tradeId"`
+	Price       int64 `json:"price"`
+	Qty         int64 `json:"qty"`
+	TimestampMs int64 `json:"timestampMs"`
+}
+
+// MarketTicker 行情统计，Volume 为 [OpenTimeMs, CloseTimeMs] 内的累计成交量
+type MarketTicker struct {
+	Symbol      string `json:"symbol"`
+	LastPrice   int64  `json:"lastPrice"`
+	Volume      int64  `json:"volume"`
+	OpenTimeMs  int64  `json:"openTimeMs"`
+	CloseTimeMs int64  `json:"closeTimeMs"`
+}
+
+// GetRecentTrades 获取最近成交（按时间升序）
+func (c *MarketDataClient) GetRecentTrades(ctx context.Context, symbol string, limit int) ([]MarketTrade, error) {
+	query := url.Values{"symbol": {symbol}, "limit": {strconv.Itoa(limit)}}
+	var trades []MarketTrade
+	if err := c.get(ctx, "/v1/trades?"+query.Encode(), &trades); err != nil {
+		return nil, fmt.Errorf("get trades: %w", err)
+	}
+	return trades, nil
+}
+
+// GetTicker 获取交易对行情统计
+func (c *MarketDataClient) GetTicker(ctx context.Context, symbol string) (*MarketTicker, error) {
+	query := url.Values{"symbol": {symbol}}
+	var ticker MarketTicker
+	if err := c.get(ctx, "/v1/ticker?"+query.Encode(), &ticker); err != nil {
+		return nil, fmt.Errorf("get ticker: %w", err)
+	}
+	return &ticker, nil
+}
+
+func (c *MarketDataClient) get(ctx context.Context, path string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if c.internalToken != "" {
+		req.Header.Set("X-Internal-Token", c.internalToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	return nil
+}
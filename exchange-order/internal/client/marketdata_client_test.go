@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMarketDataClient_TradesAndTicker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get("X-Internal-Token"); token != "internal-token" {
+			t.Fatalf("unexpected internal token: %s", token)
+		}
+		if r.URL.Query().Get("symbol") != "BTCUSDT" {
+			t.Fatalf("unexpected symbol: %s", r.URL.RawQuery)
+		}
+		switch r.URL.Path {
+		case "/v1/trades":
+			if r.URL.Query().Get("limit") != "500" {
+				t.Fatalf("unexpected limit: %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode([]map[string]int64{
+				{"tradeId": 1, "price": 100, "qty": 3, "timestampMs": 1000},
+				{"tradeId": 2, "price": 101, "qty": 4, "timestampMs": 2000},
+			})
+		case "/v1/ticker":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"symbol": "BTCUSDT", "volume": 7, "openTimeMs": 1000, "closeTimeMs": 2000,
+			})
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := NewMarketDataClient(server.URL+"/", "internal-token")
+	trades, err := c.GetRecentTrades(context.Background(), "BTCUSDT", 500)
+	if err != nil {
+		t.Fatalf("get trades: %v", err)
+	}
+	if len(trades) != 2 || trades[1].Qty != 4 || trades[1].TimestampMs != 2000 {
+		t.Fatalf("unexpected trades: %+v", trades)
+	}
+	ticker, err := c.GetTicker(context.Background(), "BTCUSDT")
+	if err != nil {
+		t.Fatalf("get ticker: %v", err)
+	}
+	if ticker.Volume != 7 || ticker.CloseTimeMs-ticker.OpenTimeMs != 1000 {
+		t.Fatalf("unexpected ticker: %+v", ticker)
+	}
+}
+
+func TestMarketDataClient_StatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	c := NewMarketDataClient(server.URL, "bad")
+	if _, err := c.GetTicker(context.Background(), "BTCUSDT"); err == nil {
+		t.Fatal("expected error for non-200 status")
+	}
+}
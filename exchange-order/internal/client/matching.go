@@ -2,6 +2,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/exchange/common/pkg/shard"
 )
 
 const (
@@ -21,6 +24,8 @@ var ErrNoReferencePrice = errors.New("no reference price")
 // MatchingClient 调用撮合引擎接口
 type MatchingClient struct {
 	baseURL       string
+	shardURLs     []string      // 按分片号排列的撮合实例地址
+	shards        *shard.Router // 为空时只访问 baseURL
 	internalToken string
 	httpClient    *http.Client
 
@@ -45,6 +50,34 @@ func NewMatchingClient(baseURL, internalToken string) *MatchingClient {
 	}
 }
 
+// NewShardedMatchingClient 创建分片撮合客户端，按交易对所属分片访问对应实例
+func NewShardedMatchingClient(shardURLs []string, shards *shard.Router, internalToken string) (*MatchingClient, error) {
+	if len(shardURLs) == 0 || shards == nil || len(shardURLs) != shards.Count() {
+		return nil, fmt.Errorf("need one matching URL per shard")
+	}
+	c := NewMatchingClient(shardURLs[0], internalToken)
+	c.shardURLs = make([]string, len(shardURLs))
+	for i, u := range shardURLs {
+		c.shardURLs[i] = strings.TrimRight(u, "/")
+	}
+	c.shards = shards
+	return c, nil
+}
+
+// urlFor 交易对所属撮合实例地址
+func (c *MatchingClient) urlFor(symbol string) (string, error) {
+	if c.shards == nil {
+		return c.baseURL, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	id, err := c.shards.Shard(ctx, symbol)
+	if err != nil {
+		return "", err
+	}
+	return c.shardURLs[id], nil
+}
+
 // GetLastPrice 获取参考价
 func (c *MatchingClient) GetLastPrice(symbol string) (int64, error) {
 	if symbol == "" {
@@ -56,7 +89,11 @@ func (c *MatchingClient) GetLastPrice(symbol string) (int64, error) {
 		return price, nil
 	}
 
-	url := fmt.Sprintf("%s/depth?symbol=%s", c.baseURL, symbol)
+	baseURL, err := c.urlFor(symbol)
+	if err != nil {
+		return 0, fmt.Errorf("route symbol: %w", err)
+	}
+	url := fmt.Sprintf("%s/depth?symbol=%s", baseURL, symbol)
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return 0, fmt.Errorf("create request: %w", err)
@@ -7,6 +7,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/exchange/common/pkg/shard"
 )
 
 func TestPriceMatchingClient_GetLastPrice(t *testing.T) {
@@ -168,3 +170,39 @@ func TestPriceMatchingClient_GetLastPrice_SymbolRequired(t *testing.T) {
 		t.Fatalf("expected symbol required error, got %v", err)
 	}
 }
+
+func TestPriceMatchingClient_GetLastPrice_Sharded(t *testing.T) {
+	newShard := func(price int64) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp := map[string]interface{}{
+				"bids": []map[string]int64{{"price": price, "qty": 1}},
+			}
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				t.Fatalf("encode response: %v", err)
+			}
+		}))
+	}
+	shard0 := newShard(100)
+	defer shard0.Close()
+	shard1 := newShard(200)
+	defer shard1.Close()
+
+	router, err := shard.NewRouter(shard.Config{Count: 2, Assignments: "BTCUSDT=1,ETHUSDT=0"}, nil)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+	client, err := NewShardedMatchingClient([]string{shard0.URL, shard1.URL + "/"}, router, "")
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if price, err := client.GetLastPrice("BTCUSDT"); err != nil || price != 200 {
+		t.Fatalf("expected BTCUSDT from shard 1, got %d, %v", price, err)
+	}
+	if price, err := client.GetLastPrice("ETHUSDT"); err != nil || price != 100 {
+		t.Fatalf("expected ETHUSDT from shard 0, got %d, %v", price, err)
+	}
+
+	if _, err := NewShardedMatchingClient([]string{shard0.URL}, router, ""); err == nil {
+		t.Fatal("expected URL count mismatch rejected")
+	}
+}
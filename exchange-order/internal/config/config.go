@@ -2,6 +2,7 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -10,6 +11,7 @@ import (
 
 	envconfig "github.com/exchange/common/pkg/config"
 	commondecimal "github.com/exchange/common/pkg/decimal"
+	"github.com/exchange/common/pkg/shard"
 )
 
 // Config 服务配置
@@ -49,6 +51,8 @@ type Config struct {
 
 	// Matching
 	MatchingServiceURL string
+	// 撮合分片：订单按交易对写入所属分片的订单流，深度从对应实例查询
+	MatchingShards shard.Config // URLs 未配置时使用 MatchingServiceURL
 
 	// Clearing
 	ClearingBaseURL string
@@ -56,6 +60,78 @@ type Config struct {
 
 	// Price protection
 	PriceProtection PriceProtectionConfig
+
+	// DAY 订单到期时间：UTC 零点后的偏移（交易时段结束）
+	DaySessionEnd time.Duration
+
+	// Market data（算法单按市场成交量调度）
+	MarketDataServiceURL string
+
+	// 算法单调度
+	Algo AlgoConfig
+
+	// 下单前风控
+	Risk RiskConfig
+}
+
+// RiskLimits 下单前风控限额（0 或空表示不限，金额为计价资产单位的十进制数）
+type RiskLimits struct {
+	MaxOpenOrders        int    `json:"maxOpenOrders"`
+	MaxOrderNotional     string `json:"maxOrderNotional"`
+	MaxNotionalPerMinute string `json:"maxNotionalPerMinute"`
+	MaxOrdersPerSecond   int    `json:"maxOrdersPerSecond"`
+}
+
+// RiskConfig 下单前风控配置
+type RiskConfig struct {
+	Enabled    bool
+	Default    RiskLimits
+	TierLimits string // 按账户等级覆盖的限额（JSON：等级 → 限额，未给出的字段沿用全局限额）
+}
+
+// Tiers 解析按账户等级覆盖的限额
+func (c RiskConfig) Tiers() (map[string]RiskLimits, error) {
+	if strings.TrimSpace(c.TierLimits) == "" {
+		return nil, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(c.TierLimits), &raw); err != nil {
+		return nil, fmt.Errorf("RISK_TIER_LIMITS: %w", err)
+	}
+	tiers := make(map[string]RiskLimits, len(raw))
+	for tier, data := range raw {
+		limits := c.Default
+		if err := json.Unmarshal(data, &limits); err != nil {
+			return nil, fmt.Errorf("RISK_TIER_LIMITS %q: %w", tier, err)
+		}
+		if err := limits.validate(); err != nil {
+			return nil, fmt.Errorf("RISK_TIER_LIMITS %q: %w", tier, err)
+		}
+		tiers[tier] = limits
+	}
+	return tiers, nil
+}
+
+func (l RiskLimits) validate() error {
+	if l.MaxOpenOrders < 0 || l.MaxOrdersPerSecond < 0 {
+		return fmt.Errorf("order limits must not be negative")
+	}
+	for _, value := range []string{l.MaxOrderNotional, l.MaxNotionalPerMinute} {
+		if value == "" {
+			continue
+		}
+		if v, err := commondecimal.New(value); err != nil || v.IsNegative() {
+			return fmt.Errorf("invalid notional limit %q", value)
+		}
+	}
+	return nil
+}
+
+// AlgoConfig 算法单调度配置
+type AlgoConfig struct {
+	PollInterval     time.Duration // 调度周期
+	MinSliceInterval time.Duration // 最小拆单间隔
+	MaxDuration      time.Duration // 最长持续时间
 }
 
 // PriceProtectionConfig 价格保护配置
@@ -97,6 +173,7 @@ func Load() *Config {
 		WorkerID: envconfig.GetEnvInt64("WORKER_ID", 3),
 
 		MatchingServiceURL: envconfig.GetEnv("MATCHING_SERVICE_URL", "http://localhost:8082"),
+		MatchingShards:     shard.ConfigFromEnv(),
 
 		ClearingBaseURL: envconfig.GetEnv("CLEARING_BASE_URL", "http://localhost:8083"),
 		InternalToken:   envconfig.GetEnv("INTERNAL_TOKEN", ""),
@@ -105,6 +182,27 @@ func Load() *Config {
 			Enabled:          envconfig.GetEnvBool("PRICE_PROTECTION_ENABLED", true),
 			DefaultLimitRate: getEnvDecimal("PRICE_PROTECTION_DEFAULT_LIMIT_RATE", defaultLimitRate),
 		},
+
+		DaySessionEnd: envconfig.GetEnvDuration("DAY_ORDER_SESSION_END", 0),
+
+		MarketDataServiceURL: envconfig.GetEnv("MARKETDATA_SERVICE_URL", "http://localhost:8084"),
+
+		Algo: AlgoConfig{
+			PollInterval:     envconfig.GetEnvDuration("ALGO_POLL_INTERVAL", time.Second),
+			MinSliceInterval: envconfig.GetEnvDuration("ALGO_MIN_SLICE_INTERVAL", 5*time.Second),
+			MaxDuration:      envconfig.GetEnvDuration("ALGO_MAX_DURATION", 24*time.Hour),
+		},
+
+		Risk: RiskConfig{
+			Enabled: envconfig.GetEnvBool("RISK_ENABLED", true),
+			Default: RiskLimits{
+				MaxOpenOrders:        envconfig.GetEnvInt("RISK_MAX_OPEN_ORDERS", 0),
+				MaxOrderNotional:     envconfig.GetEnv("RISK_MAX_ORDER_NOTIONAL", ""),
+				MaxNotionalPerMinute: envconfig.GetEnv("RISK_MAX_NOTIONAL_PER_MINUTE", ""),
+				MaxOrdersPerSecond:   envconfig.GetEnvInt("RISK_MAX_ORDERS_PER_SECOND", 0),
+			},
+			TierLimits: envconfig.GetEnv("RISK_TIER_LIMITS", ""),
+		},
 	}
 }
 
@@ -112,6 +210,24 @@ func (c *Config) Validate() error {
 	if c.InternalToken == "" {
 		return fmt.Errorf("INTERNAL_TOKEN is required")
 	}
+	if c.DaySessionEnd < 0 || c.DaySessionEnd >= 24*time.Hour {
+		return fmt.Errorf("DAY_ORDER_SESSION_END must be within [0, 24h)")
+	}
+	if c.Algo.PollInterval <= 0 || c.Algo.MinSliceInterval <= 0 || c.Algo.MaxDuration < c.Algo.MinSliceInterval {
+		return fmt.Errorf("ALGO_POLL_INTERVAL and ALGO_MIN_SLICE_INTERVAL must be positive and ALGO_MAX_DURATION at least ALGO_MIN_SLICE_INTERVAL")
+	}
+	if err := c.Risk.Default.validate(); err != nil {
+		return fmt.Errorf("invalid RISK_* limits: %w", err)
+	}
+	if _, err := c.Risk.Tiers(); err != nil {
+		return err
+	}
+	if err := c.MatchingShards.Validate(); err != nil {
+		return fmt.Errorf("invalid MATCHING_SHARD_COUNT/MATCHING_SHARDS: %w", err)
+	}
+	if err := c.MatchingShards.ValidateURLs(); err != nil {
+		return fmt.Errorf("MATCHING_SHARD_URLS must list one URL per shard: %w", err)
+	}
 
 	// 生产/预发必须显式配置，禁止使用 dev 默认值
 	if c.AppEnv != "dev" {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	envconfig "github.com/exchange/common/pkg/config"
 	commondecimal "github.com/exchange/common/pkg/decimal"
@@ -42,6 +43,118 @@ func TestPriceProtectionInvalidLimitRate(t *testing.T) {
 	}
 }
 
+func TestDaySessionEnd(t *testing.T) {
+	t.Setenv("INTERNAL_TOKEN", "token")
+	t.Setenv("DAY_ORDER_SESSION_END", "")
+	cfg := Load()
+	if cfg.DaySessionEnd != 0 {
+		t.Fatalf("expected UTC midnight by default, got %s", cfg.DaySessionEnd)
+	}
+
+	t.Setenv("DAY_ORDER_SESSION_END", "21h30m")
+	cfg = Load()
+	if cfg.DaySessionEnd != 21*time.Hour+30*time.Minute {
+		t.Fatalf("unexpected session end: %s", cfg.DaySessionEnd)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	cfg.DaySessionEnd = 24 * time.Hour
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected session end beyond a day rejected")
+	}
+}
+
+func TestAlgoConfig(t *testing.T) {
+	t.Setenv("INTERNAL_TOKEN", "token")
+	t.Setenv("ALGO_MIN_SLICE_INTERVAL", "")
+	t.Setenv("ALGO_MAX_DURATION", "2h")
+	cfg := Load()
+	if cfg.Algo.PollInterval != time.Second || cfg.Algo.MinSliceInterval != 5*time.Second || cfg.Algo.MaxDuration != 2*time.Hour {
+		t.Fatalf("unexpected algo config: %+v", cfg.Algo)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	cfg.Algo.MaxDuration = time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected max duration below min slice interval rejected")
+	}
+}
+
+func TestRiskConfig(t *testing.T) {
+	t.Setenv("INTERNAL_TOKEN", "token")
+	t.Setenv("RISK_MAX_OPEN_ORDERS", "200")
+	t.Setenv("RISK_MAX_ORDER_NOTIONAL", "100000")
+	t.Setenv("RISK_TIER_LIMITS", `{"vip":{"maxOpenOrders":1000,"maxOrdersPerSecond":50},"restricted":{"maxOrderNotional":"500.5"}}`)
+	cfg := Load()
+	if !cfg.Risk.Enabled || cfg.Risk.Default.MaxOpenOrders != 200 || cfg.Risk.Default.MaxOrderNotional != "100000" {
+		t.Fatalf("unexpected default risk limits: %+v", cfg.Risk)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	tiers, err := cfg.Risk.Tiers()
+	if err != nil {
+		t.Fatalf("tiers: %v", err)
+	}
+	// 未给出的字段沿用全局限额
+	if vip := tiers["vip"]; vip.MaxOpenOrders != 1000 || vip.MaxOrdersPerSecond != 50 || vip.MaxOrderNotional != "100000" {
+		t.Fatalf("unexpected vip limits: %+v", vip)
+	}
+	if restricted := tiers["restricted"]; restricted.MaxOpenOrders != 200 || restricted.MaxOrderNotional != "500.5" {
+		t.Fatalf("unexpected restricted limits: %+v", restricted)
+	}
+
+	cfg.Risk.TierLimits = `{"vip":{"maxOrderNotional":"-1"}}`
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected negative tier notional rejected")
+	}
+	cfg.Risk.TierLimits = `[1]`
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected malformed tier limits rejected")
+	}
+	cfg.Risk.TierLimits = ""
+	cfg.Risk.Default.MaxNotionalPerMinute = "abc"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected invalid notional limit rejected")
+	}
+}
+
+func TestMatchingShards(t *testing.T) {
+	t.Setenv("INTERNAL_TOKEN", "token")
+	t.Setenv("MATCHING_SERVICE_URL", "http://matching:8082")
+	t.Setenv("MATCHING_SHARD_COUNT", "")
+	t.Setenv("MATCHING_SHARD_URLS", "")
+	cfg := Load()
+	if urls := cfg.MatchingShards.ServiceURLs(cfg.MatchingServiceURL); cfg.MatchingShards.Count != 1 || len(urls) != 1 || urls[0] != "http://matching:8082" {
+		t.Fatalf("expected single unsharded matching URL, got %+v %v", cfg.MatchingShards, urls)
+	}
+
+	t.Setenv("MATCHING_SHARD_COUNT", "2")
+	t.Setenv("MATCHING_SHARDS", "BTCUSDT=1")
+	t.Setenv("MATCHING_SHARD_URLS", "http://matching-0:8082, http://matching-1:8082")
+	cfg = Load()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if urls := cfg.MatchingShards.ServiceURLs(cfg.MatchingServiceURL); len(urls) != 2 || urls[1] != "http://matching-1:8082" {
+		t.Fatalf("unexpected shard URLs: %v", urls)
+	}
+
+	cfg.MatchingShards.URLs = cfg.MatchingShards.URLs[:1]
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected missing shard URL rejected")
+	}
+	cfg = Load()
+	cfg.MatchingShards.Assignments = "BTCUSDT=2"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected out-of-range shard assignment rejected")
+	}
+}
+
 func TestConfigHelpers(t *testing.T) {
 	t.Setenv("TEST_ENV", "value")
 	if envconfig.GetEnv("TEST_ENV", "default") != "value" {
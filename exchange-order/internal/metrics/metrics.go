@@ -14,11 +14,15 @@ type Metrics struct {
 	orderCreated  *prometheus.CounterVec
 	orderLatency  prometheus.Histogram
 	orderRejected *prometheus.CounterVec
+	riskRejected  *prometheus.CounterVec
 	activeOrders  prometheus.Gauge
 
 	streamPending *prometheus.GaugeVec
 	streamErrors  *prometheus.CounterVec
 	streamDLQ     *prometheus.CounterVec
+
+	eventSeqGaps       *prometheus.CounterVec
+	eventSeqDuplicates *prometheus.CounterVec
 }
 
 // New creates a metrics registry and registers order metrics.
@@ -46,6 +50,11 @@ func New() *Metrics {
 		Help: "Total number of rejected orders.",
 	}, []string{"reason"})
 
+	riskRejected := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "order_risk_rejected_total",
+		Help: "Total number of orders rejected by pre-trade risk checks.",
+	}, []string{"code", "tier"})
+
 	activeOrders := prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "active_orders_count",
 		Help: "Current number of active orders.",
@@ -66,17 +75,31 @@ func New() *Metrics {
 		Help: "Total number of messages moved to Redis Stream DLQ.",
 	}, []string{"stream", "group"})
 
-	registry.MustRegister(orderCreated, orderLatency, orderRejected, activeOrders, streamPending, streamErrors, streamDLQ)
+	eventSeqGaps := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "event_seq_gaps_total",
+		Help: "Total number of matching event sequence gaps detected.",
+	}, []string{"stream", "group", "symbol"})
+
+	eventSeqDuplicates := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "event_seq_duplicates_total",
+		Help: "Total number of duplicate or out-of-order matching events received.",
+	}, []string{"stream", "group", "symbol"})
+
+	registry.MustRegister(orderCreated, orderLatency, orderRejected, riskRejected, activeOrders, streamPending, streamErrors, streamDLQ, eventSeqGaps, eventSeqDuplicates)
 
 	return &Metrics{
 		registry:      registry,
 		orderCreated:  orderCreated,
 		orderLatency:  orderLatency,
 		orderRejected: orderRejected,
+		riskRejected:  riskRejected,
 		activeOrders:  activeOrders,
 		streamPending: streamPending,
 		streamErrors:  streamErrors,
 		streamDLQ:     streamDLQ,
+
+		eventSeqGaps:       eventSeqGaps,
+		eventSeqDuplicates: eventSeqDuplicates,
 	}
 }
 
@@ -105,6 +128,14 @@ func (m *Metrics) IncOrderRejected(reason string) {
 	m.orderRejected.WithLabelValues(reason).Inc()
 }
 
+// IncRiskRejected increments the pre-trade risk rejection counter.
+func (m *Metrics) IncRiskRejected(code, tier string) {
+	if m == nil {
+		return
+	}
+	m.riskRejected.WithLabelValues(code, tier).Inc()
+}
+
 // SetActiveOrders sets the active orders gauge.
 func (m *Metrics) SetActiveOrders(count int) {
 	m.activeOrders.Set(float64(count))
@@ -140,3 +171,19 @@ func (m *Metrics) IncStreamDLQ(stream, group string) {
 	}
 	m.streamDLQ.WithLabelValues(stream, group).Inc()
 }
+
+// IncEventSeqGap increments the event sequence gap counter.
+func (m *Metrics) IncEventSeqGap(stream, group, symbol string) {
+	if m == nil {
+		return
+	}
+	m.eventSeqGaps.WithLabelValues(stream, group, symbol).Inc()
+}
+
+// IncEventSeqDuplicate increments the duplicate event sequence counter.
+func (m *Metrics) IncEventSeqDuplicate(stream, group, symbol string) {
+	if m == nil {
+		return
+	}
+	m.eventSeqDuplicates.WithLabelValues(stream, group, symbol).Inc()
+}
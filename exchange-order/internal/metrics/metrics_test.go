@@ -25,6 +25,7 @@ func TestMetricsCountersAndGauge(t *testing.T) {
 
 	m.IncOrderCreated("BTCUSDT", "BUY")
 	m.IncOrderRejected("INVALID_PRICE")
+	m.IncRiskRejected("ORDER_RATE_EXCEEDED", "default")
 	m.SetActiveOrders(5)
 	m.IncActiveOrders()
 	m.DecActiveOrders()
@@ -52,6 +53,14 @@ func TestMetricsCountersAndGauge(t *testing.T) {
 		t.Fatalf("expected order_rejected_total=1, got %v", got)
 	}
 
+	riskRejected := findMetric(t, families, "order_risk_rejected_total")
+	if riskRejected == nil || len(riskRejected.GetMetric()) != 1 {
+		t.Fatalf("expected order_risk_rejected_total metric")
+	}
+	if got := riskRejected.GetMetric()[0].GetCounter().GetValue(); got != 1 {
+		t.Fatalf("expected order_risk_rejected_total=1, got %v", got)
+	}
+
 	active := findMetric(t, families, "active_orders_count")
 	if active == nil || len(active.GetMetric()) != 1 {
 		t.Fatalf("expected active_orders_count metric")
@@ -85,3 +94,27 @@ func TestMetricsHandler(t *testing.T) {
 		t.Fatalf("expected metrics output to include order_created_total")
 	}
 }
+
+func TestMetricsEventSeq(t *testing.T) {
+	m := New()
+	m.IncEventSeqGap("matching:events", "group", "BTCUSDT")
+	m.IncEventSeqDuplicate("matching:events", "group", "BTCUSDT")
+	m.IncEventSeqDuplicate("matching:events", "group", "BTCUSDT")
+
+	var nilMetrics *Metrics
+	nilMetrics.IncEventSeqGap("s", "g", "x")
+	nilMetrics.IncEventSeqDuplicate("s", "g", "x")
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("gather metrics: %v", err)
+	}
+	gaps := findMetric(t, families, "event_seq_gaps_total")
+	if gaps == nil || gaps.GetMetric()[0].GetCounter().GetValue() != 1 {
+		t.Fatalf("expected event_seq_gaps_total=1")
+	}
+	dups := findMetric(t, families, "event_seq_duplicates_total")
+	if dups == nil || dups.GetMetric()[0].GetCounter().GetValue() != 2 {
+		t.Fatalf("expected event_seq_duplicates_total=2")
+	}
+}
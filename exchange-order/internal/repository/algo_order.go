@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrAlgoOrderNotFound = errors.New("algo order not found")
+	ErrAlgoOrderConflict = errors.New("algo order modified concurrently")
+)
+
+// 算法单类型
+const (
+	AlgoTypeTWAP = "TWAP" // 按时间均匀拆分
+	AlgoTypeVWAP = "VWAP" // 按市场成交量节奏拆分
+)
+
+// AlgoOrderStatus 算法单状态
+const (
+	AlgoStatusRunning  = 1 // 调度中
+	AlgoStatusFinished = 2 // 全部成交
+	AlgoStatusCanceled = 3 // 用户撤销
+	AlgoStatusExpired  = 4 // 到达结束时间仍有剩余
+	AlgoStatusFailed   = 5 // 子单被拒绝，Reason 为拒绝原因
+)
+
+// AlgoOrder 算法单（母单）
+//
+// 母单不冻结资产，每个子单按普通订单下单冻结。同一时间最多一个未结算子单（ActiveChildID），
+// 子单终态后累加成交量再下发下一个子单。
+type AlgoOrder struct {
+	AlgoID           int64
+	ClientAlgoID     string
+	UserID           int64
+	Symbol           string
+	Side             int
+	AlgoType         string
+	TotalQty         int64
+	ExecutedQty      int64
+	ExecutedQuoteQty int64
+	LimitPrice       int64
+	ParticipationBps int64 // 子单不超过区间市场成交量的比例（基点），0 表示不限
+	SliceIntervalMs  int64
+	StartTimeMs      int64
+	EndTimeMs        int64
+	NextSliceTimeMs  int64
+	LastSliceTimeMs  int64 // 上次统计市场成交量的时间
+	ExpectedVolume   int64 // VWAP 预计区间市场成交量，0 表示按 TWAP 节奏
+	MarketVolume     int64 // 开始后累计观察到的市场成交量
+	ChildCount       int
+	ActiveChildID    int64
+	Status           int
+	Reason           string
+	CreateTimeMs     int64
+	UpdateTimeMs     int64
+}
+
+// IsFinal 算法单是否终态
+func (a *AlgoOrder) IsFinal() bool {
+	return a.Status != AlgoStatusRunning
+}
+
+// algoOrderColumns 算法单查询列（与 scanAlgoOrder 顺序一致）
+const algoOrderColumns = `algo_id, client_algo_id, user_id, symbol, side, algo_type, total_qty,
+		       executed_qty, executed_quote_qty, limit_price, participation_bps, slice_interval_ms,
+		       start_time_ms, end_time_ms, next_slice_time_ms, last_slice_time_ms, expected_volume,
+		       market_volume, child_count, active_child_id, status, reason, create_time_ms, update_time_ms`
+
+// CreateAlgoOrder 创建算法单
+func (r *OrderRepository) CreateAlgoOrder(ctx context.Context, a *AlgoOrder) error {
+	query := `
+		INSERT INTO exchange_order.algo_orders
+		(algo_id, client_algo_id, user_id, symbol, side, algo_type, total_qty, limit_price,
+		 participation_bps, slice_interval_ms, start_time_ms, end_time_ms, next_slice_time_ms,
+		 last_slice_time_ms, expected_volume, status, create_time_ms, update_time_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		a.AlgoID, nullString(a.ClientAlgoID), a.UserID, a.Symbol, a.Side, a.AlgoType, a.TotalQty, a.LimitPrice,
+		a.ParticipationBps, a.SliceIntervalMs, a.StartTimeMs, a.EndTimeMs, a.NextSliceTimeMs,
+		a.LastSliceTimeMs, a.ExpectedVolume, a.Status, a.CreateTimeMs, a.UpdateTimeMs,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateClientOrderID
+		}
+		return fmt.Errorf("insert algo order: %w", err)
+	}
+	return nil
+}
+
+// GetAlgoOrder 获取算法单
+func (r *OrderRepository) GetAlgoOrder(ctx context.Context, algoID int64) (*AlgoOrder, error) {
+	query := `
+		SELECT ` + algoOrderColumns + `
+		FROM exchange_order.algo_orders
+		WHERE algo_id = $1
+	`
+	return scanAlgoOrder(r.db.QueryRowContext(ctx, query, algoID))
+}
+
+// GetAlgoOrderByClientID 通过 clientAlgoId 获取算法单
+func (r *OrderRepository) GetAlgoOrderByClientID(ctx context.Context, userID int64, clientAlgoID string) (*AlgoOrder, error) {
+	query := `
+		SELECT ` + algoOrderColumns + `
+		FROM exchange_order.algo_orders
+		WHERE user_id = $1 AND client_algo_id = $2
+	`
+	return scanAlgoOrder(r.db.QueryRowContext(ctx, query, userID, clientAlgoID))
+}
+
+// ListOpenAlgoOrders 查询用户调度中的算法单
+func (r *OrderRepository) ListOpenAlgoOrders(ctx context.Context, userID int64, symbol string, limit int) ([]*AlgoOrder, error) {
+	query := `
+		SELECT ` + algoOrderColumns + `
+		FROM exchange_order.algo_orders
+		WHERE user_id = $1 AND status = $2
+		  AND ($3 = '' OR symbol = $3)
+		ORDER BY create_time_ms DESC
+		LIMIT $4
+	`
+	return r.queryAlgoOrders(ctx, query, userID, AlgoStatusRunning, symbol, limit)
+}
+
+// ListDueAlgoOrders 查询需要调度的算法单：到达下次拆单时间的调度中算法单，以及仍有未结算子单的算法单
+func (r *OrderRepository) ListDueAlgoOrders(ctx context.Context, nowMs int64, limit int) ([]*AlgoOrder, error) {
+	query := `
+		SELECT ` + algoOrderColumns + `
+		FROM exchange_order.algo_orders
+		WHERE (status = $1 AND next_slice_time_ms <= $2) OR active_child_id IS NOT NULL
+		ORDER BY next_slice_time_ms
+		LIMIT $3
+	`
+	return r.queryAlgoOrders(ctx, query, AlgoStatusRunning, nowMs, limit)
+}
+
+// UpdateAlgoOrder 保存调度进度，仅当记录仍为 prevUpdateTimeMs 时更新（乐观锁），否则返回 ErrAlgoOrderConflict
+func (r *OrderRepository) UpdateAlgoOrder(ctx context.Context, a *AlgoOrder, prevUpdateTimeMs int64) error {
+	query := `
+		UPDATE exchange_order.algo_orders
+		SET executed_qty = $1, executed_quote_qty = $2, next_slice_time_ms = $3, last_slice_time_ms = $4,
+		    market_volume = $5, child_count = $6, active_child_id = $7, status = $8, reason = $9,
+		    update_time_ms = $10
+		WHERE algo_id = $11 AND update_time_ms = $12
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		a.ExecutedQty, a.ExecutedQuoteQty, a.NextSliceTimeMs, a.LastSliceTimeMs,
+		a.MarketVolume, a.ChildCount, nullInt64(a.ActiveChildID), a.Status, nullString(a.Reason),
+		a.UpdateTimeMs, a.AlgoID, prevUpdateTimeMs,
+	)
+	if err != nil {
+		return fmt.Errorf("update algo order: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrAlgoOrderConflict
+	}
+	return nil
+}
+
+// CancelAlgoOrder 将调度中的算法单置为撤销，算法单已终态时返回 ErrAlgoOrderNotFound
+//
+// update_time_ms 至少前进 1ms，使并发的调度进度保存因乐观锁失败。
+func (r *OrderRepository) CancelAlgoOrder(ctx context.Context, algoID int64, reason string, updateTimeMs int64) (*AlgoOrder, error) {
+	query := `
+		UPDATE exchange_order.algo_orders
+		SET status = $1, reason = $2, update_time_ms = GREATEST($3, update_time_ms + 1)
+		WHERE algo_id = $4 AND status = $5
+		RETURNING ` + algoOrderColumns
+	return scanAlgoOrder(r.db.QueryRowContext(ctx, query, AlgoStatusCanceled, nullString(reason), updateTimeMs, algoID, AlgoStatusRunning))
+}
+
+func (r *OrderRepository) queryAlgoOrders(ctx context.Context, query string, args ...interface{}) ([]*AlgoOrder, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query algo orders: %w", err)
+	}
+	defer rows.Close()
+
+	var algos []*AlgoOrder
+	for rows.Next() {
+		a, err := scanAlgoOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		algos = append(algos, a)
+	}
+	return algos, rows.Err()
+}
+
+func scanAlgoOrder(row rowScanner) (*AlgoOrder, error) {
+	var a AlgoOrder
+	var clientAlgoID, reason sql.NullString
+	var activeChildID sql.NullInt64
+	err := row.Scan(
+		&a.AlgoID, &clientAlgoID, &a.UserID, &a.Symbol, &a.Side, &a.AlgoType, &a.TotalQty,
+		&a.ExecutedQty, &a.ExecutedQuoteQty, &a.LimitPrice, &a.ParticipationBps, &a.SliceIntervalMs,
+		&a.StartTimeMs, &a.EndTimeMs, &a.NextSliceTimeMs, &a.LastSliceTimeMs, &a.ExpectedVolume,
+		&a.MarketVolume, &a.ChildCount, &activeChildID, &a.Status, &reason, &a.CreateTimeMs, &a.UpdateTimeMs,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrAlgoOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan algo order: %w", err)
+	}
+	a.ClientAlgoID = clientAlgoID.String
+	a.Reason = reason.String
+	a.ActiveChildID = activeChildID.Int64
+	return &a, nil
+}
@@ -4,13 +4,18 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+
+	"github.com/exchange/common/pkg/validate"
+	"github.com/lib/pq"
 )
 
 var (
 	ErrOrderNotFound          = errors.New("order not found")
 	ErrDuplicateClientOrderID = errors.New("duplicate client order id")
+	ErrAmendInProgress        = errors.New("amend in progress")
 )
 
 // OrderStatus 订单状态
@@ -24,6 +29,9 @@ const (
 	StatusExpired         = 6
 )
 
+// CancelReasonExpired GTD/DAY 订单到期由撮合撤销的原因，对应订单状态 EXPIRED
+const CancelReasonExpired = "EXPIRED"
+
 // Side 订单方向
 const (
 	SideBuy  = 1
@@ -32,8 +40,28 @@ const (
 
 // OrderType 订单类型
 const (
-	TypeLimit  = 1
-	TypeMarket = 2
+	TypeLimit         = 1
+	TypeMarket        = 2
+	TypeStopLoss      = 3 // 止损市价
+	TypeStopLossLimit = 4 // 止损限价
+	TypeTakeProfit    = 5 // 止盈市价
+	TypeTrailingStop  = 6 // 跟踪止损市价
+)
+
+// SymbolStatus 交易对状态
+const (
+	SymbolStatusTrading    = 1
+	SymbolStatusHalt       = 2
+	SymbolStatusCancelOnly = 3
+	SymbolStatusAuction    = 4 // 集合竞价：只挂单不撮合，恢复交易时统一撮合
+)
+
+// STPMode 自成交防护模式
+const (
+	STPExpireTaker = 1 // 撤销 taker（默认）
+	STPExpireMaker = 2 // 撤销 maker
+	STPExpireBoth  = 3 // 同时撤销双方
+	STPDecrement   = 4 // 双方扣减，扣减至 0 的一方撤销
 )
 
 // Order 订单
@@ -56,6 +84,30 @@ type Order struct {
 	CreateTimeMs       int64
 	UpdateTimeMs       int64
 	TransactTimeMs     int64
+	TriggerTimeMs      int64 // 条件单触发时间，0 表示未触发
+	STPMode            int   // 自成交防护模式
+	FrozenQuoteQty     int64 // 买单累计冻结额（改单后维护），0 表示 price*orig_qty
+	PendingAmendID     int64 // 已发送撮合、尚未确认的改单 ID
+	PendingAmendFreeze int64 // 改单预冻结金额（买单 quote，卖单 base）
+	DisplayQty         int64 // 冰山单每次展示数量，0 表示非冰山单
+	ExpireTimeMs       int64 // GTD/DAY 到期时间，0 表示不过期
+	QuoteID            int64 // 做市报价请求 ID，0 表示非报价单
+	QuoteOrderQty      int64 // 按金额市价买单的金额（即冻结额，orig_qty 为 0），0 表示按数量下单
+	ListID             int64 // 订单列表 ID（OCO），0 表示非列表订单；列表订单共用列表冻结
+	TrailingDelta      int64 // 跟踪止损回调价差，0 表示按 TrailingBps
+	TrailingBps        int64 // 跟踪止损回调比例（基点）
+	ActivationPrice    int64 // 跟踪止损激活价，0 表示接受即激活
+	TrailingExtreme    int64 // 跟踪止损激活后的最优价（由撮合事件更新），0 表示未激活
+}
+
+// IsStopOrder 是否为条件单
+func (o *Order) IsStopOrder() bool {
+	switch o.Type {
+	case TypeStopLoss, TypeStopLossLimit, TypeTakeProfit, TypeTrailingStop:
+		return true
+	default:
+		return false
+	}
 }
 
 // Trade 成交
@@ -85,17 +137,26 @@ type SymbolConfig struct {
 	QtyStep        string // DECIMAL from DB
 	PricePrecision int
 	QtyPrecision   int
-	BasePrecision  int    `json:"-"`
-	QuotePrecision int    `json:"-"`
-	MinQty         string // DECIMAL from DB
-	MaxQty         string // DECIMAL from DB
-	MinNotional    string // DECIMAL from DB
-	PriceLimitRate string // DECIMAL from DB
-	MakerFeeRate   string // DECIMAL from DB
-	TakerFeeRate   string // DECIMAL from DB
-	Status         int    // 1=TRADING, 2=HALT, 3=CANCEL_ONLY
+	BasePrecision  int                 `json:"-"`
+	QuotePrecision int                 `json:"-"`
+	MinQty         string              // DECIMAL from DB
+	MaxQty         string              // DECIMAL from DB
+	MinNotional    string              // DECIMAL from DB
+	PriceLimitRate string              // DECIMAL from DB
+	MakerFeeRate   string              // DECIMAL from DB
+	TakerFeeRate   string              // DECIMAL from DB
+	Status         int                 // 1=TRADING, 2=HALT, 3=CANCEL_ONLY, 4=AUCTION
+	TickBands      []validate.TickBand // 价格分档（按 minPrice 升序），为空时整条价格轴使用 PriceTick / QtyStep
 }
 
+// orderColumns 订单查询列（与 scanOrderRow 顺序一致）
+const orderColumns = `order_id, client_order_id, user_id, symbol, side, type, time_in_force,
+		       price, stop_price, orig_qty, executed_qty, cumulative_quote_qty, status,
+		       reject_reason, cancel_reason, create_time_ms, update_time_ms, transact_time_ms,
+		       trigger_time_ms, stp_mode, frozen_quote_qty, pending_amend_id, pending_amend_freeze,
+		       display_qty, expire_time_ms, quote_id, quote_order_qty, list_id,
+		       trailing_delta, trailing_bps, activation_price, trailing_extreme_price`
+
 // OrderRepository 订单仓储
 type OrderRepository struct {
 	db *sql.DB
@@ -106,21 +167,53 @@ func NewOrderRepository(db *sql.DB) *OrderRepository {
 	return &OrderRepository{db: db}
 }
 
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
 // CreateOrder 创建订单
 func (r *OrderRepository) CreateOrder(ctx context.Context, order *Order) error {
+	return insertOrder(ctx, r.db, order)
+}
+
+// CreateOrders 在同一事务中创建一组订单（做市报价），全部成功或全部失败
+func (r *OrderRepository) CreateOrders(ctx context.Context, orders []*Order) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, order := range orders {
+		if err := insertOrder(ctx, tx, order); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func insertOrder(ctx context.Context, db execer, order *Order) error {
 	query := `
 		INSERT INTO exchange_order.orders
 		(order_id, client_order_id, user_id, symbol, side, type, time_in_force,
 		 price, stop_price, orig_qty, executed_qty, cumulative_quote_qty, status,
-		 reject_reason, cancel_reason, create_time_ms, update_time_ms, transact_time_ms)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		 reject_reason, cancel_reason, create_time_ms, update_time_ms, transact_time_ms, stp_mode,
+		 display_qty, expire_time_ms, quote_id, quote_order_qty, list_id,
+		 trailing_delta, trailing_bps, activation_price)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24,
+		        $25, $26, $27)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := db.ExecContext(ctx, query,
 		order.OrderID, nullString(order.ClientOrderID), order.UserID, order.Symbol,
 		order.Side, order.Type, order.TimeInForce, order.Price, order.StopPrice,
 		order.OrigQty, order.ExecutedQty, order.CumulativeQuoteQty, order.Status,
 		order.RejectReason, order.CancelReason, order.CreateTimeMs, order.UpdateTimeMs,
-		nullInt64(order.TransactTimeMs),
+		nullInt64(order.TransactTimeMs), stpModeOrDefault(order.STPMode), order.DisplayQty,
+		order.ExpireTimeMs, nullInt64(order.QuoteID), order.QuoteOrderQty, nullInt64(order.ListID),
+		order.TrailingDelta, order.TrailingBps, order.ActivationPrice,
 	)
 	if err != nil {
 		// 检查唯一约束冲突
@@ -135,9 +228,7 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *Order) error {
 // GetOrder 获取订单
 func (r *OrderRepository) GetOrder(ctx context.Context, orderID int64) (*Order, error) {
 	query := `
-		SELECT order_id, client_order_id, user_id, symbol, side, type, time_in_force,
-		       price, stop_price, orig_qty, executed_qty, cumulative_quote_qty, status,
-		       reject_reason, cancel_reason, create_time_ms, update_time_ms, transact_time_ms
+		SELECT ` + orderColumns + `
 		FROM exchange_order.orders
 		WHERE order_id = $1
 	`
@@ -147,9 +238,7 @@ func (r *OrderRepository) GetOrder(ctx context.Context, orderID int64) (*Order,
 // GetOrderByClientID 通过 clientOrderId 获取订单
 func (r *OrderRepository) GetOrderByClientID(ctx context.Context, userID int64, clientOrderID string) (*Order, error) {
 	query := `
-		SELECT order_id, client_order_id, user_id, symbol, side, type, time_in_force,
-		       price, stop_price, orig_qty, executed_qty, cumulative_quote_qty, status,
-		       reject_reason, cancel_reason, create_time_ms, update_time_ms, transact_time_ms
+		SELECT ` + orderColumns + `
 		FROM exchange_order.orders
 		WHERE user_id = $1 AND client_order_id = $2
 	`
@@ -192,14 +281,136 @@ func (r *OrderRepository) AddOrderCumulativeQuoteQty(ctx context.Context, orderI
 	return nil
 }
 
-// CancelOrder 取消订单
+// MarkOrderTriggered 记录条件单触发时间（重复触发事件幂等）
+func (r *OrderRepository) MarkOrderTriggered(ctx context.Context, orderID int64, triggerTimeMs int64) error {
+	query := `
+		UPDATE exchange_order.orders
+		SET trigger_time_ms = COALESCE(trigger_time_ms, $1), update_time_ms = $1
+		WHERE order_id = $2
+	`
+	result, err := r.db.ExecContext(ctx, query, triggerTimeMs, orderID)
+	if err != nil {
+		return fmt.Errorf("mark order triggered: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// UpdateTrailingStop 更新跟踪止损的触发价与最优价（仅未触发的订单）
+//
+// 最优价只朝有利方向移动（卖出取最高价，买入取最低价）：乱序或重复投递的旧事件不会回退触发价。
+func (r *OrderRepository) UpdateTrailingStop(ctx context.Context, orderID, stopPrice, extremePrice, updateTimeMs int64) error {
+	query := `
+		UPDATE exchange_order.orders
+		SET stop_price = $1, trailing_extreme_price = $2, update_time_ms = $3
+		WHERE order_id = $4 AND type = $5 AND trigger_time_ms IS NULL
+		  AND (trailing_extreme_price = 0
+		       OR (side = $6 AND trailing_extreme_price <= $2)
+		       OR (side = $7 AND trailing_extreme_price >= $2))
+	`
+	result, err := r.db.ExecContext(ctx, query, stopPrice, extremePrice, updateTimeMs, orderID, TypeTrailingStop, SideSell, SideBuy)
+	if err != nil {
+		return fmt.Errorf("update trailing stop: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// ReduceOrderQty 扣减订单数量至 origQty（STP DECREMENT），重复事件幂等
+//
+// releasedQuote 为买单本次解冻的 quote 金额，同步扣减 frozen_quote_qty（未设置时保持 NULL）。
+func (r *OrderRepository) ReduceOrderQty(ctx context.Context, orderID int64, origQty int64, releasedQuote int64, updateTimeMs int64) error {
+	query := `
+		UPDATE exchange_order.orders
+		SET orig_qty = $1, frozen_quote_qty = frozen_quote_qty - $2, update_time_ms = $3
+		WHERE order_id = $4 AND orig_qty > $1
+	`
+	result, err := r.db.ExecContext(ctx, query, origQty, releasedQuote, updateTimeMs, orderID)
+	if err != nil {
+		return fmt.Errorf("reduce order qty: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// BeginAmend 登记进行中的改单（同一订单同时只允许一个改单）
+func (r *OrderRepository) BeginAmend(ctx context.Context, orderID, amendID, freezeAmount, updateTimeMs int64) error {
+	query := `
+		UPDATE exchange_order.orders
+		SET pending_amend_id = $1, pending_amend_freeze = $2, update_time_ms = $3
+		WHERE order_id = $4 AND status IN (1, 2) AND pending_amend_id IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, amendID, freezeAmount, updateTimeMs, orderID)
+	if err != nil {
+		return fmt.Errorf("begin amend: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrAmendInProgress
+	}
+	return nil
+}
+
+// ClearPendingAmend 清除进行中的改单（改单被拒绝或发送失败）
+func (r *OrderRepository) ClearPendingAmend(ctx context.Context, orderID, amendID, updateTimeMs int64) error {
+	query := `
+		UPDATE exchange_order.orders
+		SET pending_amend_id = NULL, pending_amend_freeze = 0, update_time_ms = $1
+		WHERE order_id = $2 AND pending_amend_id = $3
+	`
+	result, err := r.db.ExecContext(ctx, query, updateTimeMs, orderID, amendID)
+	if err != nil {
+		return fmt.Errorf("clear pending amend: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// ApplyAmend 落库撮合确认的改单结果并清除进行中的改单
+//
+// frozenQuoteQty 为买单改单后的累计冻结额，卖单传 0（保持 NULL）。
+func (r *OrderRepository) ApplyAmend(ctx context.Context, orderID, price, origQty, frozenQuoteQty, updateTimeMs int64) error {
+	query := `
+		UPDATE exchange_order.orders
+		SET price = $1, orig_qty = $2, frozen_quote_qty = $3,
+		    pending_amend_id = NULL, pending_amend_freeze = 0, update_time_ms = $4
+		WHERE order_id = $5
+	`
+	result, err := r.db.ExecContext(ctx, query, price, origQty, nullInt64(frozenQuoteQty), updateTimeMs, orderID)
+	if err != nil {
+		return fmt.Errorf("apply amend: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// CancelOrder 取消订单（到期撤单记为 EXPIRED 状态）
 func (r *OrderRepository) CancelOrder(ctx context.Context, orderID int64, reason string, updateTimeMs int64) error {
 	query := `
 		UPDATE exchange_order.orders
 		SET status = $1, cancel_reason = $2, update_time_ms = $3
 		WHERE order_id = $4 AND status IN (1, 2)
 	`
-	result, err := r.db.ExecContext(ctx, query, StatusCanceled, reason, updateTimeMs, orderID)
+	status := StatusCanceled
+	if reason == CancelReasonExpired {
+		status = StatusExpired
+	}
+	result, err := r.db.ExecContext(ctx, query, status, reason, updateTimeMs, orderID)
 	if err != nil {
 		return fmt.Errorf("cancel order: %w", err)
 	}
@@ -228,12 +439,40 @@ func (r *OrderRepository) RejectOrder(ctx context.Context, orderID int64, reason
 	return nil
 }
 
+// UpdateOrdersStatus 批量更新一组订单状态（仅更新处于 fromStatus 的订单），返回更新条数
+func (r *OrderRepository) UpdateOrdersStatus(ctx context.Context, orderIDs []int64, fromStatus, toStatus int, updateTimeMs int64) (int64, error) {
+	query := `
+		UPDATE exchange_order.orders
+		SET status = $1, update_time_ms = $2
+		WHERE order_id = ANY($3) AND status = $4
+	`
+	result, err := r.db.ExecContext(ctx, query, toStatus, updateTimeMs, pq.Array(orderIDs), fromStatus)
+	if err != nil {
+		return 0, fmt.Errorf("update orders status: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// RejectOrders 批量拒绝一组订单（冻结失败或未送达撮合），重复调用幂等
+func (r *OrderRepository) RejectOrders(ctx context.Context, orderIDs []int64, reason string, updateTimeMs int64) (int64, error) {
+	query := `
+		UPDATE exchange_order.orders
+		SET status = $1, reject_reason = $2, update_time_ms = $3
+		WHERE order_id = ANY($4) AND status IN (0, 1)
+	`
+	result, err := r.db.ExecContext(ctx, query, StatusRejected, reason, updateTimeMs, pq.Array(orderIDs))
+	if err != nil {
+		return 0, fmt.Errorf("reject orders: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
 // ListOpenOrders 查询当前委托
 func (r *OrderRepository) ListOpenOrders(ctx context.Context, userID int64, symbol string, limit int) ([]*Order, error) {
 	query := `
-		SELECT order_id, client_order_id, user_id, symbol, side, type, time_in_force,
-		       price, stop_price, orig_qty, executed_qty, cumulative_quote_qty, status,
-		       reject_reason, cancel_reason, create_time_ms, update_time_ms, transact_time_ms
+		SELECT ` + orderColumns + `
 		FROM exchange_order.orders
 		WHERE user_id = $1 AND status IN (1, 2)
 		  AND ($2 = '' OR symbol = $2)
@@ -243,12 +482,77 @@ func (r *OrderRepository) ListOpenOrders(ctx context.Context, userID int64, symb
 	return r.queryOrders(ctx, query, userID, symbol, limit)
 }
 
+// ListOpenQuotes 查询用户在交易对上的当前报价单（按订单 ID 排序）
+func (r *OrderRepository) ListOpenQuotes(ctx context.Context, userID int64, symbol string) ([]*Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM exchange_order.orders
+		WHERE user_id = $1 AND symbol = $2 AND status IN (1, 2)
+		  AND quote_id IS NOT NULL
+		ORDER BY order_id
+	`
+	return r.queryOrders(ctx, query, userID, symbol)
+}
+
+// UpdateQuoteStatus 更新一组报价单中仍处于 fromStatus 的订单状态，返回更新条数
+func (r *OrderRepository) UpdateQuoteStatus(ctx context.Context, quoteID int64, fromStatus, toStatus int, updateTimeMs int64) (int64, error) {
+	query := `
+		UPDATE exchange_order.orders
+		SET status = $1, update_time_ms = $2
+		WHERE quote_id = $3 AND status = $4
+	`
+	result, err := r.db.ExecContext(ctx, query, toStatus, updateTimeMs, quoteID, fromStatus)
+	if err != nil {
+		return 0, fmt.Errorf("update quote status: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// RejectQuoteOrders 拒绝一组报价单（撮合拒绝或发送失败），重复调用幂等
+func (r *OrderRepository) RejectQuoteOrders(ctx context.Context, quoteID int64, reason string, updateTimeMs int64) (int64, error) {
+	query := `
+		UPDATE exchange_order.orders
+		SET status = $1, reject_reason = $2, update_time_ms = $3
+		WHERE quote_id = $4 AND status IN (0, 1)
+	`
+	result, err := r.db.ExecContext(ctx, query, StatusRejected, reason, updateTimeMs, quoteID)
+	if err != nil {
+		return 0, fmt.Errorf("reject quote orders: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+// ListOpenSymbols 查询用户有当前委托的交易对
+func (r *OrderRepository) ListOpenSymbols(ctx context.Context, userID int64) ([]string, error) {
+	query := `
+		SELECT DISTINCT symbol
+		FROM exchange_order.orders
+		WHERE user_id = $1 AND status IN (1, 2)
+		ORDER BY symbol
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list open symbols: %w", err)
+	}
+	defer rows.Close()
+
+	var symbols []string
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, fmt.Errorf("scan symbol: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, rows.Err()
+}
+
 // ListOrders 查询历史订单
 func (r *OrderRepository) ListOrders(ctx context.Context, userID int64, symbol string, startTime, endTime int64, limit int) ([]*Order, error) {
 	query := `
-		SELECT order_id, client_order_id, user_id, symbol, side, type, time_in_force,
-		       price, stop_price, orig_qty, executed_qty, cumulative_quote_qty, status,
-		       reject_reason, cancel_reason, create_time_ms, update_time_ms, transact_time_ms
+		SELECT ` + orderColumns + `
 		FROM exchange_order.orders
 		WHERE user_id = $1
 		  AND ($2 = '' OR symbol = $2)
@@ -264,7 +568,7 @@ func (r *OrderRepository) GetSymbolConfig(ctx context.Context, symbol string) (*
 	query := `
 		SELECT sc.symbol, sc.base_asset, sc.quote_asset, sc.price_tick, sc.qty_step,
 		       sc.price_precision, sc.qty_precision, sc.min_qty, sc.max_qty, sc.min_notional,
-		       sc.price_limit_rate, sc.maker_fee_rate, sc.taker_fee_rate, sc.status,
+		       sc.price_limit_rate, sc.maker_fee_rate, sc.taker_fee_rate, sc.status, sc.tick_bands,
 		       COALESCE(base.precision, 0) AS base_precision,
 		       COALESCE(quote.precision, 0) AS quote_precision
 		FROM exchange_order.symbol_configs sc
@@ -273,10 +577,11 @@ func (r *OrderRepository) GetSymbolConfig(ctx context.Context, symbol string) (*
 		WHERE sc.symbol = $1
 	`
 	var cfg SymbolConfig
+	var tickBands []byte
 	err := r.db.QueryRowContext(ctx, query, symbol).Scan(
 		&cfg.Symbol, &cfg.BaseAsset, &cfg.QuoteAsset, &cfg.PriceTick, &cfg.QtyStep,
 		&cfg.PricePrecision, &cfg.QtyPrecision, &cfg.MinQty, &cfg.MaxQty, &cfg.MinNotional,
-		&cfg.PriceLimitRate, &cfg.MakerFeeRate, &cfg.TakerFeeRate, &cfg.Status,
+		&cfg.PriceLimitRate, &cfg.MakerFeeRate, &cfg.TakerFeeRate, &cfg.Status, &tickBands,
 		&cfg.BasePrecision, &cfg.QuotePrecision,
 	)
 	if err == sql.ErrNoRows {
@@ -285,6 +590,9 @@ func (r *OrderRepository) GetSymbolConfig(ctx context.Context, symbol string) (*
 	if err != nil {
 		return nil, fmt.Errorf("get symbol config: %w", err)
 	}
+	if cfg.TickBands, err = parseTickBands(tickBands); err != nil {
+		return nil, fmt.Errorf("symbol %s: %w", cfg.Symbol, err)
+	}
 	return &cfg, nil
 }
 
@@ -293,7 +601,7 @@ func (r *OrderRepository) ListSymbolConfigs(ctx context.Context) ([]*SymbolConfi
 	query := `
 		SELECT sc.symbol, sc.base_asset, sc.quote_asset, sc.price_tick, sc.qty_step,
 		       sc.price_precision, sc.qty_precision, sc.min_qty, sc.max_qty, sc.min_notional,
-		       sc.price_limit_rate, sc.maker_fee_rate, sc.taker_fee_rate, sc.status,
+		       sc.price_limit_rate, sc.maker_fee_rate, sc.taker_fee_rate, sc.status, sc.tick_bands,
 		       COALESCE(base.precision, 0) AS base_precision,
 		       COALESCE(quote.precision, 0) AS quote_precision
 		FROM exchange_order.symbol_configs sc
@@ -310,44 +618,79 @@ func (r *OrderRepository) ListSymbolConfigs(ctx context.Context) ([]*SymbolConfi
 	var configs []*SymbolConfig
 	for rows.Next() {
 		var cfg SymbolConfig
+		var tickBands []byte
 		if err := rows.Scan(
 			&cfg.Symbol, &cfg.BaseAsset, &cfg.QuoteAsset, &cfg.PriceTick, &cfg.QtyStep,
 			&cfg.PricePrecision, &cfg.QtyPrecision, &cfg.MinQty, &cfg.MaxQty, &cfg.MinNotional,
-			&cfg.PriceLimitRate, &cfg.MakerFeeRate, &cfg.TakerFeeRate, &cfg.Status,
+			&cfg.PriceLimitRate, &cfg.MakerFeeRate, &cfg.TakerFeeRate, &cfg.Status, &tickBands,
 			&cfg.BasePrecision, &cfg.QuotePrecision,
 		); err != nil {
 			return nil, fmt.Errorf("scan symbol config: %w", err)
 		}
+		if cfg.TickBands, err = parseTickBands(tickBands); err != nil {
+			return nil, fmt.Errorf("symbol %s: %w", cfg.Symbol, err)
+		}
 		configs = append(configs, &cfg)
 	}
 	return configs, nil
 }
 
-func (r *OrderRepository) scanOrder(row *sql.Row) (*Order, error) {
+// parseTickBands 解析价格分档（JSONB，空值表示不分档）
+func parseTickBands(raw []byte) ([]validate.TickBand, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var bands []validate.TickBand
+	if err := json.Unmarshal(raw, &bands); err != nil {
+		return nil, fmt.Errorf("parse tick bands: %w", err)
+	}
+	return bands, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrderRow(row rowScanner) (*Order, error) {
 	var o Order
 	var clientOrderID, rejectReason, cancelReason sql.NullString
-	var transactTimeMs sql.NullInt64
+	var transactTimeMs, triggerTimeMs, frozenQuoteQty, pendingAmendID, quoteID, listID sql.NullInt64
 
-	err := row.Scan(
+	if err := row.Scan(
 		&o.OrderID, &clientOrderID, &o.UserID, &o.Symbol, &o.Side, &o.Type, &o.TimeInForce,
 		&o.Price, &o.StopPrice, &o.OrigQty, &o.ExecutedQty, &o.CumulativeQuoteQty, &o.Status,
 		&rejectReason, &cancelReason, &o.CreateTimeMs, &o.UpdateTimeMs, &transactTimeMs,
-	)
-	if err == sql.ErrNoRows {
-		return nil, ErrOrderNotFound
-	}
-	if err != nil {
-		return nil, fmt.Errorf("scan order: %w", err)
+		&triggerTimeMs, &o.STPMode, &frozenQuoteQty, &pendingAmendID, &o.PendingAmendFreeze,
+		&o.DisplayQty, &o.ExpireTimeMs, &quoteID, &o.QuoteOrderQty, &listID,
+		&o.TrailingDelta, &o.TrailingBps, &o.ActivationPrice, &o.TrailingExtreme,
+	); err != nil {
+		return nil, err
 	}
 
 	o.ClientOrderID = clientOrderID.String
 	o.RejectReason = rejectReason.String
 	o.CancelReason = cancelReason.String
 	o.TransactTimeMs = transactTimeMs.Int64
+	o.TriggerTimeMs = triggerTimeMs.Int64
+	o.FrozenQuoteQty = frozenQuoteQty.Int64
+	o.PendingAmendID = pendingAmendID.Int64
+	o.QuoteID = quoteID.Int64
+	o.ListID = listID.Int64
 
 	return &o, nil
 }
 
+func (r *OrderRepository) scanOrder(row *sql.Row) (*Order, error) {
+	o, err := scanOrderRow(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan order: %w", err)
+	}
+	return o, nil
+}
+
 func (r *OrderRepository) queryOrders(ctx context.Context, query string, args ...interface{}) ([]*Order, error) {
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -357,28 +700,22 @@ func (r *OrderRepository) queryOrders(ctx context.Context, query string, args ..
 
 	var orders []*Order
 	for rows.Next() {
-		var o Order
-		var clientOrderID, rejectReason, cancelReason sql.NullString
-		var transactTimeMs sql.NullInt64
-
-		if err := rows.Scan(
-			&o.OrderID, &clientOrderID, &o.UserID, &o.Symbol, &o.Side, &o.Type, &o.TimeInForce,
-			&o.Price, &o.StopPrice, &o.OrigQty, &o.ExecutedQty, &o.CumulativeQuoteQty, &o.Status,
-			&rejectReason, &cancelReason, &o.CreateTimeMs, &o.UpdateTimeMs, &transactTimeMs,
-		); err != nil {
+		o, err := scanOrderRow(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan order: %w", err)
 		}
-
-		o.ClientOrderID = clientOrderID.String
-		o.RejectReason = rejectReason.String
-		o.CancelReason = cancelReason.String
-		o.TransactTimeMs = transactTimeMs.Int64
-
-		orders = append(orders, &o)
+		orders = append(orders, o)
 	}
 	return orders, nil
 }
 
+func stpModeOrDefault(mode int) int {
+	if mode == 0 {
+		return STPExpireTaker
+	}
+	return mode
+}
+
 func nullString(s string) sql.NullString {
 	if s == "" {
 		return sql.NullString{}
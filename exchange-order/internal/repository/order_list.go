@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrOrderListNotFound = errors.New("order list not found")
+
+// ContingencyTypeOCO 二选一订单列表：任一订单成交或触发后撤销其余订单
+const ContingencyTypeOCO = "OCO"
+
+// OrderListStatus 订单列表状态
+const (
+	OrderListStatusExecuting = 1 // 订单执行中（列表冻结未释放）
+	OrderListStatusAllDone   = 2 // 全部订单终态，剩余冻结已释放
+	OrderListStatusRejected  = 3 // 冻结失败或未送达撮合
+)
+
+// OrderList 订单列表
+//
+// 列表内订单共用一次冻结（FrozenAmount），单笔订单终态时不单独解冻，
+// 全部订单终态后按冻结额扣除已成交部分一次解冻。
+type OrderList struct {
+	ListID           int64
+	ClientListID     string
+	UserID           int64
+	Symbol           string
+	ContingencyType  string
+	Side             int
+	FreezeAsset      string
+	FrozenAmount     int64
+	Status           int
+	TriggeredOrderID int64 // 第一条成交、触发或终止的订单，0 表示均未推进
+	CreateTimeMs     int64
+	UpdateTimeMs     int64
+}
+
+// orderListColumns 订单列表查询列（与 scanOrderList 顺序一致）
+const orderListColumns = `list_id, client_list_id, user_id, symbol, contingency_type, side,
+		       freeze_asset, frozen_amount, status, triggered_order_id, create_time_ms, update_time_ms`
+
+// CreateOrderList 在同一事务中创建订单列表及其订单，全部成功或全部失败
+func (r *OrderRepository) CreateOrderList(ctx context.Context, list *OrderList, orders []*Order) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO exchange_order.order_lists
+		(list_id, client_list_id, user_id, symbol, contingency_type, side,
+		 freeze_asset, frozen_amount, status, create_time_ms, update_time_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		list.ListID, nullString(list.ClientListID), list.UserID, list.Symbol, list.ContingencyType, list.Side,
+		list.FreezeAsset, list.FrozenAmount, list.Status, list.CreateTimeMs, list.UpdateTimeMs,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateClientOrderID
+		}
+		return fmt.Errorf("insert order list: %w", err)
+	}
+	for _, order := range orders {
+		order.ListID = list.ListID
+		if err := insertOrder(ctx, tx, order); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// GetOrderList 获取订单列表
+func (r *OrderRepository) GetOrderList(ctx context.Context, listID int64) (*OrderList, error) {
+	query := `
+		SELECT ` + orderListColumns + `
+		FROM exchange_order.order_lists
+		WHERE list_id = $1
+	`
+	return scanOrderList(r.db.QueryRowContext(ctx, query, listID))
+}
+
+// GetOrderListByClientID 通过 clientListId 获取订单列表
+func (r *OrderRepository) GetOrderListByClientID(ctx context.Context, userID int64, clientListID string) (*OrderList, error) {
+	query := `
+		SELECT ` + orderListColumns + `
+		FROM exchange_order.order_lists
+		WHERE user_id = $1 AND client_list_id = $2
+	`
+	return scanOrderList(r.db.QueryRowContext(ctx, query, userID, clientListID))
+}
+
+// ListOrderListOrders 查询订单列表内的订单（按订单 ID 排序）
+func (r *OrderRepository) ListOrderListOrders(ctx context.Context, listID int64) ([]*Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM exchange_order.orders
+		WHERE list_id = $1
+		ORDER BY order_id
+	`
+	return r.queryOrders(ctx, query, listID)
+}
+
+// TriggerOrderList 记录列表中第一条推进的订单，返回最终记录的订单 ID（重复调用返回首次记录）
+func (r *OrderRepository) TriggerOrderList(ctx context.Context, listID, orderID, updateTimeMs int64) (int64, error) {
+	query := `
+		UPDATE exchange_order.order_lists
+		SET triggered_order_id = COALESCE(triggered_order_id, $1), update_time_ms = $2
+		WHERE list_id = $3
+		RETURNING triggered_order_id
+	`
+	var triggered int64
+	err := r.db.QueryRowContext(ctx, query, orderID, updateTimeMs, listID).Scan(&triggered)
+	if err == sql.ErrNoRows {
+		return 0, ErrOrderListNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("trigger order list: %w", err)
+	}
+	return triggered, nil
+}
+
+// FinishOrderList 将执行中的订单列表置为终态，列表已终态时返回 ErrOrderListNotFound
+func (r *OrderRepository) FinishOrderList(ctx context.Context, listID int64, status int, updateTimeMs int64) error {
+	query := `
+		UPDATE exchange_order.order_lists
+		SET status = $1, update_time_ms = $2
+		WHERE list_id = $3 AND status = $4
+	`
+	result, err := r.db.ExecContext(ctx, query, status, updateTimeMs, listID, OrderListStatusExecuting)
+	if err != nil {
+		return fmt.Errorf("finish order list: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return ErrOrderListNotFound
+	}
+	return nil
+}
+
+func scanOrderList(row rowScanner) (*OrderList, error) {
+	var l OrderList
+	var clientListID sql.NullString
+	var triggeredOrderID sql.NullInt64
+	err := row.Scan(
+		&l.ListID, &clientListID, &l.UserID, &l.Symbol, &l.ContingencyType, &l.Side,
+		&l.FreezeAsset, &l.FrozenAmount, &l.Status, &triggeredOrderID, &l.CreateTimeMs, &l.UpdateTimeMs,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderListNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan order list: %w", err)
+	}
+	l.ClientListID = clientListID.String
+	l.TriggeredOrderID = triggeredOrderID.Int64
+	return &l, nil
+}
@@ -27,11 +27,23 @@ type OpenOrder struct {
 	UserID        int64
 	Symbol        string
 	Side          string // BUY/SELL
-	OrderType     string // LIMIT/MARKET
-	TimeInForce   string // GTC/IOC/FOK/POST_ONLY
+	OrderType     string // LIMIT/MARKET/STOP_LOSS/STOP_LOSS_LIMIT/TAKE_PROFIT/TRAILING_STOP
+	TimeInForce   string // GTC/IOC/FOK/POST_ONLY/GTD/DAY
 	Price         int64
-	LeavesQty     int64 // 剩余数量 = orig_qty - executed_qty
-	CreatedAt     int64 // 纳秒时间戳
+	StopPrice     int64 // 条件单触发价
+	Triggered     bool  // 条件单是否已触发
+	// 跟踪止损：回调价差或比例（基点）、激活价与激活后的最优价（0 表示未激活）
+	TrailingDelta   int64
+	TrailingBps     int64
+	ActivationPrice int64
+	TrailingExtreme int64
+	STPMode         string // EXPIRE_TAKER/EXPIRE_MAKER/EXPIRE_BOTH/DECREMENT
+	OrigQty         int64  // 订单数量（含已成交）
+	LeavesQty       int64  // 剩余数量 = orig_qty - executed_qty
+	DisplayQty      int64  // 冰山单每次展示数量，0 表示非冰山单
+	ExpireTimeMs    int64  // GTD/DAY 到期时间（毫秒），0 表示不过期
+	QuoteID         int64  // 做市报价请求 ID，非 0 表示报价单
+	CreatedAt       int64  // 纳秒时间戳
 }
 
 // DBOrderLoader 使用数据库加载 OPEN 订单（用于恢复订单簿）。
@@ -47,7 +59,8 @@ func (l *DBOrderLoader) ListActiveSymbols(ctx context.Context) ([]string, error)
 	query := `
 		SELECT DISTINCT symbol
 		FROM exchange_order.orders
-		WHERE status IN (1, 2) AND type = 1
+		WHERE status IN (1, 2)
+		  AND (type IN (1, 4) OR (type IN (3, 5, 6) AND trigger_time_ms IS NULL))
 		ORDER BY symbol ASC
 	`
 	rows, err := l.db.QueryContext(ctx, query)
@@ -73,7 +86,8 @@ func (l *DBOrderLoader) ListActiveSymbols(ctx context.Context) ([]string, error)
 }
 
 func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*OpenOrder, error) {
-	// 加载 OPEN 状态（1=NEW, 2=PARTIALLY_FILLED）的 LIMIT 订单（type=1）
+	// 加载 OPEN 状态（1=NEW, 2=PARTIALLY_FILLED）的 LIMIT 订单（type=1）、止损限价单（type=4）
+	// 以及未触发的市价条件单（type=3/5/6），已触发的止损限价单按触发时间排队。
 	// 同时读取 symbol_configs 的精度用于 DECIMAL -> scaled int64。
 	query := `
 		SELECT
@@ -85,15 +99,26 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*O
 			o.type,
 			o.time_in_force,
 			o.price::text,
+			COALESCE(o.stop_price, 0)::text,
+			o.trigger_time_ms IS NOT NULL,
+			o.trailing_delta::text,
+			o.trailing_bps,
+			o.activation_price::text,
+			o.trailing_extreme_price::text,
+			o.stp_mode,
 			o.orig_qty::text,
 			o.executed_qty::text,
+			o.display_qty::text,
+			o.expire_time_ms,
+			COALESCE(o.quote_id, 0),
 			o.create_time_ms,
 			sc.price_precision,
 			sc.qty_precision
 		FROM exchange_order.orders o
 		JOIN exchange_order.symbol_configs sc ON sc.symbol = o.symbol
-		WHERE o.symbol = $1 AND o.status IN (1, 2) AND o.type = 1
-		ORDER BY o.create_time_ms ASC, o.order_id ASC
+		WHERE o.symbol = $1 AND o.status IN (1, 2)
+		  AND (o.type IN (1, 4) OR (o.type IN (3, 5, 6) AND o.trigger_time_ms IS NULL))
+		ORDER BY COALESCE(o.trigger_time_ms, o.create_time_ms) ASC, o.order_id ASC
 	`
 	rows, err := l.db.QueryContext(ctx, query, symbol)
 	if err != nil {
@@ -112,8 +137,18 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*O
 			orderType      int
 			timeInForce    int
 			priceStr       sql.NullString
+			stopPriceStr   sql.NullString
+			triggered      bool
+			trailingStr    sql.NullString
+			trailingBps    int64
+			activationStr  sql.NullString
+			extremeStr     sql.NullString
+			stpMode        int
 			origQtyStr     sql.NullString
 			executedQtyStr sql.NullString
+			displayQtyStr  sql.NullString
+			expireTimeMs   int64
+			quoteID        int64
 			createTimeMs   int64
 			pricePrecision int
 			qtyPrecision   int
@@ -127,8 +162,18 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*O
 			&orderType,
 			&timeInForce,
 			&priceStr,
+			&stopPriceStr,
+			&triggered,
+			&trailingStr,
+			&trailingBps,
+			&activationStr,
+			&extremeStr,
+			&stpMode,
 			&origQtyStr,
 			&executedQtyStr,
+			&displayQtyStr,
+			&expireTimeMs,
+			&quoteID,
 			&createTimeMs,
 			&pricePrecision,
 			&qtyPrecision,
@@ -140,6 +185,22 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*O
 		if err != nil {
 			return nil, fmt.Errorf("parse price: orderID=%d: %w", orderID, err)
 		}
+		stopPrice, err := parseDecimalToScaledInt64(nullStringToString(stopPriceStr), pricePrecision)
+		if err != nil {
+			return nil, fmt.Errorf("parse stop_price: orderID=%d: %w", orderID, err)
+		}
+		trailingDelta, err := parseDecimalToScaledInt64(nullStringToString(trailingStr), pricePrecision)
+		if err != nil {
+			return nil, fmt.Errorf("parse trailing_delta: orderID=%d: %w", orderID, err)
+		}
+		activation, err := parseDecimalToScaledInt64(nullStringToString(activationStr), pricePrecision)
+		if err != nil {
+			return nil, fmt.Errorf("parse activation_price: orderID=%d: %w", orderID, err)
+		}
+		extreme, err := parseDecimalToScaledInt64(nullStringToString(extremeStr), pricePrecision)
+		if err != nil {
+			return nil, fmt.Errorf("parse trailing_extreme_price: orderID=%d: %w", orderID, err)
+		}
 		origQty, err := parseDecimalToScaledInt64(nullStringToString(origQtyStr), qtyPrecision)
 		if err != nil {
 			return nil, fmt.Errorf("parse orig_qty: orderID=%d: %w", orderID, err)
@@ -148,6 +209,10 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*O
 		if err != nil {
 			return nil, fmt.Errorf("parse executed_qty: orderID=%d: %w", orderID, err)
 		}
+		displayQty, err := parseDecimalToScaledInt64(nullStringToString(displayQtyStr), qtyPrecision)
+		if err != nil {
+			return nil, fmt.Errorf("parse display_qty: orderID=%d: %w", orderID, err)
+		}
 
 		leavesQty := origQty - executedQty
 		if leavesQty < 0 {
@@ -155,16 +220,27 @@ func (l *DBOrderLoader) LoadOpenOrders(ctx context.Context, symbol string) ([]*O
 		}
 
 		orders = append(orders, &OpenOrder{
-			OrderID:       orderID,
-			ClientOrderID: clientOrderID.String,
-			UserID:        userID,
-			Symbol:        dbSymbol,
-			Side:          sideToString(side),
-			OrderType:     orderTypeToString(orderType),
-			TimeInForce:   timeInForceToString(timeInForce),
-			Price:         price,
-			LeavesQty:     leavesQty,
-			CreatedAt:     createTimeMs * 1_000_000, // ms -> ns
+			OrderID:         orderID,
+			ClientOrderID:   clientOrderID.String,
+			UserID:          userID,
+			Symbol:          dbSymbol,
+			Side:            sideToString(side),
+			OrderType:       orderTypeToString(orderType),
+			TimeInForce:     timeInForceToString(timeInForce),
+			Price:           price,
+			StopPrice:       stopPrice,
+			Triggered:       triggered,
+			TrailingDelta:   trailingDelta,
+			TrailingBps:     trailingBps,
+			ActivationPrice: activation,
+			TrailingExtreme: extreme,
+			STPMode:         stpModeToString(stpMode),
+			OrigQty:         origQty,
+			LeavesQty:       leavesQty,
+			DisplayQty:      displayQty,
+			ExpireTimeMs:    expireTimeMs,
+			QuoteID:         quoteID,
+			CreatedAt:       createTimeMs * 1_000_000, // ms -> ns
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -212,6 +288,29 @@ func orderTypeToString(t int) string {
 		return "LIMIT"
 	case TypeMarket:
 		return "MARKET"
+	case TypeStopLoss:
+		return "STOP_LOSS"
+	case TypeStopLossLimit:
+		return "STOP_LOSS_LIMIT"
+	case TypeTakeProfit:
+		return "TAKE_PROFIT"
+	case TypeTrailingStop:
+		return "TRAILING_STOP"
+	default:
+		return ""
+	}
+}
+
+func stpModeToString(mode int) string {
+	switch mode {
+	case 1:
+		return "EXPIRE_TAKER"
+	case 2:
+		return "EXPIRE_MAKER"
+	case 3:
+		return "EXPIRE_BOTH"
+	case 4:
+		return "DECREMENT"
 	default:
 		return ""
 	}
@@ -227,6 +326,10 @@ func timeInForceToString(tif int) string {
 		return "FOK"
 	case 4:
 		return "POST_ONLY"
+	case 5:
+		return "GTD"
+	case 6:
+		return "DAY"
 	default:
 		return ""
 	}
@@ -20,7 +20,8 @@ func TestDBOrderLoader_ListActiveSymbols(t *testing.T) {
 	query := regexp.QuoteMeta(`
 		SELECT DISTINCT symbol
 		FROM exchange_order.orders
-		WHERE status IN (1, 2) AND type = 1
+		WHERE status IN (1, 2)
+		  AND (type IN (1, 4) OR (type IN (3, 5, 6) AND trigger_time_ms IS NULL))
 		ORDER BY symbol ASC
 	`)
 
@@ -61,15 +62,26 @@ func TestDBOrderLoader_LoadOpenOrders(t *testing.T) {
 			o.type,
 			o.time_in_force,
 			o.price::text,
+			COALESCE(o.stop_price, 0)::text,
+			o.trigger_time_ms IS NOT NULL,
+			o.trailing_delta::text,
+			o.trailing_bps,
+			o.activation_price::text,
+			o.trailing_extreme_price::text,
+			o.stp_mode,
 			o.orig_qty::text,
 			o.executed_qty::text,
+			o.display_qty::text,
+			o.expire_time_ms,
+			COALESCE(o.quote_id, 0),
 			o.create_time_ms,
 			sc.price_precision,
 			sc.qty_precision
 		FROM exchange_order.orders o
 		JOIN exchange_order.symbol_configs sc ON sc.symbol = o.symbol
-		WHERE o.symbol = $1 AND o.status IN (1, 2) AND o.type = 1
-		ORDER BY o.create_time_ms ASC, o.order_id ASC
+		WHERE o.symbol = $1 AND o.status IN (1, 2)
+		  AND (o.type IN (1, 4) OR (o.type IN (3, 5, 6) AND o.trigger_time_ms IS NULL))
+		ORDER BY COALESCE(o.trigger_time_ms, o.create_time_ms) ASC, o.order_id ASC
 	`)
 
 	rows := sqlmock.NewRows([]string{
@@ -81,8 +93,18 @@ func TestDBOrderLoader_LoadOpenOrders(t *testing.T) {
 		"type",
 		"time_in_force",
 		"price",
+		"stop_price",
+		"triggered",
+		"trailing_delta",
+		"trailing_bps",
+		"activation_price",
+		"trailing_extreme_price",
+		"stp_mode",
 		"orig_qty",
 		"executed_qty",
+		"display_qty",
+		"expire_time_ms",
+		"quote_id",
 		"create_time_ms",
 		"price_precision",
 		"qty_precision",
@@ -96,11 +118,71 @@ func TestDBOrderLoader_LoadOpenOrders(t *testing.T) {
 			TypeLimit,
 			1,
 			"30000.12",
+			"0",
+			false,
+			"0",
+			int64(0),
+			"0",
+			"0",
+			1,
 			"0.5",
 			"0.1",
+			"0.05",
+			int64(0),
+			int64(0),
 			int64(1700000000123),
 			2,
 			3,
+		).
+		AddRow(
+			int64(1002),
+			"c2",
+			int64(42),
+			"BTCUSDT",
+			SideSell,
+			TypeStopLoss,
+			2,
+			"28500",
+			"29000.5",
+			false,
+			"0",
+			int64(0),
+			"0",
+			"0",
+			4,
+			"0.2",
+			"0",
+			"0",
+			int64(1700086400000),
+			int64(77),
+			int64(1700000000456),
+			2,
+			3,
+		).
+		AddRow(
+			int64(1003),
+			nil,
+			int64(43),
+			"BTCUSDT",
+			SideSell,
+			TypeTrailingStop,
+			2,
+			"0",
+			"29700.55",
+			false,
+			"300.5",
+			int64(0),
+			"30000.00",
+			"30001.05",
+			1,
+			"0.2",
+			"0",
+			"0",
+			int64(0),
+			int64(0),
+			int64(1700000000789),
+			2,
+			3,
 		)
 
 	mock.ExpectQuery(query).WithArgs("BTCUSDT").WillReturnRows(rows)
@@ -109,10 +191,10 @@ func TestDBOrderLoader_LoadOpenOrders(t *testing.T) {
 	if err != nil {
 		t.Fatalf("LoadOpenOrders: %v", err)
 	}
-	if len(got) != 1 {
-		t.Fatalf("expected 1 order, got %d", len(got))
+	if len(got) != 3 {
+		t.Fatalf("expected 3 orders, got %d", len(got))
 	}
-	if got[0].OrderID != 1001 || got[0].Side != "BUY" || got[0].OrderType != "LIMIT" || got[0].TimeInForce != "GTC" {
+	if got[0].OrderID != 1001 || got[0].Side != "BUY" || got[0].OrderType != "LIMIT" || got[0].TimeInForce != "GTC" || got[0].STPMode != "EXPIRE_TAKER" {
 		t.Fatalf("unexpected order: %#v", got[0])
 	}
 	// price_precision=2 => 30000.12 -> 3000012
@@ -123,10 +205,27 @@ func TestDBOrderLoader_LoadOpenOrders(t *testing.T) {
 	if got[0].LeavesQty != 400 {
 		t.Fatalf("expected LeavesQty=400, got %d", got[0].LeavesQty)
 	}
+	if got[0].DisplayQty != 50 || got[1].DisplayQty != 0 {
+		t.Fatalf("unexpected DisplayQty: %d, %d", got[0].DisplayQty, got[1].DisplayQty)
+	}
+	if got[0].ExpireTimeMs != 0 || got[1].ExpireTimeMs != 1700086400000 {
+		t.Fatalf("unexpected ExpireTimeMs: %d, %d", got[0].ExpireTimeMs, got[1].ExpireTimeMs)
+	}
+	if got[0].QuoteID != 0 || got[1].QuoteID != 77 {
+		t.Fatalf("unexpected QuoteID: %d, %d", got[0].QuoteID, got[1].QuoteID)
+	}
 	if got[0].CreatedAt != 1700000000123*1_000_000 {
 		t.Fatalf("expected CreatedAt=%d, got %d", 1700000000123*1_000_000, got[0].CreatedAt)
 	}
 
+	if got[1].OrderType != "STOP_LOSS" || got[1].StopPrice != 2900050 || got[1].Triggered || got[1].LeavesQty != 200 || got[1].STPMode != "DECREMENT" {
+		t.Fatalf("unexpected stop order: %#v", got[1])
+	}
+	if got[2].OrderType != "TRAILING_STOP" || got[2].StopPrice != 2970055 || got[2].TrailingDelta != 30050 ||
+		got[2].ActivationPrice != 3000000 || got[2].TrailingExtreme != 3000105 || got[2].ClientOrderID != "" {
+		t.Fatalf("unexpected trailing stop: %#v", got[2])
+	}
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("expectations: %v", err)
 	}
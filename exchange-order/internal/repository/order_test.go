@@ -1,7 +1,12 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"regexp"
 	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
 )
 
 func TestOrderConstants(t *testing.T) {
@@ -20,6 +25,14 @@ func TestOrderConstants(t *testing.T) {
 	if TypeMarket != 2 {
 		t.Fatalf("expected TypeMarket=2, got %d", TypeMarket)
 	}
+	if TypeStopLoss != 3 || TypeStopLossLimit != 4 || TypeTakeProfit != 5 {
+		t.Fatalf("unexpected stop order type constants: %d/%d/%d", TypeStopLoss, TypeStopLossLimit, TypeTakeProfit)
+	}
+
+	// STP mode constants（与撮合引擎 orderbook.STPMode 对齐）
+	if STPExpireTaker != 1 || STPExpireMaker != 2 || STPExpireBoth != 3 || STPDecrement != 4 {
+		t.Fatalf("unexpected stp mode constants: %d/%d/%d/%d", STPExpireTaker, STPExpireMaker, STPExpireBoth, STPDecrement)
+	}
 
 	// Status constants
 	if StatusNew != 1 {
@@ -185,3 +198,444 @@ func TestNewOrderRepository(t *testing.T) {
 		t.Fatal("expected non-nil repository")
 	}
 }
+
+func TestOrderIsStopOrder(t *testing.T) {
+	for _, tc := range []struct {
+		orderType int
+		want      bool
+	}{
+		{TypeLimit, false},
+		{TypeMarket, false},
+		{TypeStopLoss, true},
+		{TypeStopLossLimit, true},
+		{TypeTakeProfit, true},
+	} {
+		if got := (&Order{Type: tc.orderType}).IsStopOrder(); got != tc.want {
+			t.Fatalf("type %d: got %v, want %v", tc.orderType, got, tc.want)
+		}
+	}
+}
+
+func TestOrderRepository_GetOrderTriggerTime(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	rows := sqlmock.NewRows([]string{
+		"order_id", "client_order_id", "user_id", "symbol", "side", "type", "time_in_force",
+		"price", "stop_price", "orig_qty", "executed_qty", "cumulative_quote_qty", "status",
+		"reject_reason", "cancel_reason", "create_time_ms", "update_time_ms", "transact_time_ms",
+		"trigger_time_ms", "stp_mode", "frozen_quote_qty", "pending_amend_id", "pending_amend_freeze",
+		"display_qty", "expire_time_ms", "quote_id", "quote_order_qty", "list_id",
+		"trailing_delta", "trailing_bps", "activation_price", "trailing_extreme_price",
+	}).AddRow(1, nil, 10, "BTCUSDT", SideSell, TypeStopLossLimit, 5,
+		"9900", "10000", "5", "0", "0", StatusNew,
+		nil, nil, 1000, 2000, nil,
+		2000, STPExpireMaker, nil, 77, 2,
+		1, 86400000, nil, 0, 55,
+		0, 0, 0, 0)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM exchange_order.orders")).
+		WithArgs(int64(1)).
+		WillReturnRows(rows)
+
+	order, err := repo.GetOrder(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("get order: %v", err)
+	}
+	if order.StopPrice != "10000" || order.TriggerTimeMs != 2000 || order.TransactTimeMs != 0 || order.STPMode != STPExpireMaker {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+	if order.FrozenQuoteQty != 0 || order.PendingAmendID != 77 || order.PendingAmendFreeze != 2 || order.DisplayQty != 1 {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+	if order.TimeInForce != 5 || order.ExpireTimeMs != 86400000 || order.QuoteID != 0 || order.ListID != 55 {
+		t.Fatalf("unexpected expiry: %+v", order)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_CancelOrderExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	query := regexp.QuoteMeta(`SET status = $1, cancel_reason = $2, update_time_ms = $3`)
+
+	mock.ExpectExec(query).WithArgs(StatusExpired, CancelReasonExpired, int64(3000), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.CancelOrder(context.Background(), 1, CancelReasonExpired, 3000); err != nil {
+		t.Fatalf("expire order: %v", err)
+	}
+
+	mock.ExpectExec(query).WithArgs(StatusCanceled, "USER_CANCELED", int64(3000), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.CancelOrder(context.Background(), 2, "USER_CANCELED", 3000); err != nil {
+		t.Fatalf("cancel order: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_UpdateTrailingStop(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	query := regexp.QuoteMeta(`WHERE order_id = $4 AND type = $5 AND trigger_time_ms IS NULL
+		  AND (trailing_extreme_price = 0
+		       OR (side = $6 AND trailing_extreme_price <= $2)
+		       OR (side = $7 AND trailing_extreme_price >= $2))`)
+
+	mock.ExpectExec(query).WithArgs(int64(105), int64(110), int64(3000), int64(1), TypeTrailingStop, SideSell, SideBuy).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.UpdateTrailingStop(context.Background(), 1, 105, 110, 3000); err != nil {
+		t.Fatalf("update trailing stop: %v", err)
+	}
+
+	// 已触发的订单或最优价回退的旧事件不再更新
+	mock.ExpectExec(query).WithArgs(int64(105), int64(110), int64(3000), int64(2), TypeTrailingStop, SideSell, SideBuy).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.UpdateTrailingStop(context.Background(), 2, 105, 110, 3000); err != ErrOrderNotFound {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_MarkOrderTriggered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	query := regexp.QuoteMeta(`SET trigger_time_ms = COALESCE(trigger_time_ms, $1), update_time_ms = $1`)
+
+	mock.ExpectExec(query).WithArgs(int64(3000), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.MarkOrderTriggered(context.Background(), 1, 3000); err != nil {
+		t.Fatalf("mark triggered: %v", err)
+	}
+
+	mock.ExpectExec(query).WithArgs(int64(3000), int64(2)).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.MarkOrderTriggered(context.Background(), 2, 3000); err != ErrOrderNotFound {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_ReduceOrderQty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	query := regexp.QuoteMeta(`SET orig_qty = $1, frozen_quote_qty = frozen_quote_qty - $2, update_time_ms = $3
+		WHERE order_id = $4 AND orig_qty > $1`)
+
+	mock.ExpectExec(query).WithArgs(int64(70), int64(300), int64(3000), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.ReduceOrderQty(context.Background(), 1, 70, 300, 3000); err != nil {
+		t.Fatalf("reduce order qty: %v", err)
+	}
+
+	// 重复事件：orig_qty 已扣减，不再更新
+	mock.ExpectExec(query).WithArgs(int64(70), int64(300), int64(3000), int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.ReduceOrderQty(context.Background(), 1, 70, 300, 3000); err != ErrOrderNotFound {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_ListOpenSymbols(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT symbol`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"symbol"}).AddRow("BTCUSDT").AddRow("ETHUSDT"))
+
+	symbols, err := repo.ListOpenSymbols(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("list open symbols: %v", err)
+	}
+	if len(symbols) != 2 || symbols[0] != "BTCUSDT" || symbols[1] != "ETHUSDT" {
+		t.Fatalf("unexpected symbols: %v", symbols)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_Amend(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	ctx := context.Background()
+
+	begin := regexp.QuoteMeta(`WHERE order_id = $4 AND status IN (1, 2) AND pending_amend_id IS NULL`)
+	mock.ExpectExec(begin).WithArgs(int64(9), int64(500), int64(3000), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.BeginAmend(ctx, 1, 9, 500, 3000); err != nil {
+		t.Fatalf("begin amend: %v", err)
+	}
+	mock.ExpectExec(begin).WithArgs(int64(10), int64(0), int64(3000), int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.BeginAmend(ctx, 1, 10, 0, 3000); err != ErrAmendInProgress {
+		t.Fatalf("expected ErrAmendInProgress, got %v", err)
+	}
+
+	clear := regexp.QuoteMeta(`SET pending_amend_id = NULL, pending_amend_freeze = 0, update_time_ms = $1
+		WHERE order_id = $2 AND pending_amend_id = $3`)
+	mock.ExpectExec(clear).WithArgs(int64(4000), int64(1), int64(9)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.ClearPendingAmend(ctx, 1, 9, 4000); err != nil {
+		t.Fatalf("clear pending amend: %v", err)
+	}
+
+	apply := regexp.QuoteMeta(`SET price = $1, orig_qty = $2, frozen_quote_qty = $3`)
+	mock.ExpectExec(apply).WithArgs(int64(101), int64(6), int64(606), int64(5000), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.ApplyAmend(ctx, 1, 101, 6, 606, 5000); err != nil {
+		t.Fatalf("apply amend: %v", err)
+	}
+	mock.ExpectExec(apply).WithArgs(int64(101), int64(6), nil, int64(5000), int64(2)).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.ApplyAmend(ctx, 2, 101, 6, 0, 5000); err != ErrOrderNotFound {
+		t.Fatalf("expected ErrOrderNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_QuoteOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	insert := regexp.QuoteMeta(`INSERT INTO exchange_order.orders`)
+
+	// 整组在同一事务中写入，任一失败则回滚
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insert).WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+	orders := []*Order{
+		{OrderID: 1, UserID: 7, Symbol: "BTCUSDT", Side: SideBuy, Type: TypeLimit, TimeInForce: 1, QuoteID: 9},
+		{OrderID: 2, UserID: 7, Symbol: "BTCUSDT", Side: SideSell, Type: TypeLimit, TimeInForce: 1, QuoteID: 9},
+	}
+	if err := repo.CreateOrders(context.Background(), orders); err == nil {
+		t.Fatal("expected create orders error")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.CreateOrders(context.Background(), orders); err != nil {
+		t.Fatalf("create orders: %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE quote_id = $3 AND status = $4`)).
+		WithArgs(StatusNew, int64(3000), int64(9), StatusInit).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	if n, err := repo.UpdateQuoteStatus(context.Background(), 9, StatusInit, StatusNew, 3000); err != nil || n != 2 {
+		t.Fatalf("update quote status: n=%d err=%v", n, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE quote_id = $4 AND status IN (0, 1)`)).
+		WithArgs(StatusRejected, "INSUFFICIENT_BALANCE", int64(4000), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if n, err := repo.RejectQuoteOrders(context.Background(), 9, "INSUFFICIENT_BALANCE", 4000); err != nil || n != 0 {
+		t.Fatalf("reject quote orders: n=%d err=%v", n, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_OrderLists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	list := &OrderList{ListID: 5, UserID: 7, Symbol: "BTCUSDT", ContingencyType: ContingencyTypeOCO, Side: SideSell,
+		FreezeAsset: "BTC", FrozenAmount: 100, Status: OrderListStatusExecuting}
+	orders := []*Order{
+		{OrderID: 1, UserID: 7, Symbol: "BTCUSDT", Side: SideSell, Type: TypeLimit, TimeInForce: 1},
+		{OrderID: 2, UserID: 7, Symbol: "BTCUSDT", Side: SideSell, Type: TypeStopLossLimit, TimeInForce: 1},
+	}
+
+	// 列表与订单同一事务写入
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO exchange_order.order_lists`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO exchange_order.orders`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO exchange_order.orders`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := repo.CreateOrderList(context.Background(), list, orders); err != nil {
+		t.Fatalf("create order list: %v", err)
+	}
+	if orders[0].ListID != 5 || orders[1].ListID != 5 {
+		t.Fatalf("expected orders linked to list, got %d/%d", orders[0].ListID, orders[1].ListID)
+	}
+
+	// 已记录触发订单时保留首次记录
+	mock.ExpectQuery(regexp.QuoteMeta(`SET triggered_order_id = COALESCE(triggered_order_id, $1)`)).
+		WithArgs(int64(2), int64(3000), int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"triggered_order_id"}).AddRow(int64(1)))
+	if triggered, err := repo.TriggerOrderList(context.Background(), 5, 2, 3000); err != nil || triggered != 1 {
+		t.Fatalf("trigger order list: triggered=%d err=%v", triggered, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE list_id = $3 AND status = $4`)).
+		WithArgs(OrderListStatusAllDone, int64(4000), int64(5), OrderListStatusExecuting).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.FinishOrderList(context.Background(), 5, OrderListStatusAllDone, 4000); err != ErrOrderListNotFound {
+		t.Fatalf("expected ErrOrderListNotFound for finished list, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM exchange_order.order_lists`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"list_id", "client_list_id", "user_id", "symbol", "contingency_type", "side",
+			"freeze_asset", "frozen_amount", "status", "triggered_order_id", "create_time_ms", "update_time_ms"}).
+			AddRow(int64(5), nil, int64(7), "BTCUSDT", "OCO", SideSell, "BTC", int64(100), OrderListStatusExecuting, int64(1), int64(1000), int64(3000)))
+	got, err := repo.GetOrderList(context.Background(), 5)
+	if err != nil || got.TriggeredOrderID != 1 || got.FrozenAmount != 100 || got.ClientListID != "" {
+		t.Fatalf("unexpected order list: %+v err=%v", got, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_BatchStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	ids := []int64{11, 12, 13}
+
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE order_id = ANY($3) AND status = $4`)).
+		WithArgs(StatusNew, int64(3000), sqlmock.AnyArg(), StatusInit).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	if n, err := repo.UpdateOrdersStatus(context.Background(), ids, StatusInit, StatusNew, 3000); err != nil || n != 3 {
+		t.Fatalf("update orders status: n=%d err=%v", n, err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE order_id = ANY($4) AND status IN (0, 1)`)).
+		WithArgs(StatusRejected, "INSUFFICIENT_BALANCE", int64(4000), sqlmock.AnyArg()).
+		WillReturnError(sql.ErrConnDone)
+	if _, err := repo.RejectOrders(context.Background(), ids, "INSUFFICIENT_BALANCE", 4000); err == nil {
+		t.Fatal("expected reject orders error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_AlgoOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	algo := &AlgoOrder{AlgoID: 7, UserID: 1, Symbol: "BTCUSDT", Side: SideBuy, AlgoType: AlgoTypeTWAP, TotalQty: 1000, ExecutedQty: 200, ChildCount: 2, ActiveChildID: 0, Status: AlgoStatusRunning, UpdateTimeMs: 2000}
+
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE algo_id = $11 AND update_time_ms = $12`)).
+		WithArgs(int64(200), int64(0), int64(0), int64(0), int64(0), 2, sql.NullInt64{}, AlgoStatusRunning, sql.NullString{}, int64(2000), int64(7), int64(1000)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.UpdateAlgoOrder(context.Background(), algo, 1000); err != ErrAlgoOrderConflict {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE algo_id = $4 AND status = $5`)).
+		WithArgs(AlgoStatusCanceled, sql.NullString{String: "USER_CANCELED", Valid: true}, int64(3000), int64(7), AlgoStatusRunning).
+		WillReturnError(sql.ErrNoRows)
+	if _, err := repo.CancelAlgoOrder(context.Background(), 7, "USER_CANCELED", 3000); err != ErrAlgoOrderNotFound {
+		t.Fatalf("expected not found for final algo, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestOrderRepository_RiskSettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewOrderRepository(db)
+	settingsQuery := regexp.QuoteMeta(`FROM exchange_order.user_risk_settings`)
+
+	mock.ExpectQuery(settingsQuery).WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "tier", "trading_disabled", "reason", "updated_by", "updated_at_ms"}).
+			AddRow(int64(1), "vip", true, "abuse", int64(9), int64(1000)))
+	settings, err := repo.GetUserRiskSettings(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("get risk settings: %v", err)
+	}
+	if settings.Tier != "vip" || !settings.TradingDisabled || settings.Reason != "abuse" || settings.UpdatedBy != 9 {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+
+	// 没有设置的用户按全局限额处理
+	mock.ExpectQuery(settingsQuery).WithArgs(int64(2)).WillReturnError(sql.ErrNoRows)
+	settings, err = repo.GetUserRiskSettings(context.Background(), 2)
+	if err != nil || settings.UserID != 2 || settings.Tier != "" || settings.TradingDisabled {
+		t.Fatalf("expected default settings, got %+v %v", settings, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND symbol = $2 AND status IN (1, 2)`)).
+		WithArgs(int64(1), "BTCUSDT").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	count, err := repo.CountOpenOrders(context.Background(), 1, "BTCUSDT")
+	if err != nil || count != 7 {
+		t.Fatalf("unexpected open order count: %d %v", count, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// UserRiskSettings 用户风控设置（后台维护，无记录时按全局限额处理）
+type UserRiskSettings struct {
+	UserID          int64
+	Tier            string // 账户等级，空表示使用全局限额
+	TradingDisabled bool   // 禁止新下单（撤单不受影响）
+	Reason          string
+	UpdatedBy       int64
+	UpdatedAtMs     int64
+}
+
+// GetUserRiskSettings 获取用户风控设置，用户没有设置时返回零值设置
+func (r *OrderRepository) GetUserRiskSettings(ctx context.Context, userID int64) (*UserRiskSettings, error) {
+	query := `
+		SELECT user_id, tier, trading_disabled, reason, updated_by, updated_at_ms
+		FROM exchange_order.user_risk_settings
+		WHERE user_id = $1
+	`
+	var s UserRiskSettings
+	var reason sql.NullString
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&s.UserID, &s.Tier, &s.TradingDisabled, &reason, &s.UpdatedBy, &s.UpdatedAtMs,
+	)
+	if err == sql.ErrNoRows {
+		return &UserRiskSettings{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user risk settings: %w", err)
+	}
+	s.Reason = reason.String
+	return &s, nil
+}
+
+// CountOpenOrders 统计用户在交易对上的未完成订单数（含未触发的条件单）
+func (r *OrderRepository) CountOpenOrders(ctx context.Context, userID int64, symbol string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM exchange_order.orders
+		WHERE user_id = $1 AND symbol = $2 AND status IN (1, 2)
+	`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID, symbol).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count open orders: %w", err)
+	}
+	return count, nil
+}
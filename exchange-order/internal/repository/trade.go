@@ -49,6 +49,20 @@ func (r *TradeRepository) SaveTrade(ctx context.Context, trade *Trade) error {
 	return nil
 }
 
+// SumTradeQty 统计交易对在 (fromMs, toMs] 内的成交总量
+func (r *TradeRepository) SumTradeQty(ctx context.Context, symbol string, fromMs, toMs int64) (int64, error) {
+	query := `
+		SELECT COALESCE(SUM(qty), 0)::bigint
+		FROM exchange_order.trades
+		WHERE symbol = $1 AND timestamp_ms > $2 AND timestamp_ms <= $3
+	`
+	var volume int64
+	if err := r.db.QueryRowContext(ctx, query, symbol, fromMs, toMs).Scan(&volume); err != nil {
+		return 0, fmt.Errorf("sum trade qty: %w", err)
+	}
+	return volume, nil
+}
+
 // ListTradesByUser returns recent trades where the given user participated as maker or taker.
 func (r *TradeRepository) ListTradesByUser(ctx context.Context, userID int64, symbol string, startTimeMs, endTimeMs int64, limit int) ([]*Trade, error) {
 	if limit <= 0 {
@@ -106,3 +106,23 @@ func TestTradeRepository_SaveTrade_Duplicate(t *testing.T) {
 		t.Fatalf("expectations: %v", err)
 	}
 }
+
+func TestTradeRepository_SumTradeQty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM(qty), 0)::bigint`)).
+		WithArgs("BTCUSDT", int64(1000), int64(2000)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(int64(4200)))
+
+	volume, err := NewTradeRepository(db).SumTradeQty(context.Background(), "BTCUSDT", 1000, 2000)
+	if err != nil || volume != 4200 {
+		t.Fatalf("expected 4200, got %d %v", volume, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
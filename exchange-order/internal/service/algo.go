@@ -0,0 +1,601 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exchange/common/pkg/validate"
+	"github.com/exchange/order/internal/client"
+	"github.com/exchange/order/internal/repository"
+)
+
+const (
+	defaultAlgoSlices    = 20 // 未指定拆单间隔时，按持续时间均分的子单数
+	algoDueBatchSize     = 100
+	maxParticipationBps  = 10000
+	algoChildOrderPrefix = "algo-"
+)
+
+// AlgoStore 算法单数据接口
+type AlgoStore interface {
+	CreateAlgoOrder(ctx context.Context, algo *repository.AlgoOrder) error
+	GetAlgoOrder(ctx context.Context, algoID int64) (*repository.AlgoOrder, error)
+	GetAlgoOrderByClientID(ctx context.Context, userID int64, clientAlgoID string) (*repository.AlgoOrder, error)
+	ListOpenAlgoOrders(ctx context.Context, userID int64, symbol string, limit int) ([]*repository.AlgoOrder, error)
+	ListDueAlgoOrders(ctx context.Context, nowMs int64, limit int) ([]*repository.AlgoOrder, error)
+	UpdateAlgoOrder(ctx context.Context, algo *repository.AlgoOrder, prevUpdateTimeMs int64) error
+	CancelAlgoOrder(ctx context.Context, algoID int64, reason string, updateTimeMs int64) (*repository.AlgoOrder, error)
+}
+
+// MarketDataSource 行情数据接口（VWAP 预计成交量）
+type MarketDataSource interface {
+	GetTicker(ctx context.Context, symbol string) (*client.MarketTicker, error)
+}
+
+// TradeVolumeSource 区间市场成交量（VWAP 节奏与参与率上限）
+//
+// 按时间区间汇总全部成交，不受行情服务只保留最近成交条数的限制。
+type TradeVolumeSource interface {
+	SumTradeQty(ctx context.Context, symbol string, fromMs, toMs int64) (int64, error)
+}
+
+// algoRetryCodes 子单被拒绝时下一次拆单重试的暂时性错误码（限频、暂停交易、竞价、无对手盘等），
+// 其余错误码使算法单失败
+var algoRetryCodes = map[string]bool{
+	"ORDER_RATE_EXCEEDED":       true,
+	"NOTIONAL_RATE_EXCEEDED":    true,
+	"SYMBOL_NOT_TRADING":        true,
+	"AUCTION_ORDER_NOT_ALLOWED": true,
+	"PRICE_BAND_BREACHED":       true,
+	"NO_LIQUIDITY":              true,
+}
+
+type algoPublisher interface {
+	PublishAlgoEvent(ctx context.Context, userID int64, event string, algo interface{}) error
+}
+
+// AlgoConfig 算法单调度配置
+type AlgoConfig struct {
+	PollInterval     time.Duration // 调度周期
+	MinSliceInterval time.Duration // 最小拆单间隔
+	MaxDuration      time.Duration // 最长持续时间
+}
+
+// AlgoService 算法单服务：母单按计划拆分为 LIMIT IOC 子单，通过 OrderService 下单
+//
+// 子单使用确定的 clientOrderId（algo-<algoId>-<序号>），调度进度未保存时重新下发会命中下单幂等，
+// 不会重复下单。多实例并发调度同一算法单时，进度按 update_time_ms 乐观锁保存。
+type AlgoService struct {
+	store     AlgoStore
+	orders    *OrderService
+	market    MarketDataSource
+	volumes   TradeVolumeSource
+	publisher algoPublisher
+	cfg       AlgoConfig
+	now       func() time.Time
+}
+
+// NewAlgoService 创建算法单服务
+func NewAlgoService(store AlgoStore, orders *OrderService, market MarketDataSource, volumes TradeVolumeSource, cfg AlgoConfig) *AlgoService {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.MinSliceInterval <= 0 {
+		cfg.MinSliceInterval = 5 * time.Second
+	}
+	if cfg.MaxDuration <= 0 {
+		cfg.MaxDuration = 24 * time.Hour
+	}
+	return &AlgoService{
+		store:   store,
+		orders:  orders,
+		market:  market,
+		volumes: volumes,
+		cfg:     cfg,
+		now:     time.Now,
+	}
+}
+
+// SetPublisher 设置私有事件推送
+func (s *AlgoService) SetPublisher(publisher algoPublisher) {
+	s.publisher = publisher
+}
+
+// CreateAlgoOrderRequest 算法单请求
+type CreateAlgoOrderRequest struct {
+	UserID           int64
+	Symbol           string
+	Side             string // BUY / SELL
+	AlgoType         string // TWAP / VWAP
+	Quantity         int64  // 母单总数量
+	Price            int64  // 子单限价
+	DurationMs       int64
+	SliceIntervalMs  int64 // 0 表示按持续时间均分为 defaultAlgoSlices 份
+	ParticipationBps int64 // 子单不超过区间市场成交量的比例（基点），0 表示不限
+	ClientAlgoID     string
+}
+
+// CreateAlgoOrderResponse 算法单响应
+type CreateAlgoOrderResponse struct {
+	Algo      *repository.AlgoOrder
+	ErrorCode string
+}
+
+// CreateAlgoOrder 创建算法单，首个子单在下一个调度周期下发
+func (s *AlgoService) CreateAlgoOrder(ctx context.Context, req *CreateAlgoOrderRequest) (*CreateAlgoOrderResponse, error) {
+	if req == nil {
+		return &CreateAlgoOrderResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	req.Side = strings.ToUpper(strings.TrimSpace(req.Side))
+	req.AlgoType = strings.ToUpper(strings.TrimSpace(req.AlgoType))
+	req.ClientAlgoID = strings.TrimSpace(req.ClientAlgoID)
+
+	cfg, err := s.orders.repo.GetSymbolConfig(ctx, req.Symbol)
+	if err != nil {
+		return &CreateAlgoOrderResponse{ErrorCode: "SYMBOL_NOT_FOUND"}, nil
+	}
+	if code := s.validateAlgoOrder(req, cfg); code != "" {
+		return &CreateAlgoOrderResponse{ErrorCode: code}, nil
+	}
+
+	if req.ClientAlgoID != "" {
+		existing, err := s.store.GetAlgoOrderByClientID(ctx, req.UserID, req.ClientAlgoID)
+		if err == nil && existing != nil {
+			return &CreateAlgoOrderResponse{Algo: existing}, nil
+		}
+	}
+
+	// VWAP 按行情统计的成交速率估算持续时间内的市场成交量，无成交历史时按 TWAP 节奏
+	var expectedVolume int64
+	if req.AlgoType == repository.AlgoTypeVWAP {
+		ticker, err := s.market.GetTicker(ctx, req.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("get ticker: %w", err)
+		}
+		if span := ticker.CloseTimeMs - ticker.OpenTimeMs; span > 0 && ticker.Volume > 0 {
+			expectedVolume = int64(float64(ticker.Volume) * float64(req.DurationMs) / float64(span))
+		}
+	}
+
+	sliceInterval := req.SliceIntervalMs
+	if sliceInterval == 0 {
+		sliceInterval = max(req.DurationMs/defaultAlgoSlices, s.cfg.MinSliceInterval.Milliseconds())
+	}
+	side := repository.SideBuy
+	if req.Side == "SELL" {
+		side = repository.SideSell
+	}
+	now := s.now().UnixMilli()
+	algo := &repository.AlgoOrder{
+		AlgoID:           s.orders.idGen.NextID(),
+		ClientAlgoID:     req.ClientAlgoID,
+		UserID:           req.UserID,
+		Symbol:           req.Symbol,
+		Side:             side,
+		AlgoType:         req.AlgoType,
+		TotalQty:         req.Quantity,
+		LimitPrice:       req.Price,
+		ParticipationBps: req.ParticipationBps,
+		SliceIntervalMs:  sliceInterval,
+		StartTimeMs:      now,
+		EndTimeMs:        now + req.DurationMs,
+		NextSliceTimeMs:  now,
+		LastSliceTimeMs:  now,
+		ExpectedVolume:   expectedVolume,
+		Status:           repository.AlgoStatusRunning,
+		CreateTimeMs:     now,
+		UpdateTimeMs:     now,
+	}
+	if err := s.store.CreateAlgoOrder(ctx, algo); err != nil {
+		if errors.Is(err, repository.ErrDuplicateClientOrderID) && req.ClientAlgoID != "" {
+			if existing, fetchErr := s.store.GetAlgoOrderByClientID(ctx, req.UserID, req.ClientAlgoID); fetchErr == nil {
+				return &CreateAlgoOrderResponse{Algo: existing}, nil
+			}
+		}
+		return nil, fmt.Errorf("create algo order: %w", err)
+	}
+	s.publish(ctx, algo, "created")
+	return &CreateAlgoOrderResponse{Algo: algo}, nil
+}
+
+// validateAlgoOrder 校验算法单参数，返回错误码
+func (s *AlgoService) validateAlgoOrder(req *CreateAlgoOrderRequest, cfg *repository.SymbolConfig) string {
+	if cfg.Status != repository.SymbolStatusTrading {
+		return "SYMBOL_NOT_TRADING"
+	}
+	if !isValidSide(req.Side) {
+		return "INVALID_SIDE"
+	}
+	if req.AlgoType != repository.AlgoTypeTWAP && req.AlgoType != repository.AlgoTypeVWAP {
+		return "INVALID_ALGO_PARAM"
+	}
+	minSlice := s.cfg.MinSliceInterval.Milliseconds()
+	if req.DurationMs < minSlice || req.DurationMs > s.cfg.MaxDuration.Milliseconds() {
+		return "INVALID_ALGO_PARAM"
+	}
+	if req.SliceIntervalMs != 0 && (req.SliceIntervalMs < minSlice || req.SliceIntervalMs > req.DurationMs) {
+		return "INVALID_ALGO_PARAM"
+	}
+	if req.ParticipationBps < 0 || req.ParticipationBps > maxParticipationBps {
+		return "INVALID_ALGO_PARAM"
+	}
+	limits, err := childLimits(cfg, req.Price)
+	if err != nil {
+		return "INVALID_SYMBOL_CONFIG"
+	}
+	if req.Price <= 0 || (limits.priceTick > 0 && req.Price%limits.priceTick != 0) {
+		return "INVALID_PRICE"
+	}
+	if req.Quantity <= 0 || (limits.qtyStep > 0 && req.Quantity%limits.qtyStep != 0) {
+		return "INVALID_QUANTITY"
+	}
+	if req.Quantity < limits.minQty {
+		return "QTY_TOO_SMALL"
+	}
+	if req.Quantity < limits.minChild {
+		return "NOTIONAL_TOO_SMALL"
+	}
+	return ""
+}
+
+// GetAlgoOrder 获取算法单
+func (s *AlgoService) GetAlgoOrder(ctx context.Context, userID, algoID int64, clientAlgoID string) (*repository.AlgoOrder, error) {
+	var algo *repository.AlgoOrder
+	var err error
+	if algoID == 0 && clientAlgoID != "" {
+		algo, err = s.store.GetAlgoOrderByClientID(ctx, userID, clientAlgoID)
+	} else {
+		algo, err = s.store.GetAlgoOrder(ctx, algoID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if algo.UserID != userID {
+		return nil, repository.ErrAlgoOrderNotFound
+	}
+	return algo, nil
+}
+
+// ListOpenAlgoOrders 查询调度中的算法单
+func (s *AlgoService) ListOpenAlgoOrders(ctx context.Context, userID int64, symbol string, limit int) ([]*repository.AlgoOrder, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.store.ListOpenAlgoOrders(ctx, userID, strings.ToUpper(symbol), limit)
+}
+
+// CancelAlgoOrderRequest 撤销算法单请求
+type CancelAlgoOrderRequest struct {
+	UserID       int64
+	AlgoID       int64
+	ClientAlgoID string
+}
+
+// CancelAlgoOrder 撤销算法单：停止拆单并撤销未结算的子单，已终态的算法单原样返回
+func (s *AlgoService) CancelAlgoOrder(ctx context.Context, req *CancelAlgoOrderRequest) (*CreateAlgoOrderResponse, error) {
+	algo, err := s.GetAlgoOrder(ctx, req.UserID, req.AlgoID, req.ClientAlgoID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlgoOrderNotFound) {
+			return &CreateAlgoOrderResponse{ErrorCode: "ALGO_ORDER_NOT_FOUND"}, nil
+		}
+		return nil, err
+	}
+	if algo.IsFinal() {
+		return &CreateAlgoOrderResponse{Algo: algo}, nil
+	}
+
+	canceled, err := s.store.CancelAlgoOrder(ctx, algo.AlgoID, "USER_CANCELED", s.now().UnixMilli())
+	if errors.Is(err, repository.ErrAlgoOrderNotFound) {
+		// 并发调度已将算法单置为终态
+		algo, err = s.store.GetAlgoOrder(ctx, algo.AlgoID)
+		if err != nil {
+			return nil, err
+		}
+		return &CreateAlgoOrderResponse{Algo: algo}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if canceled.ActiveChildID != 0 {
+		s.cancelChild(ctx, canceled)
+	}
+	s.publish(ctx, canceled, "canceled")
+	return &CreateAlgoOrderResponse{Algo: canceled}, nil
+}
+
+// Start 启动调度循环
+func (s *AlgoService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.runOnce(ctx)
+			}
+		}
+	}()
+}
+
+// runOnce 调度一轮：结算已终态的子单，并为到期的算法单下发下一个子单
+func (s *AlgoService) runOnce(ctx context.Context) {
+	algos, err := s.store.ListDueAlgoOrders(ctx, s.now().UnixMilli(), algoDueBatchSize)
+	if err != nil {
+		log.Printf("list due algo orders error: %v", err)
+		return
+	}
+	for _, algo := range algos {
+		if err := s.process(ctx, algo); err != nil && !errors.Is(err, repository.ErrAlgoOrderConflict) {
+			log.Printf("process algo order %d error: %v", algo.AlgoID, err)
+		}
+	}
+}
+
+// process 推进单个算法单，出错时不保存进度，下一轮重试
+func (s *AlgoService) process(ctx context.Context, algo *repository.AlgoOrder) error {
+	prevUpdateTime := algo.UpdateTimeMs
+	now := s.now().UnixMilli()
+
+	if algo.ActiveChildID != 0 {
+		settled, err := s.settleChild(ctx, algo)
+		if err != nil || !settled {
+			return err
+		}
+	}
+
+	var placed int64
+	if !algo.IsFinal() {
+		cfg, err := s.orders.repo.GetSymbolConfig(ctx, algo.Symbol)
+		if err != nil {
+			return fmt.Errorf("get symbol config: %w", err)
+		}
+		limits, err := childLimits(cfg, algo.LimitPrice)
+		if err != nil {
+			return err
+		}
+		remaining := algo.TotalQty - algo.ExecutedQty
+		switch {
+		case remaining <= 0:
+			algo.Status = repository.AlgoStatusFinished
+		case now >= algo.EndTimeMs:
+			algo.Status = repository.AlgoStatusExpired
+		case remaining < limits.minChild:
+			algo.Status = repository.AlgoStatusExpired
+			algo.Reason = "REMAINING_BELOW_MIN_QTY"
+		case now >= algo.NextSliceTimeMs:
+			if placed, err = s.placeChild(ctx, algo, limits, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	algo.UpdateTimeMs = max(now, prevUpdateTime+1)
+	if err := s.store.UpdateAlgoOrder(ctx, algo, prevUpdateTime); err != nil {
+		if errors.Is(err, repository.ErrAlgoOrderConflict) && placed != 0 {
+			return s.attachChild(ctx, algo.AlgoID, placed, algo.ChildCount)
+		}
+		return err
+	}
+	s.publish(ctx, algo, algoEvent(algo))
+	return nil
+}
+
+// settleChild 结算子单：终态时累加成交并清除 ActiveChildID；算法单已终态而子单仍未结束时撤销子单
+//
+// 子单因暂时性原因被拒绝（见 algoRetryCodes）时算法单继续，下一次拆单重新下发。
+func (s *AlgoService) settleChild(ctx context.Context, algo *repository.AlgoOrder) (bool, error) {
+	child, err := s.orders.repo.GetOrder(ctx, algo.ActiveChildID)
+	if err != nil {
+		return false, fmt.Errorf("get child order: %w", err)
+	}
+	switch child.Status {
+	case repository.StatusFilled, repository.StatusCanceled, repository.StatusExpired, repository.StatusRejected:
+	default:
+		if algo.IsFinal() {
+			s.cancelChild(ctx, algo)
+		}
+		return false, nil
+	}
+
+	executed, _ := strconv.ParseInt(child.ExecutedQty, 10, 64)
+	executedQuote, _ := strconv.ParseInt(child.CumulativeQuoteQty, 10, 64)
+	algo.ExecutedQty += executed
+	algo.ExecutedQuoteQty += executedQuote
+	algo.ActiveChildID = 0
+	if child.Status == repository.StatusRejected && !algo.IsFinal() && !algoRetryCodes[child.RejectReason] {
+		algo.Status = repository.AlgoStatusFailed
+		algo.Reason = child.RejectReason
+	}
+	return true, nil
+}
+
+// placeChild 计算本次子单数量并下单，返回子单 ID（未下单为 0）
+func (s *AlgoService) placeChild(ctx context.Context, algo *repository.AlgoOrder, limits algoChildLimits, now int64) (int64, error) {
+	var intervalVolume int64
+	if algo.ParticipationBps > 0 || algo.ExpectedVolume > 0 {
+		vol, err := s.marketVolume(ctx, algo.Symbol, algo.LastSliceTimeMs, now)
+		if err != nil {
+			return 0, err
+		}
+		intervalVolume = vol
+	}
+	algo.MarketVolume += intervalVolume
+	algo.LastSliceTimeMs = now
+	algo.NextSliceTimeMs = now + algo.SliceIntervalMs
+
+	qty := sliceQty(algo, intervalVolume, now)
+	qty = min(qty, limits.maxQty)
+	if limits.qtyStep > 0 {
+		qty -= qty % limits.qtyStep
+	}
+	if qty < limits.minChild {
+		return 0, nil
+	}
+
+	side := "BUY"
+	if algo.Side == repository.SideSell {
+		side = "SELL"
+	}
+	seq := algo.ChildCount + 1
+	resp, err := s.orders.CreateOrder(ctx, &CreateOrderRequest{
+		UserID:        algo.UserID,
+		Symbol:        algo.Symbol,
+		Side:          side,
+		Type:          "LIMIT",
+		TimeInForce:   "IOC",
+		Price:         algo.LimitPrice,
+		Quantity:      qty,
+		ClientOrderID: fmt.Sprintf("%s%d-%d", algoChildOrderPrefix, algo.AlgoID, seq),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create child order: %w", err)
+	}
+	if resp.ErrorCode != "" {
+		// 被拒绝的子单可能已落库：序号照常递增，重试时使用新的 clientOrderId
+		algo.ChildCount = seq
+		if !algoRetryCodes[resp.ErrorCode] {
+			algo.Status = repository.AlgoStatusFailed
+			algo.Reason = resp.ErrorCode
+		}
+		return 0, nil
+	}
+	algo.ChildCount = seq
+	algo.ActiveChildID = resp.Order.OrderID
+	return resp.Order.OrderID, nil
+}
+
+// attachChild 进度保存冲突（如并发撤销）时，把已下发的子单记录到最新的算法单上，保证子单成交被结算
+func (s *AlgoService) attachChild(ctx context.Context, algoID, childID int64, seq int) error {
+	latest, err := s.store.GetAlgoOrder(ctx, algoID)
+	if err != nil {
+		return err
+	}
+	if latest.ChildCount >= seq {
+		return nil
+	}
+	prevUpdateTime := latest.UpdateTimeMs
+	latest.ChildCount = seq
+	latest.ActiveChildID = childID
+	latest.UpdateTimeMs = max(s.now().UnixMilli(), prevUpdateTime+1)
+	return s.store.UpdateAlgoOrder(ctx, latest, prevUpdateTime)
+}
+
+func (s *AlgoService) cancelChild(ctx context.Context, algo *repository.AlgoOrder) {
+	if _, err := s.orders.CancelOrder(ctx, &CancelOrderRequest{UserID: algo.UserID, OrderID: algo.ActiveChildID}); err != nil {
+		log.Printf("cancel algo %d child %d error: %v", algo.AlgoID, algo.ActiveChildID, err)
+	}
+}
+
+// marketVolume 统计 (fromMs, toMs] 内的市场成交量
+func (s *AlgoService) marketVolume(ctx context.Context, symbol string, fromMs, toMs int64) (int64, error) {
+	volume, err := s.volumes.SumTradeQty(ctx, symbol, fromMs, toMs)
+	if err != nil {
+		return 0, fmt.Errorf("sum trade qty: %w", err)
+	}
+	return volume, nil
+}
+
+// sliceQty 本次子单目标数量（未按步长与最小数量调整）
+//
+// TWAP 将剩余数量均分到剩余的拆单次数；VWAP 按已观察市场成交量占预计成交量的比例确定累计目标。
+// 最后一次拆单发送全部剩余数量；参与率上限始终生效。
+func sliceQty(algo *repository.AlgoOrder, intervalVolume, now int64) int64 {
+	remaining := algo.TotalQty - algo.ExecutedQty
+	var qty int64
+	switch {
+	case now+algo.SliceIntervalMs >= algo.EndTimeMs:
+		qty = remaining
+	case algo.AlgoType == repository.AlgoTypeVWAP && algo.ExpectedVolume > 0:
+		progress := min(float64(algo.MarketVolume)/float64(algo.ExpectedVolume), 1)
+		qty = int64(float64(algo.TotalQty)*progress) - algo.ExecutedQty
+	default:
+		slicesLeft := (algo.EndTimeMs - now + algo.SliceIntervalMs - 1) / algo.SliceIntervalMs
+		qty = (remaining + slicesLeft - 1) / slicesLeft
+	}
+	if algo.ParticipationBps > 0 {
+		qty = min(qty, intervalVolume*algo.ParticipationBps/maxParticipationBps)
+	}
+	return max(min(qty, remaining), 0)
+}
+
+// algoChildLimits 子单数量约束（按母单限价所在的价格分档）
+type algoChildLimits struct {
+	priceTick int64
+	qtyStep   int64
+	minQty    int64
+	maxQty    int64
+	minChild  int64 // 同时满足最小数量与最小成交额的最小子单数量
+}
+
+func childLimits(cfg *repository.SymbolConfig, price int64) (algoChildLimits, error) {
+	var l algoChildLimits
+	values := []struct {
+		raw       string
+		precision int
+		dst       *int64
+	}{
+		{cfg.PriceTick, cfg.PricePrecision, &l.priceTick},
+		{cfg.QtyStep, cfg.QtyPrecision, &l.qtyStep},
+		{cfg.MinQty, cfg.QtyPrecision, &l.minQty},
+		{cfg.MaxQty, cfg.QtyPrecision, &l.maxQty},
+	}
+	for _, v := range values {
+		parsed, err := parseScaledValue(v.raw, v.precision)
+		if err != nil {
+			return l, fmt.Errorf("parse symbol config: %w", err)
+		}
+		*v.dst = parsed
+	}
+	minNotional, err := parseScaledValue(cfg.MinNotional, cfg.PricePrecision)
+	if err != nil {
+		return l, fmt.Errorf("parse symbol config: %w", err)
+	}
+	l.priceTick, l.qtyStep = validate.BandSteps(cfg.TickBands, price, l.priceTick, l.qtyStep)
+
+	l.minChild = max(l.minQty, 1)
+	if minNotional > 0 && price > 0 {
+		scale := scaleFactor(cfg.QtyPrecision)
+		l.minChild = max(l.minChild, (minNotional*scale+price-1)/price)
+	}
+	if l.qtyStep > 0 && l.minChild%l.qtyStep != 0 {
+		l.minChild += l.qtyStep - l.minChild%l.qtyStep
+	}
+	if l.maxQty <= 0 {
+		l.maxQty = math.MaxInt64
+	}
+	return l, nil
+}
+
+// algoEvent 算法单推送事件：终态为状态名，调度中为 progress
+func algoEvent(algo *repository.AlgoOrder) string {
+	switch algo.Status {
+	case repository.AlgoStatusFinished:
+		return "finished"
+	case repository.AlgoStatusCanceled:
+		return "canceled"
+	case repository.AlgoStatusExpired:
+		return "expired"
+	case repository.AlgoStatusFailed:
+		return "failed"
+	default:
+		return "progress"
+	}
+}
+
+func (s *AlgoService) publish(ctx context.Context, algo *repository.AlgoOrder, event string) {
+	if s.publisher == nil {
+		return
+	}
+	if err := s.publisher.PublishAlgoEvent(ctx, algo.UserID, event, algo); err != nil {
+		log.Printf("publish algo event error: %v", err)
+	}
+}
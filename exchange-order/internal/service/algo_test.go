@@ -0,0 +1,493 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/exchange/order/internal/client"
+	"github.com/exchange/order/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// algoTestStore 订单与算法单内存存储
+type algoTestStore struct {
+	*mockOrderStore
+	orders map[int64]*repository.Order
+	algos  map[int64]*repository.AlgoOrder
+}
+
+func newAlgoTestStore() *algoTestStore {
+	return &algoTestStore{
+		mockOrderStore: &mockOrderStore{cfg: amendSymbolConfig()},
+		orders:         make(map[int64]*repository.Order),
+		algos:          make(map[int64]*repository.AlgoOrder),
+	}
+}
+
+func (a *algoTestStore) CreateOrder(_ context.Context, order *repository.Order) error {
+	a.orders[order.OrderID] = order
+	return nil
+}
+
+func (a *algoTestStore) GetOrder(_ context.Context, orderID int64) (*repository.Order, error) {
+	order, ok := a.orders[orderID]
+	if !ok {
+		return nil, repository.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (a *algoTestStore) GetOrderByClientID(_ context.Context, userID int64, clientOrderID string) (*repository.Order, error) {
+	for _, order := range a.orders {
+		if order.UserID == userID && order.ClientOrderID == clientOrderID {
+			return order, nil
+		}
+	}
+	return nil, repository.ErrOrderNotFound
+}
+
+func (a *algoTestStore) UpdateOrderStatus(_ context.Context, orderID int64, status int, _, _ int64, _ int64) error {
+	if order, ok := a.orders[orderID]; ok {
+		order.Status = status
+	}
+	return nil
+}
+
+func (a *algoTestStore) CreateAlgoOrder(_ context.Context, algo *repository.AlgoOrder) error {
+	for _, existing := range a.algos {
+		if algo.ClientAlgoID != "" && existing.UserID == algo.UserID && existing.ClientAlgoID == algo.ClientAlgoID {
+			return repository.ErrDuplicateClientOrderID
+		}
+	}
+	stored := *algo
+	a.algos[algo.AlgoID] = &stored
+	return nil
+}
+
+func (a *algoTestStore) GetAlgoOrder(_ context.Context, algoID int64) (*repository.AlgoOrder, error) {
+	algo, ok := a.algos[algoID]
+	if !ok {
+		return nil, repository.ErrAlgoOrderNotFound
+	}
+	copied := *algo
+	return &copied, nil
+}
+
+func (a *algoTestStore) GetAlgoOrderByClientID(_ context.Context, userID int64, clientAlgoID string) (*repository.AlgoOrder, error) {
+	for _, algo := range a.algos {
+		if algo.UserID == userID && algo.ClientAlgoID == clientAlgoID {
+			copied := *algo
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrAlgoOrderNotFound
+}
+
+func (a *algoTestStore) ListOpenAlgoOrders(_ context.Context, userID int64, _ string, _ int) ([]*repository.AlgoOrder, error) {
+	var out []*repository.AlgoOrder
+	for _, algo := range a.algos {
+		if algo.UserID == userID && !algo.IsFinal() {
+			copied := *algo
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (a *algoTestStore) ListDueAlgoOrders(_ context.Context, nowMs int64, _ int) ([]*repository.AlgoOrder, error) {
+	var out []*repository.AlgoOrder
+	for _, algo := range a.algos {
+		if (!algo.IsFinal() && algo.NextSliceTimeMs <= nowMs) || algo.ActiveChildID != 0 {
+			copied := *algo
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (a *algoTestStore) UpdateAlgoOrder(_ context.Context, algo *repository.AlgoOrder, prevUpdateTimeMs int64) error {
+	stored, ok := a.algos[algo.AlgoID]
+	if !ok || stored.UpdateTimeMs != prevUpdateTimeMs {
+		return repository.ErrAlgoOrderConflict
+	}
+	copied := *algo
+	a.algos[algo.AlgoID] = &copied
+	return nil
+}
+
+func (a *algoTestStore) CancelAlgoOrder(_ context.Context, algoID int64, reason string, updateTimeMs int64) (*repository.AlgoOrder, error) {
+	stored, ok := a.algos[algoID]
+	if !ok || stored.IsFinal() {
+		return nil, repository.ErrAlgoOrderNotFound
+	}
+	stored.Status = repository.AlgoStatusCanceled
+	stored.Reason = reason
+	stored.UpdateTimeMs = max(updateTimeMs, stored.UpdateTimeMs+1)
+	copied := *stored
+	return &copied, nil
+}
+
+type fakeMarketData struct {
+	trades []client.MarketTrade
+	ticker client.MarketTicker
+}
+
+func (f *fakeMarketData) SumTradeQty(_ context.Context, _ string, fromMs, toMs int64) (int64, error) {
+	var volume int64
+	for _, trade := range f.trades {
+		if trade.TimestampMs > fromMs && trade.TimestampMs <= toMs {
+			volume += trade.Qty
+		}
+	}
+	return volume, nil
+}
+
+func (f *fakeMarketData) GetTicker(_ context.Context, _ string) (*client.MarketTicker, error) {
+	ticker := f.ticker
+	return &ticker, nil
+}
+
+type fakeAlgoPublisher struct {
+	events []string
+}
+
+func (f *fakeAlgoPublisher) PublishAlgoEvent(_ context.Context, _ int64, event string, _ interface{}) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+type algoTestEnv struct {
+	svc       *AlgoService
+	store     *algoTestStore
+	market    *fakeMarketData
+	publisher *fakeAlgoPublisher
+	redis     *redis.Client
+	now       time.Time
+}
+
+func newAlgoTestEnv(t *testing.T, freezeResp client.FreezeResponse) *algoTestEnv {
+	t.Helper()
+	store := newAlgoTestStore()
+	orders, redisClient, _ := newOrderListTestService(t, store, freezeResp)
+	env := &algoTestEnv{
+		store:     store,
+		market:    &fakeMarketData{},
+		publisher: &fakeAlgoPublisher{},
+		redis:     redisClient,
+		now:       time.UnixMilli(1_000_000),
+	}
+	env.svc = NewAlgoService(store, orders, env.market, env.market, AlgoConfig{MinSliceInterval: time.Second, MaxDuration: time.Hour})
+	env.svc.now = func() time.Time { return env.now }
+	env.svc.SetPublisher(env.publisher)
+	return env
+}
+
+func (e *algoTestEnv) advance(d time.Duration) {
+	e.now = e.now.Add(d)
+}
+
+func (e *algoTestEnv) streamMessages(t *testing.T) []OrderMessage {
+	t.Helper()
+	msgs, err := e.redis.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("xrange: %v", err)
+	}
+	out := make([]OrderMessage, 0, len(msgs))
+	for _, m := range msgs {
+		var msg OrderMessage
+		if err := json.Unmarshal([]byte(m.Values["data"].(string)), &msg); err != nil {
+			t.Fatalf("unmarshal message: %v", err)
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+// finishChild 模拟撮合回报：子单以给定成交量结束
+func (e *algoTestEnv) finishChild(t *testing.T, algoID, executed int64, status int) {
+	t.Helper()
+	child := e.store.orders[e.store.algos[algoID].ActiveChildID]
+	if child == nil {
+		t.Fatalf("algo %d has no active child", algoID)
+	}
+	child.Status = status
+	child.ExecutedQty = strconv.FormatInt(executed, 10)
+	child.CumulativeQuoteQty = strconv.FormatInt(executed*100, 10)
+}
+
+func TestCreateAlgoOrder_Validation(t *testing.T) {
+	env := newAlgoTestEnv(t, client.FreezeResponse{Success: true})
+	base := func() *CreateAlgoOrderRequest {
+		return &CreateAlgoOrderRequest{UserID: 1, Symbol: "btcusdt", Side: "buy", AlgoType: "twap", Quantity: 1000, Price: 10000, DurationMs: 60_000}
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(*CreateAlgoOrderRequest)
+		code   string
+	}{
+		{"bad type", func(r *CreateAlgoOrderRequest) { r.AlgoType = "POV" }, "INVALID_ALGO_PARAM"},
+		{"duration too short", func(r *CreateAlgoOrderRequest) { r.DurationMs = 500 }, "INVALID_ALGO_PARAM"},
+		{"duration too long", func(r *CreateAlgoOrderRequest) { r.DurationMs = 2 * 3600_000 }, "INVALID_ALGO_PARAM"},
+		{"interval beyond duration", func(r *CreateAlgoOrderRequest) { r.SliceIntervalMs = 120_000 }, "INVALID_ALGO_PARAM"},
+		{"participation above 100%", func(r *CreateAlgoOrderRequest) { r.ParticipationBps = 10001 }, "INVALID_ALGO_PARAM"},
+		{"no price", func(r *CreateAlgoOrderRequest) { r.Price = 0 }, "INVALID_PRICE"},
+		{"no quantity", func(r *CreateAlgoOrderRequest) { r.Quantity = 0 }, "INVALID_QUANTITY"},
+		{"bad side", func(r *CreateAlgoOrderRequest) { r.Side = "HOLD" }, "INVALID_SIDE"},
+	}
+	for _, tc := range cases {
+		req := base()
+		tc.mutate(req)
+		resp, err := env.svc.CreateAlgoOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if resp.ErrorCode != tc.code {
+			t.Fatalf("%s: expected %s, got %+v", tc.name, tc.code, resp)
+		}
+	}
+
+	req := base()
+	req.ClientAlgoID = "twap-1"
+	resp, err := env.svc.CreateAlgoOrder(context.Background(), req)
+	if err != nil || resp.ErrorCode != "" {
+		t.Fatalf("unexpected create result: %+v %v", resp, err)
+	}
+	algo := resp.Algo
+	if algo.Symbol != "BTCUSDT" || algo.Side != repository.SideBuy || algo.SliceIntervalMs != 3000 || algo.EndTimeMs != algo.StartTimeMs+60_000 {
+		t.Fatalf("unexpected algo: %+v", algo)
+	}
+	again, err := env.svc.CreateAlgoOrder(context.Background(), base())
+	if err != nil || again.Algo.AlgoID == algo.AlgoID {
+		t.Fatalf("expected a new algo without clientAlgoId: %+v %v", again, err)
+	}
+	req = base()
+	req.ClientAlgoID = "twap-1"
+	dup, err := env.svc.CreateAlgoOrder(context.Background(), req)
+	if err != nil || dup.Algo.AlgoID != algo.AlgoID {
+		t.Fatalf("expected idempotent create, got %+v %v", dup, err)
+	}
+	if len(env.publisher.events) != 2 || env.publisher.events[0] != "created" {
+		t.Fatalf("unexpected events: %v", env.publisher.events)
+	}
+}
+
+func TestAlgoService_TWAPSlicesAndSettlesChildren(t *testing.T) {
+	env := newAlgoTestEnv(t, client.FreezeResponse{Success: true})
+	ctx := context.Background()
+
+	resp, err := env.svc.CreateAlgoOrder(ctx, &CreateAlgoOrderRequest{
+		UserID: 1, Symbol: "BTCUSDT", Side: "SELL", AlgoType: "TWAP", Quantity: 1000, Price: 10000,
+		DurationMs: 100_000, SliceIntervalMs: 25_000,
+	})
+	if err != nil || resp.ErrorCode != "" {
+		t.Fatalf("unexpected create result: %+v %v", resp, err)
+	}
+	algoID := resp.Algo.AlgoID
+
+	env.svc.runOnce(ctx)
+	msgs := env.streamMessages(t)
+	if len(msgs) != 1 {
+		t.Fatalf("expected one child order, got %d", len(msgs))
+	}
+	first := msgs[0]
+	if first.Side != "SELL" || first.OrderType != "LIMIT" || first.TimeInForce != "IOC" || first.Price != 10000 || first.Qty != 250 {
+		t.Fatalf("unexpected first child: %+v", first)
+	}
+	child := env.store.orders[first.OrderID]
+	if child.ClientOrderID != "algo-1-1" {
+		t.Fatalf("unexpected child clientOrderId: %s", child.ClientOrderID)
+	}
+	algo := env.store.algos[algoID]
+	if algo.ActiveChildID != first.OrderID || algo.ChildCount != 1 || algo.NextSliceTimeMs != algo.StartTimeMs+25_000 {
+		t.Fatalf("unexpected algo after first slice: %+v", algo)
+	}
+
+	// 子单未结束时不下发新子单
+	env.advance(25 * time.Second)
+	env.svc.runOnce(ctx)
+	if n := len(env.streamMessages(t)); n != 1 {
+		t.Fatalf("expected no new child while previous is open, got %d messages", n)
+	}
+
+	// IOC 子单部分成交后结束：累加成交，剩余 800 均分到剩余 3 次
+	env.finishChild(t, algoID, 200, repository.StatusCanceled)
+	env.svc.runOnce(ctx)
+	msgs = env.streamMessages(t)
+	if len(msgs) != 2 || msgs[1].Qty != 267 {
+		t.Fatalf("unexpected second slice: %+v", msgs)
+	}
+	algo = env.store.algos[algoID]
+	if algo.ExecutedQty != 200 || algo.ExecutedQuoteQty != 20000 || algo.ChildCount != 2 || algo.Status != repository.AlgoStatusRunning {
+		t.Fatalf("unexpected algo after settling: %+v", algo)
+	}
+
+	// 剩余数量在最后一次拆单全部下发，成交后算法单完成
+	env.finishChild(t, algoID, 267, repository.StatusFilled)
+	env.advance(50 * time.Second)
+	env.svc.runOnce(ctx)
+	msgs = env.streamMessages(t)
+	if len(msgs) != 3 || msgs[2].Qty != 533 {
+		t.Fatalf("expected final slice with the remainder, got %+v", msgs)
+	}
+	env.finishChild(t, algoID, 533, repository.StatusFilled)
+	env.svc.runOnce(ctx)
+	algo = env.store.algos[algoID]
+	if algo.Status != repository.AlgoStatusFinished || algo.ExecutedQty != 1000 || algo.ActiveChildID != 0 {
+		t.Fatalf("expected finished algo, got %+v", algo)
+	}
+	if last := env.publisher.events[len(env.publisher.events)-1]; last != "finished" {
+		t.Fatalf("expected finished event, got %v", env.publisher.events)
+	}
+}
+
+func TestAlgoService_VWAPPacesByMarketVolumeWithParticipationCap(t *testing.T) {
+	env := newAlgoTestEnv(t, client.FreezeResponse{Success: true})
+	ctx := context.Background()
+	// 过去 100s 成交 10000，持续 10s 预计成交 1000
+	env.market.ticker = client.MarketTicker{Symbol: "BTCUSDT", Volume: 10000, OpenTimeMs: 0, CloseTimeMs: 100_000}
+
+	resp, err := env.svc.CreateAlgoOrder(ctx, &CreateAlgoOrderRequest{
+		UserID: 1, Symbol: "BTCUSDT", Side: "BUY", AlgoType: "VWAP", Quantity: 500, Price: 10000,
+		DurationMs: 10_000, SliceIntervalMs: 2_000, ParticipationBps: 2500,
+	})
+	if err != nil || resp.ErrorCode != "" {
+		t.Fatalf("unexpected create result: %+v %v", resp, err)
+	}
+	algoID := resp.Algo.AlgoID
+	if resp.Algo.ExpectedVolume != 1000 {
+		t.Fatalf("expected volume 1000, got %d", resp.Algo.ExpectedVolume)
+	}
+
+	// 开始时尚无市场成交，不下单
+	env.svc.runOnce(ctx)
+	if n := len(env.streamMessages(t)); n != 0 {
+		t.Fatalf("expected no child without market volume, got %d", n)
+	}
+
+	// 区间内成交 400（区间外的成交不计）：VWAP 目标 500*40%=200，参与率上限 400*25%=100
+	start := resp.Algo.StartTimeMs
+	env.market.trades = []client.MarketTrade{
+		{TradeID: 1, Qty: 999, TimestampMs: start - 1},
+		{TradeID: 2, Qty: 150, TimestampMs: start + 500},
+		{TradeID: 3, Qty: 250, TimestampMs: start + 1500},
+	}
+	env.advance(2 * time.Second)
+	env.svc.runOnce(ctx)
+	msgs := env.streamMessages(t)
+	if len(msgs) != 1 || msgs[0].Qty != 100 || msgs[0].Side != "BUY" {
+		t.Fatalf("expected capped child of 100, got %+v", msgs)
+	}
+	if algo := env.store.algos[algoID]; algo.MarketVolume != 400 {
+		t.Fatalf("unexpected market volume: %+v", algo)
+	}
+}
+
+func TestAlgoService_CancelCancelsActiveChildAndSettlesIt(t *testing.T) {
+	env := newAlgoTestEnv(t, client.FreezeResponse{Success: true})
+	ctx := context.Background()
+
+	resp, _ := env.svc.CreateAlgoOrder(ctx, &CreateAlgoOrderRequest{
+		UserID: 1, Symbol: "BTCUSDT", Side: "BUY", AlgoType: "TWAP", Quantity: 1000, Price: 10000, DurationMs: 60_000,
+	})
+	algoID := resp.Algo.AlgoID
+	env.svc.runOnce(ctx)
+
+	if other, _ := env.svc.CancelAlgoOrder(ctx, &CancelAlgoOrderRequest{UserID: 2, AlgoID: algoID}); other.ErrorCode != "ALGO_ORDER_NOT_FOUND" {
+		t.Fatalf("expected other user's cancel to miss, got %+v", other)
+	}
+	canceled, err := env.svc.CancelAlgoOrder(ctx, &CancelAlgoOrderRequest{UserID: 1, AlgoID: algoID})
+	if err != nil || canceled.ErrorCode != "" || canceled.Algo.Status != repository.AlgoStatusCanceled || canceled.Algo.Reason != "USER_CANCELED" {
+		t.Fatalf("unexpected cancel result: %+v %v", canceled, err)
+	}
+	msgs := env.streamMessages(t)
+	if len(msgs) != 2 || msgs[1].Type != "CANCEL" || msgs[1].OrderID != msgs[0].OrderID {
+		t.Fatalf("expected child cancel message, got %+v", msgs)
+	}
+
+	// 撤销后不再拆单，子单结束时仍结算成交
+	env.finishChild(t, algoID, 30, repository.StatusCanceled)
+	env.advance(time.Minute)
+	env.svc.runOnce(ctx)
+	algo := env.store.algos[algoID]
+	if algo.Status != repository.AlgoStatusCanceled || algo.ExecutedQty != 30 || algo.ActiveChildID != 0 {
+		t.Fatalf("unexpected algo after settle: %+v", algo)
+	}
+	if n := len(env.streamMessages(t)); n != 2 {
+		t.Fatalf("expected no child after cancel, got %d messages", n)
+	}
+
+	again, err := env.svc.CancelAlgoOrder(ctx, &CancelAlgoOrderRequest{UserID: 1, AlgoID: algoID})
+	if err != nil || again.Algo == nil || again.Algo.ExecutedQty != 30 {
+		t.Fatalf("expected idempotent cancel, got %+v %v", again, err)
+	}
+}
+
+func TestAlgoService_ExpiresAndFails(t *testing.T) {
+	env := newAlgoTestEnv(t, client.FreezeResponse{Success: true})
+	ctx := context.Background()
+
+	resp, _ := env.svc.CreateAlgoOrder(ctx, &CreateAlgoOrderRequest{
+		UserID: 1, Symbol: "BTCUSDT", Side: "BUY", AlgoType: "TWAP", Quantity: 1000, Price: 10000, DurationMs: 10_000,
+		ParticipationBps: 100,
+	})
+	// 参与率上限下无市场成交，到期仍有剩余
+	env.svc.runOnce(ctx)
+	env.advance(10 * time.Second)
+	env.svc.runOnce(ctx)
+	if algo := env.store.algos[resp.Algo.AlgoID]; algo.Status != repository.AlgoStatusExpired || algo.ChildCount != 0 {
+		t.Fatalf("expected expired algo, got %+v", algo)
+	}
+	if last := env.publisher.events[len(env.publisher.events)-1]; last != "expired" {
+		t.Fatalf("expected expired event, got %v", env.publisher.events)
+	}
+
+	failing := newAlgoTestEnv(t, client.FreezeResponse{ErrorCode: "INSUFFICIENT_BALANCE"})
+	resp, _ = failing.svc.CreateAlgoOrder(ctx, &CreateAlgoOrderRequest{
+		UserID: 1, Symbol: "BTCUSDT", Side: "BUY", AlgoType: "TWAP", Quantity: 1000, Price: 10000, DurationMs: 10_000,
+	})
+	failing.svc.runOnce(ctx)
+	if algo := failing.store.algos[resp.Algo.AlgoID]; algo.Status != repository.AlgoStatusFailed || algo.Reason != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("expected failed algo, got %+v", algo)
+	}
+}
+
+func TestAlgoService_RetriesTransientRejects(t *testing.T) {
+	env := newAlgoTestEnv(t, client.FreezeResponse{Success: true})
+	risk, _, riskNow := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{MaxOrdersPerSecond: 1}})
+	env.svc.orders.SetRiskChecker(risk)
+	ctx := context.Background()
+
+	resp, _ := env.svc.CreateAlgoOrder(ctx, &CreateAlgoOrderRequest{
+		UserID: 1, Symbol: "BTCUSDT", Side: "BUY", AlgoType: "TWAP", Quantity: 1000, Price: 10000,
+		DurationMs: 10_000, SliceIntervalMs: 1_000,
+	})
+	algoID := resp.Algo.AlgoID
+	env.svc.runOnce(ctx)
+
+	// 子单无对手盘被撮合拒绝：算法单继续
+	env.store.orders[env.store.algos[algoID].ActiveChildID].RejectReason = "NO_LIQUIDITY"
+	env.finishChild(t, algoID, 0, repository.StatusRejected)
+	env.advance(time.Second)
+	// 同一秒内第二笔子单超出下单频率：算法单继续，下一次拆单重试
+	env.svc.runOnce(ctx)
+	algo := env.store.algos[algoID]
+	if algo.Status != repository.AlgoStatusRunning || algo.ActiveChildID != 0 || algo.ChildCount != 2 {
+		t.Fatalf("expected algo running after transient rejects, got %+v", algo)
+	}
+
+	*riskNow = riskNow.Add(time.Second)
+	env.advance(time.Second)
+	env.svc.runOnce(ctx)
+	algo = env.store.algos[algoID]
+	if algo.Status != repository.AlgoStatusRunning || algo.ActiveChildID == 0 || algo.ChildCount != 3 {
+		t.Fatalf("expected retried child, got %+v", algo)
+	}
+	if child := env.store.orders[algo.ActiveChildID]; child.ClientOrderID != fmt.Sprintf("algo-%d-3", algoID) {
+		t.Fatalf("expected a fresh clientOrderId, got %q", child.ClientOrderID)
+	}
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/exchange/order/internal/repository"
+)
+
+// MaxBatchOrders 单次批量下单或撤单的最大订单数
+const MaxBatchOrders = 50
+
+// BatchOrderResult 批量请求中单笔订单的结果（ErrorCode 非空表示该笔失败）
+type BatchOrderResult struct {
+	Order     *repository.Order
+	ErrorCode string
+}
+
+// BatchCreateOrdersRequest 批量下单请求
+type BatchCreateOrdersRequest struct {
+	UserID int64
+	Orders []*CreateOrderRequest
+}
+
+// BatchCreateOrdersResponse 批量下单响应（Results 与请求顺序一致；ErrorCode 表示整批被拒绝）
+type BatchCreateOrdersResponse struct {
+	Results   []BatchOrderResult
+	ErrorCode string
+}
+
+// batchOrder 通过校验、待冻结的订单
+type batchOrder struct {
+	index        int
+	order        *repository.Order
+	freezeAsset  string
+	freezeAmount int64
+	risk         *RiskOrder // 计入风控窗口的订单，未送达撮合时退回
+}
+
+// BatchCreateOrders 批量下单（同一事务落库，每种冻结资产调用一次清算，按订单流分组发送到撮合）
+func (s *OrderService) BatchCreateOrders(ctx context.Context, req *BatchCreateOrdersRequest) (*BatchCreateOrdersResponse, error) {
+	if req == nil || req.UserID <= 0 || len(req.Orders) == 0 || len(req.Orders) > MaxBatchOrders {
+		return &BatchCreateOrdersResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	if s.clearing == nil {
+		return nil, fmt.Errorf("clearing client not configured")
+	}
+
+	results := make([]BatchOrderResult, len(req.Orders))
+	reject := func(i int, code string) {
+		results[i].ErrorCode = code
+		if s.metrics != nil {
+			s.metrics.IncOrderRejected(code)
+		}
+	}
+
+	// 1. 逐笔校验与幂等检查
+	configs := make(map[string]*repository.SymbolConfig)
+	clientIDs := make(map[string]bool)
+	accepted := make(map[string]int) // 每个交易对已通过风控的订单数
+	var pending []*batchOrder
+	// 送达撮合的订单清空 risk，其余在返回时退回风控窗口
+	defer func() {
+		for _, p := range pending {
+			s.releaseRisk(ctx, p.risk)
+		}
+	}()
+	for i, orderReq := range req.Orders {
+		if orderReq == nil {
+			reject(i, "INVALID_PARAM")
+			continue
+		}
+		orderReq.UserID = req.UserID
+		normalizeCreateOrderRequest(orderReq)
+
+		cfg, ok := configs[orderReq.Symbol]
+		if !ok {
+			var err error
+			if cfg, err = s.repo.GetSymbolConfig(ctx, orderReq.Symbol); err != nil {
+				cfg = nil
+			}
+			configs[orderReq.Symbol] = cfg
+		}
+		if cfg == nil {
+			reject(i, "SYMBOL_NOT_FOUND")
+			continue
+		}
+		expireTimeMs, code := s.checkOrderRequest(orderReq, cfg)
+		if code != "" {
+			reject(i, code)
+			continue
+		}
+		if orderReq.ClientOrderID != "" {
+			if clientIDs[orderReq.ClientOrderID] {
+				reject(i, "DUPLICATE_CLIENT_ORDER_ID")
+				continue
+			}
+			clientIDs[orderReq.ClientOrderID] = true
+			// 已存在的订单原样返回，不重复冻结
+			if existing, err := s.repo.GetOrderByClientID(ctx, req.UserID, orderReq.ClientOrderID); err == nil && existing != nil {
+				results[i].Order = existing
+				continue
+			}
+		}
+		riskOrder, code, err := s.checkRisk(ctx, orderReq, cfg, accepted[orderReq.Symbol])
+		if err != nil {
+			return nil, err
+		}
+		if code != "" {
+			reject(i, code)
+			continue
+		}
+		order, freezeAsset, freezeAmount, code := s.buildOrder(ctx, orderReq, cfg, expireTimeMs)
+		if code != "" {
+			s.releaseRisk(ctx, riskOrder)
+			reject(i, code)
+			continue
+		}
+		accepted[orderReq.Symbol]++
+		pending = append(pending, &batchOrder{index: i, order: order, freezeAsset: freezeAsset, freezeAmount: freezeAmount, risk: riskOrder})
+	}
+	if len(pending) == 0 {
+		return &BatchCreateOrdersResponse{Results: results}, nil
+	}
+
+	// 2. 同一事务落库
+	orders := make([]*repository.Order, 0, len(pending))
+	for _, p := range pending {
+		orders = append(orders, p.order)
+	}
+	if err := s.repo.CreateOrders(ctx, orders); err != nil {
+		if errors.Is(err, repository.ErrDuplicateClientOrderID) {
+			// 并发请求抢先写入了相同的 clientOrderId
+			return &BatchCreateOrdersResponse{ErrorCode: "DUPLICATE_CLIENT_ORDER_ID"}, nil
+		}
+		return nil, fmt.Errorf("create orders: %w", err)
+	}
+
+	// 3. 按资产合并冻结，每种资产一次清算调用
+	batchID := s.idGen.NextID()
+	var frozen []*batchOrder
+	for _, group := range groupBatchOrders(pending, func(p *batchOrder) string { return p.freezeAsset }) {
+		asset := group[0].freezeAsset
+		var amount int64
+		for _, p := range group {
+			amount += p.freezeAmount
+		}
+		freezeKey := fmt.Sprintf("freeze:batch:%d:%s", batchID, asset)
+		code := ""
+		freezeResp, err := s.clearing.FreezeBalance(ctx, req.UserID, asset, amount, freezeKey)
+		if err != nil {
+			// 冻结结果未知：先按幂等键确认并释放可能已生效的冻结，再拒绝订单
+			log.Printf("batch freeze error: key=%s amount=%d err=%v", freezeKey, amount, err)
+			if !s.releaseUnknownFreeze(ctx, req.UserID, asset, amount, freezeKey) {
+				// 仍无法确认：订单保持 INIT，不拒绝，按幂等键人工对账
+				for _, p := range group {
+					reject(p.index, "INTERNAL_ERROR")
+				}
+				continue
+			}
+			code = "INTERNAL_ERROR"
+		} else if freezeResp == nil || !freezeResp.Success {
+			code = "FREEZE_FAILED"
+			if freezeResp != nil && freezeResp.ErrorCode != "" {
+				code = freezeResp.ErrorCode
+			}
+		}
+		if code != "" {
+			if err := s.rejectBatchOrders(ctx, group, code); err != nil {
+				return nil, fmt.Errorf("reject orders: %w", err)
+			}
+			for _, p := range group {
+				reject(p.index, code)
+			}
+			continue
+		}
+		frozen = append(frozen, group...)
+	}
+	if len(frozen) == 0 {
+		return &BatchCreateOrdersResponse{Results: results}, nil
+	}
+
+	// 4. 更新状态为 NEW
+	updateTime := time.Now().UnixMilli()
+	ids := make([]int64, 0, len(frozen))
+	for _, p := range frozen {
+		ids = append(ids, p.order.OrderID)
+	}
+	if _, err := s.repo.UpdateOrdersStatus(ctx, ids, repository.StatusInit, repository.StatusNew, updateTime); err != nil {
+		s.compensateBatchFailure(ctx, batchID, frozen, "update_status_failed")
+		return nil, fmt.Errorf("update orders status: %w", err)
+	}
+	for _, p := range frozen {
+		p.order.Status = repository.StatusNew
+		p.order.UpdateTimeMs = updateTime
+	}
+
+	// 5. 按订单流分组发送到撮合，失败的分组单独补偿
+	for _, group := range groupBatchOrders(frozen, func(p *batchOrder) string { return p.order.Symbol }) {
+		if err := s.sendBatchToMatching(ctx, group); err != nil {
+			log.Printf("send batch orders to matching error: symbol=%s err=%v", group[0].order.Symbol, err)
+			s.compensateBatchFailure(ctx, batchID, group, "send_matching_failed")
+			for _, p := range group {
+				reject(p.index, "INTERNAL_ERROR")
+			}
+			continue
+		}
+		for _, p := range group {
+			p.risk = nil
+			results[p.index].Order = p.order
+			if s.metrics != nil {
+				s.metrics.IncOrderCreated(p.order.Symbol, sideToString(p.order.Side))
+			}
+			if s.publisher != nil {
+				if err := s.publisher.PublishOrderCreated(ctx, p.order.UserID, p.order); err != nil {
+					log.Printf("publish order created error: %v", err)
+				}
+			}
+		}
+	}
+	return &BatchCreateOrdersResponse{Results: results}, nil
+}
+
+// groupBatchOrders 按键分组，保持首次出现的顺序
+func groupBatchOrders(orders []*batchOrder, key func(*batchOrder) string) [][]*batchOrder {
+	index := make(map[string]int)
+	var groups [][]*batchOrder
+	for _, p := range orders {
+		k := key(p)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], p)
+	}
+	return groups
+}
+
+func (s *OrderService) sendBatchToMatching(ctx context.Context, group []*batchOrder) error {
+	msgs := make([]*OrderMessage, 0, len(group))
+	for _, p := range group {
+		msg, err := s.newOrderMessage(ctx, p.order)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+	return s.sendMessagesToMatching(ctx, group[0].order.Symbol, msgs)
+}
+
+func (s *OrderService) rejectBatchOrders(ctx context.Context, group []*batchOrder, reason string) error {
+	ids := make([]int64, 0, len(group))
+	for _, p := range group {
+		ids = append(ids, p.order.OrderID)
+	}
+	if _, err := s.repo.RejectOrders(ctx, ids, reason, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	for _, p := range group {
+		p.order.Status = repository.StatusRejected
+		p.order.RejectReason = reason
+	}
+	return nil
+}
+
+// releaseUnknownFreeze 释放结果未知的冻结，返回 false 表示仍无法确认冻结是否生效
+//
+// 按同一幂等键重新冻结：已生效的冻结幂等返回成功，未生效时本次冻结生效或失败，结果由此确定；
+// 已冻结则按冻结键派生的幂等键解冻。未确认前直接解冻可能释放同一用户其他订单的冻结。
+func (s *OrderService) releaseUnknownFreeze(ctx context.Context, userID int64, asset string, amount int64, freezeKey string) bool {
+	freezeResp, err := s.clearing.FreezeBalance(ctx, userID, asset, amount, freezeKey)
+	if err != nil {
+		log.Printf("resolve freeze error: key=%s amount=%d err=%v", freezeKey, amount, err)
+		return false
+	}
+	if freezeResp == nil || !freezeResp.Success {
+		// 冻结失败：第一次冻结也未生效
+		return true
+	}
+	unfreezeKey := "un" + freezeKey
+	resp, err := s.clearing.UnfreezeBalance(ctx, userID, asset, amount, unfreezeKey)
+	if err == nil && resp != nil && !resp.Success {
+		err = fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
+	}
+	if err != nil {
+		log.Printf("release freeze error: key=%s amount=%d err=%v", unfreezeKey, amount, err)
+		return false
+	}
+	return true
+}
+
+// compensateBatchFailure 已冻结的订单未送达撮合：按资产解冻并拒绝订单
+func (s *OrderService) compensateBatchFailure(ctx context.Context, batchID int64, group []*batchOrder, reason string) {
+	var errs []string
+	for _, assetGroup := range groupBatchOrders(group, func(p *batchOrder) string { return p.freezeAsset }) {
+		asset := assetGroup[0].freezeAsset
+		var amount int64
+		for _, p := range assetGroup {
+			amount += p.freezeAmount
+		}
+		key := fmt.Sprintf("unfreeze:batch:%d:%s:%s:%d", batchID, asset, reason, assetGroup[0].order.OrderID)
+		resp, err := s.clearing.UnfreezeBalance(ctx, assetGroup[0].order.UserID, asset, amount, key)
+		if err == nil && resp != nil && !resp.Success {
+			err = fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("rollback freeze %s: %v", asset, err))
+		}
+	}
+	if err := s.rejectBatchOrders(ctx, group, "INTERNAL_ERROR"); err != nil {
+		errs = append(errs, fmt.Sprintf("reject orders: %v", err))
+	}
+	if len(errs) > 0 {
+		log.Printf("compensate batch orders failure (%s) error: %s", reason, strings.Join(errs, "; "))
+	}
+}
+
+// BatchCancelOrdersRequest 批量撤单请求（按订单 ID 或 clientOrderId 指定）
+type BatchCancelOrdersRequest struct {
+	UserID         int64
+	OrderIDs       []int64
+	ClientOrderIDs []string
+}
+
+// BatchCancelOrdersResponse 批量撤单响应（Results 依次对应 OrderIDs 与 ClientOrderIDs；撤单结果由撮合异步确认）
+type BatchCancelOrdersResponse struct {
+	Results   []BatchOrderResult
+	ErrorCode string
+}
+
+// BatchCancelOrders 批量撤单
+func (s *OrderService) BatchCancelOrders(ctx context.Context, req *BatchCancelOrdersRequest) (*BatchCancelOrdersResponse, error) {
+	if req == nil || req.UserID <= 0 {
+		return &BatchCancelOrdersResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	total := len(req.OrderIDs) + len(req.ClientOrderIDs)
+	if total == 0 || total > MaxBatchOrders {
+		return &BatchCancelOrdersResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+
+	results := make([]BatchOrderResult, total)
+	var targets []*batchOrder
+	seen := make(map[int64]bool)
+	for i := 0; i < total; i++ {
+		var order *repository.Order
+		var send bool
+		var code string
+		if i < len(req.OrderIDs) {
+			order, send, code = s.cancelTarget(ctx, req.UserID, req.OrderIDs[i], "")
+		} else {
+			clientOrderID := strings.TrimSpace(req.ClientOrderIDs[i-len(req.OrderIDs)])
+			order, send, code = s.cancelTarget(ctx, req.UserID, 0, clientOrderID)
+		}
+		results[i] = BatchOrderResult{Order: order, ErrorCode: code}
+		// 同一订单重复出现时只发送一次撤单
+		if send && !seen[order.OrderID] {
+			seen[order.OrderID] = true
+			targets = append(targets, &batchOrder{index: i, order: order})
+		}
+	}
+
+	for _, group := range groupBatchOrders(targets, func(p *batchOrder) string { return p.order.Symbol }) {
+		msgs := make([]*OrderMessage, 0, len(group))
+		for _, p := range group {
+			msgs = append(msgs, newCancelMessage(p.order))
+		}
+		if err := s.sendMessagesToMatching(ctx, group[0].order.Symbol, msgs); err != nil {
+			log.Printf("send batch cancel to matching error: symbol=%s err=%v", group[0].order.Symbol, err)
+			for _, p := range group {
+				results[p.index] = BatchOrderResult{ErrorCode: "INTERNAL_ERROR"}
+			}
+		}
+	}
+	return &BatchCancelOrdersResponse{Results: results}, nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/exchange/order/internal/client"
+	"github.com/exchange/order/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+func newBatchTestService(t *testing.T, store OrderStore, failAsset string) (*OrderService, *redis.Client, *[]client.FreezeRequest) {
+	t.Helper()
+	var freezes []client.FreezeRequest
+	svc, redisClient := newClearingTestService(t, store, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/internal/freeze" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		var req client.FreezeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		freezes = append(freezes, req)
+		resp := client.FreezeResponse{Success: true}
+		if req.Asset == failAsset {
+			resp = client.FreezeResponse{ErrorCode: "INSUFFICIENT_BALANCE"}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	return svc, redisClient, &freezes
+}
+
+func TestBatchCreateOrders_FreezesPerAsset(t *testing.T) {
+	store := &cancelOrderStore{cfg: amendSymbolConfig()}
+	svc, redisClient, freezes := newBatchTestService(t, store, "BTC")
+
+	resp, err := svc.BatchCreateOrders(context.Background(), &BatchCreateOrdersRequest{
+		UserID: 1,
+		Orders: []*CreateOrderRequest{
+			{Symbol: "btcusdt", Side: "BUY", Type: "LIMIT", Price: 10000, Quantity: 100, ClientOrderID: "grid-1"},
+			{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 9900, Quantity: 200},
+			{Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Price: 10100, Quantity: 100},
+			{Symbol: "BTCUSDT", Side: "HOLD", Type: "LIMIT", Price: 10100, Quantity: 100},
+			{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 9800, Quantity: 100, ClientOrderID: "grid-1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" || len(resp.Results) != 5 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	for i, code := range []string{"", "", "INSUFFICIENT_BALANCE", "INVALID_SIDE", "DUPLICATE_CLIENT_ORDER_ID"} {
+		if resp.Results[i].ErrorCode != code {
+			t.Fatalf("result %d: expected %q, got %+v", i, code, resp.Results[i])
+		}
+	}
+	if o := resp.Results[0].Order; o == nil || o.Status != repository.StatusNew || o.ClientOrderID != "grid-1" {
+		t.Fatalf("expected first order NEW, got %+v", o)
+	}
+
+	// 校验通过的 3 笔同一事务落库；买单合并冻结 100.00 + 198.00，卖单冻结失败只拒绝卖单
+	if len(store.createdOrders) != 3 {
+		t.Fatalf("expected 3 orders created, got %d", len(store.createdOrders))
+	}
+	if len(*freezes) != 2 {
+		t.Fatalf("expected one freeze per asset, got %+v", *freezes)
+	}
+	if f := (*freezes)[0]; f.Asset != "USDT" || f.Amount != 29800 || f.IdempotencyKey != "freeze:batch:4:USDT" {
+		t.Fatalf("unexpected quote freeze: %+v", f)
+	}
+	if len(store.newOrderIDs) != 2 || len(store.rejectedIDs) != 1 || store.rejectedIDs[0] != 3 || store.rejectReasons[0] != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("unexpected status updates: new=%v rejected=%v", store.newOrderIDs, store.rejectedIDs)
+	}
+	if n, _ := redisClient.XLen(context.Background(), "orders").Result(); n != 2 {
+		t.Fatalf("expected 2 orders sent to matching, got %d", n)
+	}
+}
+
+// newUnknownFreezeTestService 清算在前 failures 次冻结请求上返回 500（冻结是否生效未知），解冻均成功
+func newUnknownFreezeTestService(t *testing.T, store OrderStore, failures int) (*OrderService, *[]string) {
+	t.Helper()
+	var calls []string
+	svc, _ := newClearingTestService(t, store, func(w http.ResponseWriter, r *http.Request) {
+		var req client.FreezeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		calls = append(calls, r.URL.Path+" "+req.IdempotencyKey)
+		if r.URL.Path == "/internal/freeze" && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(client.FreezeResponse{Success: true})
+	})
+	return svc, &calls
+}
+
+func TestBatchCreateOrders_UnknownFreezeReleased(t *testing.T) {
+	store := &cancelOrderStore{cfg: amendSymbolConfig()}
+	svc, calls := newUnknownFreezeTestService(t, store, 1)
+
+	resp, err := svc.BatchCreateOrders(context.Background(), &BatchCreateOrdersRequest{
+		UserID: 1,
+		Orders: []*CreateOrderRequest{{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 10000, Quantity: 100}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Results[0].ErrorCode != "INTERNAL_ERROR" {
+		t.Fatalf("unexpected result: %+v", resp.Results[0])
+	}
+	// 同一幂等键重新冻结确认结果，再按派生键解冻，最后拒绝订单
+	want := []string{"/internal/freeze freeze:batch:2:USDT", "/internal/freeze freeze:batch:2:USDT", "/internal/unfreeze unfreeze:batch:2:USDT"}
+	if fmt.Sprint(*calls) != fmt.Sprint(want) {
+		t.Fatalf("expected clearing calls %v, got %v", want, *calls)
+	}
+	if len(store.rejectedIDs) != 1 || store.rejectReasons[0] != "INTERNAL_ERROR" {
+		t.Fatalf("expected order rejected, got %v", store.rejectedIDs)
+	}
+}
+
+func TestBatchCreateOrders_UnresolvedFreezeKeepsOrdersInit(t *testing.T) {
+	store := &cancelOrderStore{cfg: amendSymbolConfig()}
+	svc, calls := newUnknownFreezeTestService(t, store, 2)
+
+	resp, err := svc.BatchCreateOrders(context.Background(), &BatchCreateOrdersRequest{
+		UserID: 1,
+		Orders: []*CreateOrderRequest{{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 10000, Quantity: 100}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Results[0].ErrorCode != "INTERNAL_ERROR" {
+		t.Fatalf("unexpected result: %+v", resp.Results[0])
+	}
+	// 结果仍未知：不解冻、不拒绝，订单保持 INIT 待对账
+	if len(*calls) != 2 || len(store.rejectedIDs) != 0 || len(store.newOrderIDs) != 0 {
+		t.Fatalf("expected orders left INIT without unfreeze, calls=%v rejected=%v", *calls, store.rejectedIDs)
+	}
+}
+
+func TestBatchCreateOrders_Limits(t *testing.T) {
+	store := &cancelOrderStore{cfg: amendSymbolConfig()}
+	svc, _, freezes := newBatchTestService(t, store, "")
+
+	tooMany := make([]*CreateOrderRequest, MaxBatchOrders+1)
+	for _, req := range []*BatchCreateOrdersRequest{nil, {UserID: 1}, {UserID: 1, Orders: tooMany}} {
+		resp, err := svc.BatchCreateOrders(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.ErrorCode != "INVALID_PARAM" {
+			t.Fatalf("expected INVALID_PARAM, got %+v", resp)
+		}
+	}
+
+	// 全部校验失败时不落库、不冻结
+	resp, _ := svc.BatchCreateOrders(context.Background(), &BatchCreateOrdersRequest{
+		UserID: 1,
+		Orders: []*CreateOrderRequest{nil, {Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 0, Quantity: 100}},
+	})
+	if resp.Results[0].ErrorCode != "INVALID_PARAM" || resp.Results[1].ErrorCode != "INVALID_PRICE" {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+	if len(store.createdOrders) != 0 || len(*freezes) != 0 {
+		t.Fatal("expected nothing created or frozen")
+	}
+}
+
+func TestBatchCancelOrders(t *testing.T) {
+	store := &cancelOrderStore{orders: map[int64]*repository.Order{
+		1: {OrderID: 1, UserID: 1, Symbol: "BTCUSDT", Status: repository.StatusNew},
+		2: {OrderID: 2, UserID: 1, Symbol: "BTCUSDT", Status: repository.StatusCanceled},
+		3: {OrderID: 3, UserID: 1, Symbol: "BTCUSDT", Status: repository.StatusFilled},
+		4: {OrderID: 4, UserID: 2, Symbol: "BTCUSDT", Status: repository.StatusNew},
+	}}
+	svc, redisClient, _ := newBatchTestService(t, store, "")
+
+	resp, err := svc.BatchCancelOrders(context.Background(), &BatchCancelOrdersRequest{
+		UserID:   1,
+		OrderIDs: []int64{1, 2, 3, 4, 99, 1},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, code := range []string{"", "", "ORDER_ALREADY_FILLED", "ORDER_NOT_FOUND", "ORDER_NOT_FOUND", ""} {
+		if resp.Results[i].ErrorCode != code {
+			t.Fatalf("result %d: expected %q, got %+v", i, code, resp.Results[i])
+		}
+	}
+
+	msgs, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected a single cancel message, got %d (%v)", len(msgs), err)
+	}
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(msgs[0].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if msg.Type != "CANCEL" || msg.OrderID != 1 {
+		t.Fatalf("unexpected cancel message: %+v", msg)
+	}
+
+	tooMany := make([]int64, MaxBatchOrders+1)
+	if resp, _ := svc.BatchCancelOrders(context.Background(), &BatchCancelOrdersRequest{UserID: 1, OrderIDs: tooMany}); resp.ErrorCode != "INVALID_PARAM" {
+		t.Fatalf("expected INVALID_PARAM, got %+v", resp)
+	}
+}
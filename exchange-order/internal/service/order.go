@@ -12,6 +12,8 @@ import (
 	"time"
 
 	commondecimal "github.com/exchange/common/pkg/decimal"
+	"github.com/exchange/common/pkg/shard"
+	"github.com/exchange/common/pkg/validate"
 	"github.com/exchange/order/internal/client"
 	"github.com/exchange/order/internal/metrics"
 	"github.com/exchange/order/internal/repository"
@@ -28,6 +30,10 @@ type OrderService struct {
 	clearing    *client.ClearingClient
 	metrics     *metrics.Metrics
 	publisher   orderPublisher
+	shards      *shard.Router // 撮合分片路由，为空时所有消息写入 orderStream
+	risk        PreTradeRisk  // 下单前风控，为空时不检查
+
+	daySessionEnd time.Duration // DAY 订单到期时间：UTC 零点后的偏移
 }
 
 type orderPublisher interface {
@@ -40,10 +46,24 @@ type OrderStore interface {
 	GetSymbolConfig(ctx context.Context, symbol string) (*repository.SymbolConfig, error)
 	GetOrderByClientID(ctx context.Context, userID int64, clientOrderID string) (*repository.Order, error)
 	CreateOrder(ctx context.Context, order *repository.Order) error
+	CreateOrders(ctx context.Context, orders []*repository.Order) error
 	GetOrder(ctx context.Context, orderID int64) (*repository.Order, error)
 	UpdateOrderStatus(ctx context.Context, orderID int64, status int, executedQty, cumulativeQuoteQty, updateTimeMs int64) error
 	RejectOrder(ctx context.Context, orderID int64, reason string, updateTimeMs int64) error
+	UpdateOrdersStatus(ctx context.Context, orderIDs []int64, fromStatus, toStatus int, updateTimeMs int64) (int64, error)
+	RejectOrders(ctx context.Context, orderIDs []int64, reason string, updateTimeMs int64) (int64, error)
+	BeginAmend(ctx context.Context, orderID, amendID, freezeAmount, updateTimeMs int64) error
+	ClearPendingAmend(ctx context.Context, orderID, amendID, updateTimeMs int64) error
 	ListOpenOrders(ctx context.Context, userID int64, symbol string, limit int) ([]*repository.Order, error)
+	ListOpenSymbols(ctx context.Context, userID int64) ([]string, error)
+	ListOpenQuotes(ctx context.Context, userID int64, symbol string) ([]*repository.Order, error)
+	UpdateQuoteStatus(ctx context.Context, quoteID int64, fromStatus, toStatus int, updateTimeMs int64) (int64, error)
+	RejectQuoteOrders(ctx context.Context, quoteID int64, reason string, updateTimeMs int64) (int64, error)
+	CreateOrderList(ctx context.Context, list *repository.OrderList, orders []*repository.Order) error
+	GetOrderList(ctx context.Context, listID int64) (*repository.OrderList, error)
+	GetOrderListByClientID(ctx context.Context, userID int64, clientListID string) (*repository.OrderList, error)
+	ListOrderListOrders(ctx context.Context, listID int64) ([]*repository.Order, error)
+	FinishOrderList(ctx context.Context, listID int64, status int, updateTimeMs int64) error
 	ListOrders(ctx context.Context, userID int64, symbol string, startTime, endTime int64, limit int) ([]*repository.Order, error)
 	ListSymbolConfigs(ctx context.Context) ([]*repository.SymbolConfig, error)
 }
@@ -70,17 +90,48 @@ func (s *OrderService) SetPublisher(publisher orderPublisher) {
 	s.publisher = publisher
 }
 
+// SetShardRouter 设置撮合分片路由，订单消息写入交易对所属分片的订单流
+func (s *OrderService) SetShardRouter(router *shard.Router) {
+	s.shards = router
+}
+
+// matchingStream 交易对所属撮合分片的订单流
+func (s *OrderService) matchingStream(ctx context.Context, symbol string) (string, error) {
+	if s.shards == nil {
+		return s.orderStream, nil
+	}
+	return s.shards.Stream(ctx, s.orderStream, symbol)
+}
+
+// SetDaySessionEnd 设置交易时段结束时间（UTC 零点后的偏移），DAY 订单在该时间到期
+func (s *OrderService) SetDaySessionEnd(offset time.Duration) {
+	s.daySessionEnd = offset
+}
+
+// GTD 到期时间范围
+const (
+	minGTDLead     = 5 * time.Second
+	maxGTDLifetime = 365 * 24 * time.Hour
+)
+
 // CreateOrderRequest 下单请求
 type CreateOrderRequest struct {
-	UserID        int64
-	Symbol        string
-	Side          string // BUY / SELL
-	Type          string // LIMIT / MARKET
-	TimeInForce   string // GTC / IOC / FOK / POST_ONLY
-	Price         int64
-	Quantity      int64
-	QuoteOrderQty int64 // 市价买单：花多少钱
-	ClientOrderID string
+	UserID          int64
+	Symbol          string
+	Side            string // BUY / SELL
+	Type            string // LIMIT / MARKET / STOP_LOSS / STOP_LOSS_LIMIT / TAKE_PROFIT / TRAILING_STOP
+	TimeInForce     string // GTC / IOC / FOK / POST_ONLY / GTD / DAY
+	Price           int64
+	StopPrice       int64 // 条件单触发价（跟踪止损由撮合维护，不可指定）
+	Quantity        int64
+	QuoteOrderQty   int64 // 市价买单：花多少钱
+	ClientOrderID   string
+	STPMode         string // EXPIRE_TAKER（默认）/ EXPIRE_MAKER / EXPIRE_BOTH / DECREMENT
+	DisplayQty      int64  // 冰山单每次展示数量，0 表示非冰山单
+	ExpireTime      int64  // GTD 到期时间（毫秒）；DAY 由交易时段计算，不可指定
+	TrailingDelta   int64  // 跟踪止损回调价差，与 TrailingBps 二选一
+	TrailingBps     int64  // 跟踪止损回调比例（基点）
+	ActivationPrice int64  // 跟踪止损激活价，0 表示接受即激活
 }
 
 // CreateOrderResponse 下单响应
@@ -117,14 +168,10 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *CreateOrderRequest)
 		return reject("SYMBOL_NOT_FOUND"), nil
 	}
 
-	// 2. 检查交易对状态
-	if cfg.Status != 1 {
-		return reject("SYMBOL_NOT_TRADING"), nil
-	}
-
-	// 3. 参数校验
-	if err := s.validateOrder(req, cfg); err != nil {
-		return reject(err.Error()), nil
+	// 2-3. 交易对状态与参数校验
+	expireTimeMs, code := s.checkOrderRequest(req, cfg)
+	if code != "" {
+		return reject(code), nil
 	}
 
 	// 4. 幂等检查
@@ -140,54 +187,28 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *CreateOrderRequest)
 		}
 	}
 
-	// 5. 价格保护（仅限价单）
-	if req.Type == "LIMIT" && s.validator != nil {
-		if err := s.validator.ValidatePrice(req.Symbol, req.Side, req.Price); err != nil {
-			return reject(err.Error()), nil
+	// 下单前风控（幂等重放的订单不再检查）
+	riskOrder, code, err := s.checkRisk(ctx, req, cfg, 0)
+	if err != nil {
+		if s.metrics != nil {
+			s.metrics.IncOrderRejected("INTERNAL_ERROR")
 		}
+		return nil, err
+	} else if code != "" {
+		return reject(code), nil
 	}
-
-	now := time.Now().UnixMilli()
-	tif := parseTIF(req.TimeInForce)
-	if req.Type == "MARKET" && tif == 1 {
-		tif = 2 // MARKET 默认按 IOC 处理，避免挂单
-	}
-	order := &repository.Order{
-		OrderID:            s.idGen.NextID(),
-		ClientOrderID:      req.ClientOrderID,
-		UserID:             req.UserID,
-		Symbol:             req.Symbol,
-		Side:               parseSide(req.Side),
-		Type:               parseType(req.Type),
-		TimeInForce:        tif,
-		Price:              strconv.FormatInt(req.Price, 10),
-		StopPrice:          "0",
-		OrigQty:            strconv.FormatInt(req.Quantity, 10),
-		ExecutedQty:        "0",
-		CumulativeQuoteQty: "0",
-		Status:             repository.StatusInit,
-		CreateTimeMs:       now,
-		UpdateTimeMs:       now,
-	}
-
-	// 6. 计算冻结金额
-	var freezeAsset string
-	var freezeAmount int64
-	if order.Side == repository.SideBuy {
-		freezeAsset = cfg.QuoteAsset
-		if order.Type == repository.TypeMarket {
-			bufferedPrice, quoteAmount, err := s.marketBuyQuoteAmount(ctx, req.Symbol, req.Quantity, cfg)
-			if err != nil {
-				return reject("NO_REFERENCE_PRICE"), nil
-			}
-			order.Price = strconv.FormatInt(bufferedPrice, 10)
-			freezeAmount = quoteAmount
-		} else {
-			freezeAmount = quoteQty(req.Price, req.Quantity, cfg.QtyPrecision)
+	// 订单未送达撮合时退回已计入风控窗口的名义金额
+	sent := false
+	defer func() {
+		if !sent {
+			s.releaseRisk(ctx, riskOrder)
 		}
-	} else {
-		freezeAsset = cfg.BaseAsset
-		freezeAmount = req.Quantity
+	}()
+
+	// 5-6. 价格保护与冻结金额
+	order, freezeAsset, freezeAmount, code := s.buildOrder(ctx, req, cfg, expireTimeMs)
+	if code != "" {
+		return reject(code), nil
 	}
 	if s.clearing == nil {
 		if s.metrics != nil {
@@ -259,6 +280,7 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *CreateOrderRequest)
 		}
 		return nil, fmt.Errorf("send to matching: %w", err)
 	}
+	sent = true
 
 	if s.metrics != nil {
 		s.metrics.IncOrderCreated(order.Symbol, sideToString(order.Side))
@@ -272,6 +294,117 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *CreateOrderRequest)
 	return &CreateOrderResponse{Order: order}, nil
 }
 
+// checkOrderRequest 检查交易对状态并校验参数，返回到期时间或错误码
+func (s *OrderService) checkOrderRequest(req *CreateOrderRequest, cfg *repository.SymbolConfig) (int64, string) {
+	// 集合竞价期间只接受 GTC 限价单，GTD/DAY 到期前按 GTC 处理
+	switch cfg.Status {
+	case repository.SymbolStatusTrading:
+	case repository.SymbolStatusAuction:
+		if req.Type != "LIMIT" || !isRestingTimeInForce(req.TimeInForce) {
+			return 0, "AUCTION_ORDER_NOT_ALLOWED"
+		}
+	default:
+		return 0, "SYMBOL_NOT_TRADING"
+	}
+
+	if err := s.validateOrder(req, cfg); err != nil {
+		return 0, err.Error()
+	}
+	expireTimeMs, err := s.resolveExpireTime(req, time.Now())
+	if err != nil {
+		return 0, err.Error()
+	}
+	return expireTimeMs, ""
+}
+
+// buildOrder 价格保护后构造 INIT 订单，并计算冻结资产与金额
+func (s *OrderService) buildOrder(ctx context.Context, req *CreateOrderRequest, cfg *repository.SymbolConfig, expireTimeMs int64) (*repository.Order, string, int64, string) {
+	// 价格保护（仅限价单）
+	if req.Type == "LIMIT" && s.validator != nil {
+		if err := s.validator.ValidatePrice(req.Symbol, req.Side, req.Price); err != nil {
+			return nil, "", 0, err.Error()
+		}
+	}
+
+	now := time.Now().UnixMilli()
+	tif := parseTIF(req.TimeInForce)
+	if isMarketLikeType(req.Type) && tif == 1 {
+		tif = 2 // MARKET 默认按 IOC 处理，避免挂单
+	}
+	order := &repository.Order{
+		OrderID:            s.idGen.NextID(),
+		ClientOrderID:      req.ClientOrderID,
+		UserID:             req.UserID,
+		Symbol:             req.Symbol,
+		Side:               parseSide(req.Side),
+		Type:               parseType(req.Type),
+		TimeInForce:        tif,
+		Price:              strconv.FormatInt(req.Price, 10),
+		StopPrice:          strconv.FormatInt(req.StopPrice, 10),
+		OrigQty:            strconv.FormatInt(req.Quantity, 10),
+		ExecutedQty:        "0",
+		CumulativeQuoteQty: "0",
+		Status:             repository.StatusInit,
+		STPMode:            parseSTPMode(req.STPMode),
+		DisplayQty:         req.DisplayQty,
+		ExpireTimeMs:       expireTimeMs,
+		QuoteOrderQty:      req.QuoteOrderQty,
+		TrailingDelta:      req.TrailingDelta,
+		TrailingBps:        req.TrailingBps,
+		ActivationPrice:    req.ActivationPrice,
+		CreateTimeMs:       now,
+		UpdateTimeMs:       now,
+	}
+
+	// 计算冻结金额
+	if order.Side == repository.SideSell {
+		return order, cfg.BaseAsset, req.Quantity, ""
+	}
+	var freezeAmount int64
+	if order.QuoteOrderQty > 0 {
+		// 按金额市价买单冻结下单金额，未花完的部分在终态时解冻
+		order.Price = "0"
+		freezeAmount = order.QuoteOrderQty
+	} else if order.Type == repository.TypeMarket {
+		bufferedPrice, quoteAmount, err := s.marketBuyQuoteAmount(ctx, req.Symbol, req.Quantity, cfg)
+		if err != nil {
+			return nil, "", 0, "NO_REFERENCE_PRICE"
+		}
+		order.Price = strconv.FormatInt(bufferedPrice, 10)
+		freezeAmount = quoteAmount
+	} else if order.Type == repository.TypeTrailingStop {
+		// 买入跟踪止损的触发价只会下移，按激活价（未指定时为参考价）加回调幅度再加保护幅度冻结
+		ref := req.ActivationPrice
+		if ref <= 0 {
+			if s.validator == nil {
+				return nil, "", 0, "NO_REFERENCE_PRICE"
+			}
+			refPrice, err := s.validator.ReferencePrice(req.Symbol)
+			if err != nil || refPrice <= 0 {
+				return nil, "", 0, "NO_REFERENCE_PRICE"
+			}
+			ref = refPrice
+		}
+		bufferedPrice, quoteAmount, err := bufferedQuoteAmount(ref+trailingOffset(ref, req.TrailingDelta, req.TrailingBps), req.Quantity, cfg)
+		if err != nil {
+			return nil, "", 0, "INVALID_TRAILING_STOP"
+		}
+		order.Price = strconv.FormatInt(bufferedPrice, 10)
+		freezeAmount = quoteAmount
+	} else if isMarketLikeType(req.Type) {
+		// 市价条件单按触发价加保护幅度冻结
+		bufferedPrice, quoteAmount, err := bufferedQuoteAmount(req.StopPrice, req.Quantity, cfg)
+		if err != nil {
+			return nil, "", 0, "INVALID_STOP_PRICE"
+		}
+		order.Price = strconv.FormatInt(bufferedPrice, 10)
+		freezeAmount = quoteAmount
+	} else {
+		freezeAmount = quoteQty(req.Price, req.Quantity, cfg.QtyPrecision)
+	}
+	return order, cfg.QuoteAsset, freezeAmount, ""
+}
+
 func (s *OrderService) sendToMatchingWithRetry(ctx context.Context, order *repository.Order) error {
 	const maxAttempts = 3
 	backoff := 50 * time.Millisecond
@@ -348,6 +481,9 @@ func (s *OrderService) freezeSpecFromOrder(order *repository.Order, cfg *reposit
 		return "", 0, fmt.Errorf("invalid order/config")
 	}
 	if order.Side == repository.SideBuy {
+		if order.QuoteOrderQty > 0 {
+			return cfg.QuoteAsset, order.QuoteOrderQty, nil
+		}
 		price, err := parseInt64Compat(order.Price, "price")
 		if err != nil {
 			return "", 0, err
@@ -432,6 +568,121 @@ type CancelOrderResponse struct {
 
 // CancelOrder 撤销订单
 func (s *OrderService) CancelOrder(ctx context.Context, req *CancelOrderRequest) (*CancelOrderResponse, error) {
+	// 1-3. 获取订单并检查权限与状态
+	order, send, code := s.cancelTarget(ctx, req.UserID, req.OrderID, req.ClientOrderID)
+	if code != "" {
+		return &CancelOrderResponse{ErrorCode: code}, nil
+	}
+	if !send {
+		return &CancelOrderResponse{Order: order}, nil // 幂等
+	}
+
+	// 4. 发送撤单到撮合
+	if err := s.sendCancelToMatching(ctx, order); err != nil {
+		return nil, fmt.Errorf("send cancel to matching: %w", err)
+	}
+
+	return &CancelOrderResponse{Order: order}, nil
+}
+
+// cancelTarget 查找待撤订单并检查权限与状态，send 为 false 表示订单已撤销（幂等返回）
+func (s *OrderService) cancelTarget(ctx context.Context, userID, orderID int64, clientOrderID string) (order *repository.Order, send bool, code string) {
+	var err error
+	if orderID > 0 {
+		order, err = s.repo.GetOrder(ctx, orderID)
+	} else if clientOrderID != "" {
+		order, err = s.repo.GetOrderByClientID(ctx, userID, clientOrderID)
+	} else {
+		return nil, false, "INVALID_PARAM"
+	}
+	if err != nil || order.UserID != userID {
+		return nil, false, "ORDER_NOT_FOUND"
+	}
+
+	if order.Status != repository.StatusNew && order.Status != repository.StatusPartiallyFilled {
+		if order.Status == repository.StatusCanceled {
+			return order, false, ""
+		}
+		return nil, false, "ORDER_ALREADY_FILLED"
+	}
+	return order, true, ""
+}
+
+// MassCancelRequest 批量撤单请求（Symbol 为空表示全部交易对，Side 为空表示双边）
+type MassCancelRequest struct {
+	UserID int64
+	Symbol string
+	Side   string
+}
+
+// MassCancelResponse 批量撤单响应（撤单结果由撮合异步确认：MASS_CANCELED）
+type MassCancelResponse struct {
+	RequestID int64
+	Symbols   []string
+	ErrorCode string
+}
+
+// MassCancel 撤销用户的全部挂单与条件单
+//
+// 每个交易对发送一条 MASS_CANCEL，由撮合在引擎内一次性撤销并返回一个批量事件。
+func (s *OrderService) MassCancel(ctx context.Context, req *MassCancelRequest) (*MassCancelResponse, error) {
+	if req == nil || req.UserID <= 0 {
+		return &MassCancelResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	req.Side = strings.ToUpper(strings.TrimSpace(req.Side))
+	if req.Side != "" && req.Side != "BUY" && req.Side != "SELL" {
+		return &MassCancelResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+
+	symbols := []string{req.Symbol}
+	if req.Symbol == "" {
+		var err error
+		symbols, err = s.repo.ListOpenSymbols(ctx, req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("list open symbols: %w", err)
+		}
+	}
+
+	requestID := s.idGen.NextID()
+	for _, symbol := range symbols {
+		if err := s.sendMassCancelToMatching(ctx, req.UserID, symbol, req.Side, requestID); err != nil {
+			return nil, fmt.Errorf("send mass cancel to matching: %w", err)
+		}
+	}
+	return &MassCancelResponse{RequestID: requestID, Symbols: symbols}, nil
+}
+
+// AmendOrderRequest 改单请求
+//
+// Price/Quantity 为 0 表示不修改；Quantity 为改单后的订单总数量（含已成交）。
+type AmendOrderRequest struct {
+	UserID        int64
+	Symbol        string
+	OrderID       int64
+	ClientOrderID string
+	Price         int64
+	Quantity      int64
+}
+
+// AmendOrderResponse 改单响应（改单结果由撮合异步确认：ORDER_AMENDED / AMEND_REJECTED）
+type AmendOrderResponse struct {
+	Order     *repository.Order
+	AmendID   int64
+	ErrorCode string
+}
+
+// AmendOrder 原子改单
+//
+// 只冻结新旧冻结额之间的差额（不足部分），多余部分在撮合确认后由 OrderUpdater 解冻。
+func (s *OrderService) AmendOrder(ctx context.Context, req *AmendOrderRequest) (*AmendOrderResponse, error) {
+	if req == nil {
+		return &AmendOrderResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	if req.Price < 0 || req.Quantity < 0 || (req.Price == 0 && req.Quantity == 0) {
+		return &AmendOrderResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+
 	// 1. 获取订单
 	var order *repository.Order
 	var err error
@@ -440,32 +691,168 @@ func (s *OrderService) CancelOrder(ctx context.Context, req *CancelOrderRequest)
 	} else if req.ClientOrderID != "" {
 		order, err = s.repo.GetOrderByClientID(ctx, req.UserID, req.ClientOrderID)
 	} else {
-		return &CancelOrderResponse{ErrorCode: "INVALID_PARAM"}, nil
+		return &AmendOrderResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	if err != nil || order.UserID != req.UserID {
+		return &AmendOrderResponse{ErrorCode: "ORDER_NOT_FOUND"}, nil
+	}
+
+	// 2. 检查状态：仅在簿的限价单（含已触发的止损限价单）可改
+	switch order.Status {
+	case repository.StatusNew, repository.StatusPartiallyFilled:
+	case repository.StatusFilled:
+		return &AmendOrderResponse{ErrorCode: "ORDER_ALREADY_FILLED"}, nil
+	case repository.StatusCanceled:
+		return &AmendOrderResponse{ErrorCode: "ORDER_ALREADY_CANCELED"}, nil
+	default:
+		return &AmendOrderResponse{ErrorCode: "ORDER_NOT_FOUND"}, nil
+	}
+	if order.Type != repository.TypeLimit && !(order.Type == repository.TypeStopLossLimit && order.TriggerTimeMs > 0) {
+		return &AmendOrderResponse{ErrorCode: "AMEND_NOT_ALLOWED"}, nil
+	}
+	// 列表订单共用列表冻结，不支持单独改单
+	if order.ListID != 0 {
+		return &AmendOrderResponse{ErrorCode: "AMEND_NOT_ALLOWED"}, nil
+	}
+	if order.PendingAmendID != 0 {
+		return &AmendOrderResponse{ErrorCode: "AMEND_IN_PROGRESS"}, nil
 	}
 
+	cfg, err := s.repo.GetSymbolConfig(ctx, order.Symbol)
 	if err != nil {
-		return &CancelOrderResponse{ErrorCode: "ORDER_NOT_FOUND"}, nil
+		return &AmendOrderResponse{ErrorCode: "SYMBOL_NOT_FOUND"}, nil
+	}
+	if cfg.Status != repository.SymbolStatusTrading && cfg.Status != repository.SymbolStatusAuction {
+		return &AmendOrderResponse{ErrorCode: "SYMBOL_NOT_TRADING"}, nil
 	}
 
-	// 2. 检查权限
-	if order.UserID != req.UserID {
-		return &CancelOrderResponse{ErrorCode: "ORDER_NOT_FOUND"}, nil
+	// 3. 参数校验
+	oldPrice, err := parseInt64Compat(order.Price, "price")
+	if err != nil {
+		return nil, err
+	}
+	oldQty, err := parseInt64Compat(order.OrigQty, "orig_qty")
+	if err != nil {
+		return nil, err
+	}
+	executedQty, err := parseInt64Compat(order.ExecutedQty, "executed_qty")
+	if err != nil {
+		return nil, err
+	}
+	newPrice, newQty := req.Price, req.Quantity
+	if newPrice == 0 {
+		newPrice = oldPrice
+	}
+	if newQty == 0 {
+		newQty = oldQty
+	}
+	if newPrice == oldPrice && newQty == oldQty {
+		return &AmendOrderResponse{ErrorCode: "AMEND_NO_CHANGE"}, nil
+	}
+	if newQty <= executedQty {
+		return &AmendOrderResponse{ErrorCode: "INVALID_AMEND_QTY"}, nil
+	}
+	if err := s.validateOrder(&CreateOrderRequest{
+		Side:        sideToString(order.Side),
+		Type:        "LIMIT",
+		TimeInForce: tifToString(order.TimeInForce),
+		STPMode:     stpModeToString(order.STPMode),
+		Price:       newPrice,
+		Quantity:    newQty,
+	}, cfg); err != nil {
+		return &AmendOrderResponse{ErrorCode: err.Error()}, nil
+	}
+	if newPrice != oldPrice && s.validator != nil {
+		if err := s.validator.ValidatePrice(order.Symbol, sideToString(order.Side), newPrice); err != nil {
+			return &AmendOrderResponse{ErrorCode: err.Error()}, nil
+		}
+	}
+	if s.clearing == nil {
+		return nil, fmt.Errorf("clearing client not configured")
 	}
 
-	// 3. 检查状态
-	if order.Status != repository.StatusNew && order.Status != repository.StatusPartiallyFilled {
-		if order.Status == repository.StatusCanceled {
-			return &CancelOrderResponse{Order: order}, nil // 幂等
+	// 4. 计算需追加冻结的差额
+	var freezeAsset string
+	var freezeAmount int64
+	if order.Side == repository.SideBuy {
+		total, err := totalFrozenQuote(order, cfg)
+		if err != nil {
+			return nil, err
+		}
+		spent, err := parseInt64Compat(order.CumulativeQuoteQty, "cumulative_quote_qty")
+		if err != nil {
+			return nil, err
 		}
-		return &CancelOrderResponse{ErrorCode: "ORDER_ALREADY_FILLED"}, nil
+		freezeAsset = cfg.QuoteAsset
+		freezeAmount = quoteQty(newPrice, newQty-executedQty, cfg.QtyPrecision) - (total - spent)
+	} else {
+		freezeAsset = cfg.BaseAsset
+		freezeAmount = newQty - oldQty
+	}
+	if freezeAmount < 0 {
+		freezeAmount = 0
 	}
 
-	// 4. 发送撤单到撮合
-	if err := s.sendCancelToMatching(ctx, order); err != nil {
-		return nil, fmt.Errorf("send cancel to matching: %w", err)
+	// 5. 下单前风控（只检查增加的数量或名义金额）
+	riskOrder, code, err := s.checkAmendRisk(ctx, order, cfg, oldPrice, oldQty, newPrice, newQty, executedQty)
+	if err != nil {
+		return nil, err
+	}
+	if code != "" {
+		return &AmendOrderResponse{ErrorCode: code}, nil
 	}
+	sent := false
+	defer func() {
+		if !sent {
+			s.releaseRisk(ctx, riskOrder)
+		}
+	}()
 
-	return &CancelOrderResponse{Order: order}, nil
+	// 6. 登记改单（同一订单同时只允许一个改单），再冻结差额
+	amendID := s.idGen.NextID()
+	if err := s.repo.BeginAmend(ctx, order.OrderID, amendID, freezeAmount, time.Now().UnixMilli()); err != nil {
+		if errors.Is(err, repository.ErrAmendInProgress) {
+			return &AmendOrderResponse{ErrorCode: "AMEND_IN_PROGRESS"}, nil
+		}
+		return nil, fmt.Errorf("begin amend: %w", err)
+	}
+	if freezeAmount > 0 {
+		freezeKey := fmt.Sprintf("freeze:order:%d:amend:%d", order.OrderID, amendID)
+		freezeResp, err := s.clearing.FreezeBalance(ctx, order.UserID, freezeAsset, freezeAmount, freezeKey)
+		if err != nil {
+			s.clearPendingAmend(ctx, order.OrderID, amendID)
+			return nil, fmt.Errorf("freeze balance: %w", err)
+		}
+		if freezeResp == nil || !freezeResp.Success {
+			s.clearPendingAmend(ctx, order.OrderID, amendID)
+			code := "FREEZE_FAILED"
+			if freezeResp != nil && freezeResp.ErrorCode != "" {
+				code = freezeResp.ErrorCode
+			}
+			return &AmendOrderResponse{ErrorCode: code}, nil
+		}
+	}
+
+	// 7. 发送改单到撮合
+	if err := s.sendAmendToMatching(ctx, order, amendID, newPrice, newQty); err != nil {
+		if rollbackErr := s.rollbackFreeze(ctx, order, freezeAsset, freezeAmount, fmt.Sprintf("amend:%d:rollback", amendID)); rollbackErr != nil {
+			log.Printf("rollback amend freeze error: %v", rollbackErr)
+		} else {
+			s.clearPendingAmend(ctx, order.OrderID, amendID)
+		}
+		return nil, fmt.Errorf("send amend to matching: %w", err)
+	}
+	sent = true
+
+	order.PendingAmendID = amendID
+	order.PendingAmendFreeze = freezeAmount
+	return &AmendOrderResponse{Order: order, AmendID: amendID}, nil
+}
+
+func (s *OrderService) clearPendingAmend(ctx context.Context, orderID, amendID int64) {
+	if err := s.repo.ClearPendingAmend(ctx, orderID, amendID, time.Now().UnixMilli()); err != nil && !errors.Is(err, repository.ErrOrderNotFound) {
+		log.Printf("clear pending amend error: orderID=%d amendID=%d: %v", orderID, amendID, err)
+	}
 }
 
 // GetOrder 获取订单
@@ -520,9 +907,48 @@ func (s *OrderService) validateOrder(req *CreateOrderRequest, cfg *repository.Sy
 	if !isValidTimeInForce(req.TimeInForce) {
 		return fmt.Errorf("INVALID_TIME_IN_FORCE")
 	}
-	if req.Type == "MARKET" && req.TimeInForce == "POST_ONLY" {
+	if isMarketLikeType(req.Type) && req.TimeInForce == "POST_ONLY" {
 		return fmt.Errorf("INVALID_TIME_IN_FORCE")
 	}
+	// 市价单不挂单，不支持到期时间（市价条件单在触发前可到期）
+	if req.Type == "MARKET" && isExpiringTimeInForce(req.TimeInForce) {
+		return fmt.Errorf("INVALID_TIME_IN_FORCE")
+	}
+	if !isValidSTPMode(req.STPMode) {
+		return fmt.Errorf("INVALID_STP_MODE")
+	}
+	// 按金额下单仅限市价买单：不指定数量，由撮合按金额逐笔成交，不支持 FOK 与数量扣减的 STP
+	if req.QuoteOrderQty != 0 {
+		if req.Type != "MARKET" || req.Side != "BUY" || req.QuoteOrderQty < 0 || req.Quantity != 0 || req.DisplayQty != 0 {
+			return fmt.Errorf("INVALID_QUOTE_ORDER_QTY")
+		}
+		if req.TimeInForce == "FOK" {
+			return fmt.Errorf("INVALID_TIME_IN_FORCE")
+		}
+		if req.STPMode == "DECREMENT" {
+			return fmt.Errorf("INVALID_STP_MODE")
+		}
+	}
+	if req.Type == "TRAILING_STOP" {
+		if req.StopPrice != 0 {
+			return fmt.Errorf("INVALID_STOP_PRICE")
+		}
+		if req.TrailingDelta < 0 || req.TrailingBps < 0 || req.TrailingBps >= maxTrailingBps || req.ActivationPrice < 0 ||
+			(req.TrailingDelta > 0) == (req.TrailingBps > 0) {
+			return fmt.Errorf("INVALID_TRAILING_STOP")
+		}
+	} else {
+		if req.TrailingDelta != 0 || req.TrailingBps != 0 || req.ActivationPrice != 0 {
+			return fmt.Errorf("INVALID_TRAILING_STOP")
+		}
+		if isStopOrderType(req.Type) {
+			if req.StopPrice <= 0 {
+				return fmt.Errorf("INVALID_STOP_PRICE")
+			}
+		} else if req.StopPrice != 0 {
+			return fmt.Errorf("INVALID_STOP_PRICE")
+		}
+	}
 
 	if cfg.BasePrecision <= 0 || cfg.QuotePrecision <= 0 {
 		return fmt.Errorf("INVALID_SYMBOL_CONFIG")
@@ -558,6 +984,20 @@ func (s *OrderService) validateOrder(req *CreateOrderRequest, cfg *repository.Sy
 		return fmt.Errorf("INVALID_SYMBOL_CONFIG")
 	}
 
+	// 按金额市价买单只校验最小成交额
+	if req.QuoteOrderQty > 0 {
+		if req.QuoteOrderQty < minNotional {
+			return fmt.Errorf("NOTIONAL_TOO_SMALL")
+		}
+		return nil
+	}
+
+	// 价格分档：限价单按委托价取最小变动单位与数量步长，市价单使用交易对配置
+	baseTick := priceTick
+	if req.Type == "LIMIT" || req.Type == "STOP_LOSS_LIMIT" {
+		priceTick, qtyStep = validate.BandSteps(cfg.TickBands, req.Price, priceTick, qtyStep)
+	}
+
 	// 数量校验
 	if req.Quantity < minQty {
 		return fmt.Errorf("QTY_TOO_SMALL")
@@ -569,8 +1009,39 @@ func (s *OrderService) validateOrder(req *CreateOrderRequest, cfg *repository.Sy
 		return fmt.Errorf("INVALID_QUANTITY")
 	}
 
+	// 冰山单展示数量校验：仅可挂单的限价单，且小于订单数量
+	if req.DisplayQty != 0 {
+		if req.Type != "LIMIT" && req.Type != "STOP_LOSS_LIMIT" {
+			return fmt.Errorf("INVALID_DISPLAY_QTY")
+		}
+		if !isRestingTimeInForce(req.TimeInForce) && req.TimeInForce != "POST_ONLY" {
+			return fmt.Errorf("INVALID_DISPLAY_QTY")
+		}
+		if req.DisplayQty < minQty || req.DisplayQty >= req.Quantity {
+			return fmt.Errorf("INVALID_DISPLAY_QTY")
+		}
+		if qtyStep > 0 && req.DisplayQty%qtyStep != 0 {
+			return fmt.Errorf("INVALID_DISPLAY_QTY")
+		}
+	}
+
+	// 条件单触发价校验（按触发价所在分档）
+	if isStopOrderType(req.Type) && req.Type != "TRAILING_STOP" {
+		stopTick, _ := validate.BandSteps(cfg.TickBands, req.StopPrice, baseTick, 0)
+		if stopTick > 0 && req.StopPrice%stopTick != 0 {
+			return fmt.Errorf("INVALID_STOP_PRICE")
+		}
+	}
+	// 跟踪止损回调价差与激活价按激活价所在分档对齐
+	if req.Type == "TRAILING_STOP" {
+		trailTick, _ := validate.BandSteps(cfg.TickBands, req.ActivationPrice, baseTick, 0)
+		if trailTick > 0 && (req.TrailingDelta%trailTick != 0 || req.ActivationPrice%trailTick != 0) {
+			return fmt.Errorf("INVALID_TRAILING_STOP")
+		}
+	}
+
 	// 限价单价格校验
-	if req.Type == "LIMIT" {
+	if req.Type == "LIMIT" || req.Type == "STOP_LOSS_LIMIT" {
 		if req.Price <= 0 {
 			return fmt.Errorf("INVALID_PRICE")
 		}
@@ -598,6 +1069,17 @@ func (s *OrderService) marketBuyQuoteAmount(ctx context.Context, symbol string,
 	if err != nil || refPrice <= 0 {
 		return 0, 0, errors.New("no reference price")
 	}
+	return bufferedQuoteAmount(refPrice, qty, cfg)
+}
+
+// bufferedQuoteAmount 按 refPrice*(1+priceLimitRate) 计算买单冻结价格与冻结金额
+func bufferedQuoteAmount(refPrice, qty int64, cfg *repository.SymbolConfig) (int64, int64, error) {
+	if qty <= 0 {
+		return 0, 0, errors.New("invalid quantity")
+	}
+	if refPrice <= 0 {
+		return 0, 0, errors.New("invalid reference price")
+	}
 
 	limitRate := resolveLimitRate(cfg)
 	pricePrecision := normalizePrecision(cfg.PricePrecision)
@@ -630,50 +1112,56 @@ func resolveLimitRate(cfg *repository.SymbolConfig) commondecimal.Decimal {
 
 // OrderMessage 发送到撮合的消息
 type OrderMessage struct {
-	Type          string `json:"type"`
-	OrderID       int64  `json:"orderId"`
-	ClientOrderID string `json:"clientOrderId"`
-	UserID        int64  `json:"userId"`
-	Symbol        string `json:"symbol"`
-	Side          string `json:"side"`
-	OrderType     string `json:"orderType"`
-	TimeInForce   string `json:"timeInForce"`
-	Price         int64  `json:"price"`
-	Qty           int64  `json:"qty"`
+	Type            string `json:"type"`
+	OrderID         int64  `json:"orderId"`
+	ClientOrderID   string `json:"clientOrderId"`
+	UserID          int64  `json:"userId"`
+	Symbol          string `json:"symbol"`
+	Side            string `json:"side"`
+	OrderType       string `json:"orderType"`
+	TimeInForce     string `json:"timeInForce"`
+	Price           int64  `json:"price"`
+	Qty             int64  `json:"qty"`
+	QuoteOrderQty   int64  `json:"quoteOrderQty,omitempty"`
+	StopPrice       int64  `json:"stopPrice,omitempty"`
+	TrailingDelta   int64  `json:"trailingDelta,omitempty"`
+	TrailingBps     int64  `json:"trailingBps,omitempty"`
+	ActivationPrice int64  `json:"activationPrice,omitempty"`
+	STPMode         string `json:"stpMode,omitempty"`
+	AmendID         int64  `json:"amendId,omitempty"`
+	DisplayQty      int64  `json:"displayQty,omitempty"`
+	RequestID       int64  `json:"requestId,omitempty"`
+	ExpireTime      int64  `json:"expireTime,omitempty"`
+	ListID          int64  `json:"listId,omitempty"`
+	SiblingOrderID  int64  `json:"siblingOrderId,omitempty"` // 同列表的另一条订单，撮合在本订单成交或触发时撤销
+
+	// MASS_QUOTE
+	Quotes     []QuoteMessage `json:"quotes,omitempty"`
+	BuyFrozen  int64          `json:"buyFrozen,omitempty"`
+	SellFrozen int64          `json:"sellFrozen,omitempty"`
+	QtyScale   int64          `json:"qtyScale,omitempty"` // MASS_QUOTE 与按金额市价买单
 }
 
 func (s *OrderService) sendToMatching(ctx context.Context, order *repository.Order) error {
 	if s.redis == nil {
 		return fmt.Errorf("redis client not configured")
 	}
-	price, err := parseInt64Compat(order.Price, "price")
+	msg, err := s.newOrderMessage(ctx, order)
 	if err != nil {
 		return err
 	}
-	qty, err := parseInt64Compat(order.OrigQty, "orig_qty")
+
+	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
-	msg := &OrderMessage{
-		Type:          "NEW",
-		OrderID:       order.OrderID,
-		ClientOrderID: order.ClientOrderID,
-		UserID:        order.UserID,
-		Symbol:        order.Symbol,
-		Side:          sideToString(order.Side),
-		OrderType:     typeToString(order.Type),
-		TimeInForce:   tifToString(order.TimeInForce),
-		Price:         price,
-		Qty:           qty,
-	}
 
-	data, err := json.Marshal(msg)
+	stream, err := s.matchingStream(ctx, order.Symbol)
 	if err != nil {
 		return err
 	}
-
 	_, err = s.redis.XAdd(ctx, &redis.XAddArgs{
-		Stream: s.orderStream,
+		Stream: stream,
 		Values: map[string]interface{}{
 			"data": string(data),
 		},
@@ -682,6 +1170,51 @@ func (s *OrderService) sendToMatching(ctx context.Context, order *repository.Ord
 	return err
 }
 
+// newOrderMessage 构造下单消息
+func (s *OrderService) newOrderMessage(ctx context.Context, order *repository.Order) (*OrderMessage, error) {
+	price, err := parseInt64Compat(order.Price, "price")
+	if err != nil {
+		return nil, err
+	}
+	qty, err := parseInt64Compat(order.OrigQty, "orig_qty")
+	if err != nil {
+		return nil, err
+	}
+	stopPrice, err := parseInt64Compat(order.StopPrice, "stop_price")
+	if err != nil {
+		return nil, err
+	}
+	msg := &OrderMessage{
+		Type:            "NEW",
+		OrderID:         order.OrderID,
+		ClientOrderID:   order.ClientOrderID,
+		UserID:          order.UserID,
+		Symbol:          order.Symbol,
+		Side:            sideToString(order.Side),
+		OrderType:       typeToString(order.Type),
+		TimeInForce:     tifToString(order.TimeInForce),
+		Price:           price,
+		Qty:             qty,
+		StopPrice:       stopPrice,
+		STPMode:         stpModeToString(order.STPMode),
+		DisplayQty:      order.DisplayQty,
+		ExpireTime:      order.ExpireTimeMs,
+		TrailingDelta:   order.TrailingDelta,
+		TrailingBps:     order.TrailingBps,
+		ActivationPrice: order.ActivationPrice,
+	}
+	if order.QuoteOrderQty > 0 {
+		// 撮合按 price * qty / QtyScale 扣减金额，与成交额计算一致
+		cfg, err := s.repo.GetSymbolConfig(ctx, order.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		msg.QuoteOrderQty = order.QuoteOrderQty
+		msg.QtyScale = scaleFactor(cfg.QtyPrecision)
+	}
+	return msg, nil
+}
+
 func parseInt64Compat(value string, field string) (int64, error) {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -704,16 +1237,83 @@ func parseInt64Compat(value string, field string) (int64, error) {
 	return parsed, nil
 }
 
+func newCancelMessage(order *repository.Order) *OrderMessage {
+	return &OrderMessage{
+		Type:          "CANCEL",
+		OrderID:       order.OrderID,
+		ClientOrderID: order.ClientOrderID,
+		UserID:        order.UserID,
+		Symbol:        order.Symbol,
+	}
+}
+
 func (s *OrderService) sendCancelToMatching(ctx context.Context, order *repository.Order) error {
+	if s.redis == nil {
+		return fmt.Errorf("redis client not configured")
+	}
+	data, err := json.Marshal(newCancelMessage(order))
+	if err != nil {
+		return err
+	}
+
+	stream, err := s.matchingStream(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		Values: map[string]interface{}{
+			"data": string(data),
+		},
+	}).Result()
+
+	return err
+}
+
+func (s *OrderService) sendMassCancelToMatching(ctx context.Context, userID int64, symbol, side string, requestID int64) error {
 	if s.redis == nil {
 		return fmt.Errorf("redis client not configured")
 	}
 	msg := &OrderMessage{
-		Type:          "CANCEL",
+		Type:      "MASS_CANCEL",
+		UserID:    userID,
+		Symbol:    symbol,
+		Side:      side,
+		RequestID: requestID,
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	stream, err := s.matchingStream(ctx, symbol)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		Values: map[string]interface{}{
+			"data": string(data),
+		},
+	}).Result()
+
+	return err
+}
+
+func (s *OrderService) sendAmendToMatching(ctx context.Context, order *repository.Order, amendID, price, qty int64) error {
+	if s.redis == nil {
+		return fmt.Errorf("redis client not configured")
+	}
+	msg := &OrderMessage{
+		Type:          "AMEND",
 		OrderID:       order.OrderID,
 		ClientOrderID: order.ClientOrderID,
 		UserID:        order.UserID,
 		Symbol:        order.Symbol,
+		Price:         price,
+		Qty:           qty,
+		AmendID:       amendID,
 	}
 
 	data, err := json.Marshal(msg)
@@ -721,8 +1321,12 @@ func (s *OrderService) sendCancelToMatching(ctx context.Context, order *reposito
 		return err
 	}
 
+	stream, err := s.matchingStream(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
 	_, err = s.redis.XAdd(ctx, &redis.XAddArgs{
-		Stream: s.orderStream,
+		Stream: stream,
 		Values: map[string]interface{}{
 			"data": string(data),
 		},
@@ -740,6 +1344,7 @@ func normalizeCreateOrderRequest(req *CreateOrderRequest) {
 	req.Type = strings.ToUpper(strings.TrimSpace(req.Type))
 	req.TimeInForce = strings.ToUpper(strings.TrimSpace(req.TimeInForce))
 	req.ClientOrderID = strings.TrimSpace(req.ClientOrderID)
+	req.STPMode = strings.ToUpper(strings.TrimSpace(req.STPMode))
 }
 
 func isValidSide(side string) bool {
@@ -747,12 +1352,88 @@ func isValidSide(side string) bool {
 }
 
 func isValidOrderType(orderType string) bool {
-	return orderType == "LIMIT" || orderType == "MARKET"
+	return orderType == "LIMIT" || orderType == "MARKET" || isStopOrderType(orderType)
+}
+
+func isStopOrderType(orderType string) bool {
+	switch orderType {
+	case "STOP_LOSS", "STOP_LOSS_LIMIT", "TAKE_PROFIT", "TRAILING_STOP":
+		return true
+	default:
+		return false
+	}
+}
+
+// isMarketLikeType 成交时按市价执行的订单类型
+func isMarketLikeType(orderType string) bool {
+	return orderType == "MARKET" || orderType == "STOP_LOSS" || orderType == "TAKE_PROFIT" || orderType == "TRAILING_STOP"
+}
+
+// maxTrailingBps 跟踪止损回调比例上限（基点，不含），与撮合一致
+const maxTrailingBps = 10000
+
+// trailingOffset 按价格 price 计算跟踪止损的回调幅度（与撮合的取整一致，最少 1）
+func trailingOffset(price, delta, bps int64) int64 {
+	if delta > 0 {
+		return delta
+	}
+	return max(price*bps/maxTrailingBps, 1)
 }
 
 func isValidTimeInForce(tif string) bool {
 	switch tif {
-	case "", "GTC", "IOC", "FOK", "POST_ONLY":
+	case "", "GTC", "IOC", "FOK", "POST_ONLY", "GTD", "DAY":
+		return true
+	default:
+		return false
+	}
+}
+
+// isExpiringTimeInForce 到期后由撮合撤销的有效期类型
+func isExpiringTimeInForce(tif string) bool {
+	return tif == "GTD" || tif == "DAY"
+}
+
+// isRestingTimeInForce 到期前按 GTC 挂单的有效期类型
+func isRestingTimeInForce(tif string) bool {
+	return tif == "" || tif == "GTC" || isExpiringTimeInForce(tif)
+}
+
+// resolveExpireTime 计算订单到期时间：GTD 校验指定时间，DAY 取当前交易时段结束时间
+func (s *OrderService) resolveExpireTime(req *CreateOrderRequest, now time.Time) (int64, error) {
+	switch req.TimeInForce {
+	case "GTD":
+		expireAt := time.UnixMilli(req.ExpireTime)
+		if req.ExpireTime <= 0 || expireAt.Before(now.Add(minGTDLead)) || expireAt.After(now.Add(maxGTDLifetime)) {
+			return 0, fmt.Errorf("INVALID_EXPIRE_TIME")
+		}
+		return req.ExpireTime, nil
+	case "DAY":
+		if req.ExpireTime != 0 {
+			return 0, fmt.Errorf("INVALID_EXPIRE_TIME")
+		}
+		return nextSessionEnd(now, s.daySessionEnd).UnixMilli(), nil
+	default:
+		if req.ExpireTime != 0 {
+			return 0, fmt.Errorf("INVALID_EXPIRE_TIME")
+		}
+		return 0, nil
+	}
+}
+
+// nextSessionEnd 当前交易时段结束时间：UTC 零点后 offset，已过则取次日
+func nextSessionEnd(now time.Time, offset time.Duration) time.Time {
+	now = now.UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(offset)
+	if !end.After(now) {
+		end = end.Add(24 * time.Hour)
+	}
+	return end
+}
+
+func isValidSTPMode(mode string) bool {
+	switch mode {
+	case "", "EXPIRE_TAKER", "EXPIRE_MAKER", "EXPIRE_BOTH", "DECREMENT":
 		return true
 	default:
 		return false
@@ -767,10 +1448,20 @@ func parseSide(s string) int {
 }
 
 func parseType(t string) int {
-	if t == "MARKET" {
+	switch t {
+	case "MARKET":
 		return repository.TypeMarket
+	case "STOP_LOSS":
+		return repository.TypeStopLoss
+	case "STOP_LOSS_LIMIT":
+		return repository.TypeStopLossLimit
+	case "TAKE_PROFIT":
+		return repository.TypeTakeProfit
+	case "TRAILING_STOP":
+		return repository.TypeTrailingStop
+	default:
+		return repository.TypeLimit
 	}
-	return repository.TypeLimit
 }
 
 func parseTIF(tif string) int {
@@ -781,11 +1472,28 @@ func parseTIF(tif string) int {
 		return 3
 	case "POST_ONLY":
 		return 4
+	case "GTD":
+		return 5
+	case "DAY":
+		return 6
 	default:
 		return 1 // GTC
 	}
 }
 
+func parseSTPMode(mode string) int {
+	switch mode {
+	case "EXPIRE_MAKER":
+		return repository.STPExpireMaker
+	case "EXPIRE_BOTH":
+		return repository.STPExpireBoth
+	case "DECREMENT":
+		return repository.STPDecrement
+	default:
+		return repository.STPExpireTaker
+	}
+}
+
 func sideToString(side int) string {
 	if side == repository.SideBuy {
 		return "BUY"
@@ -794,10 +1502,20 @@ func sideToString(side int) string {
 }
 
 func typeToString(t int) string {
-	if t == repository.TypeMarket {
+	switch t {
+	case repository.TypeMarket:
 		return "MARKET"
+	case repository.TypeStopLoss:
+		return "STOP_LOSS"
+	case repository.TypeStopLossLimit:
+		return "STOP_LOSS_LIMIT"
+	case repository.TypeTakeProfit:
+		return "TAKE_PROFIT"
+	case repository.TypeTrailingStop:
+		return "TRAILING_STOP"
+	default:
+		return "LIMIT"
 	}
-	return "LIMIT"
 }
 
 func tifToString(tif int) string {
@@ -808,7 +1526,24 @@ func tifToString(tif int) string {
 		return "FOK"
 	case 4:
 		return "POST_ONLY"
+	case 5:
+		return "GTD"
+	case 6:
+		return "DAY"
 	default:
 		return "GTC"
 	}
 }
+
+func stpModeToString(mode int) string {
+	switch mode {
+	case repository.STPExpireMaker:
+		return "EXPIRE_MAKER"
+	case repository.STPExpireBoth:
+		return "EXPIRE_BOTH"
+	case repository.STPDecrement:
+		return "DECREMENT"
+	default:
+		return "EXPIRE_TAKER"
+	}
+}
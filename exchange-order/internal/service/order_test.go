@@ -9,9 +9,12 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	commondecimal "github.com/exchange/common/pkg/decimal"
+	"github.com/exchange/common/pkg/shard"
+	"github.com/exchange/common/pkg/validate"
 	"github.com/exchange/order/internal/client"
 	"github.com/exchange/order/internal/repository"
 	"github.com/redis/go-redis/v9"
@@ -72,6 +75,189 @@ func TestValidateOrder_Boundaries(t *testing.T) {
 	}
 }
 
+func TestValidateOrder_StopOrders(t *testing.T) {
+	s := &OrderService{}
+	cfg := &repository.SymbolConfig{
+		Symbol:         "BTCUSDT",
+		PricePrecision: 8,
+		QtyPrecision:   8,
+		BasePrecision:  8,
+		QuotePrecision: 8,
+		PriceTick:      "0.01",
+		QtyStep:        "0.001",
+		MinQty:         "0.001",
+		MaxQty:         "10.0",
+		MinNotional:    "10.0",
+		Status:         1,
+	}
+	qty := int64(0.2 * 1e8)
+
+	cases := []struct {
+		name string
+		req  *CreateOrderRequest
+		want string
+	}{
+		{"stop loss ok", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", StopPrice: 90 * 1e8, Quantity: qty}, ""},
+		{"take profit ok", &CreateOrderRequest{Side: "BUY", Type: "TAKE_PROFIT", StopPrice: 90 * 1e8, Quantity: qty}, ""},
+		{"stop limit ok", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS_LIMIT", TimeInForce: "GTC", Price: 89 * 1e8, StopPrice: 90 * 1e8, Quantity: qty}, ""},
+		{"missing stop price", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", Quantity: qty}, "INVALID_STOP_PRICE"},
+		{"stop price off tick", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", StopPrice: 90*1e8 + 1, Quantity: qty}, "INVALID_STOP_PRICE"},
+		{"stop price on limit", &CreateOrderRequest{Side: "SELL", Type: "LIMIT", Price: 90 * 1e8, StopPrice: 90 * 1e8, Quantity: qty}, "INVALID_STOP_PRICE"},
+		{"stop limit missing price", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS_LIMIT", StopPrice: 90 * 1e8, Quantity: qty}, "INVALID_PRICE"},
+		{"stop market post only", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", TimeInForce: "POST_ONLY", StopPrice: 90 * 1e8, Quantity: qty}, "INVALID_TIME_IN_FORCE"},
+		{"stp decrement ok", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", StopPrice: 90 * 1e8, Quantity: qty, STPMode: "DECREMENT"}, ""},
+		{"invalid stp mode", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", StopPrice: 90 * 1e8, Quantity: qty, STPMode: "NONE"}, "INVALID_STP_MODE"},
+		{"trailing delta ok", &CreateOrderRequest{Side: "SELL", Type: "TRAILING_STOP", TrailingDelta: 1e8, Quantity: qty}, ""},
+		{"trailing bps with activation ok", &CreateOrderRequest{Side: "BUY", Type: "TRAILING_STOP", TrailingBps: 100, ActivationPrice: 90 * 1e8, Quantity: qty}, ""},
+		{"trailing without callback", &CreateOrderRequest{Side: "SELL", Type: "TRAILING_STOP", Quantity: qty}, "INVALID_TRAILING_STOP"},
+		{"trailing with both callbacks", &CreateOrderRequest{Side: "SELL", Type: "TRAILING_STOP", TrailingDelta: 1e8, TrailingBps: 100, Quantity: qty}, "INVALID_TRAILING_STOP"},
+		{"trailing bps too large", &CreateOrderRequest{Side: "SELL", Type: "TRAILING_STOP", TrailingBps: 10000, Quantity: qty}, "INVALID_TRAILING_STOP"},
+		{"trailing delta off tick", &CreateOrderRequest{Side: "SELL", Type: "TRAILING_STOP", TrailingDelta: 1e8 + 1, Quantity: qty}, "INVALID_TRAILING_STOP"},
+		{"trailing with stop price", &CreateOrderRequest{Side: "SELL", Type: "TRAILING_STOP", TrailingDelta: 1e8, StopPrice: 90 * 1e8, Quantity: qty}, "INVALID_STOP_PRICE"},
+		{"trailing params on stop loss", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", StopPrice: 90 * 1e8, TrailingBps: 100, Quantity: qty}, "INVALID_TRAILING_STOP"},
+	}
+	for _, tc := range cases {
+		err := s.validateOrder(tc.req, cfg)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestValidateOrder_TickBands(t *testing.T) {
+	s := &OrderService{}
+	cfg := &repository.SymbolConfig{
+		Symbol:         "DOGEUSDT",
+		PricePrecision: 8,
+		QtyPrecision:   8,
+		BasePrecision:  8,
+		QuotePrecision: 8,
+		PriceTick:      "0.0001",
+		QtyStep:        "0.001",
+		MinQty:         "0.001",
+		MaxQty:         "1000.0",
+		MinNotional:    "10.0",
+		Status:         1,
+		TickBands: []validate.TickBand{
+			{MinPrice: 1e8, PriceTick: 1e6},                      // >= 1.0: 0.01
+			{MinPrice: 1000 * 1e8, PriceTick: 1e8, QtyStep: 1e6}, // >= 1000: 1.0, lot 0.01
+		},
+	}
+
+	cases := []struct {
+		name string
+		req  *CreateOrderRequest
+		want string
+	}{
+		{"fine tick below first band", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "GTC", Price: 50010000, Quantity: 100 * 1e8}, ""},
+		{"fine tick in coarser band", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "GTC", Price: 100010000, Quantity: 100 * 1e8}, "INVALID_PRICE"},
+		{"band tick ok", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "GTC", Price: 101000000, Quantity: 100 * 1e8}, ""},
+		{"top band off tick", &CreateOrderRequest{Side: "SELL", Type: "LIMIT", TimeInForce: "GTC", Price: 100050000000, Quantity: 1e6}, "INVALID_PRICE"},
+		{"top band ok", &CreateOrderRequest{Side: "SELL", Type: "LIMIT", TimeInForce: "GTC", Price: 1001 * 1e8, Quantity: 1e6}, ""},
+		{"top band lot size", &CreateOrderRequest{Side: "SELL", Type: "LIMIT", TimeInForce: "GTC", Price: 1001 * 1e8, Quantity: 15e5}, "INVALID_QUANTITY"},
+		{"market uses symbol lot size", &CreateOrderRequest{Side: "SELL", Type: "MARKET", TimeInForce: "IOC", Quantity: 15e5}, ""},
+		{"stop price in top band", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", StopPrice: 100050000000, Quantity: 1e6}, "INVALID_STOP_PRICE"},
+		{"stop price below bands", &CreateOrderRequest{Side: "SELL", Type: "STOP_LOSS", StopPrice: 50010000, Quantity: 1e6}, ""},
+	}
+	for _, tc := range cases {
+		err := s.validateOrder(tc.req, cfg)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestValidateOrder_Iceberg(t *testing.T) {
+	s := &OrderService{}
+	cfg := &repository.SymbolConfig{
+		Symbol:         "BTCUSDT",
+		PricePrecision: 8,
+		QtyPrecision:   8,
+		BasePrecision:  8,
+		QuotePrecision: 8,
+		PriceTick:      "0.01",
+		QtyStep:        "0.001",
+		MinQty:         "0.001",
+		MaxQty:         "10.0",
+		MinNotional:    "10.0",
+		Status:         1,
+	}
+	qty := int64(1 * 1e8)
+	price := int64(100 * 1e8)
+
+	cases := []struct {
+		name string
+		req  *CreateOrderRequest
+		want string
+	}{
+		{"iceberg ok", &CreateOrderRequest{Side: "SELL", Type: "LIMIT", TimeInForce: "GTC", Price: price, Quantity: qty, DisplayQty: int64(0.1 * 1e8)}, ""},
+		{"iceberg post only ok", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "POST_ONLY", Price: price, Quantity: qty, DisplayQty: int64(0.1 * 1e8)}, ""},
+		{"iceberg market", &CreateOrderRequest{Side: "BUY", Type: "MARKET", Quantity: qty, DisplayQty: int64(0.1 * 1e8)}, "INVALID_DISPLAY_QTY"},
+		{"iceberg ioc", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "IOC", Price: price, Quantity: qty, DisplayQty: int64(0.1 * 1e8)}, "INVALID_DISPLAY_QTY"},
+		{"display not below qty", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "GTC", Price: price, Quantity: qty, DisplayQty: qty}, "INVALID_DISPLAY_QTY"},
+		{"display below min qty", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "GTC", Price: price, Quantity: qty, DisplayQty: int64(0.0001 * 1e8)}, "INVALID_DISPLAY_QTY"},
+		{"display off step", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "GTC", Price: price, Quantity: qty, DisplayQty: int64(0.0015 * 1e8)}, "INVALID_DISPLAY_QTY"},
+		{"negative display", &CreateOrderRequest{Side: "BUY", Type: "LIMIT", TimeInForce: "GTC", Price: price, Quantity: qty, DisplayQty: -1}, "INVALID_DISPLAY_QTY"},
+	}
+	for _, tc := range cases {
+		err := s.validateOrder(tc.req, cfg)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestResolveExpireTime(t *testing.T) {
+	s := &OrderService{}
+	s.SetDaySessionEnd(22 * time.Hour)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	hour := int64(time.Hour / time.Millisecond)
+
+	cases := []struct {
+		name string
+		req  *CreateOrderRequest
+		want int64
+		err  string
+	}{
+		{"gtd ok", &CreateOrderRequest{TimeInForce: "GTD", ExpireTime: now.UnixMilli() + hour}, now.UnixMilli() + hour, ""},
+		{"gtd missing", &CreateOrderRequest{TimeInForce: "GTD"}, 0, "INVALID_EXPIRE_TIME"},
+		{"gtd too soon", &CreateOrderRequest{TimeInForce: "GTD", ExpireTime: now.UnixMilli() + 1000}, 0, "INVALID_EXPIRE_TIME"},
+		{"gtd too far", &CreateOrderRequest{TimeInForce: "GTD", ExpireTime: now.Add(400 * 24 * time.Hour).UnixMilli()}, 0, "INVALID_EXPIRE_TIME"},
+		{"day", &CreateOrderRequest{TimeInForce: "DAY"}, time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC).UnixMilli(), ""},
+		{"day with expire time", &CreateOrderRequest{TimeInForce: "DAY", ExpireTime: now.UnixMilli() + hour}, 0, "INVALID_EXPIRE_TIME"},
+		{"gtc with expire time", &CreateOrderRequest{TimeInForce: "GTC", ExpireTime: now.UnixMilli() + hour}, 0, "INVALID_EXPIRE_TIME"},
+		{"gtc", &CreateOrderRequest{TimeInForce: "GTC"}, 0, ""},
+	}
+	for _, tc := range cases {
+		got, err := s.resolveExpireTime(tc.req, now)
+		errCode := ""
+		if err != nil {
+			errCode = err.Error()
+		}
+		if errCode != tc.err || got != tc.want {
+			t.Fatalf("%s: expected (%d, %q), got (%d, %q)", tc.name, tc.want, tc.err, got, errCode)
+		}
+	}
+
+	// 已过当日交易时段结束时间，DAY 订单到次日结束
+	late := time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC)
+	if end := nextSessionEnd(late, 22*time.Hour); !end.Equal(time.Date(2024, 3, 2, 22, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected next session end: %s", end)
+	}
+}
+
 func TestParseSide(t *testing.T) {
 	if parseSide("BUY") != repository.SideBuy {
 		t.Fatal("expected SideBuy")
@@ -94,6 +280,20 @@ func TestParseType(t *testing.T) {
 	if parseType("") != repository.TypeLimit {
 		t.Fatal("expected default TypeLimit")
 	}
+	if parseType("STOP_LOSS") != repository.TypeStopLoss || parseType("STOP_LOSS_LIMIT") != repository.TypeStopLossLimit || parseType("TAKE_PROFIT") != repository.TypeTakeProfit {
+		t.Fatal("expected stop order types")
+	}
+}
+
+func TestParseSTPMode(t *testing.T) {
+	for _, mode := range []string{"EXPIRE_TAKER", "EXPIRE_MAKER", "EXPIRE_BOTH", "DECREMENT"} {
+		if got := stpModeToString(parseSTPMode(mode)); got != mode {
+			t.Fatalf("round trip %s: got %s", mode, got)
+		}
+	}
+	if parseSTPMode("") != repository.STPExpireTaker {
+		t.Fatal("expected default EXPIRE_TAKER")
+	}
 }
 
 func TestParseTIF(t *testing.T) {
@@ -106,6 +306,9 @@ func TestParseTIF(t *testing.T) {
 	if parseTIF("FOK") != 3 {
 		t.Fatal("expected FOK=3")
 	}
+	if parseTIF("GTD") != 5 || parseTIF("DAY") != 6 {
+		t.Fatal("expected GTD=5, DAY=6")
+	}
 	if parseTIF("UNKNOWN") != 1 {
 		t.Fatal("expected default GTC=1 for unknown")
 	}
@@ -130,6 +333,9 @@ func TestTypeToString(t *testing.T) {
 	if typeToString(repository.TypeMarket) != "MARKET" {
 		t.Fatal("expected MARKET")
 	}
+	if typeToString(repository.TypeStopLossLimit) != "STOP_LOSS_LIMIT" || typeToString(repository.TypeTakeProfit) != "TAKE_PROFIT" {
+		t.Fatal("expected stop order type strings")
+	}
 }
 
 func TestTifToString(t *testing.T) {
@@ -142,6 +348,9 @@ func TestTifToString(t *testing.T) {
 	if tifToString(3) != "FOK" {
 		t.Fatal("expected FOK")
 	}
+	if tifToString(5) != "GTD" || tifToString(6) != "DAY" {
+		t.Fatal("expected GTD/DAY")
+	}
 	if tifToString(0) != "GTC" {
 		t.Fatal("expected default GTC")
 	}
@@ -171,6 +380,24 @@ func TestCreateOrderResponse_Fields(t *testing.T) {
 	}
 }
 
+// newClearingTestService 创建连接 miniredis 与模拟清算服务（clearing 处理全部清算请求）的订单服务
+func newClearingTestService(t *testing.T, store OrderStore, clearing http.HandlerFunc) (*OrderService, *redis.Client) {
+	t.Helper()
+	server := httptest.NewServer(clearing)
+	t.Cleanup(server.Close)
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	t.Cleanup(mr.Close)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	svc := NewOrderService(store, redisClient, &seqIDGen{}, "orders", nil, client.NewClearingClient(server.URL, "internal-token"), nil)
+	return svc, redisClient
+}
+
 type mockOrderStore struct {
 	cfg             *repository.SymbolConfig
 	created         bool
@@ -191,6 +418,10 @@ type mockOrderStore struct {
 	lastUpdateExecutedQty int64
 	lastUpdateQuoteQty    int64
 	lastUpdateTime        int64
+
+	createdList        *repository.OrderList
+	createdListOrders  []*repository.Order
+	finishedListStatus int
 }
 
 type cancelOrderStore struct {
@@ -202,10 +433,29 @@ type cancelOrderStore struct {
 	lastStart     int64
 	lastEnd       int64
 	symbolConfigs []*repository.SymbolConfig
+	openSymbols   []string
+
+	cfg            *repository.SymbolConfig
+	beginAmendErr  error
+	beganAmendID   int64
+	beganFreeze    int64
+	clearedAmendID int64
+
+	openQuotes      []*repository.Order
+	createdOrders   []*repository.Order
+	createOrdersErr error
+	quoteStatus     map[int64]int
+	rejectedQuote   int64
+	quoteRejectMsg  string
+
+	orders        map[int64]*repository.Order
+	newOrderIDs   []int64
+	rejectedIDs   []int64
+	rejectReasons []string
 }
 
 func (c *cancelOrderStore) GetSymbolConfig(_ context.Context, _ string) (*repository.SymbolConfig, error) {
-	return nil, nil
+	return c.cfg, nil
 }
 
 func (c *cancelOrderStore) GetOrderByClientID(_ context.Context, _ int64, _ string) (*repository.Order, error) {
@@ -222,10 +472,17 @@ func (c *cancelOrderStore) CreateOrder(_ context.Context, _ *repository.Order) e
 	return nil
 }
 
-func (c *cancelOrderStore) GetOrder(_ context.Context, _ int64) (*repository.Order, error) {
+func (c *cancelOrderStore) GetOrder(_ context.Context, orderID int64) (*repository.Order, error) {
 	if c.getOrderErr != nil {
 		return nil, c.getOrderErr
 	}
+	if c.orders != nil {
+		order, ok := c.orders[orderID]
+		if !ok {
+			return nil, repository.ErrOrderNotFound
+		}
+		return order, nil
+	}
 	return c.order, nil
 }
 
@@ -242,6 +499,10 @@ func (c *cancelOrderStore) ListOpenOrders(_ context.Context, _ int64, _ string,
 	return nil, nil
 }
 
+func (c *cancelOrderStore) ListOpenSymbols(_ context.Context, _ int64) ([]string, error) {
+	return c.openSymbols, nil
+}
+
 func (c *cancelOrderStore) ListOrders(_ context.Context, _ int64, _ string, startTime, endTime int64, limit int) ([]*repository.Order, error) {
 	c.lastListLimit = limit
 	c.lastStart = startTime
@@ -249,10 +510,81 @@ func (c *cancelOrderStore) ListOrders(_ context.Context, _ int64, _ string, star
 	return nil, nil
 }
 
+func (c *cancelOrderStore) BeginAmend(_ context.Context, _ int64, amendID, freezeAmount, _ int64) error {
+	if c.beginAmendErr != nil {
+		return c.beginAmendErr
+	}
+	c.beganAmendID = amendID
+	c.beganFreeze = freezeAmount
+	return nil
+}
+
+func (c *cancelOrderStore) ClearPendingAmend(_ context.Context, _ int64, amendID, _ int64) error {
+	c.clearedAmendID = amendID
+	return nil
+}
+
 func (c *cancelOrderStore) ListSymbolConfigs(_ context.Context) ([]*repository.SymbolConfig, error) {
 	return c.symbolConfigs, nil
 }
 
+func (c *cancelOrderStore) CreateOrders(_ context.Context, orders []*repository.Order) error {
+	if c.createOrdersErr != nil {
+		return c.createOrdersErr
+	}
+	c.createdOrders = append(c.createdOrders, orders...)
+	return nil
+}
+
+func (c *cancelOrderStore) ListOpenQuotes(_ context.Context, _ int64, _ string) ([]*repository.Order, error) {
+	return c.openQuotes, nil
+}
+
+func (c *cancelOrderStore) UpdateQuoteStatus(_ context.Context, quoteID int64, _, toStatus int, _ int64) (int64, error) {
+	if c.quoteStatus == nil {
+		c.quoteStatus = make(map[int64]int)
+	}
+	c.quoteStatus[quoteID] = toStatus
+	return int64(len(c.createdOrders)), nil
+}
+
+func (c *cancelOrderStore) RejectQuoteOrders(_ context.Context, quoteID int64, reason string, _ int64) (int64, error) {
+	c.rejectedQuote = quoteID
+	c.quoteRejectMsg = reason
+	return int64(len(c.createdOrders)), nil
+}
+
+func (c *cancelOrderStore) UpdateOrdersStatus(_ context.Context, orderIDs []int64, _, _ int, _ int64) (int64, error) {
+	c.newOrderIDs = append(c.newOrderIDs, orderIDs...)
+	return int64(len(orderIDs)), nil
+}
+
+func (c *cancelOrderStore) RejectOrders(_ context.Context, orderIDs []int64, reason string, _ int64) (int64, error) {
+	c.rejectedIDs = append(c.rejectedIDs, orderIDs...)
+	c.rejectReasons = append(c.rejectReasons, reason)
+	return int64(len(orderIDs)), nil
+}
+
+func (c *cancelOrderStore) CreateOrderList(_ context.Context, _ *repository.OrderList, _ []*repository.Order) error {
+	return nil
+}
+
+func (c *cancelOrderStore) GetOrderList(_ context.Context, _ int64) (*repository.OrderList, error) {
+	return nil, repository.ErrOrderListNotFound
+}
+
+func (c *cancelOrderStore) GetOrderListByClientID(_ context.Context, _ int64, _ string) (*repository.OrderList, error) {
+	return nil, repository.ErrOrderListNotFound
+}
+
+func (c *cancelOrderStore) ListOrderListOrders(_ context.Context, _ int64) ([]*repository.Order, error) {
+	return nil, nil
+}
+
+func (c *cancelOrderStore) FinishOrderList(_ context.Context, _ int64, _ int, _ int64) error {
+	return nil
+}
+
 func (m *mockOrderStore) GetSymbolConfig(_ context.Context, _ string) (*repository.SymbolConfig, error) {
 	if m.cfg == nil {
 		return nil, repository.ErrOrderNotFound
@@ -298,10 +630,22 @@ func (m *mockOrderStore) RejectOrder(_ context.Context, orderID int64, reason st
 	return m.rejectErr
 }
 
+func (m *mockOrderStore) BeginAmend(_ context.Context, _, _, _, _ int64) error {
+	return nil
+}
+
+func (m *mockOrderStore) ClearPendingAmend(_ context.Context, _, _, _ int64) error {
+	return nil
+}
+
 func (m *mockOrderStore) ListOpenOrders(_ context.Context, _ int64, _ string, _ int) ([]*repository.Order, error) {
 	return nil, nil
 }
 
+func (m *mockOrderStore) ListOpenSymbols(_ context.Context, _ int64) ([]string, error) {
+	return nil, nil
+}
+
 func (m *mockOrderStore) ListOrders(_ context.Context, _ int64, _ string, _ int64, _ int64, _ int) ([]*repository.Order, error) {
 	return nil, nil
 }
@@ -310,6 +654,59 @@ func (m *mockOrderStore) ListSymbolConfigs(_ context.Context) ([]*repository.Sym
 	return nil, nil
 }
 
+func (m *mockOrderStore) CreateOrders(_ context.Context, _ []*repository.Order) error {
+	return nil
+}
+
+func (m *mockOrderStore) ListOpenQuotes(_ context.Context, _ int64, _ string) ([]*repository.Order, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) UpdateQuoteStatus(_ context.Context, _ int64, _, _ int, _ int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockOrderStore) RejectQuoteOrders(_ context.Context, _ int64, _ string, _ int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockOrderStore) UpdateOrdersStatus(_ context.Context, _ []int64, _, _ int, _ int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockOrderStore) RejectOrders(_ context.Context, _ []int64, _ string, _ int64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockOrderStore) CreateOrderList(_ context.Context, list *repository.OrderList, orders []*repository.Order) error {
+	for _, order := range orders {
+		order.ListID = list.ListID
+	}
+	m.createdList = list
+	m.createdListOrders = orders
+	return m.createErr
+}
+
+func (m *mockOrderStore) GetOrderList(_ context.Context, _ int64) (*repository.OrderList, error) {
+	if m.createdList == nil {
+		return nil, repository.ErrOrderListNotFound
+	}
+	return m.createdList, nil
+}
+
+func (m *mockOrderStore) GetOrderListByClientID(_ context.Context, _ int64, _ string) (*repository.OrderList, error) {
+	return nil, repository.ErrOrderListNotFound
+}
+
+func (m *mockOrderStore) ListOrderListOrders(_ context.Context, _ int64) ([]*repository.Order, error) {
+	return m.createdListOrders, nil
+}
+
+func (m *mockOrderStore) FinishOrderList(_ context.Context, _ int64, status int, _ int64) error {
+	m.finishedListStatus = status
+	return nil
+}
+
 type mockIDGen struct{}
 
 func (g *mockIDGen) NextID() int64 {
@@ -505,6 +902,41 @@ func TestCreateOrder_SymbolNotTrading(t *testing.T) {
 	}
 }
 
+func TestCreateOrder_AuctionOnlyAcceptsGTCLimit(t *testing.T) {
+	store := &mockOrderStore{
+		cfg: &repository.SymbolConfig{
+			Symbol:         "BTCUSDT",
+			BaseAsset:      "BTC",
+			QuoteAsset:     "USDT",
+			PricePrecision: 8,
+			QtyPrecision:   8,
+			BasePrecision:  8,
+			QuotePrecision: 8,
+			MinQty:         "0.001",
+			MaxQty:         "10.0",
+			MinNotional:    "10.0",
+			PriceTick:      "0.01",
+			QtyStep:        "0.001",
+			Status:         repository.SymbolStatusAuction,
+		},
+	}
+	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
+
+	for _, req := range []*CreateOrderRequest{
+		{UserID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: int64(1 * 1e8)},
+		{UserID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", TimeInForce: "IOC", Price: int64(100 * 1e8), Quantity: int64(1 * 1e8)},
+		{UserID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", TimeInForce: "DAY", Quantity: int64(1 * 1e8)},
+	} {
+		resp, err := svc.CreateOrder(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.ErrorCode != "AUCTION_ORDER_NOT_ALLOWED" {
+			t.Fatalf("expected AUCTION_ORDER_NOT_ALLOWED for %s/%s, got %s", req.Type, req.TimeInForce, resp.ErrorCode)
+		}
+	}
+}
+
 func TestCreateOrder_IdempotentClientID(t *testing.T) {
 	existing := &repository.Order{OrderID: 99, Status: repository.StatusNew}
 	store := &mockOrderStore{
@@ -694,7 +1126,7 @@ func TestCreateOrder_InvalidEnums(t *testing.T) {
 				Symbol:      "BTCUSDT",
 				Side:        "BUY",
 				Type:        "LIMIT",
-				TimeInForce: "DAY",
+				TimeInForce: "GTX",
 				Price:       int64(100 * 1e8),
 				Quantity:    int64(1 * 1e8),
 			},
@@ -757,6 +1189,7 @@ func TestCreateOrder_NormalizesFields(t *testing.T) {
 		TimeInForce: " gtc ",
 		Price:       int64(100 * 1e8),
 		Quantity:    int64(1 * 1e8),
+		STPMode:     " expire_maker ",
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -779,6 +1212,9 @@ func TestCreateOrder_NormalizesFields(t *testing.T) {
 	if store.createdOrder.TimeInForce != 1 {
 		t.Fatalf("expected tif GTC=1, got %d", store.createdOrder.TimeInForce)
 	}
+	if store.createdOrder.STPMode != repository.STPExpireMaker {
+		t.Fatalf("expected stp mode EXPIRE_MAKER, got %d", store.createdOrder.STPMode)
+	}
 }
 
 func TestCreateOrder_NilRequest(t *testing.T) {
@@ -1264,11 +1700,8 @@ func TestCancelOrder_SendCancel(t *testing.T) {
 	}
 }
 
-func TestCancelOrder_ByClientID(t *testing.T) {
-	store := &cancelOrderStore{
-		order:    &repository.Order{OrderID: 11, UserID: 1, Status: repository.StatusNew},
-		byClient: true,
-	}
+func TestMassCancel_SendsPerSymbol(t *testing.T) {
+	store := &cancelOrderStore{openSymbols: []string{"BTCUSDT", "ETHUSDT"}}
 
 	mr, err := miniredis.Run()
 	if err != nil {
@@ -1279,65 +1712,293 @@ func TestCancelOrder_ByClientID(t *testing.T) {
 	defer redisClient.Close()
 
 	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", nil, nil, nil)
-	resp, err := svc.CancelOrder(context.Background(), &CancelOrderRequest{UserID: 1, ClientOrderID: "c1"})
+	if resp, _ := svc.MassCancel(context.Background(), &MassCancelRequest{UserID: 1, Side: "LONG"}); resp.ErrorCode != "INVALID_PARAM" {
+		t.Fatalf("expected INVALID_PARAM, got %+v", resp)
+	}
+
+	resp, err := svc.MassCancel(context.Background(), &MassCancelRequest{UserID: 1, Side: "buy"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if resp.Order == nil || resp.Order.OrderID != 11 {
-		t.Fatal("expected order from client ID")
+	if resp.RequestID != 1 || len(resp.Symbols) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
 	}
-}
 
-func TestGetOrderAccess(t *testing.T) {
-	store := &cancelOrderStore{
-		order: &repository.Order{OrderID: 10, UserID: 1},
+	entries, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("xrange: %v", err)
 	}
-	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
-
-	if _, err := svc.GetOrder(context.Background(), 2, 10); !errors.Is(err, repository.ErrOrderNotFound) {
-		t.Fatalf("expected not found for wrong user, got %v", err)
+	if len(entries) != 2 {
+		t.Fatalf("expected one message per symbol, got %d", len(entries))
 	}
-	if order, err := svc.GetOrder(context.Background(), 1, 10); err != nil || order == nil {
-		t.Fatalf("expected order, got %v", err)
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(entries[1].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if msg.Type != "MASS_CANCEL" || msg.Symbol != "ETHUSDT" || msg.Side != "BUY" || msg.UserID != 1 || msg.RequestID != 1 {
+		t.Fatalf("unexpected message: %+v", msg)
 	}
-}
-
-func TestGetOrder_NotFound(t *testing.T) {
-	store := &cancelOrderStore{getOrderErr: repository.ErrOrderNotFound}
-	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
 
-	if _, err := svc.GetOrder(context.Background(), 1, 10); err == nil {
-		t.Fatal("expected error when order not found")
+	// 指定交易对时不查询当前委托
+	store.openSymbols = nil
+	resp, err = svc.MassCancel(context.Background(), &MassCancelRequest{UserID: 1, Symbol: "btcusdt"})
+	if err != nil || len(resp.Symbols) != 1 || resp.Symbols[0] != "BTCUSDT" {
+		t.Fatalf("unexpected symbol mass cancel: %+v err=%v", resp, err)
 	}
 }
 
-func TestListOpenOrdersLimitDefault(t *testing.T) {
-	store := &cancelOrderStore{}
-	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
+func TestMassCancel_RoutesToShardStreams(t *testing.T) {
+	store := &cancelOrderStore{openSymbols: []string{"BTCUSDT", "ETHUSDT"}}
 
-	if _, err := svc.ListOpenOrders(context.Background(), 1, "BTCUSDT", 0); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if store.lastOpenLimit != 100 {
-		t.Fatalf("expected default limit 100, got %d", store.lastOpenLimit)
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
 	}
-}
+	defer mr.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
 
-func TestListOrdersDefaults(t *testing.T) {
-	store := &cancelOrderStore{}
-	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
+	// Redis 映射优先于静态分配
+	mr.HSet("matching:shards", "ETHUSDT", "0")
+	router, err := shard.NewRouter(shard.Config{Count: 2, Assignments: "BTCUSDT=1,ETHUSDT=1", MapKey: "matching:shards"}, redisClient)
+	if err != nil {
+		t.Fatalf("new router: %v", err)
+	}
+	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", nil, nil, nil)
+	svc.SetShardRouter(router)
 
-	if _, err := svc.ListOrders(context.Background(), 1, "BTCUSDT", 0, 0, 2000); err != nil {
+	if _, err := svc.MassCancel(context.Background(), &MassCancelRequest{UserID: 1}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if store.lastListLimit != 500 {
-		t.Fatalf("expected default limit 500, got %d", store.lastListLimit)
-	}
-	if store.lastEnd == 0 || store.lastStart == 0 {
-		t.Fatal("expected default start/end time set")
+	for stream, symbol := range map[string]string{"orders:0": "ETHUSDT", "orders:1": "BTCUSDT"} {
+		entries, err := redisClient.XRange(context.Background(), stream, "-", "+").Result()
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("expected one message on %s, got %d (%v)", stream, len(entries), err)
+		}
+		var msg OrderMessage
+		if err := json.Unmarshal([]byte(entries[0].Values["data"].(string)), &msg); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if msg.Symbol != symbol {
+			t.Fatalf("expected %s on %s, got %+v", symbol, stream, msg)
+		}
 	}
-	if store.lastEnd-store.lastStart != int64(7*24*3600*1000) {
-		t.Fatalf("expected 7 days window, got %d", store.lastEnd-store.lastStart)
+	if n, _ := redisClient.XLen(context.Background(), "orders").Result(); n != 0 {
+		t.Fatalf("expected nothing on the unsharded stream, got %d", n)
+	}
+}
+
+func TestCancelOrder_ByClientID(t *testing.T) {
+	store := &cancelOrderStore{
+		order:    &repository.Order{OrderID: 11, UserID: 1, Status: repository.StatusNew},
+		byClient: true,
+	}
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", nil, nil, nil)
+	resp, err := svc.CancelOrder(context.Background(), &CancelOrderRequest{UserID: 1, ClientOrderID: "c1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Order == nil || resp.Order.OrderID != 11 {
+		t.Fatal("expected order from client ID")
+	}
+}
+
+func amendSymbolConfig() *repository.SymbolConfig {
+	return &repository.SymbolConfig{
+		Symbol:         "BTCUSDT",
+		BaseAsset:      "BTC",
+		QuoteAsset:     "USDT",
+		PricePrecision: 2,
+		QtyPrecision:   2,
+		BasePrecision:  2,
+		QuotePrecision: 2,
+		MinQty:         "0.01",
+		MaxQty:         "1000",
+		MinNotional:    "1",
+		PriceTick:      "0.01",
+		QtyStep:        "0.01",
+		Status:         1,
+	}
+}
+
+func TestAmendOrder_Validation(t *testing.T) {
+	base := func() *repository.Order {
+		return &repository.Order{
+			OrderID: 10, UserID: 1, Symbol: "BTCUSDT", Side: repository.SideBuy, Type: repository.TypeLimit,
+			TimeInForce: 1, Price: "10000", OrigQty: "1000", ExecutedQty: "400", CumulativeQuoteQty: "40000",
+			Status: repository.StatusPartiallyFilled,
+		}
+	}
+	cases := []struct {
+		name   string
+		modify func(o *repository.Order)
+		req    AmendOrderRequest
+		want   string
+	}{
+		{"wrong user", nil, AmendOrderRequest{UserID: 2, OrderID: 10, Quantity: 800}, "ORDER_NOT_FOUND"},
+		{"no change", nil, AmendOrderRequest{UserID: 1, OrderID: 10, Price: 10000, Quantity: 1000}, "AMEND_NO_CHANGE"},
+		{"empty", nil, AmendOrderRequest{UserID: 1, OrderID: 10}, "INVALID_PARAM"},
+		{"below executed", nil, AmendOrderRequest{UserID: 1, OrderID: 10, Quantity: 400}, "INVALID_AMEND_QTY"},
+		{"negative price", nil, AmendOrderRequest{UserID: 1, OrderID: 10, Price: -1}, "INVALID_PARAM"},
+		{"filled", func(o *repository.Order) { o.Status = repository.StatusFilled }, AmendOrderRequest{UserID: 1, OrderID: 10, Quantity: 800}, "ORDER_ALREADY_FILLED"},
+		{"market", func(o *repository.Order) { o.Type = repository.TypeMarket }, AmendOrderRequest{UserID: 1, OrderID: 10, Quantity: 800}, "AMEND_NOT_ALLOWED"},
+		{"untriggered stop", func(o *repository.Order) { o.Type = repository.TypeStopLossLimit }, AmendOrderRequest{UserID: 1, OrderID: 10, Quantity: 800}, "AMEND_NOT_ALLOWED"},
+		{"pending", func(o *repository.Order) { o.PendingAmendID = 3 }, AmendOrderRequest{UserID: 1, OrderID: 10, Quantity: 800}, "AMEND_IN_PROGRESS"},
+	}
+	for _, tc := range cases {
+		order := base()
+		if tc.modify != nil {
+			tc.modify(order)
+		}
+		store := &cancelOrderStore{order: order, cfg: amendSymbolConfig()}
+		svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
+		resp, err := svc.AmendOrder(context.Background(), &tc.req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if resp.ErrorCode != tc.want {
+			t.Fatalf("%s: expected %s, got %q", tc.name, tc.want, resp.ErrorCode)
+		}
+		if store.beganAmendID != 0 {
+			t.Fatalf("%s: amend should not be registered", tc.name)
+		}
+	}
+}
+
+func TestAmendOrder_FreezesDeltaAndSends(t *testing.T) {
+	store := &cancelOrderStore{
+		order: &repository.Order{
+			OrderID: 10, UserID: 1, Symbol: "BTCUSDT", Side: repository.SideBuy, Type: repository.TypeLimit,
+			TimeInForce: 1, Price: "10000", OrigQty: "1000", ExecutedQty: "400", CumulativeQuoteQty: "40000",
+			Status: repository.StatusPartiallyFilled,
+		},
+		cfg: amendSymbolConfig(),
+	}
+
+	var freezeReq client.FreezeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&freezeReq)
+		_ = json.NewEncoder(w).Encode(client.FreezeResponse{Success: true})
+	}))
+	defer server.Close()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", nil, client.NewClearingClient(server.URL, "internal-token"), nil)
+	resp, err := svc.AmendOrder(context.Background(), &AmendOrderRequest{UserID: 1, OrderID: 10, Price: 12000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" || resp.AmendID != 1 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	// 原冻结 1000.00 - 已成交 400.00 = 600.00；新剩余 6@120 需 720.00，追加冻结 120.00
+	if store.beganAmendID != 1 || store.beganFreeze != 12000 {
+		t.Fatalf("unexpected begin amend: id=%d freeze=%d", store.beganAmendID, store.beganFreeze)
+	}
+	if freezeReq.Amount != 12000 || freezeReq.Asset != "USDT" || freezeReq.IdempotencyKey != "freeze:order:10:amend:1" {
+		t.Fatalf("unexpected freeze request: %+v", freezeReq)
+	}
+
+	msgs, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected one matching message, got %d (%v)", len(msgs), err)
+	}
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(msgs[0].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if msg.Type != "AMEND" || msg.AmendID != 1 || msg.Price != 12000 || msg.Qty != 1000 {
+		t.Fatalf("unexpected amend message: %+v", msg)
+	}
+}
+
+func TestAmendOrder_InProgress(t *testing.T) {
+	store := &cancelOrderStore{
+		order: &repository.Order{
+			OrderID: 10, UserID: 1, Symbol: "BTCUSDT", Side: repository.SideSell, Type: repository.TypeLimit,
+			TimeInForce: 1, Price: "10000", OrigQty: "1000", ExecutedQty: "0", CumulativeQuoteQty: "0",
+			Status: repository.StatusNew,
+		},
+		cfg:           amendSymbolConfig(),
+		beginAmendErr: repository.ErrAmendInProgress,
+	}
+	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, client.NewClearingClient("http://127.0.0.1:0", "internal-token"), nil)
+	resp, err := svc.AmendOrder(context.Background(), &AmendOrderRequest{UserID: 1, OrderID: 10, Quantity: 500})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "AMEND_IN_PROGRESS" {
+		t.Fatalf("expected AMEND_IN_PROGRESS, got %q", resp.ErrorCode)
+	}
+}
+
+func TestGetOrderAccess(t *testing.T) {
+	store := &cancelOrderStore{
+		order: &repository.Order{OrderID: 10, UserID: 1},
+	}
+	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
+
+	if _, err := svc.GetOrder(context.Background(), 2, 10); !errors.Is(err, repository.ErrOrderNotFound) {
+		t.Fatalf("expected not found for wrong user, got %v", err)
+	}
+	if order, err := svc.GetOrder(context.Background(), 1, 10); err != nil || order == nil {
+		t.Fatalf("expected order, got %v", err)
+	}
+}
+
+func TestGetOrder_NotFound(t *testing.T) {
+	store := &cancelOrderStore{getOrderErr: repository.ErrOrderNotFound}
+	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
+
+	if _, err := svc.GetOrder(context.Background(), 1, 10); err == nil {
+		t.Fatal("expected error when order not found")
+	}
+}
+
+func TestListOpenOrdersLimitDefault(t *testing.T) {
+	store := &cancelOrderStore{}
+	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
+
+	if _, err := svc.ListOpenOrders(context.Background(), 1, "BTCUSDT", 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.lastOpenLimit != 100 {
+		t.Fatalf("expected default limit 100, got %d", store.lastOpenLimit)
+	}
+}
+
+func TestListOrdersDefaults(t *testing.T) {
+	store := &cancelOrderStore{}
+	svc := NewOrderService(store, nil, &mockIDGen{}, "orders", nil, nil, nil)
+
+	if _, err := svc.ListOrders(context.Background(), 1, "BTCUSDT", 0, 0, 2000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.lastListLimit != 500 {
+		t.Fatalf("expected default limit 500, got %d", store.lastListLimit)
+	}
+	if store.lastEnd == 0 || store.lastStart == 0 {
+		t.Fatal("expected default start/end time set")
+	}
+	if store.lastEnd-store.lastStart != int64(7*24*3600*1000) {
+		t.Fatalf("expected 7 days window, got %d", store.lastEnd-store.lastStart)
 	}
 }
 
@@ -1552,12 +2213,11 @@ func TestPriceCreateOrder_MarketSkipsCheck(t *testing.T) {
 
 	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", validator, clearingClient, nil)
 	resp, err := svc.CreateOrder(context.Background(), &CreateOrderRequest{
-		UserID:        1,
-		Symbol:        "BTCUSDT",
-		Side:          "BUY",
-		Type:          "MARKET",
-		Quantity:      int64(1 * 1e8),
-		QuoteOrderQty: int64(100 * 1e8),
+		UserID:   1,
+		Symbol:   "BTCUSDT",
+		Side:     "BUY",
+		Type:     "MARKET",
+		Quantity: int64(1 * 1e8),
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -1602,3 +2262,311 @@ func setupOrderDependencies(t *testing.T) (*redis.Client, *client.ClearingClient
 
 	return redisClient, clearingClient, cleanup
 }
+
+func TestCreateOrder_StopMarketBuyFreezesBufferedStopPrice(t *testing.T) {
+	store := &mockOrderStore{
+		cfg: &repository.SymbolConfig{
+			Symbol:         "BTCUSDT",
+			BaseAsset:      "BTC",
+			QuoteAsset:     "USDT",
+			PricePrecision: 8,
+			QtyPrecision:   8,
+			BasePrecision:  8,
+			QuotePrecision: 8,
+			MinQty:         "0.001",
+			MaxQty:         "10.0",
+			MinNotional:    "10.0",
+			PriceTick:      "0.01",
+			QtyStep:        "0.001",
+			PriceLimitRate: "0.1",
+			Status:         1,
+		},
+	}
+
+	var freezeReq client.FreezeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&freezeReq)
+		_ = json.NewEncoder(w).Encode(client.FreezeResponse{Success: true})
+	}))
+	defer server.Close()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", nil, client.NewClearingClient(server.URL, "internal-token"), nil)
+	resp, err := svc.CreateOrder(context.Background(), &CreateOrderRequest{
+		UserID:    1,
+		Symbol:    "BTCUSDT",
+		Side:      "BUY",
+		Type:      "STOP_LOSS",
+		StopPrice: int64(100 * 1e8),
+		Quantity:  int64(1 * 1e8),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" {
+		t.Fatalf("expected empty error code, got %s", resp.ErrorCode)
+	}
+
+	// 100 * (1 + 0.1) = 110
+	if freezeReq.Asset != "USDT" || freezeReq.Amount != int64(110*1e8) {
+		t.Fatalf("unexpected freeze: asset=%s amount=%d", freezeReq.Asset, freezeReq.Amount)
+	}
+	order := store.createdOrder
+	if order.Type != repository.TypeStopLoss || order.TimeInForce != 2 || order.StopPrice != strconv.FormatInt(int64(100*1e8), 10) {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+
+	entries, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one matching message, got %d (%v)", len(entries), err)
+	}
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(entries[0].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if msg.OrderType != "STOP_LOSS" || msg.StopPrice != int64(100*1e8) || msg.TimeInForce != "IOC" {
+		t.Fatalf("unexpected matching message: %+v", msg)
+	}
+}
+
+func TestCreateOrder_TrailingStopBuyFreezesFromActivationPrice(t *testing.T) {
+	store := &mockOrderStore{
+		cfg: &repository.SymbolConfig{
+			Symbol:         "BTCUSDT",
+			BaseAsset:      "BTC",
+			QuoteAsset:     "USDT",
+			PricePrecision: 8,
+			QtyPrecision:   8,
+			BasePrecision:  8,
+			QuotePrecision: 8,
+			MinQty:         "0.001",
+			MaxQty:         "10.0",
+			MinNotional:    "10.0",
+			PriceTick:      "0.01",
+			QtyStep:        "0.001",
+			PriceLimitRate: "0.1",
+			Status:         1,
+		},
+	}
+
+	var freezeReq client.FreezeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&freezeReq)
+		_ = json.NewEncoder(w).Encode(client.FreezeResponse{Success: true})
+	}))
+	defer server.Close()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", nil, client.NewClearingClient(server.URL, "internal-token"), nil)
+	resp, err := svc.CreateOrder(context.Background(), &CreateOrderRequest{
+		UserID:          1,
+		Symbol:          "BTCUSDT",
+		Side:            "BUY",
+		Type:            "TRAILING_STOP",
+		Quantity:        int64(1 * 1e8),
+		TrailingBps:     1000,
+		ActivationPrice: int64(100 * 1e8),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" {
+		t.Fatalf("expected empty error code, got %s", resp.ErrorCode)
+	}
+
+	// 最高触发价 100 + 10% = 110，再加保护幅度 110 * (1 + 0.1) = 121
+	if freezeReq.Asset != "USDT" || freezeReq.Amount != int64(121*1e8) {
+		t.Fatalf("unexpected freeze: asset=%s amount=%d", freezeReq.Asset, freezeReq.Amount)
+	}
+	order := store.createdOrder
+	if order.Type != repository.TypeTrailingStop || order.StopPrice != "0" || order.TrailingBps != 1000 || order.ActivationPrice != int64(100*1e8) {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+
+	entries, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one matching message, got %d (%v)", len(entries), err)
+	}
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(entries[0].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if msg.OrderType != "TRAILING_STOP" || msg.TrailingBps != 1000 || msg.ActivationPrice != int64(100*1e8) || msg.StopPrice != 0 {
+		t.Fatalf("unexpected matching message: %+v", msg)
+	}
+}
+
+func TestCreateOrder_MarketBuyByQuoteOrderQty(t *testing.T) {
+	store := &mockOrderStore{
+		cfg: &repository.SymbolConfig{
+			Symbol:         "BTCUSDT",
+			BaseAsset:      "BTC",
+			QuoteAsset:     "USDT",
+			PricePrecision: 8,
+			QtyPrecision:   8,
+			BasePrecision:  8,
+			QuotePrecision: 8,
+			MinQty:         "0.001",
+			MaxQty:         "10.0",
+			MinNotional:    "10.0",
+			PriceTick:      "0.01",
+			QtyStep:        "0.001",
+			Status:         1,
+		},
+	}
+
+	var freezeReq client.FreezeRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&freezeReq)
+		_ = json.NewEncoder(w).Encode(client.FreezeResponse{Success: true})
+	}))
+	defer server.Close()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	// 不需要参考价：冻结额即下单金额
+	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", nil, client.NewClearingClient(server.URL, "internal-token"), nil)
+	for _, tc := range []struct {
+		req  CreateOrderRequest
+		code string
+	}{
+		{CreateOrderRequest{Side: "SELL", Type: "MARKET", QuoteOrderQty: int64(100 * 1e8)}, "INVALID_QUOTE_ORDER_QTY"},
+		{CreateOrderRequest{Side: "BUY", Type: "LIMIT", Price: int64(100 * 1e8), QuoteOrderQty: int64(100 * 1e8)}, "INVALID_QUOTE_ORDER_QTY"},
+		{CreateOrderRequest{Side: "BUY", Type: "MARKET", Quantity: int64(1 * 1e8), QuoteOrderQty: int64(100 * 1e8)}, "INVALID_QUOTE_ORDER_QTY"},
+		{CreateOrderRequest{Side: "BUY", Type: "MARKET", TimeInForce: "FOK", QuoteOrderQty: int64(100 * 1e8)}, "INVALID_TIME_IN_FORCE"},
+		{CreateOrderRequest{Side: "BUY", Type: "MARKET", STPMode: "DECREMENT", QuoteOrderQty: int64(100 * 1e8)}, "INVALID_STP_MODE"},
+		{CreateOrderRequest{Side: "BUY", Type: "MARKET", QuoteOrderQty: int64(5 * 1e8)}, "NOTIONAL_TOO_SMALL"},
+	} {
+		req := tc.req
+		req.UserID, req.Symbol = 1, "BTCUSDT"
+		resp, err := svc.CreateOrder(context.Background(), &req)
+		if err != nil || resp.ErrorCode != tc.code {
+			t.Fatalf("%+v: expected %s, got %+v (%v)", tc.req, tc.code, resp, err)
+		}
+	}
+
+	resp, err := svc.CreateOrder(context.Background(), &CreateOrderRequest{
+		UserID:        1,
+		Symbol:        "BTCUSDT",
+		Side:          "BUY",
+		Type:          "MARKET",
+		QuoteOrderQty: int64(100 * 1e8),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" {
+		t.Fatalf("expected empty error code, got %s", resp.ErrorCode)
+	}
+	if freezeReq.Asset != "USDT" || freezeReq.Amount != int64(100*1e8) {
+		t.Fatalf("unexpected freeze: asset=%s amount=%d", freezeReq.Asset, freezeReq.Amount)
+	}
+	order := store.createdOrder
+	if order.QuoteOrderQty != int64(100*1e8) || order.OrigQty != "0" || order.Price != "0" || order.TimeInForce != 2 {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+
+	entries, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one matching message, got %d (%v)", len(entries), err)
+	}
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(entries[0].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if msg.OrderType != "MARKET" || msg.Qty != 0 || msg.QuoteOrderQty != int64(100*1e8) || msg.QtyScale != int64(1e8) {
+		t.Fatalf("unexpected matching message: %+v", msg)
+	}
+}
+
+func TestCreateOrder_GTDSendsExpireTime(t *testing.T) {
+	store := &mockOrderStore{
+		cfg: &repository.SymbolConfig{
+			Symbol:         "BTCUSDT",
+			BaseAsset:      "BTC",
+			QuoteAsset:     "USDT",
+			PricePrecision: 8,
+			QtyPrecision:   8,
+			BasePrecision:  8,
+			QuotePrecision: 8,
+			MinQty:         "0.001",
+			MaxQty:         "10.0",
+			MinNotional:    "10.0",
+			PriceTick:      "0.01",
+			QtyStep:        "0.001",
+			Status:         1,
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(client.FreezeResponse{Success: true})
+	}))
+	defer server.Close()
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	svc := NewOrderService(store, redisClient, &mockIDGen{}, "orders", nil, client.NewClearingClient(server.URL, "internal-token"), nil)
+
+	expireTime := time.Now().Add(time.Hour).UnixMilli()
+	resp, err := svc.CreateOrder(context.Background(), &CreateOrderRequest{
+		UserID:      1,
+		Symbol:      "BTCUSDT",
+		Side:        "SELL",
+		Type:        "LIMIT",
+		TimeInForce: "gtd",
+		Price:       int64(100 * 1e8),
+		Quantity:    int64(1 * 1e8),
+		ExpireTime:  expireTime,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" {
+		t.Fatalf("expected empty error code, got %s", resp.ErrorCode)
+	}
+	if order := store.createdOrder; order.TimeInForce != 5 || order.ExpireTimeMs != expireTime {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+
+	entries, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one matching message, got %d (%v)", len(entries), err)
+	}
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(entries[0].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if msg.TimeInForce != "GTD" || msg.ExpireTime != expireTime {
+		t.Fatalf("unexpected matching message: %+v", msg)
+	}
+}
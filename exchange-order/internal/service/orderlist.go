@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exchange/order/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// CreateOrderListRequest OCO 下单请求
+type CreateOrderListRequest struct {
+	UserID         int64
+	Symbol         string
+	Side           string // BUY / SELL，两条订单同向
+	Quantity       int64  // 两条订单同量
+	Price          int64  // 止盈限价单价格：卖出高于触发价，买入低于触发价
+	StopPrice      int64  // 止损触发价
+	StopLimitPrice int64  // 止损限价，0 表示触发后按市价成交（STOP_LOSS）
+	ClientListID   string
+	STPMode        string // 对两条订单生效
+}
+
+// CreateOrderListResponse OCO 下单响应（Orders 依次为限价单与止损单）
+type CreateOrderListResponse struct {
+	List      *repository.OrderList
+	Orders    []*repository.Order
+	ErrorCode string
+}
+
+// CreateOrderList 创建 OCO 订单列表（两条订单共用一次冻结：卖出冻结数量，买入按较大的冻结额）
+func (s *OrderService) CreateOrderList(ctx context.Context, req *CreateOrderListRequest) (*CreateOrderListResponse, error) {
+	if req == nil || req.UserID <= 0 {
+		return &CreateOrderListResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	req.Side = strings.ToUpper(strings.TrimSpace(req.Side))
+	req.ClientListID = strings.TrimSpace(req.ClientListID)
+	req.STPMode = strings.ToUpper(strings.TrimSpace(req.STPMode))
+
+	cfg, err := s.repo.GetSymbolConfig(ctx, req.Symbol)
+	if err != nil {
+		return &CreateOrderListResponse{ErrorCode: "SYMBOL_NOT_FOUND"}, nil
+	}
+	switch cfg.Status {
+	case repository.SymbolStatusTrading:
+	case repository.SymbolStatusAuction:
+		return &CreateOrderListResponse{ErrorCode: "AUCTION_ORDER_NOT_ALLOWED"}, nil
+	default:
+		return &CreateOrderListResponse{ErrorCode: "SYMBOL_NOT_TRADING"}, nil
+	}
+
+	// 1. 逐单校验（与普通限价单、止损单规则一致），止盈价须在触发价的盈利一侧
+	stopType := "STOP_LOSS"
+	if req.StopLimitPrice > 0 {
+		stopType = "STOP_LOSS_LIMIT"
+	}
+	limitReq := &CreateOrderRequest{
+		UserID: req.UserID, Symbol: req.Symbol, Side: req.Side, Type: "LIMIT", TimeInForce: "GTC",
+		Price: req.Price, Quantity: req.Quantity, STPMode: req.STPMode,
+	}
+	stopReq := &CreateOrderRequest{
+		UserID: req.UserID, Symbol: req.Symbol, Side: req.Side, Type: stopType, TimeInForce: "GTC",
+		Price: req.StopLimitPrice, StopPrice: req.StopPrice, Quantity: req.Quantity, STPMode: req.STPMode,
+	}
+	for _, legReq := range []*CreateOrderRequest{limitReq, stopReq} {
+		if err := s.validateOrder(legReq, cfg); err != nil {
+			return &CreateOrderListResponse{ErrorCode: err.Error()}, nil
+		}
+	}
+	if (req.Side == "SELL" && req.Price <= req.StopPrice) || (req.Side == "BUY" && req.Price >= req.StopPrice) {
+		return &CreateOrderListResponse{ErrorCode: "INVALID_STOP_PRICE"}, nil
+	}
+	if s.validator != nil {
+		if err := s.validator.ValidatePrice(req.Symbol, req.Side, req.Price); err != nil {
+			return &CreateOrderListResponse{ErrorCode: err.Error()}, nil
+		}
+		// 触发价已被最新价越过时止损单会立即触发
+		if ref, err := s.validator.ReferencePrice(req.Symbol); err == nil && ref > 0 {
+			if (req.Side == "SELL" && req.StopPrice >= ref) || (req.Side == "BUY" && req.StopPrice <= ref) {
+				return &CreateOrderListResponse{ErrorCode: "INVALID_STOP_PRICE"}, nil
+			}
+		}
+	}
+
+	// 2. 幂等检查
+	if req.ClientListID != "" {
+		if existing, err := s.repo.GetOrderListByClientID(ctx, req.UserID, req.ClientListID); err == nil {
+			return s.orderListResponse(ctx, existing)
+		}
+	}
+
+	// 下单前风控：两条订单分别计入，列表未送达撮合时一并退回
+	var riskOrders []*RiskOrder
+	sent := false
+	defer func() {
+		if !sent {
+			s.releaseRisk(ctx, riskOrders...)
+		}
+	}()
+	for i, legReq := range []*CreateOrderRequest{limitReq, stopReq} {
+		riskOrder, code, err := s.checkRisk(ctx, legReq, cfg, i)
+		if err != nil {
+			return nil, err
+		}
+		if code != "" {
+			return &CreateOrderListResponse{ErrorCode: code}, nil
+		}
+		riskOrders = append(riskOrders, riskOrder)
+	}
+
+	// 3. 计算共用冻结额
+	now := time.Now().UnixMilli()
+	newLeg := func(orderType string, price, stopPrice int64) *repository.Order {
+		return &repository.Order{
+			OrderID:            s.idGen.NextID(),
+			UserID:             req.UserID,
+			Symbol:             req.Symbol,
+			Side:               parseSide(req.Side),
+			Type:               parseType(orderType),
+			TimeInForce:        parseTIF("GTC"),
+			Price:              strconv.FormatInt(price, 10),
+			StopPrice:          strconv.FormatInt(stopPrice, 10),
+			OrigQty:            strconv.FormatInt(req.Quantity, 10),
+			ExecutedQty:        "0",
+			CumulativeQuoteQty: "0",
+			Status:             repository.StatusInit,
+			STPMode:            parseSTPMode(req.STPMode),
+			CreateTimeMs:       now,
+			UpdateTimeMs:       now,
+		}
+	}
+	list := &repository.OrderList{
+		ListID:          s.idGen.NextID(),
+		ClientListID:    req.ClientListID,
+		UserID:          req.UserID,
+		Symbol:          req.Symbol,
+		ContingencyType: repository.ContingencyTypeOCO,
+		Side:            parseSide(req.Side),
+		Status:          repository.OrderListStatusExecuting,
+		CreateTimeMs:    now,
+		UpdateTimeMs:    now,
+	}
+	limitOrder := newLeg("LIMIT", req.Price, 0)
+	stopOrder := newLeg(stopType, req.StopLimitPrice, req.StopPrice)
+	if list.Side == repository.SideBuy {
+		stopAmount := quoteQty(req.StopLimitPrice, req.Quantity, cfg.QtyPrecision)
+		if stopType == "STOP_LOSS" {
+			// 市价止损买单按触发价加保护幅度冻结
+			bufferedPrice, quoteAmount, err := bufferedQuoteAmount(req.StopPrice, req.Quantity, cfg)
+			if err != nil {
+				return &CreateOrderListResponse{ErrorCode: "INVALID_STOP_PRICE"}, nil
+			}
+			stopOrder.Price = strconv.FormatInt(bufferedPrice, 10)
+			stopAmount = quoteAmount
+		}
+		list.FreezeAsset = cfg.QuoteAsset
+		list.FrozenAmount = max(quoteQty(req.Price, req.Quantity, cfg.QtyPrecision), stopAmount)
+	} else {
+		list.FreezeAsset = cfg.BaseAsset
+		list.FrozenAmount = req.Quantity
+	}
+	orders := []*repository.Order{limitOrder, stopOrder}
+	if s.clearing == nil {
+		return nil, fmt.Errorf("clearing client not configured")
+	}
+
+	// 4. 列表与订单同一事务落库，再冻结
+	if err := s.repo.CreateOrderList(ctx, list, orders); err != nil {
+		if errors.Is(err, repository.ErrDuplicateClientOrderID) && req.ClientListID != "" {
+			if existing, fetchErr := s.repo.GetOrderListByClientID(ctx, req.UserID, req.ClientListID); fetchErr == nil {
+				return s.orderListResponse(ctx, existing)
+			}
+		}
+		return nil, fmt.Errorf("create order list: %w", err)
+	}
+
+	freezeKey := fmt.Sprintf("freeze:list:%d", list.ListID)
+	freezeResp, err := s.clearing.FreezeBalance(ctx, req.UserID, list.FreezeAsset, list.FrozenAmount, freezeKey)
+	if err != nil {
+		return nil, fmt.Errorf("freeze balance: %w", err)
+	}
+	if freezeResp == nil || !freezeResp.Success {
+		code := "FREEZE_FAILED"
+		if freezeResp != nil && freezeResp.ErrorCode != "" {
+			code = freezeResp.ErrorCode
+		}
+		if err := s.rejectOrderList(ctx, list, orders, code); err != nil {
+			return nil, fmt.Errorf("reject order list: %w", err)
+		}
+		return &CreateOrderListResponse{ErrorCode: code}, nil
+	}
+
+	// 5. 更新状态为 NEW 并发送到撮合
+	updateTime := time.Now().UnixMilli()
+	for _, order := range orders {
+		if err := s.repo.UpdateOrderStatus(ctx, order.OrderID, repository.StatusNew, 0, 0, updateTime); err != nil {
+			s.compensateOrderListFailure(ctx, list, orders, "update_status_failed")
+			return nil, fmt.Errorf("update order status: %w", err)
+		}
+		order.Status = repository.StatusNew
+		order.UpdateTimeMs = updateTime
+	}
+	if err := s.sendOrderListToMatching(ctx, orders); err != nil {
+		s.compensateOrderListFailure(ctx, list, orders, "send_matching_failed")
+		return nil, fmt.Errorf("send order list to matching: %w", err)
+	}
+	sent = true
+
+	if s.metrics != nil {
+		for _, order := range orders {
+			s.metrics.IncOrderCreated(order.Symbol, sideToString(order.Side))
+		}
+	}
+	if s.publisher != nil {
+		for _, order := range orders {
+			if err := s.publisher.PublishOrderCreated(ctx, order.UserID, order); err != nil {
+				log.Printf("publish order created error: %v", err)
+			}
+		}
+	}
+	return &CreateOrderListResponse{List: list, Orders: orders}, nil
+}
+
+func (s *OrderService) orderListResponse(ctx context.Context, list *repository.OrderList) (*CreateOrderListResponse, error) {
+	orders, err := s.repo.ListOrderListOrders(ctx, list.ListID)
+	if err != nil {
+		return nil, fmt.Errorf("list order list orders: %w", err)
+	}
+	return &CreateOrderListResponse{List: list, Orders: orders}, nil
+}
+
+// rejectOrderList 拒绝列表全部订单并将列表置为拒绝（冻结失败或未送达撮合）
+func (s *OrderService) rejectOrderList(ctx context.Context, list *repository.OrderList, orders []*repository.Order, reason string) error {
+	for _, order := range orders {
+		if err := s.rejectOrder(ctx, order.OrderID, reason); err != nil {
+			return err
+		}
+		order.Status = repository.StatusRejected
+		order.RejectReason = reason
+	}
+	err := s.repo.FinishOrderList(ctx, list.ListID, repository.OrderListStatusRejected, time.Now().UnixMilli())
+	if err != nil && !errors.Is(err, repository.ErrOrderListNotFound) {
+		return err
+	}
+	list.Status = repository.OrderListStatusRejected
+	return nil
+}
+
+// compensateOrderListFailure 列表未送达撮合：解冻列表冻结并拒绝全部订单
+func (s *OrderService) compensateOrderListFailure(ctx context.Context, list *repository.OrderList, orders []*repository.Order, reason string) {
+	var errs []string
+	key := fmt.Sprintf("unfreeze:list:%d:%s", list.ListID, reason)
+	resp, err := s.clearing.UnfreezeBalance(ctx, list.UserID, list.FreezeAsset, list.FrozenAmount, key)
+	if err == nil && resp != nil && !resp.Success {
+		err = fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("rollback freeze: %v", err))
+	}
+	if err := s.rejectOrderList(ctx, list, orders, "INTERNAL_ERROR"); err != nil {
+		errs = append(errs, fmt.Sprintf("reject order list: %v", err))
+	}
+	if len(errs) > 0 {
+		log.Printf("compensate order list failure (%s) error: %s", reason, strings.Join(errs, "; "))
+	}
+}
+
+// GetOrderList 获取订单列表及其订单
+func (s *OrderService) GetOrderList(ctx context.Context, userID, listID int64) (*repository.OrderList, []*repository.Order, error) {
+	list, err := s.repo.GetOrderList(ctx, listID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if list.UserID != userID {
+		return nil, nil, repository.ErrOrderListNotFound
+	}
+	orders, err := s.repo.ListOrderListOrders(ctx, listID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return list, orders, nil
+}
+
+// CancelOrderListRequest 撤销订单列表请求
+type CancelOrderListRequest struct {
+	UserID       int64
+	ListID       int64
+	ClientListID string
+}
+
+// CancelOrderList 撤销订单列表中仍在执行的订单（撤单结果由撮合异步确认）
+func (s *OrderService) CancelOrderList(ctx context.Context, req *CancelOrderListRequest) (*CreateOrderListResponse, error) {
+	var list *repository.OrderList
+	var err error
+	if req.ListID > 0 {
+		list, err = s.repo.GetOrderList(ctx, req.ListID)
+	} else if req.ClientListID != "" {
+		list, err = s.repo.GetOrderListByClientID(ctx, req.UserID, req.ClientListID)
+	} else {
+		return &CreateOrderListResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	if err != nil || list.UserID != req.UserID {
+		return &CreateOrderListResponse{ErrorCode: "ORDER_LIST_NOT_FOUND"}, nil
+	}
+
+	orders, err := s.repo.ListOrderListOrders(ctx, list.ListID)
+	if err != nil {
+		return nil, fmt.Errorf("list order list orders: %w", err)
+	}
+	for _, order := range orders {
+		if order.Status != repository.StatusNew && order.Status != repository.StatusPartiallyFilled {
+			continue
+		}
+		if err := s.sendCancelToMatching(ctx, order); err != nil {
+			return nil, fmt.Errorf("send cancel to matching: %w", err)
+		}
+	}
+	return &CreateOrderListResponse{List: list, Orders: orders}, nil
+}
+
+// SendCancel 发送撤单到撮合（订单列表联动撤单）
+func (s *OrderService) SendCancel(ctx context.Context, order *repository.Order) error {
+	return s.sendCancelToMatching(ctx, order)
+}
+
+// sendOrderListToMatching 在同一个 MULTI 中写入列表全部订单，联动撤单因此总是排在全部订单之后
+//
+// OCO 两条订单互为对方的 SiblingOrderID，由撮合在成交或触发时直接撤销。
+func (s *OrderService) sendOrderListToMatching(ctx context.Context, orders []*repository.Order) error {
+	msgs := make([]*OrderMessage, 0, len(orders))
+	for i, order := range orders {
+		msg, err := s.newOrderMessage(ctx, order)
+		if err != nil {
+			return err
+		}
+		msg.ListID = order.ListID
+		if len(orders) == 2 {
+			msg.SiblingOrderID = orders[1-i].OrderID
+		}
+		msgs = append(msgs, msg)
+	}
+	return s.sendMessagesToMatching(ctx, orders[0].Symbol, msgs)
+}
+
+// sendMessagesToMatching 在同一个 MULTI 中向交易对所属订单流写入一组消息（全部写入或全部不写入）
+func (s *OrderService) sendMessagesToMatching(ctx context.Context, symbol string, msgs []*OrderMessage) error {
+	if s.redis == nil {
+		return fmt.Errorf("redis client not configured")
+	}
+	payloads := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		payloads = append(payloads, string(data))
+	}
+
+	stream, err := s.matchingStream(ctx, symbol)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, data := range payloads {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: stream,
+				Values: map[string]interface{}{
+					"data": data,
+				},
+			})
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/exchange/order/internal/client"
+	"github.com/exchange/order/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+func newOrderListTestService(t *testing.T, store OrderStore, freezeResp client.FreezeResponse) (*OrderService, *redis.Client, *client.FreezeRequest) {
+	t.Helper()
+	var freezeReq client.FreezeRequest
+	svc, redisClient := newClearingTestService(t, store, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/internal/freeze" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&freezeReq)
+		_ = json.NewEncoder(w).Encode(freezeResp)
+	})
+	return svc, redisClient, &freezeReq
+}
+
+func TestCreateOrderList_SellFreezesOnceAndSendsBothLegs(t *testing.T) {
+	store := &mockOrderStore{cfg: amendSymbolConfig()}
+	svc, redisClient, freezeReq := newOrderListTestService(t, store, client.FreezeResponse{Success: true})
+
+	resp, err := svc.CreateOrderList(context.Background(), &CreateOrderListRequest{
+		UserID:         1,
+		Symbol:         "btcusdt",
+		Side:           "sell",
+		Quantity:       100,
+		Price:          11000,
+		StopPrice:      9000,
+		StopLimitPrice: 8900,
+		ClientListID:   "oco-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" || resp.List == nil || len(resp.Orders) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.List.FreezeAsset != "BTC" || resp.List.FrozenAmount != 100 || resp.List.ContingencyType != repository.ContingencyTypeOCO {
+		t.Fatalf("unexpected list: %+v", resp.List)
+	}
+	if freezeReq.Asset != "BTC" || freezeReq.Amount != 100 || freezeReq.IdempotencyKey != "freeze:list:1" {
+		t.Fatalf("unexpected freeze request: %+v", freezeReq)
+	}
+	limitLeg, stopLeg := resp.Orders[0], resp.Orders[1]
+	if limitLeg.Type != repository.TypeLimit || stopLeg.Type != repository.TypeStopLossLimit || stopLeg.StopPrice != "9000" {
+		t.Fatalf("unexpected legs: %+v %+v", limitLeg, stopLeg)
+	}
+	if limitLeg.ListID != 1 || stopLeg.ListID != 1 || limitLeg.Status != repository.StatusNew {
+		t.Fatalf("expected legs linked to list and NEW: %+v %+v", limitLeg, stopLeg)
+	}
+
+	msgs, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("expected two matching messages, got %d (%v)", len(msgs), err)
+	}
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(msgs[1].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if msg.OrderID != stopLeg.OrderID || msg.OrderType != "STOP_LOSS_LIMIT" || msg.StopPrice != 9000 || msg.Price != 8900 {
+		t.Fatalf("unexpected stop leg message: %+v", msg)
+	}
+	if msg.ListID != 1 || msg.SiblingOrderID != limitLeg.OrderID {
+		t.Fatalf("expected stop leg linked to the limit leg: %+v", msg)
+	}
+	var limitMsg OrderMessage
+	if err := json.Unmarshal([]byte(msgs[0].Values["data"].(string)), &limitMsg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if limitMsg.ListID != 1 || limitMsg.SiblingOrderID != stopLeg.OrderID {
+		t.Fatalf("expected limit leg linked to the stop leg: %+v", limitMsg)
+	}
+}
+
+func TestCreateOrderList_BuyFreezesLargerLeg(t *testing.T) {
+	store := &mockOrderStore{cfg: amendSymbolConfig()}
+	svc, _, freezeReq := newOrderListTestService(t, store, client.FreezeResponse{Success: true})
+
+	resp, err := svc.CreateOrderList(context.Background(), &CreateOrderListRequest{
+		UserID:         1,
+		Symbol:         "BTCUSDT",
+		Side:           "BUY",
+		Quantity:       100,
+		Price:          9000,
+		StopPrice:      11000,
+		StopLimitPrice: 11100,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" {
+		t.Fatalf("unexpected error code: %s", resp.ErrorCode)
+	}
+	// 限价单需 90.00，止损限价单需 111.00，按较大者冻结
+	if freezeReq.Asset != "USDT" || freezeReq.Amount != 11100 || resp.List.FrozenAmount != 11100 {
+		t.Fatalf("unexpected freeze request: %+v", freezeReq)
+	}
+}
+
+func TestCreateOrderList_FreezeRejectedRejectsList(t *testing.T) {
+	store := &mockOrderStore{cfg: amendSymbolConfig()}
+	svc, redisClient, _ := newOrderListTestService(t, store, client.FreezeResponse{ErrorCode: "INSUFFICIENT_BALANCE"})
+
+	resp, err := svc.CreateOrderList(context.Background(), &CreateOrderListRequest{
+		UserID: 1, Symbol: "BTCUSDT", Side: "SELL", Quantity: 100, Price: 11000, StopPrice: 9000,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("expected INSUFFICIENT_BALANCE, got %+v", resp)
+	}
+	if store.rejectCalls != 2 || store.finishedListStatus != repository.OrderListStatusRejected {
+		t.Fatalf("expected both legs and list rejected, got %d/%d", store.rejectCalls, store.finishedListStatus)
+	}
+	if n, _ := redisClient.XLen(context.Background(), "orders").Result(); n != 0 {
+		t.Fatalf("expected nothing sent to matching, got %d", n)
+	}
+}
+
+func TestCreateOrderList_Validation(t *testing.T) {
+	store := &mockOrderStore{cfg: amendSymbolConfig()}
+	svc, _, _ := newOrderListTestService(t, store, client.FreezeResponse{Success: true})
+
+	cases := []struct {
+		req  *CreateOrderListRequest
+		code string
+	}{
+		{req: nil, code: "INVALID_PARAM"},
+		{req: &CreateOrderListRequest{UserID: 1, Symbol: "BTCUSDT", Side: "HOLD", Quantity: 100, Price: 11000, StopPrice: 9000}, code: "INVALID_SIDE"},
+		{req: &CreateOrderListRequest{UserID: 1, Symbol: "BTCUSDT", Side: "SELL", Quantity: 100, Price: 11000}, code: "INVALID_STOP_PRICE"},
+		// 止盈价须在触发价的盈利一侧
+		{req: &CreateOrderListRequest{UserID: 1, Symbol: "BTCUSDT", Side: "SELL", Quantity: 100, Price: 9000, StopPrice: 11000}, code: "INVALID_STOP_PRICE"},
+		{req: &CreateOrderListRequest{UserID: 1, Symbol: "BTCUSDT", Side: "BUY", Quantity: 100, Price: 11000, StopPrice: 9000}, code: "INVALID_STOP_PRICE"},
+	}
+	for _, tc := range cases {
+		resp, err := svc.CreateOrderList(context.Background(), tc.req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.ErrorCode != tc.code {
+			t.Fatalf("expected %s, got %+v", tc.code, resp)
+		}
+	}
+	if store.createdList != nil {
+		t.Fatal("expected nothing created on validation failure")
+	}
+}
+
+type fakeOrderLists struct {
+	list      *repository.OrderList
+	legs      []*repository.Order
+	triggered int64
+	finished  int
+}
+
+func (f *fakeOrderLists) GetOrderList(_ context.Context, _ int64) (*repository.OrderList, error) {
+	return f.list, nil
+}
+
+func (f *fakeOrderLists) ListOrderListOrders(_ context.Context, _ int64) ([]*repository.Order, error) {
+	return f.legs, nil
+}
+
+func (f *fakeOrderLists) TriggerOrderList(_ context.Context, _ int64, orderID, _ int64) (int64, error) {
+	if f.triggered == 0 {
+		f.triggered = orderID
+	}
+	return f.triggered, nil
+}
+
+func (f *fakeOrderLists) FinishOrderList(_ context.Context, _ int64, status int, _ int64) error {
+	f.finished = status
+	f.list.Status = status
+	return nil
+}
+
+type fakeCanceler struct {
+	canceled []int64
+}
+
+func (f *fakeCanceler) SendCancel(_ context.Context, order *repository.Order) error {
+	f.canceled = append(f.canceled, order.OrderID)
+	return nil
+}
+
+func newOrderListFixture() *fakeOrderLists {
+	return &fakeOrderLists{
+		list: &repository.OrderList{
+			ListID: 7, UserID: 10, Symbol: "BTCUSDT", Side: repository.SideSell,
+			FreezeAsset: "BTC", FrozenAmount: 100, Status: repository.OrderListStatusExecuting,
+		},
+		legs: []*repository.Order{
+			{OrderID: 1, UserID: 10, ListID: 7, Side: repository.SideSell, Type: repository.TypeLimit, ExecutedQty: "0", CumulativeQuoteQty: "0", Status: repository.StatusNew},
+			{OrderID: 2, UserID: 10, ListID: 7, Side: repository.SideSell, Type: repository.TypeStopLossLimit, ExecutedQty: "0", CumulativeQuoteQty: "0", Status: repository.StatusNew},
+		},
+	}
+}
+
+func TestOrderUpdater_OrderListPartialFillCancelsSibling(t *testing.T) {
+	lists := newOrderListFixture()
+	store := &fakeOrderStore{order: lists.legs[0]}
+	unfreezer := &fakeUnfreezer{}
+	canceler := &fakeCanceler{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+	updater.SetOrderLists(lists, canceler)
+
+	lists.legs[0].Status = repository.StatusPartiallyFilled
+	if err := updater.handleOrderPartiallyFilled(context.Background(), &MatchingEvent{
+		Data: mustJSON(t, OrderPartiallyFilledData{OrderID: 1, ExecutedQty: 40}),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lists.triggered != 1 || len(canceler.canceled) != 1 || canceler.canceled[0] != 2 {
+		t.Fatalf("expected stop leg canceled, triggered=%d canceled=%v", lists.triggered, canceler.canceled)
+	}
+	if unfreezer.called || lists.finished != 0 {
+		t.Fatal("expected list freeze kept while the limit leg is open")
+	}
+}
+
+func TestOrderUpdater_OrderListCanceledReleasesListFreeze(t *testing.T) {
+	lists := newOrderListFixture()
+	store := &fakeOrderStore{cfg: &repository.SymbolConfig{BaseAsset: "BTC", QuoteAsset: "USDT"}}
+	unfreezer := &fakeUnfreezer{}
+	canceler := &fakeCanceler{}
+	publisher := &fakePrivateEventPublisher{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+	updater.SetOrderLists(lists, canceler)
+	updater.SetPublisher(publisher)
+
+	// 用户撤销止损单：联动撤销限价单，不单独解冻
+	lists.legs[1].Status = repository.StatusCanceled
+	store.order = lists.legs[1]
+	if err := updater.handleOrderCanceled(context.Background(), &MatchingEvent{
+		Data: mustJSON(t, OrderCanceledData{OrderID: 2, UserID: 10, LeavesQty: 100, Reason: "USER_CANCELED"}),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(canceler.canceled) != 1 || canceler.canceled[0] != 1 || unfreezer.called {
+		t.Fatalf("expected limit leg canceled without unfreeze, canceled=%v unfreeze=%v", canceler.canceled, unfreezer.called)
+	}
+
+	// 限价单撤销前已成交 30：列表释放剩余 70
+	lists.legs[0].Status = repository.StatusCanceled
+	lists.legs[0].ExecutedQty = "30"
+	store.order = lists.legs[0]
+	if err := updater.handleOrderCanceled(context.Background(), &MatchingEvent{
+		Data: mustJSON(t, OrderCanceledData{OrderID: 1, UserID: 10, LeavesQty: 70, Reason: "USER_CANCELED"}),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !unfreezer.called || unfreezer.asset != "BTC" || unfreezer.amount != 70 || unfreezer.key != "unfreeze:list:7" {
+		t.Fatalf("unexpected list unfreeze: asset=%s amount=%d key=%s", unfreezer.asset, unfreezer.amount, unfreezer.key)
+	}
+	if lists.finished != repository.OrderListStatusAllDone || len(canceler.canceled) != 1 {
+		t.Fatalf("expected list done without further cancels, finished=%d canceled=%v", lists.finished, canceler.canceled)
+	}
+	if len(publisher.orderEvents) != 2 || publisher.orderEvents[1] != "canceled" {
+		t.Fatalf("unexpected private events: %v", publisher.orderEvents)
+	}
+
+	// 重复事件：列表已完成，不再解冻
+	unfreezer.called = false
+	if err := updater.handleOrderCanceled(context.Background(), &MatchingEvent{
+		Data: mustJSON(t, OrderCanceledData{OrderID: 1, UserID: 10, LeavesQty: 70, Reason: "USER_CANCELED"}),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if unfreezer.called {
+		t.Fatal("expected no second list unfreeze")
+	}
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exchange/order/internal/client"
+	"github.com/exchange/order/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// MaxQuoteLevels 单次报价的最大档位数（双边合计）
+const MaxQuoteLevels = 40
+
+// QuoteLevel 报价档位（限价单）
+type QuoteLevel struct {
+	Side     string // BUY / SELL
+	Price    int64
+	Quantity int64
+}
+
+// MassQuoteRequest 批量报价请求（Quotes 为空表示撤销全部报价）
+type MassQuoteRequest struct {
+	UserID      int64
+	Symbol      string
+	TimeInForce string // GTC（默认）/ POST_ONLY，对整组生效
+	STPMode     string // 对整组生效
+	Quotes      []QuoteLevel
+}
+
+// MassQuoteResponse 批量报价响应（报价结果由撮合异步确认：MASS_QUOTED / MASS_QUOTE_REJECTED）
+type MassQuoteResponse struct {
+	QuoteID   int64
+	Orders    []*repository.Order
+	ErrorCode string
+}
+
+// QuoteMessage 批量报价中的单个报价单
+type QuoteMessage struct {
+	OrderID int64  `json:"orderId"`
+	Side    string `json:"side"`
+	Price   int64  `json:"price"`
+	Qty     int64  `json:"qty"`
+}
+
+// quoteFreeze 报价按资产的净额冻结
+type quoteFreeze struct {
+	asset  string
+	amount int64
+}
+
+// MassQuote 替换用户在交易对上的全部报价单（只冻结新报价所需资金与旧报价剩余冻结的差额）
+func (s *OrderService) MassQuote(ctx context.Context, req *MassQuoteRequest) (*MassQuoteResponse, error) {
+	if req == nil || req.UserID <= 0 || len(req.Quotes) > MaxQuoteLevels {
+		return &MassQuoteResponse{ErrorCode: "INVALID_PARAM"}, nil
+	}
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	req.TimeInForce = strings.ToUpper(strings.TrimSpace(req.TimeInForce))
+	req.STPMode = strings.ToUpper(strings.TrimSpace(req.STPMode))
+	if req.TimeInForce == "" {
+		req.TimeInForce = "GTC"
+	}
+	if req.TimeInForce != "GTC" && req.TimeInForce != "POST_ONLY" {
+		return &MassQuoteResponse{ErrorCode: "INVALID_TIME_IN_FORCE"}, nil
+	}
+
+	cfg, err := s.repo.GetSymbolConfig(ctx, req.Symbol)
+	if err != nil {
+		return &MassQuoteResponse{ErrorCode: "SYMBOL_NOT_FOUND"}, nil
+	}
+	switch cfg.Status {
+	case repository.SymbolStatusTrading:
+	case repository.SymbolStatusAuction:
+		if req.TimeInForce != "GTC" {
+			return &MassQuoteResponse{ErrorCode: "AUCTION_ORDER_NOT_ALLOWED"}, nil
+		}
+	default:
+		return &MassQuoteResponse{ErrorCode: "SYMBOL_NOT_TRADING"}, nil
+	}
+	// 撤销全部报价只检查禁止交易，新报价逐档按订单检查
+	if s.risk != nil && len(req.Quotes) == 0 {
+		code, err := s.risk.CheckTradingAllowed(ctx, req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("pre-trade risk: %w", err)
+		}
+		if code != "" {
+			return &MassQuoteResponse{ErrorCode: code}, nil
+		}
+	}
+	old, err := s.repo.ListOpenQuotes(ctx, req.UserID, req.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("list open quotes: %w", err)
+	}
+
+	// 1. 逐档校验（与普通限价单规则一致）与下单前风控（被替换的旧报价不计入未完成订单数）
+	var needBuy, needSell int64
+	var riskOrders []*RiskOrder
+	// 报价未送达撮合时退回已计入风控窗口的名义金额
+	sent := false
+	defer func() {
+		if !sent {
+			s.releaseRisk(ctx, riskOrders...)
+		}
+	}()
+	for i := range req.Quotes {
+		level := &req.Quotes[i]
+		level.Side = strings.ToUpper(strings.TrimSpace(level.Side))
+		orderReq := &CreateOrderRequest{
+			UserID:      req.UserID,
+			Symbol:      req.Symbol,
+			Side:        level.Side,
+			Type:        "LIMIT",
+			TimeInForce: req.TimeInForce,
+			Price:       level.Price,
+			Quantity:    level.Quantity,
+			STPMode:     req.STPMode,
+		}
+		if err := s.validateOrder(orderReq, cfg); err != nil {
+			return &MassQuoteResponse{ErrorCode: err.Error()}, nil
+		}
+		if s.validator != nil {
+			if err := s.validator.ValidatePrice(req.Symbol, level.Side, level.Price); err != nil {
+				return &MassQuoteResponse{ErrorCode: err.Error()}, nil
+			}
+		}
+		riskOrder, code, err := s.checkRisk(ctx, orderReq, cfg, i-len(old))
+		if err != nil {
+			return nil, err
+		}
+		if code != "" {
+			return &MassQuoteResponse{ErrorCode: code}, nil
+		}
+		riskOrders = append(riskOrders, riskOrder)
+		if level.Side == "BUY" {
+			needBuy += quoteQty(level.Price, level.Quantity, cfg.QtyPrecision)
+		} else {
+			needSell += level.Quantity
+		}
+	}
+	if s.clearing == nil {
+		return nil, fmt.Errorf("clearing client not configured")
+	}
+
+	// 2. 旧报价剩余冻结（买单按报价计算下界，与撮合核对口径一致）
+	var carryBuy, carrySell int64
+	for _, order := range old {
+		leaves, err := orderLeavesQty(order)
+		if err != nil {
+			return nil, err
+		}
+		if order.Side == repository.SideBuy {
+			price, err := parseInt64Compat(order.Price, "price")
+			if err != nil {
+				return nil, err
+			}
+			carryBuy += quoteQty(price, leaves, cfg.QtyPrecision)
+		} else {
+			carrySell += leaves
+		}
+	}
+	freezes := []quoteFreeze{
+		{asset: cfg.QuoteAsset, amount: max(needBuy-carryBuy, 0)},
+		{asset: cfg.BaseAsset, amount: max(needSell-carrySell, 0)},
+	}
+
+	// 3. 整组落库
+	quoteID := s.idGen.NextID()
+	now := time.Now().UnixMilli()
+	orders := make([]*repository.Order, 0, len(req.Quotes))
+	for _, level := range req.Quotes {
+		orders = append(orders, &repository.Order{
+			OrderID:            s.idGen.NextID(),
+			UserID:             req.UserID,
+			Symbol:             req.Symbol,
+			Side:               parseSide(level.Side),
+			Type:               repository.TypeLimit,
+			TimeInForce:        parseTIF(req.TimeInForce),
+			Price:              strconv.FormatInt(level.Price, 10),
+			StopPrice:          "0",
+			OrigQty:            strconv.FormatInt(level.Quantity, 10),
+			ExecutedQty:        "0",
+			CumulativeQuoteQty: "0",
+			Status:             repository.StatusInit,
+			STPMode:            parseSTPMode(req.STPMode),
+			QuoteID:            quoteID,
+			CreateTimeMs:       now,
+			UpdateTimeMs:       now,
+		})
+	}
+	if len(orders) > 0 {
+		if err := s.repo.CreateOrders(ctx, orders); err != nil {
+			return nil, fmt.Errorf("create quote orders: %w", err)
+		}
+	}
+
+	// 4. 一次批量冻结差额（幂等键基于报价 ID 与资产）
+	items := make([]client.FreezeRequest, 0, len(freezes))
+	for _, f := range freezes {
+		if f.amount > 0 {
+			items = append(items, client.FreezeRequest{
+				IdempotencyKey: fmt.Sprintf("freeze:quote:%d:%s", quoteID, f.asset),
+				UserID:         req.UserID,
+				Asset:          f.asset,
+				Amount:         f.amount,
+				RefType:        "QUOTE",
+				RefID:          strconv.FormatInt(quoteID, 10),
+			})
+		}
+	}
+	if len(items) > 0 {
+		freezeResp, err := s.clearing.BatchFreezeBalance(ctx, items)
+		if err != nil {
+			return nil, fmt.Errorf("batch freeze balance: %w", err)
+		}
+		if freezeResp == nil || !freezeResp.Success {
+			code := "FREEZE_FAILED"
+			if freezeResp != nil && freezeResp.ErrorCode != "" {
+				code = freezeResp.ErrorCode
+			}
+			if _, err := s.repo.RejectQuoteOrders(ctx, quoteID, code, time.Now().UnixMilli()); err != nil {
+				return nil, fmt.Errorf("reject quote orders: %w", err)
+			}
+			return &MassQuoteResponse{ErrorCode: code}, nil
+		}
+	}
+
+	// 5. 更新状态为 NEW 并发送到撮合
+	updateTime := time.Now().UnixMilli()
+	if _, err := s.repo.UpdateQuoteStatus(ctx, quoteID, repository.StatusInit, repository.StatusNew, updateTime); err != nil {
+		s.compensateMassQuoteFailure(ctx, req.UserID, quoteID, freezes, "update_status_failed")
+		return nil, fmt.Errorf("update quote status: %w", err)
+	}
+	for _, order := range orders {
+		order.Status = repository.StatusNew
+		order.UpdateTimeMs = updateTime
+	}
+
+	msg := &OrderMessage{
+		Type:        "MASS_QUOTE",
+		UserID:      req.UserID,
+		Symbol:      req.Symbol,
+		TimeInForce: req.TimeInForce,
+		STPMode:     req.STPMode,
+		RequestID:   quoteID,
+		Quotes:      make([]QuoteMessage, 0, len(orders)),
+		BuyFrozen:   freezes[0].amount,
+		SellFrozen:  freezes[1].amount,
+		QtyScale:    scaleFactor(cfg.QtyPrecision),
+	}
+	for i, order := range orders {
+		msg.Quotes = append(msg.Quotes, QuoteMessage{
+			OrderID: order.OrderID,
+			Side:    req.Quotes[i].Side,
+			Price:   req.Quotes[i].Price,
+			Qty:     req.Quotes[i].Quantity,
+		})
+	}
+	if err := s.sendMassQuoteToMatching(ctx, msg); err != nil {
+		s.compensateMassQuoteFailure(ctx, req.UserID, quoteID, freezes, "send_matching_failed")
+		return nil, fmt.Errorf("send mass quote to matching: %w", err)
+	}
+	sent = true
+
+	if s.metrics != nil {
+		for _, order := range orders {
+			s.metrics.IncOrderCreated(order.Symbol, sideToString(order.Side))
+		}
+	}
+	if s.publisher != nil {
+		for _, order := range orders {
+			if err := s.publisher.PublishOrderCreated(ctx, order.UserID, order); err != nil {
+				log.Printf("publish order created error: %v", err)
+			}
+		}
+	}
+	return &MassQuoteResponse{QuoteID: quoteID, Orders: orders}, nil
+}
+
+// compensateMassQuoteFailure 报价未送达撮合：解冻本次冻结并拒绝整组报价单
+func (s *OrderService) compensateMassQuoteFailure(ctx context.Context, userID, quoteID int64, freezes []quoteFreeze, reason string) {
+	var errs []string
+	for _, f := range freezes {
+		if f.amount <= 0 {
+			continue
+		}
+		key := fmt.Sprintf("unfreeze:quote:%d:%s:%s", quoteID, f.asset, reason)
+		resp, err := s.clearing.UnfreezeBalance(ctx, userID, f.asset, f.amount, key)
+		if err == nil && resp != nil && !resp.Success {
+			err = fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("rollback freeze %s: %v", f.asset, err))
+		}
+	}
+	if _, err := s.repo.RejectQuoteOrders(ctx, quoteID, "INTERNAL_ERROR", time.Now().UnixMilli()); err != nil {
+		errs = append(errs, fmt.Sprintf("reject quote orders: %v", err))
+	}
+	if len(errs) > 0 {
+		log.Printf("compensate mass quote failure (%s) error: %s", reason, strings.Join(errs, "; "))
+	}
+}
+
+func (s *OrderService) sendMassQuoteToMatching(ctx context.Context, msg *OrderMessage) error {
+	if s.redis == nil {
+		return fmt.Errorf("redis client not configured")
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	stream, err := s.matchingStream(ctx, msg.Symbol)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		Values: map[string]interface{}{
+			"data": string(data),
+		},
+	}).Result()
+
+	return err
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/exchange/order/internal/client"
+	"github.com/exchange/order/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+type seqIDGen struct {
+	next int64
+}
+
+func (g *seqIDGen) NextID() int64 {
+	g.next++
+	return g.next
+}
+
+func newQuoteTestService(t *testing.T, store OrderStore, freezeResp client.BatchFreezeResponse) (*OrderService, *redis.Client, *client.BatchFreezeRequest) {
+	t.Helper()
+	var freezeReq client.BatchFreezeRequest
+	svc, redisClient := newClearingTestService(t, store, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/internal/freeze/batch" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&freezeReq)
+		_ = json.NewEncoder(w).Encode(freezeResp)
+	})
+	return svc, redisClient, &freezeReq
+}
+
+func TestMassQuote_FreezesNetAndSends(t *testing.T) {
+	store := &cancelOrderStore{
+		cfg: amendSymbolConfig(),
+		openQuotes: []*repository.Order{
+			{OrderID: 90, Side: repository.SideBuy, Price: "10000", OrigQty: "1000", ExecutedQty: "400", QuoteID: 80},
+			{OrderID: 91, Side: repository.SideSell, Price: "10300", OrigQty: "500", ExecutedQty: "0", QuoteID: 80},
+		},
+	}
+	svc, redisClient, freezeReq := newQuoteTestService(t, store, client.BatchFreezeResponse{Success: true, Applied: 1})
+
+	resp, err := svc.MassQuote(context.Background(), &MassQuoteRequest{
+		UserID:      1,
+		Symbol:      "btcusdt",
+		TimeInForce: "post_only",
+		Quotes: []QuoteLevel{
+			{Side: "buy", Price: 10100, Quantity: 500},
+			{Side: "SELL", Price: 10200, Quantity: 800},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "" || resp.QuoteID != 1 || len(resp.Orders) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	// 旧买单剩余 6@100 可转 600.00，新买单需 505.00，无需追加；旧卖单剩余 5，新卖单需 8，追加 3
+	if len(freezeReq.Items) != 1 {
+		t.Fatalf("expected one netted freeze, got %+v", freezeReq.Items)
+	}
+	if item := freezeReq.Items[0]; item.Asset != "BTC" || item.Amount != 300 || item.IdempotencyKey != "freeze:quote:1:BTC" || item.RefType != "QUOTE" {
+		t.Fatalf("unexpected freeze item: %+v", item)
+	}
+	if len(store.createdOrders) != 2 || store.createdOrders[0].QuoteID != 1 || store.createdOrders[1].TimeInForce != 4 {
+		t.Fatalf("unexpected created orders: %+v", store.createdOrders)
+	}
+	if store.quoteStatus[1] != repository.StatusNew {
+		t.Fatalf("expected quote orders marked NEW, got %v", store.quoteStatus)
+	}
+
+	msgs, err := redisClient.XRange(context.Background(), "orders", "-", "+").Result()
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected one matching message, got %d (%v)", len(msgs), err)
+	}
+	var msg OrderMessage
+	if err := json.Unmarshal([]byte(msgs[0].Values["data"].(string)), &msg); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if msg.Type != "MASS_QUOTE" || msg.RequestID != 1 || msg.Symbol != "BTCUSDT" || msg.TimeInForce != "POST_ONLY" {
+		t.Fatalf("unexpected mass quote message: %+v", msg)
+	}
+	if msg.BuyFrozen != 0 || msg.SellFrozen != 300 || msg.QtyScale != 100 || len(msg.Quotes) != 2 {
+		t.Fatalf("unexpected mass quote budget: %+v", msg)
+	}
+	if q := msg.Quotes[0]; q.OrderID != 2 || q.Side != "BUY" || q.Price != 10100 || q.Qty != 500 {
+		t.Fatalf("unexpected quote: %+v", q)
+	}
+}
+
+func TestMassQuote_FreezeRejectedRejectsQuotes(t *testing.T) {
+	store := &cancelOrderStore{cfg: amendSymbolConfig()}
+	svc, redisClient, freezeReq := newQuoteTestService(t, store, client.BatchFreezeResponse{ErrorCode: "INSUFFICIENT_BALANCE"})
+
+	resp, err := svc.MassQuote(context.Background(), &MassQuoteRequest{
+		UserID: 1,
+		Symbol: "BTCUSDT",
+		Quotes: []QuoteLevel{
+			{Side: "BUY", Price: 10000, Quantity: 100},
+			{Side: "SELL", Price: 10100, Quantity: 100},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ErrorCode != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("expected INSUFFICIENT_BALANCE, got %+v", resp)
+	}
+	// 无旧报价：双边全额冻结
+	if len(freezeReq.Items) != 2 || freezeReq.Items[0].Asset != "USDT" || freezeReq.Items[0].Amount != 10000 || freezeReq.Items[1].Amount != 100 {
+		t.Fatalf("unexpected freeze items: %+v", freezeReq.Items)
+	}
+	if store.rejectedQuote != 1 || store.quoteRejectMsg != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("expected quote orders rejected, got %d %q", store.rejectedQuote, store.quoteRejectMsg)
+	}
+	if n, _ := redisClient.XLen(context.Background(), "orders").Result(); n != 0 {
+		t.Fatalf("expected nothing sent to matching, got %d", n)
+	}
+}
+
+func TestMassQuote_Validation(t *testing.T) {
+	store := &cancelOrderStore{cfg: amendSymbolConfig()}
+	svc, _, _ := newQuoteTestService(t, store, client.BatchFreezeResponse{Success: true})
+
+	tooMany := make([]QuoteLevel, MaxQuoteLevels+1)
+	cases := []struct {
+		req  *MassQuoteRequest
+		code string
+	}{
+		{req: nil, code: "INVALID_PARAM"},
+		{req: &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", Quotes: tooMany}, code: "INVALID_PARAM"},
+		{req: &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", TimeInForce: "IOC"}, code: "INVALID_TIME_IN_FORCE"},
+		{req: &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", STPMode: "NONE", Quotes: []QuoteLevel{{Side: "BUY", Price: 100, Quantity: 100}}}, code: "INVALID_STP_MODE"},
+		{req: &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", Quotes: []QuoteLevel{{Side: "BUY", Price: 0, Quantity: 100}}}, code: "INVALID_PRICE"},
+		{req: &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", Quotes: []QuoteLevel{{Side: "HOLD", Price: 100, Quantity: 100}}}, code: "INVALID_SIDE"},
+	}
+	for _, tc := range cases {
+		resp, err := svc.MassQuote(context.Background(), tc.req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.ErrorCode != tc.code {
+			t.Fatalf("expected %s, got %+v", tc.code, resp)
+		}
+	}
+
+	store.cfg.Status = repository.SymbolStatusAuction
+	resp, _ := svc.MassQuote(context.Background(), &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", TimeInForce: "POST_ONLY"})
+	if resp.ErrorCode != "AUCTION_ORDER_NOT_ALLOWED" {
+		t.Fatalf("expected AUCTION_ORDER_NOT_ALLOWED, got %+v", resp)
+	}
+	if len(store.createdOrders) != 0 {
+		t.Fatal("expected nothing created on validation failure")
+	}
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	commondecimal "github.com/exchange/common/pkg/decimal"
+	"github.com/exchange/order/internal/metrics"
+	"github.com/exchange/order/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// PreTradeRisk 下单前风控检查，返回非空错误码表示拒绝
+type PreTradeRisk interface {
+	// CheckOrder 检查单笔订单
+	CheckOrder(ctx context.Context, order *RiskOrder) (string, error)
+	// CheckTradingAllowed 检查用户是否被禁止交易
+	CheckTradingAllowed(ctx context.Context, userID int64) (string, error)
+	// ReleaseOrder 退回已通过检查、但最终未下单的订单计入的每分钟名义金额
+	ReleaseOrder(ctx context.Context, order *RiskOrder) error
+}
+
+// RiskOrder 待风控检查的订单
+type RiskOrder struct {
+	UserID     int64
+	Symbol     string
+	QuoteAsset string
+	Notional   int64 // 名义金额（按 10^pricePrecision 缩放），0 表示无法估算，不参与金额检查
+	// PricePrecision 计价精度，用于换算金额限额
+	PricePrecision int
+	// PendingOrders 同一请求中已通过检查、尚未落库的同交易对订单数（批量报价扣除被替换的旧报价，可为负）
+	PendingOrders int
+	// Amend 改单：订单已在簿，不计未完成订单数，每分钟名义金额只计入 AddedNotional
+	Amend         bool
+	AddedNotional int64
+
+	// 本单计入的每分钟名义金额窗口与金额（由 RiskEngine 记录，ReleaseOrder 据此退回）
+	windowKey     string
+	windowCounted int64
+}
+
+// RiskLimits 风控限额（0 或 nil 表示不限）
+type RiskLimits struct {
+	MaxOpenOrders        int                    // 每个交易对的未完成订单数
+	MaxOrderNotional     *commondecimal.Decimal // 单笔名义金额（计价资产单位）
+	MaxNotionalPerMinute *commondecimal.Decimal // 每分钟名义金额（按计价资产分别统计）
+	MaxOrdersPerSecond   int                    // 每秒下单数
+}
+
+// RiskConfig 风控配置
+type RiskConfig struct {
+	Default RiskLimits
+	Tiers   map[string]RiskLimits // 按账户等级覆盖
+}
+
+// limitsFor 账户等级对应的限额与指标标签
+func (c *RiskConfig) limitsFor(tier string) (RiskLimits, string) {
+	if limits, ok := c.Tiers[tier]; ok && tier != "" {
+		return limits, tier
+	}
+	return c.Default, "default"
+}
+
+// RiskStore 风控数据读取接口
+type RiskStore interface {
+	GetUserRiskSettings(ctx context.Context, userID int64) (*repository.UserRiskSettings, error)
+	CountOpenOrders(ctx context.Context, userID int64, symbol string) (int, error)
+}
+
+// RiskEngine 默认的下单前风控实现（计数窗口保存在 Redis，多个订单服务实例共享）
+type RiskEngine struct {
+	store   RiskStore
+	redis   *redis.Client
+	cfg     RiskConfig
+	metrics *metrics.Metrics
+	now     func() time.Time
+}
+
+// NewRiskEngine 创建风控引擎
+func NewRiskEngine(store RiskStore, redisClient *redis.Client, cfg RiskConfig, metricsClient *metrics.Metrics) *RiskEngine {
+	return &RiskEngine{
+		store:   store,
+		redis:   redisClient,
+		cfg:     cfg,
+		metrics: metricsClient,
+		now:     time.Now,
+	}
+}
+
+// CheckTradingAllowed 检查用户是否被禁止交易
+func (e *RiskEngine) CheckTradingAllowed(ctx context.Context, userID int64) (string, error) {
+	settings, err := e.store.GetUserRiskSettings(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if settings.TradingDisabled {
+		_, tier := e.cfg.limitsFor(settings.Tier)
+		return e.rejected("TRADING_DISABLED", tier), nil
+	}
+	return "", nil
+}
+
+// CheckOrder 检查单笔订单
+func (e *RiskEngine) CheckOrder(ctx context.Context, order *RiskOrder) (string, error) {
+	settings, err := e.store.GetUserRiskSettings(ctx, order.UserID)
+	if err != nil {
+		return "", err
+	}
+	limits, tier := e.cfg.limitsFor(settings.Tier)
+	code, err := e.checkOrder(ctx, order, settings, limits)
+	if err != nil {
+		return "", err
+	}
+	return e.rejected(code, tier), nil
+}
+
+func (e *RiskEngine) checkOrder(ctx context.Context, order *RiskOrder, settings *repository.UserRiskSettings, limits RiskLimits) (string, error) {
+	if settings.TradingDisabled {
+		return "TRADING_DISABLED", nil
+	}
+	now := e.now()
+
+	if limits.MaxOrdersPerSecond > 0 {
+		key := fmt.Sprintf("risk:orders:%d:%d", order.UserID, now.Unix())
+		count, err := e.incrWindow(ctx, key, 1, 2*time.Second)
+		if err != nil {
+			return "", err
+		}
+		if count > int64(limits.MaxOrdersPerSecond) {
+			return "ORDER_RATE_EXCEEDED", nil
+		}
+	}
+
+	maxNotional := scaledRiskLimit(limits.MaxOrderNotional, order.PricePrecision)
+	if maxNotional > 0 && order.Notional > maxNotional {
+		return "ORDER_NOTIONAL_TOO_LARGE", nil
+	}
+
+	if limits.MaxOpenOrders > 0 && !order.Amend {
+		open, err := e.store.CountOpenOrders(ctx, order.UserID, order.Symbol)
+		if err != nil {
+			return "", err
+		}
+		if open+order.PendingOrders >= limits.MaxOpenOrders {
+			return "TOO_MANY_OPEN_ORDERS", nil
+		}
+	}
+
+	// 每分钟金额最后检查：只有通过其余检查的订单计入，超限时退回本笔
+	perMinute := scaledRiskLimit(limits.MaxNotionalPerMinute, order.PricePrecision)
+	counted := order.Notional
+	if order.Amend {
+		counted = order.AddedNotional
+	}
+	if perMinute > 0 && counted > 0 {
+		key := fmt.Sprintf("risk:notional:%d:%s:%d", order.UserID, order.QuoteAsset, now.Unix()/60)
+		total, err := e.incrWindow(ctx, key, counted, 2*time.Minute)
+		if err != nil {
+			return "", err
+		}
+		if total > perMinute {
+			if err := e.redis.DecrBy(ctx, key, counted).Err(); err != nil {
+				return "", fmt.Errorf("release notional: %w", err)
+			}
+			return "NOTIONAL_RATE_EXCEEDED", nil
+		}
+		order.windowKey, order.windowCounted = key, counted
+	}
+	return "", nil
+}
+
+// ReleaseOrder 退回订单计入的每分钟名义金额（按计入时的窗口，跨分钟后退回已过期的窗口不影响当前窗口）
+func (e *RiskEngine) ReleaseOrder(ctx context.Context, order *RiskOrder) error {
+	if order == nil || order.windowKey == "" {
+		return nil
+	}
+	if err := e.redis.DecrBy(ctx, order.windowKey, order.windowCounted).Err(); err != nil {
+		return fmt.Errorf("release notional: %w", err)
+	}
+	order.windowKey, order.windowCounted = "", 0
+	return nil
+}
+
+// incrWindow 固定窗口计数，返回累加后的值
+func (e *RiskEngine) incrWindow(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := e.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, key, delta)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("risk window %s: %w", key, err)
+	}
+	return incr.Val(), nil
+}
+
+func (e *RiskEngine) rejected(code, tier string) string {
+	if code != "" && e.metrics != nil {
+		e.metrics.IncRiskRejected(code, tier)
+	}
+	return code
+}
+
+// scaledRiskLimit 将计价资产单位的金额限额换算为 10^precision 缩放值，nil 或非正数表示不限
+func scaledRiskLimit(limit *commondecimal.Decimal, precision int) int64 {
+	if limit == nil || !limit.IsPositive() {
+		return 0
+	}
+	return limit.ToInt(normalizePrecision(precision))
+}
+
+// SetRiskChecker 设置下单前风控，为空时不检查
+func (s *OrderService) SetRiskChecker(risk PreTradeRisk) {
+	s.risk = risk
+}
+
+// checkRisk 对待下单的订单执行下单前风控，返回通过检查的风控订单（未配置风控或被拒绝时为 nil），
+// 订单最终未下单时交给 releaseRisk 退回
+func (s *OrderService) checkRisk(ctx context.Context, req *CreateOrderRequest, cfg *repository.SymbolConfig, pending int) (*RiskOrder, string, error) {
+	if s.risk == nil {
+		return nil, "", nil
+	}
+	return s.checkRiskOrder(ctx, &RiskOrder{
+		UserID:         req.UserID,
+		Symbol:         req.Symbol,
+		QuoteAsset:     cfg.QuoteAsset,
+		Notional:       s.estimateNotional(req, cfg),
+		PricePrecision: cfg.PricePrecision,
+		PendingOrders:  pending,
+	})
+}
+
+// checkAmendRisk 改单风控：只在剩余数量或名义金额增加时检查，每分钟名义金额计入增加部分
+func (s *OrderService) checkAmendRisk(ctx context.Context, order *repository.Order, cfg *repository.SymbolConfig, oldPrice, oldQty, newPrice, newQty, executedQty int64) (*RiskOrder, string, error) {
+	oldNotional := quoteQty(oldPrice, oldQty-executedQty, cfg.QtyPrecision)
+	newNotional := quoteQty(newPrice, newQty-executedQty, cfg.QtyPrecision)
+	if newQty <= oldQty && newNotional <= oldNotional {
+		return nil, "", nil
+	}
+	return s.checkRiskOrder(ctx, &RiskOrder{
+		UserID:         order.UserID,
+		Symbol:         order.Symbol,
+		QuoteAsset:     cfg.QuoteAsset,
+		Notional:       newNotional,
+		PricePrecision: cfg.PricePrecision,
+		Amend:          true,
+		AddedNotional:  max(newNotional-oldNotional, 0),
+	})
+}
+
+func (s *OrderService) checkRiskOrder(ctx context.Context, order *RiskOrder) (*RiskOrder, string, error) {
+	if s.risk == nil {
+		return nil, "", nil
+	}
+	code, err := s.risk.CheckOrder(ctx, order)
+	if err != nil {
+		return nil, "", fmt.Errorf("pre-trade risk: %w", err)
+	}
+	if code != "" {
+		return nil, code, nil
+	}
+	return order, "", nil
+}
+
+// releaseRisk 退回未下单订单计入的风控窗口（尽力而为，失败只记录日志）
+func (s *OrderService) releaseRisk(ctx context.Context, orders ...*RiskOrder) {
+	if s.risk == nil {
+		return
+	}
+	for _, order := range orders {
+		if order == nil {
+			continue
+		}
+		if err := s.risk.ReleaseOrder(ctx, order); err != nil {
+			log.Printf("release pre-trade risk error: user=%d symbol=%s err=%v", order.UserID, order.Symbol, err)
+		}
+	}
+}
+
+// estimateNotional 估算订单名义金额：限价或触发价，市价单按参考价；无法估算时为 0
+func (s *OrderService) estimateNotional(req *CreateOrderRequest, cfg *repository.SymbolConfig) int64 {
+	if req.QuoteOrderQty > 0 {
+		return req.QuoteOrderQty
+	}
+	price := req.Price
+	if price <= 0 {
+		price = req.StopPrice
+	}
+	if price <= 0 {
+		price = req.ActivationPrice
+	}
+	if price <= 0 && s.validator != nil {
+		if ref, err := s.validator.ReferencePrice(req.Symbol); err == nil {
+			price = ref
+		}
+	}
+	if price <= 0 || req.Quantity <= 0 {
+		return 0
+	}
+	return quoteQty(price, req.Quantity, cfg.QtyPrecision)
+}
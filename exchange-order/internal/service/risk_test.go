@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	commondecimal "github.com/exchange/common/pkg/decimal"
+	"github.com/exchange/order/internal/client"
+	"github.com/exchange/order/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRiskStore 用户风控设置与未完成订单数
+type fakeRiskStore struct {
+	settings map[int64]*repository.UserRiskSettings
+	open     map[string]int
+}
+
+func (f *fakeRiskStore) GetUserRiskSettings(_ context.Context, userID int64) (*repository.UserRiskSettings, error) {
+	if s, ok := f.settings[userID]; ok {
+		return s, nil
+	}
+	return &repository.UserRiskSettings{UserID: userID}, nil
+}
+
+func (f *fakeRiskStore) CountOpenOrders(_ context.Context, _ int64, symbol string) (int, error) {
+	return f.open[symbol], nil
+}
+
+func newRiskTestEngine(t *testing.T, cfg RiskConfig) (*RiskEngine, *fakeRiskStore, *time.Time) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	t.Cleanup(mr.Close)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	store := &fakeRiskStore{settings: make(map[int64]*repository.UserRiskSettings), open: make(map[string]int)}
+	engine := NewRiskEngine(store, redisClient, cfg, nil)
+	now := time.Unix(1_700_000_000, 0)
+	engine.now = func() time.Time { return now }
+	return engine, store, &now
+}
+
+func riskOrder(notional int64) *RiskOrder {
+	return &RiskOrder{UserID: 1, Symbol: "BTCUSDT", QuoteAsset: "USDT", Notional: notional, PricePrecision: 2}
+}
+
+func TestRiskEngine_Limits(t *testing.T) {
+	engine, store, now := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{
+		MaxOpenOrders:        3,
+		MaxOrderNotional:     commondecimal.MustNew("100"),
+		MaxNotionalPerMinute: commondecimal.MustNew("250"),
+		MaxOrdersPerSecond:   4,
+	}})
+	ctx := context.Background()
+	check := func(order *RiskOrder, want string) {
+		t.Helper()
+		code, err := engine.CheckOrder(ctx, order)
+		if err != nil {
+			t.Fatalf("check order: %v", err)
+		}
+		if code != want {
+			t.Fatalf("expected %q, got %q", want, code)
+		}
+	}
+
+	// 单笔金额上限 100.00（按计价精度 2 换算）
+	check(riskOrder(10001), "ORDER_NOTIONAL_TOO_LARGE")
+	check(riskOrder(10000), "")
+
+	// 未完成订单数含同一请求中尚未落库的订单
+	store.open["BTCUSDT"] = 2
+	pending := riskOrder(0)
+	pending.PendingOrders = 1
+	check(pending, "TOO_MANY_OPEN_ORDERS")
+	store.open["BTCUSDT"] = 0
+
+	// 每秒第 5 笔被拒绝（被其他规则拒绝的订单也计入），下一秒重新计数
+	check(riskOrder(0), "")
+	check(riskOrder(0), "ORDER_RATE_EXCEEDED")
+	*now = now.Add(time.Second)
+
+	// 每分钟金额：已计入 100.00，再计入 100.00 后第三笔超出 250.00，被拒绝的订单不占额度
+	check(riskOrder(10000), "")
+	check(riskOrder(10000), "NOTIONAL_RATE_EXCEEDED")
+	check(riskOrder(5000), "")
+	*now = now.Add(time.Minute)
+	check(riskOrder(10000), "")
+
+	store.settings[1] = &repository.UserRiskSettings{UserID: 1, TradingDisabled: true}
+	check(riskOrder(0), "TRADING_DISABLED")
+	if code, err := engine.CheckTradingAllowed(ctx, 1); err != nil || code != "TRADING_DISABLED" {
+		t.Fatalf("expected trading disabled, got %q %v", code, err)
+	}
+	if code, err := engine.CheckTradingAllowed(ctx, 2); err != nil || code != "" {
+		t.Fatalf("expected user 2 allowed, got %q %v", code, err)
+	}
+}
+
+func TestRiskEngine_TierLimits(t *testing.T) {
+	engine, store, _ := newRiskTestEngine(t, RiskConfig{
+		Default: RiskLimits{MaxOrderNotional: commondecimal.MustNew("100")},
+		Tiers: map[string]RiskLimits{
+			"vip": {MaxOrderNotional: commondecimal.MustNew("1000")},
+		},
+	})
+	ctx := context.Background()
+
+	if code, _ := engine.CheckOrder(ctx, riskOrder(50000)); code != "ORDER_NOTIONAL_TOO_LARGE" {
+		t.Fatalf("expected default limit, got %q", code)
+	}
+	store.settings[1] = &repository.UserRiskSettings{UserID: 1, Tier: "vip"}
+	if code, _ := engine.CheckOrder(ctx, riskOrder(50000)); code != "" {
+		t.Fatalf("expected vip limit, got %q", code)
+	}
+	// 未配置的等级使用全局限额
+	store.settings[1].Tier = "unknown"
+	if code, _ := engine.CheckOrder(ctx, riskOrder(50000)); code != "ORDER_NOTIONAL_TOO_LARGE" {
+		t.Fatalf("expected default limit for unknown tier, got %q", code)
+	}
+}
+
+func TestCreateOrder_PreTradeRiskRejects(t *testing.T) {
+	store := &mockOrderStore{cfg: amendSymbolConfig()}
+	svc, redisClient, _ := newOrderListTestService(t, store, client.FreezeResponse{Success: true})
+	engine, riskStore, _ := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{MaxOrderNotional: commondecimal.MustNew("100")}})
+	svc.SetRiskChecker(engine)
+	ctx := context.Background()
+
+	// 110.00 超出单笔金额上限
+	resp, err := svc.CreateOrder(ctx, &CreateOrderRequest{UserID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 11000, Quantity: 100})
+	if err != nil || resp.ErrorCode != "ORDER_NOTIONAL_TOO_LARGE" {
+		t.Fatalf("expected ORDER_NOTIONAL_TOO_LARGE, got %+v %v", resp, err)
+	}
+	if store.created {
+		t.Fatal("rejected order must not be stored")
+	}
+
+	riskStore.settings[1] = &repository.UserRiskSettings{UserID: 1, TradingDisabled: true}
+	resp, err = svc.CreateOrder(ctx, &CreateOrderRequest{UserID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 9000, Quantity: 100})
+	if err != nil || resp.ErrorCode != "TRADING_DISABLED" {
+		t.Fatalf("expected TRADING_DISABLED, got %+v %v", resp, err)
+	}
+	quote, err := svc.MassQuote(ctx, &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", Quotes: []QuoteLevel{{Side: "BUY", Price: 9000, Quantity: 100}}})
+	if err != nil || quote.ErrorCode != "TRADING_DISABLED" {
+		t.Fatalf("expected mass quote rejected, got %+v %v", quote, err)
+	}
+	if n, _ := redisClient.XLen(ctx, "orders").Result(); n != 0 {
+		t.Fatalf("expected nothing sent to matching, got %d messages", n)
+	}
+
+	// 其他用户不受影响
+	resp, err = svc.CreateOrder(ctx, &CreateOrderRequest{UserID: 2, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 9000, Quantity: 100})
+	if err != nil || resp.ErrorCode != "" {
+		t.Fatalf("expected order accepted, got %+v %v", resp, err)
+	}
+}
+
+func TestBatchCreateOrders_RiskCountsPendingOrders(t *testing.T) {
+	store := &cancelOrderStore{cfg: amendSymbolConfig()}
+	svc, _, _ := newBatchTestService(t, store, "")
+	engine, riskStore, _ := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{MaxOpenOrders: 3}})
+	riskStore.open["BTCUSDT"] = 1
+	svc.SetRiskChecker(engine)
+
+	resp, err := svc.BatchCreateOrders(context.Background(), &BatchCreateOrdersRequest{
+		UserID: 1,
+		Orders: []*CreateOrderRequest{
+			{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 10000, Quantity: 100},
+			{Symbol: "BTCUSDT", Side: "HOLD", Type: "LIMIT", Price: 10000, Quantity: 100},
+			{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 9900, Quantity: 100},
+			{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 9800, Quantity: 100},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, code := range []string{"", "INVALID_SIDE", "", "TOO_MANY_OPEN_ORDERS"} {
+		if resp.Results[i].ErrorCode != code {
+			t.Fatalf("result %d: expected %q, got %+v", i, code, resp.Results[i])
+		}
+	}
+	if len(store.createdOrders) != 2 {
+		t.Fatalf("expected 2 orders created, got %d", len(store.createdOrders))
+	}
+}
+
+func TestRiskEngine_ReleaseAndAmend(t *testing.T) {
+	engine, store, _ := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{
+		MaxOpenOrders:        1,
+		MaxNotionalPerMinute: commondecimal.MustNew("150"),
+	}})
+	ctx := context.Background()
+
+	// 退回后额度可再次使用，重复退回不重复扣减
+	first := riskOrder(10000)
+	if code, err := engine.CheckOrder(ctx, first); err != nil || code != "" {
+		t.Fatalf("expected first order accepted, got %q %v", code, err)
+	}
+	if code, _ := engine.CheckOrder(ctx, riskOrder(10000)); code != "NOTIONAL_RATE_EXCEEDED" {
+		t.Fatalf("expected NOTIONAL_RATE_EXCEEDED, got %q", code)
+	}
+	if err := engine.ReleaseOrder(ctx, first); err != nil {
+		t.Fatalf("release order: %v", err)
+	}
+	if err := engine.ReleaseOrder(ctx, first); err != nil {
+		t.Fatalf("release order twice: %v", err)
+	}
+	second := riskOrder(10000)
+	if code, _ := engine.CheckOrder(ctx, second); code != "" {
+		t.Fatalf("expected order accepted after release, got %q", code)
+	}
+
+	// 改单不计未完成订单数，每分钟金额只计入增加部分：已计入 100.00，再计入 40.00
+	store.open["BTCUSDT"] = 1
+	amend := riskOrder(14000)
+	amend.Amend, amend.AddedNotional = true, 4000
+	if code, _ := engine.CheckOrder(ctx, amend); code != "" {
+		t.Fatalf("expected amend accepted, got %q", code)
+	}
+	if code, _ := engine.CheckOrder(ctx, riskOrder(0)); code != "TOO_MANY_OPEN_ORDERS" {
+		t.Fatalf("expected new order rejected, got %q", code)
+	}
+	store.open["BTCUSDT"] = 0
+	if code, _ := engine.CheckOrder(ctx, riskOrder(1100)); code != "NOTIONAL_RATE_EXCEEDED" {
+		t.Fatalf("expected NOTIONAL_RATE_EXCEEDED, got %q", code)
+	}
+}
+
+func TestCreateOrder_FreezeRejectionReleasesNotional(t *testing.T) {
+	engine, _, _ := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{MaxNotionalPerMinute: commondecimal.MustNew("150")}})
+	rejecting, _, _ := newOrderListTestService(t, &mockOrderStore{cfg: amendSymbolConfig()}, client.FreezeResponse{ErrorCode: "INSUFFICIENT_BALANCE"})
+	rejecting.SetRiskChecker(engine)
+	accepting, _, _ := newOrderListTestService(t, &mockOrderStore{cfg: amendSymbolConfig()}, client.FreezeResponse{Success: true})
+	accepting.SetRiskChecker(engine)
+	ctx := context.Background()
+
+	req := func() *CreateOrderRequest {
+		return &CreateOrderRequest{UserID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 11000, Quantity: 100}
+	}
+	resp, err := rejecting.CreateOrder(ctx, req())
+	if err != nil || resp.ErrorCode != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("expected INSUFFICIENT_BALANCE, got %+v %v", resp, err)
+	}
+	// 冻结失败的 110.00 已退回，同一分钟内再下 110.00 不超出 150.00
+	resp, err = accepting.CreateOrder(ctx, req())
+	if err != nil || resp.ErrorCode != "" {
+		t.Fatalf("expected order accepted, got %+v %v", resp, err)
+	}
+	resp, err = accepting.CreateOrder(ctx, req())
+	if err != nil || resp.ErrorCode != "NOTIONAL_RATE_EXCEEDED" {
+		t.Fatalf("expected NOTIONAL_RATE_EXCEEDED, got %+v %v", resp, err)
+	}
+}
+
+func TestUnsentOrdersReleaseNotional(t *testing.T) {
+	engine, _, _ := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{MaxNotionalPerMinute: commondecimal.MustNew("150")}})
+	storeErr := errors.New("db unavailable")
+	single, _, _ := newOrderListTestService(t, &mockOrderStore{cfg: amendSymbolConfig(), createErr: storeErr}, client.FreezeResponse{Success: true})
+	single.SetRiskChecker(engine)
+	failing := &cancelOrderStore{cfg: amendSymbolConfig(), createOrdersErr: storeErr}
+	batch, _, _ := newBatchTestService(t, failing, "")
+	batch.SetRiskChecker(engine)
+	quote, _, _ := newQuoteTestService(t, failing, client.BatchFreezeResponse{Success: true})
+	quote.SetRiskChecker(engine)
+	accepting, _, _ := newOrderListTestService(t, &mockOrderStore{cfg: amendSymbolConfig()}, client.FreezeResponse{Success: true})
+	accepting.SetRiskChecker(engine)
+	ctx := context.Background()
+
+	// 每次落库失败都退回 110.00，否则下一次会超出每分钟 150.00
+	req := func() *CreateOrderRequest {
+		return &CreateOrderRequest{UserID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 11000, Quantity: 100}
+	}
+	if _, err := single.CreateOrder(ctx, req()); !errors.Is(err, storeErr) {
+		t.Fatalf("expected store error, got %v", err)
+	}
+	if _, err := batch.BatchCreateOrders(ctx, &BatchCreateOrdersRequest{UserID: 1, Orders: []*CreateOrderRequest{req()}}); !errors.Is(err, storeErr) {
+		t.Fatalf("expected batch store error, got %v", err)
+	}
+	if _, err := quote.MassQuote(ctx, &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", Quotes: []QuoteLevel{{Side: "BUY", Price: 11000, Quantity: 100}}}); !errors.Is(err, storeErr) {
+		t.Fatalf("expected quote store error, got %v", err)
+	}
+	resp, err := accepting.CreateOrder(ctx, req())
+	if err != nil || resp.ErrorCode != "" {
+		t.Fatalf("expected order accepted, got %+v %v", resp, err)
+	}
+	resp, err = accepting.CreateOrder(ctx, req())
+	if err != nil || resp.ErrorCode != "NOTIONAL_RATE_EXCEEDED" {
+		t.Fatalf("expected NOTIONAL_RATE_EXCEEDED, got %+v %v", resp, err)
+	}
+}
+
+func TestMassQuote_PreTradeRisk(t *testing.T) {
+	store := &cancelOrderStore{
+		cfg: amendSymbolConfig(),
+		openQuotes: []*repository.Order{
+			{OrderID: 90, Side: repository.SideBuy, Price: "10000", OrigQty: "100", ExecutedQty: "0", QuoteID: 80},
+			{OrderID: 91, Side: repository.SideSell, Price: "10300", OrigQty: "100", ExecutedQty: "0", QuoteID: 80},
+		},
+	}
+	svc, redisClient, _ := newQuoteTestService(t, store, client.BatchFreezeResponse{Success: true, Applied: 1})
+	engine, riskStore, _ := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{
+		MaxOpenOrders:    3,
+		MaxOrderNotional: commondecimal.MustNew("105"),
+	}})
+	// 库中的 2 笔未完成订单即将被替换的旧报价
+	riskStore.open["BTCUSDT"] = 2
+	svc.SetRiskChecker(engine)
+	ctx := context.Background()
+
+	// 每档按单笔金额检查
+	resp, err := svc.MassQuote(ctx, &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", Quotes: []QuoteLevel{
+		{Side: "BUY", Price: 10000, Quantity: 100},
+		{Side: "SELL", Price: 11000, Quantity: 100},
+	}})
+	if err != nil || resp.ErrorCode != "ORDER_NOTIONAL_TOO_LARGE" {
+		t.Fatalf("expected ORDER_NOTIONAL_TOO_LARGE, got %+v %v", resp, err)
+	}
+	if len(store.createdOrders) != 0 {
+		t.Fatalf("rejected quote must not be stored, got %d orders", len(store.createdOrders))
+	}
+
+	// 替换两档旧报价不计入未完成订单数：2 + 3 档新报价超出上限 3
+	resp, err = svc.MassQuote(ctx, &MassQuoteRequest{UserID: 1, Symbol: "BTCUSDT", Quotes: []QuoteLevel{
+		{Side: "BUY", Price: 10000, Quantity: 100},
+		{Side: "BUY", Price: 9900, Quantity: 100},
+		{Side: "SELL", Price: 10200, Quantity: 100},
+	}})
+	if err != nil || resp.ErrorCode != "" {
+		t.Fatalf("expected quotes accepted, got %+v %v", resp, err)
+	}
+	if n, _ := redisClient.XLen(ctx, "orders").Result(); n != 1 {
+		t.Fatalf("expected one matching message, got %d", n)
+	}
+}
+
+func TestAmendOrder_PreTradeRiskOnIncrease(t *testing.T) {
+	store := &cancelOrderStore{
+		order: &repository.Order{
+			OrderID: 10, UserID: 1, Symbol: "BTCUSDT", Side: repository.SideBuy, Type: repository.TypeLimit,
+			TimeInForce: 1, Price: "10000", OrigQty: "1000", ExecutedQty: "400", CumulativeQuoteQty: "40000",
+			Status: repository.StatusPartiallyFilled,
+		},
+		cfg: amendSymbolConfig(),
+	}
+	svc, redisClient, _ := newQuoteTestService(t, store, client.BatchFreezeResponse{Success: true})
+	engine, _, _ := newRiskTestEngine(t, RiskConfig{Default: RiskLimits{MaxOrderNotional: commondecimal.MustNew("300")}})
+	svc.SetRiskChecker(engine)
+	ctx := context.Background()
+
+	// 提价后剩余 6@120 = 720.00 超出单笔金额上限
+	resp, err := svc.AmendOrder(ctx, &AmendOrderRequest{UserID: 1, OrderID: 10, Price: 12000})
+	if err != nil || resp.ErrorCode != "ORDER_NOTIONAL_TOO_LARGE" {
+		t.Fatalf("expected ORDER_NOTIONAL_TOO_LARGE, got %+v %v", resp, err)
+	}
+	if store.beganAmendID != 0 {
+		t.Fatal("rejected amend must not be registered")
+	}
+
+	// 减量后剩余 4@100 = 400.00 仍超出上限，但减少不检查
+	resp, err = svc.AmendOrder(ctx, &AmendOrderRequest{UserID: 1, OrderID: 10, Quantity: 800})
+	if err != nil || resp.ErrorCode != "" {
+		t.Fatalf("expected amend accepted, got %+v %v", resp, err)
+	}
+	if n, _ := redisClient.XLen(ctx, "orders").Result(); n != 1 {
+		t.Fatalf("expected amend sent to matching, got %d messages", n)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/exchange/common/pkg/eventseq"
 	"github.com/exchange/common/pkg/health"
 	"github.com/exchange/order/internal/client"
 	"github.com/exchange/order/internal/metrics"
@@ -34,12 +35,15 @@ type OrderUpdater struct {
 	clearing   ClearingUnfreezer
 	metrics    *metrics.Metrics
 	publisher  privateEventPublisher
+	lists      OrderListStore
+	canceler   OrderCanceler
 
 	eventStream string
 	group       string
 	consumer    string
 
 	loop health.LoopMonitor
+	seq  *eventseq.GroupTracker
 }
 
 type privateEventPublisher interface {
@@ -59,6 +63,24 @@ type OrderUpdaterStore interface {
 	GetOrder(ctx context.Context, orderID int64) (*repository.Order, error)
 	GetSymbolConfig(ctx context.Context, symbol string) (*repository.SymbolConfig, error)
 	AddOrderCumulativeQuoteQty(ctx context.Context, orderID int64, delta int64, updateTimeMs int64) error
+	MarkOrderTriggered(ctx context.Context, orderID int64, triggerTimeMs int64) error
+	UpdateTrailingStop(ctx context.Context, orderID, stopPrice, extremePrice, updateTimeMs int64) error
+	ReduceOrderQty(ctx context.Context, orderID int64, origQty int64, releasedQuote int64, updateTimeMs int64) error
+	ApplyAmend(ctx context.Context, orderID, price, origQty, frozenQuoteQty, updateTimeMs int64) error
+	ClearPendingAmend(ctx context.Context, orderID, amendID, updateTimeMs int64) error
+}
+
+// OrderListStore 订单列表联动依赖接口
+type OrderListStore interface {
+	GetOrderList(ctx context.Context, listID int64) (*repository.OrderList, error)
+	ListOrderListOrders(ctx context.Context, listID int64) ([]*repository.Order, error)
+	TriggerOrderList(ctx context.Context, listID, orderID, updateTimeMs int64) (int64, error)
+	FinishOrderList(ctx context.Context, listID int64, status int, updateTimeMs int64) error
+}
+
+// OrderCanceler 撤单发送接口（订单列表联动撤单）
+type OrderCanceler interface {
+	SendCancel(ctx context.Context, order *repository.Order) error
 }
 
 // TradeStore 成交存储接口
@@ -69,8 +91,12 @@ type TradeStore interface {
 // ClearingUnfreezer 解冻接口
 type ClearingUnfreezer interface {
 	UnfreezeBalance(ctx context.Context, userID int64, asset string, amount int64, idempotencyKey string) (*client.UnfreezeResponse, error)
+	BatchUnfreezeBalance(ctx context.Context, items []client.UnfreezeRequest) (*client.BatchUnfreezeResponse, error)
 }
 
+// maxBatchUnfreezeItems 单次批量解冻请求的条目上限（与清算服务一致）
+const maxBatchUnfreezeItems = 500
+
 // NewOrderUpdater 创建更新服务
 func NewOrderUpdater(redisClient *redis.Client, orderStore OrderUpdaterStore, tradeStore TradeStore, clearing ClearingUnfreezer, metricsClient *metrics.Metrics, cfg *UpdaterConfig) *OrderUpdater {
 	return &OrderUpdater{
@@ -82,6 +108,7 @@ func NewOrderUpdater(redisClient *redis.Client, orderStore OrderUpdaterStore, tr
 		eventStream: cfg.EventStream,
 		group:       cfg.Group,
 		consumer:    cfg.Consumer,
+		seq:         eventseq.NewGroupTracker(redisClient, cfg.EventStream, cfg.Group, eventseq.DefaultGroupGrace),
 	}
 }
 
@@ -89,6 +116,12 @@ func (u *OrderUpdater) SetPublisher(publisher privateEventPublisher) {
 	u.publisher = publisher
 }
 
+// SetOrderLists 启用订单列表联动：列表订单推进时撤销其余订单，全部终态后释放列表冻结
+func (u *OrderUpdater) SetOrderLists(store OrderListStore, canceler OrderCanceler) {
+	u.lists = store
+	u.canceler = canceler
+}
+
 // Start 启动消费
 func (u *OrderUpdater) Start(ctx context.Context) error {
 	err := u.redis.XGroupCreateMkStream(ctx, u.eventStream, u.group, "0").Err()
@@ -161,6 +194,10 @@ func (u *OrderUpdater) consumeOnce(ctx context.Context) error {
 	}
 
 	for _, result := range results {
+		// 先检查整批序列号再处理，缩短与同组其他实例之间的乱序窗口
+		for _, msg := range result.Messages {
+			u.checkSeq(ctx, msg)
+		}
 		for _, msg := range result.Messages {
 			if err := u.processMessage(ctx, msg); err != nil {
 				if u.metrics != nil {
@@ -256,6 +293,46 @@ func (u *OrderUpdater) processPending(ctx context.Context) error {
 	return nil
 }
 
+// checkSeq 检查新投递事件的序列号连续性（pending 重试的事件不参与检查）
+//
+// 同组的更新实例分摊事件，序列号记录按消费者组保存在 Redis。
+// 订单更新按幂等方式处理，重复事件仍然执行；缺口只上报并发起重同步请求。
+func (u *OrderUpdater) checkSeq(ctx context.Context, msg redis.XMessage) {
+	data, ok := msg.Values["data"].(string)
+	if !ok {
+		return
+	}
+	header, err := eventseq.ParseHeader(data)
+	if err != nil {
+		return
+	}
+	res, err := u.seq.Observe(ctx, header.Symbol, header.Seq, msg.ID)
+	if err != nil {
+		log.Printf("check event seq error: %v", err)
+		return
+	}
+	switch res.Status {
+	case eventseq.StatusDuplicate:
+		u.metrics.IncEventSeqDuplicate(u.eventStream, u.group, header.Symbol)
+		log.Printf("event seq duplicate: symbol=%s last=%d got=%d msgId=%s", header.Symbol, res.Last, header.Seq, msg.ID)
+	case eventseq.StatusGap:
+		u.metrics.IncEventSeqGap(u.eventStream, u.group, header.Symbol)
+		log.Printf("event seq gap: symbol=%s last=%d got=%d missing=%d msgId=%s", header.Symbol, res.Last, header.Seq, res.Missing, msg.ID)
+		req := eventseq.ResyncRequest{
+			Stream: u.eventStream, Group: u.group, Consumer: u.consumer,
+			Symbol: header.Symbol, LastSeq: res.Last, GotSeq: header.Seq, MsgID: msg.ID,
+		}
+		if err := u.redis.XAdd(ctx, &redis.XAddArgs{
+			Stream: eventseq.ResyncStream(u.eventStream),
+			Values: req.Values(),
+		}).Err(); err != nil {
+			log.Printf("request resync error: %v", err)
+		}
+	case eventseq.StatusReset:
+		log.Printf("event seq reset: symbol=%s last=%d", header.Symbol, res.Last)
+	}
+}
+
 func (u *OrderUpdater) sendToDLQ(ctx context.Context, msg *redis.XMessage, reason string) error {
 	dlqStream := u.eventStream + ":dlq"
 	_, err := u.redis.XAdd(ctx, &redis.XAddArgs{
@@ -295,8 +372,29 @@ func (u *OrderUpdater) processMessage(ctx context.Context, msg redis.XMessage) e
 		return u.handleOrderFilled(ctx, &event)
 	case "ORDER_CANCELED":
 		return u.handleOrderCanceled(ctx, &event)
+	case "ORDER_REDUCED":
+		return u.handleOrderReduced(ctx, &event)
+	case "ORDER_AMENDED":
+		return u.handleOrderAmended(ctx, &event)
+	case "AMEND_REJECTED":
+		return u.handleAmendRejected(ctx, &event)
 	case "TRADE_CREATED":
 		return u.handleTradeCreated(ctx, &event)
+	case "STOP_ORDER_ACCEPTED":
+		return u.handleStopOrderAccepted(ctx, &event)
+	case "STOP_ORDER_TRIGGERED":
+		return u.handleStopOrderTriggered(ctx, &event)
+	case "TRAILING_STOP_UPDATED":
+		return u.handleTrailingStopUpdated(ctx, &event)
+	case "MASS_CANCELED":
+		return u.handleMassCanceled(ctx, &event)
+	case "MASS_QUOTED":
+		return u.handleMassQuoted(ctx, &event)
+	case "MASS_QUOTE_REJECTED":
+		return u.handleMassQuoteRejected(ctx, &event)
+	case "AUCTION_INDICATIVE", "AUCTION_UNCROSSED", "CIRCUIT_BREAKER":
+		// 集合竞价与熔断行情事件，订单状态由相关的成交与订单事件更新
+		return nil
 	default:
 		return fmt.Errorf("unknown event type: %s", event.Type)
 	}
@@ -316,6 +414,20 @@ type OrderAcceptedData struct {
 	OrderID int64 `json:"OrderID"`
 }
 
+// StopOrderTriggeredData 条件单触发数据
+type StopOrderTriggeredData struct {
+	OrderID   int64 `json:"OrderID"`
+	StopPrice int64 `json:"StopPrice"`
+	LastPrice int64 `json:"LastPrice"`
+}
+
+// TrailingStopUpdatedData 跟踪止损触发价更新数据
+type TrailingStopUpdatedData struct {
+	OrderID      int64 `json:"OrderID"`
+	StopPrice    int64 `json:"StopPrice"`
+	ExtremePrice int64 `json:"ExtremePrice"`
+}
+
 // OrderPartiallyFilledData 订单部分成交数据
 type OrderPartiallyFilledData struct {
 	OrderID     int64 `json:"OrderID"`
@@ -336,6 +448,71 @@ type OrderCanceledData struct {
 	Reason    string `json:"Reason"`
 }
 
+// MassCanceledData 批量撤单数据
+type MassCanceledData struct {
+	RequestID int64               `json:"RequestID"`
+	UserID    int64               `json:"UserID"`
+	Orders    []MassCanceledOrder `json:"Orders"`
+	Reason    string              `json:"Reason"`
+}
+
+// MassCanceledOrder 批量撤单中被撤销的订单
+type MassCanceledOrder struct {
+	OrderID   int64 `json:"OrderID"`
+	LeavesQty int64 `json:"LeavesQty"`
+}
+
+// MassQuotedData 批量报价生效数据（新报价单的订单事件随后到达）
+type MassQuotedData struct {
+	RequestID   int64               `json:"RequestID"`
+	UserID      int64               `json:"UserID"`
+	Canceled    []MassCanceledOrder `json:"Canceled"`
+	Orders      []int64             `json:"Orders"`
+	BuyCarried  int64               `json:"BuyCarried"`
+	SellCarried int64               `json:"SellCarried"`
+}
+
+// MassQuoteRejectedData 批量报价拒绝数据
+type MassQuoteRejectedData struct {
+	RequestID  int64   `json:"RequestID"`
+	UserID     int64   `json:"UserID"`
+	Orders     []int64 `json:"Orders"`
+	BuyFrozen  int64   `json:"BuyFrozen"`
+	SellFrozen int64   `json:"SellFrozen"`
+	Reason     string  `json:"Reason"`
+}
+
+// CancelReasonQuoteReplaced 报价单被下一次批量报价替换的撤单原因
+const CancelReasonQuoteReplaced = "QUOTE_REPLACED"
+
+// OrderReducedData 订单数量扣减数据（STP DECREMENT）
+type OrderReducedData struct {
+	OrderID    int64  `json:"OrderID"`
+	ReducedQty int64  `json:"ReducedQty"`
+	OrigQty    int64  `json:"OrigQty"`
+	LeavesQty  int64  `json:"LeavesQty"`
+	Reason     string `json:"Reason"`
+}
+
+// OrderAmendedData 改单成功数据
+type OrderAmendedData struct {
+	OrderID      int64 `json:"OrderID"`
+	AmendID      int64 `json:"AmendID"`
+	OldPrice     int64 `json:"OldPrice"`
+	Price        int64 `json:"Price"`
+	OldQty       int64 `json:"OldQty"`
+	OrigQty      int64 `json:"OrigQty"`
+	LeavesQty    int64 `json:"LeavesQty"`
+	PriorityKept bool  `json:"PriorityKept"`
+}
+
+// AmendRejectedData 改单拒绝数据
+type AmendRejectedData struct {
+	OrderID int64  `json:"OrderID"`
+	AmendID int64  `json:"AmendID"`
+	Reason  string `json:"Reason"`
+}
+
 // OrderRejectedData 订单拒绝数据
 type OrderRejectedData struct {
 	OrderID int64  `json:"OrderID"`
@@ -375,6 +552,74 @@ func (u *OrderUpdater) handleOrderAccepted(ctx context.Context, event *MatchingE
 	return nil
 }
 
+// handleStopOrderAccepted 条件单进入触发簿，订单保持 NEW
+func (u *OrderUpdater) handleStopOrderAccepted(ctx context.Context, event *MatchingEvent) error {
+	var data OrderAcceptedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal stop order accepted: %w", err)
+	}
+
+	if err := u.orderStore.UpdateOrderStatus(ctx, data.OrderID, repository.StatusNew, 0, 0, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	if u.metrics != nil {
+		u.metrics.IncActiveOrders()
+	}
+	return nil
+}
+
+// handleStopOrderTriggered 记录触发时间；触发后的订单状态由后续撮合事件推进
+func (u *OrderUpdater) handleStopOrderTriggered(ctx context.Context, event *MatchingEvent) error {
+	var data StopOrderTriggeredData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal stop order triggered: %w", err)
+	}
+
+	triggerTimeMs := event.Timestamp / 1e6
+	if triggerTimeMs == 0 {
+		triggerTimeMs = time.Now().UnixMilli()
+	}
+	if err := u.orderStore.MarkOrderTriggered(ctx, data.OrderID, triggerTimeMs); err != nil {
+		return err
+	}
+	if u.metrics != nil {
+		// 触发后的订单若挂单会再次收到 ORDER_ACCEPTED
+		u.metrics.DecActiveOrders()
+	}
+	order, err := u.orderStore.GetOrder(ctx, data.OrderID)
+	if err == nil && order != nil {
+		if order.ListID != 0 {
+			if err := u.advanceOrderList(ctx, order); err != nil {
+				return err
+			}
+		}
+		if u.publisher != nil {
+			if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "triggered", order); pubErr != nil {
+				log.Printf("publish order triggered error: %v", pubErr)
+			}
+		}
+	}
+	return nil
+}
+
+// handleTrailingStopUpdated 记录撮合维护的跟踪止损触发价（每次成交推动最优价时更新）
+func (u *OrderUpdater) handleTrailingStopUpdated(ctx context.Context, event *MatchingEvent) error {
+	var data TrailingStopUpdatedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal trailing stop updated: %w", err)
+	}
+
+	updateTimeMs := event.Timestamp / 1e6
+	if updateTimeMs == 0 {
+		updateTimeMs = time.Now().UnixMilli()
+	}
+	// 已触发的订单与乱序、重复投递的旧事件（最优价未朝有利方向移动）不再更新
+	if err := u.orderStore.UpdateTrailingStop(ctx, data.OrderID, data.StopPrice, data.ExtremePrice, updateTimeMs); err != nil && err != repository.ErrOrderNotFound {
+		return err
+	}
+	return nil
+}
+
 func (u *OrderUpdater) handleOrderPartiallyFilled(ctx context.Context, event *MatchingEvent) error {
 	var data OrderPartiallyFilledData
 	if err := json.Unmarshal(event.Data, &data); err != nil {
@@ -389,9 +634,14 @@ func (u *OrderUpdater) handleOrderPartiallyFilled(ctx context.Context, event *Ma
 	if err := u.orderStore.UpdateOrderStatus(ctx, data.OrderID, repository.StatusPartiallyFilled, data.ExecutedQty, cumulative, time.Now().UnixMilli()); err != nil {
 		return err
 	}
-	if u.publisher != nil {
-		order, err := u.orderStore.GetOrder(ctx, data.OrderID)
-		if err == nil && order != nil {
+	order, err := u.orderStore.GetOrder(ctx, data.OrderID)
+	if err == nil && order != nil {
+		if order.ListID != 0 {
+			if err := u.advanceOrderList(ctx, order); err != nil {
+				return err
+			}
+		}
+		if u.publisher != nil {
 			if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "partially_filled", order); pubErr != nil {
 				log.Printf("publish order partially_filled error: %v", pubErr)
 			}
@@ -448,6 +698,14 @@ func (u *OrderUpdater) handleOrderCanceled(ctx context.Context, event *MatchingE
 	if u.metrics != nil {
 		u.metrics.DecActiveOrders()
 	}
+	if order.ListID != 0 {
+		// 列表订单不单独解冻，剩余冻结在全部订单终态后随列表释放
+		if err := u.advanceOrderList(ctx, order); err != nil {
+			return err
+		}
+		u.publishCanceled(ctx, order, data.Reason)
+		return nil
+	}
 
 	cfg, err := u.orderStore.GetSymbolConfig(ctx, order.Symbol)
 	if err != nil {
@@ -477,12 +735,436 @@ func (u *OrderUpdater) handleOrderCanceled(ctx context.Context, event *MatchingE
 	if !resp.Success {
 		return fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
 	}
+	u.publishCanceled(ctx, order, data.Reason)
+
+	return nil
+}
+
+func (u *OrderUpdater) publishCanceled(ctx context.Context, order *repository.Order, reason string) {
+	if u.publisher == nil {
+		return
+	}
+	// GTD/DAY 到期撤单单独推送，便于客户端区分主动撤单
+	event := "canceled"
+	if reason == repository.CancelReasonExpired {
+		event = "expired"
+	}
+	if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, event, order); pubErr != nil {
+		log.Printf("publish order canceled error: %v", pubErr)
+	}
+}
+
+// handleMassCanceled 批量撤单：逐单更新状态，剩余冻结通过一次批量解冻释放
+//
+// 解冻沿用单笔撤单的幂等键，重复投递或与单笔撤单交错时不会重复解冻。
+func (u *OrderUpdater) handleMassCanceled(ctx context.Context, event *MatchingEvent) error {
+	var data MassCanceledData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal mass canceled: %w", err)
+	}
+	if len(data.Orders) == 0 {
+		return nil
+	}
+
+	cfg, err := u.orderStore.GetSymbolConfig(ctx, event.Symbol)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	orders := make([]*repository.Order, 0, len(data.Orders))
+	items := make([]client.UnfreezeRequest, 0, len(data.Orders))
+	var listOrders []*repository.Order
+	for _, canceled := range data.Orders {
+		err := u.orderStore.CancelOrder(ctx, canceled.OrderID, data.Reason, now)
+		if err != nil && err != repository.ErrOrderNotFound {
+			return err
+		}
+		if err == nil && u.metrics != nil {
+			u.metrics.DecActiveOrders()
+		}
+
+		order, err := u.orderStore.GetOrder(ctx, canceled.OrderID)
+		if err != nil {
+			return err
+		}
+		orders = append(orders, order)
+		if order.ListID != 0 {
+			listOrders = append(listOrders, order)
+			continue
+		}
+
+		amount, asset, err := u.calculateCancelUnfreeze(order, cfg, canceled.LeavesQty)
+		if err != nil {
+			return err
+		}
+		if amount <= 0 {
+			continue
+		}
+		items = append(items, client.UnfreezeRequest{
+			IdempotencyKey: fmt.Sprintf("unfreeze:order:%d", order.OrderID),
+			UserID:         order.UserID,
+			Asset:          asset,
+			Amount:         amount,
+		})
+	}
+
+	for start := 0; start < len(items); start += maxBatchUnfreezeItems {
+		end := min(start+maxBatchUnfreezeItems, len(items))
+		resp, err := u.clearing.BatchUnfreezeBalance(ctx, items[start:end])
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("batch unfreeze failed: %s", resp.ErrorCode)
+		}
+	}
+	for _, order := range listOrders {
+		if err := u.advanceOrderList(ctx, order); err != nil {
+			return err
+		}
+	}
+
+	if u.publisher != nil {
+		for _, order := range orders {
+			if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "canceled", order); pubErr != nil {
+				log.Printf("publish order canceled error: %v", pubErr)
+			}
+		}
+	}
+	return nil
+}
+
+// handleMassQuoted 批量报价生效：旧报价单记为撤销，其剩余冻结扣除转给新报价的部分后一次解冻
+//
+// 解冻按报价 ID 与资产幂等，重复投递时不会重复解冻。
+func (u *OrderUpdater) handleMassQuoted(ctx context.Context, event *MatchingEvent) error {
+	var data MassQuotedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal mass quoted: %w", err)
+	}
+	if len(data.Canceled) == 0 {
+		return nil
+	}
+
+	cfg, err := u.orderStore.GetSymbolConfig(ctx, event.Symbol)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	orders := make([]*repository.Order, 0, len(data.Canceled))
+	released := map[string]int64{
+		cfg.QuoteAsset: -data.BuyCarried,
+		cfg.BaseAsset:  -data.SellCarried,
+	}
+	for _, canceled := range data.Canceled {
+		err := u.orderStore.CancelOrder(ctx, canceled.OrderID, CancelReasonQuoteReplaced, now)
+		if err != nil && err != repository.ErrOrderNotFound {
+			return err
+		}
+		if err == nil && u.metrics != nil {
+			u.metrics.DecActiveOrders()
+		}
+
+		order, err := u.orderStore.GetOrder(ctx, canceled.OrderID)
+		if err != nil {
+			return err
+		}
+		orders = append(orders, order)
+
+		amount, asset, err := u.calculateCancelUnfreeze(order, cfg, canceled.LeavesQty)
+		if err != nil {
+			return err
+		}
+		released[asset] += amount
+	}
+
+	items := make([]client.UnfreezeRequest, 0, len(released))
+	for _, asset := range []string{cfg.QuoteAsset, cfg.BaseAsset} {
+		if released[asset] <= 0 {
+			continue
+		}
+		items = append(items, client.UnfreezeRequest{
+			IdempotencyKey: fmt.Sprintf("unfreeze:quote:%d:%s", data.RequestID, asset),
+			UserID:         data.UserID,
+			Asset:          asset,
+			Amount:         released[asset],
+			RefType:        "QUOTE",
+			RefID:          strconv.FormatInt(data.RequestID, 10),
+		})
+	}
+	if len(items) > 0 {
+		resp, err := u.clearing.BatchUnfreezeBalance(ctx, items)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("batch unfreeze failed: %s", resp.ErrorCode)
+		}
+	}
+
+	if u.publisher != nil {
+		for _, order := range orders {
+			if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "canceled", order); pubErr != nil {
+				log.Printf("publish order canceled error: %v", pubErr)
+			}
+		}
+	}
+	return nil
+}
+
+// handleMassQuoteRejected 批量报价被撮合拒绝：整组报价单记为拒绝，解冻本次报价新冻结的资金
+func (u *OrderUpdater) handleMassQuoteRejected(ctx context.Context, event *MatchingEvent) error {
+	var data MassQuoteRejectedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal mass quote rejected: %w", err)
+	}
+
+	cfg, err := u.orderStore.GetSymbolConfig(ctx, event.Symbol)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	for _, orderID := range data.Orders {
+		if err := u.orderStore.RejectOrder(ctx, orderID, data.Reason, now); err != nil && err != repository.ErrOrderNotFound {
+			return err
+		}
+	}
+
+	items := make([]client.UnfreezeRequest, 0, 2)
+	for _, frozen := range []struct {
+		asset  string
+		amount int64
+	}{{cfg.QuoteAsset, data.BuyFrozen}, {cfg.BaseAsset, data.SellFrozen}} {
+		if frozen.amount <= 0 {
+			continue
+		}
+		items = append(items, client.UnfreezeRequest{
+			IdempotencyKey: fmt.Sprintf("unfreeze:quote:%d:%s:reject", data.RequestID, frozen.asset),
+			UserID:         data.UserID,
+			Asset:          frozen.asset,
+			Amount:         frozen.amount,
+			RefType:        "QUOTE",
+			RefID:          strconv.FormatInt(data.RequestID, 10),
+		})
+	}
+	if len(items) > 0 {
+		resp, err := u.clearing.BatchUnfreezeBalance(ctx, items)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("batch unfreeze failed: %s", resp.ErrorCode)
+		}
+	}
+
+	if u.publisher != nil {
+		for _, orderID := range data.Orders {
+			order, err := u.orderStore.GetOrder(ctx, orderID)
+			if err != nil {
+				log.Printf("load rejected quote order %d error: %v", orderID, err)
+				continue
+			}
+			if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "rejected", order); pubErr != nil {
+				log.Printf("publish order rejected error: %v", pubErr)
+			}
+		}
+	}
+	return nil
+}
+
+// handleOrderReduced 自成交防护扣减订单数量：同步 orig_qty 并解冻扣减部分
+func (u *OrderUpdater) handleOrderReduced(ctx context.Context, event *MatchingEvent) error {
+	var data OrderReducedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal order reduced: %w", err)
+	}
+
+	order, err := u.orderStore.GetOrder(ctx, data.OrderID)
+	if err != nil {
+		return err
+	}
+	cfg, err := u.orderStore.GetSymbolConfig(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
+
+	amount, asset, err := u.calculateUnfreeze(order, cfg, data.ReducedQty)
+	if err != nil {
+		return err
+	}
+	if order.ListID != 0 {
+		// 列表订单的扣减部分在全部订单终态后随列表冻结释放
+		amount = 0
+	}
+	releasedQuote := int64(0)
+	if order.Side == repository.SideBuy {
+		releasedQuote = amount
+	}
+
+	// orig_qty 已扣减时返回 ErrOrderNotFound（重复事件），继续完成解冻
+	if err := u.orderStore.ReduceOrderQty(ctx, data.OrderID, data.OrigQty, releasedQuote, time.Now().UnixMilli()); err != nil && err != repository.ErrOrderNotFound {
+		return err
+	}
+	order.OrigQty = strconv.FormatInt(data.OrigQty, 10)
+	if order.FrozenQuoteQty > 0 {
+		order.FrozenQuoteQty -= releasedQuote
+	}
+
+	if amount > 0 {
+		// 扣减后的 orig_qty 单调递减，可作为每次扣减的幂等键
+		unfreezeKey := fmt.Sprintf("unfreeze:order:%d:reduce:%d", order.OrderID, data.OrigQty)
+		resp, err := u.clearing.UnfreezeBalance(ctx, order.UserID, asset, amount, unfreezeKey)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
+		}
+	}
+	if u.publisher != nil {
+		if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "reduced", order); pubErr != nil {
+			log.Printf("publish order reduced error: %v", pubErr)
+		}
+	}
+	return nil
+}
+
+// handleOrderAmended 撮合确认改单：按新价格/数量重算冻结，解冻多余部分并落库
+//
+// 改单发起时已预冻结 pending_amend_freeze；重复事件时订单已是新状态、预冻结已清零，解冻金额为 0。
+func (u *OrderUpdater) handleOrderAmended(ctx context.Context, event *MatchingEvent) error {
+	var data OrderAmendedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal order amended: %w", err)
+	}
+
+	order, err := u.orderStore.GetOrder(ctx, data.OrderID)
+	if err != nil {
+		return err
+	}
+	cfg, err := u.orderStore.GetSymbolConfig(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
+
+	pending := int64(0)
+	if order.PendingAmendID == data.AmendID {
+		pending = order.PendingAmendFreeze
+	}
+
+	var release, frozenQuote int64
+	var asset string
+	if order.Side == repository.SideSell {
+		// 卖单冻结剩余数量：(原 orig + 预冻结) - 新 orig
+		oldOrig, err := parseInt64(order.OrigQty, "orig_qty")
+		if err != nil {
+			return err
+		}
+		asset = cfg.BaseAsset
+		release = oldOrig + pending - data.OrigQty
+		if release < 0 {
+			log.Printf("amend under-frozen: orderID=%d amendID=%d short=%d", order.OrderID, data.AmendID, -release)
+			release = 0
+		}
+	} else {
+		// 买单冻结 = 已成交额 + 新价格 * 剩余数量
+		total, err := totalFrozenQuote(order, cfg)
+		if err != nil {
+			return err
+		}
+		spent, err := parseInt64(order.CumulativeQuoteQty, "cumulative_quote_qty")
+		if err != nil {
+			return err
+		}
+		asset = cfg.QuoteAsset
+		held := total + pending
+		release = held - spent - quoteQty(data.Price, data.LeavesQty, cfg.QtyPrecision)
+		if release < 0 {
+			log.Printf("amend under-frozen: orderID=%d amendID=%d short=%d", order.OrderID, data.AmendID, -release)
+			release = 0
+		}
+		frozenQuote = held - release
+	}
+
+	if release > 0 {
+		unfreezeKey := fmt.Sprintf("unfreeze:order:%d:amend:%d", order.OrderID, data.AmendID)
+		resp, err := u.clearing.UnfreezeBalance(ctx, order.UserID, asset, release, unfreezeKey)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
+		}
+	}
+
+	updateTime := time.Now().UnixMilli()
+	if err := u.orderStore.ApplyAmend(ctx, order.OrderID, data.Price, data.OrigQty, frozenQuote, updateTime); err != nil {
+		return err
+	}
+	order.Price = strconv.FormatInt(data.Price, 10)
+	order.OrigQty = strconv.FormatInt(data.OrigQty, 10)
+	order.FrozenQuoteQty = frozenQuote
+	order.PendingAmendID = 0
+	order.PendingAmendFreeze = 0
+	order.UpdateTimeMs = updateTime
+
 	if u.publisher != nil {
-		if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "canceled", order); pubErr != nil {
-			log.Printf("publish order canceled error: %v", pubErr)
+		if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "amended", order); pubErr != nil {
+			log.Printf("publish order amended error: %v", pubErr)
+		}
+	}
+	return nil
+}
+
+// handleAmendRejected 改单被撮合拒绝：原订单不变，解冻预冻结金额
+func (u *OrderUpdater) handleAmendRejected(ctx context.Context, event *MatchingEvent) error {
+	var data AmendRejectedData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return fmt.Errorf("unmarshal amend rejected: %w", err)
+	}
+
+	order, err := u.orderStore.GetOrder(ctx, data.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.PendingAmendID != data.AmendID {
+		return nil // 已处理
+	}
+
+	if order.PendingAmendFreeze > 0 {
+		cfg, err := u.orderStore.GetSymbolConfig(ctx, order.Symbol)
+		if err != nil {
+			return err
+		}
+		asset := cfg.QuoteAsset
+		if order.Side == repository.SideSell {
+			asset = cfg.BaseAsset
+		}
+		unfreezeKey := fmt.Sprintf("unfreeze:order:%d:amend:%d:reject", order.OrderID, data.AmendID)
+		resp, err := u.clearing.UnfreezeBalance(ctx, order.UserID, asset, order.PendingAmendFreeze, unfreezeKey)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
 		}
 	}
 
+	if err := u.orderStore.ClearPendingAmend(ctx, order.OrderID, data.AmendID, time.Now().UnixMilli()); err != nil && err != repository.ErrOrderNotFound {
+		return err
+	}
+	if u.publisher != nil {
+		if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "amend_rejected", map[string]interface{}{
+			"orderId": order.OrderID,
+			"amendId": data.AmendID,
+			"reason":  data.Reason,
+		}); pubErr != nil {
+			log.Printf("publish amend rejected error: %v", pubErr)
+		}
+	}
 	return nil
 }
 
@@ -500,6 +1182,17 @@ func (u *OrderUpdater) handleOrderRejected(ctx context.Context, event *MatchingE
 	if err != nil {
 		return err
 	}
+	if order.ListID != 0 {
+		if err := u.advanceOrderList(ctx, order); err != nil {
+			return err
+		}
+		if u.publisher != nil {
+			if pubErr := u.publisher.PublishOrderEvent(ctx, order.UserID, "rejected", order); pubErr != nil {
+				log.Printf("publish order rejected error: %v", pubErr)
+			}
+		}
+		return nil
+	}
 
 	cfg, err := u.orderStore.GetSymbolConfig(ctx, order.Symbol)
 	if err != nil {
@@ -660,6 +1353,9 @@ func (u *OrderUpdater) unfreezeForFilled(ctx context.Context, orderID int64) err
 	if err != nil {
 		return err
 	}
+	if order.ListID != 0 {
+		return u.advanceOrderList(ctx, order)
+	}
 	if order.Side != repository.SideBuy {
 		return nil
 	}
@@ -685,7 +1381,85 @@ func (u *OrderUpdater) unfreezeForFilled(ctx context.Context, orderID int64) err
 	return nil
 }
 
+// advanceOrderList 列表订单成交、触发或终止：撤销其余仍在执行的订单，全部订单终态后一次释放列表冻结
+//
+// 第一条推进的订单记为列表的触发订单并继续执行，其余订单一律撤销。重复事件只会重发撤单
+// （撮合对已不在簿的订单回复 ORDER_NOT_FOUND），解冻按列表 ID 幂等。
+func (u *OrderUpdater) advanceOrderList(ctx context.Context, order *repository.Order) error {
+	if u.lists == nil || u.canceler == nil {
+		return fmt.Errorf("order list %d: order list linkage not configured", order.ListID)
+	}
+	triggered, err := u.lists.TriggerOrderList(ctx, order.ListID, order.OrderID, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	legs, err := u.lists.ListOrderListOrders(ctx, order.ListID)
+	if err != nil {
+		return err
+	}
+
+	done := true
+	var used int64
+	for _, leg := range legs {
+		switch leg.Status {
+		case repository.StatusInit, repository.StatusNew, repository.StatusPartiallyFilled:
+			done = false
+			if leg.OrderID == triggered || leg.Status == repository.StatusInit {
+				continue
+			}
+			if err := u.canceler.SendCancel(ctx, leg); err != nil {
+				return fmt.Errorf("cancel order list %d sibling %d: %w", order.ListID, leg.OrderID, err)
+			}
+		default:
+			// 卖出冻结数量，买入冻结金额：已用部分分别为成交数量与成交额
+			field, value := "executed_qty", leg.ExecutedQty
+			if leg.Side == repository.SideBuy {
+				field, value = "cumulative_quote_qty", leg.CumulativeQuoteQty
+			}
+			spent, err := parseInt64(value, field)
+			if err != nil {
+				return err
+			}
+			used += spent
+		}
+	}
+	if !done {
+		return nil
+	}
+
+	list, err := u.lists.GetOrderList(ctx, order.ListID)
+	if err != nil {
+		return err
+	}
+	if list.Status != repository.OrderListStatusExecuting {
+		return nil
+	}
+	if release := list.FrozenAmount - used; release > 0 {
+		unfreezeKey := fmt.Sprintf("unfreeze:list:%d", list.ListID)
+		resp, err := u.clearing.UnfreezeBalance(ctx, list.UserID, list.FreezeAsset, release, unfreezeKey)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("unfreeze failed: %s", resp.ErrorCode)
+		}
+	} else if release < 0 {
+		log.Printf("order list over-executed: listID=%d frozen=%d used=%d", list.ListID, list.FrozenAmount, used)
+	}
+	err = u.lists.FinishOrderList(ctx, list.ListID, repository.OrderListStatusAllDone, time.Now().UnixMilli())
+	if err != nil && !errors.Is(err, repository.ErrOrderListNotFound) {
+		return err
+	}
+	return nil
+}
+
 func totalFrozenQuote(order *repository.Order, cfg *repository.SymbolConfig) (int64, error) {
+	if order.QuoteOrderQty > 0 {
+		return order.QuoteOrderQty, nil
+	}
+	if order.FrozenQuoteQty > 0 {
+		return order.FrozenQuoteQty, nil
+	}
 	price, err := parseInt64(order.Price, "price")
 	if err != nil {
 		return 0, err
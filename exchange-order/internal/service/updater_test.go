@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/exchange/common/pkg/eventseq"
 	"github.com/exchange/order/internal/client"
+	"github.com/exchange/order/internal/metrics"
 	"github.com/exchange/order/internal/repository"
 	redismock "github.com/go-redis/redismock/v9"
 	"github.com/redis/go-redis/v9"
@@ -24,6 +26,30 @@ type fakeOrderStore struct {
 
 	updateCalls []updateCall
 	addCalls    []addCall
+
+	triggeredID   int64
+	triggeredTime int64
+
+	trailingID      int64
+	trailingStop    int64
+	trailingExtreme int64
+
+	reducedID      int64
+	reducedOrigQty int64
+	reducedQuote   int64
+
+	amendApplied   *amendCall
+	clearedAmendID int64
+
+	rejectedIDs  []int64
+	rejectReason string
+}
+
+type amendCall struct {
+	orderID     int64
+	price       int64
+	origQty     int64
+	frozenQuote int64
 }
 
 type updateCall struct {
@@ -55,7 +81,9 @@ func (f *fakeOrderStore) CancelOrder(_ context.Context, orderID int64, reason st
 	return nil
 }
 
-func (f *fakeOrderStore) RejectOrder(_ context.Context, _ int64, _ string, _ int64) error {
+func (f *fakeOrderStore) RejectOrder(_ context.Context, orderID int64, reason string, _ int64) error {
+	f.rejectedIDs = append(f.rejectedIDs, orderID)
+	f.rejectReason = reason
 	return nil
 }
 
@@ -72,6 +100,36 @@ func (f *fakeOrderStore) AddOrderCumulativeQuoteQty(_ context.Context, orderID i
 	return nil
 }
 
+func (f *fakeOrderStore) MarkOrderTriggered(_ context.Context, orderID int64, triggerTimeMs int64) error {
+	f.triggeredID = orderID
+	f.triggeredTime = triggerTimeMs
+	return nil
+}
+
+func (f *fakeOrderStore) UpdateTrailingStop(_ context.Context, orderID, stopPrice, extremePrice, _ int64) error {
+	f.trailingID = orderID
+	f.trailingStop = stopPrice
+	f.trailingExtreme = extremePrice
+	return nil
+}
+
+func (f *fakeOrderStore) ReduceOrderQty(_ context.Context, orderID int64, origQty int64, releasedQuote int64, _ int64) error {
+	f.reducedID = orderID
+	f.reducedOrigQty = origQty
+	f.reducedQuote = releasedQuote
+	return nil
+}
+
+func (f *fakeOrderStore) ApplyAmend(_ context.Context, orderID, price, origQty, frozenQuoteQty, _ int64) error {
+	f.amendApplied = &amendCall{orderID: orderID, price: price, origQty: origQty, frozenQuote: frozenQuoteQty}
+	return nil
+}
+
+func (f *fakeOrderStore) ClearPendingAmend(_ context.Context, orderID, amendID, _ int64) error {
+	f.clearedAmendID = amendID
+	return nil
+}
+
 type fakeTradeStore struct {
 	saved *repository.Trade
 }
@@ -117,6 +175,26 @@ func (a *addQtyErrorStore) AddOrderCumulativeQuoteQty(_ context.Context, _ int64
 	return errors.New("update failed")
 }
 
+func (a *addQtyErrorStore) MarkOrderTriggered(_ context.Context, _ int64, _ int64) error {
+	return nil
+}
+
+func (a *addQtyErrorStore) UpdateTrailingStop(_ context.Context, _, _, _, _ int64) error {
+	return nil
+}
+
+func (a *addQtyErrorStore) ReduceOrderQty(_ context.Context, _ int64, _ int64, _ int64, _ int64) error {
+	return nil
+}
+
+func (a *addQtyErrorStore) ApplyAmend(_ context.Context, _, _, _, _, _ int64) error {
+	return nil
+}
+
+func (a *addQtyErrorStore) ClearPendingAmend(_ context.Context, _, _, _ int64) error {
+	return nil
+}
+
 type errorSymbolStore struct {
 	order *repository.Order
 	err   error
@@ -146,6 +224,26 @@ func (e *errorSymbolStore) AddOrderCumulativeQuoteQty(_ context.Context, _ int64
 	return nil
 }
 
+func (e *errorSymbolStore) MarkOrderTriggered(_ context.Context, _ int64, _ int64) error {
+	return nil
+}
+
+func (e *errorSymbolStore) UpdateTrailingStop(_ context.Context, _, _, _, _ int64) error {
+	return nil
+}
+
+func (e *errorSymbolStore) ReduceOrderQty(_ context.Context, _ int64, _ int64, _ int64, _ int64) error {
+	return nil
+}
+
+func (e *errorSymbolStore) ApplyAmend(_ context.Context, _, _, _, _, _ int64) error {
+	return nil
+}
+
+func (e *errorSymbolStore) ClearPendingAmend(_ context.Context, _, _, _ int64) error {
+	return nil
+}
+
 type cancelErrStore struct {
 	order *repository.Order
 	cfg   *repository.SymbolConfig
@@ -175,6 +273,26 @@ func (c *cancelErrStore) AddOrderCumulativeQuoteQty(_ context.Context, _ int64,
 	return nil
 }
 
+func (c *cancelErrStore) MarkOrderTriggered(_ context.Context, _ int64, _ int64) error {
+	return nil
+}
+
+func (c *cancelErrStore) UpdateTrailingStop(_ context.Context, _, _, _, _ int64) error {
+	return nil
+}
+
+func (c *cancelErrStore) ReduceOrderQty(_ context.Context, _ int64, _ int64, _ int64, _ int64) error {
+	return nil
+}
+
+func (c *cancelErrStore) ApplyAmend(_ context.Context, _, _, _, _, _ int64) error {
+	return nil
+}
+
+func (c *cancelErrStore) ClearPendingAmend(_ context.Context, _, _, _ int64) error {
+	return nil
+}
+
 type orderErrStore struct {
 	err error
 }
@@ -203,19 +321,47 @@ func (o *orderErrStore) AddOrderCumulativeQuoteQty(_ context.Context, _ int64, _
 	return nil
 }
 
+func (o *orderErrStore) MarkOrderTriggered(_ context.Context, _ int64, _ int64) error {
+	return nil
+}
+
+func (o *orderErrStore) UpdateTrailingStop(_ context.Context, _, _, _, _ int64) error {
+	return nil
+}
+
+func (o *orderErrStore) ReduceOrderQty(_ context.Context, _ int64, _ int64, _ int64, _ int64) error {
+	return nil
+}
+
+func (o *orderErrStore) ApplyAmend(_ context.Context, _, _, _, _, _ int64) error {
+	return nil
+}
+
+func (o *orderErrStore) ClearPendingAmend(_ context.Context, _, _, _ int64) error {
+	return nil
+}
+
 type fakeUnfreezer struct {
-	called bool
-	asset  string
-	amount int64
+	called  bool
+	asset   string
+	amount  int64
+	key     string
+	batches [][]client.UnfreezeRequest
 }
 
-func (f *fakeUnfreezer) UnfreezeBalance(_ context.Context, _ int64, asset string, amount int64, _ string) (*client.UnfreezeResponse, error) {
+func (f *fakeUnfreezer) UnfreezeBalance(_ context.Context, _ int64, asset string, amount int64, key string) (*client.UnfreezeResponse, error) {
 	f.called = true
 	f.asset = asset
 	f.amount = amount
+	f.key = key
 	return &client.UnfreezeResponse{Success: true}, nil
 }
 
+func (f *fakeUnfreezer) BatchUnfreezeBalance(_ context.Context, items []client.UnfreezeRequest) (*client.BatchUnfreezeResponse, error) {
+	f.batches = append(f.batches, items)
+	return &client.BatchUnfreezeResponse{Success: true, Applied: len(items)}, nil
+}
+
 type failingUnfreezer struct {
 	resp *client.UnfreezeResponse
 	err  error
@@ -228,6 +374,13 @@ func (f *failingUnfreezer) UnfreezeBalance(_ context.Context, _ int64, _ string,
 	return f.resp, nil
 }
 
+func (f *failingUnfreezer) BatchUnfreezeBalance(_ context.Context, _ []client.UnfreezeRequest) (*client.BatchUnfreezeResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &client.BatchUnfreezeResponse{Success: f.resp.Success, ErrorCode: f.resp.ErrorCode}, nil
+}
+
 type fakePrivateEventPublisher struct {
 	orderEvents []string
 	tradeUsers  []int64
@@ -543,6 +696,174 @@ func TestOrderUpdater_ProcessMessage_StatusHandlers(t *testing.T) {
 	}
 }
 
+func TestOrderUpdater_HandleMassCanceled(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID: 1,
+			UserID:  10,
+			Symbol:  "BTCUSDT",
+			Side:    repository.SideSell,
+			Price:   "100000000",
+			OrigQty: "500000000",
+		},
+		cfg: &repository.SymbolConfig{
+			Symbol:     "BTCUSDT",
+			BaseAsset:  "BTC",
+			QuoteAsset: "USDT",
+		},
+	}
+	unfreezer := &fakeUnfreezer{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+	pub := &fakePrivateEventPublisher{}
+	updater.SetPublisher(pub)
+
+	event := &MatchingEvent{
+		Type:   "MASS_CANCELED",
+		Symbol: "BTCUSDT",
+		Data: mustJSON(t, MassCanceledData{
+			RequestID: 7,
+			UserID:    10,
+			Orders:    []MassCanceledOrder{{OrderID: 1, LeavesQty: 3 * 1e8}},
+			Reason:    "MASS_CANCELED",
+		}),
+	}
+	if err := updater.handleMassCanceled(context.Background(), event); err != nil {
+		t.Fatalf("handle mass canceled: %v", err)
+	}
+	if store.cancelID != 1 || store.cancelReason != "MASS_CANCELED" {
+		t.Fatalf("unexpected cancel: id=%d reason=%s", store.cancelID, store.cancelReason)
+	}
+	if unfreezer.called {
+		t.Fatal("expected batch unfreeze instead of single unfreeze")
+	}
+	if len(unfreezer.batches) != 1 || len(unfreezer.batches[0]) != 1 {
+		t.Fatalf("expected one batch unfreeze, got %+v", unfreezer.batches)
+	}
+	item := unfreezer.batches[0][0]
+	if item.Asset != "BTC" || item.Amount != 3*1e8 || item.IdempotencyKey != "unfreeze:order:1" {
+		t.Fatalf("unexpected unfreeze item: %+v", item)
+	}
+	if len(pub.orderEvents) != 1 || pub.orderEvents[0] != "canceled" {
+		t.Fatalf("expected publish order event canceled, got %+v", pub.orderEvents)
+	}
+
+	failing := NewOrderUpdater(nil, store, &fakeTradeStore{}, &failingUnfreezer{resp: &client.UnfreezeResponse{ErrorCode: "FAIL"}}, nil, &UpdaterConfig{})
+	if err := failing.handleMassCanceled(context.Background(), event); err == nil {
+		t.Fatal("expected batch unfreeze failure")
+	}
+
+	empty := &MatchingEvent{Type: "MASS_CANCELED", Symbol: "BTCUSDT", Data: mustJSON(t, MassCanceledData{RequestID: 8, UserID: 10})}
+	if err := updater.handleMassCanceled(context.Background(), empty); err != nil || len(unfreezer.batches) != 1 {
+		t.Fatalf("expected empty mass cancel to be a no-op, err=%v", err)
+	}
+}
+
+func TestOrderUpdater_HandleMassQuoted(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID:            1,
+			UserID:             10,
+			Symbol:             "BTCUSDT",
+			Side:               repository.SideBuy,
+			Price:              "10000000000",
+			OrigQty:            "500000000",
+			ExecutedQty:        "200000000",
+			CumulativeQuoteQty: "19000000000",
+			QuoteID:            8,
+		},
+		cfg: &repository.SymbolConfig{
+			Symbol:       "BTCUSDT",
+			BaseAsset:    "BTC",
+			QuoteAsset:   "USDT",
+			QtyPrecision: 8,
+		},
+	}
+	unfreezer := &fakeUnfreezer{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+	pub := &fakePrivateEventPublisher{}
+	updater.SetPublisher(pub)
+
+	event := &MatchingEvent{
+		Type:   "MASS_QUOTED",
+		Symbol: "BTCUSDT",
+		Data: mustJSON(t, MassQuotedData{
+			RequestID:  9,
+			UserID:     10,
+			Canceled:   []MassCanceledOrder{{OrderID: 1, LeavesQty: 3 * 1e8}},
+			Orders:     []int64{2, 3},
+			BuyCarried: 250 * 1e8,
+		}),
+	}
+	raw, _ := json.Marshal(event)
+	if err := updater.processMessage(context.Background(), redis.XMessage{Values: map[string]interface{}{"data": string(raw)}}); err != nil {
+		t.Fatalf("handle mass quoted: %v", err)
+	}
+	if store.cancelID != 1 || store.cancelReason != CancelReasonQuoteReplaced {
+		t.Fatalf("unexpected cancel: id=%d reason=%s", store.cancelID, store.cancelReason)
+	}
+	// 冻结 500，已花费 190，剩余 310，其中 250 转给新报价
+	if len(unfreezer.batches) != 1 || len(unfreezer.batches[0]) != 1 {
+		t.Fatalf("expected one batch unfreeze, got %+v", unfreezer.batches)
+	}
+	item := unfreezer.batches[0][0]
+	if item.Asset != "USDT" || item.Amount != 60*1e8 || item.IdempotencyKey != "unfreeze:quote:9:USDT" || item.RefType != "QUOTE" {
+		t.Fatalf("unexpected unfreeze item: %+v", item)
+	}
+	if len(pub.orderEvents) != 1 || pub.orderEvents[0] != "canceled" {
+		t.Fatalf("expected publish order event canceled, got %+v", pub.orderEvents)
+	}
+
+	// 剩余冻结全部转给新报价时无需解冻
+	event.Data = mustJSON(t, MassQuotedData{
+		RequestID:  10,
+		UserID:     10,
+		Canceled:   []MassCanceledOrder{{OrderID: 1, LeavesQty: 3 * 1e8}},
+		BuyCarried: 310 * 1e8,
+	})
+	if err := updater.handleMassQuoted(context.Background(), event); err != nil || len(unfreezer.batches) != 1 {
+		t.Fatalf("expected nothing to unfreeze, err=%v batches=%d", err, len(unfreezer.batches))
+	}
+}
+
+func TestOrderUpdater_HandleMassQuoteRejected(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{OrderID: 2, UserID: 10, Symbol: "BTCUSDT", Side: repository.SideBuy},
+		cfg:   &repository.SymbolConfig{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT"},
+	}
+	unfreezer := &fakeUnfreezer{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+	pub := &fakePrivateEventPublisher{}
+	updater.SetPublisher(pub)
+
+	event := &MatchingEvent{
+		Type:   "MASS_QUOTE_REJECTED",
+		Symbol: "BTCUSDT",
+		Data: mustJSON(t, MassQuoteRejectedData{
+			RequestID:  9,
+			UserID:     10,
+			Orders:     []int64{2, 3},
+			BuyFrozen:  100,
+			SellFrozen: 5,
+			Reason:     "INSUFFICIENT_BALANCE",
+		}),
+	}
+	if err := updater.handleMassQuoteRejected(context.Background(), event); err != nil {
+		t.Fatalf("handle mass quote rejected: %v", err)
+	}
+	if len(store.rejectedIDs) != 2 || store.rejectReason != "INSUFFICIENT_BALANCE" {
+		t.Fatalf("unexpected rejects: %v %s", store.rejectedIDs, store.rejectReason)
+	}
+	if len(unfreezer.batches) != 1 || len(unfreezer.batches[0]) != 2 {
+		t.Fatalf("expected one batch unfreeze, got %+v", unfreezer.batches)
+	}
+	if item := unfreezer.batches[0][1]; item.Asset != "BTC" || item.Amount != 5 || item.IdempotencyKey != "unfreeze:quote:9:BTC:reject" {
+		t.Fatalf("unexpected unfreeze item: %+v", item)
+	}
+	if len(pub.orderEvents) != 2 || pub.orderEvents[0] != "rejected" {
+		t.Fatalf("expected publish order event rejected, got %+v", pub.orderEvents)
+	}
+}
+
 func TestOrderUpdater_HandleOrderCanceled_UnfreezeFailures(t *testing.T) {
 	store := &fakeOrderStore{
 		order: &repository.Order{
@@ -791,6 +1112,94 @@ func TestOrderUpdater_HandleOrderCanceled_AmountZero(t *testing.T) {
 	}
 }
 
+func TestOrderUpdater_HandleOrderCanceled_Expired(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID:     1,
+			UserID:      10,
+			Symbol:      "BTCUSDT",
+			Side:        repository.SideSell,
+			TimeInForce: 5,
+			Price:       "100000000",
+			OrigQty:     "200000000",
+		},
+		cfg: &repository.SymbolConfig{
+			BaseAsset:  "BTC",
+			QuoteAsset: "USDT",
+		},
+	}
+	unfreezer := &fakeUnfreezer{}
+	publisher := &fakePrivateEventPublisher{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+	updater.SetPublisher(publisher)
+
+	if err := updater.handleOrderCanceled(context.Background(), &MatchingEvent{
+		Data: mustJSON(t, OrderCanceledData{
+			OrderID:   1,
+			UserID:    10,
+			LeavesQty: 2 * 1e8,
+			Reason:    repository.CancelReasonExpired,
+		}),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !store.cancelCalled || store.cancelReason != repository.CancelReasonExpired {
+		t.Fatalf("expected order expired, got reason %q", store.cancelReason)
+	}
+	if !unfreezer.called || unfreezer.asset != "BTC" || unfreezer.amount != 2*1e8 {
+		t.Fatalf("unexpected unfreeze: called=%v asset=%s amount=%d", unfreezer.called, unfreezer.asset, unfreezer.amount)
+	}
+	if len(publisher.orderEvents) != 1 || publisher.orderEvents[0] != "expired" {
+		t.Fatalf("unexpected private events: %v", publisher.orderEvents)
+	}
+}
+
+func TestOrderUpdater_QuoteOrderQtyUnfreezesUnspent(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID:            1,
+			UserID:             10,
+			Symbol:             "BTCUSDT",
+			Side:               repository.SideBuy,
+			Type:               repository.TypeMarket,
+			TimeInForce:        2,
+			Price:              "0",
+			OrigQty:            "0",
+			ExecutedQty:        "49",
+			CumulativeQuoteQty: "491",
+			QuoteOrderQty:      500,
+		},
+		cfg: &repository.SymbolConfig{
+			BaseAsset:  "BTC",
+			QuoteAsset: "USDT",
+		},
+	}
+	unfreezer := &fakeUnfreezer{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+
+	// 金额用尽：成交后解冻剩余的零头
+	if err := updater.handleOrderFilled(context.Background(), &MatchingEvent{
+		Data: mustJSON(t, OrderFilledData{OrderID: 1, ExecutedQty: 49}),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !unfreezer.called || unfreezer.asset != "USDT" || unfreezer.amount != 9 {
+		t.Fatalf("unexpected unfreeze: called=%v asset=%s amount=%d", unfreezer.called, unfreezer.asset, unfreezer.amount)
+	}
+
+	// 流动性不足：IOC 撤销剩余，按未花费金额解冻（与撮合上报的剩余数量无关）
+	unfreezer.called = false
+	store.order.CumulativeQuoteQty = "300"
+	if err := updater.handleOrderCanceled(context.Background(), &MatchingEvent{
+		Data: mustJSON(t, OrderCanceledData{OrderID: 1, UserID: 10, LeavesQty: 20, Reason: "IOC_EXPIRED"}),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !unfreezer.called || unfreezer.amount != 200 {
+		t.Fatalf("unexpected unfreeze: called=%v amount=%d", unfreezer.called, unfreezer.amount)
+	}
+}
+
 func TestOrderUpdater_HandleOrderCanceled_ConfigError(t *testing.T) {
 	store := &errorSymbolStore{
 		order: &repository.Order{
@@ -937,3 +1346,257 @@ func mustJSON(t *testing.T, v interface{}) json.RawMessage {
 	}
 	return raw
 }
+
+func TestOrderUpdater_ProcessMessage_StopOrderEvents(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID: 1,
+			UserID:  10,
+			Type:    repository.TypeStopLossLimit,
+		},
+	}
+	publisher := &fakePrivateEventPublisher{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, &fakeUnfreezer{}, nil, &UpdaterConfig{})
+	updater.SetPublisher(publisher)
+
+	events := []MatchingEvent{
+		{Type: "STOP_ORDER_ACCEPTED", Data: mustJSON(t, OrderAcceptedData{OrderID: 1})},
+		{Type: "STOP_ORDER_TRIGGERED", Timestamp: 5_000_000_000, Data: mustJSON(t, StopOrderTriggeredData{OrderID: 1, StopPrice: 100, LastPrice: 99})},
+	}
+	for _, evt := range events {
+		raw, _ := json.Marshal(evt)
+		msg := redis.XMessage{Values: map[string]interface{}{"data": string(raw)}}
+		if err := updater.processMessage(context.Background(), msg); err != nil {
+			t.Fatalf("process %s: %v", evt.Type, err)
+		}
+	}
+
+	if len(store.updateCalls) != 1 || store.updateCalls[0].status != repository.StatusNew {
+		t.Fatalf("expected stop accepted to keep order NEW, got %+v", store.updateCalls)
+	}
+	if store.triggeredID != 1 || store.triggeredTime != 5000 {
+		t.Fatalf("unexpected trigger mark: id=%d time=%d", store.triggeredID, store.triggeredTime)
+	}
+	if len(publisher.orderEvents) != 1 || publisher.orderEvents[0] != "triggered" {
+		t.Fatalf("unexpected published events: %v", publisher.orderEvents)
+	}
+}
+
+func TestOrderUpdater_ProcessMessage_TrailingStopUpdated(t *testing.T) {
+	store := &fakeOrderStore{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, &fakeUnfreezer{}, nil, &UpdaterConfig{})
+
+	evt := MatchingEvent{
+		Type:      "TRAILING_STOP_UPDATED",
+		Timestamp: 5_000_000_000,
+		Data:      mustJSON(t, TrailingStopUpdatedData{OrderID: 1, StopPrice: 105, ExtremePrice: 110}),
+	}
+	raw, _ := json.Marshal(evt)
+	msg := redis.XMessage{Values: map[string]interface{}{"data": string(raw)}}
+	if err := updater.processMessage(context.Background(), msg); err != nil {
+		t.Fatalf("process trailing stop updated: %v", err)
+	}
+	if store.trailingID != 1 || store.trailingStop != 105 || store.trailingExtreme != 110 {
+		t.Fatalf("unexpected trailing update: id=%d stop=%d extreme=%d", store.trailingID, store.trailingStop, store.trailingExtreme)
+	}
+	if len(store.updateCalls) != 0 {
+		t.Fatalf("trailing update must not change order status: %+v", store.updateCalls)
+	}
+}
+
+func TestOrderUpdater_ProcessMessage_OrderReduced(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID: 1,
+			UserID:  10,
+			Symbol:  "BTCUSDT",
+			Side:    repository.SideSell,
+			Price:   "100",
+			OrigQty: "70",
+		},
+		cfg: &repository.SymbolConfig{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT"},
+	}
+	unfreezer := &fakeUnfreezer{}
+	publisher := &fakePrivateEventPublisher{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+	updater.SetPublisher(publisher)
+
+	evt := MatchingEvent{
+		Type: "ORDER_REDUCED",
+		Data: mustJSON(t, OrderReducedData{OrderID: 1, ReducedQty: 30, OrigQty: 70, LeavesQty: 70, Reason: "STP_DECREMENT"}),
+	}
+	raw, _ := json.Marshal(evt)
+	if err := updater.processMessage(context.Background(), redis.XMessage{Values: map[string]interface{}{"data": string(raw)}}); err != nil {
+		t.Fatalf("process order reduced: %v", err)
+	}
+
+	if store.reducedID != 1 || store.reducedOrigQty != 70 || store.reducedQuote != 0 {
+		t.Fatalf("unexpected reduce call: id=%d orig=%d quote=%d", store.reducedID, store.reducedOrigQty, store.reducedQuote)
+	}
+	if !unfreezer.called || unfreezer.asset != "BTC" || unfreezer.amount != 30 {
+		t.Fatalf("unexpected unfreeze: called=%v %s %d", unfreezer.called, unfreezer.asset, unfreezer.amount)
+	}
+	if len(publisher.orderEvents) != 1 || publisher.orderEvents[0] != "reduced" {
+		t.Fatalf("unexpected published events: %v", publisher.orderEvents)
+	}
+}
+
+func TestOrderUpdater_ProcessMessage_OrderAmendedBuy(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID:            1,
+			UserID:             10,
+			Symbol:             "BTCUSDT",
+			Side:               repository.SideBuy,
+			Price:              "100",
+			OrigQty:            "10",
+			ExecutedQty:        "4",
+			CumulativeQuoteQty: "40",
+			PendingAmendID:     5,
+			PendingAmendFreeze: 24,
+		},
+		cfg: &repository.SymbolConfig{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT", QtyPrecision: 1},
+	}
+	unfreezer := &fakeUnfreezer{}
+	publisher := &fakePrivateEventPublisher{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+	updater.SetPublisher(publisher)
+
+	// 冻结 100 + 预冻结 24，已成交 40，新剩余 6@120 需 72，解冻 12
+	evt := MatchingEvent{
+		Type: "ORDER_AMENDED",
+		Data: mustJSON(t, OrderAmendedData{OrderID: 1, AmendID: 5, OldPrice: 100, Price: 120, OldQty: 10, OrigQty: 10, LeavesQty: 6}),
+	}
+	raw, _ := json.Marshal(evt)
+	if err := updater.processMessage(context.Background(), redis.XMessage{Values: map[string]interface{}{"data": string(raw)}}); err != nil {
+		t.Fatalf("process order amended: %v", err)
+	}
+
+	if !unfreezer.called || unfreezer.asset != "USDT" || unfreezer.amount != 12 {
+		t.Fatalf("unexpected unfreeze: called=%v %s %d", unfreezer.called, unfreezer.asset, unfreezer.amount)
+	}
+	want := amendCall{orderID: 1, price: 120, origQty: 10, frozenQuote: 112}
+	if store.amendApplied == nil || *store.amendApplied != want {
+		t.Fatalf("unexpected apply amend: %+v", store.amendApplied)
+	}
+	if len(publisher.orderEvents) != 1 || publisher.orderEvents[0] != "amended" {
+		t.Fatalf("unexpected published events: %v", publisher.orderEvents)
+	}
+}
+
+func TestOrderUpdater_ProcessMessage_OrderAmendedSellQtyDown(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID: 1,
+			UserID:  10,
+			Symbol:  "BTCUSDT",
+			Side:    repository.SideSell,
+			Price:   "100",
+			OrigQty: "10",
+		},
+		cfg: &repository.SymbolConfig{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT"},
+	}
+	unfreezer := &fakeUnfreezer{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+
+	evt := MatchingEvent{
+		Type: "ORDER_AMENDED",
+		Data: mustJSON(t, OrderAmendedData{OrderID: 1, AmendID: 6, OldPrice: 100, Price: 100, OldQty: 10, OrigQty: 7, LeavesQty: 7, PriorityKept: true}),
+	}
+	raw, _ := json.Marshal(evt)
+	if err := updater.processMessage(context.Background(), redis.XMessage{Values: map[string]interface{}{"data": string(raw)}}); err != nil {
+		t.Fatalf("process order amended: %v", err)
+	}
+
+	if !unfreezer.called || unfreezer.asset != "BTC" || unfreezer.amount != 3 {
+		t.Fatalf("unexpected unfreeze: called=%v %s %d", unfreezer.called, unfreezer.asset, unfreezer.amount)
+	}
+	if store.amendApplied == nil || store.amendApplied.origQty != 7 || store.amendApplied.frozenQuote != 0 {
+		t.Fatalf("unexpected apply amend: %+v", store.amendApplied)
+	}
+}
+
+func TestOrderUpdater_ProcessMessage_AmendRejected(t *testing.T) {
+	store := &fakeOrderStore{
+		order: &repository.Order{
+			OrderID:            1,
+			UserID:             10,
+			Symbol:             "BTCUSDT",
+			Side:               repository.SideSell,
+			OrigQty:            "10",
+			PendingAmendID:     7,
+			PendingAmendFreeze: 5,
+		},
+		cfg: &repository.SymbolConfig{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT"},
+	}
+	unfreezer := &fakeUnfreezer{}
+	updater := NewOrderUpdater(nil, store, &fakeTradeStore{}, unfreezer, nil, &UpdaterConfig{})
+
+	process := func(amendID int64) {
+		t.Helper()
+		evt := MatchingEvent{
+			Type: "AMEND_REJECTED",
+			Data: mustJSON(t, AmendRejectedData{OrderID: 1, AmendID: amendID, Reason: "POST_ONLY_REJECTED"}),
+		}
+		raw, _ := json.Marshal(evt)
+		if err := updater.processMessage(context.Background(), redis.XMessage{Values: map[string]interface{}{"data": string(raw)}}); err != nil {
+			t.Fatalf("process amend rejected: %v", err)
+		}
+	}
+
+	// 非当前改单：忽略
+	process(6)
+	if unfreezer.called || store.clearedAmendID != 0 {
+		t.Fatalf("stale amend rejection should be ignored")
+	}
+
+	process(7)
+	if !unfreezer.called || unfreezer.asset != "BTC" || unfreezer.amount != 5 {
+		t.Fatalf("unexpected unfreeze: called=%v %s %d", unfreezer.called, unfreezer.asset, unfreezer.amount)
+	}
+	if store.clearedAmendID != 7 {
+		t.Fatalf("expected pending amend cleared, got %d", store.clearedAmendID)
+	}
+}
+
+func TestOrderUpdater_ConsumeOnce_SeqGapRequestsResync(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	ctx := context.Background()
+	updater := NewOrderUpdater(redisClient, &fakeOrderStore{}, &fakeTradeStore{}, &fakeUnfreezer{}, metrics.New(), &UpdaterConfig{
+		EventStream: "matching:events",
+		Group:       "order-updater-group",
+		Consumer:    "order-updater-1",
+	})
+	// grace 为 0：超前的序列号立即按缺口上报
+	updater.seq = eventseq.NewGroupTracker(redisClient, "matching:events", "order-updater-group", 0)
+	if err := redisClient.XGroupCreateMkStream(ctx, "matching:events", "order-updater-group", "0").Err(); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	for _, seq := range []int64{1, 2, 5, 5} {
+		raw := mustJSON(t, MatchingEvent{Type: "UNKNOWN", Symbol: "BTCUSDT", Seq: seq, Data: mustJSON(t, struct{}{})})
+		if err := redisClient.XAdd(ctx, &redis.XAddArgs{Stream: "matching:events", Values: map[string]interface{}{"data": string(raw)}}).Err(); err != nil {
+			t.Fatalf("xadd: %v", err)
+		}
+	}
+
+	if err := updater.consumeOnce(ctx); err != nil {
+		t.Fatalf("consume once: %v", err)
+	}
+	if last, _ := redisClient.HGet(ctx, "matching:events:consumed:order-updater-group", "BTCUSDT").Result(); last != "5" {
+		t.Fatalf("expected group last seq=5, got %q", last)
+	}
+	resync, err := redisClient.XRange(ctx, "matching:events:resync", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("xrange resync: %v", err)
+	}
+	if len(resync) != 1 || resync[0].Values["symbol"] != "BTCUSDT" || resync[0].Values["lastSeq"] != "2" || resync[0].Values["gotSeq"] != "5" {
+		t.Fatalf("unexpected resync requests: %+v", resync)
+	}
+}
@@ -52,6 +52,11 @@ func (p *Publisher) PublishOrderCanceled(ctx context.Context, userID int64, orde
 	return p.PublishOrderEvent(ctx, userID, "canceled", order)
 }
 
+// PublishAlgoEvent publishes an algo order event for the user.
+func (p *Publisher) PublishAlgoEvent(ctx context.Context, userID int64, event string, algo interface{}) error {
+	return p.publish(ctx, userID, "algo", event, algo)
+}
+
 // PublishTradeEvent publishes a trade event for the user.
 func (p *Publisher) PublishTradeEvent(ctx context.Context, userID int64, trade interface{}) error {
 	return p.publish(ctx, userID, "trade", "", trade)
@@ -151,3 +151,42 @@ func TestPublisherPublishTradeEvent(t *testing.T) {
 		t.Fatalf("event should be omitted for trade payload")
 	}
 }
+
+func TestPublisherPublishAlgoEvent(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis run: %v", err)
+	}
+	defer mr.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	publisher := NewPublisher(client, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	sub := client.Subscribe(ctx, "private:user:7:events")
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	if err := publisher.PublishAlgoEvent(ctx, 7, "progress", map[string]interface{}{"AlgoID": 3001}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if payload["channel"] != "algo" || payload["event"] != "progress" {
+		t.Fatalf("unexpected payload: %v", payload)
+	}
+}